@@ -34,7 +34,7 @@ Flags:
 	-shared
 		generate code that can be linked into a shared library
 	-trimpath string
-		remove prefix from recorded source file paths
+		remove or rewrite prefixes of recorded source file paths
 
 Input language:
 
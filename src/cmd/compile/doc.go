@@ -79,8 +79,11 @@ Flags:
 		Write a package (archive) file rather than an object file
 	-race
 		Compile with race detector enabled.
-	-trimpath prefix
-		Remove prefix from recorded source file paths.
+	-trimpath rewrites
+		Rewrite the recorded source file paths. The rewrites are a
+		semicolon-separated list, each of the form "prefix", which
+		removes prefix from the paths, or "prefix=>replacement",
+		which replaces it.
 	-u
 		Disallow importing packages not marked as safe; implies -nolocalimports.
 
@@ -36,6 +36,10 @@ Write out an HTML file instead of launching a web browser:
 Display coverage percentages to stdout for each function:
 	go tool cover -func=c.out
 
+Additional profiles named after the flags are merged with the first,
+for example to combine the profiles of separate 'go test' runs:
+	go tool cover -func=c1.out c2.out c3.out
+
 Finally, to generate modified source code with coverage annotations
 (what go test -cover does):
 	go tool cover -mode=set -var=CoverageVariableName program.go
@@ -57,7 +61,7 @@ var (
 	funcOut = flag.String("func", "", "output coverage profile information for each function")
 )
 
-var profile string // The profile to read; the value of -html or -func
+var profiles []string // The profiles to read; the value of -html or -func and any arguments
 
 var counterStmt func(*File, ast.Expr) ast.Stmt
 
@@ -90,9 +94,9 @@ func main() {
 
 	// Output HTML or function coverage information.
 	if *htmlOut != "" {
-		err = htmlOutput(profile, *output)
+		err = htmlOutput(profiles, *output)
 	} else {
-		err = funcOutput(profile, *output)
+		err = funcOutput(profiles, *output)
 	}
 
 	if err != nil {
@@ -101,9 +105,9 @@ func main() {
 	}
 }
 
-// parseFlags sets the profile and counterStmt globals and performs validations.
+// parseFlags sets the profiles and counterStmt globals and performs validations.
 func parseFlags() error {
-	profile = *htmlOut
+	profile := *htmlOut
 	if *funcOut != "" {
 		if profile != "" {
 			return fmt.Errorf("too many options")
@@ -133,7 +137,8 @@ func parseFlags() error {
 		} else if flag.NArg() == 1 {
 			return nil
 		}
-	} else if flag.NArg() == 0 {
+	} else {
+		profiles = append([]string{profile}, flag.Args()...)
 		return nil
 	}
 	return fmt.Errorf("too many arguments")
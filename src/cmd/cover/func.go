@@ -18,8 +18,8 @@ import (
 	"text/tabwriter"
 )
 
-// funcOutput takes two file names as arguments, a coverage profile to read as input and an output
-// file to write ("" means to write to standard output). The function reads the profile and produces
+// funcOutput takes the names of the coverage profiles to read as input and an output
+// file to write ("" means to write to standard output). The function reads the profiles and produces
 // as output the coverage data broken down by function, like this:
 //
 //	fmt/format.go:30:	init			100.0%
@@ -30,8 +30,8 @@ import (
 //	fmt/scan.go:1119:	doScanf			96.8%
 //	total:		(statements)			91.9%
 
-func funcOutput(profile, outputFile string) error {
-	profiles, err := ParseProfiles(profile)
+func funcOutput(profileFiles []string, outputFile string) error {
+	profiles, err := ParseProfiles(profileFiles...)
 	if err != nil {
 		return err
 	}
@@ -18,11 +18,11 @@ import (
 	"runtime"
 )
 
-// htmlOutput reads the profile data from profile and generates an HTML
-// coverage report, writing it to outfile. If outfile is empty,
+// htmlOutput reads and merges the profile data from profileFiles and generates
+// an HTML coverage report, writing it to outfile. If outfile is empty,
 // it writes the report to a temporary file and opens it in a web browser.
-func htmlOutput(profile, outfile string) error {
-	profiles, err := ParseProfiles(profile)
+func htmlOutput(profileFiles []string, outfile string) error {
+	profiles, err := ParseProfiles(profileFiles...)
 	if err != nil {
 		return err
 	}
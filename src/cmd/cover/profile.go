@@ -39,36 +39,69 @@ func (p byFileName) Len() int           { return len(p) }
 func (p byFileName) Less(i, j int) bool { return p[i].FileName < p[j].FileName }
 func (p byFileName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 
-// ParseProfiles parses profile data in the specified file and returns a
-// Profile for each source file described therein.
-func ParseProfiles(fileName string) ([]*Profile, error) {
+// ParseProfiles parses profile data in the specified files and returns a
+// Profile for each source file described therein. Blocks that appear in
+// more than one profile, or more than once in the same profile, as they do
+// when several test binaries cover the same package, are merged into one:
+// in set mode a merged block is covered if any of its instances is, and in
+// count and atomic mode the counts are added. All the files must have been
+// generated with the same coverage mode.
+func ParseProfiles(fileNames ...string) ([]*Profile, error) {
+	files := make(map[string]*Profile)
+	mode := ""
+	for _, fileName := range fileNames {
+		m, err := parseProfile(fileName, files)
+		if err != nil {
+			return nil, err
+		}
+		if mode != "" && m != mode {
+			return nil, fmt.Errorf("%s: inconsistent coverage mode %q, expected %q", fileName, m, mode)
+		}
+		mode = m
+	}
+	for _, p := range files {
+		sort.Stable(blocksByStart(p.Blocks))
+		if err := p.mergeBlocks(); err != nil {
+			return nil, err
+		}
+	}
+	// Generate a sorted slice.
+	profiles := make([]*Profile, 0, len(files))
+	for _, profile := range files {
+		profiles = append(profiles, profile)
+	}
+	sort.Sort(byFileName(profiles))
+	return profiles, nil
+}
+
+// parseProfile parses the profile data in fileName, adding the blocks
+// it describes to the profiles in files. It returns the coverage mode.
+func parseProfile(fileName string, files map[string]*Profile) (mode string, err error) {
 	pf, err := os.Open(fileName)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	defer pf.Close()
 
-	files := make(map[string]*Profile)
 	buf := bufio.NewReader(pf)
 	// First line is "mode: foo", where foo is "set", "count", or "atomic".
 	// Rest of file is in the format
 	//	encoding/base64/base64.go:34.44,37.40 3 1
 	// where the fields are: name.go:line.column,line.column numberOfStatements count
 	s := bufio.NewScanner(buf)
-	mode := ""
 	for s.Scan() {
 		line := s.Text()
 		if mode == "" {
 			const p = "mode: "
 			if !strings.HasPrefix(line, p) || line == p {
-				return nil, fmt.Errorf("bad mode line: %v", line)
+				return "", fmt.Errorf("bad mode line: %v", line)
 			}
 			mode = line[len(p):]
 			continue
 		}
 		m := lineRe.FindStringSubmatch(line)
 		if m == nil {
-			return nil, fmt.Errorf("line %q doesn't match expected format: %v", m, lineRe)
+			return "", fmt.Errorf("line %q doesn't match expected format: %v", line, lineRe)
 		}
 		fn := m[1]
 		p := files[fn]
@@ -89,18 +122,42 @@ func ParseProfiles(fileName string) ([]*Profile, error) {
 		})
 	}
 	if err := s.Err(); err != nil {
-		return nil, err
+		return "", err
 	}
-	for _, p := range files {
-		sort.Sort(blocksByStart(p.Blocks))
+	if mode == "" {
+		return "", fmt.Errorf("%s: missing mode line", fileName)
 	}
-	// Generate a sorted slice.
-	profiles := make([]*Profile, 0, len(files))
-	for _, profile := range files {
-		profiles = append(profiles, profile)
+	return mode, nil
+}
+
+// mergeBlocks merges the blocks of p, which must be sorted by start
+// position, that describe the same section of source code.
+func (p *Profile) mergeBlocks() error {
+	j := 0
+	for i, b := range p.Blocks {
+		if i > 0 {
+			last := &p.Blocks[j-1]
+			if b.StartLine == last.StartLine && b.StartCol == last.StartCol &&
+				b.EndLine == last.EndLine && b.EndCol == last.EndCol {
+				if b.NumStmt != last.NumStmt {
+					return fmt.Errorf("%s:%d.%d,%d.%d: inconsistent number of statements: %d and %d",
+						p.FileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol, last.NumStmt, b.NumStmt)
+				}
+				if p.Mode == "set" {
+					if b.Count != 0 {
+						last.Count = 1
+					}
+				} else {
+					last.Count += b.Count
+				}
+				continue
+			}
+		}
+		p.Blocks[j] = b
+		j++
 	}
-	sort.Sort(byFileName(profiles))
-	return profiles, nil
+	p.Blocks = p.Blocks[:j]
+	return nil
 }
 
 type blocksByStart []ProfileBlock
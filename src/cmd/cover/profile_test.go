@@ -0,0 +1,127 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeProfiles writes each of the profile contents to a file in dir
+// and returns the file names.
+func writeProfiles(t *testing.T, dir string, contents ...string) []string {
+	var names []string
+	for i, c := range contents {
+		name := filepath.Join(dir, "c"+string('0'+i)+".out")
+		if err := ioutil.WriteFile(name, []byte(c), 0666); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+var mergeTests = []struct {
+	name     string
+	profiles []string
+	want     []*Profile
+}{
+	{
+		name: "set",
+		profiles: []string{
+			"mode: set\n" +
+				"p/a.go:1.1,2.2 1 0\n" +
+				"p/a.go:3.1,4.2 2 1\n",
+			"mode: set\n" +
+				"p/a.go:3.1,4.2 2 0\n" +
+				"p/a.go:1.1,2.2 1 1\n" +
+				"p/b.go:1.1,2.2 1 0\n",
+		},
+		want: []*Profile{
+			{FileName: "p/a.go", Mode: "set", Blocks: []ProfileBlock{
+				{1, 1, 2, 2, 1, 1},
+				{3, 1, 4, 2, 2, 1},
+			}},
+			{FileName: "p/b.go", Mode: "set", Blocks: []ProfileBlock{
+				{1, 1, 2, 2, 1, 0},
+			}},
+		},
+	},
+	{
+		name: "count",
+		profiles: []string{
+			"mode: count\n" +
+				"p/a.go:1.1,2.2 1 3\n" +
+				"p/a.go:3.1,4.2 2 0\n",
+			"mode: count\n" +
+				"p/a.go:1.1,2.2 1 4\n" +
+				"p/a.go:3.1,4.2 2 5\n",
+		},
+		want: []*Profile{
+			{FileName: "p/a.go", Mode: "count", Blocks: []ProfileBlock{
+				{1, 1, 2, 2, 1, 7},
+				{3, 1, 4, 2, 2, 5},
+			}},
+		},
+	},
+	{
+		name: "atomic within one profile",
+		profiles: []string{
+			"mode: atomic\n" +
+				"p/a.go:1.1,2.2 1 3\n" +
+				"p/a.go:1.1,2.2 1 2\n",
+		},
+		want: []*Profile{
+			{FileName: "p/a.go", Mode: "atomic", Blocks: []ProfileBlock{
+				{1, 1, 2, 2, 1, 5},
+			}},
+		},
+	},
+}
+
+func TestParseProfilesMerge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range mergeTests {
+		files := writeProfiles(t, dir, tt.profiles...)
+		got, err := ParseProfiles(files...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseProfiles =", tt.name)
+			for _, p := range got {
+				t.Errorf("\t%+v", *p)
+			}
+		}
+	}
+}
+
+func TestParseProfilesErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, profiles := range [][]string{
+		{"mode: set\np/a.go:1.1,2.2 1 1\n", "mode: count\np/a.go:1.1,2.2 1 1\n"},
+		{"mode: count\np/a.go:1.1,2.2 1 1\n", "mode: count\np/a.go:1.1,2.2 2 1\n"},
+		{"mode: set\np/a.go:1.1,2.2 1 1\n", ""},
+	} {
+		files := writeProfiles(t, dir, profiles...)
+		if _, err := ParseProfiles(files...); err == nil {
+			t.Errorf("ParseProfiles(%q) succeeded, want error", profiles)
+		}
+	}
+}
@@ -24,6 +24,7 @@ The commands are:
 	get         download and install packages and dependencies
 	install     compile and install packages and dependencies
 	list        list packages
+	mod         module maintenance
 	run         compile and run Go program
 	test        test packages
 	tool        run specified go tool
@@ -36,10 +37,12 @@ Additional help topics:
 
 	c           calling between Go and C
 	buildmode   description of build modes
+	cache       build and test caching
 	filetype    file types
 	gopath      GOPATH environment variable
 	environment environment variables
 	importpath  import path syntax
+	modules     modules, module versions, and more
 	packages    description of package lists
 	testflag    description of testing flags
 	testfunc    description of testing functions
@@ -80,7 +83,8 @@ The build flags are shared by the build, clean, get, install, list, run,
 and test commands:
 
 	-a
-		force rebuilding of packages that are already up-to-date.
+		force rebuilding of packages that are already up-to-date,
+		without reusing results from the build cache.
 	-n
 		print the commands but do not run them.
 	-p n
@@ -125,6 +129,9 @@ and test commands:
 	-linkshared
 		link against shared libraries previously created with
 		-buildmode=shared.
+	-mod mode
+		module download mode to use: readonly or vendor.
+		See 'go help modules' for more.
 	-pkgdir dir
 		install and load all packages from dir instead of the usual locations.
 		For example, when building with a non-standard configuration,
@@ -137,6 +144,14 @@ and test commands:
 		a program to use to invoke toolchain programs like vet and asm.
 		For example, instead of running asm, the go command will run
 		'cmd args /path/to/asm <arguments for asm>'.
+	-trimpath
+		remove all file system paths from the resulting executable.
+		Instead of absolute file system paths, the recorded file names
+		will begin with the import path of the package containing the
+		file, so that the output does not depend on where GOROOT,
+		GOPATH or the work directory are located. The install suffix
+		is automatically set to trimpath or, if set explicitly, has
+		_trimpath appended to it. Supported only by the gc toolchain.
 
 The list flags accept a space-separated list of strings. To embed spaces
 in an element in the list, surround it with either single or double quotes.
@@ -160,7 +175,7 @@ Remove object files
 
 Usage:
 
-	go clean [-i] [-r] [-n] [-x] [build flags] [packages]
+	go clean [-i] [-r] [-n] [-x] [-cache] [-fuzzcache] [build flags] [packages]
 
 Clean removes object files from package source directories.
 The go command builds most objects in a temporary directory,
@@ -198,6 +213,14 @@ dependencies of the packages named by the import paths.
 
 The -x flag causes clean to print remove commands as it executes them.
 
+The -cache flag causes clean to remove the entire go build cache,
+in addition to cleaning specified packages (if any).
+See 'go help cache' for details.
+
+The -fuzzcache flag causes clean to remove the inputs generated by
+'go test -fuzz' that are kept in the go build cache. The failing inputs
+written to testdata directories are not removed.
+
 For more about build flags, see 'go help build'.
 
 For more about specifying packages, see 'go help packages'.
@@ -602,6 +625,9 @@ syntax of package template.  The default output is equivalent to -f
         TestImports  []string // imports from TestGoFiles
         XTestGoFiles []string // _test.go files outside package
         XTestImports []string // imports from XTestGoFiles
+
+        // Module information, in module mode
+        Module *Module // module providing the package, if any
     }
 
 The error information, if any, is
@@ -612,6 +638,17 @@ The error information, if any, is
         Err           string   // the error itself
     }
 
+The module information, if any, is
+
+    type Module struct {
+        Path    string  // module path
+        Version string  // module version
+        Replace *Module // replaced by this module
+        Main    bool    // is this the main module?
+        Dir     string  // directory holding files for this module, if any
+        GoMod   string  // path to go.mod file for this module, if any
+    }
+
 The template function "join" calls strings.Join.
 
 The template function "context" returns the build context, defined as:
@@ -650,6 +687,57 @@ For more about build flags, see 'go help build'.
 For more about specifying packages, see 'go help packages'.
 
 
+Module maintenance
+
+Usage:
+
+	go mod command [arguments]
+
+Mod provides access to operations on modules.
+See 'go help modules' for an overview of module support.
+
+The commands are:
+
+	go mod init [module]
+		Initialize a new module in the current directory by writing
+		a go.mod file. The module path argument may be omitted for
+		a directory inside GOPATH, whose import path is then used.
+
+	go mod tidy [-v]
+		Make go.mod match the source code of the main module: add any
+		modules needed to provide the packages imported by the main
+		module's packages and tests, at their latest versions, and
+		remove requirements on modules that provide no such packages.
+		It also removes unused entries from go.sum.
+		The -v flag prints the modules added and removed.
+
+	go mod vendor [-v]
+		Copy the packages needed to build and test the main module's
+		packages into its vendor directory, for use with -mod=vendor.
+		The -v flag prints the vendored modules and packages.
+
+	go mod download [-json] [modules]
+		Download the named modules, given as path or path@version,
+		into the module cache. With no arguments, download all the
+		modules in the build list. The -json flag prints a JSON
+		object for each module:
+
+			type Module struct {
+				Path     string // module path
+				Version  string // module version
+				Error    string // error loading module
+				GoMod    string // absolute path to cached .mod file
+				Zip      string // absolute path to cached .zip file
+				Dir      string // absolute path to cached source root directory
+				Sum      string // checksum for path, version (as in go.sum)
+				GoModSum string // checksum for go.mod (as in go.sum)
+			}
+
+Tidy and vendor consider every Go source file in a package, regardless
+of build constraints other than "+build ignore", so that their results
+do not depend on the operating system or architecture where they run.
+
+
 Compile and run Go program
 
 Usage:
@@ -727,6 +815,13 @@ In addition to the build flags, the flags handled by 'go test' itself are:
 	    Install packages that are dependencies of the test.
 	    Do not run the test.
 
+	-json
+	    Convert test output to JSON suitable for automated processing.
+	    The test binary is run with -test.v, and each package's output
+	    is reported as a stream of events, one per line, giving the
+	    package, test name, action, elapsed time and time stamp.
+	    See 'go doc test2json' for the encoding details.
+
 	-o file
 	    Compile the test binary to the named file.
 	    The test still runs (unless -c or -i is specified).
@@ -759,16 +854,38 @@ Print Go version
 
 Usage:
 
-	go version
+	go version [-m] [-v] [file ...]
+
+Version prints the build information for Go executables.
+
+Go version reports the Go version used to build each of the named
+executable files.
+
+If no files are named on the command line, go version prints its own
+version information, as reported by runtime.Version.
 
-Version prints the Go version, as reported by runtime.Version.
+If a directory is named, go version walks that directory, recursively,
+looking for recognized Go binaries and reporting their versions.
+By default, go version does not report unrecognized files found
+during a directory scan. The -v flag causes it to report unrecognized files.
+
+The -m flag causes go version to print each executable's embedded
+build information: the main package, the repositories or modules
+providing the packages linked into it, and the build settings, such
+as GOOS and the version control revision of the main package.
+In the output, the build information is shown as multiple lines
+following the version line, each indented by a leading tab character.
+See 'go doc runtime/debug.BuildInfo' for the format.
+
+Go version reads ELF, Mach-O and PE executables, as well as the GOFF
+object files written by the linker for z/OS. It does not run them.
 
 
 Run go tool vet on packages
 
 Usage:
 
-	go vet [-n] [-x] [build flags] [packages]
+	go vet [-n] [-x] [-vettool prog] [build flags] [packages]
 
 Vet runs the Go vet command on the packages named by the import paths.
 
@@ -780,6 +897,11 @@ To run the vet tool with specific options, run 'go tool vet'.
 The -n flag prints commands that would be executed.
 The -x flag prints commands as they are executed.
 
+The -vettool=prog flag selects a different analysis tool with
+alternative or additional checks, such as a program built with the
+go/analysis/multichecker package. The tool is given the files of
+each package in the same way as 'go tool vet'.
+
 For more about build flags, see 'go help build'.
 
 See also: go fmt, go fix.
@@ -846,6 +968,48 @@ are:
 		main are ignored.
 
 
+Build and test caching
+
+The go command caches build outputs for reuse in future builds.
+The default location for cache data is a subdirectory named go-build
+in the standard user cache directory for the current operating system.
+Setting the GOCACHE environment variable overrides this default,
+and running 'go env GOCACHE' prints the current cache directory.
+Setting GOCACHE=off disables the cache.
+
+The cache is content-addressed: each entry is keyed by a hash of
+everything that went into producing it, including the source files,
+the compiler, assembler and linker binaries, the build flags, the
+target GOOS and GOARCH, and the contents of the compiled dependencies.
+Because the key does not depend on file modification times,
+switching between branches or build tags, or alternating between
+GOOS/GOARCH combinations, reuses the packages and binaries already
+built for the same inputs instead of rebuilding them from scratch.
+Packages that use cgo or SWIG, packages built for coverage analysis,
+and builds using -compiler=gccgo or -toolexec are never cached.
+
+The go command also caches successful package test results.
+When 'go test' would run a test binary whose contents, arguments,
+environment, package directory and testdata directory are all
+unchanged since a previous passing run, it redisplays the previous
+output instead of running the binary again, and prints "(cached)"
+in place of the elapsed time in the summary line. Only runs that use
+the test flags -cpu, -parallel, -run, -short, -timeout and -v are
+cached; by convention, -count=1 is the idiomatic way to disable
+test caching explicitly. Tests that depend on files outside the
+package directory or on external resources should be run that way.
+
+The cache is trimmed automatically, at most once an hour, by removing
+the least recently used entries until it is smaller than the limit set
+by the GOCACHESIZE environment variable. The limit is a byte count with
+an optional K, M or G suffix; the default is 2G.
+The 'go clean -cache' command removes all cached data.
+
+The fuzz directory of the cache holds the inputs that 'go test -fuzz'
+found to expand coverage, so that later fuzzing picks up where it left
+off. It is not trimmed; the 'go clean -fuzzcache' command removes it.
+
+
 File types
 
 The go command examines the contents of a restricted set of files
@@ -1042,16 +1206,29 @@ General-purpose environment variables:
 
 	GCCGO
 		The gccgo command to run for 'go build -compiler=gccgo'.
+	GO111MODULE
+		Controls whether the go command runs in module mode:
+		on, off, or auto. See 'go help modules'.
 	GOARCH
 		The architecture, or processor, for which to compile code.
 		Examples are amd64, 386, arm, ppc64.
 	GOBIN
 		The directory where 'go install' will install a command.
+	GOCACHE
+		The directory where the go command will store cached
+		information for reuse in future builds.
+		See 'go help cache'.
+	GOCACHESIZE
+		The size limit of the build cache.
+		See 'go help cache'.
 	GOOS
 		The operating system for which to compile code.
 		Examples are linux, darwin, windows, netbsd.
 	GOPATH
 		See 'go help gopath'.
+	GOPROXY
+		The module proxy to download modules from, or off.
+		See 'go help modules'.
 	GORACE
 		Options for the race detector.
 		See https://golang.org/doc/articles/race_detector.html.
@@ -1279,6 +1456,102 @@ comments.
 See https://golang.org/s/go14customimport for details.
 
 
+Modules, module versions, and more
+
+A module is a collection of related Go packages that are versioned
+together as a single unit. Modules record precise dependency requirements
+and create reproducible builds.
+
+A module is defined by a tree of Go source files with a go.mod file
+in the tree's root directory. The go.mod file declares the module path,
+which is the import path prefix for all the packages in the module,
+and the minimum versions of the other modules it requires:
+
+	module example.com/hello
+
+	require (
+		example.com/greet v1.2.0
+		example.com/util v0.3.1 // indirect
+	)
+
+	replace example.com/util => ../util
+
+Each require directive names a module path and a semantic version,
+such as v1.2.0 or v2.0.0-beta.1. A module path ending in a major
+version suffix such as /v2 must be required at a version with that
+major version. The "// indirect" comment marks requirements of modules
+that provide no packages imported directly by the main module.
+A replace directive substitutes another module version, or a directory
+containing a go.mod file, for the given module, or for one version of it.
+Replacements apply only in the main module's go.mod file.
+
+Module mode
+
+The go command works in module mode when the GO111MODULE environment
+variable is set to "on", or when it is unset or "auto" and a go.mod
+file is found in the current directory or one of its parents.
+The module containing that go.mod file is the main module.
+Setting GO111MODULE=off selects the GOPATH mode described in
+'go help gopath' even if there is a go.mod file.
+
+In module mode, an import path is resolved to a package in the standard
+library, in the main module, or in exactly one of the modules in the
+build list; GOPATH/src and vendor directories are not consulted.
+The build list is computed by minimal version selection: starting from
+the requirements of the main module, it includes every module reachable
+through the requirements in the go.mod files of the required module
+versions, each at the maximum of the versions required for it.
+Because the selected versions depend only on the go.mod files,
+and not on when the build happens or which versions have since been
+published, the same go.mod files always produce the same build list.
+
+Module downloads and verification
+
+The go command downloads the module versions in the build list as
+needed, into the module cache in $GOPATH/pkg/mod, and afterward works
+from the cache without network access. Module versions are fetched from
+the module proxy named by the GOPROXY environment variable, which may be:
+
+	off
+		Use only the module cache. This is the default.
+	file:///path/to/proxy, /path/to/proxy
+		Read module versions from a local directory, laid out like
+		$GOPATH/pkg/mod/cache/download. That directory of one machine's
+		module cache can therefore serve as GOPROXY for another.
+	https://host/path
+		Read module versions from a module proxy server.
+
+The proxy directory for a module path holds, in the subdirectory @v,
+a list file listing the known versions one per line, and for each
+version v a v.mod file holding its go.mod file and a v.zip file holding
+its source tree, in which every file name starts with path@v/.
+Upper-case letters in paths and versions are written as an exclamation
+mark followed by the lower-case letter.
+
+The go.sum file next to go.mod records the expected cryptographic
+hashes of the content of each module version and of each go.mod
+file used in the build. When the go command downloads a module or
+finds it in the cache, it checks its hash against go.sum and reports
+a checksum mismatch if they differ. The hashes of modules not yet in
+go.sum are added to it. Both go.mod and go.sum should be checked into
+version control. Hashes cover the file contents exactly as stored in
+the module zip files, so builds on different operating systems, such
+as Linux and z/OS, verify against the same go.sum.
+
+Vendoring
+
+The 'go mod vendor' command copies the packages needed to build and
+test the main module into its vendor directory, along with a
+vendor/modules.txt file recording the module versions they came from.
+Building with the -mod=vendor flag then uses only the main module and
+the vendor directory, without consulting the build list or the module
+cache. The -mod=readonly flag instead makes the go command fail rather
+than add missing hashes to go.sum.
+
+See 'go help mod' for the commands that maintain go.mod, go.sum and
+the module cache.
+
+
 Description of package lists
 
 Many commands apply to a set of packages:
@@ -1361,7 +1634,10 @@ The following flags are recognized by the 'go test' command and
 control the execution of any test:
 
 	-bench regexp
-	    Run benchmarks matching the regular expression.
+	    Run (sub)benchmarks matching a regular expression.
+	    The given regular expression is split into smaller ones by
+	    top-level '/', where each must match the corresponding part of a
+	    benchmark's identifier.
 	    By default, no benchmarks run. To run all benchmarks,
 	    use '-bench .' or '-bench=.'.
 
@@ -1406,14 +1682,19 @@ control the execution of any test:
 			significantly more expensive.
 	    Sets -cover.
 
-	-coverpkg pkg1,pkg2,pkg3
-	    Apply coverage analysis in each test to the given list of packages.
+	-coverpkg pattern1,pattern2,pattern3
+	    Apply coverage analysis in each test to packages matching the patterns.
 	    The default is for each test to analyze only the package being tested.
-	    Packages are specified as import paths.
+	    See 'go help packages' for a description of package patterns.
+	    A pattern containing '...', or one of 'all', 'std' and 'cmd', selects
+	    only the matching packages that the tests depend on; a single import
+	    path selects that package even if no test depends on it.
 	    Sets -cover.
 
 	-coverprofile cover.out
 	    Write a coverage profile to the file after all tests have passed.
+	    When testing multiple packages, the profiles of all the test binaries
+	    are merged into the one file. See 'go tool cover -help'.
 	    Sets -cover.
 
 	-cpu 1,2,4
@@ -1425,6 +1706,23 @@ control the execution of any test:
 	    Write a CPU profile to the specified file before exiting.
 	    Writes test binary as -c would.
 
+	-fuzz regexp
+	    After running the tests, fuzz the fuzz test matching the regular
+	    expression: run its fuzz target with inputs generated by mutating
+	    the seed corpus, guided by coverage instrumentation of the package
+	    being tested. The regular expression must match exactly one fuzz
+	    test, and only one package may be tested.
+	    Inputs that expand coverage are kept in the build cache
+	    (see 'go help cache'), and an input that makes the fuzz target
+	    fail is written to testdata/fuzz/FuzzXXX in the package directory,
+	    where it becomes part of the seed corpus.
+	    See 'go help testfunc'.
+
+	-fuzztime t
+	    Fuzz for the duration t, specified as a time.Duration
+	    (for example, -fuzztime 1h30s). The default is to fuzz
+	    until a failure is found.
+
 	-memprofile mem.out
 	    Write a memory profile to the file after all tests have passed.
 	    Writes test binary as -c would.
@@ -1435,6 +1733,19 @@ control the execution of any test:
 	    To profile all memory allocations, use -test.memprofilerate=1
 	    and pass --alloc_space flag to the pprof tool.
 
+	-mutexprofile mutex.out
+	    Write a mutex contention profile to the specified file
+	    when all tests are complete.
+	    Writes test binary as -c would.
+
+	-mutexprofilefraction n
+	    Sample 1 in n stack traces of goroutines holding a
+	    contended mutex.
+	    See 'go doc runtime.SetMutexProfileFraction'.
+	    By default, if -test.mutexprofile is set without this flag,
+	    all contention events are recorded, equivalent to
+	    -test.mutexprofilefraction=1.
+
 	-outputdir directory
 	    Place output files from profiling in the specified directory,
 	    by default the directory in which "go test" is running.
@@ -1449,8 +1760,11 @@ control the execution of any test:
 	    (see 'go help build').
 
 	-run regexp
-	    Run only those tests and examples matching the regular
-	    expression.
+	    Run only those tests and examples matching the regular expression.
+	    For tests the regular expression is split into smaller ones by
+	    top-level '/', where each must match the corresponding part of a
+	    test's identifier. For example, '-run TestFoo/bar' runs only the
+	    subtests of TestFoo whose names match bar.
 
 	-short
 	    Tell long-running tests to shorten their run time.
@@ -1543,6 +1857,28 @@ A benchmark function is one named BenchmarkXXX and should have the signature,
 
 	func BenchmarkXXX(b *testing.B) { ... }
 
+Test and benchmark functions may call the Run method of *testing.T or
+*testing.B to start subtests and sub-benchmarks. Their names are formed by
+joining the name of the enclosing function and the names passed to Run
+with slashes, and their results are reported indented beneath their parent.
+
+A fuzz test is one named FuzzXXX and should have the signature,
+
+	func FuzzXXX(f *testing.F) { ... }
+
+It adds seed inputs with f.Add and passes its fuzz target to f.Fuzz:
+
+	func FuzzParse(f *testing.F) {
+		f.Add([]byte("seed input"))
+		f.Fuzz(func(t *testing.T, data []byte) {
+			Parse(data)
+		})
+	}
+
+Normally the fuzz target runs as a subtest for each seed input and for
+each file in the package's testdata/fuzz/FuzzXXX directory. The -fuzz
+flag runs it with generated inputs instead (see 'go help testflag').
+
 An example function is similar to a test function but, instead of using
 *testing.T to report success or failure, prints output to os.Stdout.
 That output is compared against the function's "Output:" comment, which
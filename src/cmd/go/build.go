@@ -59,7 +59,8 @@ The build flags are shared by the build, clean, get, install, list, run,
 and test commands:
 
 	-a
-		force rebuilding of packages that are already up-to-date.
+		force rebuilding of packages that are already up-to-date,
+		without reusing results from the build cache.
 	-n
 		print the commands but do not run them.
 	-p n
@@ -104,6 +105,9 @@ and test commands:
 	-linkshared
 		link against shared libraries previously created with
 		-buildmode=shared.
+	-mod mode
+		module download mode to use: readonly or vendor.
+		See 'go help modules' for more.
 	-pkgdir dir
 		install and load all packages from dir instead of the usual locations.
 		For example, when building with a non-standard configuration,
@@ -116,6 +120,14 @@ and test commands:
 		a program to use to invoke toolchain programs like vet and asm.
 		For example, instead of running asm, the go command will run
 		'cmd args /path/to/asm <arguments for asm>'.
+	-trimpath
+		remove all file system paths from the resulting executable.
+		Instead of absolute file system paths, the recorded file names
+		will begin with the import path of the package containing the
+		file, so that the output does not depend on where GOROOT,
+		GOPATH or the work directory are located. The install suffix
+		is automatically set to trimpath or, if set explicitly, has
+		_trimpath appended to it. Supported only by the gc toolchain.
 
 The list flags accept a space-separated list of strings. To embed spaces
 in an element in the list, surround it with either single or double quotes.
@@ -177,6 +189,7 @@ var buildToolExec []string   // -toolexec flag
 var buildBuildmode string    // -buildmode flag
 var buildLinkshared bool     // -linkshared flag
 var buildPkgdir string       // -pkgdir flag
+var buildTrimpath bool       // -trimpath flag
 
 var buildContext = build.Default
 var buildToolchain toolchain = noToolchain{}
@@ -230,11 +243,13 @@ func addBuildFlags(cmd *Command) {
 	cmd.Flag.StringVar(&buildContext.InstallSuffix, "installsuffix", "", "")
 	cmd.Flag.Var((*stringsFlag)(&buildLdflags), "ldflags", "")
 	cmd.Flag.BoolVar(&buildLinkshared, "linkshared", false, "")
+	cmd.Flag.StringVar(&modMode, "mod", "", "")
 	cmd.Flag.StringVar(&buildPkgdir, "pkgdir", "", "")
 	cmd.Flag.BoolVar(&buildRace, "race", false, "")
 	cmd.Flag.BoolVar(&buildMSan, "msan", false, "")
 	cmd.Flag.Var((*stringsFlag)(&buildContext.BuildTags), "tags", "")
 	cmd.Flag.Var((*stringsFlag)(&buildToolExec), "toolexec", "")
+	cmd.Flag.BoolVar(&buildTrimpath, "trimpath", false, "")
 	cmd.Flag.BoolVar(&buildWork, "work", false, "")
 }
 
@@ -431,6 +446,15 @@ func buildModeInit() {
 		}
 		buildContext.InstallSuffix += codegenArg[1:]
 	}
+	if buildTrimpath {
+		if gccgo {
+			fatalf("-trimpath is not supported by gccgo")
+		}
+		if buildContext.InstallSuffix != "" {
+			buildContext.InstallSuffix += "_"
+		}
+		buildContext.InstallSuffix += "trimpath"
+	}
 }
 
 func runBuild(cmd *Command, args []string) {
@@ -584,6 +608,9 @@ func runInstall(cmd *Command, args []string) {
 	for _, p := range pkgs {
 		if p.Target == "" && (!p.Standard || p.ImportPath != "unsafe") {
 			switch {
+			case p.Module != nil && p.Name != "main":
+				// Module packages are built as needed; only commands
+				// are installed.
 			case p.gobinSubdir:
 				errorf("go install: cannot install cross-compiled binaries when GOBIN is set")
 			case p.cmdline:
@@ -984,8 +1011,8 @@ func (b *builder) action1(mode buildMode, depMode buildMode, p *Package, looksha
 		return a
 	}
 
-	if p.local && p.target == "" {
-		// Imported via local path.  No permanent target.
+	if (p.local || p.Module != nil) && p.target == "" {
+		// Imported via local path or from a module.  No permanent target.
 		mode = modeBuild
 	}
 	work := p.pkgdir
@@ -1379,6 +1406,18 @@ func (b *builder) build(a *action) (err error) {
 		}
 	}
 
+	// Reuse a previously built archive with the same inputs, if any.
+	if id, ok := b.archiveCacheID(a); ok {
+		if b.getCached(a, id, a.objpkg, 0666) {
+			return b.link(a, nil)
+		}
+		defer func() {
+			if err == nil {
+				b.putCached(id, a.objpkg)
+			}
+		}()
+	}
+
 	var gofiles, cgofiles, cfiles, sfiles, cxxfiles, objects, cgoObjects, pcCFLAGS, pcLDFLAGS []string
 
 	gofiles = append(gofiles, a.p.GoFiles...)
@@ -1561,17 +1600,34 @@ func (b *builder) build(a *action) (err error) {
 		}
 	}
 
-	// Link if needed.
-	if a.link {
-		// The compiler only cares about direct imports, but the
-		// linker needs the whole dependency tree.
-		all := actionList(a)
-		all = all[:len(all)-1] // drop a
-		if err := buildToolchain.ld(b, a, a.target, all, a.objpkg, objects); err != nil {
-			return err
-		}
+	return b.link(a, objects)
+}
+
+// link links the executable for a, if a is a link action.
+// The objects are the non-Go object files packed into a.objpkg.
+func (b *builder) link(a *action, objects []string) error {
+	if !a.link {
+		return nil
 	}
 
+	a.p.buildInfo = b.buildInfo(a)
+
+	// Reuse a previously linked executable with the same inputs, if any.
+	id, cached := b.linkCacheID(a)
+	if cached && b.getCached(a, id, a.target, 0777) {
+		return nil
+	}
+
+	// The compiler only cares about direct imports, but the
+	// linker needs the whole dependency tree.
+	all := actionList(a)
+	all = all[:len(all)-1] // drop a
+	if err := buildToolchain.ld(b, a, a.target, all, a.objpkg, objects); err != nil {
+		return err
+	}
+	if cached {
+		b.putCached(id, a.target)
+	}
 	return nil
 }
 
@@ -2161,6 +2217,18 @@ func joinUnambiguously(a []string) string {
 }
 
 // mkdir makes the named directory.
+// writeFile writes the text to file, showing the write as a command
+// with -n and -x.
+func (b *builder) writeFile(file string, text []byte) error {
+	if buildN || buildX {
+		b.showcmd("", "cat >%s << 'EOF' # internal\n%sEOF", file, text)
+	}
+	if buildN {
+		return nil
+	}
+	return ioutil.WriteFile(file, text, 0666)
+}
+
 func (b *builder) mkdir(dir string) error {
 	b.exec.Lock()
 	defer b.exec.Unlock()
@@ -2308,7 +2376,7 @@ func (gcToolchain) gc(b *builder, p *Package, archive, obj string, asmhdr bool,
 	extFiles := len(p.CgoFiles) + len(p.CFiles) + len(p.CXXFiles) + len(p.MFiles) + len(p.SFiles) + len(p.SysoFiles) + len(p.SwigFiles) + len(p.SwigCXXFiles)
 	if p.Standard {
 		switch p.ImportPath {
-		case "bytes", "net", "os", "runtime/pprof", "sync", "time":
+		case "bytes", "net", "os", "runtime/pprof", "runtime/trace", "sync", "time":
 			extFiles++
 		}
 	}
@@ -2330,7 +2398,7 @@ func (gcToolchain) gc(b *builder, p *Package, archive, obj string, asmhdr bool,
 		}
 	}
 
-	args := []interface{}{buildToolExec, tool("compile"), "-o", ofile, "-trimpath", b.work, buildGcflags, gcargs, "-D", p.localPrefix, importArgs}
+	args := []interface{}{buildToolExec, tool("compile"), "-o", ofile, "-trimpath", b.trimpath(p), buildGcflags, gcargs, "-D", p.localPrefix, importArgs}
 	if ofile == archive {
 		args = append(args, "-pack")
 	}
@@ -2349,13 +2417,25 @@ func (gcToolchain) asm(b *builder, p *Package, obj, ofile, sfile string) error {
 	// Add -I pkg/GOOS_GOARCH so #include "textflag.h" works in .s files.
 	inc := filepath.Join(goroot, "pkg", "include")
 	sfile = mkAbs(p.Dir, sfile)
-	args := []interface{}{buildToolExec, tool("asm"), "-o", ofile, "-trimpath", b.work, "-I", obj, "-I", inc, "-D", "GOOS_" + goos, "-D", "GOARCH_" + goarch, buildAsmflags, sfile}
+	args := []interface{}{buildToolExec, tool("asm"), "-o", ofile, "-trimpath", b.trimpath(p), "-I", obj, "-I", inc, "-D", "GOOS_" + goos, "-D", "GOARCH_" + goarch, buildAsmflags, sfile}
 	if err := b.run(p.Dir, p.ImportPath, nil, args...); err != nil {
 		return err
 	}
 	return nil
 }
 
+// trimpath returns the -trimpath argument for the compiler and assembler
+// building p. The temporary work directory is always removed from the
+// recorded file names. With go build -trimpath, the package directory
+// is also replaced by the import path of p.
+func (b *builder) trimpath(p *Package) string {
+	rewrites := b.work
+	if buildTrimpath {
+		rewrites += ";" + p.Dir + "=>" + p.ImportPath
+	}
+	return rewrites
+}
+
 // toolVerify checks that the command line args writes the same output file
 // if run using newTool instead.
 // Unused now but kept around for future use.
@@ -2525,6 +2605,13 @@ func (gcToolchain) ld(b *builder, root *action, out string, allactions []*action
 	if root.p.buildID != "" {
 		ldflags = append(ldflags, "-buildid="+root.p.buildID)
 	}
+	if root.p.buildInfo != "" {
+		file := root.objdir + "buildinfo.txt"
+		if err := b.writeFile(file, []byte(root.p.buildInfo)); err != nil {
+			return err
+		}
+		ldflags = append(ldflags, "-buildinfo="+file)
+	}
 	ldflags = append(ldflags, buildLdflags...)
 
 	// On OS X when using external linking to build a shared library,
@@ -2539,7 +2626,15 @@ func (gcToolchain) ld(b *builder, root *action, out string, allactions []*action
 		dir, out = filepath.Split(out)
 	}
 
-	return b.run(dir, root.p.ImportPath, nil, buildToolExec, tool("link"), "-o", out, importArgs, ldflags, mainpkg)
+	// With -trimpath, the file names that the compiler and assembler
+	// still record relative to $GOROOT, such as those of the headers
+	// in $GOROOT/pkg/include, are expanded to go/... by the linker.
+	var env []string
+	if buildTrimpath {
+		env = []string{"GOROOT_FINAL=go"}
+	}
+
+	return b.run(dir, root.p.ImportPath, env, buildToolExec, tool("link"), "-o", out, importArgs, ldflags, mainpkg)
 }
 
 func (gcToolchain) ldShared(b *builder, toplevelactions []*action, out string, allactions []*action) error {
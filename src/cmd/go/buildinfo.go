@@ -0,0 +1,157 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// buildInfo returns the build information that the linker records
+// in the binary built by the link action a, in the text format of
+// runtime/debug.BuildInfo. The Go version is recorded by the linker.
+func (b *builder) buildInfo(a *action) string {
+	p := a.p
+	info := &debug.BuildInfo{Path: p.ImportPath}
+	main := p
+	if p.testedPkg != nil {
+		main = p.testedPkg
+		info.Path = main.ImportPath + ".test"
+	}
+
+	if main.Module != nil {
+		b.moduleBuildInfo(info, a)
+	} else {
+		b.gopathBuildInfo(info, a, main)
+	}
+
+	appendSetting := func(key, value string) {
+		info.Settings = append(info.Settings, debug.BuildSetting{Key: key, Value: value})
+	}
+	appendSetting("-compiler", buildContext.Compiler)
+	if len(buildAsmflags) > 0 {
+		appendSetting("-asmflags", strings.Join(buildAsmflags, " "))
+	}
+	if len(buildGcflags) > 0 {
+		appendSetting("-gcflags", strings.Join(buildGcflags, " "))
+	}
+	if len(buildLdflags) > 0 {
+		appendSetting("-ldflags", strings.Join(buildLdflags, " "))
+	}
+	if buildRace {
+		appendSetting("-race", "true")
+	}
+	if buildMSan {
+		appendSetting("-msan", "true")
+	}
+	if buildTrimpath {
+		appendSetting("-trimpath", "true")
+	}
+	if len(buildContext.BuildTags) > 0 {
+		appendSetting("-tags", strings.Join(buildContext.BuildTags, ","))
+	}
+	cgo := "0"
+	if buildContext.CgoEnabled {
+		cgo = "1"
+	}
+	appendSetting("CGO_ENABLED", cgo)
+	appendSetting("GOARCH", goarch)
+	appendSetting("GOOS", goos)
+
+	// Record the revision of the repository containing the main package,
+	// but not for test binaries or programs in GOROOT.
+	if p.testedPkg == nil && !p.Goroot && !p.fake && p.build.SrcRoot != "" {
+		if vcs, root, err := vcsForDir(p); err == nil && vcs.status != nil {
+			if _, err := exec.LookPath(vcs.cmd); err == nil {
+				st, err := vcs.status(vcs, filepath.Join(p.build.SrcRoot, root))
+				if err == nil {
+					appendSetting("vcs", vcs.cmd)
+					appendSetting("vcs.revision", st.revision)
+					if !st.commitTime.IsZero() {
+						appendSetting("vcs.time", st.commitTime.Format(time.RFC3339Nano))
+					}
+					appendSetting("vcs.modified", strconv.FormatBool(st.uncommitted))
+				}
+			}
+		}
+	}
+	return info.String()
+}
+
+// gopathBuildInfo records in info the main package's repository and
+// the repositories of its dependencies, all at version "(devel)".
+func (b *builder) gopathBuildInfo(info *debug.BuildInfo, a *action, main *Package) {
+	seen := make(map[string]bool)
+	if !main.Goroot && !main.cmdline && !main.local {
+		root := repoRootPath(main)
+		info.Main = debug.Module{Path: root, Version: "(devel)"}
+		seen[root] = true
+	}
+	for _, a1 := range actionList(a) {
+		p1 := a1.p
+		if p1 == nil || p1 == a.p || p1.fake || p1.Goroot {
+			continue
+		}
+		root := repoRootPath(p1)
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		info.Deps = append(info.Deps, &debug.Module{Path: root, Version: "(devel)"})
+	}
+	sort.Sort(byModulePath(info.Deps))
+}
+
+// moduleBuildInfo records in info the main module and the versions
+// and go.sum hashes of the modules providing the linked packages.
+func (b *builder) moduleBuildInfo(info *debug.BuildInfo, a *action) {
+	info.Main = debug.Module{Path: modTarget.Path, Version: "(devel)"}
+	seen := map[modVersion]bool{modTarget: true}
+	for _, a1 := range actionList(a) {
+		p1 := a1.p
+		if p1 == nil || p1.fake || p1.Module == nil {
+			continue
+		}
+		m := modVersion{p1.Module.Path, p1.Module.Version}
+		if seen[m] {
+			continue
+		}
+		seen[m] = true
+		dep := &debug.Module{Path: m.Path, Version: m.Version, Sum: modSumOf(m)}
+		if r := p1.Module.Replace; r != nil {
+			dep.Replace = &debug.Module{Path: r.Path, Version: r.Version}
+			if r.Version != "" {
+				dep.Replace.Sum = modSumOf(modVersion{r.Path, r.Version})
+			} else {
+				dep.Replace.Version = "(devel)"
+			}
+		}
+		info.Deps = append(info.Deps, dep)
+	}
+	sort.Sort(byModulePath(info.Deps))
+}
+
+// repoRootPath returns the import path of the root of the version
+// control repository containing p, or the import path of p if p is
+// not in such a repository.
+func repoRootPath(p *Package) string {
+	if p.build != nil && p.build.SrcRoot != "" && !p.local {
+		if _, root, err := vcsForDir(p); err == nil {
+			return filepath.ToSlash(root)
+		}
+	}
+	return p.ImportPath
+}
+
+type byModulePath []*debug.Module
+
+func (x byModulePath) Len() int           { return len(x) }
+func (x byModulePath) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x byModulePath) Less(i, j int) bool { return x[i].Path < x[j].Path }
@@ -0,0 +1,758 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var helpCache = &Command{
+	UsageLine: "cache",
+	Short:     "build and test caching",
+	Long: `
+The go command caches build outputs for reuse in future builds.
+The default location for cache data is a subdirectory named go-build
+in the standard user cache directory for the current operating system.
+Setting the GOCACHE environment variable overrides this default,
+and running 'go env GOCACHE' prints the current cache directory.
+Setting GOCACHE=off disables the cache.
+
+The cache is content-addressed: each entry is keyed by a hash of
+everything that went into producing it, including the source files,
+the compiler, assembler and linker binaries, the build flags, the
+target GOOS and GOARCH, and the contents of the compiled dependencies.
+Because the key does not depend on file modification times,
+switching between branches or build tags, or alternating between
+GOOS/GOARCH combinations, reuses the packages and binaries already
+built for the same inputs instead of rebuilding them from scratch.
+Packages that use cgo or SWIG, packages built for coverage analysis,
+and builds using -compiler=gccgo or -toolexec are never cached.
+
+The go command also caches successful package test results.
+When 'go test' would run a test binary whose contents, arguments,
+environment, package directory and testdata directory are all
+unchanged since a previous passing run, it redisplays the previous
+output instead of running the binary again, and prints "(cached)"
+in place of the elapsed time in the summary line. Only runs that use
+the test flags -cpu, -parallel, -run, -short, -timeout and -v are
+cached; by convention, -count=1 is the idiomatic way to disable
+test caching explicitly. Tests that depend on files outside the
+package directory or on external resources should be run that way.
+
+The cache is trimmed automatically, at most once an hour, by removing
+the least recently used entries until it is smaller than the limit set
+by the GOCACHESIZE environment variable. The limit is a byte count with
+an optional K, M or G suffix; the default is 2G.
+The 'go clean -cache' command removes all cached data.
+
+The fuzz directory of the cache holds the inputs that 'go test -fuzz'
+found to expand coverage, so that later fuzzing picks up where it left
+off. It is not trimmed; the 'go clean -fuzzcache' command removes it.
+	`,
+}
+
+const (
+	// cacheTrimInterval is how often the cache is checked against its size limit.
+	cacheTrimInterval = 1 * time.Hour
+
+	// cacheTouchInterval is how stale an entry's modification time may get
+	// before a use of the entry updates it. Trimming evicts the entries
+	// with the oldest modification times first.
+	cacheTouchInterval = 1 * time.Hour
+
+	// defaultCacheSize is the default limit on the size of the cache.
+	defaultCacheSize = 2 << 30
+)
+
+// A cacheID identifies an action or an output in the build cache.
+type cacheID [sha256.Size]byte
+
+func (id cacheID) String() string {
+	return fmt.Sprintf("%x", id[:])
+}
+
+// A cacheHash accumulates the inputs of an action to compute its cacheID.
+type cacheHash struct {
+	h hash.Hash
+}
+
+// newCacheHash returns a cacheHash for an action of the given kind.
+func newCacheHash(kind string) *cacheHash {
+	h := &cacheHash{sha256.New()}
+	h.add("go build cache v1 %s", kind)
+	h.add("go %s", runtime.Version())
+	return h
+}
+
+// add adds a line formatted as by fmt.Sprintf to the hash.
+func (h *cacheHash) add(format string, args ...interface{}) {
+	fmt.Fprintf(h.h, format, args...)
+	h.h.Write([]byte{'\n'})
+}
+
+// addFile adds the name and content of file to the hash.
+func (h *cacheHash) addFile(name, file string) error {
+	id, err := hashFile(file)
+	if err != nil {
+		return err
+	}
+	h.add("file %s %s", name, id)
+	return nil
+}
+
+// sum returns the cacheID of the accumulated inputs.
+func (h *cacheHash) sum() cacheID {
+	var id cacheID
+	h.h.Sum(id[:0])
+	return id
+}
+
+// A fileHash records the content hash of a file
+// along with the size and modification time it was computed for.
+type fileHash struct {
+	size  int64
+	mtime time.Time
+	id    cacheID
+}
+
+var fileHashCache struct {
+	sync.Mutex
+	m map[string]fileHash
+}
+
+// hashFile returns the SHA-256 hash of the content of file.
+// The result is remembered for as long as the file's size and
+// modification time are unchanged, since the same tools and
+// dependency archives are hashed over and over during a build.
+func hashFile(file string) (cacheID, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return cacheID{}, err
+	}
+	if !fi.Mode().IsRegular() {
+		return cacheID{}, fmt.Errorf("%s is not a regular file", file)
+	}
+
+	fileHashCache.Lock()
+	fh, ok := fileHashCache.m[file]
+	fileHashCache.Unlock()
+	if ok && fh.size == fi.Size() && fh.mtime.Equal(fi.ModTime()) {
+		return fh.id, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return cacheID{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return cacheID{}, err
+	}
+	var id cacheID
+	h.Sum(id[:0])
+
+	fileHashCache.Lock()
+	if fileHashCache.m == nil {
+		fileHashCache.m = make(map[string]fileHash)
+	}
+	fileHashCache.m[file] = fileHash{fi.Size(), fi.ModTime(), id}
+	fileHashCache.Unlock()
+	return id, nil
+}
+
+// A buildCache is a content-addressed store of build outputs.
+//
+// The cache directory holds 256 subdirectories named for the first
+// byte of the IDs stored in them. An action entry, named ID-a,
+// records the ID and size of the output of the action with that ID.
+// The output itself is stored in a file named ID-d, where ID is the
+// SHA-256 hash of its content, so that identical outputs of
+// different actions are stored only once.
+// Files are written to a temporary name and renamed into place,
+// so that concurrent go commands sharing a cache never observe
+// a partially written entry.
+type buildCache struct {
+	dir string
+}
+
+var theCache struct {
+	once sync.Once
+	c    *buildCache
+}
+
+// defaultCache returns the build cache to use, or nil if caching is disabled.
+func defaultCache() *buildCache {
+	theCache.once.Do(func() {
+		if buildN {
+			return
+		}
+		dir := cacheDir()
+		if dir == "off" {
+			return
+		}
+		limit := cacheSizeLimit()
+		c, err := openCache(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go: disabling build cache: %v\n", err)
+			return
+		}
+		atexit(func() { c.trim(limit) })
+		theCache.c = c
+	})
+	return theCache.c
+}
+
+// cacheDir returns the build cache directory, or "off" if caching is disabled.
+func cacheDir() string {
+	if dir := os.Getenv("GOCACHE"); dir != "" {
+		return dir
+	}
+	var dir string
+	switch runtime.GOOS {
+	case "windows":
+		dir = os.Getenv("LocalAppData")
+	case "darwin":
+		if home := os.Getenv("HOME"); home != "" {
+			dir = filepath.Join(home, "Library", "Caches")
+		}
+	case "plan9":
+		if home := os.Getenv("home"); home != "" {
+			dir = filepath.Join(home, "lib", "cache")
+		}
+	default:
+		dir = os.Getenv("XDG_CACHE_HOME")
+		if dir == "" {
+			if home := os.Getenv("HOME"); home != "" {
+				dir = filepath.Join(home, ".cache")
+			}
+		}
+	}
+	if dir == "" {
+		return "off"
+	}
+	return filepath.Join(dir, "go-build")
+}
+
+// cacheSizeLimit returns the cache size limit set by $GOCACHESIZE.
+func cacheSizeLimit() int64 {
+	s := os.Getenv("GOCACHESIZE")
+	if s == "" {
+		return defaultCacheSize
+	}
+	n, err := parseCacheSize(s)
+	if err != nil {
+		fatalf("go: invalid GOCACHESIZE %q", s)
+	}
+	return n
+}
+
+// parseCacheSize parses a byte count with an optional K, M or G suffix.
+func parseCacheSize(s string) (int64, error) {
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		mult = 1 << 30
+	}
+	if mult != 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > (1<<62)/mult {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+// openCache opens the build cache in dir, creating it if necessary.
+func openCache(dir string) (*buildCache, error) {
+	if !filepath.IsAbs(dir) {
+		return nil, fmt.Errorf("cache directory %s is not an absolute path", dir)
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	for i := 0; i < 256; i++ {
+		if err := os.MkdirAll(filepath.Join(dir, fmt.Sprintf("%02x", i)), 0777); err != nil {
+			return nil, err
+		}
+	}
+	readme := filepath.Join(dir, "README")
+	if _, err := os.Stat(readme); err != nil {
+		ioutil.WriteFile(readme, []byte("This directory holds cached build artifacts from the go command.\n"), 0666)
+	}
+	return &buildCache{dir: dir}, nil
+}
+
+// fileName returns the name of the cache file for id with the given suffix.
+func (c *buildCache) fileName(id cacheID, suffix string) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%02x", id[0]), id.String()+"-"+suffix)
+}
+
+// get returns the name of the file holding the output of the action id.
+func (c *buildCache) get(id cacheID) (file string, ok bool) {
+	entry := c.fileName(id, "a")
+	data, err := ioutil.ReadFile(entry)
+	if err != nil {
+		return "", false
+	}
+	f := strings.Fields(string(data))
+	if len(f) != 3 || f[0] != "v1" || !isHexID(f[1]) {
+		return "", false
+	}
+	size, err := strconv.ParseInt(f[2], 10, 64)
+	if err != nil {
+		return "", false
+	}
+	file = filepath.Join(c.dir, f[1][:2], f[1]+"-d")
+	fi, err := os.Stat(file)
+	if err != nil || fi.Size() != size {
+		return "", false
+	}
+	c.touch(entry)
+	c.touch(file)
+	return file, true
+}
+
+// isHexID reports whether s is the hexadecimal form of a cacheID.
+func isHexID(s string) bool {
+	if len(s) != 2*len(cacheID{}) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// getBytes returns the output of the action id.
+func (c *buildCache) getBytes(id cacheID) ([]byte, bool) {
+	file, ok := c.get(id)
+	if !ok {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// touch marks file as recently used, so that trimming keeps it.
+func (c *buildCache) touch(file string) {
+	now := time.Now()
+	if fi, err := os.Stat(file); err == nil && now.Sub(fi.ModTime()) < cacheTouchInterval {
+		return
+	}
+	os.Chtimes(file, now, now)
+}
+
+// put records the content of file as the output of the action id.
+func (c *buildCache) put(id cacheID, file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return c.putBytes(id, data)
+}
+
+// putBytes records data as the output of the action id.
+func (c *buildCache) putBytes(id cacheID, data []byte) error {
+	out := cacheID(sha256.Sum256(data))
+	file := c.fileName(out, "d")
+	if fi, err := os.Stat(file); err != nil || fi.Size() != int64(len(data)) {
+		if err := c.writeFile(file, data); err != nil {
+			return err
+		}
+	} else {
+		c.touch(file)
+	}
+	entry := fmt.Sprintf("v1 %s %d\n", out, len(data))
+	return c.writeFile(c.fileName(id, "a"), []byte(entry))
+}
+
+// writeFile writes data to a temporary file and renames it to file.
+func (c *buildCache) writeFile(file string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// trim removes the least recently used cache files until the
+// total size of the cache is at most limit bytes.
+// It does nothing if the cache was checked in the last cacheTrimInterval.
+func (c *buildCache) trim(limit int64) {
+	now := time.Now()
+	stamp := filepath.Join(c.dir, "trim.txt")
+	if data, err := ioutil.ReadFile(stamp); err == nil {
+		if t, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil && now.Sub(time.Unix(t, 0)) < cacheTrimInterval {
+			return
+		}
+	}
+	ioutil.WriteFile(stamp, []byte(fmt.Sprintf("%d\n", now.Unix())), 0666)
+
+	var files []cacheFile
+	var total int64
+	for i := 0; i < 256; i++ {
+		subdir := filepath.Join(c.dir, fmt.Sprintf("%02x", i))
+		infos, err := ioutil.ReadDir(subdir)
+		if err != nil {
+			continue
+		}
+		for _, fi := range infos {
+			name := fi.Name()
+			if strings.Contains(name, ".tmp") {
+				// Remove temporary files left behind by interrupted
+				// writes, once they are clearly abandoned.
+				if now.Sub(fi.ModTime()) > cacheTrimInterval {
+					os.Remove(filepath.Join(subdir, name))
+				}
+				continue
+			}
+			if !strings.HasSuffix(name, "-a") && !strings.HasSuffix(name, "-d") {
+				continue
+			}
+			files = append(files, cacheFile{filepath.Join(subdir, name), fi.Size(), fi.ModTime()})
+			total += fi.Size()
+		}
+	}
+	if total <= limit {
+		return
+	}
+	sort.Sort(byCacheMtime(files))
+	for _, f := range files {
+		if total <= limit {
+			break
+		}
+		if os.Remove(f.name) == nil {
+			total -= f.size
+		}
+	}
+}
+
+type cacheFile struct {
+	name  string
+	size  int64
+	mtime time.Time
+}
+
+type byCacheMtime []cacheFile
+
+func (x byCacheMtime) Len() int           { return len(x) }
+func (x byCacheMtime) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x byCacheMtime) Less(i, j int) bool { return x[i].mtime.Before(x[j].mtime) }
+
+// clean removes all entries from the cache.
+func (c *buildCache) clean(b *builder) {
+	for i := 0; i < 256; i++ {
+		subdir := filepath.Join(c.dir, fmt.Sprintf("%02x", i))
+		if buildN || buildX {
+			b.showcmd("", "rm -r %s", subdir)
+		}
+		if !buildN {
+			if err := os.RemoveAll(subdir); err != nil {
+				errorf("go clean -cache: %v", err)
+			}
+		}
+	}
+	if !buildN {
+		os.Remove(filepath.Join(c.dir, "trim.txt"))
+	}
+}
+
+// cacheable reports whether the gc toolchain builds p in a way
+// that the build cache can model: pure Go and assembly sources,
+// compiled directly by the toolchain.
+func cacheable(p *Package) bool {
+	if _, ok := buildToolchain.(gcToolchain); !ok {
+		return false
+	}
+	if len(buildToolExec) > 0 {
+		return false
+	}
+	return !p.usesCgo() && !p.usesSwig() && p.coverMode == "" &&
+		len(p.CFiles) == 0 && len(p.CXXFiles) == 0 && len(p.MFiles) == 0
+}
+
+// addBuildContext adds the toolchain settings shared by
+// all compile and link actions to h.
+func addBuildContext(h *cacheHash) {
+	h.add("goos %s goarch %s", goos, goarch)
+	for _, env := range []string{"GOARM", "GO386", "GOROOT_FINAL", "GO_EXTLINK_ENABLED"} {
+		h.add("env %s=%s", env, os.Getenv(env))
+	}
+	h.add("goroot %s", goroot)
+	h.add("installsuffix %q", buildContext.InstallSuffix)
+	h.add("buildmode %s race %v msan %v", buildBuildmode, buildRace, buildMSan)
+	h.add("trimpath %v", buildTrimpath)
+}
+
+// archiveCacheID returns the cache ID of the package archive
+// built by a, which must be a build action, and reports whether
+// the archive can be cached.
+func (b *builder) archiveCacheID(a *action) (cacheID, bool) {
+	p := a.p
+	if defaultCache() == nil || !cacheable(p) {
+		return cacheID{}, false
+	}
+	h := newCacheHash("compile")
+	addBuildContext(h)
+	if err := h.addFile("compile", tool("compile")); err != nil {
+		return cacheID{}, false
+	}
+	if len(p.SFiles) > 0 {
+		if err := h.addFile("asm", tool("asm")); err != nil {
+			return cacheID{}, false
+		}
+	}
+	h.add("gcflags %q", buildGcflags)
+	h.add("asmflags %q", buildAsmflags)
+	h.add("package %s %s standard %v", p.ImportPath, p.Name, p.Standard)
+	h.add("buildid %s", p.buildID)
+	h.add("localprefix %s", p.localPrefix)
+	h.add("imports %q", p.Imports)
+
+	// Source file names are recorded in the compiled code,
+	// so the package directory is part of the key. The compiler
+	// trims the temporary work directory from recorded names,
+	// so generated packages like the test main are keyed alike
+	// from one build to the next.
+	dir := p.Dir
+	if hasFilePathPrefix(dir, b.work) {
+		dir = "$WORK" + dir[len(b.work):]
+	}
+	h.add("dir %s", dir)
+	for _, list := range [][]string{p.GoFiles, p.SFiles, p.HFiles, p.SysoFiles} {
+		for _, f := range list {
+			if err := h.addFile(f, filepath.Join(p.Dir, f)); err != nil {
+				return cacheID{}, false
+			}
+		}
+	}
+	if len(p.SFiles) > 0 {
+		inc := filepath.Join(goroot, "pkg", "include")
+		for _, f := range []string{"asm_amd64.h", "asm_ppc64x.h", "funcdata.h", "textflag.h"} {
+			if _, err := os.Stat(filepath.Join(inc, f)); err == nil {
+				if err := h.addFile("include/"+f, filepath.Join(inc, f)); err != nil {
+					return cacheID{}, false
+				}
+			}
+		}
+	}
+
+	// The compiled form of the package depends on the export data
+	// of its dependencies, so their archives are part of the key.
+	for _, a1 := range allArchiveActions(a) {
+		if a1.p == nil {
+			continue
+		}
+		if err := h.addFile("import "+a1.p.ImportPath, a1.target); err != nil {
+			return cacheID{}, false
+		}
+	}
+	return h.sum(), true
+}
+
+// linkCacheID returns the cache ID of the executable linked by a,
+// which must be a link action, and reports whether the executable
+// can be cached.
+func (b *builder) linkCacheID(a *action) (cacheID, bool) {
+	if defaultCache() == nil || !cacheable(a.p) || buildLinkshared {
+		return cacheID{}, false
+	}
+	switch buildBuildmode {
+	case "", "default", "exe", "pie":
+	default:
+		return cacheID{}, false
+	}
+	h := newCacheHash("link")
+	addBuildContext(h)
+	if err := h.addFile("link", tool("link")); err != nil {
+		return cacheID{}, false
+	}
+	h.add("ldflags %q", buildLdflags)
+	h.add("omitdwarf %v", a.p.omitDWARF)
+	h.add("buildid %s", a.p.buildID)
+	h.add("buildinfo %q", a.p.buildInfo)
+	for _, env := range []string{"CC", "CXX", "CGO_LDFLAGS"} {
+		h.add("env %s=%s", env, os.Getenv(env))
+	}
+	if err := h.addFile("main", a.objpkg); err != nil {
+		return cacheID{}, false
+	}
+	all := actionList(a)
+	for _, a1 := range all[:len(all)-1] {
+		if a1.p == nil || !strings.HasSuffix(a1.target, ".a") {
+			continue
+		}
+		if !cacheable(a1.p) {
+			// Packages using cgo may pull in host objects
+			// that the hash cannot see.
+			return cacheID{}, false
+		}
+		if err := h.addFile("import "+a1.p.ImportPath, a1.target); err != nil {
+			return cacheID{}, false
+		}
+	}
+	return h.sum(), true
+}
+
+// getCached copies the cached output of the action id to dst,
+// reporting whether it was found. With -a nothing is reused,
+// but the rebuilt output is still recorded by putCached.
+func (b *builder) getCached(a *action, id cacheID, dst string, perm os.FileMode) bool {
+	if buildA {
+		return false
+	}
+	file, ok := defaultCache().get(id)
+	if !ok {
+		return false
+	}
+	if err := b.copyFile(a, dst, file, perm, true); err != nil {
+		return false
+	}
+	return true
+}
+
+// putCached records the content of file as the output of the action id.
+// Failures are ignored: the cache is only an optimization.
+func (b *builder) putCached(id cacheID, file string) {
+	if err := defaultCache().put(id, file); err != nil && buildX {
+		b.print(fmt.Sprintf("# go: cannot cache %s: %v\n", file, err))
+	}
+}
+
+// cacheableTestFlags lists the test binary flags that
+// still allow the test result to be cached.
+var cacheableTestFlags = map[string]bool{
+	"cpu":      true,
+	"parallel": true,
+	"run":      true,
+	"short":    true,
+	"timeout":  true,
+	"v":        true,
+}
+
+// testCacheID returns the cache ID of the output of the test run
+// by a, which must be a run action, and reports whether the
+// result can be cached.
+func (b *builder) testCacheID(a *action, env []string) (cacheID, bool) {
+	if defaultCache() == nil || testC || testProfile || testBench || testCoverProfile != "" {
+		return cacheID{}, false
+	}
+	for _, arg := range testArgs {
+		if !strings.HasPrefix(arg, "-test.") {
+			return cacheID{}, false
+		}
+		name := strings.TrimPrefix(arg, "-test.")
+		if i := strings.Index(name, "="); i >= 0 {
+			name = name[:i]
+		}
+		if !cacheableTestFlags[name] {
+			return cacheID{}, false
+		}
+	}
+
+	h := newCacheHash("test")
+	if err := h.addFile("test", a.deps[0].target); err != nil {
+		return cacheID{}, false
+	}
+	h.add("exec %q", findExecCmd())
+	h.add("args %q", testArgs)
+	env = append([]string(nil), env...)
+	sort.Strings(env)
+	for _, kv := range env {
+		switch {
+		case strings.HasPrefix(kv, "OLDPWD="),
+			strings.HasPrefix(kv, "SHLVL="),
+			strings.HasPrefix(kv, "_="):
+			// Shell bookkeeping that does not affect the test.
+			continue
+		}
+		h.add("env %s", kv)
+	}
+
+	// Tests commonly read files in their package directory,
+	// so the directory contents and testdata are part of the key.
+	dir := a.p.Dir
+	h.add("dir %s", dir)
+	if !hashDir(h, dir, "", false) || !hashDir(h, dir, "testdata", true) {
+		return cacheID{}, false
+	}
+	return h.sum(), true
+}
+
+// hashDir adds the names and contents of the regular files in the
+// directory rel, relative to root, to h, recursing into subdirectories
+// if recursive is set. It reports whether all files could be read.
+func hashDir(h *cacheHash, root, rel string, recursive bool) bool {
+	infos, err := ioutil.ReadDir(filepath.Join(root, rel))
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	for _, fi := range infos {
+		name := filepath.Join(rel, fi.Name())
+		switch {
+		case fi.Mode().IsRegular():
+			if err := h.addFile(filepath.ToSlash(name), filepath.Join(root, name)); err != nil {
+				return false
+			}
+		case fi.IsDir():
+			if recursive && !hashDir(h, root, name, true) {
+				return false
+			}
+		case fi.Mode()&os.ModeSymlink != 0:
+			target, _ := os.Readlink(filepath.Join(root, name))
+			h.add("symlink %s %s", filepath.ToSlash(name), target)
+		}
+	}
+	return true
+}
+
+// cachedTestOutput returns the output of the passing test run
+// recorded in the cache under id, if any.
+func cachedTestOutput(id cacheID) ([]byte, bool) {
+	out, ok := defaultCache().getBytes(id)
+	if !ok || !bytes.HasPrefix(out, []byte("v1\n")) {
+		return nil, false
+	}
+	return out[len("v1\n"):], true
+}
+
+// putTestOutput records out as the output of the passing test run id.
+func putTestOutput(id cacheID, out []byte) {
+	defaultCache().putBytes(id, append([]byte("v1\n"), out...))
+}
@@ -0,0 +1,137 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var parseCacheSizeTests = []struct {
+	in  string
+	out int64
+	ok  bool
+}{
+	{"0", 0, true},
+	{"12345", 12345, true},
+	{"10K", 10 << 10, true},
+	{"500M", 500 << 20, true},
+	{"2G", 2 << 30, true},
+	{"", 0, false},
+	{"G", 0, false},
+	{"-1", 0, false},
+	{"1T", 0, false},
+	{"1.5G", 0, false},
+}
+
+func TestParseCacheSize(t *testing.T) {
+	for _, tt := range parseCacheSizeTests {
+		n, err := parseCacheSize(tt.in)
+		if ok := err == nil; ok != tt.ok || n != tt.out {
+			t.Errorf("parseCacheSize(%q) = %d, %v; want %d, ok=%v", tt.in, n, err, tt.out, tt.ok)
+		}
+	}
+}
+
+func testCacheID(s string) cacheID {
+	h := newCacheHash("test")
+	h.add("%s", s)
+	return h.sum()
+}
+
+func TestCachePutGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c, err := openCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id1, id2 := testCacheID("one"), testCacheID("two")
+	if _, ok := c.getBytes(id1); ok {
+		t.Fatalf("getBytes(id1) succeeded in empty cache")
+	}
+	data := []byte("hello, world\n")
+	if err := c.putBytes(id1, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.putBytes(id2, data); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []cacheID{id1, id2} {
+		out, ok := c.getBytes(id)
+		if !ok || !bytes.Equal(out, data) {
+			t.Errorf("getBytes(%s) = %q, %v; want %q, true", id, out, ok, data)
+		}
+	}
+
+	// Identical outputs are stored once.
+	f1, _ := c.get(id1)
+	f2, _ := c.get(id2)
+	if f1 != f2 {
+		t.Errorf("identical outputs stored as %s and %s", f1, f2)
+	}
+
+	// An entry whose output has gone missing is a miss.
+	os.Remove(f1)
+	if _, ok := c.get(id1); ok {
+		t.Errorf("get succeeded with missing output file")
+	}
+
+	if _, err := openCache("relative/dir"); err == nil {
+		t.Errorf("openCache accepted a relative directory")
+	}
+}
+
+func TestCacheTrim(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c, err := openCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, recent := testCacheID("old"), testCacheID("recent")
+	if err := c.putBytes(old, bytes.Repeat([]byte("o"), 1000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.putBytes(recent, bytes.Repeat([]byte("r"), 1000)); err != nil {
+		t.Fatal(err)
+	}
+	file, _ := c.get(old)
+	past := time.Now().Add(-24 * time.Hour)
+	for _, f := range []string{file, c.fileName(old, "a")} {
+		if err := os.Chtimes(f, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c.trim(1500)
+	if _, ok := c.get(old); ok {
+		t.Errorf("trim kept least recently used entry")
+	}
+	if _, ok := c.get(recent); !ok {
+		t.Errorf("trim removed most recently used entry")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "trim.txt")); err != nil {
+		t.Errorf("trim did not record its run: %v", err)
+	}
+
+	// A second trim within the interval does nothing.
+	c.trim(0)
+	if _, ok := c.get(recent); !ok {
+		t.Errorf("trim ran again within trim interval")
+	}
+}
@@ -13,7 +13,7 @@ import (
 )
 
 var cmdClean = &Command{
-	UsageLine: "clean [-i] [-r] [-n] [-x] [build flags] [packages]",
+	UsageLine: "clean [-i] [-r] [-n] [-x] [-cache] [-fuzzcache] [build flags] [packages]",
 	Short:     "remove object files",
 	Long: `
 Clean removes object files from package source directories.
@@ -52,14 +52,24 @@ dependencies of the packages named by the import paths.
 
 The -x flag causes clean to print remove commands as it executes them.
 
+The -cache flag causes clean to remove the entire go build cache,
+in addition to cleaning specified packages (if any).
+See 'go help cache' for details.
+
+The -fuzzcache flag causes clean to remove the inputs generated by
+'go test -fuzz' that are kept in the go build cache. The failing inputs
+written to testdata directories are not removed.
+
 For more about build flags, see 'go help build'.
 
 For more about specifying packages, see 'go help packages'.
 	`,
 }
 
-var cleanI bool // clean -i flag
-var cleanR bool // clean -r flag
+var cleanI bool     // clean -i flag
+var cleanR bool     // clean -r flag
+var cleanCache bool // clean -cache flag
+var cleanFuzz bool  // clean -fuzzcache flag
 
 func init() {
 	// break init cycle
@@ -67,6 +77,8 @@ func init() {
 
 	cmdClean.Flag.BoolVar(&cleanI, "i", false, "")
 	cmdClean.Flag.BoolVar(&cleanR, "r", false, "")
+	cmdClean.Flag.BoolVar(&cleanCache, "cache", false, "")
+	cmdClean.Flag.BoolVar(&cleanFuzz, "fuzzcache", false, "")
 	// -n and -x are important enough to be
 	// mentioned explicitly in the docs but they
 	// are part of the build flags.
@@ -75,8 +87,36 @@ func init() {
 }
 
 func runClean(cmd *Command, args []string) {
-	for _, pkg := range packagesAndErrors(args) {
-		clean(pkg)
+	if len(args) > 0 || !cleanCache && !cleanFuzz {
+		for _, pkg := range packagesAndErrors(args) {
+			clean(pkg)
+		}
+	}
+
+	if cleanCache {
+		if dir := cacheDir(); dir != "off" {
+			if _, err := os.Stat(dir); err == nil {
+				var b builder
+				b.print = fmt.Print
+				(&buildCache{dir: dir}).clean(&b)
+			}
+		}
+	}
+
+	if cleanFuzz {
+		if dir := cacheDir(); dir != "off" {
+			fuzzDir := filepath.Join(dir, "fuzz")
+			if buildN || buildX {
+				var b builder
+				b.print = fmt.Print
+				b.showcmd("", "rm -rf %s", fuzzDir)
+			}
+			if !buildN {
+				if err := os.RemoveAll(fuzzDir); err != nil {
+					errorf("go clean -fuzzcache: %v", err)
+				}
+			}
+		}
 	}
 }
 
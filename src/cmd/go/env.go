@@ -39,13 +39,17 @@ func mkEnv() []envVar {
 	}
 
 	env := []envVar{
+		{"GO111MODULE", os.Getenv("GO111MODULE")},
 		{"GOARCH", goarch},
 		{"GOBIN", gobin},
+		{"GOCACHE", cacheDir()},
 		{"GOEXE", exeSuffix},
 		{"GOHOSTARCH", runtime.GOARCH},
 		{"GOHOSTOS", runtime.GOOS},
+		{"GOMOD", modGoModPath()},
 		{"GOOS", goos},
 		{"GOPATH", os.Getenv("GOPATH")},
+		{"GOPROXY", os.Getenv("GOPROXY")},
 		{"GORACE", os.Getenv("GORACE")},
 		{"GOROOT", goroot},
 		{"GOTOOLDIR", toolDir},
@@ -83,6 +83,9 @@ func init() {
 }
 
 func runGet(cmd *Command, args []string) {
+	if modEnabled() {
+		fatalf("go get: cannot use go get in module mode; edit go.mod or run 'go mod tidy'")
+	}
 	if *getF && !*getU {
 		fatalf("go get: cannot use -f flag without -u")
 	}
@@ -101,6 +101,10 @@ func TestMain(m *testing.M) {
 	os.Unsetenv("GOBIN")
 	os.Unsetenv("GOPATH")
 
+	// Tests that look at what the go command rebuilds
+	// must not be satisfied from a shared build cache.
+	os.Setenv("GOCACHE", "off")
+
 	r := m.Run()
 
 	if canRun {
@@ -2014,6 +2018,160 @@ func TestCoverageWithCgo(t *testing.T) {
 	checkCoverage(tg, data)
 }
 
+func setupCoverPackages(tg *testgoData) {
+	tg.tempFile("src/covera/a.go", `package covera
+		import "coverb"
+		func A() int { return coverb.B() + 1 }
+	`)
+	tg.tempFile("src/covera/a_test.go", `package covera
+		import (
+			"coverb"
+			"testing"
+		)
+		func TestA(t *testing.T) { A(); coverb.C() }
+	`)
+	tg.tempFile("src/coverb/b.go", `package coverb
+		func B() int { return 1 }
+		func C() int { return 2 }
+	`)
+	tg.tempFile("src/coverb/b_test.go", `package coverb
+		import "testing"
+		func TestC(t *testing.T) { C() }
+	`)
+	tg.tempFile("src/coverc/c.go", `package coverc
+		func C() int { return 3 }
+	`)
+	tg.setenv("GOPATH", tg.path("."))
+}
+
+func TestCoverageMergedProfile(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	setupCoverPackages(tg)
+	profile := tg.path("cover.out")
+	tg.run("test", "-coverprofile="+profile, "covera", "coverb")
+	data, err := ioutil.ReadFile(profile)
+	tg.must(err)
+	if n := bytes.Count(data, []byte("mode: ")); n != 1 {
+		t.Errorf("merged profile has %d mode lines, want 1:\n%s", n, data)
+	}
+	for _, file := range []string{"covera/a.go", "coverb/b.go"} {
+		if !bytes.Contains(data, []byte(file)) {
+			t.Errorf("merged profile does not cover %s:\n%s", file, data)
+		}
+	}
+
+	tg.run("tool", "cover", "-func="+profile)
+	tg.grepStdout(`coverb/b.go:\d+:\s+C\s+100.0%`, "go tool cover -func did not report coverb.C as covered")
+	tg.grepStdout(`total:`, "go tool cover -func did not report total")
+}
+
+func TestCoveragePatternCoverpkg(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	setupCoverPackages(tg)
+	profile := tg.path("cover.out")
+	tg.run("test", "-coverpkg=cover...", "-coverprofile="+profile, "covera", "coverb")
+	tg.grepStderrNot(`warning: no packages`, "go test -coverpkg warned about pattern matching dependencies")
+	data, err := ioutil.ReadFile(profile)
+	tg.must(err)
+	if !bytes.Contains(data, []byte("covera/a.go")) || !bytes.Contains(data, []byte("coverb/b.go")) {
+		t.Errorf("profile does not cover matched dependencies:\n%s", data)
+	}
+	if bytes.Contains(data, []byte("coverc/c.go")) {
+		t.Errorf("profile covers package no test depends on:\n%s", data)
+	}
+	checkMergedBlocks(t, data)
+
+	// coverb.B is called only by the test of covera, and coverb.C
+	// by the tests of both packages; the merged profile covers both.
+	tg.run("tool", "cover", "-func="+profile)
+	tg.grepStdout(`coverb/b.go:\d+:\s+B\s+100.0%`, "merged profile does not report coverb.B as covered")
+	tg.grepStdout(`coverb/b.go:\d+:\s+C\s+100.0%`, "merged profile does not report coverb.C as covered")
+
+	tg.run("test", "-coverpkg=coverc/...", "covera")
+	tg.grepStderr(`warning: no packages being tested depend on coverc/...`, "go test -coverpkg did not warn about unused pattern")
+
+	// In count mode the counts of coverb.C in both tests are added.
+	tg.run("test", "-coverpkg=cover...", "-covermode=count", "-coverprofile="+profile, "covera", "coverb")
+	data, err = ioutil.ReadFile(profile)
+	tg.must(err)
+	checkMergedBlocks(t, data)
+	if !regexp.MustCompile(`(?m)coverb/b\.go:\S+ 1 2$`).Match(data) {
+		t.Errorf("merged profile does not add the counts of coverb.C:\n%s", data)
+	}
+}
+
+// checkMergedBlocks checks that no block occurs twice in the coverage
+// profile data.
+func checkMergedBlocks(t *testing.T, data []byte) {
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		if i := strings.LastIndex(line, " "); i >= 0 {
+			if block := line[:i]; seen[block] {
+				t.Errorf("merged profile has block %s twice:\n%s", block, data)
+			} else {
+				seen[block] = true
+			}
+		}
+	}
+}
+
+func TestFuzz(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.tempFile("src/fuzzer/parse.go", `package fuzzer
+		func Parse(b []byte) int {
+			if len(b) > 2 && b[0] == 'F' {
+				if b[1] == 'U' {
+					if b[2] == 'Z' {
+						panic("unreachable")
+					}
+					return 2
+				}
+				return 1
+			}
+			return 0
+		}
+	`)
+	tg.tempFile("src/fuzzer/parse_test.go", `package fuzzer
+		import "testing"
+		func FuzzParse(f *testing.F) {
+			f.Add([]byte("seed"))
+			f.Fuzz(func(t *testing.T, b []byte) { Parse(b) })
+		}
+	`)
+	tg.tempFile("src/other/other.go", "package other\n")
+	tg.tempDir("cache")
+	tg.setenv("GOPATH", tg.path("."))
+	tg.setenv("GOCACHE", tg.path("cache"))
+
+	tg.run("test", "-v", "fuzzer")
+	tg.grepStdout(`--- PASS: FuzzParse/seed#0`, "go test did not run the seed corpus")
+
+	tg.runFail("test", "-fuzz=FuzzParse", "-fuzztime=5m", "fuzzer")
+	tg.grepStdout(`panic: unreachable`, "go test -fuzz did not report the failure")
+	tg.grepStdout(`Failing input written to testdata/fuzz/FuzzParse/[0-9a-f]+`, "go test -fuzz did not write the failing input")
+	tg.grepStdoutNot(`coverage:`, "go test -fuzz reported coverage")
+	if _, err := os.Stat(tg.path("cache/fuzz/fuzzer/FuzzParse")); err != nil {
+		t.Errorf("go test -fuzz did not cache interesting inputs: %v", err)
+	}
+
+	// The failing input is now part of the seed corpus.
+	tg.runFail("test", "-v", "fuzzer")
+	tg.grepStdout(`=== RUN   FuzzParse/[0-9a-f]+`, "go test did not run the failing input")
+	tg.grepBoth(`panic: unreachable`, "failing input did not fail")
+
+	tg.runFail("test", "-fuzz=FuzzParse", "fuzzer", "other")
+	tg.grepStderr(`cannot use -fuzz flag with multiple packages`, "go test -fuzz accepted multiple packages")
+
+	tg.run("clean", "-fuzzcache")
+	tg.mustNotExist(tg.path("cache/fuzz"))
+}
+
 func TestCgoDependsOnSyscall(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping test that removes $GOROOT/pkg/*_race in short mode")
@@ -2186,6 +2344,164 @@ func TestGoTestFooTestWorks(t *testing.T) {
 	tg.run("test", "testdata/standalone_test.go")
 }
 
+func TestGoTestSubtests(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.run("test", "-v", "testdata/subtest_test.go")
+	tg.grepStdout(`(?m)^=== RUN   TestOuter/inner_A/leaf$`, "go test -v did not announce nested subtest")
+	tg.grepStdout(`(?m)^    --- PASS: TestOuter/inner_A \(`, "go test -v did not indent subtest result")
+	tg.grepStdout(`(?m)^        --- PASS: TestOuter/inner_A/leaf \(`, "go test -v did not indent nested subtest result")
+	tg.grepStdout(`(?m)^    --- PASS: TestOuter/inner_B \(`, "go test -v did not report parallel subtest")
+
+	tg.run("test", "-v", "-run", "Outer/B", "testdata/subtest_test.go")
+	tg.grepStdout(`--- PASS: TestOuter/inner_B`, "go test -run Outer/B did not run matching subtest")
+	tg.grepStdoutNot(`inner_A`, "go test -run Outer/B ran non-matching subtest")
+	tg.grepStdoutNot(`TestOther`, "go test -run Outer/B ran non-matching test")
+
+	tg.run("test", "-run", "^$", "-bench", "Outer/inner", "-benchtime", "1ms", "testdata/subtest_test.go")
+	tg.grepStdout(`(?m)^BenchmarkOuter/inner(-\d+)?\s+\d+`, "go test -bench did not report sub-benchmark")
+}
+
+func TestGoTestJSON(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.run("test", "-json", "testdata/subtest_test.go")
+	tg.grepStdout(`"Action":"run","Package":"command-line-arguments","Test":"TestOuter/inner_A/leaf"`, "go test -json did not report nested subtest run")
+	tg.grepStdout(`"Action":"pause","Package":"command-line-arguments","Test":"TestOuter/inner_B"`, "go test -json did not report paused subtest")
+	tg.grepStdout(`"Action":"pass","Package":"command-line-arguments","Test":"TestOther/x","Elapsed":`, "go test -json did not report subtest result")
+	tg.grepStdout(`"Action":"pass","Package":"command-line-arguments","Elapsed":`, "go test -json did not report package result")
+	tg.grepStdoutNot(`^ok`, "go test -json printed plain text summary")
+	for _, line := range strings.Split(tg.getStdout(), "\n") {
+		if line != "" && !strings.HasPrefix(line, "{") {
+			t.Errorf("go test -json printed non-JSON line %q", line)
+		}
+	}
+}
+
+func TestBuildCache(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.tempFile("src/cachecmd/main.go", `package main; import "cachecmd/lib"; func main() { println(lib.X) }`)
+	tg.tempFile("src/cachecmd/lib/lib.go", `package lib; const X = 1`)
+	tg.tempDir("cache")
+	tg.setenv("GOPATH", tg.path("."))
+	tg.setenv("GOCACHE", tg.path("cache"))
+	exe := tg.path("cachecmd" + exeSuffix)
+
+	tg.run("build", "-x", "-o", exe, "cachecmd")
+	tg.grepStderr(`[/\\]compile`, "first build did not compile")
+	tg.grepStderr(`[/\\]link`, "first build did not link")
+
+	// A rebuild from identical sources reuses the cached results,
+	// even though the sources look newer than any installed package.
+	tg.sleep()
+	tg.tempFile("src/cachecmd/lib/lib.go", `package lib; const X = 1`)
+	tg.run("build", "-x", "-o", exe, "cachecmd")
+	tg.grepStderrNot(`[/\\]compile`, "rebuild of unchanged sources ran the compiler")
+	tg.grepStderrNot(`[/\\]link`, "rebuild of unchanged sources ran the linker")
+	tg.grepStderr(`cp .*cache.*-d `, "rebuild did not copy from the cache")
+
+	// Changing a dependency invalidates both the importer and the binary.
+	tg.tempFile("src/cachecmd/lib/lib.go", `package lib; const X = 2`)
+	tg.run("build", "-x", "-o", exe, "cachecmd")
+	tg.grepStderr(`[/\\]compile`, "build after change did not compile")
+	tg.grepStderr(`[/\\]link`, "build after change did not link")
+
+	// Different flags are cached separately.
+	tg.run("build", "-x", "-gcflags=-N", "-o", exe, "cachecmd")
+	tg.grepStderr(`[/\\]compile`, "build with new flags did not compile")
+
+	tg.run("env", "GOCACHE")
+	tg.grepStdout(regexp.QuoteMeta(tg.path("cache")), "go env did not report GOCACHE")
+}
+
+func TestBuildCacheA(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping rebuild of the standard library in short mode")
+	}
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.tempFile("src/cachea/main.go", `package main; func main() {}`)
+	tg.tempDir("cache")
+	tg.setenv("GOPATH", tg.path("."))
+	tg.setenv("GOCACHE", tg.path("cache"))
+	exe := tg.path("cachea" + exeSuffix)
+
+	tg.run("build", "-o", exe, "cachea")
+	tg.run("build", "-a", "-x", "-o", exe, "cachea")
+	tg.grepStderr(`[/\\]compile`, "go build -a on a warm cache did not compile")
+	tg.grepStderr(`[/\\]link`, "go build -a on a warm cache did not link")
+	tg.grepStderrNot(`cp .*cache.*-d `, "go build -a copied from the cache")
+
+	// The results of the rebuild are cached.
+	tg.run("build", "-x", "-o", exe, "cachea")
+	tg.grepStderrNot(`[/\\]compile`, "build after go build -a ran the compiler")
+}
+
+func TestTestCache(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.tempFile("src/cachetest/x_test.go", `package cachetest
+		import ("io/ioutil"; "testing")
+		func TestData(t *testing.T) {
+			data, err := ioutil.ReadFile("testdata/in.txt")
+			if err != nil { t.Fatal(err) }
+			t.Logf("data %s", data)
+		}
+	`)
+	tg.tempFile("src/cachetest/testdata/in.txt", "one")
+	tg.tempDir("cache")
+	tg.setenv("GOPATH", tg.path("."))
+	tg.setenv("GOCACHE", tg.path("cache"))
+
+	tg.run("test", "cachetest")
+	tg.grepStdoutNot(`\(cached\)`, "first test run was cached")
+	tg.run("test", "cachetest")
+	tg.grepStdout(`ok  \tcachetest\t\(cached\)`, "second test run was not cached")
+
+	// Cached -v output is replayed.
+	tg.run("test", "-v", "cachetest")
+	tg.grepStdout(`data one`, "go test -v did not print test output")
+	tg.run("test", "-v", "cachetest")
+	tg.grepStdout(`data one`, "cached go test -v did not replay test output")
+	tg.grepStdout(`\(cached\)`, "second go test -v was not cached")
+
+	// -count disables caching.
+	tg.run("test", "-count=1", "cachetest")
+	tg.grepStdoutNot(`\(cached\)`, "go test -count=1 was cached")
+
+	// Changes to testdata invalidate the result.
+	tg.tempFile("src/cachetest/testdata/in.txt", "two")
+	tg.run("test", "-v", "cachetest")
+	tg.grepStdout(`data two`, "go test after testdata change used stale result")
+	tg.grepStdoutNot(`\(cached\)`, "go test after testdata change was cached")
+
+	// go clean -cache removes the results.
+	tg.run("clean", "-cache")
+	tg.run("test", "cachetest")
+	tg.grepStdoutNot(`\(cached\)`, "go test after go clean -cache was cached")
+}
+
+func TestTestCacheFailure(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.tempFile("src/cachefail/x_test.go", `package cachefail
+		import "testing"
+		func TestFail(t *testing.T) { t.Fatal("failed") }
+	`)
+	tg.tempDir("cache")
+	tg.setenv("GOPATH", tg.path("."))
+	tg.setenv("GOCACHE", tg.path("cache"))
+	tg.runFail("test", "cachefail")
+	tg.runFail("test", "cachefail")
+	tg.grepStdoutNot(`\(cached\)`, "failing test result was cached")
+	tg.grepStdout(`failed`, "failing test did not run again")
+}
+
 func TestGoTestFlagsAfterPackage(t *testing.T) {
 	tg := testgo(t)
 	defer tg.cleanup()
@@ -2346,6 +2662,53 @@ func TestGoVetWithTags(t *testing.T) {
 	tg.grepBoth(`c\.go.*wrong number of args for format`, "go get vetpkg did not run scan tagged file")
 }
 
+func TestGoVetWithVetTool(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.tempFile("src/vettool/main.go", `package main
+
+		import (
+			"go/analysis"
+			"go/analysis/multichecker"
+			"go/ast"
+		)
+
+		var deprecated = &analysis.Analyzer{
+			Name: "deprecated",
+			Doc:  "report calls of functions named Deprecated",
+			Run: func(pass *analysis.Pass) (interface{}, error) {
+				for _, f := range pass.Files {
+					ast.Inspect(f, func(n ast.Node) bool {
+						if id, ok := n.(*ast.Ident); ok && id.Name == "Deprecated" {
+							if _, ok := pass.TypesInfo.Uses[id]; ok {
+								pass.Reportf(id.Pos(), "call of Deprecated")
+							}
+						}
+						return true
+					})
+				}
+				return nil, nil
+			},
+		}
+
+		func main() { multichecker.Main(deprecated) }
+	`)
+	tg.tempFile("src/p/p.go", `package p
+
+		func Deprecated() {}
+
+		func F() { Deprecated() }
+	`)
+	tg.tempFile("src/q/q.go", "package q\n")
+	tg.setenv("GOPATH", tg.path("."))
+	tool := tg.path("bin/vettool" + exeSuffix)
+	tg.run("build", "-o", tool, "vettool")
+	tg.runFail("vet", "-vettool", tool, "p")
+	tg.grepStderr(`p\.go:5: call of Deprecated`, "vet tool did not report call of Deprecated")
+	tg.run("vet", "-vettool", tool, "q")
+}
+
 // Issue 9767.
 func TestGoGetRscIoToolstash(t *testing.T) {
 	testenv.MustHaveExternalNetwork(t)
@@ -2772,7 +3135,147 @@ func TestIssue13655(t *testing.T) {
 	}
 }
 
+func TestGoVersionBuildInfo(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.tempFile("src/vers/cmd/vers/main.go", `package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"versdep"
+)
+
+func main() {
+	versdep.F()
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		panic("no build info")
+	}
+	fmt.Print(bi)
+}
+`)
+	tg.tempFile("src/versdep/dep.go", "package versdep\n\nfunc F() {}\n")
+	tg.setenv("GOPATH", tg.path("."))
+	exe := tg.path("vers" + exeSuffix)
+	tg.run("build", "-ldflags=-w", "-o", exe, "vers/cmd/vers")
+
+	tg.run("version", exe)
+	tg.grepStdout(`^`+regexp.QuoteMeta(exe)+`: go`, "go version did not report the Go version of the binary")
+	tg.grepStdoutNot(`path`, "go version without -m printed build information")
+
+	tg.run("version", "-m", exe)
+	tg.grepStdout(`^\tpath\tvers/cmd/vers$`, "go version -m did not report the main package")
+	tg.grepStdout(`^\tmod\tvers/cmd/vers\t\(devel\)`, "go version -m did not report the main repository")
+	tg.grepStdout(`^\tdep\tversdep\t\(devel\)`, "go version -m did not report the dependency")
+	tg.grepStdout(`^\tbuild\t-ldflags=-w$`, "go version -m did not report -ldflags")
+	tg.grepStdout(`^\tbuild\tGOOS=`, "go version -m did not report GOOS")
+	tg.grepStdoutNot(`fmt`, "go version -m reported a standard library package")
+
+	// The running binary reports the same information.
+	out, err := exec.Command(exe).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", exe, err, out)
+	}
+	for _, line := range []string{"go\t", "path\tvers/cmd/vers\n", "dep\tversdep\t(devel)\t\n", "build\t-ldflags=-w\n"} {
+		if !strings.Contains(string(out), line) {
+			t.Errorf("ReadBuildInfo did not report %q:\n%s", line, out)
+		}
+	}
+	tg.run("run", tg.path("src/vers/cmd/vers/main.go"))
+	tg.grepStdout(`^path\tcommand-line-arguments$`, "ReadBuildInfo did not report the main package of go run")
+
+	tg.runFail("version", "-m")
+	tg.grepStderr("flags can only be used with arguments", "go version -m without arguments did not fail")
+	tg.runFail("version", tg.path("src/versdep/dep.go"))
+	tg.grepStderr("not a Go executable", "go version did not reject a source file")
+}
+
+func TestGoVersionBuildInfoVCS(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("skipping because git binary not found")
+	}
+
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.tempFile("src/example.com/repo/main.go", "package main\n\nfunc main() {}\n")
+	tg.setenv("GOPATH", tg.path("."))
+	repo := tg.path("src/example.com/repo")
+	git := []string{"-c", "user.name=gopher", "-c", "user.email=gopher@example.com"}
+	tg.runGit(repo, "init")
+	tg.runGit(repo, append(git, "add", "main.go")...)
+	tg.runGit(repo, append(git, "commit", "-m", "initial")...)
+	tg.runGit(repo, "rev-parse", "HEAD")
+	rev := strings.TrimSpace(tg.stdout.String())
+	exe := tg.path("repo" + exeSuffix)
+
+	tg.run("build", "-o", exe, "example.com/repo")
+	tg.run("version", "-m", exe)
+	tg.grepStdout(`^\tbuild\tvcs=git$`, "go version -m did not report the version control system")
+	tg.grepStdout(`^\tbuild\tvcs.revision=`+rev+`$`, "go version -m did not report the revision")
+	tg.grepStdout(`^\tbuild\tvcs.time=`, "go version -m did not report the commit time")
+	tg.grepStdout(`^\tbuild\tvcs.modified=false$`, "go version -m reported uncommitted changes")
+
+	tg.tempFile("src/example.com/repo/README", "uncommitted\n")
+	tg.run("build", "-o", exe, "example.com/repo")
+	tg.run("version", "-m", exe)
+	tg.grepStdout(`^\tbuild\tvcs.modified=true$`, "go version -m did not report uncommitted changes")
+}
+
 // For issue 14337.
+func TestBuildTrimpath(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	const src = `package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func main() {
+	_, file, _, _ := runtime.Caller(0)
+	fmt.Println(file)
+}
+`
+	var exes []string
+	for _, gopath := range []string{"a", "bb"} {
+		tg.tempFile(gopath+"/src/example.com/trim/main.go", src)
+		tg.setenv("GOPATH", tg.path(gopath))
+		exe := tg.path(gopath + "/trim" + exeSuffix)
+		tg.run("build", "-trimpath", "-o", exe, "example.com/trim")
+		exes = append(exes, exe)
+	}
+
+	out, err := exec.Command(exes[0]).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", exes[0], err, out)
+	}
+	if got, want := strings.TrimSpace(string(out)), "example.com/trim/main.go"; got != want {
+		t.Errorf("runtime.Caller reported file %q, want %q", got, want)
+	}
+
+	data0, err := ioutil.ReadFile(exes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	data1, err := ioutil.ReadFile(exes[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data0, data1) {
+		t.Error("binaries built with -trimpath in different GOPATH directories differ")
+	}
+	if bytes.Contains(data0, []byte(tg.path("a"))) {
+		t.Error("binary built with -trimpath contains its GOPATH directory")
+	}
+
+	tg.run("version", "-m", exes[0])
+	tg.grepStdout(`^\tbuild\t-trimpath=true$`, "go version -m did not report -trimpath")
+}
+
 func TestParallelTest(t *testing.T) {
 	tg := testgo(t)
 	defer tg.cleanup()
@@ -445,16 +445,29 @@ General-purpose environment variables:
 
 	GCCGO
 		The gccgo command to run for 'go build -compiler=gccgo'.
+	GO111MODULE
+		Controls whether the go command runs in module mode:
+		on, off, or auto. See 'go help modules'.
 	GOARCH
 		The architecture, or processor, for which to compile code.
 		Examples are amd64, 386, arm, ppc64.
 	GOBIN
 		The directory where 'go install' will install a command.
+	GOCACHE
+		The directory where the go command will store cached
+		information for reuse in future builds.
+		See 'go help cache'.
+	GOCACHESIZE
+		The size limit of the build cache.
+		See 'go help cache'.
 	GOOS
 		The operating system for which to compile code.
 		Examples are linux, darwin, windows, netbsd.
 	GOPATH
 		See 'go help gopath'.
+	GOPROXY
+		The module proxy to download modules from, or off.
+		See 'go help modules'.
 	GORACE
 		Options for the race detector.
 		See https://golang.org/doc/articles/race_detector.html.
@@ -76,6 +76,9 @@ syntax of package template.  The default output is equivalent to -f
         TestImports  []string // imports from TestGoFiles
         XTestGoFiles []string // _test.go files outside package
         XTestImports []string // imports from XTestGoFiles
+
+        // Module information, in module mode
+        Module *Module // module providing the package, if any
     }
 
 The error information, if any, is
@@ -86,6 +89,17 @@ The error information, if any, is
         Err           string   // the error itself
     }
 
+The module information, if any, is
+
+    type Module struct {
+        Path    string  // module path
+        Version string  // module version
+        Replace *Module // replaced by this module
+        Main    bool    // is this the main module?
+        Dir     string  // directory holding files for this module, if any
+        GoMod   string  // path to go.mod file for this module, if any
+    }
+
 The template function "join" calls strings.Join.
 
 The template function "context" returns the build context, defined as:
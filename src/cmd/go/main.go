@@ -85,6 +85,7 @@ var commands = []*Command{
 	cmdGet,
 	cmdInstall,
 	cmdList,
+	cmdMod,
 	cmdRun,
 	cmdTest,
 	cmdTool,
@@ -93,10 +94,12 @@ var commands = []*Command{
 
 	helpC,
 	helpBuildmode,
+	helpCache,
 	helpFileType,
 	helpGopath,
 	helpEnvironment,
 	helpImportPath,
+	helpModules,
 	helpPackages,
 	helpTestflag,
 	helpTestfunc,
@@ -563,6 +566,9 @@ func allPackages(pattern string) []string {
 }
 
 func matchPackages(pattern string) []string {
+	if modEnabled() && pattern == "all" {
+		return modAllPackages()
+	}
 	match := func(string) bool { return true }
 	treeCanMatch := func(string) bool { return true }
 	if !isMetaPackage(pattern) {
@@ -579,7 +585,7 @@ func matchPackages(pattern string) []string {
 	var pkgs []string
 
 	for _, src := range buildContext.SrcDirs() {
-		if (pattern == "std" || pattern == "cmd") && src != gorootSrc {
+		if (pattern == "std" || pattern == "cmd" || modEnabled()) && src != gorootSrc {
 			continue
 		}
 		src = filepath.Clean(src) + string(filepath.Separator)
@@ -624,6 +630,9 @@ func matchPackages(pattern string) []string {
 			return nil
 		})
 	}
+	if modEnabled() && pattern != "std" && pattern != "cmd" {
+		pkgs = append(pkgs, modMatchPackages(pattern, have)...)
+	}
 	return pkgs
 }
 
@@ -679,6 +688,16 @@ func matchPackagesInFS(pattern string) []string {
 		if dot || strings.HasPrefix(elem, "_") || elem == "testdata" {
 			return filepath.SkipDir
 		}
+		if modEnabled() && path != dir {
+			// In module mode, vendor directories and nested
+			// modules are not part of the module being matched.
+			if elem == "vendor" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
 
 		name := prefix + filepath.ToSlash(path)
 		if !match(name) {
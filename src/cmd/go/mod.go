@@ -0,0 +1,585 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var cmdMod = &Command{
+	Run:         runMod,
+	UsageLine:   "mod command [arguments]",
+	Short:       "module maintenance",
+	CustomFlags: true,
+	Long: `
+Mod provides access to operations on modules.
+See 'go help modules' for an overview of module support.
+
+The commands are:
+
+	go mod init [module]
+		Initialize a new module in the current directory by writing
+		a go.mod file. The module path argument may be omitted for
+		a directory inside GOPATH, whose import path is then used.
+
+	go mod tidy [-v]
+		Make go.mod match the source code of the main module: add any
+		modules needed to provide the packages imported by the main
+		module's packages and tests, at their latest versions, and
+		remove requirements on modules that provide no such packages.
+		It also removes unused entries from go.sum.
+		The -v flag prints the modules added and removed.
+
+	go mod vendor [-v]
+		Copy the packages needed to build and test the main module's
+		packages into its vendor directory, for use with -mod=vendor.
+		The -v flag prints the vendored modules and packages.
+
+	go mod download [-json] [modules]
+		Download the named modules, given as path or path@version,
+		into the module cache. With no arguments, download all the
+		modules in the build list. The -json flag prints a JSON
+		object for each module:
+
+			type Module struct {
+				Path     string // module path
+				Version  string // module version
+				Error    string // error loading module
+				GoMod    string // absolute path to cached .mod file
+				Zip      string // absolute path to cached .zip file
+				Dir      string // absolute path to cached source root directory
+				Sum      string // checksum for path, version (as in go.sum)
+				GoModSum string // checksum for go.mod (as in go.sum)
+			}
+
+Tidy and vendor consider every Go source file in a package, regardless
+of build constraints other than "+build ignore", so that their results
+do not depend on the operating system or architecture where they run.
+	`,
+}
+
+var (
+	modV    bool // -v flag
+	modJSON bool // -json flag
+)
+
+func runMod(cmd *Command, args []string) {
+	if len(args) == 0 {
+		cmd.Usage()
+	}
+	f := flag.NewFlagSet("mod "+args[0], flag.ExitOnError)
+	f.Usage = func() { cmd.Usage() }
+	var run func([]string)
+	switch args[0] {
+	case "init":
+		run = runModInit
+	case "tidy":
+		f.BoolVar(&modV, "v", false, "")
+		run = runModTidy
+	case "vendor":
+		f.BoolVar(&modV, "v", false, "")
+		run = runModVendor
+	case "download":
+		f.BoolVar(&modJSON, "json", false, "")
+		run = runModDownload
+	default:
+		fatalf("go mod %s: unknown command\nRun 'go help mod' for usage.", args[0])
+	}
+	f.Parse(args[1:])
+	if os.Getenv("GO111MODULE") == "off" {
+		fatalf("go: modules disabled by GO111MODULE=off; see 'go help modules'")
+	}
+	run(f.Args())
+}
+
+func runModInit(args []string) {
+	if len(args) > 1 {
+		fatalf("go mod init: too many arguments")
+	}
+	file := filepath.Join(cwd, "go.mod")
+	if _, err := os.Stat(file); err == nil {
+		fatalf("go mod init: go.mod already exists")
+	}
+	var path string
+	if len(args) == 1 {
+		path = args[0]
+	} else {
+		for _, root := range filepath.SplitList(buildContext.GOPATH) {
+			if rel, ok := hasSubdir(filepath.Join(root, "src"), cwd); ok && rel != "" {
+				path = rel
+				break
+			}
+		}
+		if path == "" {
+			fatalf("go mod init: cannot determine module path for source directory %s (outside GOPATH)\n\n"+
+				"Example usage:\n\t'go mod init example.com/m' to initialize a v0 or v1 module\n"+
+				"\t'go mod init example.com/m/v2' to initialize a v2 module", cwd)
+		}
+	}
+	if err := checkModulePath(path); err != nil {
+		fatalf("go mod init: invalid module path: %v", err)
+	}
+	f := &modFile{module: path}
+	fmt.Fprintf(os.Stderr, "go: creating new go.mod: module %s\n", path)
+	if err := writeFileAtomic(file, f.format()); err != nil {
+		fatalf("go: %v", err)
+	}
+}
+
+func runModTidy(args []string) {
+	if len(args) > 0 {
+		fatalf("go mod tidy: no arguments allowed")
+	}
+	modLoad()
+
+	// Add modules for missing packages until the graph is complete.
+	// Adding a module can raise the versions of others, and the new
+	// versions may import still more packages.
+	var g *modGraph
+	for {
+		g = modImportGraph()
+		exitIfErrors()
+		if len(g.missing) == 0 {
+			break
+		}
+		var added []modVersion
+	Missing:
+		for _, path := range g.missing {
+			for _, m := range added {
+				if hasPathPrefix(path, m.Path) {
+					continue Missing
+				}
+			}
+			m, err := modQueryPackage(path)
+			if err != nil {
+				errorf("go: finding module for package %s: %v", path, err)
+				continue
+			}
+			if modV {
+				fmt.Fprintf(os.Stderr, "go: found %s in %s\n", path, m)
+			}
+			added = append(added, m)
+			modFileMod.require = append(modFileMod.require, modRequire{mod: m})
+		}
+		exitIfErrors()
+		modComputeBuildList()
+	}
+
+	// Require every module that provides a package at its selected
+	// version, marking those not imported directly as indirect.
+	old := modFileMod.require
+	var require []modRequire
+	for _, m := range modBuild[1:] {
+		if g.used[m] {
+			require = append(require, modRequire{mod: m, indirect: !g.direct[m]})
+		}
+	}
+	if modV {
+		for _, r := range old {
+			if m, ok := modBuildListModule(r.mod.Path); !ok || !g.used[m] {
+				fmt.Fprintf(os.Stderr, "unused %s\n", r.mod.Path)
+			}
+		}
+	}
+	modFileMod.require = require
+	modComputeBuildList()
+	if err := writeFileAtomic(filepath.Join(modRoot, "go.mod"), modFileMod.format()); err != nil {
+		fatalf("go: %v", err)
+	}
+
+	// Keep go.sum entries only for the modules in the build list
+	// and those whose go.mod files were consulted to compute it.
+	keep := make(map[string]bool)
+	for _, m := range modBuild {
+		keep[m.Path] = true
+	}
+	modTrimGoSum(func(path string) bool { return keep[path] })
+}
+
+func runModVendor(args []string) {
+	if len(args) > 0 {
+		fatalf("go mod vendor: no arguments allowed")
+	}
+	modLoad()
+	g := modImportGraph()
+	exitIfErrors()
+	if len(g.missing) > 0 {
+		fatalf("go mod vendor: %v", &modMissingError{g.missing[0]})
+	}
+
+	vdir := filepath.Join(modRoot, "vendor")
+	if err := os.RemoveAll(vdir); err != nil {
+		fatalf("go mod vendor: %v", err)
+	}
+
+	byModule := make(map[modVersion][]string)
+	for path, m := range g.mods {
+		if m != modTarget {
+			byModule[m] = append(byModule[m], path)
+		}
+	}
+	var buf bytes.Buffer
+	for _, m := range modBuild[1:] {
+		pkgs := byModule[m]
+		if len(pkgs) == 0 {
+			continue
+		}
+		sort.Strings(pkgs)
+		line := "# " + m.Path + " " + m.Version
+		if r, ok := modReplacement(m); ok {
+			line += " => " + r.Path
+			if r.Version != "" {
+				line += " " + r.Version
+			}
+		}
+		fmt.Fprintf(&buf, "%s\n", line)
+		if modV {
+			fmt.Fprintf(os.Stderr, "%s\n", line)
+		}
+		for _, pkg := range pkgs {
+			fmt.Fprintf(&buf, "%s\n", pkg)
+			if modV {
+				fmt.Fprintf(os.Stderr, "%s\n", pkg)
+			}
+			if err := modVendorPackage(g.pkgs[pkg], filepath.Join(vdir, filepath.FromSlash(pkg))); err != nil {
+				fatalf("go mod vendor: %v", err)
+			}
+		}
+	}
+	if buf.Len() == 0 {
+		fmt.Fprintf(os.Stderr, "go: no dependencies to vendor\n")
+		return
+	}
+	if err := ioutil.WriteFile(filepath.Join(vdir, "modules.txt"), buf.Bytes(), 0666); err != nil {
+		fatalf("go mod vendor: %v", err)
+	}
+}
+
+// modVendorPackage copies the files of the package in src, other than
+// its tests, into dst. Subdirectories are separate packages.
+func modVendorPackage(src, dst string) error {
+	if err := os.MkdirAll(dst, 0777); err != nil {
+		return err
+	}
+	fis, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		name := fi.Name()
+		if !fi.Mode().IsRegular() || strings.HasSuffix(name, "_test.go") || name == "go.mod" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(src, name))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dst, name), data, 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A modDownloadInfo is the JSON form printed by go mod download -json.
+type modDownloadInfo struct {
+	Path     string `json:",omitempty"`
+	Version  string `json:",omitempty"`
+	Error    string `json:",omitempty"`
+	GoMod    string `json:",omitempty"`
+	Zip      string `json:",omitempty"`
+	Dir      string `json:",omitempty"`
+	Sum      string `json:",omitempty"`
+	GoModSum string `json:",omitempty"`
+}
+
+func runModDownload(args []string) {
+	modLoad()
+	var mods []modVersion
+	if len(args) == 0 {
+		mods = modBuild[1:]
+	}
+	for _, arg := range args {
+		path, vers := arg, ""
+		if i := strings.Index(arg, "@"); i >= 0 {
+			path, vers = arg[:i], arg[i+1:]
+		}
+		if vers == "" {
+			m, ok := modBuildListModule(path)
+			if !ok || m == modTarget {
+				errorf("go mod download: module %s not in build list", path)
+				continue
+			}
+			mods = append(mods, m)
+			continue
+		}
+		m := modVersion{path, semverCanonical(vers)}
+		if err := checkModVersion(modVersion{path, vers}); err != nil {
+			errorf("go mod download: %v", err)
+			continue
+		}
+		mods = append(mods, m)
+	}
+	exitIfErrors()
+
+	for _, m := range mods {
+		info := &modDownloadInfo{Path: m.Path, Version: m.Version}
+		if r, ok := modReplacement(m); ok {
+			if r.Version == "" {
+				continue // nothing to download for a directory
+			}
+			info.Path, info.Version = r.Path, r.Version
+		}
+		r := modVersion{info.Path, info.Version}
+		if err := modDownloadInfoFor(r, info); err != nil {
+			info.Error = err.Error()
+			if !modJSON {
+				errorf("go mod download: %v", err)
+			}
+		}
+		if modJSON {
+			b, err := json.MarshalIndent(info, "", "\t")
+			if err != nil {
+				fatalf("%s", err)
+			}
+			os.Stdout.Write(append(b, '\n'))
+		}
+	}
+}
+
+// modDownloadInfoFor downloads the module version m and records the
+// locations and hashes of its files in info.
+func modDownloadInfoFor(m modVersion, info *modDownloadInfo) error {
+	if _, err := modGoModData(m); err != nil {
+		return err
+	}
+	dir, err := modDownload(m)
+	if err != nil {
+		return err
+	}
+	info.Dir = dir
+	dldir, err := modDownloadDir(m.Path)
+	if err != nil {
+		return err
+	}
+	encVer, err := modEscapePath(m.Version)
+	if err != nil {
+		return err
+	}
+	info.GoMod = filepath.Join(dldir, encVer+".mod")
+	info.Zip = filepath.Join(dldir, encVer+".zip")
+	info.Sum = modSumOf(m)
+	info.GoModSum = modSumOf(modVersion{m.Path, m.Version + "/go.mod"})
+	return nil
+}
+
+// A modGraph is the import graph of the main module's packages
+// and tests, as computed by modImportGraph.
+type modGraph struct {
+	pkgs    map[string]string     // import path to directory, for all non-standard packages
+	mods    map[string]modVersion // import path to module providing it
+	used    map[modVersion]bool   // modules providing packages
+	direct  map[modVersion]bool   // modules providing packages imported by the main module
+	missing []string              // import paths provided by no module, sorted
+}
+
+// modImportGraph computes the import graph of the packages in the
+// main module, including their tests, using all of their source files
+// regardless of build constraints. Errors other than missing packages
+// are reported with errorf.
+func modImportGraph() *modGraph {
+	g := &modGraph{
+		pkgs:   make(map[string]string),
+		mods:   make(map[string]modVersion),
+		used:   make(map[modVersion]bool),
+		direct: make(map[modVersion]bool),
+	}
+	missing := make(map[string]bool)
+
+	var queue []string
+	for _, dir := range modMainPackageDirs() {
+		path := modTarget.Path
+		if rel, _ := hasSubdir(modRoot, dir); rel != "" {
+			path += "/" + rel
+		}
+		g.pkgs[path] = dir
+		g.mods[path] = modTarget
+		queue = append(queue, path)
+	}
+
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		m := g.mods[path]
+		imports, err := modScanImports(g.pkgs[path], m == modTarget)
+		if err != nil {
+			errorf("go: %v", err)
+			continue
+		}
+		for _, imp := range imports {
+			if imp == "C" || build.IsLocalImport(imp) || modIsStandard(imp) || missing[imp] {
+				continue
+			}
+			if _, ok := g.pkgs[imp]; ok {
+				if m == modTarget {
+					g.direct[g.mods[imp]] = true
+				}
+				continue
+			}
+			dir, m1, err := modImportDir(imp)
+			if err != nil {
+				if _, ok := err.(*modMissingError); ok {
+					missing[imp] = true
+				} else {
+					errorf("go: %s: %v", path, err)
+				}
+				continue
+			}
+			g.pkgs[imp] = dir
+			g.mods[imp] = m1
+			g.used[m1] = true
+			if m == modTarget {
+				g.direct[m1] = true
+			}
+			queue = append(queue, imp)
+		}
+	}
+
+	for path := range missing {
+		g.missing = append(g.missing, path)
+	}
+	sort.Strings(g.missing)
+	return g
+}
+
+// modMainPackageDirs returns the directories of the packages in
+// the main module.
+func modMainPackageDirs() []string {
+	var dirs []string
+	filepath.Walk(modRoot, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.IsDir() {
+			return nil
+		}
+		if path != modRoot {
+			elem := fi.Name()
+			if strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") || elem == "testdata" || elem == "vendor" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		if hasGoFiles(path) {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return dirs
+}
+
+// modScanImports returns the sorted import paths in the Go source
+// files in dir, including test files if tests is set. Build
+// constraints are ignored, except that files marked "+build ignore"
+// are skipped, so that the result is the same on every system.
+func modScanImports(dir string, tests bool) ([]string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	seen := make(map[string]bool)
+	var list []string
+	for _, fi := range fis {
+		name := fi.Name()
+		if !fi.Mode().IsRegular() || !strings.HasSuffix(name, ".go") ||
+			strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+			!tests && strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), data, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		ignore := false
+		for _, cg := range f.Comments {
+			if cg.Pos() >= f.Package {
+				break
+			}
+			for _, c := range cg.List {
+				if fields := strings.Fields(strings.TrimPrefix(c.Text, "//")); len(fields) > 0 && fields[0] == "+build" {
+					for _, tag := range fields[1:] {
+						if tag == "ignore" {
+							ignore = true
+						}
+					}
+				}
+			}
+		}
+		if ignore {
+			continue
+		}
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			if !seen[path] {
+				seen[path] = true
+				list = append(list, path)
+			}
+		}
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// modQueryPackage returns the latest version of the module providing
+// the package with the given import path, trying the longest module
+// path first.
+func modQueryPackage(path string) (modVersion, error) {
+	var found []modVersion
+	for prefix := path; prefix != "."; prefix = pathpkg.Dir(prefix) {
+		if checkModulePath(prefix) != nil {
+			continue
+		}
+		list, err := modVersions(prefix)
+		if isModNotFound(err) || err == nil && len(list) == 0 {
+			continue
+		}
+		if err != nil {
+			return modVersion{}, err
+		}
+		m := modVersion{prefix, latestVersion(list)}
+		root, err := modDownload(m)
+		if err != nil {
+			return modVersion{}, err
+		}
+		if dir := filepath.Join(root, filepath.FromSlash(path[len(prefix):])); isDir(dir) && hasGoFiles(dir) {
+			return m, nil
+		}
+		found = append(found, m)
+	}
+	if len(found) > 0 {
+		return modVersion{}, fmt.Errorf("module %s found, but does not contain package %s", found[0], path)
+	}
+	return modVersion{}, fmt.Errorf("no module found")
+}
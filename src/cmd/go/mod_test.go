@@ -0,0 +1,192 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var semverCompareTests = []string{
+	"v0.0.0-alpha",
+	"v0.0.0-alpha.1",
+	"v0.0.0-alpha.beta",
+	"v0.0.0-beta",
+	"v0.0.0-beta.2",
+	"v0.0.0-beta.11",
+	"v0.0.0-rc.1",
+	"v0.0.0",
+	"v0.1.0",
+	"v0.9.0",
+	"v0.10.0",
+	"v1.0.0",
+	"v1.2.3",
+	"v2.0.0",
+}
+
+func TestSemverCompare(t *testing.T) {
+	for i, v := range semverCompareTests {
+		for j, w := range semverCompareTests {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = +1
+			}
+			if got := semverCompare(v, w); got != want {
+				t.Errorf("semverCompare(%q, %q) = %d, want %d", v, w, got, want)
+			}
+		}
+	}
+}
+
+var semverCanonicalTests = []struct {
+	in, out string
+}{
+	{"v1", "v1.0.0"},
+	{"v1.2", "v1.2.0"},
+	{"v1.2.3", "v1.2.3"},
+	{"v1.2.3-pre", "v1.2.3-pre"},
+	{"v1.2.3+meta", "v1.2.3"},
+	{"v2.0.0+incompatible", "v2.0.0+incompatible"},
+	{"1.2.3", ""},
+	{"v01.2.3", ""},
+	{"v1.2.3-01", ""},
+	{"v1.2.3-", ""},
+}
+
+func TestSemverCanonical(t *testing.T) {
+	for _, tt := range semverCanonicalTests {
+		if out := semverCanonical(tt.in); out != tt.out {
+			t.Errorf("semverCanonical(%q) = %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestMVSBuildList(t *testing.T) {
+	reqs := map[modVersion][]modVersion{
+		{"m", ""}:       {{"a", "v1.0.0"}, {"b", "v1.1.0"}},
+		{"a", "v1.0.0"}: {{"c", "v1.0.0"}},
+		{"b", "v1.1.0"}: {{"c", "v1.2.0"}, {"m", "v0.1.0"}},
+		{"c", "v1.0.0"}: nil,
+		{"c", "v1.2.0"}: {{"d", "v1.0.0"}},
+		{"d", "v1.0.0"}: nil,
+	}
+	list, err := mvsBuildList(modVersion{"m", ""}, func(m modVersion) ([]modVersion, error) {
+		r, ok := reqs[m]
+		if !ok {
+			return nil, fmt.Errorf("unknown module")
+		}
+		return r, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []modVersion{{"m", ""}, {"a", "v1.0.0"}, {"b", "v1.1.0"}, {"c", "v1.2.0"}, {"d", "v1.0.0"}}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("mvsBuildList = %v, want %v", list, want)
+	}
+
+	delete(reqs, modVersion{"d", "v1.0.0"})
+	_, err = mvsBuildList(modVersion{"m", ""}, func(m modVersion) ([]modVersion, error) {
+		r, ok := reqs[m]
+		if !ok {
+			return nil, fmt.Errorf("unknown module")
+		}
+		return r, nil
+	})
+	wantErr := "m requires\n\tb@v1.1.0 requires\n\tc@v1.2.0 requires\n\td@v1.0.0: unknown module"
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("mvsBuildList error = %v, want %q", err, wantErr)
+	}
+}
+
+const modFileText = `module example.com/m
+
+go 1.6
+
+require (
+	example.com/a v1.2.0
+	example.com/b/v2 v2.0.1 // indirect
+)
+
+replace (
+	example.com/a v1.2.0 => example.com/fork/a v1.2.1
+	example.com/c => ../c
+)
+`
+
+func TestModFileRoundTrip(t *testing.T) {
+	f, err := parseModFile("go.mod", []byte(modFileText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.module != "example.com/m" || f.goVersion != "1.6" {
+		t.Errorf("module %q, go %q", f.module, f.goVersion)
+	}
+	wantReq := []modRequire{
+		{modVersion{"example.com/a", "v1.2.0"}, false},
+		{modVersion{"example.com/b/v2", "v2.0.1"}, true},
+	}
+	if !reflect.DeepEqual(f.require, wantReq) {
+		t.Errorf("require = %v, want %v", f.require, wantReq)
+	}
+	wantRep := []modReplace{
+		{modVersion{"example.com/a", "v1.2.0"}, modVersion{"example.com/fork/a", "v1.2.1"}},
+		{modVersion{"example.com/c", ""}, modVersion{"../c", ""}},
+	}
+	if !reflect.DeepEqual(f.replace, wantRep) {
+		t.Errorf("replace = %v, want %v", f.replace, wantRep)
+	}
+	if out := string(f.format()); out != modFileText {
+		t.Errorf("format:\n%s\nwant:\n%s", out, modFileText)
+	}
+}
+
+var modFileErrorTests = []struct {
+	in, err string
+}{
+	{"", "go.mod:1: no module statement"},
+	{"module x\nmodule y\n", "go.mod:2: repeated module statement"},
+	{"module x\nrequire a.com/b 1.0.0\n", `go.mod:2: a.com/b: invalid version "1.0.0"`},
+	{"module x\nrequire a.com/b/v2 v1.0.0\n", "go.mod:2: a.com/b/v2: invalid version v1.0.0: should be v2"},
+	{"module x\nrequire a.com/b v2.0.0\n", "go.mod:2: a.com/b: invalid version v2.0.0: should be v0 or v1, not v2"},
+	{"module x\nreplace a.com/b => c.com/d\n", "go.mod:2: replacement module without version must be directory path"},
+	{"module x\nrequire (\n", "go.mod:3: missing ) at end of require block"},
+	{"module x\nexclude a.com/b v1.0.0\n", "go.mod:2: unknown directive: exclude"},
+}
+
+func TestModFileErrors(t *testing.T) {
+	for _, tt := range modFileErrorTests {
+		_, err := parseModFile("go.mod", []byte(tt.in))
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("parseModFile(%q) error = %v, want %q", tt.in, err, tt.err)
+		}
+	}
+}
+
+func TestModHashGoMod(t *testing.T) {
+	// The hash of a go.mod file is the hash of a tree
+	// containing just that file, named go.mod.
+	h := modHashGoMod([]byte("module example.com/a\n"))
+	if !strings.HasPrefix(h, "h1:") || len(h) != len("h1:")+44 {
+		t.Errorf("modHashGoMod = %q, want h1: and base64 SHA-256", h)
+	}
+	if h2 := modHashGoMod([]byte("module example.com/b\n")); h2 == h {
+		t.Errorf("modHashGoMod of different files = %q for both", h)
+	}
+}
+
+func TestModEscapePath(t *testing.T) {
+	got, err := modEscapePath("github.com/Azure/go-Auth")
+	if want := "github.com/!azure/go-!auth"; err != nil || got != want {
+		t.Errorf("modEscapePath = %q, %v, want %q", got, err, want)
+	}
+	if _, err := modEscapePath("example.com/!x"); err == nil {
+		t.Errorf("modEscapePath accepted !")
+	}
+}
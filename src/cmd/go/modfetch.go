@@ -0,0 +1,621 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// The module cache, in $GOPATH/pkg/mod, holds the extracted source
+// trees of module versions, in directories named path@version.
+// The cache/download subdirectory holds the files downloaded from
+// the module proxy, in the same layout that a proxy serves them:
+//
+//	path/@v/list          the known versions of path, one per line
+//	path/@v/version.mod   the go.mod file of the module version
+//	path/@v/version.zip   the source of the module version
+//
+// Upper-case letters in paths and versions are written as an
+// exclamation mark followed by the lower-case letter, so that the
+// layout works on case-insensitive file systems. The download
+// directory can itself serve as GOPROXY for another machine.
+
+// modCacheRoot returns the root directory of the module cache.
+func modCacheRoot() string {
+	list := filepath.SplitList(buildContext.GOPATH)
+	if len(list) == 0 || list[0] == "" {
+		fatalf("go: GOPATH must be set to locate the module cache ($GOPATH/pkg/mod)")
+	}
+	return filepath.Join(list[0], "pkg", "mod")
+}
+
+// modEscapePath returns the escaped form of the module path or version s,
+// as used in the module cache and the proxy protocol.
+func modEscapePath(s string) (string, error) {
+	var buf []byte
+	for _, r := range s {
+		switch {
+		case r == '!' || r >= utf8.RuneSelf:
+			return "", fmt.Errorf("invalid char %q in %q", r, s)
+		case 'A' <= r && r <= 'Z':
+			buf = append(buf, '!', byte(r+'a'-'A'))
+		default:
+			buf = append(buf, byte(r))
+		}
+	}
+	return string(buf), nil
+}
+
+// modDownloadDir returns the directory holding the downloaded files
+// for versions of the module path.
+func modDownloadDir(path string) (string, error) {
+	enc, err := modEscapePath(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(modCacheRoot(), "cache", "download", filepath.FromSlash(enc), "@v"), nil
+}
+
+// modExtractDir returns the directory holding the extracted source
+// tree of the module version m.
+func modExtractDir(m modVersion) (string, error) {
+	enc, err := modEscapePath(m.Path)
+	if err != nil {
+		return "", err
+	}
+	encVer, err := modEscapePath(m.Version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(modCacheRoot(), filepath.FromSlash(enc)+"@"+encVer), nil
+}
+
+// modProxy returns the GOPROXY setting: a file:// URL or absolute
+// directory path, an http:// or https:// URL, or "off".
+func modProxy() string {
+	proxy := os.Getenv("GOPROXY")
+	if proxy == "" {
+		proxy = "off"
+	}
+	return strings.TrimSuffix(proxy, "/")
+}
+
+// errModNotFound is returned (wrapped) by modProxyGet when the proxy
+// does not have the requested file.
+var errModNotFound = errors.New("not found")
+
+// modProxyGet returns the contents of the file with the given
+// slash-separated name, relative to the root of the module proxy.
+func modProxyGet(name string) ([]byte, error) {
+	proxy := modProxy()
+	switch {
+	case proxy == "off":
+		return nil, fmt.Errorf("module lookup disabled by GOPROXY=off")
+	case strings.HasPrefix(proxy, "http://"), strings.HasPrefix(proxy, "https://"):
+		data, err := httpGET(proxy + "/" + name)
+		if e, ok := err.(*httpError); ok && (e.statusCode == 404 || e.statusCode == 410) {
+			return nil, fmt.Errorf("reading %s/%s: %v", proxy, name, errModNotFound)
+		}
+		return data, err
+	}
+
+	dir := proxy
+	if strings.HasPrefix(dir, "file://") {
+		dir = filepath.FromSlash(strings.TrimPrefix(dir, "file://"))
+		if runtime.GOOS == "windows" && len(dir) > 2 && dir[0] == '\\' && dir[2] == ':' {
+			dir = dir[1:] // file:///C:/dir
+		}
+	}
+	if !filepath.IsAbs(dir) {
+		return nil, fmt.Errorf("invalid GOPROXY %q: must be off, a file:// or https:// URL, or an absolute directory path", proxy)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading %s/%s: %v", proxy, name, errModNotFound)
+	}
+	return data, err
+}
+
+// isModNotFound reports whether err reports a file missing from the proxy.
+func isModNotFound(err error) bool {
+	return err != nil && strings.HasSuffix(err.Error(), ": "+errModNotFound.Error())
+}
+
+// modProxyFile returns the proxy-relative name of the file
+// for the module version m with the given suffix, such as ".mod".
+func modProxyFile(m modVersion, suffix string) (string, error) {
+	enc, err := modEscapePath(m.Path)
+	if err != nil {
+		return "", err
+	}
+	encVer, err := modEscapePath(m.Version)
+	if err != nil {
+		return "", err
+	}
+	return enc + "/@v/" + encVer + suffix, nil
+}
+
+// modVersions returns the known release and prerelease versions of
+// the module path, sorted in increasing order. If GOPROXY is off,
+// modVersions reports the versions in the module cache.
+func modVersions(path string) ([]string, error) {
+	var data []byte
+	if modProxy() == "off" {
+		dir, err := modDownloadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		data, _ = ioutil.ReadFile(filepath.Join(dir, "list"))
+	} else {
+		enc, err := modEscapePath(path)
+		if err != nil {
+			return nil, err
+		}
+		data, err = modProxyGet(enc + "/@v/list")
+		if err != nil {
+			return nil, err
+		}
+	}
+	var list []string
+	for _, v := range strings.Fields(string(data)) {
+		if semverIsValid(v) && semverCanonical(v) == v && checkModVersion(modVersion{path, v}) == nil {
+			list = append(list, v)
+		}
+	}
+	sort.Sort(bySemver(list))
+	return list, nil
+}
+
+// modLatest returns the latest version of the module path:
+// the highest release version, or if there is none,
+// the highest prerelease version.
+func modLatest(path string) (string, error) {
+	list, err := modVersions(path)
+	if err != nil {
+		return "", err
+	}
+	if len(list) == 0 {
+		return "", fmt.Errorf("no versions of module %s", path)
+	}
+	return latestVersion(list), nil
+}
+
+// latestVersion returns the latest version in the non-empty sorted list.
+func latestVersion(list []string) string {
+	for i := len(list) - 1; i >= 0; i-- {
+		if semverPrerelease(list[i]) == "" {
+			return list[i]
+		}
+	}
+	return list[len(list)-1]
+}
+
+// modGoModData returns the contents of the go.mod file of the module
+// version m, downloading it if needed and checking it against go.sum.
+func modGoModData(m modVersion) ([]byte, error) {
+	dir, err := modDownloadDir(m.Path)
+	if err != nil {
+		return nil, err
+	}
+	encVer, err := modEscapePath(m.Version)
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(dir, encVer+".mod")
+	data, err := ioutil.ReadFile(file)
+	if err == nil {
+		if err := modCheckSum(modVersion{m.Path, m.Version + "/go.mod"}, modHashGoMod(data)); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	name, err := modProxyFile(m, ".mod")
+	if err != nil {
+		return nil, err
+	}
+	data, err = modProxyGet(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := modCheckSum(modVersion{m.Path, m.Version + "/go.mod"}, modHashGoMod(data)); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(file, data); err != nil {
+		return nil, err
+	}
+	modRecordVersion(dir, m.Version)
+	return data, nil
+}
+
+// modRecordVersion adds vers to the list file in the download directory dir,
+// so that the download directory can serve as a module proxy.
+func modRecordVersion(dir, vers string) {
+	file := filepath.Join(dir, "list")
+	data, _ := ioutil.ReadFile(file)
+	for _, v := range strings.Fields(string(data)) {
+		if v == vers {
+			return
+		}
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(f, "%s\n", vers)
+	f.Close()
+}
+
+// modDownload returns the directory holding the extracted source tree
+// of the module version m, downloading and extracting the module zip
+// file if needed. The zip file is checked against go.sum.
+func modDownload(m modVersion) (string, error) {
+	target, err := modExtractDir(m)
+	if err != nil {
+		return "", err
+	}
+	dldir, err := modDownloadDir(m.Path)
+	if err != nil {
+		return "", err
+	}
+	encVer, err := modEscapePath(m.Version)
+	if err != nil {
+		return "", err
+	}
+	zipfile := filepath.Join(dldir, encVer+".zip")
+
+	// The hash of an extracted module is recorded next to its zip file,
+	// so that go.sum can be checked without rehashing the zip file.
+	if _, err := os.Stat(target); err == nil {
+		if h, err := ioutil.ReadFile(zipfile + "hash"); err == nil {
+			if err := modCheckSum(m, strings.TrimSpace(string(h))); err != nil {
+				return "", err
+			}
+			return target, nil
+		}
+	}
+
+	if _, err := os.Stat(zipfile); err != nil {
+		name, err := modProxyFile(m, ".zip")
+		if err != nil {
+			return "", err
+		}
+		data, err := modProxyGet(name)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", m, err)
+		}
+		if err := os.MkdirAll(dldir, 0777); err != nil {
+			return "", err
+		}
+		if err := writeFileAtomic(zipfile, data); err != nil {
+			return "", err
+		}
+	}
+	h, err := modHashZip(zipfile)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", m, err)
+	}
+	if err := modCheckSum(m, h); err != nil {
+		os.Remove(zipfile)
+		return "", err
+	}
+	if err := modExtractZip(m, zipfile, target); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(zipfile+"hash", []byte(h+"\n")); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// modExtractZip extracts the module zip file for m into dir.
+// Every file in the zip must be in a top-level directory named path@version.
+func modExtractZip(m modVersion, zipfile, dir string) error {
+	z, err := zip.OpenReader(zipfile)
+	if err != nil {
+		return fmt.Errorf("%s: %v", m, err)
+	}
+	defer z.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(filepath.Dir(dir), filepath.Base(dir)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	prefix := m.Path + "@" + m.Version + "/"
+	for _, zf := range z.File {
+		if !strings.HasPrefix(zf.Name, prefix) {
+			return fmt.Errorf("%s: unexpected file name %s in zip file", m, zf.Name)
+		}
+		name := zf.Name[len(prefix):]
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if pathpkg.Clean(name) != name || strings.HasPrefix(name, "../") || strings.Contains(name, `\`) {
+			return fmt.Errorf("%s: invalid file name %s in zip file", m, zf.Name)
+		}
+		file := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+			return err
+		}
+		r, err := zf.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %v", m, err)
+		}
+		w, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if err != nil {
+			r.Close()
+			return err
+		}
+		_, err = io.Copy(w, r)
+		r.Close()
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", m, err)
+		}
+	}
+
+	if err := os.Rename(tmp, dir); err != nil {
+		if _, serr := os.Stat(dir); serr == nil {
+			// Extracted concurrently by another go command.
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to file, by way of a temporary file in
+// the same directory, so that concurrent readers never see a partial file.
+func writeFileAtomic(file string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// modHash1 returns the "h1:" hash of the named files:
+// the base64-encoded SHA-256 hash of a summary listing the
+// hexadecimal SHA-256 hash and name of each file, sorted by name.
+// It is the hash recorded in go.sum files.
+func modHash1(files []string, open func(string) (io.ReadCloser, error)) (string, error) {
+	files = append([]string(nil), files...)
+	sort.Strings(files)
+	summary := sha256.New()
+	for _, file := range files {
+		if strings.Contains(file, "\n") {
+			return "", errors.New("file names with newlines are not supported")
+		}
+		r, err := open(file)
+		if err != nil {
+			return "", err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, r)
+		r.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(summary, "%x  %s\n", h.Sum(nil), file)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+// modHashZip returns the hash of the files in the module zip file.
+func modHashZip(zipfile string) (string, error) {
+	z, err := zip.OpenReader(zipfile)
+	if err != nil {
+		return "", err
+	}
+	defer z.Close()
+	var files []string
+	byName := make(map[string]*zip.File)
+	for _, f := range z.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		if byName[f.Name] != nil {
+			return "", fmt.Errorf("duplicate file %s in zip file", f.Name)
+		}
+		files = append(files, f.Name)
+		byName[f.Name] = f
+	}
+	return modHash1(files, func(name string) (io.ReadCloser, error) {
+		return byName[name].Open()
+	})
+}
+
+// modHashGoMod returns the hash of the go.mod file data.
+func modHashGoMod(data []byte) string {
+	h, _ := modHash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	})
+	return h
+}
+
+// The go.sum file next to go.mod records the expected hashes of the
+// module versions used in the build: one line "path version hash" for
+// the source tree of each module version and one line
+// "path version/go.mod hash" for each go.mod file consulted.
+var modSum struct {
+	mu      sync.Mutex
+	file    string                  // go.sum file; "" if not loaded
+	m       map[modVersion][]string // hashes listed in go.sum
+	used    map[modVersion]bool     // entries used in this command
+	dirty   bool                    // hashes were added to m
+	noWrite bool                    // do not write go.sum (-mod=readonly)
+}
+
+// modInitGoSum loads the go.sum file. If writable, hashes of
+// newly downloaded modules are added to the file when the go
+// command exits.
+func modInitGoSum(file string, writable bool) error {
+	modSum.mu.Lock()
+	defer modSum.mu.Unlock()
+	modSum.file = file
+	modSum.m = make(map[modVersion][]string)
+	modSum.used = make(map[modVersion]bool)
+	modSum.noWrite = !writable
+	data, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 3 {
+			return fmt.Errorf("malformed go.sum:\n%s:%d: wrong number of fields %d", file, i+1, len(f))
+		}
+		m := modVersion{f[0], f[1]}
+		modSum.m[m] = append(modSum.m[m], f[2])
+	}
+	if writable {
+		atexit(modWriteGoSum)
+	}
+	return nil
+}
+
+// modCheckSum checks the hash h of the module version m, or for
+// a version ending in "/go.mod", of its go.mod file, against go.sum.
+// If go.sum has no entry for m, modCheckSum records h.
+func modCheckSum(m modVersion, h string) error {
+	modSum.mu.Lock()
+	defer modSum.mu.Unlock()
+	if modSum.m == nil {
+		return nil
+	}
+	modSum.used[m] = true
+	hashes := modSum.m[m]
+	for _, vh := range hashes {
+		if vh == h {
+			return nil
+		}
+	}
+	if len(hashes) > 0 {
+		return fmt.Errorf("verifying %s: checksum mismatch\n\tdownloaded: %s\n\tgo.sum:     %s", m, h, strings.Join(hashes, ", "))
+	}
+	if modSum.noWrite {
+		return fmt.Errorf("verifying %s: missing go.sum entry; to add it, run 'go mod download %s'", m, strings.TrimSuffix(m.String(), "/go.mod"))
+	}
+	modSum.m[m] = []string{h}
+	modSum.dirty = true
+	return nil
+}
+
+// modSumOf returns the go.sum hash of the module version m, if known.
+func modSumOf(m modVersion) string {
+	modSum.mu.Lock()
+	defer modSum.mu.Unlock()
+	if hashes := modSum.m[m]; len(hashes) > 0 {
+		return hashes[0]
+	}
+	return ""
+}
+
+// modTrimGoSum removes from go.sum the entries for the modules
+// for which keep returns false.
+func modTrimGoSum(keep func(path string) bool) {
+	modSum.mu.Lock()
+	defer modSum.mu.Unlock()
+	for m := range modSum.m {
+		if !keep(m.Path) {
+			delete(modSum.m, m)
+			modSum.dirty = true
+		}
+	}
+}
+
+// modWriteGoSum writes the go.sum file if hashes have been added to it.
+func modWriteGoSum() {
+	modSum.mu.Lock()
+	defer modSum.mu.Unlock()
+	if !modSum.dirty || modSum.noWrite || modSum.file == "" {
+		return
+	}
+	var ms []modVersion
+	for m := range modSum.m {
+		ms = append(ms, m)
+	}
+	sort.Sort(byGoSumLine(ms))
+	var buf bytes.Buffer
+	for _, m := range ms {
+		hashes := append([]string(nil), modSum.m[m]...)
+		sort.Strings(hashes)
+		for _, h := range hashes {
+			fmt.Fprintf(&buf, "%s %s %s\n", m.Path, m.Version, h)
+		}
+	}
+	if err := writeFileAtomic(modSum.file, buf.Bytes()); err != nil {
+		errorf("go: writing go.sum: %v", err)
+		return
+	}
+	modSum.dirty = false
+}
+
+// byGoSumLine sorts go.sum entries by path and version,
+// listing the hash of each go.mod file after that of its module.
+type byGoSumLine []modVersion
+
+func (x byGoSumLine) Len() int      { return len(x) }
+func (x byGoSumLine) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x byGoSumLine) Less(i, j int) bool {
+	if x[i].Path != x[j].Path {
+		return x[i].Path < x[j].Path
+	}
+	vi := strings.TrimSuffix(x[i].Version, "/go.mod")
+	vj := strings.TrimSuffix(x[j].Version, "/go.mod")
+	if c := semverCompare(vi, vj); c != 0 {
+		return c < 0
+	}
+	return x[i].Version < x[j].Version
+}
+
+type bySemver []string
+
+func (x bySemver) Len() int      { return len(x) }
+func (x bySemver) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x bySemver) Less(i, j int) bool {
+	if c := semverCompare(x[i], x[j]); c != 0 {
+		return c < 0
+	}
+	return x[i] < x[j]
+}
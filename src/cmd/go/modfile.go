@@ -0,0 +1,345 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A modFile is the parsed form of a go.mod file.
+type modFile struct {
+	module    string // module path
+	goVersion string // go directive, if any
+	require   []modRequire
+	replace   []modReplace
+}
+
+// A modRequire is a require directive in a go.mod file.
+type modRequire struct {
+	mod      modVersion
+	indirect bool // has "// indirect" comment
+}
+
+// A modReplace is a replace directive in a go.mod file.
+// If old.Version is empty, the replacement applies to all versions
+// of old.Path. If new.Version is empty, new.Path is a file system
+// directory, relative to the directory containing the go.mod file
+// unless it is absolute.
+type modReplace struct {
+	old, new modVersion
+}
+
+// parseModFile parses the go.mod file data, using file in error messages.
+func parseModFile(file string, data []byte) (*modFile, error) {
+	f := new(modFile)
+	var errs []string
+	errf := func(line int, format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf("%s:%d: %s", file, line, fmt.Sprintf(format, args...)))
+	}
+
+	block := "" // directive of the enclosing ( ) block, if any
+	for i, line := range strings.Split(string(data), "\n") {
+		lineno := i + 1
+		fields, comment, err := modFileFields(line)
+		if err != nil {
+			errf(lineno, "%v", err)
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		verb := block
+		if block == "" {
+			verb, fields = fields[0], fields[1:]
+			if len(fields) == 1 && fields[0] == "(" {
+				switch verb {
+				case "require", "replace":
+					block = verb
+				default:
+					errf(lineno, "unexpected ( after %s", verb)
+				}
+				continue
+			}
+		} else if len(fields) == 1 && fields[0] == ")" {
+			block = ""
+			continue
+		}
+
+		switch verb {
+		default:
+			errf(lineno, "unknown directive: %s", verb)
+
+		case "module":
+			if f.module != "" {
+				errf(lineno, "repeated module statement")
+				continue
+			}
+			if len(fields) != 1 {
+				errf(lineno, "usage: module module/path")
+				continue
+			}
+			if err := checkModulePath(fields[0]); err != nil {
+				errf(lineno, "invalid module path: %v", err)
+				continue
+			}
+			f.module = fields[0]
+
+		case "go":
+			if len(fields) != 1 || !strings.HasPrefix(fields[0], "1.") {
+				errf(lineno, "usage: go 1.6")
+				continue
+			}
+			f.goVersion = fields[0]
+
+		case "require":
+			if len(fields) != 2 {
+				errf(lineno, "usage: require module/path v1.2.3")
+				continue
+			}
+			m := modVersion{fields[0], fields[1]}
+			if err := checkModVersion(m); err != nil {
+				errf(lineno, "%v", err)
+				continue
+			}
+			m.Version = semverCanonical(m.Version)
+			f.require = append(f.require, modRequire{m, comment == "indirect"})
+
+		case "replace":
+			arrow := 1
+			if len(fields) >= 2 && fields[1] != "=>" {
+				arrow = 2
+			}
+			if len(fields) < arrow+2 || len(fields) > arrow+3 || fields[arrow] != "=>" {
+				errf(lineno, "usage: replace module/path [v1.2.3] => other/module v1.4\n\t or replace module/path [v1.2.3] => ../local/directory")
+				continue
+			}
+			var r modReplace
+			r.old.Path = fields[0]
+			if err := checkModulePath(r.old.Path); err != nil {
+				errf(lineno, "invalid module path: %v", err)
+				continue
+			}
+			if arrow == 2 {
+				r.old.Version = fields[1]
+				if err := checkModVersion(r.old); err != nil {
+					errf(lineno, "%v", err)
+					continue
+				}
+				r.old.Version = semverCanonical(r.old.Version)
+			}
+			r.new.Path = fields[arrow+1]
+			if len(fields) == arrow+2 {
+				if !isModDirPath(r.new.Path) {
+					errf(lineno, "replacement module without version must be directory path (rooted or starting with ./ or ../)")
+					continue
+				}
+			} else {
+				if isModDirPath(r.new.Path) {
+					errf(lineno, "replacement directory cannot have a version")
+					continue
+				}
+				r.new.Version = fields[arrow+2]
+				if err := checkModVersion(r.new); err != nil {
+					errf(lineno, "%v", err)
+					continue
+				}
+				r.new.Version = semverCanonical(r.new.Version)
+			}
+			f.replace = append(f.replace, r)
+		}
+	}
+	if block != "" {
+		errf(strings.Count(string(data), "\n")+1, "missing ) at end of %s block", block)
+	}
+	if f.module == "" && len(errs) == 0 {
+		errf(1, "no module statement")
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return f, nil
+}
+
+// modFileFields splits a go.mod line into fields, unquoting quoted
+// fields, and returns the text of any trailing // comment.
+func modFileFields(line string) (fields []string, comment string, err error) {
+	for {
+		line = strings.TrimLeft(line, " \t\r")
+		if line == "" {
+			return
+		}
+		if strings.HasPrefix(line, "//") {
+			comment = strings.TrimSpace(line[2:])
+			return
+		}
+		if line[0] == '"' || line[0] == '`' {
+			end := -1
+			if line[0] == '`' {
+				end = strings.IndexByte(line[1:], '`') + 1
+			} else {
+				for i := 1; i < len(line); i++ {
+					if line[i] == '\\' {
+						i++
+					} else if line[i] == '"' {
+						end = i
+						break
+					}
+				}
+			}
+			if end <= 0 {
+				return nil, "", fmt.Errorf("unterminated quoted string")
+			}
+			s, err := strconv.Unquote(line[:end+1])
+			if err != nil {
+				return nil, "", err
+			}
+			fields = append(fields, s)
+			line = line[end+1:]
+			continue
+		}
+		i := strings.IndexAny(line, " \t\r")
+		if i < 0 {
+			i = len(line)
+		}
+		if j := strings.Index(line[:i], "//"); j > 0 {
+			i = j
+		}
+		fields = append(fields, line[:i])
+		line = line[i:]
+	}
+}
+
+// isModDirPath reports whether the replacement path names a directory.
+func isModDirPath(path string) bool {
+	return strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") || path == "." || path == ".." ||
+		filepath.IsAbs(path) || strings.HasPrefix(path, "/")
+}
+
+// checkModulePath checks that path is a plausible module path.
+func checkModulePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("empty path")
+	}
+	if !utf8.ValidString(path) {
+		return fmt.Errorf("invalid UTF-8")
+	}
+	if path[0] == '/' || path[len(path)-1] == '/' || strings.Contains(path, "//") {
+		return fmt.Errorf("malformed path %q", path)
+	}
+	for _, elem := range strings.Split(path, "/") {
+		if elem[0] == '.' {
+			return fmt.Errorf("leading dot in path element of %q", path)
+		}
+	}
+	for _, r := range path {
+		switch {
+		case 'A' <= r && r <= 'Z', 'a' <= r && r <= 'z', '0' <= r && r <= '9':
+		case strings.ContainsRune("-._~/+", r):
+		default:
+			return fmt.Errorf("invalid char %q in path %q", r, path)
+		}
+	}
+	return nil
+}
+
+// checkModVersion checks that m.Path is a valid module path and
+// that m.Version is a valid version for it. A path ending in a major
+// version suffix /vN, for N ≥ 2, requires a version with major version vN.
+func checkModVersion(m modVersion) error {
+	if err := checkModulePath(m.Path); err != nil {
+		return fmt.Errorf("invalid module path: %v", err)
+	}
+	if !semverIsValid(m.Version) {
+		return fmt.Errorf("%s: invalid version %q", m.Path, m.Version)
+	}
+	major := semverMajor(m.Version)
+	suffix := ""
+	if i := strings.LastIndex(m.Path, "/"); i >= 0 {
+		if e := m.Path[i+1:]; len(e) >= 2 && e[0] == 'v' && isNum(e[1:]) && e[1] != '0' && e != "v1" {
+			suffix = e
+		}
+	}
+	switch {
+	case suffix != "" && major != suffix:
+		return fmt.Errorf("%s: invalid version %s: should be %s", m.Path, m.Version, suffix)
+	case suffix == "" && major != "v0" && major != "v1" && !strings.HasSuffix(m.Version, "+incompatible"):
+		return fmt.Errorf("%s: invalid version %s: should be v0 or v1, not %s", m.Path, m.Version, major)
+	}
+	return nil
+}
+
+// format returns the canonical text of the go.mod file f,
+// with requirements and replacements sorted by module path.
+func (f *modFile) format() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "module %s\n", modQuote(f.module))
+	if f.goVersion != "" {
+		fmt.Fprintf(&buf, "\ngo %s\n", f.goVersion)
+	}
+
+	req := append([]modRequire(nil), f.require...)
+	sort.Sort(byRequirePath(req))
+	var lines []string
+	for _, r := range req {
+		line := modQuote(r.mod.Path) + " " + r.mod.Version
+		if r.indirect {
+			line += " // indirect"
+		}
+		lines = append(lines, line)
+	}
+	formatModBlock(&buf, "require", lines)
+
+	lines = nil
+	for _, r := range f.replace {
+		line := modQuote(r.old.Path)
+		if r.old.Version != "" {
+			line += " " + r.old.Version
+		}
+		line += " => " + modQuote(r.new.Path)
+		if r.new.Version != "" {
+			line += " " + r.new.Version
+		}
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+	formatModBlock(&buf, "replace", lines)
+	return buf.Bytes()
+}
+
+// formatModBlock writes the directive verb with arguments lines to buf,
+// using a ( ) block if there is more than one.
+func formatModBlock(buf *bytes.Buffer, verb string, lines []string) {
+	switch len(lines) {
+	case 0:
+	case 1:
+		fmt.Fprintf(buf, "\n%s %s\n", verb, lines[0])
+	default:
+		fmt.Fprintf(buf, "\n%s (\n", verb)
+		for _, line := range lines {
+			fmt.Fprintf(buf, "\t%s\n", line)
+		}
+		fmt.Fprintf(buf, ")\n")
+	}
+}
+
+// modQuote returns s, quoted if necessary to be parsed as a single field.
+func modQuote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"`()") || strings.Contains(s, "//") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+type byRequirePath []modRequire
+
+func (x byRequirePath) Len() int           { return len(x) }
+func (x byRequirePath) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x byRequirePath) Less(i, j int) bool { return x[i].mod.Path < x[j].mod.Path }
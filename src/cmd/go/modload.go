@@ -0,0 +1,652 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var helpModules = &Command{
+	UsageLine: "modules",
+	Short:     "modules, module versions, and more",
+	Long: `
+A module is a collection of related Go packages that are versioned
+together as a single unit. Modules record precise dependency requirements
+and create reproducible builds.
+
+A module is defined by a tree of Go source files with a go.mod file
+in the tree's root directory. The go.mod file declares the module path,
+which is the import path prefix for all the packages in the module,
+and the minimum versions of the other modules it requires:
+
+	module example.com/hello
+
+	require (
+		example.com/greet v1.2.0
+		example.com/util v0.3.1 // indirect
+	)
+
+	replace example.com/util => ../util
+
+Each require directive names a module path and a semantic version,
+such as v1.2.0 or v2.0.0-beta.1. A module path ending in a major
+version suffix such as /v2 must be required at a version with that
+major version. The "// indirect" comment marks requirements of modules
+that provide no packages imported directly by the main module.
+A replace directive substitutes another module version, or a directory
+containing a go.mod file, for the given module, or for one version of it.
+Replacements apply only in the main module's go.mod file.
+
+Module mode
+
+The go command works in module mode when the GO111MODULE environment
+variable is set to "on", or when it is unset or "auto" and a go.mod
+file is found in the current directory or one of its parents.
+The module containing that go.mod file is the main module.
+Setting GO111MODULE=off selects the GOPATH mode described in
+'go help gopath' even if there is a go.mod file.
+
+In module mode, an import path is resolved to a package in the standard
+library, in the main module, or in exactly one of the modules in the
+build list; GOPATH/src and vendor directories are not consulted.
+The build list is computed by minimal version selection: starting from
+the requirements of the main module, it includes every module reachable
+through the requirements in the go.mod files of the required module
+versions, each at the maximum of the versions required for it.
+Because the selected versions depend only on the go.mod files,
+and not on when the build happens or which versions have since been
+published, the same go.mod files always produce the same build list.
+
+Module downloads and verification
+
+The go command downloads the module versions in the build list as
+needed, into the module cache in $GOPATH/pkg/mod, and afterward works
+from the cache without network access. Module versions are fetched from
+the module proxy named by the GOPROXY environment variable, which may be:
+
+	off
+		Use only the module cache. This is the default.
+	file:///path/to/proxy, /path/to/proxy
+		Read module versions from a local directory, laid out like
+		$GOPATH/pkg/mod/cache/download. That directory of one machine's
+		module cache can therefore serve as GOPROXY for another.
+	https://host/path
+		Read module versions from a module proxy server.
+
+The proxy directory for a module path holds, in the subdirectory @v,
+a list file listing the known versions one per line, and for each
+version v a v.mod file holding its go.mod file and a v.zip file holding
+its source tree, in which every file name starts with path@v/.
+Upper-case letters in paths and versions are written as an exclamation
+mark followed by the lower-case letter.
+
+The go.sum file next to go.mod records the expected cryptographic
+hashes of the content of each module version and of each go.mod
+file used in the build. When the go command downloads a module or
+finds it in the cache, it checks its hash against go.sum and reports
+a checksum mismatch if they differ. The hashes of modules not yet in
+go.sum are added to it. Both go.mod and go.sum should be checked into
+version control. Hashes cover the file contents exactly as stored in
+the module zip files, so builds on different operating systems, such
+as Linux and z/OS, verify against the same go.sum.
+
+Vendoring
+
+The 'go mod vendor' command copies the packages needed to build and
+test the main module into its vendor directory, along with a
+vendor/modules.txt file recording the module versions they came from.
+Building with the -mod=vendor flag then uses only the main module and
+the vendor directory, without consulting the build list or the module
+cache. The -mod=readonly flag instead makes the go command fail rather
+than add missing hashes to go.sum.
+
+See 'go help mod' for the commands that maintain go.mod, go.sum and
+the module cache.
+	`,
+}
+
+// Module mode state. The main module and its build list are loaded on
+// first use by modLoad.
+var (
+	modOn      bool   // module mode is enabled
+	modRoot    string // directory containing the main module's go.mod; "" if none
+	modMode    string // -mod flag
+	modInitMu  sync.Once
+	modLoadMu  sync.Once
+	modTarget  modVersion   // the main module
+	modFileMod *modFile     // the main module's go.mod file
+	modBuild   []modVersion // the build list; modBuild[0] is modTarget
+
+	modDirs      = make(map[modVersion]string)       // directories of module versions
+	modPkgModule = make(map[string]modVersion)       // import path to module providing it
+	modReqCache  = make(map[modVersion][]modVersion) // requirements of module versions
+	modVendorMod = make(map[string]modVersion)       // -mod=vendor: import path to module
+)
+
+// modEnabled reports whether the go command is in module mode.
+func modEnabled() bool {
+	modInitMu.Do(modInit)
+	return modOn
+}
+
+func modInit() {
+	env := os.Getenv("GO111MODULE")
+	switch env {
+	default:
+		fatalf("go: unknown environment setting GO111MODULE=%s", env)
+	case "off":
+		return
+	case "", "auto", "on":
+	}
+	modRoot = findModRoot(cwd)
+	modOn = modRoot != "" || env == "on"
+}
+
+// findModRoot returns the directory containing the go.mod file
+// governing dir, or "" if there is none.
+func findModRoot(dir string) string {
+	dir = filepath.Clean(dir)
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil && !fi.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// modGoModPath returns the path of the main module's go.mod file,
+// or "" if the go command is not in module mode.
+func modGoModPath() string {
+	if !modEnabled() || modRoot == "" {
+		return ""
+	}
+	return filepath.Join(modRoot, "go.mod")
+}
+
+// modLoad loads the main module and computes the build list.
+func modLoad() {
+	if !modEnabled() {
+		fatalf("go: internal error: modLoad called outside module mode")
+	}
+	modLoadMu.Do(func() {
+		switch modMode {
+		case "", "readonly", "vendor":
+		default:
+			fatalf("go: -mod=%s not recognized; must be readonly or vendor", modMode)
+		}
+		if modRoot == "" {
+			fatalf("go: cannot find main module; see 'go help modules'")
+		}
+		modReadGoMod()
+		if err := modInitGoSum(filepath.Join(modRoot, "go.sum"), modMode == ""); err != nil {
+			fatalf("go: %v", err)
+		}
+		if modMode == "vendor" {
+			modReadVendor()
+			return
+		}
+		modComputeBuildList()
+	})
+}
+
+// modReadGoMod reads and parses the main module's go.mod file.
+func modReadGoMod() {
+	file := filepath.Join(modRoot, "go.mod")
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		fatalf("go: %v", err)
+	}
+	f, err := parseModFile(shortPath(file), data)
+	if err != nil {
+		fatalf("go: errors parsing go.mod:\n%v", err)
+	}
+	modFileMod = f
+	modTarget = modVersion{Path: f.module}
+	modDirs[modTarget] = modRoot
+}
+
+// modComputeBuildList computes modBuild from the requirements in modFileMod.
+func modComputeBuildList() {
+	list, err := mvsBuildList(modTarget, modReqs)
+	if err != nil {
+		fatalf("go: %v", err)
+	}
+	modBuild = list
+}
+
+// modReqs returns the requirements of the module version m,
+// from the main module's go.mod file or that of m.
+func modReqs(m modVersion) ([]modVersion, error) {
+	if m == modTarget {
+		var list []modVersion
+		for _, r := range modFileMod.require {
+			list = append(list, r.mod)
+		}
+		return list, nil
+	}
+	if list, ok := modReqCache[m]; ok {
+		return list, nil
+	}
+	f, err := modGoModFile(m)
+	if err != nil {
+		return nil, err
+	}
+	var list []modVersion
+	for _, r := range f.require {
+		list = append(list, r.mod)
+	}
+	modReqCache[m] = list
+	return list, nil
+}
+
+// modGoModFile returns the parsed go.mod file of the module version m,
+// after applying the main module's replacements.
+func modGoModFile(m modVersion) (*modFile, error) {
+	r, replaced := modReplacement(m)
+	var data []byte
+	var err error
+	if replaced && r.Version == "" {
+		dir := modReplaceDir(r.Path)
+		data, err = ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if os.IsNotExist(err) {
+			return &modFile{module: m.Path}, nil
+		}
+	} else {
+		data, err = modGoModData(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	f, err := parseModFile("go.mod", data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %v", err)
+	}
+	if f.module != m.Path && !replaced {
+		return nil, fmt.Errorf("parsing go.mod: unexpected module path %q", f.module)
+	}
+	return f, nil
+}
+
+// modReplacement returns the replacement for the module version m
+// in the main module's go.mod file, or m itself if there is none.
+func modReplacement(m modVersion) (modVersion, bool) {
+	var found *modReplace
+	for i := range modFileMod.replace {
+		r := &modFileMod.replace[i]
+		if r.old.Path == m.Path && (r.old.Version == "" || r.old.Version == m.Version) {
+			found = r
+			if r.old.Version != "" {
+				break
+			}
+		}
+	}
+	if found == nil {
+		return m, false
+	}
+	return found.new, true
+}
+
+// modReplaceDir returns the absolute form of the replacement directory dir.
+func modReplaceDir(dir string) string {
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(modRoot, filepath.FromSlash(dir))
+}
+
+// modDir returns the directory holding the source tree of the module
+// version m, downloading it into the module cache if needed.
+func modDir(m modVersion) (string, error) {
+	if dir, ok := modDirs[m]; ok {
+		return dir, nil
+	}
+	var dir string
+	var err error
+	if r, replaced := modReplacement(m); replaced && r.Version == "" {
+		dir = modReplaceDir(r.Path)
+		if !isDir(dir) {
+			err = fmt.Errorf("replacement directory %s does not exist", r.Path)
+		}
+	} else {
+		dir, err = modDownload(r)
+	}
+	if err != nil {
+		return "", err
+	}
+	modDirs[m] = dir
+	return dir, nil
+}
+
+// modBuildListModule returns the module version of path in the build list.
+func modBuildListModule(path string) (modVersion, bool) {
+	for _, m := range modBuild {
+		if m.Path == path {
+			return m, true
+		}
+	}
+	return modVersion{}, false
+}
+
+// modIsStandard reports whether the import path names a package
+// in the standard library. Paths whose first element contains a dot
+// are never standard; other paths are standard if GOROOT has them.
+func modIsStandard(path string) bool {
+	if !isStandardImportPath(path) {
+		return false
+	}
+	return path == "C" || path == "unsafe" || isDir(filepath.Join(gorootSrc, filepath.FromSlash(path)))
+}
+
+// A modMissingError reports that no module in the build list
+// provides the package with the given import path.
+type modMissingError struct {
+	path string
+}
+
+func (e *modMissingError) Error() string {
+	if modMode == "vendor" {
+		return fmt.Sprintf("cannot find package %q in vendor directory; run 'go mod vendor'", e.path)
+	}
+	return fmt.Sprintf("cannot find module providing package %s; run 'go mod tidy' to add it", e.path)
+}
+
+// modImportDir returns the directory holding the package with the
+// given import path in module mode, and the module providing it.
+func modImportDir(path string) (string, modVersion, error) {
+	modLoad()
+	if hasPathPrefix(path, modTarget.Path) {
+		dir := filepath.Join(modRoot, filepath.FromSlash(path[len(modTarget.Path):]))
+		if isDir(dir) && !inNestedModule(modRoot, dir) {
+			return dir, modTarget, nil
+		}
+	}
+	if modMode == "vendor" {
+		dir := filepath.Join(modRoot, "vendor", filepath.FromSlash(path))
+		if m, ok := modVendorMod[path]; ok && isDir(dir) {
+			return dir, m, nil
+		}
+		return "", modVersion{}, &modMissingError{path}
+	}
+
+	var dirs []string
+	var mods []modVersion
+	for _, m := range modBuild[1:] {
+		if !hasPathPrefix(path, m.Path) {
+			continue
+		}
+		root, err := modDir(m)
+		if err != nil {
+			return "", modVersion{}, err
+		}
+		dir := filepath.Join(root, filepath.FromSlash(path[len(m.Path):]))
+		if isDir(dir) && hasGoFiles(dir) && !inNestedModule(root, dir) {
+			dirs = append(dirs, dir)
+			mods = append(mods, m)
+		}
+	}
+	switch len(dirs) {
+	case 0:
+		return "", modVersion{}, &modMissingError{path}
+	case 1:
+		return dirs[0], mods[0], nil
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "ambiguous import: found %s in multiple modules:", path)
+	for i, m := range mods {
+		fmt.Fprintf(&buf, "\n\t%s (%s)", m, dirs[i])
+	}
+	return "", modVersion{}, fmt.Errorf("%s", buf.String())
+}
+
+// inNestedModule reports whether dir, inside the tree of the module
+// rooted at root, belongs to another module with its own go.mod file.
+func inNestedModule(root, dir string) bool {
+	for dir != root && len(dir) > len(root) {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return true
+		}
+		dir = filepath.Dir(dir)
+	}
+	return false
+}
+
+// modImportPackage is the module mode form of buildContext.Import
+// for the non-standard import path.
+func modImportPackage(path string, mode build.ImportMode) (*build.Package, error) {
+	dir, m, err := modImportDir(path)
+	if err != nil {
+		return &build.Package{ImportPath: path}, err
+	}
+	// Import comments do not apply in module mode: the module path
+	// determines the import paths of the packages in a module.
+	bp, err := buildContext.ImportDir(dir, mode&^build.ImportComment)
+	bp.ImportPath = path
+	bp.Goroot = false
+	bp.Root = ""
+	bp.SrcRoot = ""
+	bp.PkgRoot = ""
+	bp.PkgObj = ""
+	bp.PkgTargetRoot = ""
+	bp.BinDir = modBinDir()
+	modPkgModule[path] = m
+	return bp, err
+}
+
+// modBinDir returns the directory where go install installs commands
+// in module mode: $GOBIN, or else the bin directory of the first
+// GOPATH entry.
+func modBinDir() string {
+	if gobin != "" {
+		return gobin
+	}
+	if list := filepath.SplitList(buildContext.GOPATH); len(list) > 0 && list[0] != "" {
+		return filepath.Join(list[0], "bin")
+	}
+	return ""
+}
+
+// modDirImportPath returns the import path of the package in the
+// directory dir in module mode, if dir is in the main module, in its
+// vendor directory, or in a module version already located.
+func modDirImportPath(dir string) (string, bool) {
+	modLoad()
+	dir = filepath.Clean(dir)
+	if modMode == "vendor" {
+		if rel, ok := modSubdir(filepath.Join(modRoot, "vendor"), dir); ok && rel != "" {
+			return rel, true
+		}
+	}
+	if rel, ok := modSubdir(modRoot, dir); ok && !inNestedModule(modRoot, dir) {
+		if rel == "" {
+			return modTarget.Path, true
+		}
+		return modTarget.Path + "/" + rel, true
+	}
+	for m, root := range modDirs {
+		if rel, ok := modSubdir(root, dir); ok && m != modTarget {
+			if rel == "" {
+				return m.Path, true
+			}
+			return m.Path + "/" + rel, true
+		}
+	}
+	return "", false
+}
+
+// modSubdir is like hasSubdir but also accepts dir == root,
+// returning an empty rel.
+func modSubdir(root, dir string) (rel string, ok bool) {
+	if r, err := filepath.EvalSymlinks(root); err == nil {
+		root = r
+	}
+	if d, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = d
+	}
+	if filepath.Clean(root) == filepath.Clean(dir) {
+		return "", true
+	}
+	return hasSubdir(root, dir)
+}
+
+// modMatchPackages returns the import paths of the packages in the
+// main module and the build list that match pattern.
+// The walk skips import paths in have and adds them to it.
+func modMatchPackages(pattern string, have map[string]bool) []string {
+	modLoad()
+	match := matchPattern(pattern)
+	treeCanMatch := treeCanMatchPattern(pattern)
+
+	type root struct {
+		dir, path string
+	}
+	roots := []root{{modRoot, modTarget.Path}}
+	if modMode == "vendor" {
+		roots = append(roots, root{filepath.Join(modRoot, "vendor"), ""})
+	} else {
+		for _, m := range modBuild[1:] {
+			if !treeCanMatch(m.Path) {
+				continue
+			}
+			dir, err := modDir(m)
+			if err != nil {
+				errorf("go: %v", err)
+				continue
+			}
+			roots = append(roots, root{dir, m.Path})
+		}
+	}
+
+	var pkgs []string
+	for _, r := range roots {
+		filepath.Walk(r.dir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil || !fi.IsDir() {
+				return nil
+			}
+			name := r.path
+			if path != r.dir {
+				// Avoid .foo, _foo, testdata and vendor directory trees,
+				// and nested modules.
+				_, elem := filepath.Split(path)
+				if strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") || elem == "testdata" || elem == "vendor" {
+					return filepath.SkipDir
+				}
+				if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil && r.path != "" {
+					return filepath.SkipDir
+				}
+				name = pathpkg.Join(r.path, filepath.ToSlash(path[len(r.dir)+1:]))
+			}
+			if name == "" {
+				return nil // root of vendor directory
+			}
+			if !treeCanMatch(name) {
+				return filepath.SkipDir
+			}
+			if have[name] || !match(name) {
+				return nil
+			}
+			have[name] = true
+			if _, err := buildContext.ImportDir(path, 0); err != nil {
+				if _, noGo := err.(*build.NoGoError); noGo {
+					return nil
+				}
+			}
+			pkgs = append(pkgs, name)
+			return nil
+		})
+	}
+	return pkgs
+}
+
+// modAllPackages returns the packages matched by "all" in module mode:
+// the packages in the main module and their dependencies.
+func modAllPackages() []string {
+	paths := modMatchPackages(modTarget.Path+"/...", map[string]bool{})
+	seen := make(map[string]bool)
+	var all []string
+	for _, p := range packageList(packages(paths)) {
+		if !seen[p.ImportPath] {
+			seen[p.ImportPath] = true
+			all = append(all, p.ImportPath)
+		}
+	}
+	sort.Strings(all)
+	return all
+}
+
+// modReadVendor reads vendor/modules.txt, which records the modules
+// providing the packages in the vendor directory, for -mod=vendor.
+func modReadVendor() {
+	file := filepath.Join(modRoot, "vendor", "modules.txt")
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		fatalf("go: -mod=vendor requires vendor/modules.txt: %v; run 'go mod vendor'", err)
+	}
+	var m modVersion
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "# ") {
+			f := strings.Fields(line[2:])
+			m = modVersion{}
+			if len(f) >= 2 {
+				m = modVersion{f[0], f[1]}
+			}
+			continue
+		}
+		if line = strings.TrimSpace(line); line != "" && m.Path != "" {
+			modVendorMod[line] = m
+		}
+	}
+}
+
+// A ModulePublic describes a module, as reported by go list.
+type ModulePublic struct {
+	Path    string        `json:",omitempty"` // module path
+	Version string        `json:",omitempty"` // module version
+	Replace *ModulePublic `json:",omitempty"` // replaced by this module
+	Main    bool          `json:",omitempty"` // is this the main module?
+	Dir     string        `json:",omitempty"` // directory holding files for this module, if any
+	GoMod   string        `json:",omitempty"` // path to go.mod file for this module, if any
+}
+
+// modPublic returns the go list description of the module version m.
+func modPublic(m modVersion) *ModulePublic {
+	info := &ModulePublic{Path: m.Path, Version: m.Version, Main: m == modTarget}
+	if m == modTarget {
+		info.Dir = modRoot
+		info.GoMod = filepath.Join(modRoot, "go.mod")
+		return info
+	}
+	if modMode == "vendor" {
+		return info
+	}
+	info.Dir = modDirs[m]
+	r, replaced := modReplacement(m)
+	if replaced {
+		info.Replace = &ModulePublic{Path: r.Path, Version: r.Version}
+		if r.Version == "" {
+			info.Replace.Dir = modReplaceDir(r.Path)
+			info.Replace.GoMod = filepath.Join(info.Replace.Dir, "go.mod")
+			return info
+		}
+	}
+	if dir, err := modDownloadDir(r.Path); err == nil {
+		if encVer, err := modEscapePath(r.Version); err == nil {
+			info.GoMod = filepath.Join(dir, encVer+".mod")
+		}
+	}
+	return info
+}
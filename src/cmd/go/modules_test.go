@@ -0,0 +1,224 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests for module mode.
+
+package main_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// modProxyFiles are the module versions served by the test module proxy,
+// keyed by path@version, with the go.mod file as the file "go.mod".
+var modProxyFiles = map[string]map[string]string{
+	"example.com/a@v1.0.0": {
+		"go.mod": "module example.com/a\n",
+		"a.go":   "package a\n\nconst V = \"a1.0.0\"\n",
+	},
+	"example.com/a@v1.1.0": {
+		"go.mod": "module example.com/a\n",
+		"a.go":   "package a\n\nconst V = \"a1.1.0\"\n",
+	},
+	"example.com/b@v1.0.0": {
+		"go.mod": "module example.com/b\n\nrequire example.com/a v1.1.0\n",
+		"b.go":   "package b\n\nimport \"example.com/a\"\n\nconst V = \"b1.0.0 \" + a.V\n",
+	},
+	"example.com/zos@v1.0.0": {
+		"go.mod":      "module example.com/zos\n",
+		"zos.go":      "package zos\n",
+		"zos_zos.go":  "package zos\n\nimport _ \"example.com/a\"\n",
+		"ignored.go":  "// +build ignore\n\npackage main\n\nimport _ \"example.com/missing\"\n",
+		"zos_test.go": "package zos\n\nimport _ \"example.com/missing\"\n",
+	},
+}
+
+// modSetup writes the test module proxy into the directory proxy
+// and configures tg to use it, with the module cache in a fresh GOPATH.
+func modSetup(tg *testgoData) {
+	for key, files := range modProxyFiles {
+		i := strings.Index(key, "@")
+		path, vers := key[:i], key[i+1:]
+		dir := "proxy/" + path + "/@v/"
+		tg.tempFile(dir+vers+".mod", files["go.mod"])
+		list, _ := ioutil.ReadFile(tg.path(dir + "list"))
+		tg.tempFile(dir+"list", string(list)+vers+"\n")
+
+		var buf bytes.Buffer
+		z := zip.NewWriter(&buf)
+		for name, data := range files {
+			w, err := z.Create(key + "/" + name)
+			tg.must(err)
+			_, err = w.Write([]byte(data))
+			tg.must(err)
+		}
+		tg.must(z.Close())
+		tg.must(ioutil.WriteFile(tg.path(dir+vers+".zip"), buf.Bytes(), 0666))
+	}
+	tg.tempDir("gopath")
+	tg.setenv("GOPATH", tg.path("gopath"))
+	tg.setenv("GOPROXY", "file://"+filepath.ToSlash(tg.path("proxy")))
+	tg.setenv("GO111MODULE", "auto")
+}
+
+const modMainSrc = `package main
+
+import (
+	"fmt"
+
+	"example.com/a"
+	"example.com/b"
+)
+
+func main() { fmt.Println(a.V, "|", b.V) }
+`
+
+func TestModMinimalVersionSelection(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	modSetup(tg)
+	tg.tempFile("m/go.mod", "module example.com/m\n\nrequire (\n\texample.com/a v1.0.0\n\texample.com/b v1.0.0\n)\n")
+	tg.tempFile("m/main.go", modMainSrc)
+	tg.cd(tg.path("m"))
+
+	// b requires a v1.1.0, which is higher than the main module's v1.0.0.
+	tg.run("run", "main.go")
+	tg.grepStdout(`^a1.1.0 \| b1.0.0 a1.1.0$`, "did not select example.com/a v1.1.0")
+
+	sum, err := ioutil.ReadFile(tg.path("m/go.sum"))
+	tg.must(err)
+	for _, line := range []string{"example.com/a v1.1.0 h1:", "example.com/a v1.0.0/go.mod h1:", "example.com/b v1.0.0 h1:"} {
+		if !bytes.Contains(sum, []byte("\n"+line)) && !bytes.HasPrefix(sum, []byte(line)) {
+			t.Errorf("go.sum lacks %q:\n%s", line, sum)
+		}
+	}
+
+	tg.run("list", "-f", "{{.ImportPath}} {{.Module.Path}} {{.Module.Version}}", "example.com/a", "example.com/m")
+	tg.grepStdout(`^example.com/a example.com/a v1.1.0$`, "wrong module for example.com/a")
+	tg.grepStdout(`^example.com/m example.com/m $`, "wrong module for example.com/m")
+
+	tg.run("build", "-o", "m"+exeSuffix, ".")
+	tg.run("version", "-m", "m"+exeSuffix)
+	tg.grepStdout(`^\tpath\texample.com/m$`, "go version -m did not report the main package")
+	tg.grepStdout(`^\tdep\texample.com/a\tv1.1.0\th1:`, "go version -m did not report example.com/a v1.1.0")
+	tg.grepStdout(`^\tdep\texample.com/b\tv1.0.0\th1:`, "go version -m did not report example.com/b v1.0.0")
+
+	// The module cache works offline.
+	tg.setenv("GOPROXY", "off")
+	tg.run("build", "-o", "m"+exeSuffix, ".")
+}
+
+func TestModTidy(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	modSetup(tg)
+	tg.tempFile("m/go.mod", "module example.com/m\n\nrequire example.com/zos v1.0.0\n")
+	tg.tempFile("m/main.go", modMainSrc)
+	tg.cd(tg.path("m"))
+
+	tg.runFail("build", ".")
+	tg.grepStderr("cannot find module providing package example.com/b", "missing module not reported")
+
+	tg.run("mod", "tidy")
+	data, err := ioutil.ReadFile(tg.path("m/go.mod"))
+	tg.must(err)
+	want := "module example.com/m\n\nrequire (\n\texample.com/a v1.1.0\n\texample.com/b v1.0.0\n)\n"
+	if string(data) != want {
+		t.Errorf("go.mod after go mod tidy:\n%s\nwant:\n%s", data, want)
+	}
+	sum, err := ioutil.ReadFile(tg.path("m/go.sum"))
+	tg.must(err)
+	if bytes.Contains(sum, []byte("example.com/zos")) {
+		t.Errorf("go.sum still lists example.com/zos:\n%s", sum)
+	}
+	tg.run("run", "main.go")
+	tg.grepStdout(`^a1.1.0 \| b1.0.0 a1.1.0$`, "wrong output after go mod tidy")
+}
+
+func TestModTidyAllSystems(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	modSetup(tg)
+	tg.tempFile("m/go.mod", "module example.com/m\n")
+	tg.tempFile("m/main.go", "package main\n\nimport _ \"example.com/zos\"\n\nfunc main() {}\n")
+	tg.cd(tg.path("m"))
+
+	// zos_zos.go imports example.com/a. Tidy must add it on every system,
+	// but must not follow the tests or ignored files of dependencies.
+	tg.run("mod", "tidy")
+	data, err := ioutil.ReadFile(tg.path("m/go.mod"))
+	tg.must(err)
+	want := "module example.com/m\n\nrequire (\n\texample.com/a v1.1.0 // indirect\n\texample.com/zos v1.0.0\n)\n"
+	if string(data) != want {
+		t.Errorf("go.mod after go mod tidy:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestModVendor(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	modSetup(tg)
+	tg.tempFile("m/go.mod", "module example.com/m\n\nrequire example.com/b v1.0.0\n")
+	tg.tempFile("m/main.go", modMainSrc)
+	tg.cd(tg.path("m"))
+
+	tg.run("mod", "vendor")
+	data, err := ioutil.ReadFile(tg.path("m/vendor/modules.txt"))
+	tg.must(err)
+	want := "# example.com/a v1.1.0\nexample.com/a\n# example.com/b v1.0.0\nexample.com/b\n"
+	if string(data) != want {
+		t.Errorf("vendor/modules.txt:\n%s\nwant:\n%s", data, want)
+	}
+
+	// Building from the vendor directory needs neither proxy nor cache.
+	tg.setenv("GOPROXY", "off")
+	tg.must(os.RemoveAll(tg.path("gopath/pkg/mod")))
+	tg.run("run", "-mod=vendor", "main.go")
+	tg.grepStdout(`^a1.1.0 \| b1.0.0 a1.1.0$`, "wrong output with -mod=vendor")
+}
+
+func TestModChecksumMismatch(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	modSetup(tg)
+	tg.tempFile("m/go.mod", "module example.com/m\n\nrequire example.com/a v1.0.0\n")
+	tg.tempFile("m/go.sum", "example.com/a v1.0.0 h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n")
+	tg.tempFile("m/main.go", "package main\n\nimport \"example.com/a\"\n\nfunc main() { println(a.V) }\n")
+	tg.cd(tg.path("m"))
+
+	tg.runFail("build", ".")
+	tg.grepStderr("verifying example.com/a@v1.0.0: checksum mismatch", "checksum mismatch not reported")
+
+	tg.must(os.Remove(tg.path("m/go.sum")))
+	tg.runFail("build", "-mod=readonly", ".")
+	tg.grepStderr("missing go.sum entry", "-mod=readonly did not report missing go.sum entry")
+	tg.mustNotExist(tg.path("m/go.sum"))
+}
+
+func TestModInit(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	modSetup(tg)
+	tg.tempFile("gopath/src/example.com/init/main.go", "package main\n\nfunc main() {}\n")
+	tg.cd(tg.path("gopath/src/example.com/init"))
+	tg.run("mod", "init")
+	tg.grepStderr("creating new go.mod: module example.com/init", "go mod init did not infer module path")
+	data, err := ioutil.ReadFile("go.mod")
+	tg.must(err)
+	if string(data) != "module example.com/init\n" {
+		t.Errorf("go.mod = %q", data)
+	}
+	tg.runFail("mod", "init")
+	tg.grepStderr("go.mod already exists", "second go mod init succeeded")
+
+	tg.setenv("GO111MODULE", "off")
+	tg.run("env", "GOMOD")
+	tg.grepStdout(`^$`, "GO111MODULE=off did not disable module mode")
+}
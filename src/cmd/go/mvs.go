@@ -0,0 +1,90 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A modVersion is a module path together with a version of it.
+// The main module has an empty Version.
+type modVersion struct {
+	Path    string
+	Version string
+}
+
+func (m modVersion) String() string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + "@" + m.Version
+}
+
+// mvsBuildList returns the build list for the target module,
+// computed by minimal version selection: the list contains
+// the target and, for every other module path reachable in the
+// requirement graph, the maximum version of that path required
+// by any module version in the graph. The target is first;
+// the other modules follow sorted by path.
+//
+// The reqs function returns the requirements of a module version.
+// It is called at most once for each module version in the graph.
+// If it fails, the error returned by mvsBuildList shows the chain of
+// requirements that led to the failing module version.
+func mvsBuildList(target modVersion, reqs func(modVersion) ([]modVersion, error)) ([]modVersion, error) {
+	selected := map[string]string{target.Path: target.Version}
+	requiredBy := map[modVersion]modVersion{} // first module to require each module version
+	seen := map[modVersion]bool{target: true}
+	queue := []modVersion{target}
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		required, err := reqs(m)
+		if err != nil {
+			trace := m.String()
+			for m1 := m; m1 != target; {
+				m1 = requiredBy[m1]
+				trace = m1.String() + " requires\n\t" + trace
+			}
+			return nil, fmt.Errorf("%s: %v", trace, err)
+		}
+		for _, r := range required {
+			if r.Path == target.Path {
+				// Requirements on the target itself, as from a cycle
+				// through a dependency, are satisfied by the target.
+				continue
+			}
+			if v, ok := selected[r.Path]; !ok || semverCompare(r.Version, v) > 0 {
+				selected[r.Path] = r.Version
+			}
+			if !seen[r] {
+				seen[r] = true
+				requiredBy[r] = m
+				queue = append(queue, r)
+			}
+		}
+	}
+
+	list := []modVersion{target}
+	for path, vers := range selected {
+		if path != target.Path {
+			list = append(list, modVersion{path, vers})
+		}
+	}
+	sort.Sort(byModVersionPath(list[1:]))
+	return list, nil
+}
+
+type byModVersionPath []modVersion
+
+func (x byModVersionPath) Len() int      { return len(x) }
+func (x byModVersionPath) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x byModVersionPath) Less(i, j int) bool {
+	if x[i].Path != x[j].Path {
+		return x[i].Path < x[j].Path
+	}
+	return semverCompare(x[i].Version, x[j].Version) < 0
+}
@@ -77,6 +77,9 @@ type Package struct {
 	XTestGoFiles []string `json:",omitempty"` // _test.go files outside package
 	XTestImports []string `json:",omitempty"` // imports from XTestGoFiles
 
+	// Module information, in module mode
+	Module *ModulePublic `json:",omitempty"` // module providing the package
+
 	// Unexported fields are not part of the public API.
 	build        *build.Package
 	pkgdir       string // overrides build.PkgDir
@@ -98,6 +101,8 @@ type Package struct {
 	coverVars    map[string]*CoverVar // variables created by coverage analysis
 	omitDWARF    bool                 // tell linker not to write DWARF information
 	buildID      string               // expected build ID for generated package
+	buildInfo    string               // build information recorded in the binary by the linker
+	testedPkg    *Package             // package under test, for a generated test main
 	gobinSubdir  bool                 // install target would be subdir of GOBIN
 }
 
@@ -365,7 +370,13 @@ func loadImport(path, srcDir string, parent *Package, stk *importStack, importPo
 		// Not vendoring, or we already found the vendored path.
 		buildMode |= build.IgnoreVendor
 	}
-	bp, err := buildContext.Import(path, srcDir, buildMode)
+	var bp *build.Package
+	var err error
+	if modEnabled() && !isLocal && !modIsStandard(path) {
+		bp, err = modImportPackage(path, buildMode)
+	} else {
+		bp, err = buildContext.Import(path, srcDir, buildMode)
+	}
 	bp.ImportPath = importPath
 	if gobin != "" {
 		bp.BinDir = gobin
@@ -541,6 +552,20 @@ func disallowInternal(srcDir string, p *Package, stk *importStack) *Package {
 	if i > 0 {
 		i-- // rewind over slash in ".../internal"
 	}
+	if p.Module != nil {
+		// In module mode, the tree rooted at the parent of internal
+		// is a tree of import paths, which may span modules.
+		if importer, ok := modDirImportPath(srcDir); ok && hasPathPrefix(importer, p.ImportPath[:i]) {
+			return p
+		}
+		perr := *p
+		perr.Error = &PackageError{
+			ImportStack: stk.copy(),
+			Err:         "use of internal package not allowed",
+		}
+		perr.Incomplete = true
+		return &perr
+	}
 	parent := p.Dir[:i+len(p.Dir)-len(p.ImportPath)]
 	if hasFilePathPrefix(filepath.Clean(srcDir), filepath.Clean(parent)) {
 		return p
@@ -714,6 +739,7 @@ var goTools = map[string]targetDir{
 	"cmd/objdump":                          toTool,
 	"cmd/pack":                             toTool,
 	"cmd/pprof":                            toTool,
+	"cmd/test2json":                        toTool,
 	"cmd/trace":                            toTool,
 	"cmd/vet":                              toTool,
 	"cmd/yacc":                             toTool,
@@ -767,6 +793,9 @@ var cgoSyscallExclude = map[string]bool{
 // be the result of calling build.Context.Import.
 func (p *Package) load(stk *importStack, bp *build.Package, err error) *Package {
 	p.copyBuild(bp)
+	if m, ok := modPkgModule[p.ImportPath]; ok {
+		p.Module = modPublic(m)
+	}
 
 	// The localPrefix is the path we interpret ./ imports relative to.
 	// Synthesized main packages sometimes override this.
@@ -799,6 +828,11 @@ func (p *Package) load(stk *importStack, bp *build.Package, err error) *Package
 			return p
 		}
 		_, elem := filepath.Split(p.Dir)
+		if p.Module != nil {
+			// The directory of a module root is named path@version
+			// in the module cache; name the command after its import path.
+			elem = pathpkg.Base(p.ImportPath)
+		}
 		full := buildContext.GOOS + "_" + buildContext.GOARCH + "/" + elem
 		if buildContext.GOOS != toolGOOS || buildContext.GOARCH != toolGOARCH {
 			// Install cross-compiled binaries to subdirectories of bin.
@@ -1630,9 +1664,16 @@ func loadPackage(arg string, stk *importStack) *Package {
 	// referring to io/ioutil rather than a hypothetical import of
 	// "./ioutil".
 	if build.IsLocalImport(arg) {
-		bp, _ := buildContext.ImportDir(filepath.Join(cwd, arg), build.FindOnly)
-		if bp.ImportPath != "" && bp.ImportPath != "." {
-			arg = bp.ImportPath
+		dir := filepath.Join(cwd, arg)
+		if _, inGoroot := hasSubdir(gorootSrc, dir); modEnabled() && !inGoroot {
+			if path, ok := modDirImportPath(dir); ok {
+				arg = path
+			}
+		} else {
+			bp, _ := buildContext.ImportDir(dir, build.FindOnly)
+			if bp.ImportPath != "" && bp.ImportPath != "." {
+				arg = bp.ImportPath
+			}
 		}
 	}
 
@@ -13,6 +13,7 @@ import (
 	"go/doc"
 	"go/parser"
 	"go/token"
+	"io"
 	"os"
 	"os/exec"
 	"path"
@@ -21,10 +22,13 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 	"unicode"
 	"unicode/utf8"
+
+	"cmd/internal/test2json"
 )
 
 // Break init loop.
@@ -96,6 +100,13 @@ In addition to the build flags, the flags handled by 'go test' itself are:
 	    Install packages that are dependencies of the test.
 	    Do not run the test.
 
+	-json
+	    Convert test output to JSON suitable for automated processing.
+	    The test binary is run with -test.v, and each package's output
+	    is reported as a stream of events, one per line, giving the
+	    package, test name, action, elapsed time and time stamp.
+	    See 'go doc test2json' for the encoding details.
+
 	-o file
 	    Compile the test binary to the named file.
 	    The test still runs (unless -c or -i is specified).
@@ -125,7 +136,10 @@ control the execution of any test:
 
 const testFlag2 = `
 	-bench regexp
-	    Run benchmarks matching the regular expression.
+	    Run (sub)benchmarks matching a regular expression.
+	    The given regular expression is split into smaller ones by
+	    top-level '/', where each must match the corresponding part of a
+	    benchmark's identifier.
 	    By default, no benchmarks run. To run all benchmarks,
 	    use '-bench .' or '-bench=.'.
 
@@ -170,14 +184,19 @@ const testFlag2 = `
 			significantly more expensive.
 	    Sets -cover.
 
-	-coverpkg pkg1,pkg2,pkg3
-	    Apply coverage analysis in each test to the given list of packages.
+	-coverpkg pattern1,pattern2,pattern3
+	    Apply coverage analysis in each test to packages matching the patterns.
 	    The default is for each test to analyze only the package being tested.
-	    Packages are specified as import paths.
+	    See 'go help packages' for a description of package patterns.
+	    A pattern containing '...', or one of 'all', 'std' and 'cmd', selects
+	    only the matching packages that the tests depend on; a single import
+	    path selects that package even if no test depends on it.
 	    Sets -cover.
 
 	-coverprofile cover.out
 	    Write a coverage profile to the file after all tests have passed.
+	    When testing multiple packages, the profiles of all the test binaries
+	    are merged into the one file. See 'go tool cover -help'.
 	    Sets -cover.
 
 	-cpu 1,2,4
@@ -189,6 +208,23 @@ const testFlag2 = `
 	    Write a CPU profile to the specified file before exiting.
 	    Writes test binary as -c would.
 
+	-fuzz regexp
+	    After running the tests, fuzz the fuzz test matching the regular
+	    expression: run its fuzz target with inputs generated by mutating
+	    the seed corpus, guided by coverage instrumentation of the package
+	    being tested. The regular expression must match exactly one fuzz
+	    test, and only one package may be tested.
+	    Inputs that expand coverage are kept in the build cache
+	    (see 'go help cache'), and an input that makes the fuzz target
+	    fail is written to testdata/fuzz/FuzzXXX in the package directory,
+	    where it becomes part of the seed corpus.
+	    See 'go help testfunc'.
+
+	-fuzztime t
+	    Fuzz for the duration t, specified as a time.Duration
+	    (for example, -fuzztime 1h30s). The default is to fuzz
+	    until a failure is found.
+
 	-memprofile mem.out
 	    Write a memory profile to the file after all tests have passed.
 	    Writes test binary as -c would.
@@ -199,6 +235,19 @@ const testFlag2 = `
 	    To profile all memory allocations, use -test.memprofilerate=1
 	    and pass --alloc_space flag to the pprof tool.
 
+	-mutexprofile mutex.out
+	    Write a mutex contention profile to the specified file
+	    when all tests are complete.
+	    Writes test binary as -c would.
+
+	-mutexprofilefraction n
+	    Sample 1 in n stack traces of goroutines holding a
+	    contended mutex.
+	    See 'go doc runtime.SetMutexProfileFraction'.
+	    By default, if -test.mutexprofile is set without this flag,
+	    all contention events are recorded, equivalent to
+	    -test.mutexprofilefraction=1.
+
 	-outputdir directory
 	    Place output files from profiling in the specified directory,
 	    by default the directory in which "go test" is running.
@@ -213,8 +262,11 @@ const testFlag2 = `
 	    (see 'go help build').
 
 	-run regexp
-	    Run only those tests and examples matching the regular
-	    expression.
+	    Run only those tests and examples matching the regular expression.
+	    For tests the regular expression is split into smaller ones by
+	    top-level '/', where each must match the corresponding part of a
+	    test's identifier. For example, '-run TestFoo/bar' runs only the
+	    subtests of TestFoo whose names match bar.
 
 	-short
 	    Tell long-running tests to shorten their run time.
@@ -309,6 +361,28 @@ A benchmark function is one named BenchmarkXXX and should have the signature,
 
 	func BenchmarkXXX(b *testing.B) { ... }
 
+Test and benchmark functions may call the Run method of *testing.T or
+*testing.B to start subtests and sub-benchmarks. Their names are formed by
+joining the name of the enclosing function and the names passed to Run
+with slashes, and their results are reported indented beneath their parent.
+
+A fuzz test is one named FuzzXXX and should have the signature,
+
+	func FuzzXXX(f *testing.F) { ... }
+
+It adds seed inputs with f.Add and passes its fuzz target to f.Fuzz:
+
+	func FuzzParse(f *testing.F) {
+		f.Add([]byte("seed input"))
+		f.Fuzz(func(t *testing.T, data []byte) {
+			Parse(data)
+		})
+	}
+
+Normally the fuzz target runs as a subtest for each seed input and for
+each file in the package's testdata/fuzz/FuzzXXX directory. The -fuzz
+flag runs it with generated inputs instead (see 'go help testflag').
+
 An example function is similar to a test function but, instead of using
 *testing.T to report success or failure, prints output to os.Stdout.
 That output is compared against the function's "Output:" comment, which
@@ -344,10 +418,13 @@ var (
 	testCoverMode    string     // -covermode flag
 	testCoverPaths   []string   // -coverpkg flag
 	testCoverPkgs    []*Package // -coverpkg flag
+	testCoverProfile string     // -coverprofile flag
+	testFuzz         string     // -fuzz flag
 	testO            string     // -o flag
 	testProfile      bool       // some profiling flag
 	testNeedBinary   bool       // profile needs to keep binary around
 	testV            bool       // -v flag
+	testJSON         bool       // -json flag
 	testTimeout      string     // -timeout flag
 	testArgs         []string
 	testBench        bool
@@ -386,6 +463,9 @@ func runTest(cmd *Command, args []string) {
 	if testProfile && len(pkgs) != 1 {
 		fatalf("cannot use test profile flag with multiple packages")
 	}
+	if testFuzz != "" && len(pkgs) != 1 {
+		fatalf("cannot use -fuzz flag with multiple packages")
+	}
 
 	// If a test timeout was given and is parseable, set our kill timeout
 	// to that timeout plus one minute.  This is a backup alarm in case
@@ -394,6 +474,11 @@ func runTest(cmd *Command, args []string) {
 	if dt, err := time.ParseDuration(testTimeout); err == nil && dt > 0 {
 		testKillTimeout = dt + 1*time.Minute
 	}
+	if testFuzz != "" {
+		// Fuzzing runs until a failure is found or -fuzztime elapses;
+		// the test timeout does not apply to it.
+		testKillTimeout = 100 * 365 * 24 * time.Hour
+	}
 
 	// show passing test output (after buffering) with -v flag.
 	// must buffer because tests are running in parallel, and
@@ -407,8 +492,10 @@ func runTest(cmd *Command, args []string) {
 	// single package under test or if parallelism is set to 1.
 	// In these cases, streaming the output produces the same result
 	// as not streaming, just more immediately.
-	testStreamOutput = len(pkgArgs) == 0 || testBench ||
-		(testShowPass && (len(pkgs) == 1 || buildP == 1))
+	// Also stream with -json: the events are attributed to their
+	// package, so output from parallel package tests may interleave.
+	testStreamOutput = len(pkgArgs) == 0 || testBench || testFuzz != "" ||
+		(testShowPass && (len(pkgs) == 1 || buildP == 1)) || testJSON
 
 	var b builder
 	b.init()
@@ -468,20 +555,42 @@ func runTest(cmd *Command, args []string) {
 
 	if testCoverPaths != nil {
 		// Load packages that were asked about for coverage.
-		// packagesForBuild exits if the packages cannot be loaded.
-		testCoverPkgs = packagesForBuild(testCoverPaths)
-
-		// Warn about -coverpkg arguments that are not actually used.
-		used := make(map[string]bool)
-		for _, p := range pkgs {
-			used[p.ImportPath] = true
-			for _, dep := range p.Deps {
-				used[dep] = true
+		// An argument naming a single package selects it even if
+		// no package being tested depends on it, so that its
+		// coverage is reported as 0%. An argument that is a
+		// pattern selects the matching packages that are
+		// dependencies of the tests.
+		used := testDeps(pkgs)
+		seen := make(map[*Package]bool)
+		for _, arg := range testCoverPaths {
+			pattern := strings.Contains(arg, "...") || isMetaPackage(arg)
+			// packagesForBuild exits if the packages cannot be loaded.
+			matched := false
+			for _, p := range packagesForBuild([]string{arg}) {
+				if !used[p.ImportPath] {
+					if pattern {
+						continue
+					}
+				} else {
+					matched = true
+				}
+				if pattern && p.Standard && (p.ImportPath == "runtime" || strings.HasPrefix(p.ImportPath, "runtime/internal/")) {
+					// The runtime cannot be instrumented.
+					continue
+				}
+				if testCoverMode == "atomic" && p.ImportPath == "sync/atomic" {
+					// Atomic coverage counters are updated using sync/atomic.
+					continue
+				}
+				if !seen[p] {
+					seen[p] = true
+					testCoverPkgs = append(testCoverPkgs, p)
+				}
 			}
-		}
-		for _, p := range testCoverPkgs {
-			if !used[p.ImportPath] {
-				fmt.Fprintf(os.Stderr, "warning: no packages being tested depend on %s\n", p.ImportPath)
+
+			// Warn about -coverpkg arguments that are not actually used.
+			if !matched {
+				fmt.Fprintf(os.Stderr, "warning: no packages being tested depend on %s\n", arg)
 			}
 		}
 
@@ -592,7 +701,33 @@ func runTest(cmd *Command, args []string) {
 		fmt.Fprintf(os.Stderr, "installing these packages with 'go test %s-i%s' will speed future tests.\n\n", extraOpts, args)
 	}
 
+	initCoverProfile()
 	b.do(root)
+	closeCoverProfile()
+}
+
+// testDeps returns the set of import paths of the packages that
+// the tests of pkgs are built from: the packages themselves and
+// their dependencies, including those of the test files.
+func testDeps(pkgs []*Package) map[string]bool {
+	deps := make(map[string]bool)
+	var stk importStack
+	add := func(p *Package) {
+		deps[p.ImportPath] = true
+		for _, dep := range p.Deps {
+			deps[dep] = true
+		}
+	}
+	for _, p := range pkgs {
+		add(p)
+		for _, path := range stringList(p.TestImports, p.XTestImports) {
+			p1 := loadImport(path, p.Dir, p, &stk, nil, useVendor)
+			if p1.Error == nil {
+				add(p1)
+			}
+		}
+	}
+	return deps
 }
 
 func contains(x []string, s string) bool {
@@ -711,9 +846,9 @@ func (b *builder) test(p *Package) (buildAction, runAction, printAction *action,
 
 	// Should we apply coverage analysis locally,
 	// only for this package and only for this test?
-	// Yes, if -cover is on but -coverpkg has not specified
+	// Yes, if -cover or -fuzz is on but -coverpkg has not specified
 	// a list of packages for global coverage.
-	localCover := testCover && testCoverPaths == nil
+	localCover := (testCover || testFuzz != "") && testCoverPaths == nil
 
 	// Test package.
 	if len(p.TestGoFiles) > 0 || localCover || p.Name == "main" {
@@ -787,6 +922,7 @@ func (b *builder) test(p *Package) (buildAction, runAction, printAction *action,
 		fake:       true,
 		Stale:      true,
 		omitDWARF:  !testC && !testNeedBinary,
+		testedPkg:  p,
 	}
 
 	// The generated main also imports testing, regexp, and os.
@@ -810,7 +946,7 @@ func (b *builder) test(p *Package) (buildAction, runAction, printAction *action,
 			seen[p1] = true
 		}
 		for _, p1 := range testCoverPkgs {
-			if !seen[p1] {
+			if !seen[p1] && p1.Name != "main" {
 				seen[p1] = true
 				pmain.imports = append(pmain.imports, p1)
 			}
@@ -1055,8 +1191,32 @@ func declareCoverVars(importPath string, files ...string) map[string]*CoverVar {
 // runTest is the action for running a test binary.
 func (b *builder) runTest(a *action) error {
 	args := stringList(findExecCmd(), a.deps[0].target, testArgs)
+	if testCoverProfile != "" {
+		// Write coverage to temporary profile, for merging later.
+		args = append(args, "-test.coverprofile="+coverProfileFile(a))
+	}
+	if testFuzz != "" {
+		if dir := cacheDir(); dir != "off" {
+			args = append(args, "-test.fuzzcachedir="+filepath.Join(dir, "fuzz", a.p.ImportPath))
+		}
+	}
 	a.testOutput = new(bytes.Buffer)
 
+	var stdout io.Writer = os.Stdout
+	var testErr error // test result, for the JSON event stream
+	if testJSON {
+		// Convert the test binary output and the summary line
+		// into a stream of JSON events for this package.
+		json := test2json.NewConverter(lockedStdout{}, a.p.ImportPath, test2json.Timestamp)
+		defer func() {
+			json.Write(a.testOutput.Bytes())
+			a.testOutput.Reset()
+			json.Exited(testErr)
+			json.Close()
+		}()
+		stdout = json
+	}
+
 	if buildN || buildX {
 		b.showcmd("", "%s", strings.Join(args, " "))
 		if buildN {
@@ -1069,20 +1229,49 @@ func (b *builder) runTest(a *action) error {
 		a.failed = false
 		fmt.Fprintf(a.testOutput, "FAIL\t%s [build failed]\n", a.p.ImportPath)
 		setExitStatus(1)
+		testErr = errors.New("build failed")
 		return nil
 	}
 
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Dir = a.p.Dir
 	cmd.Env = envForDir(cmd.Dir, origEnv)
+
+	// Replay the output of an earlier passing run with the same inputs.
+	cacheID, cached := b.testCacheID(a, cmd.Env)
+	if cached {
+		if out, ok := cachedTestOutput(cacheID); ok {
+			if testJSON || testStreamOutput {
+				stdout.Write(out)
+			} else if testShowPass {
+				a.testOutput.Write(out)
+			}
+			fmt.Fprintf(a.testOutput, "ok  \t%s\t(cached)%s\n", a.p.ImportPath, coveragePercentage(out))
+			return nil
+		}
+	}
+
 	var buf bytes.Buffer
-	if testStreamOutput {
+	saved := &buf // output to record in the cache
+	if testJSON {
+		cmd.Stdout = stdout
+		cmd.Stderr = stdout
+	} else if testStreamOutput {
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 	} else {
 		cmd.Stdout = &buf
 		cmd.Stderr = &buf
 	}
+	if cached && cmd.Stdout != io.Writer(&buf) {
+		// Keep a copy of the streamed output for the cache.
+		// Standard error is merged into standard output,
+		// as it is when the output is replayed.
+		saved = new(bytes.Buffer)
+		w := io.MultiWriter(cmd.Stdout, saved)
+		cmd.Stdout = w
+		cmd.Stderr = w
+	}
 
 	// If there are any local SWIG dependencies, we want to load
 	// the shared library from the build directory.
@@ -1139,9 +1328,16 @@ func (b *builder) runTest(a *action) error {
 		}
 		tick.Stop()
 	}
+	testErr = err
+	if testCoverProfile != "" {
+		mergeCoverProfile(a.testOutput, coverProfileFile(a))
+	}
 	out := buf.Bytes()
 	t := fmt.Sprintf("%.3fs", time.Since(t0).Seconds())
 	if err == nil {
+		if cached {
+			putTestOutput(cacheID, saved.Bytes())
+		}
 		if testShowPass {
 			a.testOutput.Write(out)
 		}
@@ -1202,10 +1398,30 @@ func (b *builder) printTest(a *action) error {
 
 // notest is the action for testing a package with no test files.
 func (b *builder) notest(a *action) error {
+	if testJSON {
+		json := test2json.NewConverter(lockedStdout{}, a.p.ImportPath, test2json.Timestamp)
+		fmt.Fprintf(json, "?   \t%s\t[no test files]\n", a.p.ImportPath)
+		json.Close()
+		return nil
+	}
 	fmt.Printf("?   \t%s\t[no test files]\n", a.p.ImportPath)
 	return nil
 }
 
+// stdoutMu serializes writes to standard output by the
+// JSON converters of concurrently running package tests.
+var stdoutMu sync.Mutex
+
+// lockedStdout is an io.Writer that writes to os.Stdout
+// while holding stdoutMu, so that each write stays intact.
+type lockedStdout struct{}
+
+func (lockedStdout) Write(b []byte) (int, error) {
+	stdoutMu.Lock()
+	defer stdoutMu.Unlock()
+	return os.Stdout.Write(b)
+}
+
 // isTestMain tells whether fn is a TestMain(m *testing.M) function.
 func isTestMain(fn *ast.FuncDecl) bool {
 	if fn.Name.String() != "TestMain" ||
@@ -1286,6 +1502,7 @@ func writeTestmain(out string, t *testFuncs) error {
 type testFuncs struct {
 	Tests       []testFunc
 	Benchmarks  []testFunc
+	FuzzTargets []testFunc
 	Examples    []testFunc
 	TestMain    *testFunc
 	Package     *Package
@@ -1305,6 +1522,12 @@ func (t *testFuncs) CoverEnabled() bool {
 	return testCover
 }
 
+// CoverCounters reports whether the test binary registers coverage
+// counters, which fuzzing uses even when coverage is not reported.
+func (t *testFuncs) CoverCounters() bool {
+	return testCover || testFuzz != ""
+}
+
 // Covered returns a string describing which packages are being tested for coverage.
 // If the covered package is the same as the tested package, it returns the empty string.
 // Otherwise it is a comma-separated human-readable list of packages beginning with
@@ -1356,6 +1579,9 @@ func (t *testFuncs) load(filename, pkg string, doImport, seen *bool) error {
 		case isTest(name, "Benchmark"):
 			t.Benchmarks = append(t.Benchmarks, testFunc{pkg, name, ""})
 			*doImport, *seen = true, true
+		case isTest(name, "Fuzz"):
+			t.FuzzTargets = append(t.FuzzTargets, testFunc{pkg, name, ""})
+			*doImport, *seen = true, true
 		}
 	}
 	ex := doc.Examples(f)
@@ -1415,6 +1641,12 @@ var benchmarks = []testing.InternalBenchmark{
 {{end}}
 }
 
+var fuzzTargets = []testing.InternalFuzzTarget{
+{{range .FuzzTargets}}
+	{"{{.Name}}", {{.Package}}.{{.Name}}},
+{{end}}
+}
+
 var examples = []testing.InternalExample{
 {{range .Examples}}
 	{"{{.Name}}", {{.Package}}.{{.Name}}, {{.Output | printf "%q"}}},
@@ -1435,7 +1667,7 @@ func matchString(pat, str string) (result bool, err error) {
 	return matchRe.MatchString(str), nil
 }
 
-{{if .CoverEnabled}}
+{{if .CoverCounters}}
 
 // Only updated by init functions, so no need for atomicity.
 var (
@@ -1483,6 +1715,7 @@ func main() {
 		CoveredPackages: {{printf "%q" .Covered}},
 	})
 {{end}}
+	testing.RegisterFuzz(fuzzTargets, {{if .CoverCounters}}coverCounters{{else}}nil{{end}})
 	m := testing.MainStart(matchString, tests, benchmarks, examples)
 {{with .TestMain}}
 	{{.Package}}.{{.Name}}(m)
@@ -0,0 +1,125 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// coverMerge manages the state for merging the coverage profiles
+// written by the individual test binaries into the -coverprofile file.
+var coverMerge struct {
+	f      *os.File
+	counts map[string]int // count of each block, keyed by its position and number of statements
+	blocks []string       // keys of counts, in the order first seen
+	sync.Mutex
+}
+
+// initCoverProfile initializes the test coverage profile.
+// It must be run before any calls to mergeCoverProfile or closeCoverProfile.
+// Using this function clears the profile in case it existed from a previous run,
+// or in case it doesn't exist and the test is going to fail to create it (or not run).
+func initCoverProfile() {
+	if testCoverProfile == "" || testC {
+		return
+	}
+	f, err := os.Create(testCoverProfile)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if _, err := fmt.Fprintf(f, "mode: %s\n", testCoverMode); err != nil {
+		fatalf("%v", err)
+	}
+	coverMerge.f = f
+	coverMerge.counts = make(map[string]int)
+}
+
+// coverProfileFile returns the name of the file to which the test
+// binary for the run action a writes its coverage profile.
+func coverProfileFile(a *action) string {
+	return filepath.Join(a.deps[0].objdir, "_cover_.out")
+}
+
+// mergeCoverProfile merges the coverage profile written by a test binary
+// in file into the -coverprofile file. Errors are reported to ew.
+// A block covered by several test binaries, as happens with -coverpkg,
+// appears once in the result: in set mode it is covered if any binary
+// covered it, and in count and atomic mode its counts are added.
+func mergeCoverProfile(ew io.Writer, file string) {
+	if coverMerge.f == nil {
+		return
+	}
+	coverMerge.Lock()
+	defer coverMerge.Unlock()
+
+	expect := fmt.Sprintf("mode: %s\n", testCoverMode)
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		// Test did not create profile, which is OK.
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+	if !bytes.HasPrefix(data, []byte(expect)) {
+		fmt.Fprintf(ew, "error: test wrote malformed coverage profile.\n")
+		return
+	}
+
+	// Each line is "file:start,end numStmt count".
+	lines := strings.Split(strings.TrimSuffix(string(data[len(expect):]), "\n"), "\n")
+	counts := make([]int, len(lines))
+	for i, line := range lines {
+		j := strings.LastIndex(line, " ")
+		n, err := strconv.Atoi(line[j+1:])
+		if j < 0 || err != nil {
+			fmt.Fprintf(ew, "error: test wrote malformed coverage profile.\n")
+			return
+		}
+		lines[i], counts[i] = line[:j], n
+	}
+	for i, block := range lines {
+		old, ok := coverMerge.counts[block]
+		if !ok {
+			coverMerge.blocks = append(coverMerge.blocks, block)
+		}
+		if testCoverMode == "set" {
+			if old != 0 || counts[i] != 0 {
+				coverMerge.counts[block] = 1
+			} else {
+				coverMerge.counts[block] = 0
+			}
+		} else {
+			coverMerge.counts[block] = old + counts[i]
+		}
+	}
+}
+
+// closeCoverProfile writes the merged blocks to the -coverprofile file
+// and closes it.
+func closeCoverProfile() {
+	if coverMerge.f == nil {
+		return
+	}
+	w := bufio.NewWriter(coverMerge.f)
+	for _, block := range coverMerge.blocks {
+		fmt.Fprintf(w, "%s %d\n", block, coverMerge.counts[block])
+	}
+	if err := w.Flush(); err != nil {
+		errorf("saving coverage profile: %v", err)
+	}
+	if err := coverMerge.f.Close(); err != nil {
+		errorf("closing coverage profile: %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package subtest_test
+
+import "testing"
+
+func TestOuter(t *testing.T) {
+	t.Run("inner A", func(t *testing.T) {
+		t.Run("leaf", func(t *testing.T) {})
+	})
+	t.Run("inner B", func(t *testing.T) {
+		t.Parallel()
+	})
+}
+
+func TestOther(t *testing.T) {
+	t.Run("x", func(t *testing.T) {})
+}
+
+func BenchmarkOuter(b *testing.B) {
+	b.Run("inner", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+		}
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -36,18 +37,23 @@ var testFlagDefn = []*testFlagSpec{
 	{name: "cover", boolVar: &testCover},
 	{name: "covermode"},
 	{name: "coverpkg"},
+	{name: "coverprofile"},
 	{name: "exec"},
+	{name: "json", boolVar: &testJSON},
 
 	// passed to 6.out, adding a "test." prefix to the name if necessary: -v becomes -test.v.
 	{name: "bench", passToTest: true},
 	{name: "benchmem", boolVar: new(bool), passToTest: true},
 	{name: "benchtime", passToTest: true},
 	{name: "count", passToTest: true},
-	{name: "coverprofile", passToTest: true},
 	{name: "cpu", passToTest: true},
 	{name: "cpuprofile", passToTest: true},
+	{name: "fuzz", passToTest: true},
+	{name: "fuzztime", passToTest: true},
 	{name: "memprofile", passToTest: true},
 	{name: "memprofilerate", passToTest: true},
+	{name: "mutexprofile", passToTest: true},
+	{name: "mutexprofilefraction", passToTest: true},
 	{name: "blockprofile", passToTest: true},
 	{name: "blockprofilerate", passToTest: true},
 	{name: "outputdir", passToTest: true},
@@ -134,7 +140,7 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 			var err error
 			switch f.name {
 			// bool flags.
-			case "c", "i", "v", "cover":
+			case "c", "i", "v", "cover", "json":
 				setBoolFlag(f.boolVar, value)
 			case "o":
 				testO = value
@@ -149,7 +155,9 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 				testBench = true
 			case "timeout":
 				testTimeout = value
-			case "blockprofile", "cpuprofile", "memprofile", "trace":
+			case "fuzz":
+				testFuzz = value
+			case "blockprofile", "cpuprofile", "memprofile", "mutexprofile", "trace":
 				testProfile = true
 				testNeedBinary = true
 			case "coverpkg":
@@ -161,7 +169,7 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 				}
 			case "coverprofile":
 				testCover = true
-				testProfile = true
+				testCoverProfile = value
 			case "covermode":
 				switch value {
 				case "set", "count", "atomic":
@@ -182,14 +190,29 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 		}
 	}
 
+	// -json needs the verbose test output to find the test events.
+	if testJSON && !testV {
+		passToTest = append(passToTest, "-test.v=true")
+	}
+
 	if testCoverMode == "" {
 		testCoverMode = "set"
+		if testFuzz != "" {
+			// Fuzzing is guided by how often code runs, not just whether it does.
+			testCoverMode = "count"
+		}
 		if buildRace {
 			// Default coverage mode is atomic when -race is set.
 			testCoverMode = "atomic"
 		}
 	}
 
+	// The go command writes the coverage profile itself,
+	// merging the profiles written by the individual test binaries.
+	if testCoverProfile != "" && !filepath.IsAbs(testCoverProfile) && outputDir != "" {
+		testCoverProfile = filepath.Join(outputDir, testCoverProfile)
+	}
+
 	// Tell the test what directory we're running in, so it can write the profiles there.
 	if testProfile && outputDir == "" {
 		dir, err := os.Getwd()
@@ -16,8 +16,10 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // A vcsCmd describes how to use a version control system
@@ -39,6 +41,15 @@ type vcsCmd struct {
 
 	remoteRepo  func(v *vcsCmd, rootDir string) (remoteRepo string, err error)
 	resolveRepo func(v *vcsCmd, rootDir, remoteRepo string) (realRepo string, err error)
+	status      func(v *vcsCmd, rootDir string) (vcsStatus, error)
+}
+
+// A vcsStatus describes the current commit or checkout of a
+// repository, as recorded in the build information of binaries.
+type vcsStatus struct {
+	revision    string    // revision identifier of the current commit or checkout
+	commitTime  time.Time // time of the current commit or checkout
+	uncommitted bool      // whether the working tree has uncommitted changes
 }
 
 var isSecureScheme = map[string]bool{
@@ -107,6 +118,7 @@ var vcsHg = &vcsCmd{
 	scheme:     []string{"https", "http", "ssh"},
 	pingCmd:    "identify {scheme}://{repo}",
 	remoteRepo: hgRemoteRepo,
+	status:     hgStatus,
 }
 
 func hgRemoteRepo(vcsHg *vcsCmd, rootDir string) (remoteRepo string, err error) {
@@ -117,6 +129,37 @@ func hgRemoteRepo(vcsHg *vcsCmd, rootDir string) (remoteRepo string, err error)
 	return strings.TrimSpace(string(out)), nil
 }
 
+func hgStatus(vcsHg *vcsCmd, rootDir string) (vcsStatus, error) {
+	out, err := vcsHg.run1(rootDir, "log -l1 -T {node}:{date|hgdate}", nil, false)
+	if err != nil {
+		return vcsStatus{}, err
+	}
+	// The output is like "node:seconds offset".
+	i := bytes.IndexByte(out, ':')
+	if i < 0 {
+		return vcsStatus{}, fmt.Errorf("unrecognized output of hg log: %q", out)
+	}
+	f := strings.Fields(string(out[i+1:]))
+	if len(f) != 2 {
+		return vcsStatus{}, fmt.Errorf("unrecognized output of hg log: %q", out)
+	}
+	sec, err := strconv.ParseInt(f[0], 10, 64)
+	if err != nil {
+		return vcsStatus{}, fmt.Errorf("unrecognized output of hg log: %q", out)
+	}
+	st := vcsStatus{
+		revision:   string(out[:i]),
+		commitTime: time.Unix(sec, 0).UTC(),
+	}
+
+	out, err = vcsHg.run1(rootDir, "status", nil, false)
+	if err != nil {
+		return vcsStatus{}, err
+	}
+	st.uncommitted = len(out) > 0
+	return st, nil
+}
+
 // vcsGit describes how to use Git.
 var vcsGit = &vcsCmd{
 	name: "Git",
@@ -144,6 +187,7 @@ var vcsGit = &vcsCmd{
 	scheme:     []string{"git", "https", "http", "git+ssh", "ssh"},
 	pingCmd:    "ls-remote {scheme}://{repo}",
 	remoteRepo: gitRemoteRepo,
+	status:     gitStatus,
 }
 
 // scpSyntaxRe matches the SCP-like addresses used by Git to access
@@ -194,6 +238,33 @@ func gitRemoteRepo(vcsGit *vcsCmd, rootDir string) (remoteRepo string, err error
 	return "", errParse
 }
 
+func gitStatus(vcsGit *vcsCmd, rootDir string) (vcsStatus, error) {
+	out, err := vcsGit.run1(rootDir, "-c log.showsignature=false log -1 --format=%H:%ct", nil, false)
+	if err != nil {
+		return vcsStatus{}, err
+	}
+	// The output is like "hash:seconds".
+	f := strings.Split(strings.TrimSpace(string(out)), ":")
+	if len(f) != 2 {
+		return vcsStatus{}, fmt.Errorf("unrecognized output of git log: %q", out)
+	}
+	sec, err := strconv.ParseInt(f[1], 10, 64)
+	if err != nil {
+		return vcsStatus{}, fmt.Errorf("unrecognized output of git log: %q", out)
+	}
+	st := vcsStatus{
+		revision:   f[0],
+		commitTime: time.Unix(sec, 0).UTC(),
+	}
+
+	out, err = vcsGit.run1(rootDir, "status --porcelain", nil, false)
+	if err != nil {
+		return vcsStatus{}, err
+	}
+	st.uncommitted = len(out) > 0
+	return st, nil
+}
+
 // vcsBzr describes how to use Bazaar.
 var vcsBzr = &vcsCmd{
 	name: "Bazaar",
@@ -5,21 +5,317 @@
 package main
 
 import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 var cmdVersion = &Command{
-	Run:       runVersion,
-	UsageLine: "version",
+	UsageLine: "version [-m] [-v] [file ...]",
 	Short:     "print Go version",
-	Long:      `Version prints the Go version, as reported by runtime.Version.`,
+	Long: `
+Version prints the build information for Go executables.
+
+Go version reports the Go version used to build each of the named
+executable files.
+
+If no files are named on the command line, go version prints its own
+version information, as reported by runtime.Version.
+
+If a directory is named, go version walks that directory, recursively,
+looking for recognized Go binaries and reporting their versions.
+By default, go version does not report unrecognized files found
+during a directory scan. The -v flag causes it to report unrecognized files.
+
+The -m flag causes go version to print each executable's embedded
+build information: the main package, the repositories or modules
+providing the packages linked into it, and the build settings, such
+as GOOS and the version control revision of the main package.
+In the output, the build information is shown as multiple lines
+following the version line, each indented by a leading tab character.
+See 'go doc runtime/debug.BuildInfo' for the format.
+
+Go version reads ELF, Mach-O and PE executables, as well as the GOFF
+object files written by the linker for z/OS. It does not run them.
+`,
+}
+
+var (
+	versionM = cmdVersion.Flag.Bool("m", false, "")
+	versionV = cmdVersion.Flag.Bool("v", false, "")
+)
+
+func init() {
+	cmdVersion.Run = runVersion // break init cycle
 }
 
 func runVersion(cmd *Command, args []string) {
-	if len(args) != 0 {
-		cmd.Usage()
+	if len(args) == 0 {
+		if *versionM || *versionV {
+			fatalf("go version: flags can only be used with arguments")
+		}
+		fmt.Printf("go version %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+		return
+	}
+
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			errorf("%v", err)
+			continue
+		}
+		if info.IsDir() {
+			scanDir(arg)
+		} else {
+			scanFile(arg, info, true)
+		}
+	}
+}
+
+// scanDir scans a directory for executables to run scanFile on.
+func scanDir(dir string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if info != nil && info.Mode().IsRegular() {
+			scanFile(path, info, false)
+		}
+		return nil
+	})
+}
+
+// isExe reports whether the file should be considered executable.
+func isExe(file string, info os.FileInfo) bool {
+	if runtime.GOOS == "windows" {
+		return strings.HasSuffix(strings.ToLower(file), ".exe")
+	}
+	return info.Mode()&0111 != 0
+}
+
+// scanFile scans file to try to report the Go version and build
+// information. If file was named on the command line, scanFile reports
+// any error reading it. Otherwise, as when it is called by scanDir,
+// scanFile reports files that are not Go executables only with -v.
+func scanFile(file string, info os.FileInfo, named bool) {
+	if !named && !isExe(file, info) {
+		if *versionV {
+			fmt.Fprintf(os.Stderr, "%s: not executable file\n", file)
+		}
+		return
+	}
+
+	vers, mod, err := readBuildInfo(file)
+	if err != nil {
+		if named {
+			errorf("%s: %v", file, err)
+		} else if *versionV {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+		}
+		return
+	}
+
+	fmt.Printf("%s: %s\n", file, vers)
+	if *versionM && mod != "" {
+		fmt.Printf("\t%s\n", strings.Replace(strings.TrimSuffix(mod, "\n"), "\n", "\n\t", -1))
+	}
+}
+
+var errNotGoExe = errors.New("not a Go executable")
+
+// The magic at the start of the go.buildinfo data written by the linker.
+var buildInfoMagic = []byte("\xff Go buildinf:")
+
+// readBuildInfo returns the Go version and the build information
+// recorded by the linker in the executable file.
+func readBuildInfo(file string) (vers, mod string, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	data, err := readExeData(f)
+	if err != nil {
+		return "", "", err
+	}
+	for _, d := range data {
+		for i := 0; ; {
+			j := bytes.Index(d[i:], buildInfoMagic)
+			if j < 0 {
+				break
+			}
+			i += j
+			if vers, mod, ok := decodeBuildInfo(d[i:]); ok {
+				return vers, mod, nil
+			}
+			i += len(buildInfoMagic)
+		}
+	}
+	return "", "", errNotGoExe
+}
+
+// decodeBuildInfo decodes the go.buildinfo data at the start of data,
+// reporting whether it is well formed.
+func decodeBuildInfo(data []byte) (vers, mod string, ok bool) {
+	data = data[len(buildInfoMagic):]
+	if len(data) < 2 {
+		return "", "", false
+	}
+	ptrSize, flags := data[0], data[1]
+	if ptrSize != 4 && ptrSize != 8 || flags&^1 != 2 {
+		// Only inline strings are written by the linker.
+		return "", "", false
+	}
+	data = data[2:]
+	var strs [2]string
+	for i := range strs {
+		n, w := binary.Uvarint(data)
+		if w <= 0 || n > uint64(len(data)-w) {
+			return "", "", false
+		}
+		strs[i] = string(data[w : w+int(n)])
+		data = data[w+int(n):]
+	}
+	if strs[0] == "" {
+		return "", "", false
+	}
+	return strs[0], strs[1], true
+}
+
+// readExeData returns the contents of the writable data sections of
+// the executable f, one of which holds the go.buildinfo data.
+func readExeData(f *os.File) ([][]byte, error) {
+	ident := make([]byte, 16)
+	if n, err := io.ReadFull(f, ident); n < len(ident) {
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			err = errNotGoExe
+		}
+		return nil, err
 	}
 
-	fmt.Printf("go version %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	var data [][]byte
+	switch {
+	case bytes.HasPrefix(ident, []byte("\x7FELF")):
+		ef, err := elf.NewFile(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range ef.Sections {
+			if s.Type == elf.SHT_PROGBITS && s.Flags&(elf.SHF_ALLOC|elf.SHF_WRITE) == elf.SHF_ALLOC|elf.SHF_WRITE {
+				d, err := s.Data()
+				if err != nil {
+					return nil, err
+				}
+				data = append(data, d)
+			}
+		}
+
+	case bytes.HasPrefix(ident, []byte("\xFE\xED\xFA")) || bytes.HasPrefix(ident[1:], []byte("\xFA\xED\xFE")):
+		mf, err := macho.NewFile(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range mf.Sections {
+			if s.Seg == "__DATA" {
+				d, err := s.Data()
+				if err != nil {
+					return nil, err
+				}
+				data = append(data, d)
+			}
+		}
+
+	case bytes.HasPrefix(ident, []byte("MZ")):
+		pf, err := pe.NewFile(f)
+		if err != nil {
+			return nil, err
+		}
+		const imageScnMemWrite = 0x80000000
+		for _, s := range pf.Sections {
+			if s.Characteristics&imageScnMemWrite != 0 {
+				d, err := s.Data()
+				if err != nil {
+					return nil, err
+				}
+				data = append(data, d)
+			}
+		}
+
+	case ident[0] == goffPrefix && ident[1]>>4 == goffHDR:
+		d, err := readGOFFText(f)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, d)
+
+	default:
+		return nil, errNotGoExe
+	}
+	return data, nil
+}
+
+// GOFF object files, as written by the linker for z/OS, are sequences
+// of 80-byte records, each starting with a 3-byte prefix that gives the
+// record type. The TXT records hold the contents of the sections.
+const (
+	goffRecordLen = 80
+	goffPrefix    = 0x03
+	goffTXT       = 1
+	goffHDR       = 15
+
+	goffTXTHeaderLen  = 24 // length of the fixed part of a TXT record
+	goffContHeaderLen = 3  // length of the fixed part of a continuation record
+)
+
+// readGOFFText returns the concatenated data of the TXT records of the
+// GOFF object file f, in the order of the records.
+func readGOFFText(f *os.File) ([]byte, error) {
+	if _, err := f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	var text []byte
+	rec := make([]byte, goffRecordLen)
+	left := 0 // bytes of the current TXT record left to read from continuation records
+	for {
+		if _, err := io.ReadFull(f, rec); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if rec[0] != goffPrefix {
+			return nil, fmt.Errorf("malformed GOFF record")
+		}
+		const continuation = 1 << 1
+		if rec[1]&continuation != 0 {
+			if rec[1]>>4 != goffTXT {
+				continue
+			}
+			n := left
+			if n > goffRecordLen-goffContHeaderLen {
+				n = goffRecordLen - goffContHeaderLen
+			}
+			text = append(text, rec[goffContHeaderLen:goffContHeaderLen+n]...)
+			left -= n
+			continue
+		}
+		if rec[1]>>4 != goffTXT {
+			continue
+		}
+		n := int(binary.BigEndian.Uint16(rec[22:]))
+		left = n
+		if n > goffRecordLen-goffTXTHeaderLen {
+			n = goffRecordLen - goffTXTHeaderLen
+		}
+		text = append(text, rec[goffTXTHeaderLen:goffTXTHeaderLen+n]...)
+		left -= n
+	}
+	return text, nil
 }
@@ -8,11 +8,14 @@ import "path/filepath"
 
 func init() {
 	addBuildFlags(cmdVet)
+	cmdVet.Flag.StringVar(&vetTool, "vettool", "", "")
 }
 
+var vetTool string // -vettool flag
+
 var cmdVet = &Command{
 	Run:       runVet,
-	UsageLine: "vet [-n] [-x] [build flags] [packages]",
+	UsageLine: "vet [-n] [-x] [-vettool prog] [build flags] [packages]",
 	Short:     "run go tool vet on packages",
 	Long: `
 Vet runs the Go vet command on the packages named by the import paths.
@@ -25,6 +28,11 @@ To run the vet tool with specific options, run 'go tool vet'.
 The -n flag prints commands that would be executed.
 The -x flag prints commands as they are executed.
 
+The -vettool=prog flag selects a different analysis tool with
+alternative or additional checks, such as a program built with the
+go/analysis/multichecker package. The tool is given the files of
+each package in the same way as 'go tool vet'.
+
 For more about build flags, see 'go help build'.
 
 See also: go fmt, go fix.
@@ -48,5 +56,9 @@ func runVetFiles(p *Package, files []string) {
 	for i := range files {
 		files[i] = filepath.Join(p.Dir, files[i])
 	}
-	run(buildToolExec, tool("vet"), relPaths(files))
+	vet := tool("vet")
+	if vetTool != "" {
+		vet = vetTool
+	}
+	run(buildToolExec, vet, relPaths(files))
 }
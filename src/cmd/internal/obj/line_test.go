@@ -49,3 +49,26 @@ func TestLineHist(t *testing.T) {
 		}
 	}
 }
+
+func TestApplyRewrites(t *testing.T) {
+	tests := []struct {
+		abs, rewrites string
+		want          string
+		ok            bool
+	}{
+		{"/work/b001/x.go", "", "/work/b001/x.go", false},
+		{"/work/b001/x.go", "/work", "b001/x.go", true},
+		{"/work/b001/x.go", "/wo", "/work/b001/x.go", false},
+		{"/work", "/work", "", true},
+		{"/gopath/src/example.com/p/x.go", "/work;/gopath/src/example.com/p=>example.com/p", "example.com/p/x.go", true},
+		{"/gopath/src/example.com/p/x.go", "/gopath/src=>;/gopath/src/example.com/p=>q", "example.com/p/x.go", true},
+		{"/goroot/src/runtime", "/goroot/src/runtime=>runtime", "runtime", true},
+		{"/other/x.go", "/work;/gopath/src/p=>p", "/other/x.go", false},
+	}
+	for _, tt := range tests {
+		got, ok := ApplyRewrites(tt.abs, tt.rewrites)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ApplyRewrites(%q, %q) = %q, %v, want %q, %v", tt.abs, tt.rewrites, got, ok, tt.want, tt.ok)
+		}
+	}
+}
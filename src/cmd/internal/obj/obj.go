@@ -28,7 +28,7 @@ type LineHist struct {
 	Top            *LineStack  // current top of stack
 	Ranges         []LineRange // ranges for lookup
 	Dir            string      // directory to qualify relative paths
-	TrimPathPrefix string      // remove leading TrimPath from recorded file names
+	TrimPathPrefix string      // rewrites to apply to recorded file names; see ApplyRewrites
 	GOROOT         string      // current GOROOT
 	GOROOT_FINAL   string      // target GOROOT
 }
@@ -74,13 +74,9 @@ func (h *LineHist) setFile(stk *LineStack, file string) {
 		abs = filepath.Join(h.Dir, file)
 	}
 
-	// Remove leading TrimPathPrefix, or else rewrite $GOROOT to literal $GOROOT.
-	if h.TrimPathPrefix != "" && hasPathPrefix(abs, h.TrimPathPrefix) {
-		if abs == h.TrimPathPrefix {
-			abs = ""
-		} else {
-			abs = abs[len(h.TrimPathPrefix)+1:]
-		}
+	// Apply the TrimPathPrefix rewrites, or else rewrite $GOROOT to literal $GOROOT.
+	if rewritten, ok := ApplyRewrites(abs, h.TrimPathPrefix); ok {
+		abs = rewritten
 	} else if hasPathPrefix(abs, h.GOROOT) {
 		abs = "$GOROOT" + abs[len(h.GOROOT):]
 	}
@@ -96,6 +92,32 @@ func (h *LineHist) setFile(stk *LineStack, file string) {
 	stk.File = file
 }
 
+// ApplyRewrites returns the file name abs as rewritten by rewrites,
+// and reports whether any rewrite applied. The rewrites argument is
+// a semicolon-separated list of rewrites, each of the form "prefix",
+// which removes a leading prefix from the file name, or
+// "prefix=>replacement", which replaces it. The prefix must match
+// whole path elements. The first rewrite that matches is applied.
+func ApplyRewrites(abs, rewrites string) (string, bool) {
+	for _, r := range strings.Split(rewrites, ";") {
+		prefix, replace := r, ""
+		if i := strings.Index(r, "=>"); i >= 0 {
+			prefix, replace = r[:i], r[i+len("=>"):]
+		}
+		if prefix == "" || !hasPathPrefix(abs, prefix) {
+			continue
+		}
+		if abs == prefix || len(abs) == len(prefix)+1 {
+			return replace, true
+		}
+		if replace == "" {
+			return abs[len(prefix)+1:], true
+		}
+		return replace + abs[len(prefix):], true
+	}
+	return abs, false
+}
+
 // Does s have t as a path prefix?
 // That is, does s == t or does s begin with t followed by a slash?
 // For portability, we allow ASCII case folding, so that hasPathPrefix("a/b/c", "A/B") is true.
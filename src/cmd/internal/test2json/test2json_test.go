@@ -0,0 +1,251 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test2json
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.json files")
+
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no testdata/*.test files")
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".test")
+		t.Run(name, func(t *testing.T) {
+			orig, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Test one line written to c at a time.
+			// Assume that's the most likely to be handled correctly.
+			var buf bytes.Buffer
+			c := NewConverter(&buf, "", 0)
+			in := append([]byte{}, orig...)
+			for _, line := range bytes.SplitAfter(in, []byte("\n")) {
+				writeAndKill(c, line)
+			}
+			c.Close()
+
+			if *update {
+				js := strings.TrimSuffix(file, ".test") + ".json"
+				t.Logf("rewriting %s", js)
+				if err := ioutil.WriteFile(js, buf.Bytes(), 0666); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := ioutil.ReadFile(strings.TrimSuffix(file, ".test") + ".json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			diffJSON(t, buf.Bytes(), want)
+			if t.Failed() {
+				// If the line-at-a-time conversion fails, no point testing boundary conditions.
+				return
+			}
+
+			// Write entire input in bulk.
+			t.Run("bulk", func(t *testing.T) {
+				buf.Reset()
+				c = NewConverter(&buf, "", 0)
+				in = append([]byte{}, orig...)
+				writeAndKill(c, in)
+				c.Close()
+				diffJSON(t, buf.Bytes(), want)
+			})
+
+			// Write 2 bytes at a time on even boundaries.
+			t.Run("even2", func(t *testing.T) {
+				buf.Reset()
+				c = NewConverter(&buf, "", 0)
+				in = append([]byte{}, orig...)
+				for i := 0; i < len(in); i += 2 {
+					if i+2 <= len(in) {
+						writeAndKill(c, in[i:i+2])
+					} else {
+						writeAndKill(c, in[i:])
+					}
+				}
+				c.Close()
+				diffJSON(t, buf.Bytes(), want)
+			})
+
+			// Write 2 bytes at a time on odd boundaries.
+			t.Run("odd2", func(t *testing.T) {
+				buf.Reset()
+				c = NewConverter(&buf, "", 0)
+				in = append([]byte{}, orig...)
+				if len(in) > 0 {
+					writeAndKill(c, in[:1])
+				}
+				for i := 1; i < len(in); i += 2 {
+					if i+2 <= len(in) {
+						writeAndKill(c, in[i:i+2])
+					} else {
+						writeAndKill(c, in[i:])
+					}
+				}
+				c.Close()
+				diffJSON(t, buf.Bytes(), want)
+			})
+
+			// Test with very small output buffers, to check that
+			// UTF8 sequences are not broken up.
+			for b := 5; b <= 8; b++ {
+				t.Run(fmt.Sprintf("tiny%d", b), func(t *testing.T) {
+					oldIn := inBuffer
+					oldOut := outBuffer
+					defer func() {
+						inBuffer = oldIn
+						outBuffer = oldOut
+					}()
+					inBuffer = 64
+					outBuffer = b
+					buf.Reset()
+					c = NewConverter(&buf, "", 0)
+					in = append([]byte{}, orig...)
+					writeAndKill(c, in)
+					c.Close()
+					diffJSON(t, buf.Bytes(), want)
+				})
+			}
+		})
+	}
+}
+
+// writeAndKill writes b to w and then fills b with Zs.
+// The filling makes sure that if w is holding onto b for
+// future use, that future use will have obviously wrong data.
+func writeAndKill(w io.Writer, b []byte) {
+	w.Write(b)
+	for i := range b {
+		b[i] = 'Z'
+	}
+}
+
+// diffJSON diffs the stream we have against the stream we want
+// and fails the test with a useful message if they don't match.
+// Consecutive output events for the same test are merged before
+// comparing, since the split of output into events depends on
+// how the input was written.
+func diffJSON(t *testing.T, have, want []byte) {
+	h, w := mergedEvents(t, have), mergedEvents(t, want)
+	if !reflect.DeepEqual(h, w) {
+		t.Errorf("JSON mismatch:\nhave:\n%s\nwant:\n%s", formatEvents(h), formatEvents(w))
+	}
+}
+
+// testEvent is the decoded form of event.
+type testEvent struct {
+	Time    *time.Time `json:",omitempty"`
+	Action  string
+	Package string   `json:",omitempty"`
+	Test    string   `json:",omitempty"`
+	Elapsed *float64 `json:",omitempty"`
+	Output  string   `json:",omitempty"`
+}
+
+func mergedEvents(t *testing.T, js []byte) []testEvent {
+	var evs []testEvent
+	dec := json.NewDecoder(bytes.NewReader(js))
+	for {
+		var e testEvent
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, js)
+		}
+		if n := len(evs); n > 0 && e.Action == "output" && evs[n-1].Action == "output" && evs[n-1].Test == e.Test {
+			evs[n-1].Output += e.Output
+			continue
+		}
+		evs = append(evs, e)
+	}
+	return evs
+}
+
+func formatEvents(evs []testEvent) string {
+	var buf bytes.Buffer
+	for _, e := range evs {
+		js, _ := json.Marshal(e)
+		buf.Write(js)
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+func TestTimestamp(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewConverter(&buf, "example.com/p", Timestamp)
+	c.Write([]byte("=== RUN   TestX\n--- PASS: TestX (1.25s)\nPASS\n"))
+	c.Close()
+
+	evs := mergedEvents(t, buf.Bytes())
+	if len(evs) != 5 {
+		t.Fatalf("got %d events, want 5:\n%s", len(evs), buf.Bytes())
+	}
+	for _, e := range evs {
+		if e.Time == nil {
+			t.Errorf("event %+v has no Time", e)
+		}
+		if e.Package != "example.com/p" {
+			t.Errorf("event %+v has Package %q, want %q", e, e.Package, "example.com/p")
+		}
+	}
+	if e := evs[2]; e.Action != "pass" || e.Test != "TestX" || e.Elapsed == nil || *e.Elapsed != 1.25 {
+		t.Errorf("test result event = %+v, want pass of TestX with Elapsed 1.25", e)
+	}
+	if e := evs[4]; e.Action != "pass" || e.Test != "" || e.Elapsed == nil {
+		t.Errorf("package result event = %+v, want pass with Elapsed", e)
+	}
+}
+
+func TestExited(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewConverter(&buf, "", 0)
+	c.Write([]byte("=== RUN   TestCrash\npanic: boom\n"))
+	c.Exited(fmt.Errorf("exit status 2"))
+	c.Close()
+
+	evs := mergedEvents(t, buf.Bytes())
+	if e := evs[len(evs)-1]; e.Action != "fail" || e.Test != "" {
+		t.Errorf("final event = %+v, want package fail", e)
+	}
+}
+
+func TestTrimUTF8(t *testing.T) {
+	s := "hello α ☺ 😂 world" // α is 2-byte, ☺ is 3-byte, 😂 is 4-byte
+	b := []byte(s)
+	for i := 0; i < len(s); i++ {
+		j := trimUTF8(b[:i])
+		u := string([]rune(s[:j])) + string([]rune(s[j:]))
+		if u != s {
+			t.Errorf("trimUTF8(%q) = %d (-%d), not at boundary (split: %q %q)", s[:i], j, i-j, s[:j], s[j:])
+		}
+		if utf8.FullRune(b[j:i]) {
+			t.Errorf("trimUTF8(%q) = %d (-%d), too early (missed: %q)", s[:j], j, i-j, s[j:i])
+		}
+	}
+}
@@ -41,6 +41,10 @@ Flags:
 		Set the value of the string variable in importpath named name to value.
 		Note that before Go 1.5 this option took two separate arguments.
 		Now it takes one argument split on the first = sign.
+	-buildinfo file
+		Record the build information in file, as written by the go command,
+		in the binary, where runtime/debug.ReadBuildInfo and 'go version -m'
+		can find it.
 	-buildmode mode
 		Set build mode (default exe).
 	-cpuprofile file
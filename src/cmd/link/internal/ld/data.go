@@ -34,7 +34,9 @@ package ld
 import (
 	"cmd/internal/gcprog"
 	"cmd/internal/obj"
+	"encoding/binary"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"strconv"
@@ -1686,6 +1688,60 @@ func textbuildid() {
 	Ctxt.Textp = sym
 }
 
+// setbuildinfo reads the build information recorded by cmd/go from
+// file and sets runtime.modinfo to it, as with -X.
+func setbuildinfo(file string) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		Exitf("-buildinfo: %v", err)
+	}
+	gobuildinfo = string(data)
+	addstrdata("runtime.modinfo", gobuildinfo)
+}
+
+// The magic at the start of the go.buildinfo symbol.
+// The \xff is invalid UTF-8, meant to make it less likely
+// to find one of these accidentally.
+const buildinfoMagic = "\xff Go buildinf:"
+
+// dobuildinfo adds the go.buildinfo data symbol, which records the
+// Go version and the build information so that cmd/go can read them
+// from the binary without running it. The symbol holds
+//
+//	the 14-byte buildinfoMagic
+//	the pointer size (1 byte)
+//	flags (1 byte): 1 if big-endian, 2 as the strings are inline
+//	the Go version, as a uvarint length followed by the bytes
+//	the build information, as a uvarint length followed by the bytes
+//
+// The strings are stored inline, rather than as pointers to the Go
+// strings, so that no relocation is needed to read them in any of
+// the object formats, including GOFF.
+func dobuildinfo() {
+	if Buildmode == BuildmodeShared {
+		return
+	}
+	sym := Linklookup(Ctxt, "go.buildinfo", 0)
+	sym.Reachable = true
+	sym.Local = true
+	sym.Type = obj.SNOPTRDATA
+
+	data := []byte(buildinfoMagic)
+	flags := byte(2)
+	if Ctxt.Arch.ByteOrder == binary.BigEndian {
+		flags |= 1
+	}
+	data = append(data, byte(Thearch.Ptrsize), flags)
+	var buf [binary.MaxVarintLen64]byte
+	for _, str := range []string{obj.Getgoversion(), gobuildinfo} {
+		n := binary.PutUvarint(buf[:], uint64(len(str)))
+		data = append(data, buf[:n]...)
+		data = append(data, str...)
+	}
+	sym.P = data
+	sym.Size = int64(len(data))
+}
+
 // assign addresses to text
 func textaddress() {
 	var sub *LSym
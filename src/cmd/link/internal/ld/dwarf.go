@@ -18,7 +18,6 @@ import (
 	"cmd/internal/obj"
 	"encoding/binary"
 	"fmt"
-	"os"
 	"strings"
 )
 
@@ -1554,11 +1553,14 @@ func flushunit(dwinfo *DWDie, pc int64, pcsym *LSym, unitstart int64, header_len
 	}
 }
 
+// getCompilationDir returns the DW_AT_comp_dir of the compilation unit.
+// The linker runs in a temporary or otherwise arbitrary directory,
+// so recording it would only make the binary depend on where it was
+// linked. Recorded file names are usually absolute, in which case
+// debuggers ignore this value; when they have been made relative by
+// the -trimpath rewrites, "." resolves them against the current directory.
 func getCompilationDir() string {
-	if dir, err := os.Getwd(); err == nil {
-		return dir
-	}
-	return "/"
+	return "."
 }
 
 func writelines() {
@@ -43,6 +43,7 @@ import (
 var (
 	pkglistfornote []byte
 	buildid        string
+	gobuildinfo    string
 )
 
 func Ldmain() {
@@ -88,6 +89,7 @@ func Ldmain() {
 	obj.Flagfn1("X", "add string value `definition` of the form importpath.name=value", addstrdata1)
 	obj.Flagcount("a", "disassemble output", &Debug['a'])
 	obj.Flagstr("buildid", "record `id` as Go toolchain build id", &buildid)
+	obj.Flagfn1("buildinfo", "record the build information in `file` in the binary", setbuildinfo)
 	flag.Var(&Buildmode, "buildmode", "set build `mode`")
 	obj.Flagcount("c", "dump call graph", &Debug['c'])
 	obj.Flagcount("d", "disable dynamic executable", &Debug['d'])
@@ -249,6 +251,7 @@ func Ldmain() {
 	addexport()
 	Thearch.Gentext() // trampolines, call stubs, etc.
 	textbuildid()
+	dobuildinfo()
 	textaddress()
 	pclntab()
 	findfunctab()
@@ -688,8 +688,8 @@ func scaleHeapSample(count, size, rate int64) (int64, int64) {
 	return int64(float64(count) * scale), int64(float64(size) * scale)
 }
 
-// parseContention parses a contentionz profile and returns a newly
-// populated Profile.
+// parseContention parses a contentionz profile, or a Go mutex profile
+// in the same format, and returns a newly populated Profile.
 func parseContention(b []byte) (p *Profile, err error) {
 	r := bytes.NewBuffer(b)
 	l, err := r.ReadString('\n')
@@ -697,7 +697,7 @@ func parseContention(b []byte) (p *Profile, err error) {
 		return nil, errUnrecognized
 	}
 
-	if !strings.HasPrefix(l, "--- contention") {
+	if !strings.HasPrefix(l, "--- contention") && !strings.HasPrefix(l, "--- mutex") {
 		return nil, errUnrecognized
 	}
 
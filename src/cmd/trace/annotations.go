@@ -0,0 +1,559 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Views of the user annotations made with runtime/trace:
+// tasks, regions and log messages.
+
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"internal/trace"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+func init() {
+	http.HandleFunc("/usertasks", httpUserTasks)
+	http.HandleFunc("/usertask", httpUserTask)
+	http.HandleFunc("/userregions", httpUserRegions)
+	http.HandleFunc("/userregion", httpUserRegion)
+}
+
+var (
+	annotationsInit sync.Once
+	tasks           map[uint64]*trace.TaskDesc
+	regions         []*trace.RegionDesc
+	lastTs          int64 // time of the last event of the trace
+)
+
+// analyzeAnnotations collects the tasks and regions of the trace and
+// stores them in tasks and regions.
+func analyzeAnnotations(events []*trace.Event) {
+	annotationsInit.Do(func() {
+		tasks, regions = trace.UserAnnotations(events)
+		if len(events) > 0 {
+			lastTs = events[len(events)-1].Ts
+		}
+	})
+}
+
+// durationBounds are the upper bounds of the buckets of the duration
+// histograms; the last bucket is unbounded.
+var durationBounds = []time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// A bucket is a bucket of a duration histogram. It counts the
+// durations d with Min <= d < Max; Max is 0 for the last bucket.
+type bucket struct {
+	Min, Max time.Duration
+	Count    int
+}
+
+// Label returns the description of the range of durations of b.
+func (b bucket) Label() string {
+	if b.Max == 0 {
+		return fmt.Sprintf(">= %v", b.Min)
+	}
+	return fmt.Sprintf("< %v", b.Max)
+}
+
+// A histogram counts durations in the buckets given by durationBounds.
+type histogram []bucket
+
+func newHistogram() histogram {
+	h := make(histogram, len(durationBounds)+1)
+	for i, max := range durationBounds {
+		h[i].Max = max
+		if i+1 < len(h) {
+			h[i+1].Min = max
+		}
+	}
+	return h
+}
+
+func (h histogram) add(d time.Duration) {
+	for i := range h {
+		if h[i].Max == 0 || d < h[i].Max {
+			h[i].Count++
+			return
+		}
+	}
+}
+
+// durationFilter selects the durations in the range given by the
+// latmin and latmax parameters of a request, as in the links of the
+// histogram buckets.
+type durationFilter struct {
+	min, max time.Duration // max is 0 if unbounded
+	set      bool          // whether either parameter was given
+}
+
+func parseDurationFilter(r *http.Request) (durationFilter, error) {
+	var f durationFilter
+	for _, p := range []struct {
+		name string
+		d    *time.Duration
+	}{{"latmin", &f.min}, {"latmax", &f.max}} {
+		s := r.FormValue(p.name)
+		if s == "" {
+			continue
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return f, fmt.Errorf("failed to parse %s parameter '%v': %v", p.name, s, err)
+		}
+		*p.d = d
+		f.set = true
+	}
+	return f, nil
+}
+
+// match reports whether the duration d, which is known only if
+// complete is set, is selected by f.
+func (f durationFilter) match(d time.Duration, complete bool) bool {
+	if !f.set {
+		return true
+	}
+	return complete && d >= f.min && (f.max == 0 || d < f.max)
+}
+
+// parseTaskID returns the task given by the taskid parameter of the
+// request, or nil if the request has no such parameter.
+func parseTaskID(r *http.Request, events []*trace.Event) (*trace.TaskDesc, error) {
+	s := r.FormValue("taskid")
+	if s == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse taskid parameter '%v': %v", s, err)
+	}
+	analyzeAnnotations(events)
+	task := tasks[id]
+	if task == nil {
+		return nil, fmt.Errorf("task %v not found", id)
+	}
+	return task, nil
+}
+
+// taskGoroutines returns the goroutines that worked on task or on one
+// of its subtasks.
+func taskGoroutines(task *trace.TaskDesc) map[uint64]bool {
+	gs := make(map[uint64]bool)
+	for _, t := range task.Descendants() {
+		for g := range t.Gs {
+			gs[g] = true
+		}
+	}
+	return gs
+}
+
+// taskType summarizes the tasks of one type.
+type taskType struct {
+	Type       string
+	Count      int       // number of tasks
+	Incomplete int       // number of tasks whose creation or end is not in the trace
+	Histogram  histogram // durations of the complete tasks
+}
+
+// httpUserTasks serves the summary of the tasks of the trace by type.
+func httpUserTasks(w http.ResponseWriter, r *http.Request) {
+	events, err := parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	analyzeAnnotations(events)
+	types := make(map[string]*taskType)
+	for _, task := range tasks {
+		tt := types[task.Name]
+		if tt == nil {
+			tt = &taskType{Type: task.Name, Histogram: newHistogram()}
+			types[task.Name] = tt
+		}
+		tt.Count++
+		if task.Complete() {
+			tt.Histogram.add(time.Duration(task.Duration(0, lastTs)))
+		} else {
+			tt.Incomplete++
+		}
+	}
+	var list []*taskType
+	for _, tt := range types {
+		list = append(list, tt)
+	}
+	sort.Sort(taskTypeList(list))
+	err = templUserTasks.Execute(w, list)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+type taskTypeList []*taskType
+
+func (l taskTypeList) Len() int {
+	return len(l)
+}
+
+func (l taskTypeList) Less(i, j int) bool {
+	return l[i].Type < l[j].Type
+}
+
+func (l taskTypeList) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+var templUserTasks = template.Must(template.New("").Parse(`
+<html>
+<body>
+<h2>User-defined tasks</h2>
+<table border="1">
+<tr>
+<th> Task type </th>
+<th> Count </th>
+<th> Duration distribution (complete tasks) </th>
+</tr>
+{{range $}}
+  <tr>
+    <td> {{if .Type}}{{.Type}}{{else}}(unknown){{end}} </td>
+    <td> <a href="/usertask?type={{.Type}}">{{.Count}}</a>{{if .Incomplete}} ({{.Incomplete}} incomplete){{end}} </td>
+    <td>
+    {{$type := .Type}}
+    {{range .Histogram}}
+      {{if .Count}}<a href="/usertask?type={{$type}}&latmin={{.Min}}&latmax={{.Max}}">{{.Label}}</a>: {{.Count}}<br>{{end}}
+    {{end}}
+    </td>
+  </tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+// A taskEvent is an event of a task, as shown by /usertask.
+type taskEvent struct {
+	Offset time.Duration // since the start of the task
+	G      uint64
+	What   string
+}
+
+// taskEntry describes a task, as shown by /usertask.
+type taskEntry struct {
+	*trace.TaskDesc
+	Start    time.Duration // since the start of the trace
+	Duration time.Duration // 0 if the task is incomplete
+	Events   []taskEvent
+}
+
+// httpUserTask serves the list of the tasks selected by the type,
+// taskid, latmin and latmax parameters, with their events.
+func httpUserTask(w http.ResponseWriter, r *http.Request) {
+	events, err := parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	filter, err := parseDurationFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	only, err := parseTaskID(r, events)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	analyzeAnnotations(events)
+	_, anyType := r.Form["type"]
+	typ := r.FormValue("type")
+
+	var list []*taskEntry
+	for _, task := range tasks {
+		if only != nil && task != only || anyType && task.Name != typ {
+			continue
+		}
+		d := time.Duration(task.Duration(0, lastTs))
+		if !filter.match(d, task.Complete()) {
+			continue
+		}
+		e := &taskEntry{TaskDesc: task, Start: time.Duration(task.FirstTimestamp(0))}
+		if task.Complete() {
+			e.Duration = d
+		}
+		e.Events = taskEvents(task)
+		list = append(list, e)
+	}
+	sort.Sort(taskEntryList(list))
+	err = templUserTask.Execute(w, list)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// taskEvents returns the events of the task, in time order.
+func taskEvents(task *trace.TaskDesc) []taskEvent {
+	start := task.FirstTimestamp(0)
+	var evs []taskEvent
+	add := func(ev *trace.Event, what string) {
+		evs = append(evs, taskEvent{Offset: time.Duration(ev.Ts - start), G: ev.G, What: what})
+	}
+	if task.Create != nil {
+		add(task.Create, "task created")
+	}
+	for _, c := range task.Children {
+		if c.Create != nil {
+			add(c.Create, fmt.Sprintf("subtask %v (%s) created", c.ID, c.Name))
+		}
+	}
+	for _, rd := range task.Regions {
+		if rd.Start != nil {
+			add(rd.Start, fmt.Sprintf("region %s started", rd.Name))
+		}
+		if rd.End != nil {
+			add(rd.End, fmt.Sprintf("region %s ended", rd.Name))
+		}
+	}
+	for _, ev := range task.Logs {
+		if ev.SArgs[0] != "" {
+			add(ev, fmt.Sprintf("log %s: %s", ev.SArgs[0], ev.SArgs[1]))
+		} else {
+			add(ev, fmt.Sprintf("log: %s", ev.SArgs[1]))
+		}
+	}
+	if task.End != nil {
+		add(task.End, "task ended")
+	}
+	sort.Stable(taskEventList(evs))
+	return evs
+}
+
+type taskEventList []taskEvent
+
+func (l taskEventList) Len() int {
+	return len(l)
+}
+
+func (l taskEventList) Less(i, j int) bool {
+	return l[i].Offset < l[j].Offset
+}
+
+func (l taskEventList) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+type taskEntryList []*taskEntry
+
+func (l taskEntryList) Len() int {
+	return len(l)
+}
+
+func (l taskEntryList) Less(i, j int) bool {
+	if l[i].Start != l[j].Start {
+		return l[i].Start < l[j].Start
+	}
+	return l[i].ID < l[j].ID
+}
+
+func (l taskEntryList) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+var templUserTask = template.Must(template.New("").Parse(`
+<html>
+<body>
+<h2>User-defined tasks</h2>
+<table border="1">
+<tr>
+<th> Task </th>
+<th> Start </th>
+<th> Duration </th>
+<th> Events </th>
+</tr>
+{{range $}}
+  <tr>
+    <td>
+      {{.ID}} {{.Name}}<br>
+      {{with .Parent}}parent <a href="/usertask?taskid={{.ID}}">{{.ID}}</a><br>{{end}}
+      <a href="/trace?taskid={{.ID}}">trace</a>
+      <a href="/goroutines?taskid={{.ID}}">goroutines</a>
+    </td>
+    <td> {{.Start}} </td>
+    <td> {{if .Duration}}{{.Duration}}{{else}}incomplete{{end}} </td>
+    <td>
+    {{range .Events}}
+      +{{.Offset}} G{{.G}} {{.What}}<br>
+    {{end}}
+    </td>
+  </tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+// regionType summarizes the regions of one type.
+type regionType struct {
+	Type       string
+	Count      int       // number of regions
+	Incomplete int       // number of regions whose start or end is not in the trace
+	Histogram  histogram // durations of the complete regions
+}
+
+// httpUserRegions serves the summary of the regions of the trace by type.
+func httpUserRegions(w http.ResponseWriter, r *http.Request) {
+	events, err := parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	analyzeAnnotations(events)
+	types := make(map[string]*regionType)
+	var list []*regionType
+	for _, rd := range regions {
+		rt := types[rd.Name]
+		if rt == nil {
+			rt = &regionType{Type: rd.Name, Histogram: newHistogram()}
+			types[rd.Name] = rt
+			list = append(list, rt)
+		}
+		rt.Count++
+		if rd.Start != nil && rd.End != nil {
+			rt.Histogram.add(time.Duration(rd.Duration(0, lastTs)))
+		} else {
+			rt.Incomplete++
+		}
+	}
+	sort.Sort(regionTypeList(list))
+	err = templUserRegions.Execute(w, list)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+type regionTypeList []*regionType
+
+func (l regionTypeList) Len() int {
+	return len(l)
+}
+
+func (l regionTypeList) Less(i, j int) bool {
+	return l[i].Type < l[j].Type
+}
+
+func (l regionTypeList) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+var templUserRegions = template.Must(template.New("").Parse(`
+<html>
+<body>
+<h2>User-defined regions</h2>
+<table border="1">
+<tr>
+<th> Region type </th>
+<th> Count </th>
+<th> Duration distribution (complete regions) </th>
+</tr>
+{{range $}}
+  <tr>
+    <td> {{.Type}} </td>
+    <td> <a href="/userregion?type={{.Type}}">{{.Count}}</a>{{if .Incomplete}} ({{.Incomplete}} incomplete){{end}} </td>
+    <td>
+    {{$type := .Type}}
+    {{range .Histogram}}
+      {{if .Count}}<a href="/userregion?type={{$type}}&latmin={{.Min}}&latmax={{.Max}}">{{.Label}}</a>: {{.Count}}<br>{{end}}
+    {{end}}
+    </td>
+  </tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+// regionEntry describes a region, as shown by /userregion.
+type regionEntry struct {
+	*trace.RegionDesc
+	Start    time.Duration // since the start of the trace
+	Duration time.Duration // 0 if the region is incomplete
+}
+
+// httpUserRegion serves the list of the regions selected by the type,
+// latmin and latmax parameters.
+func httpUserRegion(w http.ResponseWriter, r *http.Request) {
+	events, err := parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	filter, err := parseDurationFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	analyzeAnnotations(events)
+	_, anyType := r.Form["type"]
+	typ := r.FormValue("type")
+
+	var list []*regionEntry
+	for _, rd := range regions {
+		if anyType && rd.Name != typ {
+			continue
+		}
+		complete := rd.Start != nil && rd.End != nil
+		d := time.Duration(rd.Duration(0, lastTs))
+		if !filter.match(d, complete) {
+			continue
+		}
+		e := &regionEntry{RegionDesc: rd, Start: time.Duration(rd.FirstTimestamp(0))}
+		if complete {
+			e.Duration = d
+		}
+		list = append(list, e)
+	}
+	err = templUserRegion.Execute(w, list)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+var templUserRegion = template.Must(template.New("").Parse(`
+<html>
+<body>
+<h2>User-defined regions</h2>
+<table border="1">
+<tr>
+<th> Goroutine </th>
+<th> Task </th>
+<th> Start </th>
+<th> Duration </th>
+</tr>
+{{range $}}
+  <tr>
+    <td> <a href="/trace?goid={{.G}}">{{.G}}</a> </td>
+    <td> {{if .TaskID}}<a href="/usertask?taskid={{.TaskID}}">{{.TaskID}}</a>{{else}}background{{end}} </td>
+    <td> {{.Start}} </td>
+    <td> {{if .Duration}}{{.Duration}}{{else}}incomplete{{end}} </td>
+  </tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
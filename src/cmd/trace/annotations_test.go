@@ -0,0 +1,259 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"internal/trace"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	rtrace "runtime/trace"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var annotated struct {
+	once   sync.Once
+	events []*trace.Event
+	err    error
+}
+
+// annotatedTrace records a trace with nested tasks and regions and
+// makes it the trace analyzed by the handlers. The trace is recorded
+// once, since the handlers load and analyze it only once.
+func annotatedTrace(t *testing.T) []*trace.Event {
+	annotated.once.Do(func() {
+		buf := new(bytes.Buffer)
+		if annotated.err = rtrace.Start(buf); annotated.err != nil {
+			return
+		}
+		task := rtrace.NewTask(nil, "task0")
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sub := rtrace.NewTask(task, "task1")
+			defer sub.End()
+			rtrace.WithRegion(sub, "region0", func() {
+				defer rtrace.StartRegion(sub, "region1").End()
+				rtrace.Log(sub, "key0", "value0")
+			})
+		}()
+		wg.Wait()
+		rtrace.WithRegion(task, "region0", func() {})
+		task.End()
+		rtrace.NewTask(nil, "task2") // not ended
+		rtrace.Stop()
+
+		annotated.events, annotated.err = trace.Parse(buf)
+		loader.once.Do(func() {
+			loader.events, loader.err = annotated.events, annotated.err
+		})
+	})
+	if annotated.err == trace.ErrTimeOrder {
+		t.Skipf("skipping trace: %v", annotated.err)
+	}
+	if annotated.err != nil {
+		t.Fatalf("failed to trace: %v", annotated.err)
+	}
+	analyzeAnnotations(annotated.events)
+	return annotated.events
+}
+
+// taskNamed returns the task named name.
+func taskNamed(t *testing.T, name string) *trace.TaskDesc {
+	for _, task := range tasks {
+		if task.Name == name {
+			return task
+		}
+	}
+	t.Fatalf("no task %s in %v", name, tasks)
+	return nil
+}
+
+func TestAnalyzeAnnotations(t *testing.T) {
+	if runtime.GOOS == "nacl" {
+		t.Skip("skipping: tracing is not supported on nacl")
+	}
+	annotatedTrace(t)
+	if len(tasks) != 3 {
+		t.Errorf("found %d tasks, want 3", len(tasks))
+	}
+	task0, task1, task2 := taskNamed(t, "task0"), taskNamed(t, "task1"), taskNamed(t, "task2")
+	if task1.Parent != task0 || len(task0.Children) != 1 || task0.Children[0] != task1 {
+		t.Errorf("task1 is not the subtask of task0")
+	}
+	if !task0.Complete() || !task1.Complete() || task2.Complete() {
+		t.Errorf("complete = %v %v %v, want true true false", task0.Complete(), task1.Complete(), task2.Complete())
+	}
+	if len(task1.Logs) != 1 || len(task1.Regions) != 2 || len(task0.Regions) != 1 {
+		t.Errorf("task1 has %d logs and %d regions, task0 %d regions; want 1, 2 and 1", len(task1.Logs), len(task1.Regions), len(task0.Regions))
+	}
+	if len(regions) != 3 {
+		t.Errorf("found %d regions, want 3", len(regions))
+	}
+
+	// task1 ran on a goroutine of its own; the goroutines of task0
+	// include it.
+	if len(task1.Gs) != 1 {
+		t.Fatalf("task1 ran on goroutines %v, want one", task1.Gs)
+	}
+	gs := taskGoroutines(task0)
+	if len(gs) != 2 {
+		t.Errorf("taskGoroutines(task0) = %v, want 2 goroutines", gs)
+	}
+	for g := range task1.Gs {
+		if !gs[g] {
+			t.Errorf("taskGoroutines(task0) = %v, does not contain goroutine %d of task1", gs, g)
+		}
+	}
+	for g := range task0.Gs {
+		if !gs[g] {
+			t.Errorf("taskGoroutines(task0) = %v, does not contain goroutine %d of task0", gs, g)
+		}
+	}
+	if gs := taskGoroutines(task1); len(gs) != 1 {
+		t.Errorf("taskGoroutines(task1) = %v, want 1 goroutine", gs)
+	}
+}
+
+func TestParseTaskID(t *testing.T) {
+	if runtime.GOOS == "nacl" {
+		t.Skip("skipping: tracing is not supported on nacl")
+	}
+	events := annotatedTrace(t)
+	task1 := taskNamed(t, "task1")
+	for _, tt := range []struct {
+		query string
+		task  *trace.TaskDesc
+		err   string
+	}{
+		{"", nil, ""},
+		{"taskid=" + strconv.FormatUint(task1.ID, 10), task1, ""},
+		{"taskid=x", nil, "failed to parse taskid parameter 'x'"},
+		{"taskid=1000000", nil, "task 1000000 not found"},
+	} {
+		r, err := http.NewRequest("GET", "/goroutines?"+tt.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		task, err := parseTaskID(r, events)
+		if task != tt.task || (err == nil) != (tt.err == "") || err != nil && !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("parseTaskID(%q) = %v, %v, want %v, %q", tt.query, task, err, tt.task, tt.err)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram()
+	if len(h) != len(durationBounds)+1 {
+		t.Fatalf("histogram has %d buckets, want %d", len(h), len(durationBounds)+1)
+	}
+	for _, d := range []time.Duration{0, 9 * time.Microsecond, 10 * time.Microsecond, 5 * time.Millisecond, 10 * time.Second, time.Hour} {
+		h.add(d)
+	}
+	want := []int{2, 1, 0, 1, 0, 0, 0, 2}
+	for i, b := range h {
+		if b.Count != want[i] {
+			t.Errorf("bucket %d [%v, %v) has count %d, want %d", i, b.Min, b.Max, b.Count, want[i])
+		}
+	}
+	if l := h[0].Label(); l != "< 10µs" {
+		t.Errorf("first bucket label = %q, want %q", l, "< 10µs")
+	}
+	if l := h[len(h)-1].Label(); l != ">= 10s" {
+		t.Errorf("last bucket label = %q, want %q", l, ">= 10s")
+	}
+}
+
+func TestDurationFilter(t *testing.T) {
+	for _, tt := range []struct {
+		query    string
+		d        time.Duration
+		complete bool
+		match    bool
+	}{
+		{"", time.Second, false, true},
+		{"", time.Second, true, true},
+		{"latmin=1ms&latmax=10ms", time.Millisecond, true, true},
+		{"latmin=1ms&latmax=10ms", 10 * time.Millisecond, true, false},
+		{"latmin=1ms&latmax=10ms", 999 * time.Microsecond, true, false},
+		{"latmin=1ms&latmax=10ms", 5 * time.Millisecond, false, false},
+		{"latmin=10s", time.Hour, true, true},
+		{"latmin=10s", time.Second, true, false},
+		{"latmax=10µs", 0, true, true},
+	} {
+		r, err := http.NewRequest("GET", "/usertask?"+tt.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := parseDurationFilter(r)
+		if err != nil {
+			t.Errorf("parseDurationFilter(%q): %v", tt.query, err)
+			continue
+		}
+		if m := f.match(tt.d, tt.complete); m != tt.match {
+			t.Errorf("filter %q: match(%v, %v) = %v, want %v", tt.query, tt.d, tt.complete, m, tt.match)
+		}
+	}
+
+	r, err := http.NewRequest("GET", "/usertask?latmin=x", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseDurationFilter(r); err == nil {
+		t.Errorf("parseDurationFilter accepted latmin=x")
+	}
+}
+
+func TestHTTPUserTasks(t *testing.T) {
+	if runtime.GOOS == "nacl" {
+		t.Skip("skipping: tracing is not supported on nacl")
+	}
+	annotatedTrace(t)
+	w := httptest.NewRecorder()
+	httpUserTasks(w, &http.Request{})
+	if w.Code != http.StatusOK {
+		t.Fatalf("/usertasks: status %d: %s", w.Code, w.Body)
+	}
+	body := w.Body.String()
+	for _, s := range []string{
+		`<a href="/usertask?type=task0">1</a>`,
+		`<a href="/usertask?type=task1">1</a>`,
+		`<a href="/usertask?type=task2">1</a> (1 incomplete)`,
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("/usertasks does not contain %s:\n%s", s, body)
+		}
+	}
+}
+
+func TestHTTPUserRegions(t *testing.T) {
+	if runtime.GOOS == "nacl" {
+		t.Skip("skipping: tracing is not supported on nacl")
+	}
+	annotatedTrace(t)
+	w := httptest.NewRecorder()
+	httpUserRegions(w, &http.Request{})
+	if w.Code != http.StatusOK {
+		t.Fatalf("/userregions: status %d: %s", w.Code, w.Body)
+	}
+	body := w.Body.String()
+	for _, s := range []string{
+		`<a href="/userregion?type=region0">2</a>`,
+		`<a href="/userregion?type=region1">1</a>`,
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("/userregions does not contain %s:\n%s", s, body)
+		}
+	}
+	if strings.Contains(body, "incomplete") {
+		t.Errorf("/userregions reports incomplete regions:\n%s", body)
+	}
+}
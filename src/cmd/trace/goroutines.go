@@ -69,6 +69,25 @@ func analyzeGoroutines(events []*trace.Event) {
 	})
 }
 
+// goroutineFilter holds the optional task, given by the taskid
+// parameter, to whose goroutines the goroutine analysis is restricted.
+type goroutineFilter struct {
+	Task *trace.TaskDesc
+	gs   map[uint64]bool // goroutines of Task and its subtasks
+}
+
+func parseGoroutineFilter(r *http.Request, events []*trace.Event) (goroutineFilter, error) {
+	task, err := parseTaskID(r, events)
+	if err != nil || task == nil {
+		return goroutineFilter{}, err
+	}
+	return goroutineFilter{Task: task, gs: taskGoroutines(task)}, nil
+}
+
+func (f goroutineFilter) match(g *trace.GDesc) bool {
+	return f.Task == nil || f.gs[g.ID]
+}
+
 // httpGoroutines serves list of goroutine groups.
 func httpGoroutines(w http.ResponseWriter, r *http.Request) {
 	events, err := parseEvents()
@@ -76,9 +95,17 @@ func httpGoroutines(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	filter, err := parseGoroutineFilter(r, events)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	analyzeGoroutines(events)
 	gss := make(map[uint64]gtype)
 	for _, g := range gs {
+		if !filter.match(g) {
+			continue
+		}
 		gs1 := gss[g.PC]
 		gs1.ID = g.PC
 		gs1.Name = g.Name
@@ -92,15 +119,18 @@ func httpGoroutines(w http.ResponseWriter, r *http.Request) {
 		glist = append(glist, v)
 	}
 	sort.Sort(glist)
-	templGoroutines.Execute(w, glist)
+	templGoroutines.Execute(w, struct {
+		goroutineFilter
+		Groups gtypeList
+	}{filter, glist})
 }
 
 var templGoroutines = template.Must(template.New("").Parse(`
 <html>
 <body>
-Goroutines: <br>
-{{range $}}
-  <a href="/goroutine?id={{.ID}}">{{.Name}}</a> N={{.N}} <br>
+{{with .Task}}Goroutines of task {{.ID}} {{.Name}} and its subtasks:{{else}}Goroutines:{{end}} <br>
+{{range .Groups}}
+  <a href="/goroutine?id={{.ID}}{{with $.Task}}&taskid={{.ID}}{{end}}">{{.Name}}</a> N={{.N}} <br>
 {{end}}
 </body>
 </html>
@@ -118,10 +148,15 @@ func httpGoroutine(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("failed to parse id parameter '%v': %v", r.FormValue("id"), err), http.StatusInternalServerError)
 		return
 	}
+	filter, err := parseGoroutineFilter(r, events)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	analyzeGoroutines(events)
 	var glist gdescList
 	for _, g := range gs {
-		if g.PC != pc || g.ExecTime == 0 {
+		if g.PC != pc || g.ExecTime == 0 || !filter.match(g) {
 			continue
 		}
 		glist = append(glist, g)
@@ -15,6 +15,12 @@ Generate a trace file with 'go test':
 	go test -trace trace.out pkg
 View the trace in a web browser:
 	go tool trace pkg.test trace.out
+
+The tasks, regions and log messages with which a program annotates
+its trace using runtime/trace are shown in the trace view, and the
+tool summarizes the latencies of the tasks and regions by type. The
+goroutine analysis and the trace view can be restricted to the
+goroutines that worked on a task.
 */
 package main
 
@@ -128,6 +134,8 @@ var templMain = []byte(`
 <a href="/block">Synchronization blocking profile</a><br>
 <a href="/syscall">Syscall blocking profile</a><br>
 <a href="/sched">Scheduler latency profile</a><br>
+<a href="/usertasks">User-defined tasks</a><br>
+<a href="/userregions">User-defined regions</a><br>
 </body>
 </html>
 `)
@@ -22,9 +22,10 @@ func init() {
 	http.HandleFunc("/trace_viewer_html", httpTraceViewerHTML)
 }
 
-// httpTrace serves either whole trace (goid==0) or trace for goid goroutine.
+// httpTrace serves either whole trace (goid==0), trace for goid goroutine,
+// or trace for the goroutines of task taskid.
 func httpTrace(w http.ResponseWriter, r *http.Request) {
-	_, err := parseEvents()
+	events, err := parseEvents()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -38,6 +39,11 @@ func httpTrace(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		params = fmt.Sprintf("?goid=%v", goid)
+	} else if task, err := parseTaskID(r, events); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if task != nil {
+		params = fmt.Sprintf("?taskid=%v", task.ID)
 	}
 	html := strings.Replace(templTrace, "{{PARAMS}}", params, -1)
 	w.Write([]byte(html))
@@ -93,6 +99,19 @@ func httpJsonTrace(w http.ResponseWriter, r *http.Request) {
 		params.endTime = g.EndTime
 		params.maing = goid
 		params.gs = trace.RelatedGoroutines(events, goid)
+	} else if task, err := parseTaskID(r, events); err != nil {
+		log.Printf("%v", err)
+		return
+	} else if task != nil {
+		analyzeAnnotations(events)
+		params.gtrace = true
+		params.startTime = task.FirstTimestamp(0)
+		params.endTime = task.LastTimestamp(lastTs)
+		if task.Create != nil {
+			params.maing = task.Create.G
+		}
+		params.gs = taskGoroutines(task)
+		params.gs[0] = true // for GC events
 	}
 
 	err = json.NewEncoder(w).Encode(generateTrace(params))
@@ -283,6 +302,18 @@ func generateTrace(params *traceParams) ViewerData {
 		case trace.EvNextGC:
 			ctx.nextGC = ev.Args[0]
 			ctx.emitHeapCounters(ev)
+		case trace.EvUserTaskCreate:
+			ctx.emitInstant(ev, "task start")
+		case trace.EvUserTaskEnd:
+			ctx.emitInstant(ev, "task end")
+		case trace.EvUserRegion:
+			// Regions are shown only on the timelines of goroutines,
+			// as a goroutine may run on several Ps during a region.
+			if ctx.gtrace && ev.Args[1] == 0 && ev.Link != nil {
+				ctx.emitSlice(ev, "region "+ev.SArgs[0])
+			}
+		case trace.EvUserLog:
+			ctx.emitInstant(ev, "log")
 		}
 	}
 
@@ -390,11 +421,24 @@ func (ctx *traceContext) emitThreadCounters(ev *trace.Event) {
 
 func (ctx *traceContext) emitInstant(ev *trace.Event, name string) {
 	var arg interface{}
-	if ev.Type == trace.EvProcStart {
+	switch ev.Type {
+	case trace.EvProcStart:
 		type Arg struct {
 			ThreadID uint64
 		}
 		arg = &Arg{ev.Args[0]}
+	case trace.EvUserTaskCreate, trace.EvUserTaskEnd:
+		type Arg struct {
+			TaskID uint64
+		}
+		arg = &Arg{ev.Args[0]}
+	case trace.EvUserLog:
+		type Arg struct {
+			TaskID   uint64
+			Category string
+			Message  string
+		}
+		arg = &Arg{ev.Args[0], ev.SArgs[0], ev.SArgs[1]}
 	}
 	ctx.emit(&ViewerEvent{Name: name, Phase: "I", Scope: "t", Time: ctx.time(ev), Tid: ctx.proc(ev), Stack: ctx.stack(ev.Stk), Arg: arg})
 }
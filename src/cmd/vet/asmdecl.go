@@ -9,8 +9,10 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"go/analysis"
 	"go/ast"
 	"go/token"
+	"io/ioutil"
 	"regexp"
 	"strconv"
 	"strings"
@@ -91,48 +93,58 @@ var (
 	ppc64Suff    = re(`([BHWD])(ZU|Z|U|BR)?$`)
 )
 
-func asmCheck(pkg *Package) {
-	if !vet("asmdecl") {
-		return
-	}
+func init() {
+	registerAnalyzer(asmDeclAnalyzer)
+}
+
+var asmDeclAnalyzer = &analysis.Analyzer{
+	Name:             "asmdecl",
+	Doc:              "check assembly against Go declarations",
+	Run:              asmCheck,
+	RunDespiteErrors: true,
+}
 
+func asmCheck(pass *analysis.Pass) (interface{}, error) {
 	// No work if no assembly files.
-	if !pkg.hasFileWithSuffix(".s") {
-		return
+	var sfiles []string
+	for _, name := range pass.OtherFiles {
+		if strings.HasSuffix(name, ".s") {
+			sfiles = append(sfiles, name)
+		}
+	}
+	if len(sfiles) == 0 {
+		return nil, nil
 	}
 
 	// Gather declarations. knownFunc[name][arch] is func description.
 	knownFunc := make(map[string]map[string]*asmFunc)
 
-	for _, f := range pkg.files {
-		if f.file != nil {
-			for _, decl := range f.file.Decls {
-				if decl, ok := decl.(*ast.FuncDecl); ok && decl.Body == nil {
-					knownFunc[decl.Name.Name] = f.asmParseDecl(decl)
-				}
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if decl, ok := decl.(*ast.FuncDecl); ok && decl.Body == nil {
+				knownFunc[decl.Name.Name] = asmParseDecl(pass, decl)
 			}
 		}
 	}
 
 Files:
-	for _, f := range pkg.files {
-		if !strings.HasSuffix(f.name, ".s") {
-			continue
+	for _, name := range sfiles {
+		content, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
 		}
-		Println("Checking file", f.name)
-
 		// Determine architecture from file name if possible.
 		var arch string
 		var archDef *asmArch
 		for _, a := range arches {
-			if strings.HasSuffix(f.name, "_"+a.name+".s") {
+			if strings.HasSuffix(name, "_"+a.name+".s") {
 				arch = a.name
 				archDef = a
 				break
 			}
 		}
 
-		lines := strings.SplitAfter(string(f.content), "\n")
+		lines := strings.SplitAfter(string(content), "\n")
 		var (
 			fn                 *asmFunc
 			fnName             string
@@ -146,7 +158,7 @@ Files:
 			if fn != nil && fn.vars["ret"] != nil && !haveRetArg && len(retLine) > 0 {
 				v := fn.vars["ret"]
 				for _, line := range retLine {
-					f.Badf(token.NoPos, "%s:%d: [%s] %s: RET without writing to %d-byte ret+%d(FP)", f.name, line, arch, fnName, v.size, v.off)
+					pass.Reportf(token.NoPos, "%s:%d: [%s] %s: RET without writing to %d-byte ret+%d(FP)", name, line, arch, fnName, v.size, v.off)
 				}
 			}
 			retLine = nil
@@ -155,7 +167,7 @@ Files:
 			lineno++
 
 			badf := func(format string, args ...interface{}) {
-				f.Badf(token.NoPos, "%s:%d: [%s] %s: %s", f.name, lineno, arch, fnName, fmt.Sprintf(format, args...))
+				pass.Reportf(token.NoPos, "%s:%d: [%s] %s: %s", name, lineno, arch, fnName, fmt.Sprintf(format, args...))
 			}
 
 			if arch == "" {
@@ -177,7 +189,7 @@ Files:
 			if m := asmTEXT.FindStringSubmatch(line); m != nil {
 				flushRet()
 				if arch == "" {
-					f.Warnf(token.NoPos, "%s: cannot determine architecture for assembly file", f.name)
+					warnfAt(pass, token.NoPos, "%s: cannot determine architecture for assembly file", name)
 					continue Files
 				}
 				fnName = m[1]
@@ -296,10 +308,11 @@ Files:
 		}
 		flushRet()
 	}
+	return nil, nil
 }
 
 // asmParseDecl parses a function decl for expected assembly variables.
-func (f *File) asmParseDecl(decl *ast.FuncDecl) map[string]*asmFunc {
+func asmParseDecl(pass *analysis.Pass, decl *ast.FuncDecl) map[string]*asmFunc {
 	var (
 		arch   *asmArch
 		fn     *asmFunc
@@ -323,12 +336,12 @@ func (f *File) asmParseDecl(decl *ast.FuncDecl) map[string]*asmFunc {
 			var align, size int
 			var kind asmKind
 			names := fld.Names
-			typ := f.gofmt(fld.Type)
+			typ := gofmt(pass.Fset, fld.Type)
 			switch t := fld.Type.(type) {
 			default:
 				switch typ {
 				default:
-					f.Warnf(fld.Type.Pos(), "unknown assembly argument type %s", typ)
+					warnfAt(pass, fld.Type.Pos(), "unknown assembly argument type %s", typ)
 					failed = true
 					return
 				case "int8", "uint8", "byte", "bool":
@@ -366,10 +379,10 @@ func (f *File) asmParseDecl(decl *ast.FuncDecl) map[string]*asmFunc {
 					kind = asmSlice
 					break
 				}
-				f.Warnf(fld.Type.Pos(), "unsupported assembly argument type %s", typ)
+				warnfAt(pass, fld.Type.Pos(), "unsupported assembly argument type %s", typ)
 				failed = true
 			case *ast.StructType:
-				f.Warnf(fld.Type.Pos(), "unsupported assembly argument type %s", typ)
+				warnfAt(pass, fld.Type.Pos(), "unsupported assembly argument type %s", typ)
 				failed = true
 			}
 			if align == 0 {
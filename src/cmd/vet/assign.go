@@ -9,16 +9,33 @@ This file contains the code to check for useless assignments.
 package main
 
 import (
+	"go/analysis"
 	"go/ast"
 	"go/token"
 	"reflect"
 )
 
 func init() {
-	register("assign",
-		"check for useless assignments",
-		checkAssignStmt,
-		assignStmt)
+	registerAnalyzer(assignAnalyzer)
+}
+
+var assignAnalyzer = &analysis.Analyzer{
+	Name:             "assign",
+	Doc:              "check for useless assignments",
+	Run:              runAssign,
+	RunDespiteErrors: true,
+}
+
+func runAssign(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if stmt, ok := n.(*ast.AssignStmt); ok {
+				checkAssignStmt(pass, stmt)
+			}
+			return true
+		})
+	}
+	return nil, nil
 }
 
 // TODO: should also check for assignments to struct fields inside methods
@@ -26,8 +43,7 @@ func init() {
 
 // checkAssignStmt checks for assignments of the form "<expr> = <expr>".
 // These are almost always useless, and even when they aren't they are usually a mistake.
-func checkAssignStmt(f *File, node ast.Node) {
-	stmt := node.(*ast.AssignStmt)
+func checkAssignStmt(pass *analysis.Pass, stmt *ast.AssignStmt) {
 	if stmt.Tok != token.ASSIGN {
 		return // ignore :=
 	}
@@ -40,10 +56,10 @@ func checkAssignStmt(f *File, node ast.Node) {
 		if reflect.TypeOf(lhs) != reflect.TypeOf(rhs) {
 			continue // short-circuit the heavy-weight gofmt check
 		}
-		le := f.gofmt(lhs)
-		re := f.gofmt(rhs)
+		le := gofmt(pass.Fset, lhs)
+		re := gofmt(pass.Fset, rhs)
 		if le == re {
-			f.Badf(stmt.Pos(), "self-assignment of %s to %s", re, le)
+			pass.Reportf(stmt.Pos(), "self-assignment of %s to %s", re, le)
 		}
 	}
 }
@@ -5,21 +5,37 @@
 package main
 
 import (
+	"go/analysis"
 	"go/ast"
 	"go/token"
 )
 
 func init() {
-	register("atomic",
-		"check for common mistaken usages of the sync/atomic package",
-		checkAtomicAssignment,
-		assignStmt)
+	registerAnalyzer(atomicAnalyzer)
+}
+
+var atomicAnalyzer = &analysis.Analyzer{
+	Name:             "atomic",
+	Doc:              "check for common mistaken usages of the sync/atomic package",
+	Run:              runAtomic,
+	RunDespiteErrors: true,
+}
+
+func runAtomic(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if stmt, ok := n.(*ast.AssignStmt); ok {
+				checkAtomicAssignment(pass, stmt)
+			}
+			return true
+		})
+	}
+	return nil, nil
 }
 
 // checkAtomicAssignment walks the assignment statement checking for common
 // mistaken usage of atomic package, such as: x = atomic.AddUint64(&x, 1)
-func checkAtomicAssignment(f *File, node ast.Node) {
-	n := node.(*ast.AssignStmt)
+func checkAtomicAssignment(pass *analysis.Pass, n *ast.AssignStmt) {
 	if len(n.Lhs) != len(n.Rhs) {
 		return
 	}
@@ -40,14 +56,14 @@ func checkAtomicAssignment(f *File, node ast.Node) {
 
 		switch sel.Sel.Name {
 		case "AddInt32", "AddInt64", "AddUint32", "AddUint64", "AddUintptr":
-			f.checkAtomicAddAssignment(n.Lhs[i], call)
+			checkAtomicAddAssignment(pass, n.Lhs[i], call)
 		}
 	}
 }
 
 // checkAtomicAddAssignment walks the atomic.Add* method calls checking for assigning the return value
 // to the same variable being used in the operation
-func (f *File) checkAtomicAddAssignment(left ast.Expr, call *ast.CallExpr) {
+func checkAtomicAddAssignment(pass *analysis.Pass, left ast.Expr, call *ast.CallExpr) {
 	if len(call.Args) != 2 {
 		return
 	}
@@ -55,12 +71,12 @@ func (f *File) checkAtomicAddAssignment(left ast.Expr, call *ast.CallExpr) {
 	broken := false
 
 	if uarg, ok := arg.(*ast.UnaryExpr); ok && uarg.Op == token.AND {
-		broken = f.gofmt(left) == f.gofmt(uarg.X)
+		broken = gofmt(pass.Fset, left) == gofmt(pass.Fset, uarg.X)
 	} else if star, ok := left.(*ast.StarExpr); ok {
-		broken = f.gofmt(star.X) == f.gofmt(arg)
+		broken = gofmt(pass.Fset, star.X) == gofmt(pass.Fset, arg)
 	}
 
 	if broken {
-		f.Bad(left.Pos(), "direct assignment to atomic value")
+		pass.Reportf(left.Pos(), "direct assignment to atomic value")
 	}
 }
@@ -7,20 +7,35 @@
 package main
 
 import (
+	"go/analysis"
 	"go/ast"
 	"go/token"
 )
 
 func init() {
-	register("bool",
-		"check for mistakes involving boolean operators",
-		checkBool,
-		binaryExpr)
+	registerAnalyzer(boolAnalyzer)
 }
 
-func checkBool(f *File, n ast.Node) {
-	e := n.(*ast.BinaryExpr)
+var boolAnalyzer = &analysis.Analyzer{
+	Name:             "bool",
+	Doc:              "check for mistakes involving boolean operators",
+	Run:              runBool,
+	RunDespiteErrors: true,
+}
+
+func runBool(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n, ok := n.(*ast.BinaryExpr); ok {
+				checkBool(pass, n)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
 
+func checkBool(pass *analysis.Pass, e *ast.BinaryExpr) {
 	var op boolOp
 	switch e.Op {
 	case token.LOR:
@@ -33,8 +48,8 @@ func checkBool(f *File, n ast.Node) {
 
 	comm := op.commutativeSets(e)
 	for _, exprs := range comm {
-		op.checkRedundant(f, exprs)
-		op.checkSuspect(f, exprs)
+		op.checkRedundant(pass, exprs)
+		op.checkSuspect(pass, exprs)
 	}
 }
 
@@ -75,12 +90,12 @@ func (op boolOp) commutativeSets(e *ast.BinaryExpr) [][]ast.Expr {
 //   e && e
 //   e || e
 // Exprs must contain only side effect free expressions.
-func (op boolOp) checkRedundant(f *File, exprs []ast.Expr) {
+func (op boolOp) checkRedundant(pass *analysis.Pass, exprs []ast.Expr) {
 	seen := make(map[string]bool)
 	for _, e := range exprs {
-		efmt := f.gofmt(e)
+		efmt := gofmt(pass.Fset, e)
 		if seen[efmt] {
-			f.Badf(e.Pos(), "redundant %s: %s %s %s", op.name, efmt, op.tok, efmt)
+			pass.Reportf(e.Pos(), "redundant %s: %s %s %s", op.name, efmt, op.tok, efmt)
 		} else {
 			seen[efmt] = true
 		}
@@ -94,7 +109,7 @@ func (op boolOp) checkRedundant(f *File, exprs []ast.Expr) {
 // If c1 and c2 are the same then it's redundant;
 // if c1 and c2 are different then it's always true or always false.
 // Exprs must contain only side effect free expressions.
-func (op boolOp) checkSuspect(f *File, exprs []ast.Expr) {
+func (op boolOp) checkSuspect(pass *analysis.Pass, exprs []ast.Expr) {
 	// seen maps from expressions 'x' to equality expressions 'x != c'.
 	seen := make(map[string]string)
 
@@ -113,21 +128,21 @@ func (op boolOp) checkSuspect(f *File, exprs []ast.Expr) {
 		// code is written.
 		var x ast.Expr
 		switch {
-		case f.pkg.types[bin.Y].Value != nil:
+		case pass.TypesInfo.Types[bin.Y].Value != nil:
 			x = bin.X
-		case f.pkg.types[bin.X].Value != nil:
+		case pass.TypesInfo.Types[bin.X].Value != nil:
 			x = bin.Y
 		default:
 			continue
 		}
 
 		// e is of the form 'x != c' or 'x == c'.
-		xfmt := f.gofmt(x)
-		efmt := f.gofmt(e)
+		xfmt := gofmt(pass.Fset, x)
+		efmt := gofmt(pass.Fset, e)
 		if prev, found := seen[xfmt]; found {
 			// checkRedundant handles the case in which efmt == prev.
 			if efmt != prev {
-				f.Badf(e.Pos(), "suspect %s: %s %s %s", op.name, efmt, op.tok, prev)
+				pass.Reportf(e.Pos(), "suspect %s: %s %s %s", op.name, efmt, op.tok, prev)
 			}
 		} else {
 			seen[xfmt] = efmt
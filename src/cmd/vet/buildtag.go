@@ -6,24 +6,51 @@ package main
 
 import (
 	"bytes"
-	"fmt"
-	"os"
+	"go/analysis"
+	"go/token"
+	"io/ioutil"
 	"strings"
 	"unicode"
 )
 
+func init() {
+	registerAnalyzer(buildTagAnalyzer)
+}
+
+var buildTagAnalyzer = &analysis.Analyzer{
+	Name:             "buildtags",
+	Doc:              "check that +build tags are valid",
+	Run:              checkBuildTags,
+	RunDespiteErrors: true,
+}
+
 var (
 	nl         = []byte("\n")
 	slashSlash = []byte("//")
 	plusBuild  = []byte("+build")
 )
 
-// checkBuildTag checks that build tags are in the correct location and well-formed.
-func checkBuildTag(name string, data []byte) {
-	if !vet("buildtags") {
-		return
+// checkBuildTags checks the build tags of every file in the package,
+// including the non-Go files.
+func checkBuildTags(pass *analysis.Pass) (interface{}, error) {
+	var names []string
+	for _, file := range pass.Files {
+		names = append(names, pass.Fset.Position(file.Pos()).Filename)
 	}
-	lines := bytes.SplitAfter(data, nl)
+	names = append(names, pass.OtherFiles...)
+	for _, name := range names {
+		content, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		checkBuildTag(pass, name, content)
+	}
+	return nil, nil
+}
+
+// checkBuildTag checks that build tags are in the correct location and well-formed.
+func checkBuildTag(pass *analysis.Pass, name string, content []byte) {
+	lines := bytes.SplitAfter(content, nl)
 
 	// Determine cutpoint where +build comments are no longer valid.
 	// They are valid in leading // comments in the file followed by
@@ -51,12 +78,11 @@ func checkBuildTag(name string, data []byte) {
 			fields := bytes.Fields(text)
 			if !bytes.Equal(fields[0], plusBuild) {
 				// Comment is something like +buildasdf not +build.
-				fmt.Fprintf(os.Stderr, "%s:%d: possible malformed +build comment\n", name, i+1)
+				warnfAt(pass, token.NoPos, "%s:%d: possible malformed +build comment", name, i+1)
 				continue
 			}
 			if i >= cutoff {
-				fmt.Fprintf(os.Stderr, "%s:%d: +build comment must appear before package clause and be followed by a blank line\n", name, i+1)
-				setExit(1)
+				pass.Reportf(token.NoPos, "%s:%d: +build comment must appear before package clause and be followed by a blank line", name, i+1)
 				continue
 			}
 			// Check arguments.
@@ -64,8 +90,7 @@ func checkBuildTag(name string, data []byte) {
 			for _, arg := range fields[1:] {
 				for _, elem := range strings.Split(string(arg), ",") {
 					if strings.HasPrefix(elem, "!!") {
-						fmt.Fprintf(os.Stderr, "%s:%d: invalid double negative in build constraint: %s\n", name, i+1, arg)
-						setExit(1)
+						pass.Reportf(token.NoPos, "%s:%d: invalid double negative in build constraint: %s", name, i+1, arg)
 						break Args
 					}
 					if strings.HasPrefix(elem, "!") {
@@ -73,8 +98,7 @@ func checkBuildTag(name string, data []byte) {
 					}
 					for _, c := range elem {
 						if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
-							fmt.Fprintf(os.Stderr, "%s:%d: invalid non-alphanumeric build constraint: %s\n", name, i+1, arg)
-							setExit(1)
+							pass.Reportf(token.NoPos, "%s:%d: invalid non-alphanumeric build constraint: %s", name, i+1, arg)
 							break Args
 						}
 					}
@@ -84,7 +108,7 @@ func checkBuildTag(name string, data []byte) {
 		}
 		// Comment with +build but not at beginning.
 		if bytes.Contains(line, plusBuild) && i < cutoff {
-			fmt.Fprintf(os.Stderr, "%s:%d: possible malformed +build comment\n", name, i+1)
+			warnfAt(pass, token.NoPos, "%s:%d: possible malformed +build comment", name, i+1)
 			continue
 		}
 	}
@@ -12,21 +12,36 @@
 package main
 
 import (
+	"go/analysis"
 	"go/ast"
 	"go/token"
 	"go/types"
 )
 
 func init() {
-	register("cgocall",
-		"check for types that may not be passed to cgo calls",
-		checkCgoCall,
-		callExpr)
+	registerAnalyzer(cgoCallAnalyzer)
 }
 
-func checkCgoCall(f *File, node ast.Node) {
-	x := node.(*ast.CallExpr)
+var cgoCallAnalyzer = &analysis.Analyzer{
+	Name:             "cgocall",
+	Doc:              "check for types that may not be passed to cgo calls",
+	Run:              runCgoCall,
+	RunDespiteErrors: true,
+}
+
+func runCgoCall(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n, ok := n.(*ast.CallExpr); ok {
+				checkCgoCall(pass, n)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
 
+func checkCgoCall(pass *analysis.Pass, x *ast.CallExpr) {
 	// We are only looking for calls to functions imported from
 	// the "C" package.
 	sel, ok := x.Fun.(*ast.SelectorExpr)
@@ -39,17 +54,17 @@ func checkCgoCall(f *File, node ast.Node) {
 	}
 
 	for _, arg := range x.Args {
-		if !typeOKForCgoCall(cgoBaseType(f, arg)) {
-			f.Badf(arg.Pos(), "possibly passing Go type with embedded pointer to C")
+		if !typeOKForCgoCall(cgoBaseType(pass, arg)) {
+			pass.Reportf(arg.Pos(), "possibly passing Go type with embedded pointer to C")
 		}
 
 		// Check for passing the address of a bad type.
-		if conv, ok := arg.(*ast.CallExpr); ok && len(conv.Args) == 1 && f.hasBasicType(conv.Fun, types.UnsafePointer) {
+		if conv, ok := arg.(*ast.CallExpr); ok && len(conv.Args) == 1 && hasBasicType(pass, conv.Fun, types.UnsafePointer) {
 			arg = conv.Args[0]
 		}
 		if u, ok := arg.(*ast.UnaryExpr); ok && u.Op == token.AND {
-			if !typeOKForCgoCall(cgoBaseType(f, u.X)) {
-				f.Badf(arg.Pos(), "possibly passing Go type with embedded pointer to C")
+			if !typeOKForCgoCall(cgoBaseType(pass, u.X)) {
+				pass.Reportf(arg.Pos(), "possibly passing Go type with embedded pointer to C")
 			}
 		}
 	}
@@ -59,11 +74,11 @@ func checkCgoCall(f *File, node ast.Node) {
 // unsafe.Pointer to find the real type.  It converts:
 //   unsafe.Pointer(x) => x
 //   *(*unsafe.Pointer)(unsafe.Pointer(&x)) => x
-func cgoBaseType(f *File, arg ast.Expr) types.Type {
+func cgoBaseType(pass *analysis.Pass, arg ast.Expr) types.Type {
 	switch arg := arg.(type) {
 	case *ast.CallExpr:
-		if len(arg.Args) == 1 && f.hasBasicType(arg.Fun, types.UnsafePointer) {
-			return cgoBaseType(f, arg.Args[0])
+		if len(arg.Args) == 1 && hasBasicType(pass, arg.Fun, types.UnsafePointer) {
+			return cgoBaseType(pass, arg.Args[0])
 		}
 	case *ast.StarExpr:
 		call, ok := arg.X.(*ast.CallExpr)
@@ -71,7 +86,7 @@ func cgoBaseType(f *File, arg ast.Expr) types.Type {
 			break
 		}
 		// Here arg is *f(v).
-		t := f.pkg.types[call.Fun].Type
+		t := pass.TypesInfo.Types[call.Fun].Type
 		if t == nil {
 			break
 		}
@@ -90,7 +105,7 @@ func cgoBaseType(f *File, arg ast.Expr) types.Type {
 			break
 		}
 		// Here arg is *(*unsafe.Pointer)(f(v))
-		if !f.hasBasicType(call.Fun, types.UnsafePointer) {
+		if !hasBasicType(pass, call.Fun, types.UnsafePointer) {
 			break
 		}
 		// Here arg is *(*unsafe.Pointer)(unsafe.Pointer(v))
@@ -99,10 +114,10 @@ func cgoBaseType(f *File, arg ast.Expr) types.Type {
 			break
 		}
 		// Here arg is *(*unsafe.Pointer)(unsafe.Pointer(&v))
-		return cgoBaseType(f, u.X)
+		return cgoBaseType(pass, u.X)
 	}
 
-	return f.pkg.types[arg].Type
+	return pass.TypesInfo.Types[arg].Type
 }
 
 // typeOKForCgoCall returns true if the type of arg is OK to pass to a
@@ -9,6 +9,7 @@ package main
 import (
 	"cmd/vet/internal/whitelist"
 	"flag"
+	"go/analysis"
 	"go/ast"
 	"strings"
 )
@@ -16,16 +17,31 @@ import (
 var compositeWhiteList = flag.Bool("compositewhitelist", true, "use composite white list; for testing only")
 
 func init() {
-	register("composites",
-		"check that composite literals used field-keyed elements",
-		checkUnkeyedLiteral,
-		compositeLit)
+	registerAnalyzer(compositeAnalyzer)
+}
+
+var compositeAnalyzer = &analysis.Analyzer{
+	Name:             "composites",
+	Doc:              "check that composite literals used field-keyed elements",
+	Run:              runComposite,
+	RunDespiteErrors: true,
+}
+
+func runComposite(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n, ok := n.(*ast.CompositeLit); ok {
+				checkUnkeyedLiteral(pass, file, n)
+			}
+			return true
+		})
+	}
+	return nil, nil
 }
 
 // checkUnkeyedLiteral checks if a composite literal is a struct literal with
 // unkeyed fields.
-func checkUnkeyedLiteral(f *File, node ast.Node) {
-	c := node.(*ast.CompositeLit)
+func checkUnkeyedLiteral(pass *analysis.Pass, file *ast.File, c *ast.CompositeLit) {
 	typ := c.Type
 	for {
 		if typ1, ok := c.Type.(*ast.ParenExpr); ok {
@@ -53,13 +69,13 @@ func checkUnkeyedLiteral(f *File, node ast.Node) {
 
 	// Otherwise the type is a selector like pkg.Name.
 	// We only care if pkg.Name is a struct, not if it's a map, array, or slice.
-	isStruct, typeString := f.pkg.isStruct(c)
+	isStruct, typeString := isStruct(pass, c)
 	if !isStruct {
 		return
 	}
 
 	if typeString == "" { // isStruct doesn't know
-		typeString = f.gofmt(typ)
+		typeString = gofmt(pass.Fset, typ)
 	}
 
 	// It's a struct, or we can't tell it's not a struct because we don't have types.
@@ -87,9 +103,9 @@ func checkUnkeyedLiteral(f *File, node ast.Node) {
 	}
 
 	// Convert the package name to an import path, and compare to a whitelist.
-	path := pkgPath(f, pkg.Name)
+	path := pkgPath(file, pkg.Name)
 	if path == "" {
-		f.Badf(c.Pos(), "unresolvable package for %s.%s literal", pkg.Name, s.Sel.Name)
+		pass.Reportf(c.Pos(), "unresolvable package for %s.%s literal", pkg.Name, s.Sel.Name)
 		return
 	}
 	typeName := path + "." + s.Sel.Name
@@ -97,16 +113,17 @@ func checkUnkeyedLiteral(f *File, node ast.Node) {
 		return
 	}
 
-	f.Bad(c.Pos(), typeString+" composite literal uses unkeyed fields")
+	pass.Reportf(c.Pos(), "%s composite literal uses unkeyed fields", typeString)
 }
 
-// pkgPath returns the import path "image/png" for the package name "png".
+// pkgPath returns the import path "image/png" for the package name "png"
+// as imported by file.
 //
 // This is based purely on syntax and convention, and not on the imported
 // package's contents. It will be incorrect if a package name differs from the
 // leaf element of the import path, or if the package was a dot import.
-func pkgPath(f *File, pkgName string) (path string) {
-	for _, x := range f.file.Imports {
+func pkgPath(file *ast.File, pkgName string) (path string) {
+	for _, x := range file.Imports {
 		s := strings.Trim(x.Path.Value, `"`)
 		if x.Name != nil {
 			// Catch `import pkgName "foo/bar"`.
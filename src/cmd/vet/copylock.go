@@ -9,39 +9,54 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"go/analysis"
 	"go/ast"
 	"go/token"
 	"go/types"
 )
 
 func init() {
-	register("copylocks",
-		"check that locks are not passed by value",
-		checkCopyLocks,
-		funcDecl, rangeStmt, funcLit, assignStmt)
+	registerAnalyzer(copyLockAnalyzer)
+}
+
+var copyLockAnalyzer = &analysis.Analyzer{
+	Name:             "copylocks",
+	Doc:              "check that locks are not passed by value",
+	Run:              runCopyLocks,
+	RunDespiteErrors: true,
+}
+
+func runCopyLocks(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			checkCopyLocks(pass, n)
+			return true
+		})
+	}
+	return nil, nil
 }
 
 // checkCopyLocks checks whether node might
 // inadvertently copy a lock.
-func checkCopyLocks(f *File, node ast.Node) {
+func checkCopyLocks(pass *analysis.Pass, node ast.Node) {
 	switch node := node.(type) {
 	case *ast.RangeStmt:
-		checkCopyLocksRange(f, node)
+		checkCopyLocksRange(pass, node)
 	case *ast.FuncDecl:
-		checkCopyLocksFunc(f, node.Name.Name, node.Recv, node.Type)
+		checkCopyLocksFunc(pass, node.Name.Name, node.Recv, node.Type)
 	case *ast.FuncLit:
-		checkCopyLocksFunc(f, "func", nil, node.Type)
+		checkCopyLocksFunc(pass, "func", nil, node.Type)
 	case *ast.AssignStmt:
-		checkCopyLocksAssign(f, node)
+		checkCopyLocksAssign(pass, node)
 	}
 }
 
 // checkCopyLocksAssign checks whether an assignment
 // copies a lock.
-func checkCopyLocksAssign(f *File, as *ast.AssignStmt) {
+func checkCopyLocksAssign(pass *analysis.Pass, as *ast.AssignStmt) {
 	for _, x := range as.Lhs {
-		if path := lockPath(f.pkg.typesPkg, f.pkg.types[x].Type); path != nil {
-			f.Badf(x.Pos(), "assignment copies lock value to %v: %v", f.gofmt(x), path)
+		if path := lockPath(pass.Pkg, pass.TypesInfo.Types[x].Type); path != nil {
+			pass.Reportf(x.Pos(), "assignment copies lock value to %v: %v", gofmt(pass.Fset, x), path)
 		}
 	}
 }
@@ -50,19 +65,19 @@ func checkCopyLocksAssign(f *File, as *ast.AssignStmt) {
 // inadvertently copy a lock, by checking whether
 // its receiver, parameters, or return values
 // are locks.
-func checkCopyLocksFunc(f *File, name string, recv *ast.FieldList, typ *ast.FuncType) {
+func checkCopyLocksFunc(pass *analysis.Pass, name string, recv *ast.FieldList, typ *ast.FuncType) {
 	if recv != nil && len(recv.List) > 0 {
 		expr := recv.List[0].Type
-		if path := lockPath(f.pkg.typesPkg, f.pkg.types[expr].Type); path != nil {
-			f.Badf(expr.Pos(), "%s passes lock by value: %v", name, path)
+		if path := lockPath(pass.Pkg, pass.TypesInfo.Types[expr].Type); path != nil {
+			pass.Reportf(expr.Pos(), "%s passes lock by value: %v", name, path)
 		}
 	}
 
 	if typ.Params != nil {
 		for _, field := range typ.Params.List {
 			expr := field.Type
-			if path := lockPath(f.pkg.typesPkg, f.pkg.types[expr].Type); path != nil {
-				f.Badf(expr.Pos(), "%s passes lock by value: %v", name, path)
+			if path := lockPath(pass.Pkg, pass.TypesInfo.Types[expr].Type); path != nil {
+				pass.Reportf(expr.Pos(), "%s passes lock by value: %v", name, path)
 			}
 		}
 	}
@@ -70,8 +85,8 @@ func checkCopyLocksFunc(f *File, name string, recv *ast.FieldList, typ *ast.Func
 	if typ.Results != nil {
 		for _, field := range typ.Results.List {
 			expr := field.Type
-			if path := lockPath(f.pkg.typesPkg, f.pkg.types[expr].Type); path != nil {
-				f.Badf(expr.Pos(), "%s returns lock by value: %v", name, path)
+			if path := lockPath(pass.Pkg, pass.TypesInfo.Types[expr].Type); path != nil {
+				pass.Reportf(expr.Pos(), "%s returns lock by value: %v", name, path)
 			}
 		}
 	}
@@ -80,12 +95,12 @@ func checkCopyLocksFunc(f *File, name string, recv *ast.FieldList, typ *ast.Func
 // checkCopyLocksRange checks whether a range statement
 // might inadvertently copy a lock by checking whether
 // any of the range variables are locks.
-func checkCopyLocksRange(f *File, r *ast.RangeStmt) {
-	checkCopyLocksRangeVar(f, r.Tok, r.Key)
-	checkCopyLocksRangeVar(f, r.Tok, r.Value)
+func checkCopyLocksRange(pass *analysis.Pass, r *ast.RangeStmt) {
+	checkCopyLocksRangeVar(pass, r.Tok, r.Key)
+	checkCopyLocksRangeVar(pass, r.Tok, r.Value)
 }
 
-func checkCopyLocksRangeVar(f *File, rtok token.Token, e ast.Expr) {
+func checkCopyLocksRangeVar(pass *analysis.Pass, rtok token.Token, e ast.Expr) {
 	if e == nil {
 		return
 	}
@@ -99,20 +114,20 @@ func checkCopyLocksRangeVar(f *File, rtok token.Token, e ast.Expr) {
 		if !isId {
 			return
 		}
-		obj := f.pkg.defs[id]
+		obj := pass.TypesInfo.Defs[id]
 		if obj == nil {
 			return
 		}
 		typ = obj.Type()
 	} else {
-		typ = f.pkg.types[e].Type
+		typ = pass.TypesInfo.Types[e].Type
 	}
 
 	if typ == nil {
 		return
 	}
-	if path := lockPath(f.pkg.typesPkg, typ); path != nil {
-		f.Badf(e.Pos(), "range var %s copies lock: %v", f.gofmt(e), path)
+	if path := lockPath(pass.Pkg, typ); path != nil {
+		pass.Reportf(e.Pos(), "range var %s copies lock: %v", gofmt(pass.Fset, e), path)
 	}
 }
 
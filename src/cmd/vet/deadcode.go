@@ -7,19 +7,34 @@
 package main
 
 import (
+	"go/analysis"
 	"go/ast"
 	"go/token"
 )
 
 func init() {
-	register("unreachable",
-		"check for unreachable code",
-		checkUnreachable,
-		funcDecl, funcLit)
+	registerAnalyzer(unreachableAnalyzer)
+}
+
+var unreachableAnalyzer = &analysis.Analyzer{
+	Name:             "unreachable",
+	Doc:              "check for unreachable code",
+	Run:              runUnreachable,
+	RunDespiteErrors: true,
+}
+
+func runUnreachable(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			checkUnreachable(pass, n)
+			return true
+		})
+	}
+	return nil, nil
 }
 
 type deadState struct {
-	f           *File
+	pass        *analysis.Pass
 	hasBreak    map[ast.Stmt]bool
 	hasGoto     map[string]bool
 	labels      map[string]ast.Stmt
@@ -29,7 +44,7 @@ type deadState struct {
 }
 
 // checkUnreachable checks a function body for dead code.
-func checkUnreachable(f *File, node ast.Node) {
+func checkUnreachable(pass *analysis.Pass, node ast.Node) {
 	var body *ast.BlockStmt
 	switch n := node.(type) {
 	case *ast.FuncDecl:
@@ -42,7 +57,7 @@ func checkUnreachable(f *File, node ast.Node) {
 	}
 
 	d := &deadState{
-		f:        f,
+		pass:     pass,
 		hasBreak: make(map[ast.Stmt]bool),
 		hasGoto:  make(map[string]bool),
 		labels:   make(map[string]ast.Stmt),
@@ -59,7 +74,7 @@ func checkUnreachable(f *File, node ast.Node) {
 func (d *deadState) findLabels(stmt ast.Stmt) {
 	switch x := stmt.(type) {
 	default:
-		d.f.Warnf(x.Pos(), "internal error in findLabels: unexpected statement %T", x)
+		warnfAt(d.pass, x.Pos(), "internal error in findLabels: unexpected statement %T", x)
 
 	case *ast.AssignStmt,
 		*ast.BadStmt,
@@ -171,14 +186,14 @@ func (d *deadState) findDead(stmt ast.Stmt) {
 		case *ast.EmptyStmt:
 			// do not warn about unreachable empty statements
 		default:
-			d.f.Bad(stmt.Pos(), "unreachable code")
+			d.pass.Reportf(stmt.Pos(), "unreachable code")
 			d.reachable = true // silence error about next statement
 		}
 	}
 
 	switch x := stmt.(type) {
 	default:
-		d.f.Warnf(x.Pos(), "internal error in findDead: unexpected statement %T", x)
+		warnfAt(d.pass, x.Pos(), "internal error in findDead: unexpected statement %T", x)
 
 	case *ast.AssignStmt,
 		*ast.BadStmt,
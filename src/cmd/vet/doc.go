@@ -35,6 +35,11 @@ explicitly set to false, only those tests are disabled.
 Thus -printf=true runs the printf check, -printf=false runs all checks
 except the printf check.
 
+Each check is an analyzer written against the go/analysis package.
+Analyzers for project-specific checks can be combined into a separate
+vet-like program using go/analysis/multichecker and run with
+'go vet -vettool=prog'.
+
 Available checks:
 
 Assembly declarations
@@ -179,6 +184,16 @@ discarded.  By default, this includes functions like fmt.Errorf and
 fmt.Sprintf and methods like String and Error. The flags -unusedfuncs
 and -unusedstringmethods control the set.
 
+Uses of package syscall not available on zos
+
+Flag: -zossyscall
+
+Uses of names that package syscall does not declare on zos, and calls
+of syscall.Syscall and its variants with a literal system call number,
+as system call numbers differ on zos. The check follows such uses into
+functions of other packages, reporting calls of those functions too.
+It is experimental and must be set explicitly.
+
 Other flags
 
 These flags configure the behavior of vet:
@@ -199,6 +214,6 @@ These flags configure the behavior of vet:
 	-shadowstrict
 		Whether to be strict about shadowing; can be noisy.
 	-test
-		For testing only: sets -all and -shadow.
+		For testing only: sets -all and the experimental checks.
 */
 package main // import "golang.org/x/tools/cmd/vet"
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"go/analysis"
 	"go/ast"
 	"go/types"
 	"strings"
@@ -13,10 +14,28 @@ import (
 )
 
 func init() {
-	register("example",
-		"check for common mistaken usages of documentation examples",
-		checkExample,
-		funcDecl)
+	registerAnalyzer(exampleAnalyzer)
+}
+
+var exampleAnalyzer = &analysis.Analyzer{
+	Name:             "example",
+	Doc:              "check for common mistaken usages of documentation examples",
+	Run:              runExample,
+	RunDespiteErrors: true,
+}
+
+func runExample(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		if !strings.HasSuffix(pass.Fset.Position(file.Pos()).Filename, "_test.go") {
+			continue
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				checkExample(pass, fn)
+			}
+		}
+	}
+	return nil, nil
 }
 
 func isExampleSuffix(s string) bool {
@@ -27,14 +46,10 @@ func isExampleSuffix(s string) bool {
 // checkExample walks the documentation example functions checking for common
 // mistakes of misnamed functions, failure to map functions to existing
 // identifiers, etc.
-func checkExample(f *File, node ast.Node) {
-	if !strings.HasSuffix(f.name, "_test.go") {
-		return
-	}
+func checkExample(pass *analysis.Pass, fn *ast.FuncDecl) {
 	var (
-		pkg     = f.pkg
-		pkgName = pkg.typesPkg.Name()
-		scopes  = []*types.Scope{pkg.typesPkg.Scope()}
+		pkgName = pass.Pkg.Name()
+		scopes  = []*types.Scope{pass.Pkg.Scope()}
 		lookup  = func(name string) types.Object {
 			for _, scope := range scopes {
 				if o := scope.Lookup(name); o != nil {
@@ -46,25 +61,17 @@ func checkExample(f *File, node ast.Node) {
 	)
 	if strings.HasSuffix(pkgName, "_test") {
 		// Treat 'package foo_test' as an alias for 'package foo'.
-		var (
-			basePkg = strings.TrimSuffix(pkgName, "_test")
-			pkg     = f.pkg
-		)
-		for _, p := range pkg.typesPkg.Imports() {
+		basePkg := strings.TrimSuffix(pkgName, "_test")
+		for _, p := range pass.Pkg.Imports() {
 			if p.Name() == basePkg {
 				scopes = append(scopes, p.Scope())
 				break
 			}
 		}
 	}
-	fn, ok := node.(*ast.FuncDecl)
-	if !ok {
-		// Ignore non-functions.
-		return
-	}
 	var (
 		fnName = fn.Name.Name
-		report = func(format string, args ...interface{}) { f.Badf(node.Pos(), format, args...) }
+		report = func(format string, args ...interface{}) { pass.Reportf(fn.Pos(), format, args...) }
 	)
 	if fn.Recv != nil || !strings.HasPrefix(fnName, "Example") {
 		// Ignore methods and types not named "Example".
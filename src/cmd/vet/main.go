@@ -10,22 +10,22 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"go/analysis"
+	"go/analysis/checker"
 	"go/ast"
 	"go/build"
-	"go/parser"
 	"go/printer"
 	"go/token"
-	"go/types"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
 
 var (
 	verbose  = flag.Bool("v", false, "verbose")
-	testFlag = flag.Bool("test", false, "for testing only: sets -all and -shadow")
+	testFlag = flag.Bool("test", false, "for testing only: sets -all and the experimental checks")
 	tags     = flag.String("tags", "", "comma-separated list of build tags to apply when parsing")
 	tagList  = []string{} // exploded version of tags flag; set in main
 )
@@ -36,12 +36,11 @@ var exitCode = 0
 var all = triStateFlag("all", unset, "enable all non-experimental checks")
 
 // Flags to control which individual checks to perform.
-var report = map[string]*triState{
-	// Only unusual checks are written here.
-	// Most checks that operate during the AST walk are added by register.
-	"asmdecl":   triStateFlag("asmdecl", unset, "check assembly against Go declarations"),
-	"buildtags": triStateFlag("buildtags", unset, "check that +build tags are valid"),
-}
+// Checks are added by registerAnalyzer.
+var report = map[string]*triState{}
+
+// analyzers maps the name of each check to the analyzer that implements it.
+var analyzers = map[string]*analysis.Analyzer{}
 
 // experimental records the flags enabling experimental features. These must be
 // requested explicitly; they are not enabled by -all.
@@ -130,36 +129,10 @@ func setExit(err int) {
 	}
 }
 
-var (
-	// Each of these vars has a corresponding case in (*File).Visit.
-	assignStmt    *ast.AssignStmt
-	binaryExpr    *ast.BinaryExpr
-	callExpr      *ast.CallExpr
-	compositeLit  *ast.CompositeLit
-	exprStmt      *ast.ExprStmt
-	field         *ast.Field
-	funcDecl      *ast.FuncDecl
-	funcLit       *ast.FuncLit
-	genDecl       *ast.GenDecl
-	interfaceType *ast.InterfaceType
-	rangeStmt     *ast.RangeStmt
-
-	// checkers is a two-level map.
-	// The outer level is keyed by a nil pointer, one of the AST vars above.
-	// The inner level is keyed by checker name.
-	checkers = make(map[ast.Node]map[string]func(*File, ast.Node))
-)
-
-func register(name, usage string, fn func(*File, ast.Node), types ...ast.Node) {
-	report[name] = triStateFlag(name, unset, usage)
-	for _, typ := range types {
-		m := checkers[typ]
-		if m == nil {
-			m = make(map[string]func(*File, ast.Node))
-			checkers[typ] = m
-		}
-		m[name] = fn
-	}
+// registerAnalyzer adds a check implemented by the analyzer a.
+func registerAnalyzer(a *analysis.Analyzer) {
+	report[a.Name] = triStateFlag(a.Name, unset, a.Doc)
+	analyzers[a.Name] = a
 }
 
 // Usage is a replacement usage function for the flags package.
@@ -174,24 +147,6 @@ func Usage() {
 	os.Exit(2)
 }
 
-// File is a wrapper for the state of a file used in the parser.
-// The parse tree walkers are all methods of this type.
-type File struct {
-	pkg     *Package
-	fset    *token.FileSet
-	name    string
-	content []byte
-	file    *ast.File
-	b       bytes.Buffer // for use by methods
-
-	// The objects that are receivers of a "String() string" method.
-	// This is used by the recursiveStringer method in print.go.
-	stringers map[*ast.Object]bool
-
-	// Registered checkers to run.
-	checkers map[ast.Node][]func(*File, ast.Node)
-}
-
 func main() {
 	flag.Usage = Usage
 	flag.Parse()
@@ -212,6 +167,17 @@ func main() {
 	initPrintFlags()
 	initUnusedFlags()
 
+	context := build.Default
+	if len(context.BuildTags) != 0 {
+		warnf("build tags %s previously set", context.BuildTags)
+	}
+	context.BuildTags = append(tagList, context.BuildTags...)
+	vetChecker = &checker.Checker{
+		Analyzers: enabledAnalyzers(),
+		Context:   &context,
+		Importer:  stdImporter,
+	}
+
 	if flag.NArg() == 0 {
 		Usage()
 	}
@@ -246,6 +212,25 @@ func main() {
 	os.Exit(exitCode)
 }
 
+// vetChecker runs the enabled checks on each package.
+var vetChecker *checker.Checker
+
+// enabledAnalyzers returns the analyzers of the enabled checks, sorted by name.
+func enabledAnalyzers() []*analysis.Analyzer {
+	var names []string
+	for name := range analyzers {
+		if vet(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	list := make([]*analysis.Analyzer, len(names))
+	for i, name := range names {
+		list[i] = analyzers[name]
+	}
+	return list
+}
+
 // prefixDirectory places the directory name on the beginning of each name in the list.
 func prefixDirectory(directory string, names []string) {
 	if directory != "." {
@@ -258,13 +243,7 @@ func prefixDirectory(directory string, names []string) {
 // doPackageDir analyzes the single package found in the directory, if there is one,
 // plus a test package, if there is one.
 func doPackageDir(directory string) {
-	context := build.Default
-	if len(context.BuildTags) != 0 {
-		warnf("build tags %s previously set", context.BuildTags)
-	}
-	context.BuildTags = append(tagList, context.BuildTags...)
-
-	pkg, err := context.ImportDir(directory, 0)
+	pkg, err := vetChecker.Context.ImportDir(directory, 0)
 	if err != nil {
 		// If it's just that there are no go source files, that's fine.
 		if _, nogo := err.(*build.NoGoError); nogo {
@@ -289,71 +268,35 @@ func doPackageDir(directory string) {
 	}
 }
 
-type Package struct {
-	path      string
-	defs      map[*ast.Ident]types.Object
-	uses      map[*ast.Ident]types.Object
-	selectors map[*ast.SelectorExpr]*types.Selection
-	types     map[ast.Expr]types.TypeAndValue
-	spans     map[types.Object]Span
-	files     []*File
-	typesPkg  *types.Package
-}
-
 // doPackage analyzes the single package constructed from the named files.
 // It returns whether any files were checked.
 func doPackage(directory string, names []string) bool {
-	var files []*File
-	var astFiles []*ast.File
-	fs := token.NewFileSet()
 	for _, name := range names {
-		data, err := ioutil.ReadFile(name)
-		if err != nil {
-			// Warn but continue to next package.
-			warnf("%s: %s", name, err)
-			return false
-		}
-		checkBuildTag(name, data)
-		var parsedFile *ast.File
-		if strings.HasSuffix(name, ".go") {
-			parsedFile, err = parser.ParseFile(fs, name, data, 0)
-			if err != nil {
-				warnf("%s: %s", name, err)
-				return false
-			}
-			astFiles = append(astFiles, parsedFile)
-		}
-		files = append(files, &File{fset: fs, content: data, name: name, file: parsedFile})
+		Println("Checking file", name)
 	}
-	if len(astFiles) == 0 {
+	pkg, err := vetChecker.Load("", names)
+	if err != nil {
+		// Warn but continue to next package.
+		warnf("%s", err)
 		return false
 	}
-	pkg := new(Package)
-	pkg.path = astFiles[0].Name.Name
-	pkg.files = files
-	// Type check the package.
-	err := pkg.check(fs, astFiles)
-	if err != nil && *verbose {
-		warnf("%s", err)
+	if len(pkg.TypeErrors) > 0 && *verbose {
+		warnf("%s", pkg.TypeErrors[0])
 	}
-
-	// Check.
-	chk := make(map[ast.Node][]func(*File, ast.Node))
-	for typ, set := range checkers {
-		for name, fn := range set {
-			if vet(name) {
-				chk[typ] = append(chk[typ], fn)
-			}
+	diags, err := vetChecker.Check(pkg)
+	for _, d := range diags {
+		loc := ""
+		if d.Pos.IsValid() {
+			// As in warnfAt, do not print columns.
+			posn := pkg.Fset.Position(d.Pos)
+			loc = fmt.Sprintf("%s:%d: ", posn.Filename, posn.Line)
 		}
+		fmt.Fprintf(os.Stderr, "%s%s\n", loc, d.Message)
+		setExit(1)
 	}
-	for _, file := range files {
-		file.pkg = pkg
-		file.checkers = chk
-		if file.file != nil {
-			file.walkFile(file.name, file.file)
-		}
+	if err != nil {
+		warnf("%s", err)
 	}
-	asmCheck(pkg)
 	return true
 }
 
@@ -370,15 +313,6 @@ func visit(path string, f os.FileInfo, err error) error {
 	return nil
 }
 
-func (pkg *Package) hasFileWithSuffix(suffix string) bool {
-	for _, f := range pkg.files {
-		if strings.HasSuffix(f.name, suffix) {
-			return true
-		}
-	}
-	return false
-}
-
 // walkDir recursively walks the tree looking for Go packages.
 func walkDir(root string) {
 	filepath.Walk(root, visit)
@@ -414,82 +348,22 @@ func Printf(format string, args ...interface{}) {
 	fmt.Printf(format+"\n", args...)
 }
 
-// Bad reports an error and sets the exit code..
-func (f *File) Bad(pos token.Pos, args ...interface{}) {
-	f.Warn(pos, args...)
-	setExit(1)
-}
-
-// Badf reports a formatted error and sets the exit code.
-func (f *File) Badf(pos token.Pos, format string, args ...interface{}) {
-	f.Warnf(pos, format, args...)
-	setExit(1)
-}
-
-// loc returns a formatted representation of the position.
-func (f *File) loc(pos token.Pos) string {
-	if pos == token.NoPos {
-		return ""
-	}
-	// Do not print columns. Because the pos often points to the start of an
-	// expression instead of the inner part with the actual error, the
-	// precision can mislead.
-	posn := f.fset.Position(pos)
-	return fmt.Sprintf("%s:%d: ", posn.Filename, posn.Line)
-}
-
-// Warn reports an error but does not set the exit code.
-func (f *File) Warn(pos token.Pos, args ...interface{}) {
-	fmt.Fprint(os.Stderr, f.loc(pos)+fmt.Sprintln(args...))
-}
-
-// Warnf reports a formatted error but does not set the exit code.
-func (f *File) Warnf(pos token.Pos, format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, f.loc(pos)+format+"\n", args...)
-}
-
-// walkFile walks the file's tree.
-func (f *File) walkFile(name string, file *ast.File) {
-	Println("Checking file", name)
-	ast.Walk(f, file)
-}
-
-// Visit implements the ast.Visitor interface.
-func (f *File) Visit(node ast.Node) ast.Visitor {
-	var key ast.Node
-	switch node.(type) {
-	case *ast.AssignStmt:
-		key = assignStmt
-	case *ast.BinaryExpr:
-		key = binaryExpr
-	case *ast.CallExpr:
-		key = callExpr
-	case *ast.CompositeLit:
-		key = compositeLit
-	case *ast.ExprStmt:
-		key = exprStmt
-	case *ast.Field:
-		key = field
-	case *ast.FuncDecl:
-		key = funcDecl
-	case *ast.FuncLit:
-		key = funcLit
-	case *ast.GenDecl:
-		key = genDecl
-	case *ast.InterfaceType:
-		key = interfaceType
-	case *ast.RangeStmt:
-		key = rangeStmt
-	}
-	for _, fn := range f.checkers[key] {
-		fn(f, node)
+// warnfAt reports a formatted error at pos but does not set the exit code.
+func warnfAt(pass *analysis.Pass, pos token.Pos, format string, args ...interface{}) {
+	loc := ""
+	if pos.IsValid() {
+		// Do not print columns. Because the pos often points to the start of an
+		// expression instead of the inner part with the actual error, the
+		// precision can mislead.
+		posn := pass.Fset.Position(pos)
+		loc = fmt.Sprintf("%s:%d: ", posn.Filename, posn.Line)
 	}
-	return f
+	fmt.Fprintf(os.Stderr, loc+format+"\n", args...)
 }
 
 // gofmt returns a string representation of the expression.
-func (f *File) gofmt(x ast.Expr) string {
-	f.b.Reset()
-	printer.Fprint(&f.b, f.fset, x)
-	return f.b.String()
+func gofmt(fset *token.FileSet, x ast.Expr) string {
+	var b bytes.Buffer
+	printer.Fprint(&b, fset, x)
+	return b.String()
 }
@@ -7,17 +7,33 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"go/analysis"
 	"go/ast"
 	"go/printer"
 	"strings"
 )
 
 func init() {
-	register("methods",
-		"check that canonically named methods are canonically defined",
-		checkCanonicalMethod,
-		funcDecl, interfaceType)
+	registerAnalyzer(methodsAnalyzer)
+}
+
+var methodsAnalyzer = &analysis.Analyzer{
+	Name:             "methods",
+	Doc:              "check that canonically named methods are canonically defined",
+	Run:              runMethods,
+	RunDespiteErrors: true,
+}
+
+func runMethods(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			checkCanonicalMethod(pass, n)
+			return true
+		})
+	}
+	return nil, nil
 }
 
 type MethodSig struct {
@@ -62,22 +78,22 @@ var canonicalMethods = map[string]MethodSig{
 	"WriteTo":       {[]string{"=io.Writer"}, []string{"int64", "error"}}, // io.WriterTo
 }
 
-func checkCanonicalMethod(f *File, node ast.Node) {
+func checkCanonicalMethod(pass *analysis.Pass, node ast.Node) {
 	switch n := node.(type) {
 	case *ast.FuncDecl:
 		if n.Recv != nil {
-			canonicalMethod(f, n.Name, n.Type)
+			canonicalMethod(pass, n.Name, n.Type)
 		}
 	case *ast.InterfaceType:
 		for _, field := range n.Methods.List {
 			for _, id := range field.Names {
-				canonicalMethod(f, id, field.Type.(*ast.FuncType))
+				canonicalMethod(pass, id, field.Type.(*ast.FuncType))
 			}
 		}
 	}
 }
 
-func canonicalMethod(f *File, id *ast.Ident, t *ast.FuncType) {
+func canonicalMethod(pass *analysis.Pass, id *ast.Ident, t *ast.FuncType) {
 	// Expected input/output.
 	expect, ok := canonicalMethods[id.Name]
 	if !ok {
@@ -92,12 +108,12 @@ func canonicalMethod(f *File, id *ast.Ident, t *ast.FuncType) {
 	}
 
 	// Do the =s (if any) all match?
-	if !f.matchParams(expect.args, args, "=") || !f.matchParams(expect.results, results, "=") {
+	if !matchParams(pass, expect.args, args, "=") || !matchParams(pass, expect.results, results, "=") {
 		return
 	}
 
 	// Everything must match.
-	if !f.matchParams(expect.args, args, "") || !f.matchParams(expect.results, results, "") {
+	if !matchParams(pass, expect.args, args, "") || !matchParams(pass, expect.results, results, "") {
 		expectFmt := id.Name + "(" + argjoin(expect.args) + ")"
 		if len(expect.results) == 1 {
 			expectFmt += " " + argjoin(expect.results)
@@ -105,15 +121,15 @@ func canonicalMethod(f *File, id *ast.Ident, t *ast.FuncType) {
 			expectFmt += " (" + argjoin(expect.results) + ")"
 		}
 
-		f.b.Reset()
-		if err := printer.Fprint(&f.b, f.fset, t); err != nil {
-			fmt.Fprintf(&f.b, "<%s>", err)
+		var b bytes.Buffer
+		if err := printer.Fprint(&b, pass.Fset, t); err != nil {
+			fmt.Fprintf(&b, "<%s>", err)
 		}
-		actual := f.b.String()
+		actual := b.String()
 		actual = strings.TrimPrefix(actual, "func")
 		actual = id.Name + actual
 
-		f.Badf(id.Pos(), "method %s should have signature %s", actual, expectFmt)
+		pass.Reportf(id.Pos(), "method %s should have signature %s", actual, expectFmt)
 	}
 }
 
@@ -147,7 +163,7 @@ func typeFlatten(l []*ast.Field) []ast.Expr {
 }
 
 // Does each type in expect with the given prefix match the corresponding type in actual?
-func (f *File) matchParams(expect []string, actual []ast.Expr, prefix string) bool {
+func matchParams(pass *analysis.Pass, expect []string, actual []ast.Expr, prefix string) bool {
 	for i, x := range expect {
 		if !strings.HasPrefix(x, prefix) {
 			continue
@@ -155,7 +171,7 @@ func (f *File) matchParams(expect []string, actual []ast.Expr, prefix string) bo
 		if i >= len(actual) {
 			return false
 		}
-		if !f.matchParamType(x, actual[i]) {
+		if !matchParamType(pass, x, actual[i]) {
 			return false
 		}
 	}
@@ -166,17 +182,15 @@ func (f *File) matchParams(expect []string, actual []ast.Expr, prefix string) bo
 }
 
 // Does this one type match?
-func (f *File) matchParamType(expect string, actual ast.Expr) bool {
+func matchParamType(pass *analysis.Pass, expect string, actual ast.Expr) bool {
 	if strings.HasPrefix(expect, "=") {
 		expect = expect[1:]
 	}
 	// Strip package name if we're in that package.
-	if n := len(f.file.Name.Name); len(expect) > n && expect[:n] == f.file.Name.Name && expect[n] == '.' {
+	if n := len(pass.Pkg.Name()); len(expect) > n && expect[:n] == pass.Pkg.Name() && expect[n] == '.' {
 		expect = expect[n+1:]
 	}
 
 	// Overkill but easy.
-	f.b.Reset()
-	printer.Fprint(&f.b, f.fset, actual)
-	return f.b.String() == expect
+	return gofmt(pass.Fset, actual) == expect
 }
@@ -10,21 +10,36 @@ A useless comparison is one like f == nil as opposed to f() == nil.
 package main
 
 import (
+	"go/analysis"
 	"go/ast"
 	"go/token"
 	"go/types"
 )
 
 func init() {
-	register("nilfunc",
-		"check for comparisons between functions and nil",
-		checkNilFuncComparison,
-		binaryExpr)
+	registerAnalyzer(nilFuncAnalyzer)
 }
 
-func checkNilFuncComparison(f *File, node ast.Node) {
-	e := node.(*ast.BinaryExpr)
+var nilFuncAnalyzer = &analysis.Analyzer{
+	Name:             "nilfunc",
+	Doc:              "check for comparisons between functions and nil",
+	Run:              runNilFunc,
+	RunDespiteErrors: true,
+}
+
+func runNilFunc(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n, ok := n.(*ast.BinaryExpr); ok {
+				checkNilFuncComparison(pass, n)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
 
+func checkNilFuncComparison(pass *analysis.Pass, e *ast.BinaryExpr) {
 	// Only want == or != comparisons.
 	if e.Op != token.EQL && e.Op != token.NEQ {
 		return
@@ -33,9 +48,9 @@ func checkNilFuncComparison(f *File, node ast.Node) {
 	// Only want comparisons with a nil identifier on one side.
 	var e2 ast.Expr
 	switch {
-	case f.isNil(e.X):
+	case isNil(pass, e.X):
 		e2 = e.Y
-	case f.isNil(e.Y):
+	case isNil(pass, e.Y):
 		e2 = e.X
 	default:
 		return
@@ -45,9 +60,9 @@ func checkNilFuncComparison(f *File, node ast.Node) {
 	var obj types.Object
 	switch v := e2.(type) {
 	case *ast.Ident:
-		obj = f.pkg.uses[v]
+		obj = pass.TypesInfo.Uses[v]
 	case *ast.SelectorExpr:
-		obj = f.pkg.uses[v.Sel]
+		obj = pass.TypesInfo.Uses[v.Sel]
 	default:
 		return
 	}
@@ -57,11 +72,11 @@ func checkNilFuncComparison(f *File, node ast.Node) {
 		return
 	}
 
-	f.Badf(e.Pos(), "comparison of function %v %v nil is always %v", obj.Name(), e.Op, e.Op == token.NEQ)
+	pass.Reportf(e.Pos(), "comparison of function %v %v nil is always %v", obj.Name(), e.Op, e.Op == token.NEQ)
 }
 
 // isNil reports whether the provided expression is the built-in nil
 // identifier.
-func (f *File) isNil(e ast.Expr) bool {
-	return f.pkg.types[e].Type == types.Typ[types.UntypedNil]
+func isNil(pass *analysis.Pass, e ast.Expr) bool {
+	return pass.TypesInfo.Types[e].Type == types.Typ[types.UntypedNil]
 }
@@ -9,6 +9,7 @@ package main
 import (
 	"bytes"
 	"flag"
+	"go/analysis"
 	"go/ast"
 	"go/constant"
 	"go/token"
@@ -21,10 +22,40 @@ import (
 var printfuncs = flag.String("printfuncs", "", "comma-separated list of print function names to check")
 
 func init() {
-	register("printf",
-		"check printf-like invocations",
-		checkFmtPrintfCall,
-		funcDecl, callExpr)
+	registerAnalyzer(printfAnalyzer)
+}
+
+var printfAnalyzer = &analysis.Analyzer{
+	Name:             "printf",
+	Doc:              "check printf-like invocations",
+	Run:              runPrintf,
+	RunDespiteErrors: true,
+}
+
+func runPrintf(pass *analysis.Pass) (interface{}, error) {
+	// Find the receivers of String methods first, so that calls
+	// within those methods can be checked for recursion.
+	stringers := make(map[types.Object]bool)
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if d, ok := decl.(*ast.FuncDecl); ok && isStringer(pass, d) {
+				if l := d.Recv.List; len(l) == 1 {
+					if n := l[0].Names; len(n) == 1 && pass.TypesInfo.Defs[n[0]] != nil {
+						stringers[pass.TypesInfo.Defs[n[0]]] = true
+					}
+				}
+			}
+		}
+	}
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				checkFmtPrintfCall(pass, stringers, call)
+			}
+			return true
+		})
+	}
+	return nil, nil
 }
 
 func initPrintFlags() {
@@ -79,25 +110,9 @@ var printList = map[string]int{
 	"sprint": 0, "sprintln": 0,
 }
 
-// checkCall triggers the print-specific checks if the call invokes a print function.
-func checkFmtPrintfCall(f *File, node ast.Node) {
-	if d, ok := node.(*ast.FuncDecl); ok && isStringer(f, d) {
-		// Remember we saw this.
-		if f.stringers == nil {
-			f.stringers = make(map[*ast.Object]bool)
-		}
-		if l := d.Recv.List; len(l) == 1 {
-			if n := l[0].Names; len(n) == 1 {
-				f.stringers[n[0].Obj] = true
-			}
-		}
-		return
-	}
-
-	call, ok := node.(*ast.CallExpr)
-	if !ok {
-		return
-	}
+// checkFmtPrintfCall triggers the print-specific checks if the call invokes a print function.
+// The stringers are the receivers of the String methods of the package.
+func checkFmtPrintfCall(pass *analysis.Pass, stringers map[types.Object]bool, call *ast.CallExpr) {
 	var Name string
 	switch x := call.Fun.(type) {
 	case *ast.Ident:
@@ -110,21 +125,21 @@ func checkFmtPrintfCall(f *File, node ast.Node) {
 
 	name := strings.ToLower(Name)
 	if skip, ok := printfList[name]; ok {
-		f.checkPrintf(call, Name, skip)
+		checkPrintf(pass, stringers, call, Name, skip)
 		return
 	}
 	if skip, ok := printList[name]; ok {
-		f.checkPrint(call, Name, skip)
+		checkPrint(pass, stringers, call, Name, skip)
 		return
 	}
 }
 
 // isStringer returns true if the provided declaration is a "String() string"
 // method, an implementation of fmt.Stringer.
-func isStringer(f *File, d *ast.FuncDecl) bool {
+func isStringer(pass *analysis.Pass, d *ast.FuncDecl) bool {
 	return d.Recv != nil && d.Name.Name == "String" && d.Type.Results != nil &&
 		len(d.Type.Params.List) == 0 && len(d.Type.Results.List) == 1 &&
-		f.pkg.types[d.Type.Results.List[0].Type].Type == types.Typ[types.String]
+		pass.TypesInfo.Types[d.Type.Results.List[0].Type].Type == types.Typ[types.String]
 }
 
 // formatState holds the parsed representation of a printf directive such as "%3.*[4]d".
@@ -138,7 +153,7 @@ type formatState struct {
 	indexed  bool   // whether an indexing expression appears: %[1]d.
 	firstArg int    // Index of first argument after the format in the Printf call.
 	// Used only during parse.
-	file         *File
+	pass         *analysis.Pass
 	call         *ast.CallExpr
 	argNum       int  // Which argument we're expecting to format now.
 	indexPending bool // Whether we have an indexed argument that has not resolved.
@@ -147,27 +162,27 @@ type formatState struct {
 
 // checkPrintf checks a call to a formatted print routine such as Printf.
 // call.Args[formatIndex] is (well, should be) the format argument.
-func (f *File) checkPrintf(call *ast.CallExpr, name string, formatIndex int) {
+func checkPrintf(pass *analysis.Pass, stringers map[types.Object]bool, call *ast.CallExpr, name string, formatIndex int) {
 	if formatIndex >= len(call.Args) {
-		f.Bad(call.Pos(), "too few arguments in call to", name)
+		pass.Reportf(call.Pos(), "too few arguments in call to %s", name)
 		return
 	}
-	lit := f.pkg.types[call.Args[formatIndex]].Value
+	lit := pass.TypesInfo.Types[call.Args[formatIndex]].Value
 	if lit == nil {
 		if *verbose {
-			f.Warn(call.Pos(), "can't check non-constant format in call to", name)
+			warnfAt(pass, call.Pos(), "can't check non-constant format in call to %s", name)
 		}
 		return
 	}
 	if lit.Kind() != constant.String {
-		f.Badf(call.Pos(), "constant %v not a string in call to %s", lit, name)
+		pass.Reportf(call.Pos(), "constant %v not a string in call to %s", lit, name)
 		return
 	}
 	format := constant.StringVal(lit)
 	firstArg := formatIndex + 1 // Arguments are immediately after format string.
 	if !strings.Contains(format, "%") {
 		if len(call.Args) > firstArg {
-			f.Badf(call.Pos(), "no formatting directive in %s call", name)
+			pass.Reportf(call.Pos(), "no formatting directive in %s call", name)
 		}
 		return
 	}
@@ -177,7 +192,7 @@ func (f *File) checkPrintf(call *ast.CallExpr, name string, formatIndex int) {
 	for i, w := 0, 0; i < len(format); i += w {
 		w = 1
 		if format[i] == '%' {
-			state := f.parsePrintfVerb(call, name, format[i:], firstArg, argNum)
+			state := parsePrintfVerb(pass, call, name, format[i:], firstArg, argNum)
 			if state == nil {
 				return
 			}
@@ -185,7 +200,7 @@ func (f *File) checkPrintf(call *ast.CallExpr, name string, formatIndex int) {
 			if state.indexed {
 				indexed = true
 			}
-			if !f.okPrintfArg(call, state) { // One error per format is enough.
+			if !okPrintfArg(pass, stringers, call, state) { // One error per format is enough.
 				return
 			}
 			if len(state.argNums) > 0 {
@@ -203,7 +218,7 @@ func (f *File) checkPrintf(call *ast.CallExpr, name string, formatIndex int) {
 	if !indexed && argNum != len(call.Args) {
 		expect := argNum - firstArg
 		numArgs := len(call.Args) - firstArg
-		f.Badf(call.Pos(), "wrong number of args for format in %s call: %d needed but %d args", name, expect, numArgs)
+		pass.Reportf(call.Pos(), "wrong number of args for format in %s call: %d needed but %d args", name, expect, numArgs)
 	}
 }
 
@@ -241,12 +256,12 @@ func (s *formatState) parseIndex() bool {
 	start := s.nbytes
 	s.scanNum()
 	if s.nbytes == len(s.format) || s.nbytes == start || s.format[s.nbytes] != ']' {
-		s.file.Badf(s.call.Pos(), "illegal syntax for printf argument index")
+		s.pass.Reportf(s.call.Pos(), "illegal syntax for printf argument index")
 		return false
 	}
 	arg32, err := strconv.ParseInt(s.format[start:s.nbytes], 10, 32)
 	if err != nil {
-		s.file.Badf(s.call.Pos(), "illegal syntax for printf argument index: %s", err)
+		s.pass.Reportf(s.call.Pos(), "illegal syntax for printf argument index: %s", err)
 		return false
 	}
 	s.nbytes++ // skip ']'
@@ -291,7 +306,7 @@ func (s *formatState) parsePrecision() bool {
 // parsePrintfVerb looks the formatting directive that begins the format string
 // and returns a formatState that encodes what the directive wants, without looking
 // at the actual arguments present in the call. The result is nil if there is an error.
-func (f *File) parsePrintfVerb(call *ast.CallExpr, name, format string, firstArg, argNum int) *formatState {
+func parsePrintfVerb(pass *analysis.Pass, call *ast.CallExpr, name, format string, firstArg, argNum int) *formatState {
 	state := &formatState{
 		format:   format,
 		name:     name,
@@ -301,7 +316,7 @@ func (f *File) parsePrintfVerb(call *ast.CallExpr, name, format string, firstArg
 		nbytes:   1, // There's guaranteed to be a percent sign.
 		indexed:  false,
 		firstArg: firstArg,
-		file:     f,
+		pass:     pass,
 		call:     call,
 	}
 	// There may be flags.
@@ -324,7 +339,7 @@ func (f *File) parsePrintfVerb(call *ast.CallExpr, name, format string, firstArg
 		return nil
 	}
 	if state.nbytes == len(state.format) {
-		f.Badf(call.Pos(), "missing verb at end of format string in %s call", name)
+		pass.Reportf(call.Pos(), "missing verb at end of format string in %s call", name)
 		return nil
 	}
 	verb, w := utf8.DecodeRuneInString(state.format[state.nbytes:])
@@ -399,7 +414,7 @@ var printVerbs = []printVerb{
 // okPrintfArg compares the formatState to the arguments actually present,
 // reporting any discrepancies it can discern. If the final argument is ellipsissed,
 // there's little it can do for that.
-func (f *File) okPrintfArg(call *ast.CallExpr, state *formatState) (ok bool) {
+func okPrintfArg(pass *analysis.Pass, stringers map[types.Object]bool, call *ast.CallExpr, state *formatState) (ok bool) {
 	var v printVerb
 	found := false
 	// Linear scan is fast enough for a small list.
@@ -410,12 +425,12 @@ func (f *File) okPrintfArg(call *ast.CallExpr, state *formatState) (ok bool) {
 		}
 	}
 	if !found {
-		f.Badf(call.Pos(), "unrecognized printf verb %q", state.verb)
+		pass.Reportf(call.Pos(), "unrecognized printf verb %q", state.verb)
 		return false
 	}
 	for _, flag := range state.flags {
 		if !strings.ContainsRune(v.flags, rune(flag)) {
-			f.Badf(call.Pos(), "unrecognized printf flag for verb %q: %q", state.verb, flag)
+			pass.Reportf(call.Pos(), "unrecognized printf flag for verb %q: %q", state.verb, flag)
 			return false
 		}
 	}
@@ -428,12 +443,12 @@ func (f *File) okPrintfArg(call *ast.CallExpr, state *formatState) (ok bool) {
 	nargs := len(state.argNums)
 	for i := 0; i < nargs-trueArgs; i++ {
 		argNum := state.argNums[i]
-		if !f.argCanBeChecked(call, i, true, state) {
+		if !argCanBeChecked(pass, call, i, true, state) {
 			return
 		}
 		arg := call.Args[argNum]
-		if !f.matchArgType(argInt, nil, arg) {
-			f.Badf(call.Pos(), "arg %s for * in printf format not of type int", f.gofmt(arg))
+		if !matchArgType(pass, argInt, nil, arg) {
+			pass.Reportf(call.Pos(), "arg %s for * in printf format not of type int", gofmt(pass.Fset, arg))
 			return false
 		}
 	}
@@ -441,49 +456,49 @@ func (f *File) okPrintfArg(call *ast.CallExpr, state *formatState) (ok bool) {
 		return true
 	}
 	argNum := state.argNums[len(state.argNums)-1]
-	if !f.argCanBeChecked(call, len(state.argNums)-1, false, state) {
+	if !argCanBeChecked(pass, call, len(state.argNums)-1, false, state) {
 		return false
 	}
 	arg := call.Args[argNum]
-	if f.isFunctionValue(arg) && state.verb != 'p' && state.verb != 'T' {
-		f.Badf(call.Pos(), "arg %s in printf call is a function value, not a function call", f.gofmt(arg))
+	if isFunctionValue(pass, arg) && state.verb != 'p' && state.verb != 'T' {
+		pass.Reportf(call.Pos(), "arg %s in printf call is a function value, not a function call", gofmt(pass.Fset, arg))
 		return false
 	}
-	if !f.matchArgType(v.typ, nil, arg) {
+	if !matchArgType(pass, v.typ, nil, arg) {
 		typeString := ""
-		if typ := f.pkg.types[arg].Type; typ != nil {
+		if typ := pass.TypesInfo.Types[arg].Type; typ != nil {
 			typeString = typ.String()
 		}
-		f.Badf(call.Pos(), "arg %s for printf verb %%%c of wrong type: %s", f.gofmt(arg), state.verb, typeString)
+		pass.Reportf(call.Pos(), "arg %s for printf verb %%%c of wrong type: %s", gofmt(pass.Fset, arg), state.verb, typeString)
 		return false
 	}
-	if v.typ&argString != 0 && v.verb != 'T' && !bytes.Contains(state.flags, []byte{'#'}) && f.recursiveStringer(arg) {
-		f.Badf(call.Pos(), "arg %s for printf causes recursive call to String method", f.gofmt(arg))
+	if v.typ&argString != 0 && v.verb != 'T' && !bytes.Contains(state.flags, []byte{'#'}) && recursiveStringer(pass, stringers, arg) {
+		pass.Reportf(call.Pos(), "arg %s for printf causes recursive call to String method", gofmt(pass.Fset, arg))
 		return false
 	}
 	return true
 }
 
-// recursiveStringer reports whether the provided argument is r or &r for the
-// fmt.Stringer receiver identifier r.
-func (f *File) recursiveStringer(e ast.Expr) bool {
-	if len(f.stringers) == 0 {
+// recursiveStringer reports whether the provided argument is r or &r for one
+// of the fmt.Stringer receiver identifiers r in stringers.
+func recursiveStringer(pass *analysis.Pass, stringers map[types.Object]bool, e ast.Expr) bool {
+	if len(stringers) == 0 {
 		return false
 	}
-	var obj *ast.Object
+	var obj types.Object
 	switch e := e.(type) {
 	case *ast.Ident:
-		obj = e.Obj
+		obj = pass.TypesInfo.Uses[e]
 	case *ast.UnaryExpr:
 		if id, ok := e.X.(*ast.Ident); ok && e.Op == token.AND {
-			obj = id.Obj
+			obj = pass.TypesInfo.Uses[id]
 		}
 	}
 
 	// It's unlikely to be a recursive stringer if it has a Format method.
-	if typ := f.pkg.types[e].Type; typ != nil {
+	if typ := pass.TypesInfo.Types[e].Type; typ != nil {
 		// Not a perfect match; see issue 6259.
-		if f.hasMethod(typ, "Format") {
+		if hasMethod(pass, typ, "Format") {
 			return false
 		}
 	}
@@ -491,13 +506,13 @@ func (f *File) recursiveStringer(e ast.Expr) bool {
 	// We compare the underlying Object, which checks that the identifier
 	// is the one we declared as the receiver for the String method in
 	// which this printf appears.
-	return f.stringers[obj]
+	return obj != nil && stringers[obj]
 }
 
 // isFunctionValue reports whether the expression is a function as opposed to a function call.
 // It is almost always a mistake to print a function value.
-func (f *File) isFunctionValue(e ast.Expr) bool {
-	if typ := f.pkg.types[e].Type; typ != nil {
+func isFunctionValue(pass *analysis.Pass, e ast.Expr) bool {
+	if typ := pass.TypesInfo.Types[e].Type; typ != nil {
 		_, ok := typ.(*types.Signature)
 		return ok
 	}
@@ -507,14 +522,14 @@ func (f *File) isFunctionValue(e ast.Expr) bool {
 // argCanBeChecked reports whether the specified argument is statically present;
 // it may be beyond the list of arguments or in a terminal slice... argument, which
 // means we can't see it.
-func (f *File) argCanBeChecked(call *ast.CallExpr, formatArg int, isStar bool, state *formatState) bool {
+func argCanBeChecked(pass *analysis.Pass, call *ast.CallExpr, formatArg int, isStar bool, state *formatState) bool {
 	argNum := state.argNums[formatArg]
 	if argNum < 0 {
 		// Shouldn't happen, so catch it with prejudice.
 		panic("negative arg num")
 	}
 	if argNum == 0 {
-		f.Badf(call.Pos(), `index value [0] for %s("%s"); indexes start at 1`, state.name, state.format)
+		pass.Reportf(call.Pos(), `index value [0] for %s("%s"); indexes start at 1`, state.name, state.format)
 		return false
 	}
 	if argNum < len(call.Args)-1 {
@@ -529,13 +544,13 @@ func (f *File) argCanBeChecked(call *ast.CallExpr, formatArg int, isStar bool, s
 	// There are bad indexes in the format or there are fewer arguments than the format needs.
 	// This is the argument number relative to the format: Printf("%s", "hi") will give 1 for the "hi".
 	arg := argNum - state.firstArg + 1 // People think of arguments as 1-indexed.
-	f.Badf(call.Pos(), `missing argument for %s("%s"): format reads arg %d, have only %d args`, state.name, state.format, arg, len(call.Args)-state.firstArg)
+	pass.Reportf(call.Pos(), `missing argument for %s("%s"): format reads arg %d, have only %d args`, state.name, state.format, arg, len(call.Args)-state.firstArg)
 	return false
 }
 
 // checkPrint checks a call to an unformatted print routine such as Println.
 // call.Args[firstArg] is the first argument to be printed.
-func (f *File) checkPrint(call *ast.CallExpr, name string, firstArg int) {
+func checkPrint(pass *analysis.Pass, stringers map[types.Object]bool, call *ast.CallExpr, name string, firstArg int) {
 	isLn := strings.HasSuffix(name, "ln")
 	isF := strings.HasPrefix(name, "F")
 	args := call.Args
@@ -556,7 +571,7 @@ func (f *File) checkPrint(call *ast.CallExpr, name string, firstArg int) {
 		if sel, ok := args[0].(*ast.SelectorExpr); ok {
 			if x, ok := sel.X.(*ast.Ident); ok {
 				if x.Name == "os" && strings.HasPrefix(sel.Sel.Name, "Std") {
-					f.Badf(call.Pos(), "first argument to %s is %s.%s", name, x.Name, sel.Sel.Name)
+					pass.Reportf(call.Pos(), "first argument to %s is %s.%s", name, x.Name, sel.Sel.Name)
 				}
 			}
 		}
@@ -565,14 +580,14 @@ func (f *File) checkPrint(call *ast.CallExpr, name string, firstArg int) {
 		// If we have a call to a method called Error that satisfies the Error interface,
 		// then it's ok. Otherwise it's something like (*T).Error from the testing package
 		// and we need to check it.
-		if name == "Error" && f.isErrorMethodCall(call) {
+		if name == "Error" && isErrorMethodCall(pass, call) {
 			return
 		}
 		// If it's an Error call now, it's probably for printing errors.
 		if !isLn {
 			// Check the signature to be sure: there are niladic functions called "error".
-			if firstArg != 0 || f.numArgsInSignature(call) != firstArg {
-				f.Badf(call.Pos(), "no args in %s call", name)
+			if firstArg != 0 || numArgsInSignature(pass, call) != firstArg {
+				pass.Reportf(call.Pos(), "no args in %s call", name)
 			}
 		}
 		return
@@ -580,7 +595,7 @@ func (f *File) checkPrint(call *ast.CallExpr, name string, firstArg int) {
 	arg := args[firstArg]
 	if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
 		if strings.Contains(lit.Value, "%") {
-			f.Badf(call.Pos(), "possible formatting directive in %s call", name)
+			pass.Reportf(call.Pos(), "possible formatting directive in %s call", name)
 		}
 	}
 	if isLn {
@@ -588,16 +603,16 @@ func (f *File) checkPrint(call *ast.CallExpr, name string, firstArg int) {
 		arg = args[len(call.Args)-1]
 		if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
 			if strings.HasSuffix(lit.Value, `\n"`) {
-				f.Badf(call.Pos(), "%s call ends with newline", name)
+				pass.Reportf(call.Pos(), "%s call ends with newline", name)
 			}
 		}
 	}
 	for _, arg := range args {
-		if f.isFunctionValue(arg) {
-			f.Badf(call.Pos(), "arg %s in %s call is a function value, not a function call", f.gofmt(arg), name)
+		if isFunctionValue(pass, arg) {
+			pass.Reportf(call.Pos(), "arg %s in %s call is a function value, not a function call", gofmt(pass.Fset, arg), name)
 		}
-		if f.recursiveStringer(arg) {
-			f.Badf(call.Pos(), "arg %s in %s call causes recursive call to String method", f.gofmt(arg), name)
+		if recursiveStringer(pass, stringers, arg) {
+			pass.Reportf(call.Pos(), "arg %s in %s call causes recursive call to String method", gofmt(pass.Fset, arg), name)
 		}
 	}
 }
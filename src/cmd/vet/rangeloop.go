@@ -21,20 +21,38 @@ See: https://golang.org/doc/go_faq.html#closures_and_goroutines
 
 package main
 
-import "go/ast"
+import (
+	"go/analysis"
+	"go/ast"
+)
 
 func init() {
-	register("rangeloops",
-		"check that range loop variables are used correctly",
-		checkRangeLoop,
-		rangeStmt)
+	registerAnalyzer(rangeLoopAnalyzer)
+}
+
+var rangeLoopAnalyzer = &analysis.Analyzer{
+	Name:             "rangeloops",
+	Doc:              "check that range loop variables are used correctly",
+	Run:              runRangeLoop,
+	RunDespiteErrors: true,
+}
+
+func runRangeLoop(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n, ok := n.(*ast.RangeStmt); ok {
+				checkRangeLoop(pass, n)
+			}
+			return true
+		})
+	}
+	return nil, nil
 }
 
 // checkRangeLoop walks the body of the provided range statement, checking if
 // its index or value variables are used unsafely inside goroutines or deferred
 // function literals.
-func checkRangeLoop(f *File, node ast.Node) {
-	n := node.(*ast.RangeStmt)
+func checkRangeLoop(pass *analysis.Pass, n *ast.RangeStmt) {
 	key, _ := n.Key.(*ast.Ident)
 	val, _ := n.Value.(*ast.Ident)
 	if key == nil && val == nil {
@@ -63,7 +81,7 @@ func checkRangeLoop(f *File, node ast.Node) {
 			return true
 		}
 		if key != nil && id.Obj == key.Obj || val != nil && id.Obj == val.Obj {
-			f.Bad(id.Pos(), "range variable", id.Name, "captured by func literal")
+			pass.Reportf(id.Pos(), "range variable %s captured by func literal", id.Name)
 		}
 		return true
 	})
@@ -32,6 +32,7 @@ package main
 
 import (
 	"flag"
+	"go/analysis"
 	"go/ast"
 	"go/token"
 	"go/types"
@@ -40,19 +41,44 @@ import (
 var strictShadowing = flag.Bool("shadowstrict", false, "whether to be strict about shadowing; can be noisy")
 
 func init() {
-	register("shadow",
-		"check for shadowed variables (experimental; must be set explicitly)",
-		checkShadow,
-		assignStmt, genDecl)
+	registerAnalyzer(shadowAnalyzer)
 	experimental["shadow"] = true
 }
 
-func checkShadow(f *File, node ast.Node) {
+var shadowAnalyzer = &analysis.Analyzer{
+	Name:             "shadow",
+	Doc:              "check for shadowed variables (experimental; must be set explicitly)",
+	Run:              runShadow,
+	RunDespiteErrors: true,
+}
+
+func runShadow(pass *analysis.Pass) (interface{}, error) {
+	spans := make(map[types.Object]Span)
+	if !*strictShadowing {
+		for id, obj := range pass.TypesInfo.Defs {
+			growSpan(spans, id, obj)
+		}
+		for id, obj := range pass.TypesInfo.Uses {
+			growSpan(spans, id, obj)
+		}
+	}
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			checkShadow(pass, spans, n)
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// checkShadow checks a node for shadowing, using the spans of the
+// objects of the package.
+func checkShadow(pass *analysis.Pass, spans map[types.Object]Span, node ast.Node) {
 	switch n := node.(type) {
 	case *ast.AssignStmt:
-		checkShadowAssignment(f, n)
+		checkShadowAssignment(pass, spans, n)
 	case *ast.GenDecl:
-		checkShadowDecl(f, n)
+		checkShadowDecl(pass, spans, n)
 	}
 }
 
@@ -88,13 +114,10 @@ func (s Span) contains(pos token.Pos) bool {
 
 // growSpan expands the span for the object to contain the instance represented
 // by the identifier.
-func (pkg *Package) growSpan(ident *ast.Ident, obj types.Object) {
-	if *strictShadowing {
-		return // No need
-	}
+func growSpan(spans map[types.Object]Span, ident *ast.Ident, obj types.Object) {
 	pos := ident.Pos()
 	end := ident.End()
-	span, ok := pkg.spans[obj]
+	span, ok := spans[obj]
 	if ok {
 		if span.min > pos {
 			span.min = pos
@@ -105,30 +128,30 @@ func (pkg *Package) growSpan(ident *ast.Ident, obj types.Object) {
 	} else {
 		span = Span{pos, end}
 	}
-	pkg.spans[obj] = span
+	spans[obj] = span
 }
 
 // checkShadowAssignment checks for shadowing in a short variable declaration.
-func checkShadowAssignment(f *File, a *ast.AssignStmt) {
+func checkShadowAssignment(pass *analysis.Pass, spans map[types.Object]Span, a *ast.AssignStmt) {
 	if a.Tok != token.DEFINE {
 		return
 	}
-	if f.idiomaticShortRedecl(a) {
+	if idiomaticShortRedecl(pass, a) {
 		return
 	}
 	for _, expr := range a.Lhs {
 		ident, ok := expr.(*ast.Ident)
 		if !ok {
-			f.Badf(expr.Pos(), "invalid AST: short variable declaration of non-identifier")
+			pass.Reportf(expr.Pos(), "invalid AST: short variable declaration of non-identifier")
 			return
 		}
-		checkShadowing(f, ident)
+		checkShadowing(pass, spans, ident)
 	}
 }
 
 // idiomaticShortRedecl reports whether this short declaration can be ignored for
 // the purposes of shadowing, that is, that any redeclarations it contains are deliberate.
-func (f *File) idiomaticShortRedecl(a *ast.AssignStmt) bool {
+func idiomaticShortRedecl(pass *analysis.Pass, a *ast.AssignStmt) bool {
 	// Don't complain about deliberate redeclarations of the form
 	//	i := i
 	// Such constructs are idiomatic in range loops to create a new variable
@@ -141,7 +164,7 @@ func (f *File) idiomaticShortRedecl(a *ast.AssignStmt) bool {
 	for i, expr := range a.Lhs {
 		lhs, ok := expr.(*ast.Ident)
 		if !ok {
-			f.Badf(expr.Pos(), "invalid AST: short variable declaration of non-identifier")
+			pass.Reportf(expr.Pos(), "invalid AST: short variable declaration of non-identifier")
 			return true // Don't do any more processing.
 		}
 		switch rhs := a.Rhs[i].(type) {
@@ -164,7 +187,7 @@ func (f *File) idiomaticShortRedecl(a *ast.AssignStmt) bool {
 
 // idiomaticRedecl reports whether this declaration spec can be ignored for
 // the purposes of shadowing, that is, that any redeclarations it contains are deliberate.
-func (f *File) idiomaticRedecl(d *ast.ValueSpec) bool {
+func idiomaticRedecl(pass *analysis.Pass, d *ast.ValueSpec) bool {
 	// Don't complain about deliberate redeclarations of the form
 	//	var i, j = i, j
 	if len(d.Names) != len(d.Values) {
@@ -181,34 +204,34 @@ func (f *File) idiomaticRedecl(d *ast.ValueSpec) bool {
 }
 
 // checkShadowDecl checks for shadowing in a general variable declaration.
-func checkShadowDecl(f *File, d *ast.GenDecl) {
+func checkShadowDecl(pass *analysis.Pass, spans map[types.Object]Span, d *ast.GenDecl) {
 	if d.Tok != token.VAR {
 		return
 	}
 	for _, spec := range d.Specs {
 		valueSpec, ok := spec.(*ast.ValueSpec)
 		if !ok {
-			f.Badf(spec.Pos(), "invalid AST: var GenDecl not ValueSpec")
+			pass.Reportf(spec.Pos(), "invalid AST: var GenDecl not ValueSpec")
 			return
 		}
 		// Don't complain about deliberate redeclarations of the form
 		//	var i = i
-		if f.idiomaticRedecl(valueSpec) {
+		if idiomaticRedecl(pass, valueSpec) {
 			return
 		}
 		for _, ident := range valueSpec.Names {
-			checkShadowing(f, ident)
+			checkShadowing(pass, spans, ident)
 		}
 	}
 }
 
 // checkShadowing checks whether the identifier shadows an identifier in an outer scope.
-func checkShadowing(f *File, ident *ast.Ident) {
+func checkShadowing(pass *analysis.Pass, spans map[types.Object]Span, ident *ast.Ident) {
 	if ident.Name == "_" {
 		// Can't shadow the blank identifier.
 		return
 	}
-	obj := f.pkg.defs[ident]
+	obj := pass.TypesInfo.Defs[ident]
 	if obj == nil {
 		return
 	}
@@ -230,9 +253,9 @@ func checkShadowing(f *File, ident *ast.Ident) {
 	} else {
 		// Don't complain if the span of validity of the shadowed identifier doesn't include
 		// the shadowing identifier.
-		span, ok := f.pkg.spans[shadowed]
+		span, ok := spans[shadowed]
 		if !ok {
-			f.Badf(ident.Pos(), "internal error: no range for %s", ident.Name)
+			pass.Reportf(ident.Pos(), "internal error: no range for %s", ident.Name)
 			return
 		}
 		if !span.contains(ident.Pos()) {
@@ -241,6 +264,7 @@ func checkShadowing(f *File, ident *ast.Ident) {
 	}
 	// Don't complain if the types differ: that implies the programmer really wants two different things.
 	if types.Identical(obj.Type(), shadowed.Type()) {
-		f.Badf(ident.Pos(), "declaration of %s shadows declaration at %s", obj.Name(), f.loc(shadowed.Pos()))
+		posn := pass.Fset.Position(shadowed.Pos())
+		pass.Reportf(ident.Pos(), "declaration of %s shadows declaration at %s:%d", obj.Name(), posn.Filename, posn.Line)
 	}
 }
@@ -9,6 +9,7 @@ This file contains the code to check for suspicious shifts.
 package main
 
 import (
+	"go/analysis"
 	"go/ast"
 	"go/constant"
 	"go/token"
@@ -16,32 +17,46 @@ import (
 )
 
 func init() {
-	register("shift",
-		"check for useless shifts",
-		checkShift,
-		binaryExpr, assignStmt)
+	registerAnalyzer(shiftAnalyzer)
 }
 
-func checkShift(f *File, node ast.Node) {
+var shiftAnalyzer = &analysis.Analyzer{
+	Name:             "shift",
+	Doc:              "check for useless shifts",
+	Run:              runShift,
+	RunDespiteErrors: true,
+}
+
+func runShift(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			checkShift(pass, n)
+			return true
+		})
+	}
+	return nil, nil
+}
+
+func checkShift(pass *analysis.Pass, node ast.Node) {
 	switch node := node.(type) {
 	case *ast.BinaryExpr:
 		if node.Op == token.SHL || node.Op == token.SHR {
-			checkLongShift(f, node, node.X, node.Y)
+			checkLongShift(pass, node, node.X, node.Y)
 		}
 	case *ast.AssignStmt:
 		if len(node.Lhs) != 1 || len(node.Rhs) != 1 {
 			return
 		}
 		if node.Tok == token.SHL_ASSIGN || node.Tok == token.SHR_ASSIGN {
-			checkLongShift(f, node, node.Lhs[0], node.Rhs[0])
+			checkLongShift(pass, node, node.Lhs[0], node.Rhs[0])
 		}
 	}
 }
 
 // checkLongShift checks if shift or shift-assign operations shift by more than
 // the length of the underlying variable.
-func checkLongShift(f *File, node ast.Node, x, y ast.Expr) {
-	v := f.pkg.types[y].Value
+func checkLongShift(pass *analysis.Pass, node ast.Node, x, y ast.Expr) {
+	v := pass.TypesInfo.Types[y].Value
 	if v == nil {
 		return
 	}
@@ -49,7 +64,7 @@ func checkLongShift(f *File, node ast.Node, x, y ast.Expr) {
 	if !ok {
 		return
 	}
-	t := f.pkg.types[x].Type
+	t := pass.TypesInfo.Types[x].Type
 	if t == nil {
 		return
 	}
@@ -76,7 +91,7 @@ func checkLongShift(f *File, node ast.Node, x, y ast.Expr) {
 		return
 	}
 	if amt >= size {
-		ident := f.gofmt(x)
-		f.Badf(node.Pos(), "%s %stoo small for shift of %d", ident, msg, amt)
+		ident := gofmt(pass.Fset, x)
+		pass.Reportf(node.Pos(), "%s %stoo small for shift of %d", ident, msg, amt)
 	}
 }
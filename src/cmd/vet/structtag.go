@@ -8,33 +8,49 @@ package main
 
 import (
 	"errors"
+	"go/analysis"
 	"go/ast"
 	"reflect"
 	"strconv"
 )
 
 func init() {
-	register("structtags",
-		"check that struct field tags have canonical format and apply to exported fields as needed",
-		checkCanonicalFieldTag,
-		field)
+	registerAnalyzer(structTagAnalyzer)
+}
+
+var structTagAnalyzer = &analysis.Analyzer{
+	Name:             "structtags",
+	Doc:              "check that struct field tags have canonical format and apply to exported fields as needed",
+	Run:              runStructTag,
+	RunDespiteErrors: true,
+}
+
+func runStructTag(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n, ok := n.(*ast.Field); ok {
+				checkCanonicalFieldTag(pass, n)
+			}
+			return true
+		})
+	}
+	return nil, nil
 }
 
 // checkCanonicalFieldTag checks a struct field tag.
-func checkCanonicalFieldTag(f *File, node ast.Node) {
-	field := node.(*ast.Field)
+func checkCanonicalFieldTag(pass *analysis.Pass, field *ast.Field) {
 	if field.Tag == nil {
 		return
 	}
 
 	tag, err := strconv.Unquote(field.Tag.Value)
 	if err != nil {
-		f.Badf(field.Pos(), "unable to read struct tag %s", field.Tag.Value)
+		pass.Reportf(field.Pos(), "unable to read struct tag %s", field.Tag.Value)
 		return
 	}
 
 	if err := validateStructTag(tag); err != nil {
-		f.Badf(field.Pos(), "struct field tag %s not compatible with reflect.StructTag.Get: %s", field.Tag.Value, err)
+		pass.Reportf(field.Pos(), "struct field tag %s not compatible with reflect.StructTag.Get: %s", field.Tag.Value, err)
 	}
 
 	// Check for use of json or xml tags with unexported fields.
@@ -52,7 +68,7 @@ func checkCanonicalFieldTag(f *File, node ast.Node) {
 	st := reflect.StructTag(tag)
 	for _, enc := range [...]string{"json", "xml"} {
 		if st.Get(enc) != "" {
-			f.Badf(field.Pos(), "struct field %s has %s tag but is not exported", field.Names[0].Name, enc)
+			pass.Reportf(field.Pos(), "struct field %s has %s tag but is not exported", field.Names[0].Name, enc)
 			return
 		}
 	}
@@ -0,0 +1,20 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains tests for the zossyscall checker.
+
+package testdata
+
+import "syscall"
+
+func ZosSyscalls() {
+	_ = syscall.Getpid()
+	syscall.RawSyscall(syscall.SYS_GETPID, 0, 0, 0)
+	_, _ = syscall.EpollCreate(1)   // ERROR "syscall.EpollCreate is not available on zos"
+	syscall.RawSyscall(39, 0, 0, 0) // ERROR "syscall.RawSyscall with literal system call number 39; system call numbers differ on zos"
+	const getpid = 20
+	syscall.Syscall(getpid, 0, 0, 0) // ERROR "literal system call number 20"
+	var trap uintptr
+	syscall.Syscall(trap, 0, 0, 0)
+}
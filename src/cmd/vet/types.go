@@ -7,9 +7,9 @@
 package main
 
 import (
+	"go/analysis"
 	"go/ast"
 	"go/importer"
-	"go/token"
 	"go/types"
 )
 
@@ -53,43 +53,11 @@ func importType(path, name string) types.Type {
 	return nil
 }
 
-func (pkg *Package) check(fs *token.FileSet, astFiles []*ast.File) error {
-	pkg.defs = make(map[*ast.Ident]types.Object)
-	pkg.uses = make(map[*ast.Ident]types.Object)
-	pkg.selectors = make(map[*ast.SelectorExpr]*types.Selection)
-	pkg.spans = make(map[types.Object]Span)
-	pkg.types = make(map[ast.Expr]types.TypeAndValue)
-	config := types.Config{
-		// We use the same importer for all imports to ensure that
-		// everybody sees identical packages for the given paths.
-		Importer: stdImporter,
-		// By providing a Config with our own error function, it will continue
-		// past the first error. There is no need for that function to do anything.
-		Error: func(error) {},
-	}
-	info := &types.Info{
-		Selections: pkg.selectors,
-		Types:      pkg.types,
-		Defs:       pkg.defs,
-		Uses:       pkg.uses,
-	}
-	typesPkg, err := config.Check(pkg.path, fs, astFiles, info)
-	pkg.typesPkg = typesPkg
-	// update spans
-	for id, obj := range pkg.defs {
-		pkg.growSpan(id, obj)
-	}
-	for id, obj := range pkg.uses {
-		pkg.growSpan(id, obj)
-	}
-	return err
-}
-
 // isStruct reports whether the composite literal c is a struct.
 // If it is not (probably a struct), it returns a printable form of the type.
-func (pkg *Package) isStruct(c *ast.CompositeLit) (bool, string) {
+func isStruct(pass *analysis.Pass, c *ast.CompositeLit) (bool, string) {
 	// Check that the CompositeLit's type is a slice or array (which needs no field keys), if possible.
-	typ := pkg.types[c].Type
+	typ := pass.TypesInfo.Types[c].Type
 	// If it's a named type, pull out the underlying type. If it's not, the Underlying
 	// method returns the type itself.
 	actual := typ
@@ -116,28 +84,28 @@ func (pkg *Package) isStruct(c *ast.CompositeLit) (bool, string) {
 // (Recursion arises from the compound types {map,chan,slice} which
 // may be printed with %d etc. if that is appropriate for their element
 // types.)
-func (f *File) matchArgType(t printfArgType, typ types.Type, arg ast.Expr) bool {
-	return f.matchArgTypeInternal(t, typ, arg, make(map[types.Type]bool))
+func matchArgType(pass *analysis.Pass, t printfArgType, typ types.Type, arg ast.Expr) bool {
+	return matchArgTypeInternal(pass, t, typ, arg, make(map[types.Type]bool))
 }
 
 // matchArgTypeInternal is the internal version of matchArgType. It carries a map
 // remembering what types are in progress so we don't recur when faced with recursive
 // types or mutually recursive types.
-func (f *File) matchArgTypeInternal(t printfArgType, typ types.Type, arg ast.Expr, inProgress map[types.Type]bool) bool {
+func matchArgTypeInternal(pass *analysis.Pass, t printfArgType, typ types.Type, arg ast.Expr, inProgress map[types.Type]bool) bool {
 	// %v, %T accept any argument type.
 	if t == anyType {
 		return true
 	}
 	if typ == nil {
 		// external call
-		typ = f.pkg.types[arg].Type
+		typ = pass.TypesInfo.Types[arg].Type
 		if typ == nil {
 			return true // probably a type check problem
 		}
 	}
 	// If the type implements fmt.Formatter, we have nothing to check.
 	// formatterTyp may be nil - be conservative and check for Format method in that case.
-	if formatterType != nil && types.Implements(typ, formatterType) || f.hasMethod(typ, "Format") {
+	if formatterType != nil && types.Implements(typ, formatterType) || hasMethod(pass, typ, "Format") {
 		return true
 	}
 	// If we can use a string, might arg (dynamically) implement the Stringer or Error interface?
@@ -161,7 +129,7 @@ func (f *File) matchArgTypeInternal(t printfArgType, typ types.Type, arg ast.Exp
 	case *types.Map:
 		// Recur: map[int]int matches %d.
 		return t&argPointer != 0 ||
-			(f.matchArgTypeInternal(t, typ.Key(), arg, inProgress) && f.matchArgTypeInternal(t, typ.Elem(), arg, inProgress))
+			(matchArgTypeInternal(pass, t, typ.Key(), arg, inProgress) && matchArgTypeInternal(pass, t, typ.Elem(), arg, inProgress))
 
 	case *types.Chan:
 		return t&argPointer != 0
@@ -172,7 +140,7 @@ func (f *File) matchArgTypeInternal(t printfArgType, typ types.Type, arg ast.Exp
 			return true // %s matches []byte
 		}
 		// Recur: []int matches %d.
-		return t&argPointer != 0 || f.matchArgTypeInternal(t, typ.Elem().Underlying(), arg, inProgress)
+		return t&argPointer != 0 || matchArgTypeInternal(pass, t, typ.Elem().Underlying(), arg, inProgress)
 
 	case *types.Slice:
 		// Same as array.
@@ -182,14 +150,14 @@ func (f *File) matchArgTypeInternal(t printfArgType, typ types.Type, arg ast.Exp
 		// Recur: []int matches %d. But watch out for
 		//	type T []T
 		// If the element is a pointer type (type T[]*T), it's handled fine by the Pointer case below.
-		return t&argPointer != 0 || f.matchArgTypeInternal(t, typ.Elem(), arg, inProgress)
+		return t&argPointer != 0 || matchArgTypeInternal(pass, t, typ.Elem(), arg, inProgress)
 
 	case *types.Pointer:
 		// Ugly, but dealing with an edge case: a known pointer to an invalid type,
 		// probably something from a failed import.
 		if typ.Elem().String() == "invalid type" {
 			if *verbose {
-				f.Warnf(arg.Pos(), "printf argument %v is pointer to invalid or unknown type", f.gofmt(arg))
+				warnfAt(pass, arg.Pos(), "printf argument %v is pointer to invalid or unknown type", gofmt(pass.Fset, arg))
 			}
 			return true // special case
 		}
@@ -199,13 +167,13 @@ func (f *File) matchArgTypeInternal(t printfArgType, typ types.Type, arg ast.Exp
 		}
 		// If it's pointer to struct, that's equivalent in our analysis to whether we can print the struct.
 		if str, ok := typ.Elem().Underlying().(*types.Struct); ok {
-			return f.matchStructArgType(t, str, arg, inProgress)
+			return matchStructArgType(pass, t, str, arg, inProgress)
 		}
 		// The rest can print with %p as pointers, or as integers with %x etc.
 		return t&(argInt|argPointer) != 0
 
 	case *types.Struct:
-		return f.matchStructArgType(t, typ, arg, inProgress)
+		return matchStructArgType(pass, t, typ, arg, inProgress)
 
 	case *types.Interface:
 		// If the static type of the argument is empty interface, there's little we can do.
@@ -261,7 +229,7 @@ func (f *File) matchArgTypeInternal(t printfArgType, typ types.Type, arg ast.Exp
 
 		case types.Invalid:
 			if *verbose {
-				f.Warnf(arg.Pos(), "printf argument %v has invalid or unknown type", f.gofmt(arg))
+				warnfAt(pass, arg.Pos(), "printf argument %v has invalid or unknown type", gofmt(pass.Fset, arg))
 			}
 			return true // Probably a type check problem.
 		}
@@ -272,8 +240,8 @@ func (f *File) matchArgTypeInternal(t printfArgType, typ types.Type, arg ast.Exp
 }
 
 // hasBasicType reports whether x's type is a types.Basic with the given kind.
-func (f *File) hasBasicType(x ast.Expr, kind types.BasicKind) bool {
-	t := f.pkg.types[x].Type
+func hasBasicType(pass *analysis.Pass, x ast.Expr, kind types.BasicKind) bool {
+	t := pass.TypesInfo.Types[x].Type
 	if t != nil {
 		t = t.Underlying()
 	}
@@ -283,9 +251,9 @@ func (f *File) hasBasicType(x ast.Expr, kind types.BasicKind) bool {
 
 // matchStructArgType reports whether all the elements of the struct match the expected
 // type. For instance, with "%d" all the elements must be printable with the "%d" format.
-func (f *File) matchStructArgType(t printfArgType, typ *types.Struct, arg ast.Expr, inProgress map[types.Type]bool) bool {
+func matchStructArgType(pass *analysis.Pass, t printfArgType, typ *types.Struct, arg ast.Expr, inProgress map[types.Type]bool) bool {
 	for i := 0; i < typ.NumFields(); i++ {
-		if !f.matchArgTypeInternal(t, typ.Field(i).Type(), arg, inProgress) {
+		if !matchArgTypeInternal(pass, t, typ.Field(i).Type(), arg, inProgress) {
 			return false
 		}
 	}
@@ -294,9 +262,9 @@ func (f *File) matchStructArgType(t printfArgType, typ *types.Struct, arg ast.Ex
 
 // numArgsInSignature tells how many formal arguments the function type
 // being called has.
-func (f *File) numArgsInSignature(call *ast.CallExpr) int {
+func numArgsInSignature(pass *analysis.Pass, call *ast.CallExpr) int {
 	// Check the type of the function or method declaration
-	typ := f.pkg.types[call.Fun].Type
+	typ := pass.TypesInfo.Types[call.Fun].Type
 	if typ == nil {
 		return 0
 	}
@@ -311,13 +279,13 @@ func (f *File) numArgsInSignature(call *ast.CallExpr) int {
 // isErrorMethodCall reports whether the call is of a method with signature
 //	func Error() string
 // where "string" is the universe's string type. We know the method is called "Error".
-func (f *File) isErrorMethodCall(call *ast.CallExpr) bool {
-	typ := f.pkg.types[call].Type
+func isErrorMethodCall(pass *analysis.Pass, call *ast.CallExpr) bool {
+	typ := pass.TypesInfo.Types[call].Type
 	if typ != nil {
 		// We know it's called "Error", so just check the function signature
 		// (stringerType has exactly one method, String).
 		if stringerType != nil && stringerType.NumMethods() == 1 {
-			return types.Identical(f.pkg.types[call.Fun].Type, stringerType.Method(0).Type())
+			return types.Identical(pass.TypesInfo.Types[call.Fun].Type, stringerType.Method(0).Type())
 		}
 	}
 	// Without types, we can still check by hand.
@@ -331,7 +299,7 @@ func (f *File) isErrorMethodCall(call *ast.CallExpr) bool {
 		return false
 	}
 	// Check the type of the method declaration
-	typ = f.pkg.types[sel].Type
+	typ = pass.TypesInfo.Types[sel].Type
 	if typ == nil {
 		return false
 	}
@@ -362,9 +330,9 @@ func (f *File) isErrorMethodCall(call *ast.CallExpr) bool {
 // It is part of the workaround for Formatters and should be deleted when
 // that workaround is no longer necessary.
 // TODO: This could be better once issue 6259 is fixed.
-func (f *File) hasMethod(typ types.Type, name string) bool {
+func hasMethod(pass *analysis.Pass, typ types.Type, name string) bool {
 	// assume we have an addressable variable of type typ
-	obj, _, _ := types.LookupFieldOrMethod(typ, true, f.pkg.typesPkg, name)
+	obj, _, _ := types.LookupFieldOrMethod(typ, true, pass.Pkg, name)
 	_, ok := obj.(*types.Func)
 	return ok
 }
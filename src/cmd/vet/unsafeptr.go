@@ -7,25 +7,41 @@
 package main
 
 import (
+	"go/analysis"
 	"go/ast"
 	"go/token"
 	"go/types"
 )
 
 func init() {
-	register("unsafeptr",
-		"check for misuse of unsafe.Pointer",
-		checkUnsafePointer,
-		callExpr)
+	registerAnalyzer(unsafePointerAnalyzer)
 }
 
-func checkUnsafePointer(f *File, node ast.Node) {
-	x := node.(*ast.CallExpr)
+var unsafePointerAnalyzer = &analysis.Analyzer{
+	Name:             "unsafeptr",
+	Doc:              "check for misuse of unsafe.Pointer",
+	Run:              runUnsafePointer,
+	RunDespiteErrors: true,
+}
+
+func runUnsafePointer(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n, ok := n.(*ast.CallExpr); ok {
+				checkUnsafePointer(pass, n)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+func checkUnsafePointer(pass *analysis.Pass, x *ast.CallExpr) {
 	if len(x.Args) != 1 {
 		return
 	}
-	if f.hasBasicType(x.Fun, types.UnsafePointer) && f.hasBasicType(x.Args[0], types.Uintptr) && !f.isSafeUintptr(x.Args[0]) {
-		f.Badf(x.Pos(), "possible misuse of unsafe.Pointer")
+	if hasBasicType(pass, x.Fun, types.UnsafePointer) && hasBasicType(pass, x.Args[0], types.Uintptr) && !isSafeUintptr(pass, x.Args[0]) {
+		pass.Reportf(x.Pos(), "possible misuse of unsafe.Pointer")
 	}
 }
 
@@ -34,10 +50,10 @@ func checkUnsafePointer(f *File, node ast.Node) {
 // directly from an unsafe.Pointer via conversion and pointer arithmetic
 // or if x is the result of reflect.Value.Pointer or reflect.Value.UnsafeAddr
 // or obtained from the Data field of a *reflect.SliceHeader or *reflect.StringHeader.
-func (f *File) isSafeUintptr(x ast.Expr) bool {
+func isSafeUintptr(pass *analysis.Pass, x ast.Expr) bool {
 	switch x := x.(type) {
 	case *ast.ParenExpr:
-		return f.isSafeUintptr(x.X)
+		return isSafeUintptr(pass, x.X)
 
 	case *ast.SelectorExpr:
 		switch x.Sel.Name {
@@ -54,7 +70,7 @@ func (f *File) isSafeUintptr(x ast.Expr) bool {
 			// by the time we get to the conversion at the end.
 			// For now approximate by saying that *Header is okay
 			// but Header is not.
-			pt, ok := f.pkg.types[x.X].Type.(*types.Pointer)
+			pt, ok := pass.TypesInfo.Types[x.X].Type.(*types.Pointer)
 			if ok {
 				t, ok := pt.Elem().(*types.Named)
 				if ok && t.Obj().Pkg().Path() == "reflect" {
@@ -76,7 +92,7 @@ func (f *File) isSafeUintptr(x ast.Expr) bool {
 			}
 			switch sel.Sel.Name {
 			case "Pointer", "UnsafeAddr":
-				t, ok := f.pkg.types[sel.X].Type.(*types.Named)
+				t, ok := pass.TypesInfo.Types[sel.X].Type.(*types.Named)
 				if ok && t.Obj().Pkg().Path() == "reflect" && t.Obj().Name() == "Value" {
 					return true
 				}
@@ -84,13 +100,13 @@ func (f *File) isSafeUintptr(x ast.Expr) bool {
 
 		case 1:
 			// maybe conversion of uintptr to unsafe.Pointer
-			return f.hasBasicType(x.Fun, types.Uintptr) && f.hasBasicType(x.Args[0], types.UnsafePointer)
+			return hasBasicType(pass, x.Fun, types.Uintptr) && hasBasicType(pass, x.Args[0], types.UnsafePointer)
 		}
 
 	case *ast.BinaryExpr:
 		switch x.Op {
 		case token.ADD, token.SUB:
-			return f.isSafeUintptr(x.X) && !f.isSafeUintptr(x.Y)
+			return isSafeUintptr(pass, x.X) && !isSafeUintptr(pass, x.Y)
 		}
 	}
 	return false
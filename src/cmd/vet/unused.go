@@ -9,6 +9,7 @@ package main
 
 import (
 	"flag"
+	"go/analysis"
 	"go/ast"
 	"go/token"
 	"go/types"
@@ -24,10 +25,26 @@ var unusedStringMethodsFlag = flag.String("unusedstringmethods",
 	"comma-separated list of names of methods of type func() string whose results must be used")
 
 func init() {
-	register("unusedresult",
-		"check for unused result of calls to functions in -unusedfuncs list and methods in -unusedstringmethods list",
-		checkUnusedResult,
-		exprStmt)
+	registerAnalyzer(unusedResultAnalyzer)
+}
+
+var unusedResultAnalyzer = &analysis.Analyzer{
+	Name:             "unusedresult",
+	Doc:              "check for unused result of calls to functions in -unusedfuncs list and methods in -unusedstringmethods list",
+	Run:              runUnusedResult,
+	RunDespiteErrors: true,
+}
+
+func runUnusedResult(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n, ok := n.(*ast.ExprStmt); ok {
+				checkUnusedResult(pass, n)
+			}
+			return true
+		})
+	}
+	return nil, nil
 }
 
 // func() string
@@ -53,14 +70,14 @@ func initUnusedFlags() {
 	commaSplit(*unusedStringMethodsFlag, unusedStringMethods)
 }
 
-func checkUnusedResult(f *File, n ast.Node) {
-	call, ok := unparen(n.(*ast.ExprStmt).X).(*ast.CallExpr)
+func checkUnusedResult(pass *analysis.Pass, stmt *ast.ExprStmt) {
+	call, ok := unparen(stmt.X).(*ast.CallExpr)
 	if !ok {
 		return // not a call statement
 	}
 	fun := unparen(call.Fun)
 
-	if f.pkg.types[fun].IsType() {
+	if pass.TypesInfo.Types[fun].IsType() {
 		return // a conversion, not a call
 	}
 
@@ -69,24 +86,24 @@ func checkUnusedResult(f *File, n ast.Node) {
 		return // neither a method call nor a qualified ident
 	}
 
-	sel, ok := f.pkg.selectors[selector]
+	sel, ok := pass.TypesInfo.Selections[selector]
 	if ok && sel.Kind() == types.MethodVal {
 		// method (e.g. foo.String())
 		obj := sel.Obj().(*types.Func)
 		sig := sel.Type().(*types.Signature)
 		if types.Identical(sig, sigNoArgsStringResult) {
 			if unusedStringMethods[obj.Name()] {
-				f.Badf(call.Lparen, "result of (%s).%s call not used",
+				pass.Reportf(call.Lparen, "result of (%s).%s call not used",
 					sig.Recv().Type(), obj.Name())
 			}
 		}
 	} else if !ok {
 		// package-qualified function (e.g. fmt.Errorf)
-		obj, _ := f.pkg.uses[selector.Sel]
+		obj, _ := pass.TypesInfo.Uses[selector.Sel]
 		if obj, ok := obj.(*types.Func); ok {
 			qname := obj.Pkg().Path() + "." + obj.Name()
 			if unusedFuncs[qname] {
-				f.Badf(call.Lparen, "result of %v call not used", qname)
+				pass.Reportf(call.Lparen, "result of %v call not used", qname)
 			}
 		}
 	}
@@ -0,0 +1,222 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains the check for uses of package syscall that are
+// not available on zos. It is written directly against the go/analysis
+// API and uses facts to follow such uses across packages.
+
+package main
+
+import (
+	"go/analysis"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+)
+
+func init() {
+	registerAnalyzer(zosSyscallAnalyzer)
+	experimental["zossyscall"] = true
+}
+
+var zosSyscallAnalyzer = &analysis.Analyzer{
+	Name:             "zossyscall",
+	Doc:              "check for uses of package syscall that are not available on zos (experimental; must be set explicitly)",
+	Run:              checkZosSyscall,
+	RunDespiteErrors: true,
+	FactTypes:        []analysis.Fact{new(zosSyscallFact)},
+}
+
+// A zosSyscallFact records that a function uses, directly or through
+// other functions, a part of package syscall that is not available
+// on zos.
+type zosSyscallFact struct {
+	Use string // the offending use, such as "syscall.EpollWait"
+}
+
+func (*zosSyscallFact) AFact() {}
+
+// zosContext is the build context of the zos port.
+var zosContext = func() *build.Context {
+	ctxt := build.Default
+	ctxt.GOOS = "zos"
+	ctxt.GOARCH = "s390x"
+	ctxt.CgoEnabled = false
+	return &ctxt
+}()
+
+// zosSyscallNames holds the names declared by package syscall on zos.
+// It is computed on first use by loadZosSyscallNames.
+var zosSyscallNames map[string]bool
+
+// loadZosSyscallNames returns the set of package-level names declared
+// by the source of package syscall when built for zos.
+func loadZosSyscallNames() (map[string]bool, error) {
+	if zosSyscallNames != nil {
+		return zosSyscallNames, nil
+	}
+	bp, err := zosContext.Import("syscall", "", 0)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(bp.Dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					names[decl.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						names[spec.Name.Name] = true
+					case *ast.ValueSpec:
+						for _, id := range spec.Names {
+							names[id.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	zosSyscallNames = names
+	return names, nil
+}
+
+// isRawSyscall reports whether name is one of the syscall functions
+// that take a system call number.
+func isRawSyscall(name string) bool {
+	switch name {
+	case "Syscall", "Syscall6", "RawSyscall", "RawSyscall6":
+		return true
+	}
+	return false
+}
+
+// checkZosSyscall reports, in the files of the package that are built
+// on zos, uses of names that package syscall does not declare on zos,
+// system calls made with a literal call number, and calls of functions
+// from other packages that do either. It exports a zosSyscallFact for
+// each function of the package that does so itself.
+func checkZosSyscall(pass *analysis.Pass) (interface{}, error) {
+	names, err := loadZosSyscallNames()
+	if err != nil {
+		return nil, err
+	}
+
+	// uses records the first offending use in each function of the
+	// package, and calls the functions of the package each one calls.
+	uses := make(map[*types.Func]string)
+	calls := make(map[*types.Func][]*types.Func)
+	for _, f := range pass.Files {
+		filename := pass.Fset.Position(f.Pos()).Filename
+		if ok, err := zosContext.MatchFile(filepath.Dir(filename), filepath.Base(filename)); err == nil && !ok {
+			continue
+		}
+		for _, decl := range f.Decls {
+			var fn *types.Func
+			if decl, ok := decl.(*ast.FuncDecl); ok {
+				fn, _ = pass.TypesInfo.Defs[decl.Name].(*types.Func)
+			}
+			use := func(use string) {
+				if fn != nil && uses[fn] == "" {
+					uses[fn] = use
+				}
+			}
+			ast.Inspect(decl, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.SelectorExpr:
+					if isSyscallPkg(pass, n.X) && !names[n.Sel.Name] {
+						pass.Reportf(n.Pos(), "syscall.%s is not available on zos", n.Sel.Name)
+						use("syscall." + n.Sel.Name)
+					}
+				case *ast.CallExpr:
+					if sel, ok := unparen(n.Fun).(*ast.SelectorExpr); ok && isSyscallPkg(pass, sel.X) && isRawSyscall(sel.Sel.Name) && len(n.Args) > 0 {
+						trap := unparen(n.Args[0])
+						if tv := pass.TypesInfo.Types[trap]; tv.Value != nil {
+							if tsel, ok := trap.(*ast.SelectorExpr); !ok || !isSyscallPkg(pass, tsel.X) {
+								pass.Reportf(n.Pos(), "syscall.%s with literal system call number %s; system call numbers differ on zos", sel.Sel.Name, tv.Value)
+								use("syscall." + sel.Sel.Name)
+							}
+						}
+						return true
+					}
+					callee := calledFunc(pass.TypesInfo, n.Fun)
+					if callee == nil || callee.Pkg() == nil {
+						return true
+					}
+					if callee.Pkg() == pass.Pkg {
+						if fn != nil {
+							calls[fn] = append(calls[fn], callee)
+						}
+						return true
+					}
+					var fact zosSyscallFact
+					if pass.ImportObjectFact(callee, &fact) {
+						pass.Reportf(n.Pos(), "call of %s.%s, which uses %s that is not available on zos", callee.Pkg().Name(), callee.Name(), fact.Use)
+						use(fact.Use)
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	// Propagate uses to the callers within the package.
+	for changed := true; changed; {
+		changed = false
+		for caller, callees := range calls {
+			if uses[caller] != "" {
+				continue
+			}
+			for _, callee := range callees {
+				if use := uses[callee]; use != "" {
+					uses[caller] = use
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	for fn, use := range uses {
+		pass.ExportObjectFact(fn, &zosSyscallFact{use})
+	}
+	return nil, nil
+}
+
+// isSyscallPkg reports whether x refers to the imported package syscall.
+func isSyscallPkg(pass *analysis.Pass, x ast.Expr) bool {
+	id, ok := x.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	pkg, ok := pass.TypesInfo.Uses[id].(*types.PkgName)
+	return ok && pkg.Imported().Path() == "syscall"
+}
+
+// calledFunc returns the function or method called by a call of fun,
+// or nil if it is not statically known.
+func calledFunc(info *types.Info, fun ast.Expr) *types.Func {
+	var id *ast.Ident
+	switch fun := unparen(fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil
+	}
+	fn, _ := info.Uses[id].(*types.Func)
+	return fn
+}
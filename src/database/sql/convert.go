@@ -13,36 +13,86 @@ import (
 	"reflect"
 	"strconv"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 var errNilPtr = errors.New("destination pointer is nil") // embedded in descriptive error
 
-// driverArgs converts arguments from callers of Stmt.Exec and
-// Stmt.Query into driver Values.
+func describeNamedValue(nv *driver.NamedValue) string {
+	if len(nv.Name) == 0 {
+		return fmt.Sprintf("$%d", nv.Ordinal)
+	}
+	return fmt.Sprintf("with name %q", nv.Name)
+}
+
+func validateNamedValueName(name string) error {
+	if len(name) == 0 {
+		return nil
+	}
+	r, _ := utf8.DecodeRuneInString(name)
+	if unicode.IsLetter(r) {
+		return nil
+	}
+	return fmt.Errorf("name %q does not begin with a letter", name)
+}
+
+// driverArgsConnLocked converts arguments from callers of Stmt.Exec and
+// Stmt.Query into driver Values, numbered by their ordinal position and
+// named if given as a NamedArg.
 //
 // The statement ds may be nil, if no statement is available.
-func driverArgs(ds *driverStmt, args []interface{}) ([]driver.Value, error) {
-	dargs := make([]driver.Value, len(args))
+//
+// The conn ci and the statement ds must be locked by the caller.
+func driverArgsConnLocked(ci driver.Conn, ds *driverStmt, args []interface{}) ([]driver.NamedValue, error) {
+	nvargs := make([]driver.NamedValue, len(args))
 	var si driver.Stmt
 	if ds != nil {
 		si = ds.si
 	}
-	cc, ok := si.(driver.ColumnConverter)
+	cc, isCC := si.(driver.ColumnConverter)
+
+	// Drivers may check and convert the values themselves, which is
+	// how they accept types such as Out.
+	nvc, isNVC := si.(driver.NamedValueChecker)
+	if !isNVC {
+		nvc, isNVC = ci.(driver.NamedValueChecker)
+	}
+
+	for n, arg := range args {
+		nv := &nvargs[n]
+		nv.Ordinal = n + 1
+		if np, ok := arg.(NamedArg); ok {
+			if err := validateNamedValueName(np.Name); err != nil {
+				return nil, err
+			}
+			arg = np.Value
+			nv.Name = np.Name
+		}
+		nv.Value = arg
 
-	// Normal path, for a driver.Stmt that is not a ColumnConverter.
-	if !ok {
-		for n, arg := range args {
+		if isNVC {
+			err := nvc.CheckNamedValue(nv)
+			if err == nil {
+				continue
+			}
+			if err != driver.ErrSkip {
+				return nil, fmt.Errorf("sql: converting argument %s type: %v", describeNamedValue(nv), err)
+			}
+		}
+
+		// Normal path, for a driver.Stmt that is not a ColumnConverter.
+		if !isCC {
 			var err error
-			dargs[n], err = driver.DefaultParameterConverter.ConvertValue(arg)
+			nv.Value, err = driver.DefaultParameterConverter.ConvertValue(arg)
 			if err != nil {
 				return nil, fmt.Errorf("sql: converting Exec argument #%d's type: %v", n, err)
 			}
+			continue
 		}
-		return dargs, nil
-	}
 
-	// Let the Stmt convert its own arguments.
-	for n, arg := range args {
+		// Let the Stmt convert its own arguments.
+		//
 		// First, see if the value itself knows how to convert
 		// itself to a driver type.  For example, a NullString
 		// struct changing into a string or nil.
@@ -65,19 +115,17 @@ func driverArgs(ds *driverStmt, args []interface{}) ([]driver.Value, error) {
 		// column before going across the network to get the
 		// same error.
 		var err error
-		ds.Lock()
-		dargs[n], err = cc.ColumnConverter(n).ConvertValue(arg)
-		ds.Unlock()
+		nv.Value, err = cc.ColumnConverter(n).ConvertValue(arg)
 		if err != nil {
 			return nil, fmt.Errorf("sql: converting argument #%d's type: %v", n, err)
 		}
-		if !driver.IsValue(dargs[n]) {
+		if !driver.IsValue(nv.Value) {
 			return nil, fmt.Errorf("sql: driver ColumnConverter error converted %T to unsupported type %T",
-				arg, dargs[n])
+				arg, nv.Value)
 		}
 	}
 
-	return dargs, nil
+	return nvargs, nil
 }
 
 // convertAssign copies to dest the value in src, converting it if possible.
@@ -5,11 +5,13 @@
 package sql
 
 import (
+	"context"
 	"database/sql/driver"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -31,11 +33,25 @@ var _ = log.Printf
 //   CREATE|<tablename>|<col>=<type>,<col>=<type>,...
 //     where types are: "string", [u]int{8,16,32,64}, "bool"
 //   INSERT|<tablename>|col=val,col2=val2,col3=?
-//   SELECT|<tablename>|projectcol1,projectcol2|filtercol=?,filtercol2=?
+//   SELECT|<tablename>|projectcol1,projectcol2|filtercol=?,filtercol2=?name
+//   COUNT|<tablename>|?name
+//
+// A placeholder is either ? for the next positional argument, or
+// ?<name> for the argument with that name. COUNT stores the number of
+// rows in the table into its argument, which must be an Out value with
+// a destination of type *int64; if the Out is also an input, the count
+// is added to the destination's value.
+//
+// Several SELECTs may be separated by semicolons, to produce a result
+// set for each.
 //
 // Any of these can be preceded by PANIC|<method>|, to cause the
 // named method on fakeStmt to panic.
 //
+// Any of these can also be preceded by WAIT|<duration>|, to cause the
+// statement's ExecContext and QueryContext methods to wait for the
+// given duration, or until their context is done, before executing.
+//
 // When opening a fakeDriver's database, it starts empty with no
 // tables.  All tables and data are stored in memory only.
 type fakeDriver struct {
@@ -105,7 +121,8 @@ func (c *fakeConn) incrStat(v *int) {
 }
 
 type fakeTx struct {
-	c *fakeConn
+	c    *fakeConn
+	opts driver.TxOptions
 }
 
 type fakeStmt struct {
@@ -115,19 +132,30 @@ type fakeStmt struct {
 	cmd   string
 	table string
 	panic string
+	wait  time.Duration
 
 	closed bool
+	next   *fakeStmt // next statement in the query, if any
 
 	colName      []string      // used by CREATE, INSERT, SELECT (selected columns)
 	colType      []string      // used by CREATE
 	colValue     []interface{} // used by INSERT (mix of strings and "?" for bound params)
-	placeholders int           // used by INSERT/SELECT: number of ? params
+	placeholders int           // used by INSERT/SELECT: number of positional ? params
+	named        bool          // used by SELECT/COUNT: whether there are named ?name params
 
-	whereCol []string // used by SELECT (all placeholders)
+	whereCol []boundCol // used by SELECT (all placeholders)
+	outParam boundCol   // used by COUNT
 
 	placeholderConverter []driver.ValueConverter // used by INSERT
 }
 
+// boundCol is a column bound to a placeholder.
+type boundCol struct {
+	Column      string
+	Placeholder string // "?" or "?name"
+	Ordinal     int    // position of a "?" argument, starting from one
+}
+
 var fdriver driver.Driver = &fakeDriver{}
 
 func init() {
@@ -282,13 +310,25 @@ func (c *fakeConn) isBad() bool {
 }
 
 func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
 	if c.isBad() {
 		return nil, driver.ErrBadConn
 	}
 	if c.currTx != nil {
 		return nil, errors.New("already in a transaction")
 	}
-	c.currTx = &fakeTx{c: c}
+	switch IsolationLevel(opts.Isolation) {
+	case LevelDefault, LevelReadCommitted, LevelSerializable:
+	default:
+		return nil, fmt.Errorf("fakedb: unsupported isolation level %d", opts.Isolation)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.currTx = &fakeTx{c: c, opts: opts}
 	return c.currTx, nil
 }
 
@@ -342,46 +382,63 @@ func (c *fakeConn) Close() (err error) {
 	return nil
 }
 
-func checkSubsetTypes(args []driver.Value) error {
-	for n, arg := range args {
-		switch arg.(type) {
+func checkSubsetTypes(allowOut bool, args []driver.NamedValue) error {
+	for _, arg := range args {
+		switch arg.Value.(type) {
 		case int64, float64, bool, nil, []byte, string, time.Time:
+		case Out:
+			if !allowOut {
+				return fmt.Errorf("fakedb_test: output parameter %s not allowed", describeNamedValue(&arg))
+			}
 		default:
-			return fmt.Errorf("fakedb_test: invalid argument #%d: %v, type %T", n+1, arg, arg)
+			return fmt.Errorf("fakedb_test: invalid argument #%d: %v, type %T", arg.Ordinal, arg.Value, arg.Value)
 		}
 	}
 	return nil
 }
 
-func (c *fakeConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
 	// This is an optional interface, but it's implemented here
 	// just to check that all the args are of the proper types.
 	// ErrSkip is returned so the caller acts as if we didn't
 	// implement this at all.
-	err := checkSubsetTypes(args)
+	err := checkSubsetTypes(true, args)
 	if err != nil {
 		return nil, err
 	}
 	return nil, driver.ErrSkip
 }
 
-func (c *fakeConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
 	// This is an optional interface, but it's implemented here
 	// just to check that all the args are of the proper types.
 	// ErrSkip is returned so the caller acts as if we didn't
 	// implement this at all.
-	err := checkSubsetTypes(args)
+	err := checkSubsetTypes(false, args)
 	if err != nil {
 		return nil, err
 	}
 	return nil, driver.ErrSkip
 }
 
+// CheckNamedValue accepts the Out values used by COUNT, leaving all
+// other values to the statement's ColumnConverter.
+func (c *fakeConn) CheckNamedValue(nv *driver.NamedValue) error {
+	out, ok := nv.Value.(Out)
+	if !ok {
+		return driver.ErrSkip
+	}
+	if _, ok := out.Dest.(*int64); !ok {
+		return fmt.Errorf("fakedb: output destination must be *int64, not %T", out.Dest)
+	}
+	return nil
+}
+
 func errf(msg string, args ...interface{}) error {
 	return errors.New("fakedb: " + fmt.Sprintf(msg, args...))
 }
 
-// parts are table|selectCol1,selectCol2|whereCol=?,whereCol2=?
+// parts are table|selectCol1,selectCol2|whereCol=?,whereCol2=?name
 // (note that where columns must always contain ? marks,
 //  just a limitation for fakedb)
 func (c *fakeConn) prepareSelect(stmt *fakeStmt, parts []string) (driver.Stmt, error) {
@@ -406,17 +463,51 @@ func (c *fakeConn) prepareSelect(stmt *fakeStmt, parts []string) (driver.Stmt, e
 			stmt.Close()
 			return nil, errf("SELECT on table %q references non-existent column %q", stmt.table, column)
 		}
-		if value != "?" {
+		if !strings.HasPrefix(value, "?") {
 			stmt.Close()
 			return nil, errf("SELECT on table %q has pre-bound value for where column %q; need a question mark",
 				stmt.table, column)
 		}
-		stmt.whereCol = append(stmt.whereCol, column)
-		stmt.placeholders++
+		stmt.whereCol = append(stmt.whereCol, stmt.bind(column, value))
 	}
 	return stmt, nil
 }
 
+// parts are table|?name
+func (c *fakeConn) prepareCount(stmt *fakeStmt, parts []string) (driver.Stmt, error) {
+	if len(parts) != 2 || !strings.HasPrefix(parts[1], "?") {
+		stmt.Close()
+		return nil, errf("invalid COUNT syntax; want COUNT|<table>|?<param>")
+	}
+	stmt.table = parts[0]
+	stmt.outParam = stmt.bind("", parts[1])
+	return stmt, nil
+}
+
+// bind returns column bound to placeholder, counting the placeholder
+// as one of stmt's parameters.
+func (stmt *fakeStmt) bind(column, placeholder string) boundCol {
+	if placeholder != "?" {
+		stmt.named = true
+		return boundCol{Column: column, Placeholder: placeholder}
+	}
+	stmt.placeholders++
+	return boundCol{Column: column, Placeholder: placeholder, Ordinal: stmt.placeholders}
+}
+
+// arg returns the argument bound to col.
+func (s *fakeStmt) arg(args []driver.NamedValue, col boundCol) (interface{}, error) {
+	if col.Placeholder == "?" {
+		return args[col.Ordinal-1].Value, nil
+	}
+	for _, arg := range args {
+		if arg.Name == col.Placeholder[1:] {
+			return arg.Value, nil
+		}
+	}
+	return nil, errf("no argument for placeholder %q", col.Placeholder)
+}
+
 // parts are table|col=type,col2=type2
 func (c *fakeConn) prepareCreate(stmt *fakeStmt, parts []string) (driver.Stmt, error) {
 	if len(parts) != 2 {
@@ -499,6 +590,35 @@ func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
 		return nil, driver.ErrBadConn
 	}
 
+	// Each statement of a query numbers its positional parameters
+	// after those of the statements before it.
+	var first, prev *fakeStmt
+	positional := 0
+	for _, q := range strings.Split(query, ";") {
+		stmt, err := c.prepareStmt(q)
+		if err != nil {
+			if first != nil {
+				first.Close()
+			}
+			return nil, err
+		}
+		for i := range stmt.whereCol {
+			if stmt.whereCol[i].Placeholder == "?" {
+				stmt.whereCol[i].Ordinal += positional
+			}
+		}
+		positional += stmt.placeholders
+		if first == nil {
+			first = stmt
+		} else {
+			prev.next = stmt
+		}
+		prev = stmt
+	}
+	return first, nil
+}
+
+func (c *fakeConn) prepareStmt(query string) (*fakeStmt, error) {
 	parts := strings.Split(query, "|")
 	if len(parts) < 1 {
 		return nil, errf("empty query")
@@ -508,29 +628,52 @@ func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
 		stmt.panic = parts[1]
 		parts = parts[2:]
 	}
+	if len(parts) >= 3 && parts[0] == "WAIT" {
+		wait, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return nil, errf("expected WAIT|<duration>|, got %q", parts[1])
+		}
+		stmt.wait = wait
+		parts = parts[2:]
+	}
 	cmd := parts[0]
 	stmt.cmd = cmd
 	parts = parts[1:]
 
 	c.incrStat(&c.stmtsMade)
+	var si driver.Stmt
+	var err error
 	switch cmd {
 	case "WIPE":
 		// Nothing
+		return stmt, nil
 	case "SELECT":
-		return c.prepareSelect(stmt, parts)
+		si, err = c.prepareSelect(stmt, parts)
 	case "CREATE":
-		return c.prepareCreate(stmt, parts)
+		si, err = c.prepareCreate(stmt, parts)
 	case "INSERT":
-		return c.prepareInsert(stmt, parts)
+		si, err = c.prepareInsert(stmt, parts)
 	case "NOSERT":
 		// Do all the prep-work like for an INSERT but don't actually insert the row.
 		// Used for some of the concurrent tests.
-		return c.prepareInsert(stmt, parts)
+		si, err = c.prepareInsert(stmt, parts)
+	case "COUNT":
+		si, err = c.prepareCount(stmt, parts)
 	default:
 		stmt.Close()
 		return nil, errf("unsupported command type %q", cmd)
 	}
-	return stmt, nil
+	if err != nil {
+		return nil, err
+	}
+	return si.(*fakeStmt), nil
+}
+
+func (c *fakeConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Prepare(query)
 }
 
 func (s *fakeStmt) ColumnConverter(idx int) driver.ValueConverter {
@@ -557,6 +700,9 @@ func (s *fakeStmt) Close() error {
 		s.c.incrStat(&s.c.stmtsClosed)
 		s.closed = true
 	}
+	if s.next != nil {
+		s.next.Close()
+	}
 	return nil
 }
 
@@ -565,7 +711,20 @@ var errClosed = errors.New("fakedb: statement has been closed")
 // hook to simulate broken connections
 var hookExecBadConn func() bool
 
+// namedValues returns args numbered by their position.
+func namedValues(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		nv[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nv
+}
+
 func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *fakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
 	if s.panic == "Exec" {
 		panic(s.panic)
 	}
@@ -576,11 +735,17 @@ func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
 	if s.c.stickyBad || (hookExecBadConn != nil && hookExecBadConn()) {
 		return nil, driver.ErrBadConn
 	}
+	if s.next != nil {
+		return nil, errf("Exec of a query with several statements")
+	}
 
-	err := checkSubsetTypes(args)
+	err := checkSubsetTypes(s.cmd == "COUNT", args)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.waitContext(ctx); err != nil {
+		return nil, err
+	}
 
 	db := s.c.db
 	switch s.cmd {
@@ -598,15 +763,33 @@ func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
 		// Do all the prep-work like for an INSERT but don't actually insert the row.
 		// Used for some of the concurrent tests.
 		return s.execInsert(args, false)
+	case "COUNT":
+		return s.execCount(args)
 	}
 	fmt.Printf("EXEC statement, cmd=%q: %#v\n", s.cmd, s)
 	return nil, fmt.Errorf("unimplemented statement Exec command type of %q", s.cmd)
 }
 
+// waitContext waits for the statement's WAIT duration, returning
+// early with the context's error if ctx is done first.
+func (s *fakeStmt) waitContext(ctx context.Context) error {
+	if s.wait <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(s.wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
 // When doInsert is true, add the row to the table.
 // When doInsert is false do prep-work and error checking, but don't
 // actually add the row to the table.
-func (s *fakeStmt) execInsert(args []driver.Value, doInsert bool) (driver.Result, error) {
+func (s *fakeStmt) execInsert(args []driver.NamedValue, doInsert bool) (driver.Result, error) {
 	db := s.c.db
 	if len(args) != s.placeholders {
 		panic("error in pkg db; should only get here if size is correct")
@@ -633,7 +816,7 @@ func (s *fakeStmt) execInsert(args []driver.Value, doInsert bool) (driver.Result
 		}
 		var val interface{}
 		if strvalue, ok := s.colValue[n].(string); ok && strvalue == "?" {
-			val = args[argPos]
+			val = args[argPos].Value
 			argPos++
 		} else {
 			val = s.colValue[n]
@@ -649,10 +832,43 @@ func (s *fakeStmt) execInsert(args []driver.Value, doInsert bool) (driver.Result
 	return driver.RowsAffected(1), nil
 }
 
+func (s *fakeStmt) execCount(args []driver.NamedValue) (driver.Result, error) {
+	arg, err := s.arg(args, s.outParam)
+	if err != nil {
+		return nil, err
+	}
+	out, ok := arg.(Out)
+	if !ok {
+		return nil, errf("COUNT parameter %q is not an output parameter", s.outParam.Placeholder)
+	}
+	db := s.c.db
+	db.mu.Lock()
+	t, ok := db.table(s.table)
+	db.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("fakedb: table %q doesn't exist", s.table)
+	}
+
+	t.mu.Lock()
+	n := int64(len(t.rows))
+	t.mu.Unlock()
+
+	dest := out.Dest.(*int64) // checked by CheckNamedValue
+	if out.In {
+		n += *dest
+	}
+	*dest = n
+	return driver.RowsAffected(0), nil
+}
+
 // hook to simulate broken connections
 var hookQueryBadConn func() bool
 
 func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *fakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
 	if s.panic == "Query" {
 		panic(s.panic)
 	}
@@ -664,27 +880,53 @@ func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
 		return nil, driver.ErrBadConn
 	}
 
-	err := checkSubsetTypes(args)
+	err := checkSubsetTypes(false, args)
 	if err != nil {
 		return nil, err
 	}
 
-	db := s.c.db
-	if len(args) != s.placeholders {
+	if want := s.NumInput(); want != -1 && len(args) != want {
 		panic("error in pkg db; should only get here if size is correct")
 	}
+	if err := s.waitContext(ctx); err != nil {
+		return nil, err
+	}
 
+	cursor := &rowsCursor{
+		posRow: -1,
+		errPos: -1,
+	}
+	for ; s != nil; s = s.next {
+		mrows, colType, err := s.querySet(args)
+		if err != nil {
+			return nil, err
+		}
+		cursor.rows = append(cursor.rows, mrows)
+		cursor.cols = append(cursor.cols, s.colName)
+		cursor.colType = append(cursor.colType, colType)
+	}
+	return cursor, nil
+}
+
+// querySet returns the rows selected by the SELECT statement s and the
+// types of its columns.
+func (s *fakeStmt) querySet(args []driver.NamedValue) ([]*row, []string, error) {
+	if s.cmd != "SELECT" {
+		return nil, nil, errf("Query of a %s statement", s.cmd)
+	}
+
+	db := s.c.db
 	db.mu.Lock()
 	t, ok := db.table(s.table)
 	db.mu.Unlock()
 	if !ok {
-		return nil, fmt.Errorf("fakedb: table %q doesn't exist", s.table)
+		return nil, nil, fmt.Errorf("fakedb: table %q doesn't exist", s.table)
 	}
 
 	if s.table == "magicquery" {
-		if len(s.whereCol) == 2 && s.whereCol[0] == "op" && s.whereCol[1] == "millis" {
-			if args[0] == "sleep" {
-				time.Sleep(time.Duration(args[1].(int64)) * time.Millisecond)
+		if len(s.whereCol) == 2 && s.whereCol[0].Column == "op" && s.whereCol[1].Column == "millis" {
+			if args[0].Value == "sleep" {
+				time.Sleep(time.Duration(args[1].Value.(int64)) * time.Millisecond)
 			}
 		}
 	}
@@ -696,7 +938,7 @@ func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
 	for _, name := range s.colName {
 		idx := t.columnIndex(name)
 		if idx == -1 {
-			return nil, fmt.Errorf("fakedb: unknown column name %q", name)
+			return nil, nil, fmt.Errorf("fakedb: unknown column name %q", name)
 		}
 		colIdx[name] = idx
 	}
@@ -707,17 +949,21 @@ rows:
 		// Process the where clause, skipping non-match rows. This is lazy
 		// and just uses fmt.Sprintf("%v") to test equality.  Good enough
 		// for test code.
-		for widx, wcol := range s.whereCol {
-			idx := t.columnIndex(wcol)
+		for _, wcol := range s.whereCol {
+			idx := t.columnIndex(wcol.Column)
 			if idx == -1 {
-				return nil, fmt.Errorf("db: invalid where clause column %q", wcol)
+				return nil, nil, fmt.Errorf("db: invalid where clause column %q", wcol.Column)
 			}
 			tcol := trow.cols[idx]
 			if bs, ok := tcol.([]byte); ok {
 				// lazy hack to avoid sprintf %v on a []byte
 				tcol = string(bs)
 			}
-			if fmt.Sprintf("%v", tcol) != fmt.Sprintf("%v", args[widx]) {
+			argValue, err := s.arg(args, wcol)
+			if err != nil {
+				return nil, nil, err
+			}
+			if fmt.Sprintf("%v", tcol) != fmt.Sprintf("%v", argValue) {
 				continue rows
 			}
 		}
@@ -728,20 +974,27 @@ rows:
 		mrows = append(mrows, mrow)
 	}
 
-	cursor := &rowsCursor{
-		pos:    -1,
-		rows:   mrows,
-		cols:   s.colName,
-		errPos: -1,
+	colType := make([]string, len(s.colName))
+	for i, name := range s.colName {
+		colType[i] = t.coltype[colIdx[name]]
 	}
-	return cursor, nil
+	return mrows, colType, nil
 }
 
+// NumInput returns the number of positional parameters of all the
+// statements in the query, or -1 if there are named parameters.
 func (s *fakeStmt) NumInput() int {
 	if s.panic == "NumInput" {
 		panic(s.panic)
 	}
-	return s.placeholders
+	n := 0
+	for ; s != nil; s = s.next {
+		if s.named {
+			return -1
+		}
+		n += s.placeholders
+	}
+	return n
 }
 
 // hook to simulate broken connections
@@ -767,10 +1020,12 @@ func (tx *fakeTx) Rollback() error {
 }
 
 type rowsCursor struct {
-	cols   []string
-	pos    int
-	rows   []*row
-	closed bool
+	cols    [][]string
+	colType [][]string
+	posSet  int
+	posRow  int
+	rows    [][]*row
+	closed  bool
 
 	// errPos and err are for making Next return early with error.
 	errPos int
@@ -793,7 +1048,19 @@ func (rc *rowsCursor) Close() error {
 }
 
 func (rc *rowsCursor) Columns() []string {
-	return rc.cols
+	return rc.cols[rc.posSet]
+}
+
+func (rc *rowsCursor) ColumnTypeScanType(index int) reflect.Type {
+	return colTypeToReflectType(rc.colType[rc.posSet][index])
+}
+
+func (rc *rowsCursor) ColumnTypeDatabaseTypeName(index int) string {
+	return strings.ToUpper(rc.colType[rc.posSet][index])
+}
+
+func (rc *rowsCursor) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return strings.HasPrefix(rc.colType[rc.posSet][index], "null"), true
 }
 
 var rowsCursorNextHook func(dest []driver.Value) error
@@ -806,14 +1073,14 @@ func (rc *rowsCursor) Next(dest []driver.Value) error {
 	if rc.closed {
 		return errors.New("fakedb: cursor is closed")
 	}
-	rc.pos++
-	if rc.pos == rc.errPos {
+	rc.posRow++
+	if rc.posRow == rc.errPos {
 		return rc.err
 	}
-	if rc.pos >= len(rc.rows) {
+	if rc.posRow >= len(rc.rows[rc.posSet]) {
 		return io.EOF // per interface spec
 	}
-	for i, v := range rc.rows[rc.pos].cols {
+	for i, v := range rc.rows[rc.posSet][rc.posRow].cols {
 		// TODO(bradfitz): convert to subset types? naah, I
 		// think the subset types should only be input to
 		// driver, but the sql package should be able to handle
@@ -838,6 +1105,19 @@ func (rc *rowsCursor) Next(dest []driver.Value) error {
 	return nil
 }
 
+func (rc *rowsCursor) HasNextResultSet() bool {
+	return rc.posSet < len(rc.rows)-1
+}
+
+func (rc *rowsCursor) NextResultSet() error {
+	if !rc.HasNextResultSet() {
+		return io.EOF
+	}
+	rc.posSet++
+	rc.posRow = -1
+	return nil
+}
+
 // fakeDriverString is like driver.String, but indirects pointers like
 // DefaultValueConverter.
 //
@@ -889,3 +1169,31 @@ func converterForType(typ string) driver.ValueConverter {
 	}
 	panic("invalid fakedb column type of " + typ)
 }
+
+func colTypeToReflectType(typ string) reflect.Type {
+	switch typ {
+	case "bool":
+		return reflect.TypeOf(false)
+	case "nullbool":
+		return reflect.TypeOf(NullBool{})
+	case "int32":
+		return reflect.TypeOf(int32(0))
+	case "string":
+		return reflect.TypeOf("")
+	case "nullstring":
+		return reflect.TypeOf(NullString{})
+	case "int64":
+		return reflect.TypeOf(int64(0))
+	case "nullint64":
+		return reflect.TypeOf(NullInt64{})
+	case "float64":
+		return reflect.TypeOf(float64(0))
+	case "nullfloat64":
+		return reflect.TypeOf(NullFloat64{})
+	case "datetime":
+		return reflect.TypeOf(time.Time{})
+	case "blob":
+		return reflect.TypeOf([]byte(nil))
+	}
+	panic("invalid fakedb column type of " + typ)
+}
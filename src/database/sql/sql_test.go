@@ -5,9 +5,11 @@
 package sql
 
 import (
+	"context"
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"reflect"
 	"runtime"
@@ -115,6 +117,25 @@ func exec(t testing.TB, db *DB, query string, args ...interface{}) {
 	}
 }
 
+// waitCondition reports whether fn returns true within waitFor,
+// polling every 5ms.
+func waitCondition(waitFor time.Duration, fn func() bool) bool {
+	deadline := time.Now().Add(waitFor)
+	for time.Now().Before(deadline) {
+		if fn() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return fn()
+}
+
+func (rs *Rows) isClosed() bool {
+	rs.closemu.RLock()
+	defer rs.closemu.RUnlock()
+	return rs.closed
+}
+
 func closeDB(t testing.TB, db *DB) {
 	if e := recover(); e != nil {
 		fmt.Printf("Panic: %v\n", e)
@@ -260,6 +281,135 @@ func TestQuery(t *testing.T) {
 	}
 }
 
+func TestQueryContext(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err := db.QueryContext(ctx, "SELECT|people|age,name|")
+	if err != nil {
+		t.Fatalf("QueryContext: %v", err)
+	}
+	if !rows.Next() {
+		t.Fatalf("Next: no first row: %v", rows.Err())
+	}
+	var age int
+	var name string
+	if err := rows.Scan(&age, &name); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if age != 1 || name != "Alice" {
+		t.Errorf("got %d, %q; want 1, Alice", age, name)
+	}
+
+	cancel()
+	if !waitCondition(5*time.Second, func() bool { return rows.isClosed() }) {
+		t.Fatal("rows not closed after context was canceled")
+	}
+	if rows.Next() {
+		t.Error("Next returned true after context was canceled")
+	}
+	if err := rows.Err(); err != context.Canceled {
+		t.Errorf("Err = %v; want %v", err, context.Canceled)
+	}
+	if err := rows.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if n := db.numFreeConns(); n != 1 {
+		t.Errorf("free conns after canceled query = %d; want 1", n)
+	}
+}
+
+func TestQueryContextTimeout(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := db.QueryContext(ctx, "WAIT|10s|SELECT|people|age,name|")
+	if err != context.DeadlineExceeded {
+		t.Fatalf("QueryContext error = %v; want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("QueryContext took %v to time out", d)
+	}
+
+	// Once the context is done, no connection is even requested.
+	_, err = db.ExecContext(ctx, "INSERT|people|name=Dave,age=?", 4)
+	if err != context.DeadlineExceeded {
+		t.Errorf("ExecContext error = %v; want %v", err, context.DeadlineExceeded)
+	}
+	if n := db.numFreeConns(); n != 1 {
+		t.Errorf("free conns after timed out query = %d; want 1", n)
+	}
+}
+
+func TestStmtContext(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	stmt, err := db.PrepareContext(context.Background(), "WAIT|10s|SELECT|people|name|age=?")
+	if err != nil {
+		t.Fatalf("PrepareContext: %v", err)
+	}
+	defer stmt.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	var name string
+	err = stmt.QueryRowContext(ctx, 2).Scan(&name)
+	if err != context.Canceled {
+		t.Errorf("QueryRowContext error = %v; want %v", err, context.Canceled)
+	}
+
+	ins, err := db.Prepare("INSERT|people|name=?,age=?")
+	if err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	defer ins.Close()
+	if _, err := ins.ExecContext(ctx, "Dave", 4); err != context.Canceled {
+		t.Errorf("ExecContext error = %v; want %v", err, context.Canceled)
+	}
+	if _, err := ins.ExecContext(context.Background(), "Dave", 4); err != nil {
+		t.Fatalf("ExecContext: %v", err)
+	}
+	if err := db.QueryRowContext(context.Background(), "SELECT|people|name|age=?", 4).Scan(&name); err != nil || name != "Dave" {
+		t.Errorf("QueryRowContext = %q, %v; want Dave", name, err)
+	}
+}
+
+// Tests that a request for a connection that has to wait for a free
+// one gives up when its context is done.
+func TestConnContextWait(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = db.QueryContext(ctx, "SELECT|people|name|")
+	if err != context.DeadlineExceeded {
+		t.Errorf("QueryContext error = %v; want %v", err, context.DeadlineExceeded)
+	}
+	db.mu.Lock()
+	n := len(db.connRequests)
+	db.mu.Unlock()
+	if n != 0 {
+		t.Errorf("pending connection requests = %d; want 0", n)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	exec(t, db, "INSERT|people|name=Dave,age=?", 4)
+}
+
 func TestByteOwnership(t *testing.T) {
 	db := newTestDB(t, "people")
 	defer closeDB(t, db)
@@ -317,6 +467,303 @@ func TestRowsColumns(t *testing.T) {
 	}
 }
 
+func TestRowsColumnTypes(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	rows, err := db.Query("SELECT|people|age,name|")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	defer rows.Close()
+	tt, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes: %v", err)
+	}
+	if len(tt) != 2 {
+		t.Fatalf("got %d column types; want 2", len(tt))
+	}
+	for i, want := range []struct {
+		name, dbType string
+		scanType     reflect.Type
+	}{
+		{"age", "INT32", reflect.TypeOf(int32(0))},
+		{"name", "STRING", reflect.TypeOf("")},
+	} {
+		ct := tt[i]
+		if ct.Name() != want.name || ct.DatabaseTypeName() != want.dbType || ct.ScanType() != want.scanType {
+			t.Errorf("column %d = %q %q %v; want %q %q %v", i, ct.Name(), ct.DatabaseTypeName(), ct.ScanType(), want.name, want.dbType, want.scanType)
+		}
+		if nullable, ok := ct.Nullable(); nullable || !ok {
+			t.Errorf("column %d: Nullable = %v, %v; want false, true", i, nullable, ok)
+		}
+		if _, ok := ct.Length(); ok {
+			t.Errorf("column %d: Length reported by driver without RowsColumnTypeLength", i)
+		}
+		if _, _, ok := ct.DecimalSize(); ok {
+			t.Errorf("column %d: DecimalSize reported by driver without RowsColumnTypePrecisionScale", i)
+		}
+	}
+
+	// The scan types can be used to allocate destinations.
+	values := make([]interface{}, len(tt))
+	for i, ct := range tt {
+		values[i] = reflect.New(ct.ScanType()).Interface()
+	}
+	if !rows.Next() {
+		t.Fatalf("Next: no first row: %v", rows.Err())
+	}
+	if err := rows.Scan(values...); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if age, name := *values[0].(*int32), *values[1].(*string); age != 1 || name != "Alice" {
+		t.Errorf("got %d, %q; want 1, Alice", age, name)
+	}
+
+	rows.Close()
+	if _, err := rows.ColumnTypes(); err == nil {
+		t.Error("ColumnTypes succeeded on closed rows")
+	}
+}
+
+// decimalRows is a driver.Rows that describes its columns with all
+// of the optional column type interfaces.
+type decimalRows struct{}
+
+func (decimalRows) Columns() []string              { return []string{"amount", "note"} }
+func (decimalRows) Close() error                   { return nil }
+func (decimalRows) Next(dest []driver.Value) error { return io.EOF }
+
+func (decimalRows) ColumnTypeScanType(index int) reflect.Type {
+	return []reflect.Type{reflect.TypeOf(""), reflect.TypeOf(NullString{})}[index]
+}
+
+func (decimalRows) ColumnTypeDatabaseTypeName(index int) string {
+	return []string{"DECIMAL", "VARCHAR"}[index]
+}
+
+func (decimalRows) ColumnTypeLength(index int) (int64, bool) {
+	if index == 1 {
+		return 254, true
+	}
+	return 0, false
+}
+
+func (decimalRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return index == 1, true
+}
+
+func (decimalRows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if index == 0 {
+		return 31, 2, true
+	}
+	return 0, 0, false
+}
+
+func TestColumnTypeProperties(t *testing.T) {
+	tt := rowsColumnInfoSetup(decimalRows{})
+	amount, note := tt[0], tt[1]
+	if p, s, ok := amount.DecimalSize(); p != 31 || s != 2 || !ok {
+		t.Errorf("amount DecimalSize = %d, %d, %v; want 31, 2, true", p, s, ok)
+	}
+	if _, ok := amount.Length(); ok {
+		t.Error("amount has a Length")
+	}
+	if n, ok := amount.Nullable(); n || !ok {
+		t.Errorf("amount Nullable = %v, %v; want false, true", n, ok)
+	}
+	if amount.DatabaseTypeName() != "DECIMAL" || amount.ScanType() != reflect.TypeOf("") {
+		t.Errorf("amount type = %q %v; want DECIMAL string", amount.DatabaseTypeName(), amount.ScanType())
+	}
+	if l, ok := note.Length(); l != 254 || !ok {
+		t.Errorf("note Length = %d, %v; want 254, true", l, ok)
+	}
+	if _, _, ok := note.DecimalSize(); ok {
+		t.Error("note has a DecimalSize")
+	}
+	if n, ok := note.Nullable(); !n || !ok {
+		t.Errorf("note Nullable = %v, %v; want true, true", n, ok)
+	}
+	if note.Name() != "note" || note.ScanType() != reflect.TypeOf(NullString{}) {
+		t.Errorf("note = %q %v; want note NullString", note.Name(), note.ScanType())
+	}
+
+	// Without the optional interfaces only the name and the generic
+	// scan type are known.
+	tt = rowsColumnInfoSetup(struct{ driver.Rows }{decimalRows{}})
+	if tt[0].Name() != "amount" || tt[0].DatabaseTypeName() != "" {
+		t.Errorf("column = %q %q; want amount and no type name", tt[0].Name(), tt[0].DatabaseTypeName())
+	}
+	if st := tt[0].ScanType(); st != reflect.TypeOf(new(interface{})).Elem() {
+		t.Errorf("ScanType = %v; want interface {}", st)
+	}
+	if _, ok := tt[0].Nullable(); ok {
+		t.Error("Nullable reported by driver without RowsColumnTypeNullable")
+	}
+}
+
+func TestMultiResultSet(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	rows, err := db.Query("SELECT|people|name|;SELECT|people|age,name|age=?", 3)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		names = append(names, name)
+	}
+	if want := []string{"Alice", "Bob", "Chris"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("first result set = %q; want %q", names, want)
+	}
+	if !rows.HasNextResultSet() {
+		t.Fatalf("HasNextResultSet = false after first result set: %v", rows.Err())
+	}
+	if !rows.NextResultSet() {
+		t.Fatalf("NextResultSet = false: %v", rows.Err())
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		t.Fatalf("Columns: %v", err)
+	}
+	if want := []string{"age", "name"}; !reflect.DeepEqual(cols, want) {
+		t.Errorf("second result set columns = %q; want %q", cols, want)
+	}
+	n := 0
+	for rows.Next() {
+		var age int
+		var name string
+		if err := rows.Scan(&age, &name); err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		if age != 3 || name != "Chris" {
+			t.Errorf("got %d, %q; want 3, Chris", age, name)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Errorf("second result set had %d rows; want 1", n)
+	}
+	if rows.HasNextResultSet() {
+		t.Error("HasNextResultSet = true after last result set")
+	}
+	if rows.NextResultSet() {
+		t.Error("NextResultSet = true after last result set")
+	}
+	if err := rows.Err(); err != nil {
+		t.Errorf("Err: %v", err)
+	}
+	if !rows.isClosed() {
+		t.Error("rows not closed after last result set")
+	}
+}
+
+func TestNextResultSetUnsupported(t *testing.T) {
+	rows := &Rows{rowsi: decimalRows{}, releaseConn: func(error) {}}
+	if rows.HasNextResultSet() {
+		t.Error("HasNextResultSet = true for driver without RowsNextResultSet")
+	}
+	if rows.NextResultSet() {
+		t.Error("NextResultSet = true for driver without RowsNextResultSet")
+	}
+	if !rows.isClosed() {
+		t.Error("rows not closed by NextResultSet")
+	}
+}
+
+func TestNamedArgs(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	var name string
+	err := db.QueryRow("SELECT|people|name|age=?age", Named("age", 2)).Scan(&name)
+	if err != nil {
+		t.Fatalf("QueryRow: %v", err)
+	}
+	if name != "Bob" {
+		t.Errorf("name = %q; want Bob", name)
+	}
+
+	stmt, err := db.Prepare("SELECT|people|name|age=?age,name=?name")
+	if err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	defer stmt.Close()
+	err = stmt.QueryRow(Named("name", "Chris"), Named("age", 3)).Scan(&name)
+	if err != nil {
+		t.Fatalf("stmt.QueryRow: %v", err)
+	}
+	if name != "Chris" {
+		t.Errorf("name = %q; want Chris", name)
+	}
+
+	_, err = db.Query("SELECT|people|name|age=?age", Named("1age", 2))
+	if err == nil || !strings.Contains(err.Error(), "does not begin with a letter") {
+		t.Errorf("Query with invalid name error = %v; want name error", err)
+	}
+	_, err = db.Query("SELECT|people|name|age=?age", Named("other", 2))
+	if err == nil || !strings.Contains(err.Error(), `"?age"`) {
+		t.Errorf("Query with missing named argument error = %v; want error", err)
+	}
+}
+
+func TestNamedArgsUnsupported(t *testing.T) {
+	_, err := namedValueToValue([]driver.NamedValue{{Name: "age", Ordinal: 1, Value: int64(2)}})
+	if err == nil {
+		t.Error("namedValueToValue accepted a named parameter")
+	}
+	dargs, err := namedValueToValue([]driver.NamedValue{{Ordinal: 1, Value: int64(2)}})
+	if err != nil || len(dargs) != 1 || dargs[0] != int64(2) {
+		t.Errorf("namedValueToValue = %v, %v; want [2], nil", dargs, err)
+	}
+}
+
+func TestOutParam(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	var n int64
+	if _, err := db.Exec("COUNT|people|?n", Named("n", Out{Dest: &n})); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("out n = %d; want 3", n)
+	}
+
+	n = 10
+	if _, err := db.Exec("COUNT|people|?n", Named("n", Out{Dest: &n, In: true})); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if n != 13 {
+		t.Errorf("inout n = %d; want 13", n)
+	}
+
+	stmt, err := db.Prepare("COUNT|people|?n")
+	if err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	defer stmt.Close()
+	n = 0
+	if _, err := stmt.Exec(Named("n", Out{Dest: &n})); err != nil {
+		t.Fatalf("stmt.Exec: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("stmt out n = %d; want 3", n)
+	}
+
+	var s string
+	if _, err := db.Exec("COUNT|people|?n", Named("n", Out{Dest: &s})); err == nil {
+		t.Error("Exec accepted an output parameter of the wrong type")
+	}
+	if _, err := db.Query("SELECT|people|name|age=?age", Named("age", Out{Dest: &n})); err == nil {
+		t.Error("Query accepted an output parameter")
+	}
+}
+
 func TestQueryRow(t *testing.T) {
 	db := newTestDB(t, "people")
 	defer closeDB(t, db)
@@ -640,6 +1087,114 @@ func TestTxQueryInvalid(t *testing.T) {
 	}
 }
 
+func TestBeginTxOptions(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+
+	tx, err := db.BeginTx(context.Background(), &TxOptions{Isolation: LevelSerializable, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	want := driver.TxOptions{Isolation: driver.IsolationLevel(LevelSerializable), ReadOnly: true}
+	if got := tx.txi.(*fakeTx).opts; got != want {
+		t.Errorf("driver got options %+v; want %+v", got, want)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.BeginTx(context.Background(), &TxOptions{Isolation: LevelSnapshot})
+	if err == nil || !strings.Contains(err.Error(), "unsupported isolation level") {
+		t.Errorf("BeginTx with LevelSnapshot error = %v; want unsupported isolation level", err)
+	}
+}
+
+// Tests that a driver without BeginTx can only begin transactions
+// with the default options.
+func TestBeginTxOptionsUnsupported(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	dc, err := db.conn(context.Background(), cachedOrNewConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dc.releaseConn(nil)
+	ci := struct{ driver.Conn }{dc.ci}
+
+	ctx := context.Background()
+	if _, err := ctxDriverBegin(ctx, &TxOptions{Isolation: LevelReadCommitted}, ci); err != errNoIsolationLevel {
+		t.Errorf("isolation level error = %v; want %v", err, errNoIsolationLevel)
+	}
+	if _, err := ctxDriverBegin(ctx, &TxOptions{ReadOnly: true}, ci); err != errNoReadOnly {
+		t.Errorf("read-only error = %v; want %v", err, errNoReadOnly)
+	}
+	txi, err := ctxDriverBegin(ctx, &TxOptions{}, ci)
+	if err != nil {
+		t.Fatalf("default options: %v", err)
+	}
+	txi.Rollback()
+}
+
+// Tests that a transaction is rolled back and its connection
+// discarded when its context is canceled.
+func TestTxContextCancel(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+	exec(t, db, "CREATE|t1|name=string,age=int32,dead=bool")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT|t1|name=?", "Alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	if !waitCondition(5*time.Second, tx.isDone) {
+		t.Fatal("transaction not rolled back after context was canceled")
+	}
+	if err := tx.Commit(); err != context.Canceled {
+		t.Errorf("Commit error = %v; want %v", err, context.Canceled)
+	}
+	if _, err := tx.Exec("INSERT|t1|name=?", "Bob"); err != ErrTxDone {
+		t.Errorf("Exec error = %v; want %v", err, ErrTxDone)
+	}
+	if err := tx.Rollback(); err != ErrTxDone {
+		t.Errorf("Rollback error = %v; want %v", err, ErrTxDone)
+	}
+	db.mu.Lock()
+	numOpen := db.numOpen
+	db.mu.Unlock()
+	if numOpen != 0 {
+		t.Errorf("open conns after canceled transaction = %d; want 0", numOpen)
+	}
+}
+
+// Tests that committing a transaction stops the goroutine watching
+// its context, leaving the connection in the pool.
+func TestTxContextCommit(t *testing.T) {
+	db := newTestDB(t, "")
+	defer closeDB(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := tx.Rollback(); err != ErrTxDone {
+		t.Errorf("Rollback error = %v; want %v", err, ErrTxDone)
+	}
+	if n := db.numFreeConns(); n != 1 {
+		t.Errorf("free conns after commit = %d; want 1", n)
+	}
+}
+
 // Tests fix for issue 4433, that retries in Begin happen when
 // conn.Begin() returns ErrBadConn
 func TestTxErrBadConn(t *testing.T) {
@@ -1159,17 +1714,17 @@ func TestMaxOpenConnsOnBusy(t *testing.T) {
 
 	db.SetMaxOpenConns(3)
 
-	conn0, err := db.conn(cachedOrNewConn)
+	conn0, err := db.conn(context.Background(), cachedOrNewConn)
 	if err != nil {
 		t.Fatalf("db open conn fail: %v", err)
 	}
 
-	conn1, err := db.conn(cachedOrNewConn)
+	conn1, err := db.conn(context.Background(), cachedOrNewConn)
 	if err != nil {
 		t.Fatalf("db open conn fail: %v", err)
 	}
 
-	conn2, err := db.conn(cachedOrNewConn)
+	conn2, err := db.conn(context.Background(), cachedOrNewConn)
 	if err != nil {
 		t.Fatalf("db open conn fail: %v", err)
 	}
@@ -0,0 +1,866 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package binary
+
+import (
+	"errors"
+	"internal/ebcdic"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Marshal returns the encoding of v in the given byte order, laid out
+// as described by the struct tags of v's type.
+//
+// Marshal encodes the fixed-size values that Write accepts, bools as
+// a single byte 0 or 1, and strings and slices whose length is given
+// by the layout. The layout of a struct field is set by the "binary"
+// key in its tag, a comma-separated list of options:
+//
+//	-          the field is not encoded
+//	big        the field is big-endian, as are nested fields without
+//	           a byte order of their own
+//	little     the field is little-endian
+//	len=N      a string or []byte is stored in exactly N bytes,
+//	           padded at the end
+//	pad=B      the padding byte for len=N; the default is a space for
+//	           a string and zero for a []byte
+//	ebcdic     a string is stored in the EBCDIC code page IBM-1047
+//	prefix=N   a string or slice is preceded by its length, stored as
+//	           an unsigned integer of N bytes (1, 2, 4 or 8)
+//	count=F    the length of a string or slice is held in F, an earlier
+//	           integer field of the same struct
+//	offset=N   the field starts N bytes after the start of the struct
+//	bits=N     the field is a bit field of N bits
+//
+// The length of a string is counted in bytes as stored, and the
+// length of a slice in elements. Marshal sets a count field that is
+// zero to the length of the value it counts; a nonzero count field must
+// equal the length.
+//
+// Unmarshal removes trailing pad bytes from a string stored with len=N.
+// It keeps all N bytes of a []byte unless the pad option is given, as
+// trailing zero bytes may be part of the data.
+//
+// Before a field with an offset, Marshal writes zero bytes up to the
+// offset, and Unmarshal skips them. The offset of a field must not be
+// before the end of the preceding fields.
+//
+// Consecutive bit fields are packed, starting at the most significant
+// bit, into an unsigned integer of 8, 16, 32 or 64 bits stored in the
+// byte order of the first of them. A bit field must be a bool of one
+// bit or a sized integer type.
+//
+// As with Write, Marshal writes zero bytes for a blank (_) field, and
+// Unmarshal skips them.
+func Marshal(order ByteOrder, v interface{}) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return nil, errors.New("binary: Marshal of nil value")
+	}
+	e := &layoutEncoder{}
+	if err := e.value(rv, order); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// Unmarshal decodes the value pointed to by v from data, in the given
+// byte order and the layout described for Marshal. It returns the number
+// of bytes of data used by the value. Data after the value is ignored.
+//
+// If data ends before the value, Unmarshal returns io.ErrUnexpectedEOF.
+func Unmarshal(data []byte, order ByteOrder, v interface{}) (int, error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return 0, errors.New("binary: Unmarshal of nil value")
+	}
+	if rv.Kind() != reflect.Ptr {
+		return 0, errors.New("binary: Unmarshal of non-pointer type " + rv.Type().String())
+	}
+	if rv.IsNil() {
+		return 0, errors.New("binary: Unmarshal(nil " + rv.Type().String() + ")")
+	}
+	d := &layoutDecoder{data: data}
+	if err := d.value(rv.Elem(), order); err != nil {
+		return 0, err
+	}
+	return d.off, nil
+}
+
+// A layout is the compiled layout of a struct type.
+type layout struct {
+	fields []layoutField
+	counts bool  // some field has a count option
+	err    error // error in the struct tags, reported on use
+}
+
+// A layoutField is the layout of one struct field.
+type layoutField struct {
+	name   string // qualified name, for errors
+	index  int
+	blank  bool
+	order  ByteOrder // byte order from the tag, or nil
+	length int       // len=N, or 0
+	pad    byte
+	trim   bool // Unmarshal removes trailing pad bytes
+	ebcdic bool
+	prefix int // prefix=N, or 0
+	count  int // index in fields of the count field, or -1
+	offset int // offset=N, or -1
+	bits   int // bits=N, or 0
+	run    int // for the first bit field of a run, the number of fields in the run
+}
+
+// sized reports whether the length of the field is part of its layout.
+func (f *layoutField) sized() bool {
+	return f.length > 0 || f.prefix > 0 || f.count >= 0
+}
+
+var layoutCache struct {
+	sync.RWMutex
+	m map[reflect.Type]*layout
+}
+
+// cachedLayout returns the layout of the struct type t, compiling
+// it on first use.
+func cachedLayout(t reflect.Type) *layout {
+	layoutCache.RLock()
+	l := layoutCache.m[t]
+	layoutCache.RUnlock()
+	if l != nil {
+		return l
+	}
+
+	l = compileLayout(t)
+	layoutCache.Lock()
+	if layoutCache.m == nil {
+		layoutCache.m = make(map[reflect.Type]*layout)
+	}
+	layoutCache.m[t] = l
+	layoutCache.Unlock()
+	return l
+}
+
+// compileLayout parses the tags of the struct type t.
+func compileLayout(t reflect.Type) *layout {
+	l := &layout{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("binary")
+		if tag == "-" {
+			continue
+		}
+		f := layoutField{
+			name:   t.String() + "." + sf.Name,
+			index:  i,
+			blank:  sf.Name == "_",
+			count:  -1,
+			offset: -1,
+		}
+		if err := l.parseTag(&f, sf.Type, tag); err != nil {
+			l.err = err
+			return l
+		}
+		if f.count >= 0 {
+			l.counts = true
+		}
+		l.fields = append(l.fields, f)
+	}
+
+	// Group consecutive bit fields into runs.
+	for i := 0; i < len(l.fields); {
+		if l.fields[i].bits == 0 {
+			i++
+			continue
+		}
+		first := &l.fields[i]
+		bits := 0
+		j := i
+		for ; j < len(l.fields) && l.fields[j].bits > 0; j++ {
+			if j > i && l.fields[j].offset >= 0 {
+				break
+			}
+			bits += l.fields[j].bits
+		}
+		switch bits {
+		case 8, 16, 32, 64:
+		default:
+			l.err = errors.New("binary: bit fields starting at " + first.name + " fill " + strconv.Itoa(bits) + " bits, not 8, 16, 32 or 64")
+			return l
+		}
+		first.run = j - i
+		i = j
+	}
+	return l
+}
+
+// parseTag sets the options of f, a field of type typ, from tag.
+func (l *layout) parseTag(f *layoutField, typ reflect.Type, tag string) error {
+	bad := func(msg string) error {
+		return errors.New("binary: " + f.name + ": " + msg)
+	}
+	num := func(opt, s string) (int, error) {
+		n, err := strconv.ParseUint(s, 0, 31)
+		if err != nil {
+			return 0, bad("invalid " + opt + " " + strconv.Quote(s))
+		}
+		return int(n), nil
+	}
+
+	hasPad := false
+	if tag != "" {
+		for _, opt := range strings.Split(tag, ",") {
+			name, arg := opt, ""
+			if i := strings.Index(opt, "="); i >= 0 {
+				name, arg = opt[:i], opt[i+1:]
+			}
+			var err error
+			switch name {
+			case "big":
+				f.order = BigEndian
+			case "little":
+				f.order = LittleEndian
+			case "ebcdic":
+				f.ebcdic = true
+			case "len":
+				f.length, err = num(name, arg)
+				if err == nil && f.length == 0 {
+					err = bad("len must be positive")
+				}
+			case "pad":
+				var n int
+				n, err = num(name, arg)
+				if err == nil && n > 0xff {
+					err = bad("pad must be a byte")
+				}
+				f.pad, hasPad = byte(n), true
+			case "prefix":
+				f.prefix, err = num(name, arg)
+				switch f.prefix {
+				case 1, 2, 4, 8:
+				default:
+					err = bad("prefix must be 1, 2, 4 or 8")
+				}
+			case "count":
+				f.count = -1
+				for i := range l.fields {
+					if strings.HasSuffix(l.fields[i].name, "."+arg) {
+						f.count = i
+					}
+				}
+				if f.count < 0 {
+					err = bad("count field " + arg + " is not an earlier field")
+				}
+			case "offset":
+				f.offset, err = num(name, arg)
+			case "bits":
+				f.bits, err = num(name, arg)
+				if err == nil && (f.bits == 0 || f.bits > 64) {
+					err = bad("bits must be between 1 and 64")
+				}
+			default:
+				err = bad("unknown option " + strconv.Quote(opt))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Check that the options suit each other and the type.
+	kind := typ.Kind()
+	isBytes := kind == reflect.Slice && typ.Elem().Kind() == reflect.Uint8
+	n := 0
+	for _, set := range []bool{f.length > 0, f.prefix > 0, f.count >= 0, f.bits > 0} {
+		if set {
+			n++
+		}
+	}
+	switch {
+	case n > 1:
+		return bad("only one of len, prefix, count and bits is allowed")
+	case f.length > 0 && kind != reflect.String && !isBytes:
+		return bad("len requires a string or []byte")
+	case f.sized() && kind != reflect.String && kind != reflect.Slice:
+		return bad("prefix and count require a string or slice")
+	case (kind == reflect.String || kind == reflect.Slice) && !f.sized():
+		return bad("a " + typ.String() + " needs a len, prefix or count option")
+	case f.ebcdic && kind != reflect.String:
+		return bad("ebcdic requires a string")
+	case hasPad && f.length == 0:
+		return bad("pad requires len")
+	}
+	if !hasPad && kind == reflect.String {
+		f.pad = ' '
+		if f.ebcdic {
+			f.pad = 0x40 // EBCDIC space
+		}
+	}
+	// Trailing zero bytes may be data in a []byte, so only an
+	// explicit pad is removed from one.
+	f.trim = f.length > 0 && (kind == reflect.String || hasPad)
+	if f.count >= 0 {
+		c := &l.fields[f.count]
+		if c.bits > 0 || c.blank {
+			return bad("count field " + c.name + " must be an integer field")
+		}
+	}
+	if f.bits > 0 {
+		switch kind {
+		case reflect.Bool:
+			if f.bits != 1 {
+				return bad("a bool bit field must have 1 bit")
+			}
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if f.bits > 8*int(typ.Size()) {
+				return bad("too many bits for " + typ.String())
+			}
+		default:
+			return bad("a bit field must be a bool or a sized integer")
+		}
+	}
+	return nil
+}
+
+// countField returns the layout of the field counted by count, or nil.
+func (l *layout) countField(count int) *layoutField {
+	for i := range l.fields {
+		if l.fields[i].count == count {
+			return &l.fields[i]
+		}
+	}
+	return nil
+}
+
+// isIntKind reports whether k is an integer kind that can hold a count.
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// intValue returns the value of the integer v, and false if v is
+// negative or not an integer.
+func intValue(v reflect.Value) (uint64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			return 0, false
+		}
+		return uint64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), true
+	}
+	return 0, false
+}
+
+// bitMask returns a mask of the low n bits.
+func bitMask(n int) uint64 {
+	if n == 64 {
+		return math.MaxUint64
+	}
+	return 1<<uint(n) - 1
+}
+
+// A layoutEncoder appends the encoding of values to buf.
+type layoutEncoder struct {
+	buf []byte
+}
+
+func (e *layoutEncoder) uint(x uint64, size int, order ByteOrder) {
+	var b [8]byte
+	switch size {
+	case 1:
+		b[0] = byte(x)
+	case 2:
+		order.PutUint16(b[:], uint16(x))
+	case 4:
+		order.PutUint32(b[:], uint32(x))
+	case 8:
+		order.PutUint64(b[:], x)
+	}
+	e.buf = append(e.buf, b[:size]...)
+}
+
+func (e *layoutEncoder) zero(n int) {
+	for ; n > 0; n-- {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+// value appends the encoding of v, which has no layout options.
+func (e *layoutEncoder) value(v reflect.Value, order ByteOrder) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		var x uint64
+		if v.Bool() {
+			x = 1
+		}
+		e.uint(x, 1, order)
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.uint(uint64(v.Int()), int(v.Type().Size()), order)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		e.uint(v.Uint(), int(v.Type().Size()), order)
+	case reflect.Float32:
+		e.uint(uint64(math.Float32bits(float32(v.Float()))), 4, order)
+	case reflect.Float64:
+		e.uint(math.Float64bits(v.Float()), 8, order)
+	case reflect.Complex64:
+		x := v.Complex()
+		e.uint(uint64(math.Float32bits(float32(real(x)))), 4, order)
+		e.uint(uint64(math.Float32bits(float32(imag(x)))), 4, order)
+	case reflect.Complex128:
+		x := v.Complex()
+		e.uint(math.Float64bits(real(x)), 8, order)
+		e.uint(math.Float64bits(imag(x)), 8, order)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := e.value(v.Index(i), order); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		return e.structValue(v, order)
+	default:
+		return errors.New("binary: cannot marshal type " + v.Type().String())
+	}
+	return nil
+}
+
+func (e *layoutEncoder) structValue(v reflect.Value, order ByteOrder) error {
+	l := cachedLayout(v.Type())
+	if l.err != nil {
+		return l.err
+	}
+	start := len(e.buf)
+	for i := 0; i < len(l.fields); i++ {
+		f := &l.fields[i]
+		fv := v.Field(f.index)
+		o := order
+		if f.order != nil {
+			o = f.order
+		}
+		if f.offset >= 0 {
+			pos := start + f.offset
+			if len(e.buf) > pos {
+				return errors.New("binary: " + f.name + ": offset " + strconv.Itoa(f.offset) + " overlaps the preceding fields")
+			}
+			e.zero(pos - len(e.buf))
+		}
+
+		var err error
+		switch {
+		case f.run > 0:
+			err = e.bits(v, l.fields[i:i+f.run], o)
+			i += f.run - 1
+		case f.blank:
+			if n := sizeof(fv.Type()); n < 0 {
+				err = errors.New("binary: " + f.name + ": cannot marshal blank field of type " + fv.Type().String())
+			} else {
+				e.zero(n)
+			}
+		case f.sized():
+			err = e.sizedField(fv, f, o)
+		case l.counts && isIntKind(fv.Kind()):
+			err = e.countField(v, l, i, o)
+		default:
+			err = e.value(fv, o)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storedLen returns the length of the string or slice v as stored.
+func storedLen(v reflect.Value, f *layoutField) int {
+	if v.Kind() == reflect.String && f.ebcdic {
+		return utf8.RuneCountInString(v.String())
+	}
+	return v.Len()
+}
+
+// countField appends field i of struct v, which may be the count of
+// a later field.
+func (e *layoutEncoder) countField(v reflect.Value, l *layout, i int, order ByteOrder) error {
+	f := &l.fields[i]
+	fv := v.Field(f.index)
+	counted := l.countField(i)
+	if counted == nil {
+		return e.value(fv, order)
+	}
+	n := uint64(storedLen(v.Field(counted.index), counted))
+	x, ok := intValue(fv)
+	switch {
+	case !ok:
+		return errors.New("binary: " + f.name + ": invalid count " + strconv.FormatInt(fv.Int(), 10))
+	case x != 0 && x != n:
+		return errors.New("binary: " + f.name + " is " + strconv.FormatUint(x, 10) + ", but " + counted.name + " has length " + strconv.FormatUint(n, 10))
+	}
+	c := reflect.New(fv.Type()).Elem()
+	if fv.Kind() >= reflect.Uint && fv.Kind() <= reflect.Uintptr {
+		c.SetUint(n)
+		if c.Uint() != n {
+			return errors.New("binary: " + f.name + ": length " + strconv.FormatUint(n, 10) + " overflows " + fv.Type().String())
+		}
+	} else {
+		c.SetInt(int64(n))
+		if c.Int() != int64(n) {
+			return errors.New("binary: " + f.name + ": length " + strconv.FormatUint(n, 10) + " overflows " + fv.Type().String())
+		}
+	}
+	return e.value(c, order)
+}
+
+// sizedField appends a string or slice field with a len, prefix or
+// count option.
+func (e *layoutEncoder) sizedField(v reflect.Value, f *layoutField, order ByteOrder) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		if f.prefix > 0 {
+			if err := e.prefix(v.Len(), f, order); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := e.value(v.Index(i), order); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var b []byte
+	switch {
+	case v.Kind() == reflect.String && f.ebcdic:
+		var errStr string
+		if b, errStr = ebcdic.Encode([]rune(v.String())); errStr != "" {
+			return errors.New("binary: " + f.name + ": " + errStr)
+		}
+	case v.Kind() == reflect.String:
+		b = []byte(v.String())
+	default:
+		b = v.Bytes()
+	}
+	if f.length > 0 {
+		if len(b) > f.length {
+			return errors.New("binary: " + f.name + ": value of " + strconv.Itoa(len(b)) + " bytes exceeds len=" + strconv.Itoa(f.length))
+		}
+		e.buf = append(e.buf, b...)
+		for i := len(b); i < f.length; i++ {
+			e.buf = append(e.buf, f.pad)
+		}
+		return nil
+	}
+	if f.prefix > 0 {
+		if err := e.prefix(len(b), f, order); err != nil {
+			return err
+		}
+	}
+	e.buf = append(e.buf, b...)
+	return nil
+}
+
+// prefix appends the length prefix n of field f.
+func (e *layoutEncoder) prefix(n int, f *layoutField, order ByteOrder) error {
+	if uint64(n) > bitMask(8*f.prefix) {
+		return errors.New("binary: " + f.name + ": length " + strconv.Itoa(n) + " overflows prefix=" + strconv.Itoa(f.prefix))
+	}
+	e.uint(uint64(n), f.prefix, order)
+	return nil
+}
+
+// bits appends a run of bit fields of struct v.
+func (e *layoutEncoder) bits(v reflect.Value, run []layoutField, order ByteOrder) error {
+	var acc uint64
+	total := 0
+	for i := range run {
+		f := &run[i]
+		fv := v.Field(f.index)
+		mask := bitMask(f.bits)
+		var x uint64
+		switch {
+		case f.blank:
+			// Blank bit fields are zero, as blank fields are.
+		case fv.Kind() == reflect.Bool:
+			if fv.Bool() {
+				x = 1
+			}
+		case fv.Kind() >= reflect.Int8 && fv.Kind() <= reflect.Int64:
+			i := fv.Int()
+			if f.bits < 64 && (i < -1<<uint(f.bits-1) || i >= 1<<uint(f.bits-1)) {
+				return errors.New("binary: " + f.name + ": value " + strconv.FormatInt(i, 10) + " overflows " + strconv.Itoa(f.bits) + " bits")
+			}
+			x = uint64(i) & mask
+		default:
+			x = fv.Uint()
+			if x > mask {
+				return errors.New("binary: " + f.name + ": value " + strconv.FormatUint(x, 10) + " overflows " + strconv.Itoa(f.bits) + " bits")
+			}
+		}
+		if f.bits < 64 {
+			acc <<= uint(f.bits)
+		}
+		acc |= x
+		total += f.bits
+	}
+	e.uint(acc, total/8, order)
+	return nil
+}
+
+// A layoutDecoder decodes values from data.
+type layoutDecoder struct {
+	data []byte
+	off  int
+}
+
+// next returns the next n bytes of data.
+func (d *layoutDecoder) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.off {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := d.data[d.off : d.off+n]
+	d.off += n
+	return b, nil
+}
+
+func (d *layoutDecoder) uint(size int, order ByteOrder) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(order.Uint16(b)), nil
+	case 4:
+		return uint64(order.Uint32(b)), nil
+	}
+	return order.Uint64(b), nil
+}
+
+// value decodes v, which has no layout options.
+func (d *layoutDecoder) value(v reflect.Value, order ByteOrder) error {
+	switch v.Kind() {
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := d.value(v.Index(i), order); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		return d.structValue(v, order)
+	case reflect.Complex64, reflect.Complex128:
+		size := int(v.Type().Size()) / 2
+		re, err := d.uint(size, order)
+		if err != nil {
+			return err
+		}
+		im, err := d.uint(size, order)
+		if err != nil {
+			return err
+		}
+		if size == 4 {
+			v.SetComplex(complex(float64(math.Float32frombits(uint32(re))), float64(math.Float32frombits(uint32(im)))))
+		} else {
+			v.SetComplex(complex(math.Float64frombits(re), math.Float64frombits(im)))
+		}
+		return nil
+	}
+
+	var size int
+	switch v.Kind() {
+	case reflect.Bool:
+		size = 1
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		size = int(v.Type().Size())
+	default:
+		return errors.New("binary: cannot unmarshal type " + v.Type().String())
+	}
+	x, err := d.uint(size, order)
+	if err != nil {
+		return err
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(x != 0)
+	case reflect.Int8:
+		v.SetInt(int64(int8(x)))
+	case reflect.Int16:
+		v.SetInt(int64(int16(x)))
+	case reflect.Int32:
+		v.SetInt(int64(int32(x)))
+	case reflect.Int64:
+		v.SetInt(int64(x))
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(uint32(x))))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(x))
+	default:
+		v.SetUint(x)
+	}
+	return nil
+}
+
+func (d *layoutDecoder) structValue(v reflect.Value, order ByteOrder) error {
+	l := cachedLayout(v.Type())
+	if l.err != nil {
+		return l.err
+	}
+	start := d.off
+	for i := 0; i < len(l.fields); i++ {
+		f := &l.fields[i]
+		fv := v.Field(f.index)
+		o := order
+		if f.order != nil {
+			o = f.order
+		}
+		if f.offset >= 0 {
+			pos := start + f.offset
+			if d.off > pos {
+				return errors.New("binary: " + f.name + ": offset " + strconv.Itoa(f.offset) + " overlaps the preceding fields")
+			}
+			if _, err := d.next(pos - d.off); err != nil {
+				return err
+			}
+		}
+		if !f.blank && !fv.CanSet() {
+			return errors.New("binary: cannot unmarshal into unexported field " + f.name)
+		}
+
+		var err error
+		switch {
+		case f.run > 0:
+			err = d.bits(v, l.fields[i:i+f.run], o)
+			i += f.run - 1
+		case f.blank:
+			if n := sizeof(fv.Type()); n < 0 {
+				err = errors.New("binary: " + f.name + ": cannot unmarshal blank field of type " + fv.Type().String())
+			} else {
+				_, err = d.next(n)
+			}
+		case f.sized():
+			err = d.sizedField(v, l, f, o)
+		default:
+			err = d.value(fv, o)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sizedField decodes a string or slice field of struct v with a len,
+// prefix or count option.
+func (d *layoutDecoder) sizedField(v reflect.Value, l *layout, f *layoutField, order ByteOrder) error {
+	fv := v.Field(f.index)
+	var n int
+	switch {
+	case f.length > 0:
+		n = f.length
+	case f.prefix > 0:
+		x, err := d.uint(f.prefix, order)
+		if err != nil {
+			return err
+		}
+		if x > uint64(len(d.data)-d.off) {
+			return io.ErrUnexpectedEOF
+		}
+		n = int(x)
+	default:
+		c := &l.fields[f.count]
+		x, ok := intValue(v.Field(c.index))
+		if !ok {
+			return errors.New("binary: " + c.name + ": invalid count " + strconv.FormatInt(v.Field(c.index).Int(), 10))
+		}
+		if x > uint64(len(d.data)-d.off) {
+			return io.ErrUnexpectedEOF
+		}
+		n = int(x)
+	}
+
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(fv.Type(), n, n)
+		for i := 0; i < n; i++ {
+			if err := d.value(s.Index(i), order); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+	}
+
+	b, err := d.next(n)
+	if err != nil {
+		return err
+	}
+	if f.trim {
+		for len(b) > 0 && b[len(b)-1] == f.pad {
+			b = b[:len(b)-1]
+		}
+	}
+	switch {
+	case fv.Kind() == reflect.String && f.ebcdic:
+		runes, errStr := ebcdic.Decode(b)
+		if errStr != "" {
+			return errors.New("binary: " + f.name + ": " + errStr)
+		}
+		fv.SetString(string(runes))
+	case fv.Kind() == reflect.String:
+		fv.SetString(string(b))
+	default:
+		fv.SetBytes(append([]byte(nil), b...))
+	}
+	return nil
+}
+
+// bits decodes a run of bit fields of struct v.
+func (d *layoutDecoder) bits(v reflect.Value, run []layoutField, order ByteOrder) error {
+	total := 0
+	for i := range run {
+		total += run[i].bits
+	}
+	acc, err := d.uint(total/8, order)
+	if err != nil {
+		return err
+	}
+	for i := range run {
+		f := &run[i]
+		total -= f.bits
+		if f.blank {
+			continue
+		}
+		fv := v.Field(f.index)
+		if !fv.CanSet() {
+			return errors.New("binary: cannot unmarshal into unexported field " + f.name)
+		}
+		x := acc >> uint(total) & bitMask(f.bits)
+		switch fv.Kind() {
+		case reflect.Bool:
+			fv.SetBool(x != 0)
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			shift := uint(64 - f.bits)
+			fv.SetInt(int64(x<<shift) >> shift)
+		default:
+			fv.SetUint(x)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package binary
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalStruct(t *testing.T) {
+	for _, tt := range []struct {
+		order ByteOrder
+		b     []byte
+	}{{BigEndian, big}, {LittleEndian, little}} {
+		b, err := Marshal(tt.order, &s)
+		if err != nil || !bytes.Equal(b, tt.b) {
+			t.Errorf("Marshal %v = %v, %v, want %v", tt.order, b, err, tt.b)
+		}
+		var s2 Struct
+		n, err := Unmarshal(tt.b, tt.order, &s2)
+		checkResult(t, "Unmarshal", tt.order, err, s2, s)
+		if n != len(tt.b) {
+			t.Errorf("Unmarshal %v used %d bytes, want %d", tt.order, n, len(tt.b))
+		}
+	}
+}
+
+// ppa1 is a program prolog area, as generated for each function by
+// the linker for z/OS, with the function name in EBCDIC at its end.
+type ppa1 struct {
+	Version      uint8
+	Signature    uint8
+	SavedGPRMask uint16
+	PPA2Offset   uint32
+	Flags1       struct {
+		Offset64  bool   `binary:"bits=1"`
+		_         uint8  `binary:"bits=6"`
+		ExitDSA   bool   `binary:"bits=1"`
+		Flags2to4 uint32 `binary:"bits=24"`
+	}
+	ParmsLength  uint16
+	PrologLength uint8
+	Alloca       uint8 `binary:"bits=4"`
+	ChgOffset    uint8 `binary:"bits=4"`
+	CodeLength   uint32
+	NameLength   uint16
+	Name         string `binary:"count=NameLength,ebcdic"`
+}
+
+var ppa1Value = ppa1{
+	Version:      2,
+	Signature:    0xce,
+	SavedGPRMask: 0x0fff,
+	ParmsLength:  3,
+	PrologLength: 9,
+	Alloca:       0,
+	ChgOffset:    6,
+	CodeLength:   0x1234,
+	NameLength:   9,
+	Name:         "main.main",
+}
+
+func init() {
+	ppa1Value.Flags1.Offset64 = true
+	ppa1Value.Flags1.Flags2to4 = 1
+}
+
+var ppa1Bytes = []byte{
+	0x02, 0xce, 0x0f, 0xff,
+	0x00, 0x00, 0x00, 0x00,
+	0x80, 0x00, 0x00, 0x01,
+	0x00, 0x03, 0x09, 0x06,
+	0x00, 0x00, 0x12, 0x34,
+	0x00, 0x09,
+	0x94, 0x81, 0x89, 0x95, 0x4b, 0x94, 0x81, 0x89, 0x95,
+}
+
+type lengths struct {
+	Fixed  string   `binary:"len=6"`
+	Bytes  []byte   `binary:"len=4"`
+	Zeros  []byte   `binary:"len=3,pad=0"`
+	Padded string   `binary:"len=4,pad=0x2e"`
+	EBCDIC string   `binary:"len=3,ebcdic"`
+	Words  []uint16 `binary:"prefix=1"`
+	Text   string   `binary:"prefix=2,little"`
+	N      int8
+	Pairs  [][2]uint8 `binary:"count=N"`
+	Skip   int        `binary:"-"`
+}
+
+type offsets struct {
+	A uint8
+	B uint16 `binary:"offset=4"`
+	_ [2]byte
+	C int32 `binary:"little"`
+	D uint8 `binary:"offset=12"`
+}
+
+type bitFields struct {
+	A bool  `binary:"bits=1"`
+	B int8  `binary:"bits=3"`
+	C uint8 `binary:"bits=4"`
+	D uint16
+	E uint16 `binary:"bits=12,little"`
+	F int8   `binary:"bits=4"`
+}
+
+var layoutTests = []struct {
+	Name  string
+	Order ByteOrder
+	Value interface{}
+	Bytes []byte
+}{
+	{
+		Name:  "PPA1",
+		Order: BigEndian,
+		Value: &ppa1Value,
+		Bytes: ppa1Bytes,
+	},
+	{
+		Name:  "Lengths",
+		Order: BigEndian,
+		Value: &lengths{"ab", []byte{1, 0, 2, 0}, []byte{3}, "x", "A", []uint16{1, 2}, "hi", 2, [][2]uint8{{3, 4}, {5, 6}}, 0},
+		Bytes: []byte{
+			'a', 'b', ' ', ' ', ' ', ' ',
+			1, 0, 2, 0,
+			3, 0, 0,
+			'x', '.', '.', '.',
+			0xc1, 0x40, 0x40,
+			2, 0, 1, 0, 2,
+			2, 0, 'h', 'i',
+			2, 3, 4, 5, 6,
+		},
+	},
+	{
+		Name:  "Offsets",
+		Order: BigEndian,
+		Value: &offsets{A: 1, B: 2, C: -2, D: 3},
+		Bytes: []byte{1, 0, 0, 0, 0, 2, 0, 0, 0xfe, 0xff, 0xff, 0xff, 3},
+	},
+	{
+		Name:  "BitFields",
+		Order: BigEndian,
+		Value: &bitFields{A: true, B: -1, C: 5, D: 0x0102, E: 0xabc, F: -2},
+		Bytes: []byte{0xf5, 0x01, 0x02, 0xce, 0xab},
+	},
+	{
+		Name:  "Bool",
+		Order: LittleEndian,
+		Value: &struct {
+			A, B bool
+			C    uint16
+		}{true, false, 1},
+		Bytes: []byte{1, 0, 1, 0},
+	},
+}
+
+func TestMarshalLayout(t *testing.T) {
+	for _, tt := range layoutTests {
+		b, err := Marshal(tt.Order, tt.Value)
+		if err != nil {
+			t.Errorf("%s: Marshal: %v", tt.Name, err)
+			continue
+		}
+		if !bytes.Equal(b, tt.Bytes) {
+			t.Errorf("%s: Marshal:\n\thave % x\n\twant % x", tt.Name, b, tt.Bytes)
+		}
+	}
+}
+
+func TestUnmarshalLayout(t *testing.T) {
+	for _, tt := range layoutTests {
+		v := reflect.New(reflect.TypeOf(tt.Value).Elem())
+		data := append(append([]byte(nil), tt.Bytes...), 0xff)
+		n, err := Unmarshal(data, tt.Order, v.Interface())
+		if err != nil {
+			t.Errorf("%s: Unmarshal: %v", tt.Name, err)
+			continue
+		}
+		if n != len(tt.Bytes) {
+			t.Errorf("%s: Unmarshal used %d bytes, want %d", tt.Name, n, len(tt.Bytes))
+		}
+		if !reflect.DeepEqual(v.Interface(), tt.Value) {
+			t.Errorf("%s: Unmarshal:\n\thave %+v\n\twant %+v", tt.Name, v.Interface(), tt.Value)
+		}
+
+		for i := 0; i < len(tt.Bytes); i++ {
+			v := reflect.New(reflect.TypeOf(tt.Value).Elem())
+			if _, err := Unmarshal(tt.Bytes[:i], tt.Order, v.Interface()); err != io.ErrUnexpectedEOF {
+				t.Errorf("%s: Unmarshal of %d bytes: error %v, want ErrUnexpectedEOF", tt.Name, i, err)
+				break
+			}
+		}
+	}
+}
+
+// Test that Marshal sets a zero count field from the length it counts.
+func TestMarshalCount(t *testing.T) {
+	v := ppa1Value
+	v.NameLength = 0
+	b, err := Marshal(BigEndian, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, ppa1Bytes) {
+		t.Errorf("Marshal:\n\thave % x\n\twant % x", b, ppa1Bytes)
+	}
+}
+
+var layoutErrorTests = []struct {
+	Name  string
+	Value interface{}
+	Error string
+}{
+	{
+		Name:  "NoLength",
+		Value: struct{ S string }{},
+		Error: "binary: struct { S string }.S: a string needs a len, prefix or count option",
+	},
+	{
+		Name: "Unknown",
+		Value: struct {
+			A uint8 `binary:"size=2"`
+		}{},
+		Error: `binary: struct { A uint8 "binary:\"size=2\"" }.A: unknown option "size=2"`,
+	},
+	{
+		Name:  "TooLong",
+		Value: &lengths{Fixed: "abcdefg"},
+		Error: "binary: binary.lengths.Fixed: value of 7 bytes exceeds len=6",
+	},
+	{
+		Name:  "CountMismatch",
+		Value: &lengths{N: 1},
+		Error: "binary: binary.lengths.N is 1, but binary.lengths.Pairs has length 0",
+	},
+	{
+		Name:  "PrefixOverflow",
+		Value: &lengths{Words: make([]uint16, 256)},
+		Error: "binary: binary.lengths.Words: length 256 overflows prefix=1",
+	},
+	{
+		Name:  "NotEBCDIC",
+		Value: &lengths{EBCDIC: "é"},
+		Error: "binary: binary.lengths.EBCDIC: rune not in EBCDIC CodePage1047",
+	},
+	{
+		Name:  "BitOverflow",
+		Value: &bitFields{B: 4},
+		Error: "binary: binary.bitFields.B: value 4 overflows 3 bits",
+	},
+	{
+		Name: "BitRun",
+		Value: struct {
+			A uint8 `binary:"bits=3"`
+			B uint8
+		}{},
+		Error: `binary: bit fields starting at struct { A uint8 "binary:\"bits=3\""; B uint8 }.A fill 3 bits, not 8, 16, 32 or 64`,
+	},
+	{
+		Name: "Overlap",
+		Value: struct {
+			A uint32
+			B uint8 `binary:"offset=2"`
+		}{},
+		Error: `binary: struct { A uint32; B uint8 "binary:\"offset=2\"" }.B: offset 2 overlaps the preceding fields`,
+	},
+	{
+		Name:  "Int",
+		Value: struct{ A int }{},
+		Error: "binary: cannot marshal type int",
+	},
+}
+
+func TestMarshalLayoutErrors(t *testing.T) {
+	for _, tt := range layoutErrorTests {
+		_, err := Marshal(BigEndian, tt.Value)
+		if err == nil || err.Error() != tt.Error {
+			t.Errorf("%s: error %v, want %s", tt.Name, err, tt.Error)
+		}
+	}
+}
+
+func TestUnmarshalLayoutErrors(t *testing.T) {
+	var u struct {
+		A uint8
+		b uint8
+	}
+	if _, err := Unmarshal([]byte{1, 2}, BigEndian, &u); err == nil || err.Error() != "binary: cannot unmarshal into unexported field struct { A uint8; b uint8 }.b" {
+		t.Errorf("Unmarshal into unexported field: error %v", err)
+	}
+	if _, err := Unmarshal([]byte{1, 2}, BigEndian, u); err == nil || err.Error() != "binary: Unmarshal of non-pointer type struct { A uint8; b uint8 }" {
+		t.Errorf("Unmarshal into non-pointer: error %v", err)
+	}
+	if _, err := Unmarshal([]byte{1, 2}, BigEndian, (*struct{ A uint8 })(nil)); err == nil || err.Error() != "binary: Unmarshal(nil *struct { A uint8 })" {
+		t.Errorf("Unmarshal into nil pointer: error %v", err)
+	}
+	if _, err := Unmarshal([]byte{1, 2}, BigEndian, nil); err == nil || err.Error() != "binary: Unmarshal of nil value" {
+		t.Errorf("Unmarshal into nil: error %v", err)
+	}
+}
+
+func BenchmarkMarshalLayout(b *testing.B) {
+	b.SetBytes(int64(len(ppa1Bytes)))
+	for i := 0; i < b.N; i++ {
+		Marshal(BigEndian, &ppa1Value)
+	}
+}
+
+func BenchmarkUnmarshalLayout(b *testing.B) {
+	b.SetBytes(int64(len(ppa1Bytes)))
+	var v ppa1
+	for i := 0; i < b.N; i++ {
+		Unmarshal(ppa1Bytes, BigEndian, &v)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package copybook decodes and encodes records described by COBOL
+// copybooks.
+//
+// Parse reads the data description entries of a copybook and returns
+// the Layout of the record they describe. The Layout's Unmarshal and
+// Marshal methods convert between the bytes of a record, as read from
+// a z/OS dataset, and Go values, much as package json does for JSON
+// documents. A record is stored in a struct or in a map; see Unmarshal
+// for the rules that match items of the record to struct fields.
+//
+// The following kinds of elementary items are supported:
+//
+//	PIC X(n), PIC A(n)              EBCDIC text
+//	numeric-edited, such as ZZ9.99  EBCDIC text
+//	PIC S9(n)V9(m)                  zoned decimal
+//	PIC S9(n)V9(m) COMP-3           packed decimal
+//	PIC S9(n) COMP, COMP-4, BINARY  big-endian binary of at most n digits
+//	PIC S9(n) COMP-5                big-endian binary
+//	COMP-1, COMP-2                  hexadecimal floating point
+//
+// Groups may be redefined with REDEFINES and repeated with OCCURS,
+// including OCCURS DEPENDING ON. Level-88 condition names and VALUE
+// clauses are ignored. The P picture symbol, SIGN SEPARATE, SIGN
+// LEADING, SYNCHRONIZED and RENAMES are not supported.
+//
+// Text is converted between UTF-8 and EBCDIC (IBM-1047).
+package copybook
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// A Usage is the internal representation of an item.
+type Usage int
+
+const (
+	Display Usage = iota // characters or zoned decimal
+	Comp                 // binary, limited to the digits of the picture
+	Comp1                // short hexadecimal floating point
+	Comp2                // long hexadecimal floating point
+	Comp3                // packed decimal
+	Comp5                // binary
+)
+
+var usageNames = []string{
+	Display: "DISPLAY",
+	Comp:    "COMP",
+	Comp1:   "COMP-1",
+	Comp2:   "COMP-2",
+	Comp3:   "COMP-3",
+	Comp5:   "COMP-5",
+}
+
+func (u Usage) String() string {
+	if u >= 0 && int(u) < len(usageNames) {
+		return usageNames[u]
+	}
+	return fmt.Sprintf("Usage(%d)", int(u))
+}
+
+// A Field is an item of a record, as described by a data description
+// entry of the copybook.
+type Field struct {
+	Level       int
+	Name        string // data name, or FILLER
+	Picture     string // PICTURE character string; empty for groups
+	Usage       Usage
+	Redefines   string   // name of the item redefined by this one
+	Occurs      int      // maximum number of occurrences, or 0 without OCCURS
+	MinOccurs   int      // minimum number of occurrences for OCCURS DEPENDING ON
+	DependingOn string   // name of the OCCURS DEPENDING ON object, with any qualifiers
+	Children    []*Field // subordinate items of a group
+
+	// Size is the length in bytes of one occurrence of the item.
+	// For a group that contains OCCURS DEPENDING ON it is the
+	// maximum length.
+	Size int
+
+	// For numeric items, Digits is the number of digits in the
+	// picture, Scale the number of them after the implied decimal
+	// point and Signed reports whether the picture starts with S.
+	Digits int
+	Scale  int
+	Signed bool
+
+	alpha     bool   // text or numeric-edited item
+	usage     bool   // entry has a USAGE clause
+	inTable   bool   // item is or is subordinate to an item with OCCURS
+	object    bool   // item is the object of an OCCURS DEPENDING ON
+	dynamic   bool   // item is or contains an OCCURS DEPENDING ON or its object
+	dependsOn *Field // object of the OCCURS DEPENDING ON
+	parent    *Field // group containing the item
+	line      int    // line of the entry in the copybook
+}
+
+func (f *Field) group() bool { return len(f.Children) > 0 }
+
+// total returns the maximum length in bytes of all occurrences of f.
+func (f *Field) total() int {
+	if f.Occurs > 0 {
+		return f.Size * f.Occurs
+	}
+	return f.Size
+}
+
+// describe returns a description of f for error messages.
+func (f *Field) describe() string {
+	switch {
+	case f.group():
+		return "group"
+	case f.Usage == Comp1 || f.Usage == Comp2:
+		return f.Usage.String()
+	case f.Usage == Display:
+		return "PIC " + f.Picture
+	}
+	return "PIC " + f.Picture + " " + f.Usage.String()
+}
+
+// A Layout describes the items of a record.
+type Layout struct {
+	// Name is the name of the level-01 item of the copybook, or
+	// empty if the copybook has none.
+	Name string
+
+	// Fields holds the items of the record: the items subordinate
+	// to the level-01 item, or the items at the top of the copybook
+	// if it has no level-01 group.
+	Fields []*Field
+
+	root *Field
+}
+
+// Size returns the maximum length in bytes of a record.
+func (l *Layout) Size() int { return l.root.Size }
+
+// A SyntaxError reports an invalid or unsupported entry in a copybook.
+type SyntaxError struct {
+	Line int    // line of the copybook, starting at 1
+	Msg  string // description of the error
+}
+
+func (e *SyntaxError) Error() string {
+	return "copybook: line " + strconv.Itoa(e.Line) + ": " + e.Msg
+}
+
+// A FieldError reports an error decoding or encoding an item of a
+// record.
+type FieldError struct {
+	Path   string // path of the item, such as ORDERS(2).AMOUNT
+	Offset int    // byte offset of the item in the record
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	return "copybook: " + e.Path + " at offset " + strconv.Itoa(e.Offset) + ": " + e.Err.Error()
+}
+
+var (
+	errRange   = errors.New("value out of range")
+	errInexact = errors.New("value has more fractional digits than the picture")
+)
+
+const ebcdicSP = 0x40 // EBCDIC space
@@ -0,0 +1,430 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package copybook
+
+import (
+	"encoding/binary"
+	"encoding/packed"
+	"errors"
+	"fmt"
+	"internal/ebcdic"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Unmarshal decodes the record in data and stores the result in the
+// value pointed to by v, which must be a struct or a map with string
+// keys. Bytes after the end of the record are ignored.
+//
+// The items of a group are stored in the fields of a struct or as the
+// elements of a map. A struct field matches an item if its tag, the
+// value associated with the key "copybook", is the item's name, or if
+// it has no tag and its name matches the item's name with the hyphens
+// removed, ignoring case: the field CustName matches CUST-NAME. Fields
+// with the tag "-" are ignored. A map stores every item under its name,
+// including all the items that share storage through REDEFINES, so
+// the storage must be valid for each of them. FILLER items and items
+// with no matching field are skipped.
+//
+// An item with OCCURS is stored in a slice or an array. A subordinate
+// group is stored in a struct, a map or a pointer to either. An
+// elementary item is stored as follows:
+//
+//	text and numeric-edited items in a string, converted to UTF-8 with
+//	trailing spaces removed, or in a []byte holding the original bytes.
+//
+//	decimal items in an integer, if they have no digits after the
+//	decimal point, or in a floating-point number, a string or a
+//	packed.Decimal.
+//
+//	COMP-1 and COMP-2 items in a floating-point number or a string.
+//
+// In an interface{} value a group is stored as a
+// map[string]interface{}, a table as a []interface{}, text as a
+// string, a decimal as an int64 if it has no digits after the decimal
+// point and as a packed.Decimal otherwise, and a floating-point number
+// as a float64.
+//
+// Unmarshal returns a *FieldError for an item that cannot be decoded
+// or stored.
+func (l *Layout) Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("copybook: Unmarshal requires a non-nil pointer")
+	}
+	d := &decodeState{data: data, objects: make(map[*Field]int64)}
+	_, err := d.group(l.root, 0, "", rv.Elem())
+	return err
+}
+
+type decodeState struct {
+	data    []byte
+	objects map[*Field]int64 // values of OCCURS DEPENDING ON objects
+}
+
+// group decodes the items of group f at offset off into v, and returns
+// the length of the group. If v is the zero Value the items are
+// skipped.
+func (d *decodeState) group(f *Field, off int, path string, v reflect.Value) (int, error) {
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Invalid, reflect.Struct:
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return 0, typeError(f, path, off, v.Type())
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return 0, typeError(f, path, off, v.Type())
+		}
+		m := reflect.ValueOf(make(map[string]interface{}))
+		v.Set(m)
+		v = m
+	default:
+		return 0, typeError(f, path, off, v.Type())
+	}
+
+	slot, end := off, off
+	for _, c := range f.Children {
+		if c.Redefines == "" {
+			slot = end
+		}
+		var cv reflect.Value
+		switch {
+		case c.Name == "FILLER":
+		case v.Kind() == reflect.Struct:
+			if i, ok := structFields(v.Type())[fieldKey(c.Name)]; ok {
+				cv = v.Field(i)
+			}
+		case v.Kind() == reflect.Map:
+			cv = reflect.New(v.Type().Elem()).Elem()
+		}
+		n, err := d.item(c, slot, join(path, c.Name), cv)
+		if err != nil {
+			return 0, err
+		}
+		if cv.IsValid() && v.Kind() == reflect.Map {
+			v.SetMapIndex(reflect.ValueOf(c.Name).Convert(v.Type().Key()), cv)
+		}
+		if slot+n > end {
+			end = slot + n
+		}
+	}
+	return end - off, nil
+}
+
+// item decodes all occurrences of f at offset off into v, and returns
+// their length.
+func (d *decodeState) item(f *Field, off int, path string, v reflect.Value) (int, error) {
+	if !v.IsValid() && !f.dynamic && !f.object {
+		return f.total(), nil
+	}
+	if f.Occurs == 0 {
+		return d.value(f, off, path, v)
+	}
+
+	count := f.Occurs
+	if f.DependingOn != "" {
+		n := d.objects[f.dependsOn]
+		if n < int64(f.MinOccurs) || n > int64(f.Occurs) {
+			return 0, &FieldError{path, off, fmt.Errorf("%s is %d, outside OCCURS %d TO %d", f.DependingOn, n, f.MinOccurs, f.Occurs)}
+		}
+		count = int(n)
+	}
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Invalid:
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), count, count))
+	case reflect.Array:
+		if v.Len() < count {
+			return 0, typeError(f, path, off, v.Type())
+		}
+		z := reflect.Zero(v.Type().Elem())
+		for i := count; i < v.Len(); i++ {
+			v.Index(i).Set(z)
+		}
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return 0, typeError(f, path, off, v.Type())
+		}
+		s := reflect.ValueOf(make([]interface{}, count))
+		v.Set(s)
+		v = s
+	default:
+		return 0, typeError(f, path, off, v.Type())
+	}
+
+	n := 0
+	for i := 0; i < count; i++ {
+		var ev reflect.Value
+		if v.IsValid() {
+			ev = v.Index(i)
+		}
+		m, err := d.value(f, off+n, fmt.Sprintf("%s(%d)", path, i+1), ev)
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	return n, nil
+}
+
+// value decodes one occurrence of f at offset off into v, and returns
+// its length.
+func (d *decodeState) value(f *Field, off int, path string, v reflect.Value) (int, error) {
+	if f.group() {
+		return d.group(f, off, path, v)
+	}
+	if off+f.Size > len(d.data) {
+		return 0, &FieldError{path, off, io.ErrUnexpectedEOF}
+	}
+	v = indirect(v)
+	if err := d.elementary(f, d.data[off:off+f.Size], v); err != nil {
+		if err == errType {
+			return 0, typeError(f, path, off, v.Type())
+		}
+		return 0, &FieldError{path, off, err}
+	}
+	return f.Size, nil
+}
+
+var errType = errors.New("type mismatch")
+
+// elementary decodes the elementary item f held in b into v.
+// It returns errType if f cannot be stored in v.
+func (d *decodeState) elementary(f *Field, b []byte, v reflect.Value) error {
+	switch {
+	case f.alpha:
+		if !v.IsValid() {
+			return nil
+		}
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(append([]byte(nil), b...))
+			return nil
+		}
+		runes, errStr := ebcdic.Decode(b)
+		if errStr != "" {
+			return errors.New(errStr)
+		}
+		s := strings.TrimRight(string(runes), " ")
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(s)
+		case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+			v.Set(reflect.ValueOf(s))
+		default:
+			return errType
+		}
+		return nil
+
+	case f.Usage == Comp1 || f.Usage == Comp2:
+		if !v.IsValid() {
+			return nil
+		}
+		var x float64
+		if f.Usage == Comp1 {
+			x, _ = math.HFPToFloat64(uint64(binary.BigEndian.Uint32(b)) << 32)
+		} else {
+			x, _ = math.HFPToFloat64(binary.BigEndian.Uint64(b))
+		}
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			if v.OverflowFloat(x) {
+				return errRange
+			}
+			v.SetFloat(x)
+		case reflect.String:
+			v.SetString(strconv.FormatFloat(x, 'g', -1, 64))
+		case reflect.Interface:
+			if v.NumMethod() != 0 {
+				return errType
+			}
+			v.Set(reflect.ValueOf(x))
+		default:
+			return errType
+		}
+		return nil
+	}
+
+	dec, err := decodeNumber(f, b)
+	if err != nil {
+		return err
+	}
+	if f.object {
+		// The value is in range: an object has at most 18 digits.
+		d.objects[f] = dec.Unscaled.Int64()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return setNumber(v, dec)
+}
+
+// decodeNumber decodes the decimal item f held in b.
+func decodeNumber(f *Field, b []byte) (packed.Decimal, error) {
+	pf := packed.Format{Precision: f.Digits, Scale: f.Scale, Unsigned: !f.Signed}
+	var dec packed.Decimal
+	var err error
+	switch f.Usage {
+	case Display:
+		dec, err = pf.DecodeZonedDecimal(b)
+	case Comp3:
+		dec, err = pf.DecodePackedDecimal(b)
+	default:
+		var u uint64
+		for _, c := range b {
+			u = u<<8 | uint64(c)
+		}
+		v := new(big.Int)
+		if f.Signed {
+			shift := 64 - 8*uint(len(b))
+			v.SetInt64(int64(u<<shift) >> shift)
+		} else {
+			v.SetUint64(u)
+		}
+		dec = packed.Decimal{Unscaled: v, Scale: f.Scale}
+	}
+	if dec.Unscaled == nil {
+		dec.Unscaled = new(big.Int)
+	}
+	return dec, err
+}
+
+var decimalType = reflect.TypeOf(packed.Decimal{})
+
+// setNumber stores dec in v. It returns errType if v cannot hold a
+// decimal number with the scale of dec.
+func setNumber(v reflect.Value, dec packed.Decimal) error {
+	u := dec.Unscaled
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if dec.Scale != 0 {
+			return errType
+		}
+		if u.BitLen() > 63 || v.OverflowInt(u.Int64()) {
+			return errRange
+		}
+		v.SetInt(u.Int64())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if dec.Scale != 0 {
+			return errType
+		}
+		if u.Sign() < 0 || u.BitLen() > 64 || v.OverflowUint(u.Uint64()) {
+			return errRange
+		}
+		v.SetUint(u.Uint64())
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(dec.String(), v.Type().Bits())
+		if err != nil {
+			return errRange
+		}
+		v.SetFloat(x)
+	case reflect.String:
+		v.SetString(dec.String())
+	case reflect.Struct:
+		if v.Type() != decimalType {
+			return errType
+		}
+		v.Set(reflect.ValueOf(dec))
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return errType
+		}
+		if dec.Scale == 0 && u.BitLen() <= 63 {
+			v.Set(reflect.ValueOf(u.Int64()))
+		} else {
+			v.Set(reflect.ValueOf(dec))
+		}
+	default:
+		return errType
+	}
+	return nil
+}
+
+func typeError(f *Field, path string, off int, t reflect.Type) error {
+	return &FieldError{path, off, fmt.Errorf("cannot store %s in Go value of type %s", f.describe(), t)}
+}
+
+// indirect follows pointers from v, allocating values as needed.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// fieldKey returns the key under which an item or struct field name
+// is matched.
+func fieldKey(name string) string {
+	return strings.ToUpper(strings.Replace(name, "-", "", -1))
+}
+
+var fieldCache struct {
+	sync.RWMutex
+	m map[reflect.Type]map[string]int
+}
+
+// structFields returns the indexes of the fields of struct type t that
+// can store items, by item key.
+func structFields(t reflect.Type) map[string]int {
+	fieldCache.RLock()
+	fields := fieldCache.m[t]
+	fieldCache.RUnlock()
+	if fields != nil {
+		return fields
+	}
+
+	fields = make(map[string]int)
+	tagged := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		tag := sf.Tag.Get("copybook")
+		switch {
+		case tag == "-":
+		case tag != "":
+			// A tag takes precedence over a field name.
+			key := fieldKey(tag)
+			if !tagged[key] {
+				fields[key] = i
+				tagged[key] = true
+			}
+		default:
+			key := fieldKey(sf.Name)
+			if _, ok := fields[key]; !ok {
+				fields[key] = i
+			}
+		}
+	}
+
+	fieldCache.Lock()
+	if fieldCache.m == nil {
+		fieldCache.m = make(map[reflect.Type]map[string]int)
+	}
+	fieldCache.m[t] = fields
+	fieldCache.Unlock()
+	return fields
+}
@@ -0,0 +1,264 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package copybook
+
+import (
+	"encoding/packed"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const customerCopybook = `
+      * Customer record.
+       01  CUSTOMER-REC.
+           05  CUST-ID          PIC 9(6).
+           05  CUST-NAME        PIC X(20).
+           05  BALANCE          PIC S9(7)V99 COMP-3.
+           05  ORDER-COUNT      PIC S9(4) COMP.
+           05  ORDERS OCCURS 0 TO 5 TIMES DEPENDING ON ORDER-COUNT.
+               10  ORDER-ID     PIC X(4).
+               10  AMOUNT       PIC 9(5)V99.
+`
+
+// customerRecord is a record of customerCopybook with two orders.
+var customerRecord = "\xf0\xf0\xf1\xf2\xf3\xf4" + // CUST-ID 1234
+	"\xd1\xd6\xc8\xd5\x40\xe2\xd4\xc9\xe3\xc8" + strings.Repeat("\x40", 10) + // CUST-NAME JOHN SMITH
+	"\x00\x12\x34\x56\x7d" + // BALANCE -12345.67
+	"\x00\x02" + // ORDER-COUNT 2
+	"\xc1\xf0\xf0\xf1\xf0\xf0\xf1\xf2\xf3\xf4\xf5" + // A001 123.45
+	"\xc2\xf0\xf0\xf2\xf0\xf0\xf0\xf0\xf0\xf5\xf0" // B002 0.50
+
+type order struct {
+	ID     string `copybook:"ORDER-ID"`
+	Amount float64
+}
+
+type customer struct {
+	CustID     int
+	Name       string `copybook:"CUST-NAME"`
+	Balance    packed.Decimal
+	OrderCount int16
+	Orders     []order
+}
+
+func dec(unscaled int64, scale int) packed.Decimal {
+	return packed.Decimal{Unscaled: big.NewInt(unscaled), Scale: scale}
+}
+
+func strPtr(s string) *string { return &s }
+
+func mustParse(t *testing.T, copybook string) *Layout {
+	l, err := Parse(strings.NewReader(copybook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestUnmarshal(t *testing.T) {
+	l := mustParse(t, customerCopybook)
+	var c customer
+	if err := l.Unmarshal([]byte(customerRecord), &c); err != nil {
+		t.Fatal(err)
+	}
+	want := customer{
+		CustID:     1234,
+		Name:       "JOHN SMITH",
+		Balance:    dec(-1234567, 2),
+		OrderCount: 2,
+		Orders:     []order{{"A001", 123.45}, {"B002", 0.5}},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("Unmarshal = %+v, want %+v", c, want)
+	}
+}
+
+func TestUnmarshalMap(t *testing.T) {
+	l := mustParse(t, customerCopybook)
+	var m map[string]interface{}
+	if err := l.Unmarshal([]byte(customerRecord), &m); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"CUST-ID":     int64(1234),
+		"CUST-NAME":   "JOHN SMITH",
+		"BALANCE":     dec(-1234567, 2),
+		"ORDER-COUNT": int64(2),
+		"ORDERS": []interface{}{
+			map[string]interface{}{"ORDER-ID": "A001", "AMOUNT": dec(12345, 2)},
+			map[string]interface{}{"ORDER-ID": "B002", "AMOUNT": dec(50, 2)},
+		},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Unmarshal = %v, want %v", m, want)
+	}
+}
+
+// otherTypes is a layout with the kinds of items not in customerCopybook.
+const otherTypes = `
+       01  REC.
+           05  KIND       PIC X.
+           05  BODY       PIC X(4).
+           05  NUM-BODY   REDEFINES BODY.
+               10  N      PIC S9(3) COMP-3 OCCURS 2.
+           05  B          PIC S9(4) COMP.
+           05  U          PIC 9(9)V9 COMP-5.
+           05  F1         COMP-1.
+           05  F2         COMP-2.
+           05  ED         PIC -9.9.
+`
+
+var unmarshalTests = []struct {
+	Name     string
+	Copybook string
+	Input    string
+	Value    interface{} // pointer to a zero value of the type to decode into
+
+	Output interface{}
+	Error  string
+}{
+	{
+		Name:     "Redefines",
+		Copybook: otherTypes,
+		Input:    "\xd5\x00\x1c\x99\x9d" + "\xff\xfe" + "\x00\x00\x00\x00\xff\xff\xff\xff" + "\x41\x10\x00\x00" + "\xc2\x64\x00\x00\x00\x00\x00\x00" + "\x60\xf1\x4b\xf5",
+		Value: new(struct {
+			Kind    string
+			NumBody struct{ N [3]int }
+			B       int
+			U       float64
+			F1      float64
+			F2      string
+			Ed      []byte
+		}),
+		Output: &struct {
+			Kind    string
+			NumBody struct{ N [3]int }
+			B       int
+			U       float64
+			F1      float64
+			F2      string
+			Ed      []byte
+		}{"N", struct{ N [3]int }{[3]int{1, -999, 0}}, -2, 429496729.5, 1, "-100", []byte("\x60\xf1\x4b\xf5")},
+	},
+	{
+		Name:     "Text",
+		Copybook: otherTypes,
+		Input:    "\xe3\x85\xa7\xa3\x40" + strings.Repeat("\x00", 22) + "\x60\xf1\x4b\xf5",
+		Value: new(struct {
+			Body *string
+			Ed   string
+		}),
+		Output: &struct {
+			Body *string
+			Ed   string
+		}{strPtr("ext"), "-1.5"},
+	},
+	{
+		Name:     "Short",
+		Copybook: customerCopybook,
+		Input:    customerRecord[:30],
+		Value:    new(customer),
+		Error:    "copybook: BALANCE at offset 26: unexpected EOF",
+	},
+	{
+		Name:     "BadPacked",
+		Copybook: customerCopybook,
+		Input:    customerRecord[:30] + "\x77" + customerRecord[31:],
+		Value:    new(customer),
+		Error:    "copybook: BALANCE at offset 26: packed: illegal data at input byte 4",
+	},
+	{
+		Name:     "UnmappedBadPacked",
+		Copybook: customerCopybook,
+		Input:    customerRecord[:30] + "\x77" + customerRecord[31:],
+		Value:    new(struct{ CustID int }),
+		Output:   &struct{ CustID int }{1234},
+	},
+	{
+		Name:     "DependingOnRange",
+		Copybook: customerCopybook,
+		Input:    customerRecord[:31] + "\x00\x06" + customerRecord[33:],
+		Value:    new(struct{}),
+		Error:    "copybook: ORDERS at offset 33: ORDER-COUNT is 6, outside OCCURS 0 TO 5",
+	},
+	{
+		Name: "QualifiedDependingOn",
+		Copybook: `
+       01  REC.
+           05  HDR.
+               10  CNT    PIC 9.
+           05  TRL.
+               10  CNT    PIC 9.
+           05  T          PIC X OCCURS 1 TO 3 DEPENDING ON CNT OF HDR.
+`,
+		Input:  "\xf2\xf3\xc1\xc2",
+		Value:  new(struct{ T []string }),
+		Output: &struct{ T []string }{[]string{"A", "B"}},
+	},
+	{
+		Name:     "TablePath",
+		Copybook: customerCopybook,
+		Input:    customerRecord[:50] + "\x4b" + customerRecord[51:],
+		Value:    new(customer),
+		Error:    "copybook: ORDERS(2).AMOUNT at offset 48: packed: illegal data at input byte 2",
+	},
+	{
+		Name:     "Type",
+		Copybook: customerCopybook,
+		Input:    customerRecord,
+		Value:    new(struct{ Balance int }),
+		Error:    "copybook: BALANCE at offset 26: cannot store PIC S9(7)V99 COMP-3 in Go value of type int",
+	},
+	{
+		Name:     "GroupType",
+		Copybook: customerCopybook,
+		Input:    customerRecord,
+		Value:    new(struct{ Orders []string }),
+		Error:    "copybook: ORDERS(1) at offset 33: cannot store group in Go value of type string",
+	},
+	{
+		Name:     "Overflow",
+		Copybook: customerCopybook,
+		Input:    customerRecord,
+		Value:    new(struct{ CustID int8 }),
+		Error:    "copybook: CUST-ID at offset 0: value out of range",
+	},
+	{
+		Name:     "ShortArray",
+		Copybook: customerCopybook,
+		Input:    customerRecord,
+		Value:    new(struct{ Orders [1]order }),
+		Error:    "copybook: ORDERS at offset 33: cannot store group in Go value of type [1]copybook.order",
+	},
+}
+
+func TestUnmarshalTypes(t *testing.T) {
+	for _, tt := range unmarshalTests {
+		l := mustParse(t, tt.Copybook)
+		err := l.Unmarshal([]byte(tt.Input), tt.Value)
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("%s: error %v, want %s", tt.Name, err, tt.Error)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(tt.Value, tt.Output) {
+			t.Errorf("%s: out=%+v want %+v", tt.Name, tt.Value, tt.Output)
+		}
+	}
+}
+
+func TestUnmarshalNonPointer(t *testing.T) {
+	l := mustParse(t, customerCopybook)
+	if err := l.Unmarshal([]byte(customerRecord), customer{}); err == nil {
+		t.Error("Unmarshal of non-pointer succeeded")
+	}
+}
@@ -0,0 +1,424 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package copybook
+
+import (
+	"encoding/binary"
+	"encoding/packed"
+	"errors"
+	"fmt"
+	"internal/ebcdic"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// Marshal returns the record encoding of v, which must be a struct or
+// a map with string keys, or a pointer to either.
+//
+// Marshal matches items to struct fields and map elements as Unmarshal
+// does and accepts the same Go types for them, as well as integers for
+// COMP-1 and COMP-2 items. Numbers are rounded to the scale of the
+// item only if they are floating-point; other numbers must fit exactly.
+//
+// Items without a value are encoded as spaces, if they are text, or
+// zero, except those that redefine another item, which are encoded
+// only if their value is not the zero value of its type. A redefining
+// item overwrites the item it redefines.
+//
+// A table with OCCURS DEPENDING ON has as many occurrences as its
+// object says if the object has a value, and otherwise as many as
+// the slice or array of the table has elements, in which case the
+// object is set accordingly. Fixed-size tables are padded with items
+// without a value.
+//
+// Marshal returns a *FieldError for an item that cannot be encoded.
+func (l *Layout) Marshal(v interface{}) ([]byte, error) {
+	e := &encodeState{objects: make(map[*Field]*object)}
+	n, err := e.group(l.root, 0, "", reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return e.at(0, n), nil
+}
+
+type encodeState struct {
+	buf     []byte
+	objects map[*Field]*object
+}
+
+// An object is an encoded OCCURS DEPENDING ON object.
+type object struct {
+	f     *Field
+	off   int
+	value int64
+	set   bool // value comes from the Go value
+}
+
+// at returns the n bytes of the record at offset off, extending the
+// record with spaces as needed.
+func (e *encodeState) at(off, n int) []byte {
+	for len(e.buf) < off+n {
+		e.buf = append(e.buf, ebcdicSP)
+	}
+	return e.buf[off : off+n]
+}
+
+// group encodes the items of group f from v at offset off, and returns
+// the length of the group. If v is the zero Value the items have no
+// value.
+func (e *encodeState) group(f *Field, off int, path string, v reflect.Value) (int, error) {
+	v = deref(v)
+	switch v.Kind() {
+	case reflect.Invalid, reflect.Struct:
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return 0, marshalError(f, path, off, v.Type())
+		}
+	default:
+		return 0, marshalError(f, path, off, v.Type())
+	}
+
+	slot, end := off, off
+	for _, c := range f.Children {
+		if c.Redefines == "" {
+			slot = end
+		}
+		var cv reflect.Value
+		switch {
+		case c.Name == "FILLER":
+		case v.Kind() == reflect.Struct:
+			if i, ok := structFields(v.Type())[fieldKey(c.Name)]; ok {
+				cv = v.Field(i)
+			}
+		case v.Kind() == reflect.Map:
+			cv = v.MapIndex(reflect.ValueOf(c.Name).Convert(v.Type().Key()))
+		}
+		var n int
+		if c.Redefines != "" && (!cv.IsValid() || isZero(cv)) {
+			n = c.total()
+		} else {
+			var err error
+			if n, err = e.item(c, slot, join(path, c.Name), cv); err != nil {
+				return 0, err
+			}
+		}
+		if slot+n > end {
+			end = slot + n
+		}
+	}
+	return end - off, nil
+}
+
+// item encodes all occurrences of f from v at offset off, and returns
+// their length.
+func (e *encodeState) item(f *Field, off int, path string, v reflect.Value) (int, error) {
+	if f.Occurs == 0 {
+		return e.value(f, off, path, v)
+	}
+
+	v = deref(v)
+	switch v.Kind() {
+	case reflect.Invalid, reflect.Slice, reflect.Array:
+	default:
+		return 0, marshalError(f, path, off, v.Type())
+	}
+	// Elements of an array beyond the number of occurrences are
+	// ignored, but those of a slice are an error.
+	array := v.Kind() == reflect.Array
+	if v.IsValid() && !array && v.Len() > f.Occurs {
+		return 0, &FieldError{path, off, fmt.Errorf("%d elements exceed OCCURS %d", v.Len(), f.Occurs)}
+	}
+	count := f.Occurs
+	if f.DependingOn != "" {
+		obj := e.objects[f.dependsOn]
+		if obj == nil {
+			return 0, &FieldError{path, off, fmt.Errorf("%s not encoded", f.DependingOn)}
+		}
+		if obj.set {
+			if obj.value < int64(f.MinOccurs) || obj.value > int64(f.Occurs) {
+				return 0, &FieldError{path, off, fmt.Errorf("%s is %d, outside OCCURS %d TO %d", f.DependingOn, obj.value, f.MinOccurs, f.Occurs)}
+			}
+			count = int(obj.value)
+			if v.IsValid() && (v.Len() < count || !array && v.Len() != count) {
+				return 0, &FieldError{path, off, fmt.Errorf("%s is %d, but table has %d elements", f.DependingOn, count, v.Len())}
+			}
+		} else {
+			count = f.MinOccurs
+			if v.IsValid() {
+				count = v.Len()
+			}
+			if count < f.MinOccurs || count > f.Occurs {
+				return 0, &FieldError{path, off, fmt.Errorf("%d elements outside OCCURS %d TO %d", count, f.MinOccurs, f.Occurs)}
+			}
+			// Set the object to the number of elements.
+			b := e.buf[obj.off : obj.off+obj.f.Size]
+			if err := encodeElementary(obj.f, b, reflect.ValueOf(count)); err != nil {
+				return 0, &FieldError{f.DependingOn, obj.off, err}
+			}
+		}
+	}
+
+	n := 0
+	for i := 0; i < count; i++ {
+		var ev reflect.Value
+		if v.IsValid() && i < v.Len() {
+			ev = v.Index(i)
+		}
+		m, err := e.value(f, off+n, fmt.Sprintf("%s(%d)", path, i+1), ev)
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	return n, nil
+}
+
+// value encodes one occurrence of f from v at offset off, and returns
+// its length.
+func (e *encodeState) value(f *Field, off int, path string, v reflect.Value) (int, error) {
+	if f.group() {
+		return e.group(f, off, path, v)
+	}
+	v = deref(v)
+	if err := encodeElementary(f, e.at(off, f.Size), v); err != nil {
+		if err == errType {
+			return 0, marshalError(f, path, off, v.Type())
+		}
+		return 0, &FieldError{path, off, err}
+	}
+	if f.object {
+		dec, _ := decodeNumber(f, e.buf[off:off+f.Size])
+		e.objects[f] = &object{f: f, off: off, value: dec.Unscaled.Int64(), set: v.IsValid()}
+	}
+	return f.Size, nil
+}
+
+// encodeElementary encodes v as the elementary item f into b, which
+// has the length of f. The zero Value encodes as spaces or zero.
+// It returns errType if v cannot be encoded as f.
+func encodeElementary(f *Field, b []byte, v reflect.Value) error {
+	switch {
+	case f.alpha:
+		var s []byte
+		switch v.Kind() {
+		case reflect.Invalid:
+		case reflect.String:
+			var errStr string
+			if s, errStr = ebcdic.Encode([]rune(v.String())); errStr != "" {
+				return errors.New(errStr)
+			}
+		case reflect.Slice:
+			if v.Type().Elem().Kind() != reflect.Uint8 {
+				return errType
+			}
+			s = v.Bytes()
+		default:
+			return errType
+		}
+		if len(s) > len(b) {
+			return fmt.Errorf("value of %d bytes too long", len(s))
+		}
+		copy(b, s)
+		for i := len(s); i < len(b); i++ {
+			b[i] = ebcdicSP
+		}
+		return nil
+
+	case f.Usage == Comp1 || f.Usage == Comp2:
+		var x float64
+		switch v.Kind() {
+		case reflect.Invalid:
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			x = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			x = float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			x = v.Float()
+		case reflect.String:
+			var err error
+			if x, err = strconv.ParseFloat(v.String(), 64); err != nil {
+				return fmt.Errorf("invalid number %q", v.String())
+			}
+		default:
+			return errType
+		}
+		var r math.HFPResult
+		if f.Usage == Comp1 {
+			var h uint32
+			h, r = math.Float32ToHFP(float32(x))
+			binary.BigEndian.PutUint32(b, h)
+		} else {
+			var h uint64
+			h, r = math.Float64ToHFP(x)
+			binary.BigEndian.PutUint64(b, h)
+		}
+		if r == math.HFPOverflow || r == math.HFPInvalid {
+			return errRange
+		}
+		return nil
+	}
+
+	dec, err := numberValue(v, f.Scale)
+	if err != nil {
+		return err
+	}
+	u, err := rescale(dec, f.Scale)
+	if err != nil {
+		return err
+	}
+	pf := packed.Format{Precision: f.Digits, Scale: f.Scale, Unsigned: !f.Signed}
+	switch f.Usage {
+	case Display:
+		_, err = pf.AppendZonedBig(b[:0], u)
+	case Comp3:
+		_, err = pf.AppendPackedBig(b[:0], u)
+	default:
+		err = putBinary(f, b, u)
+	}
+	if err == packed.ErrOverflow {
+		err = errRange
+	}
+	return err
+}
+
+// numberValue returns the decimal number held in v. Floating-point
+// numbers are rounded to scale.
+func numberValue(v reflect.Value, scale int) (packed.Decimal, error) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return packed.Decimal{}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return packed.Decimal{Unscaled: big.NewInt(v.Int())}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return packed.Decimal{Unscaled: new(big.Int).SetUint64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		x := v.Float()
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return packed.Decimal{}, errRange
+		}
+		dec, _ := parseDecimal(strconv.FormatFloat(x, 'f', scale, 64))
+		return dec, nil
+	case reflect.String:
+		dec, ok := parseDecimal(v.String())
+		if !ok {
+			return packed.Decimal{}, fmt.Errorf("invalid number %q", v.String())
+		}
+		return dec, nil
+	case reflect.Struct:
+		if v.Type() == decimalType {
+			return v.Interface().(packed.Decimal), nil
+		}
+	}
+	return packed.Decimal{}, errType
+}
+
+// parseDecimal parses a decimal number such as -123.45.
+func parseDecimal(s string) (packed.Decimal, bool) {
+	neg := false
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	digits := make([]byte, 0, len(s))
+	scale, point := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case '0' <= c && c <= '9':
+			digits = append(digits, c)
+			if point {
+				scale++
+			}
+		case c == '.' && !point:
+			point = true
+		default:
+			return packed.Decimal{}, false
+		}
+	}
+	if len(digits) == 0 {
+		return packed.Decimal{}, false
+	}
+	u, _ := new(big.Int).SetString(string(digits), 10)
+	if neg {
+		u.Neg(u)
+	}
+	return packed.Decimal{Unscaled: u, Scale: scale}, true
+}
+
+var bigTen = big.NewInt(10)
+
+// rescale returns the unscaled value of dec for the given scale.
+func rescale(dec packed.Decimal, scale int) (*big.Int, error) {
+	u := dec.Unscaled
+	switch {
+	case u == nil:
+		return new(big.Int), nil
+	case dec.Scale == scale:
+		return u, nil
+	case dec.Scale < scale:
+		p := new(big.Int).Exp(bigTen, big.NewInt(int64(scale-dec.Scale)), nil)
+		return p.Mul(p, u), nil
+	}
+	p := new(big.Int).Exp(bigTen, big.NewInt(int64(dec.Scale-scale)), nil)
+	var r big.Int
+	p.QuoRem(u, p, &r)
+	if r.Sign() != 0 {
+		return nil, errInexact
+	}
+	return p, nil
+}
+
+// putBinary stores the unscaled value u of binary item f in b.
+func putBinary(f *Field, b []byte, u *big.Int) error {
+	if f.Usage == Comp && len(new(big.Int).Abs(u).String()) > f.Digits {
+		return errRange
+	}
+	// A signed value fits if -u-1 does for a negative u.
+	m := u
+	if u.Sign() < 0 {
+		if !f.Signed {
+			return errRange
+		}
+		m = new(big.Int).Not(u)
+	}
+	bits := 8 * len(b)
+	if f.Signed {
+		bits--
+	}
+	if m.BitLen() > bits {
+		return errRange
+	}
+	x := u.Uint64()
+	if u.Sign() < 0 {
+		x = uint64(u.Int64())
+	}
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte(x)
+		x >>= 8
+	}
+	return nil
+}
+
+// deref follows pointers and interfaces from v. It returns the zero
+// Value for a nil pointer or interface.
+func deref(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// isZero reports whether v holds the zero value of its type.
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+func marshalError(f *Field, path string, off int, t reflect.Type) error {
+	return &FieldError{path, off, fmt.Errorf("cannot encode Go value of type %s as %s", t, f.describe())}
+}
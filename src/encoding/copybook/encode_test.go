@@ -0,0 +1,190 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package copybook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	l := mustParse(t, customerCopybook)
+	c := customer{
+		CustID:     1234,
+		Name:       "JOHN SMITH",
+		Balance:    dec(-1234567, 2),
+		OrderCount: 2,
+		Orders:     []order{{"A001", 123.45}, {"B002", 0.5}},
+	}
+	b, err := l.Marshal(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != customerRecord {
+		t.Errorf("Marshal = %q, want %q", b, customerRecord)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, tt := range unmarshalTests {
+		if tt.Error != "" || tt.Copybook != otherTypes {
+			continue
+		}
+		l := mustParse(t, tt.Copybook)
+		b, err := l.Marshal(tt.Output)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+		v := reflect.New(reflect.TypeOf(tt.Output).Elem()).Interface()
+		if err := l.Unmarshal(b, v); err != nil {
+			t.Errorf("%s: Unmarshal: %v", tt.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, tt.Output) {
+			t.Errorf("%s: out=%+v want %+v", tt.Name, v, tt.Output)
+		}
+	}
+}
+
+// customerDefaults is the start of a record of customerCopybook
+// with no values and no orders.
+var customerDefaults = strings.Repeat("\xf0", 6) + strings.Repeat("\x40", 20) + "\x00\x00\x00\x00\x0c"
+
+var marshalTests = []struct {
+	Name     string
+	Copybook string
+	Value    interface{}
+
+	Output string
+	Error  string
+}{
+	{
+		Name:     "Defaults",
+		Copybook: customerCopybook,
+		Value:    map[string]interface{}{},
+		Output:   customerDefaults + "\x00\x00",
+	},
+	{
+		Name:     "Nil",
+		Copybook: customerCopybook,
+		Value:    (*customer)(nil),
+		Output:   customerDefaults + "\x00\x00",
+	},
+	{
+		Name:     "DependingOnFromTable",
+		Copybook: customerCopybook,
+		Value:    struct{ Orders []order }{[]order{{"A001", 123.45}}},
+		Output:   customerDefaults + "\x00\x01" + customerRecord[33:44],
+	},
+	{
+		Name:     "Strings",
+		Copybook: customerCopybook,
+		Value:    map[string]interface{}{"CUST-ID": "42", "BALANCE": "-1.5"},
+		Output:   "\xf0\xf0\xf0\xf0\xf4\xf2" + strings.Repeat("\x40", 20) + "\x00\x00\x00\x15\x0d\x00\x00",
+	},
+	{
+		Name:     "FloatRounding",
+		Copybook: customerCopybook,
+		Value:    map[string]interface{}{"BALANCE": 1.006},
+		Output:   customerDefaults[:26] + "\x00\x00\x00\x10\x1c\x00\x00",
+	},
+	{
+		Name:     "Binary",
+		Copybook: otherTypes,
+		Value:    map[string]interface{}{"B": -2, "U": 1.5, "F1": -100},
+		Output:   "\x40\x40\x40\x40\x40" + "\xff\xfe" + "\x00\x00\x00\x00\x00\x00\x00\x0f" + "\xc2\x64\x00\x00" + strings.Repeat("\x00", 8) + "\x40\x40\x40\x40",
+	},
+	{
+		Name:     "RedefinesZero",
+		Copybook: otherTypes,
+		Value: struct {
+			Body    string
+			NumBody *struct{ N []int }
+		}{Body: "ab"},
+		Output: "\x40\x81\x82\x40\x40" + strings.Repeat("\x00", 22) + "\x40\x40\x40\x40",
+	},
+	{
+		Name:     "DependingOnMismatch",
+		Copybook: customerCopybook,
+		Value:    customer{OrderCount: 3, Orders: make([]order, 2)},
+		Error:    "copybook: ORDERS at offset 33: ORDER-COUNT is 3, but table has 2 elements",
+	},
+	{
+		Name:     "TooManyElements",
+		Copybook: customerCopybook,
+		Value:    customer{Orders: make([]order, 6)},
+		Error:    "copybook: ORDERS at offset 33: 6 elements exceed OCCURS 5",
+	},
+	{
+		Name:     "TextTooLong",
+		Copybook: customerCopybook,
+		Value:    customer{Name: strings.Repeat("x", 21)},
+		Error:    "copybook: CUST-NAME at offset 6: value of 21 bytes too long",
+	},
+	{
+		Name:     "Inexact",
+		Copybook: customerCopybook,
+		Value:    map[string]interface{}{"BALANCE": dec(1234, 3)},
+		Error:    "copybook: BALANCE at offset 26: value has more fractional digits than the picture",
+	},
+	{
+		Name:     "Overflow",
+		Copybook: customerCopybook,
+		Value:    map[string]interface{}{"CUST-ID": 1234567},
+		Error:    "copybook: CUST-ID at offset 0: value out of range",
+	},
+	{
+		Name:     "Unsigned",
+		Copybook: customerCopybook,
+		Value:    map[string]interface{}{"CUST-ID": -1},
+		Error:    "copybook: CUST-ID at offset 0: value out of range",
+	},
+	{
+		Name:     "BinaryDigits",
+		Copybook: customerCopybook,
+		Value:    map[string]interface{}{"ORDER-COUNT": 10000},
+		Error:    "copybook: ORDER-COUNT at offset 31: value out of range",
+	},
+	{
+		Name:     "BadString",
+		Copybook: customerCopybook,
+		Value:    map[string]interface{}{"BALANCE": "1,5"},
+		Error:    `copybook: BALANCE at offset 26: invalid number "1,5"`,
+	},
+	{
+		Name:     "Type",
+		Copybook: customerCopybook,
+		Value:    map[string]interface{}{"CUST-NAME": 5},
+		Error:    "copybook: CUST-NAME at offset 6: cannot encode Go value of type int as PIC X(20)",
+	},
+	{
+		Name:     "TableType",
+		Copybook: customerCopybook,
+		Value:    map[string]interface{}{"ORDERS": "x"},
+		Error:    "copybook: ORDERS at offset 33: cannot encode Go value of type string as group",
+	},
+}
+
+func TestMarshalTypes(t *testing.T) {
+	for _, tt := range marshalTests {
+		l := mustParse(t, tt.Copybook)
+		b, err := l.Marshal(tt.Value)
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("%s: error %v, want %s", tt.Name, err, tt.Error)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+		if string(b) != tt.Output {
+			t.Errorf("%s: out=%q want %q", tt.Name, b, tt.Output)
+		}
+	}
+}
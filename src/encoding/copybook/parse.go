@@ -0,0 +1,603 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package copybook
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Columns of the reference format.
+const (
+	indicatorCol = 6  // indicator area
+	textCol      = 7  // start of program text
+	endCol       = 72 // end of program text
+)
+
+// maxDigits is the largest number of digits in a numeric item.
+const maxDigits = 31
+
+// Parse reads a copybook from r and returns the layout of the record
+// it describes.
+//
+// The copybook is in the fixed reference format: columns 1 to 6 hold
+// sequence numbers, column 7 an indicator and columns 8 to 72 the
+// data description entries. Lines with an asterisk or slash in
+// column 7 are comments and lines with a hyphen continue a literal.
+//
+// The items of a copybook are either all subordinate to a single
+// level-01 item or, in a copybook meant to be included in a record,
+// have no level-01 item at all.
+func Parse(r io.Reader) (*Layout, error) {
+	p := &parser{seen: make(map[string][]*Field)}
+	var toks []token
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		p.line++
+		var err error
+		if toks, err = p.scanLine(toks, s.Text()); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if p.quote != 0 {
+		return nil, p.errorf(p.line, "unterminated literal")
+	}
+	if len(toks) > 0 {
+		return nil, p.errorf(toks[len(toks)-1].line, "entry not terminated by a period")
+	}
+	return p.layout()
+}
+
+// A token is a word, literal or separator period of a copybook.
+type token struct {
+	text string
+	line int
+}
+
+func (t token) is(s string) bool { return strings.EqualFold(t.text, s) }
+
+type parser struct {
+	line    int
+	word    []byte // word being scanned across lines
+	quote   byte   // closing quote of a literal being scanned, or 0
+	top     []*Field
+	stack   []*Field
+	seen    map[string][]*Field // items resolved so far, by name
+	inTable int                 // number of enclosing OCCURS
+}
+
+func (p *parser) errorf(line int, format string, args ...interface{}) error {
+	return &SyntaxError{line, fmt.Sprintf(format, args...)}
+}
+
+// scanLine splits the program text of line into tokens, appending
+// them to toks. Every complete entry is parsed and removed from toks.
+func (p *parser) scanLine(toks []token, line string) ([]token, error) {
+	if len(line) <= indicatorCol {
+		return toks, nil
+	}
+	ind := line[indicatorCol]
+	if ind == '*' || ind == '/' {
+		return toks, nil
+	}
+	text := ""
+	if len(line) > textCol {
+		text = line[textCol:]
+		if len(text) > endCol-textCol {
+			text = text[:endCol-textCol]
+		}
+	}
+	i := 0
+	if p.quote != 0 {
+		if ind != '-' {
+			return nil, p.errorf(p.line-1, "unterminated literal")
+		}
+		// The literal continues after the first quote of the line.
+		i = strings.IndexAny(text, `'"`) + 1
+		if i == 0 {
+			return nil, p.errorf(p.line, "continuation line without quote")
+		}
+	} else if ind == '-' {
+		return nil, p.errorf(p.line, "unsupported continuation line")
+	}
+
+	for ; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case p.quote != 0:
+			p.word = append(p.word, c)
+			if c == p.quote {
+				p.quote = 0
+			}
+			continue
+		case c == '\'' || c == '"':
+			p.quote = c
+			p.word = append(p.word, c)
+			continue
+		case c != ' ' && c != '\t':
+			p.word = append(p.word, c)
+			if i+1 < len(text) && text[i+1] != ' ' && text[i+1] != '\t' {
+				continue
+			}
+		}
+		if len(p.word) == 0 {
+			continue
+		}
+		var err error
+		if toks, err = p.endWord(toks); err != nil {
+			return nil, err
+		}
+	}
+	if p.quote == 0 && len(p.word) > 0 {
+		return p.endWord(toks)
+	}
+	return toks, nil
+}
+
+// endWord adds the word just scanned to toks. A word ending in a
+// period ends the entry.
+func (p *parser) endWord(toks []token) ([]token, error) {
+	w := string(p.word)
+	p.word = p.word[:0]
+	period := strings.HasSuffix(w, ".")
+	w = strings.TrimRight(w, ".,;")
+	if w != "" {
+		toks = append(toks, token{w, p.line})
+	}
+	if !period {
+		return toks, nil
+	}
+	if len(toks) > 0 {
+		if err := p.entry(toks); err != nil {
+			return nil, err
+		}
+	}
+	return toks[:0], nil
+}
+
+// isClause reports whether t starts a clause of a data description
+// entry.
+func isClause(t token) bool {
+	switch strings.ToUpper(t.text) {
+	case "PIC", "PICTURE", "USAGE", "REDEFINES", "OCCURS", "VALUE", "VALUES",
+		"SIGN", "LEADING", "TRAILING", "SYNC", "SYNCHRONIZED", "JUST", "JUSTIFIED",
+		"BLANK", "GLOBAL", "EXTERNAL":
+		return true
+	}
+	_, ok := usages[strings.ToUpper(t.text)]
+	return ok
+}
+
+var usages = map[string]Usage{
+	"DISPLAY":         Display,
+	"COMP":            Comp,
+	"COMPUTATIONAL":   Comp,
+	"COMP-4":          Comp,
+	"COMPUTATIONAL-4": Comp,
+	"BINARY":          Comp,
+	"COMP-1":          Comp1,
+	"COMPUTATIONAL-1": Comp1,
+	"COMP-2":          Comp2,
+	"COMPUTATIONAL-2": Comp2,
+	"COMP-3":          Comp3,
+	"COMPUTATIONAL-3": Comp3,
+	"PACKED-DECIMAL":  Comp3,
+	"COMP-5":          Comp5,
+	"COMPUTATIONAL-5": Comp5,
+}
+
+// entry parses a data description entry and adds it to the tree of
+// items.
+func (p *parser) entry(toks []token) error {
+	switch {
+	case toks[0].is("EJECT"), toks[0].is("SKIP1"), toks[0].is("SKIP2"), toks[0].is("SKIP3"):
+		// Compiler-directing statements, sometimes without a period.
+		toks = toks[1:]
+		if len(toks) == 0 {
+			return nil
+		}
+	}
+	line := toks[0].line
+	level, err := strconv.Atoi(toks[0].text)
+	if err != nil || level < 1 || level > 49 && level != 66 && level != 77 && level != 88 {
+		return p.errorf(line, "invalid level number %q", toks[0].text)
+	}
+	switch level {
+	case 88:
+		return nil
+	case 66:
+		return p.errorf(line, "RENAMES is not supported")
+	case 77:
+		level = 1
+	}
+
+	f := &Field{Level: level, Name: "FILLER", line: line}
+	i := 1
+	if i < len(toks) && !isClause(toks[i]) {
+		f.Name = strings.ToUpper(toks[i].text)
+		i++
+	}
+	// next returns the next token, skipping the optional word skip.
+	next := func(what, skip string) (token, error) {
+		if i < len(toks) && skip != "" && toks[i].is(skip) {
+			i++
+		}
+		if i == len(toks) {
+			return token{}, p.errorf(toks[i-1].line, "missing %s", what)
+		}
+		i++
+		return toks[i-1], nil
+	}
+	number := func(what string) (int, error) {
+		t, err := next(what, "")
+		if err != nil {
+			return 0, err
+		}
+		n, err := strconv.Atoi(t.text)
+		if err != nil || n < 0 {
+			return 0, p.errorf(t.line, "invalid %s %q", what, t.text)
+		}
+		return n, nil
+	}
+	// skipWords skips the words up to the next clause.
+	skipWords := func() {
+		for i < len(toks) && !isClause(toks[i]) {
+			i++
+		}
+	}
+
+	for i < len(toks) {
+		t := toks[i]
+		i++
+		kw := strings.ToUpper(t.text)
+		if u, ok := usages[kw]; ok {
+			f.Usage, f.usage = u, true
+			continue
+		}
+		switch kw {
+		case "PIC", "PICTURE":
+			pic, err := next("picture", "IS")
+			if err != nil {
+				return err
+			}
+			f.Picture = strings.ToUpper(pic.text)
+		case "USAGE":
+			t, err := next("usage", "IS")
+			if err != nil {
+				return err
+			}
+			u, ok := usages[strings.ToUpper(t.text)]
+			if !ok {
+				return p.errorf(t.line, "unsupported usage %s", t.text)
+			}
+			f.Usage, f.usage = u, true
+		case "REDEFINES":
+			t, err := next("redefined item", "")
+			if err != nil {
+				return err
+			}
+			f.Redefines = strings.ToUpper(t.text)
+		case "OCCURS":
+			n, err := number("number of occurrences")
+			if err != nil {
+				return err
+			}
+			f.Occurs = n
+			if i < len(toks) && toks[i].is("TO") {
+				i++
+				if f.Occurs, err = number("number of occurrences"); err != nil {
+					return err
+				}
+				f.MinOccurs = n
+			}
+			if i < len(toks) && toks[i].is("TIMES") {
+				i++
+			}
+			if i < len(toks) && toks[i].is("DEPENDING") {
+				i++
+				t, err := next("DEPENDING ON object", "ON")
+				if err != nil {
+					return err
+				}
+				f.DependingOn = strings.ToUpper(t.text)
+				// The object may be qualified by the names of the
+				// groups containing it.
+				for i < len(toks) && (toks[i].is("OF") || toks[i].is("IN")) {
+					i++
+					q, err := next("qualifier", "")
+					if err != nil {
+						return err
+					}
+					f.DependingOn += " OF " + strings.ToUpper(q.text)
+				}
+			}
+			if f.Occurs < 1 || f.MinOccurs > f.Occurs {
+				return p.errorf(t.line, "invalid number of occurrences")
+			}
+			if f.DependingOn == "" && f.MinOccurs > 0 {
+				return p.errorf(t.line, "OCCURS TO without DEPENDING ON")
+			}
+			// Skip the KEY and INDEXED BY phrases.
+			for i < len(toks) && (toks[i].is("ASCENDING") || toks[i].is("DESCENDING") || toks[i].is("INDEXED")) {
+				i++
+				skipWords()
+			}
+		case "VALUE", "VALUES":
+			skipWords()
+		case "SIGN", "LEADING", "TRAILING":
+			if kw == "SIGN" {
+				t, err := next("sign position", "IS")
+				if err != nil {
+					return err
+				}
+				kw = strings.ToUpper(t.text)
+			}
+			if kw != "TRAILING" || i < len(toks) && toks[i].is("SEPARATE") {
+				return p.errorf(t.line, "only SIGN TRAILING is supported")
+			}
+		case "SYNC", "SYNCHRONIZED":
+			return p.errorf(t.line, "SYNCHRONIZED is not supported")
+		case "JUST", "JUSTIFIED", "BLANK", "GLOBAL", "EXTERNAL":
+			// These do not change the representation of the item.
+			skipWords()
+		default:
+			return p.errorf(t.line, "unexpected %q in entry for %s", t.text, f.Name)
+		}
+	}
+
+	for len(p.stack) > 0 && p.stack[len(p.stack)-1].Level >= f.Level {
+		p.stack = p.stack[:len(p.stack)-1]
+	}
+	if len(p.stack) == 0 {
+		p.top = append(p.top, f)
+	} else {
+		parent := p.stack[len(p.stack)-1]
+		if parent.Picture != "" || parent.Usage == Comp1 || parent.Usage == Comp2 {
+			return p.errorf(line, "%s is subordinate to elementary item %s", f.Name, parent.Name)
+		}
+		parent.Children = append(parent.Children, f)
+		f.parent = parent
+	}
+	p.stack = append(p.stack, f)
+	return nil
+}
+
+// layout resolves the items of the copybook and returns its layout.
+func (p *parser) layout() (*Layout, error) {
+	if len(p.top) == 0 {
+		return nil, p.errorf(p.line, "no data description entries")
+	}
+	for _, f := range p.top[1:] {
+		if f.Level == 1 || p.top[0].Level == 1 {
+			return nil, p.errorf(f.line, "more than one record")
+		}
+	}
+	l := &Layout{Fields: p.top}
+	if p.top[0].Level == 1 {
+		if p.top[0].group() {
+			l.Name = p.top[0].Name
+			l.Fields = p.top[0].Children
+		}
+	}
+	l.root = &Field{Name: l.Name, Children: l.Fields}
+	if err := p.resolve(l.root, Display); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// resolve checks f and its subordinate items and computes their sizes.
+// Items are resolved in order, so that the object of an OCCURS
+// DEPENDING ON is resolved before the table.
+func (p *parser) resolve(f *Field, usage Usage) error {
+	if !f.usage {
+		f.Usage = usage
+	}
+	if f.DependingOn != "" {
+		obj, n := p.lookup(f.DependingOn)
+		switch {
+		case n == 0:
+			return p.errorf(f.line, "OCCURS DEPENDING ON object %s not defined before %s", f.DependingOn, f.Name)
+		case n > 1:
+			return p.errorf(f.line, "OCCURS DEPENDING ON object %s is ambiguous", f.DependingOn)
+		case obj.group() || obj.alpha || obj.Usage == Comp1 || obj.Usage == Comp2 || obj.Scale > 0 || obj.Digits > 18:
+			return p.errorf(f.line, "OCCURS DEPENDING ON object %s is not an integer", f.DependingOn)
+		case obj.inTable:
+			return p.errorf(f.line, "OCCURS DEPENDING ON object %s is in a table", f.DependingOn)
+		}
+		obj.object = true
+		// The groups containing the object were resolved
+		// before it was known to be one.
+		for g := obj.parent; g != nil; g = g.parent {
+			g.dynamic = true
+		}
+		f.dependsOn = obj
+		f.dynamic = true
+	}
+	if f.Occurs > 0 {
+		p.inTable++
+		defer func() { p.inTable-- }()
+	}
+	f.inTable = p.inTable > 0
+	if !f.group() {
+		if err := p.elementary(f); err != nil {
+			return err
+		}
+		p.seen[f.Name] = append(p.seen[f.Name], f)
+		return nil
+	}
+
+	if f.Picture != "" {
+		return p.errorf(f.line, "group %s has a picture", f.Name)
+	}
+	slot, end := 0, 0 // start and end of the current storage slot
+	var slotItems []string
+	for _, c := range f.Children {
+		if err := p.resolve(c, f.Usage); err != nil {
+			return err
+		}
+		if c.Redefines == "" {
+			slot = f.Size
+			slotItems = slotItems[:0]
+		} else {
+			ok := false
+			for _, name := range slotItems {
+				ok = ok || name == c.Redefines
+			}
+			if !ok {
+				return p.errorf(c.line, "%s redefines %s, which does not precede it", c.Name, c.Redefines)
+			}
+		}
+		slotItems = append(slotItems, c.Name)
+		if end = slot + c.total(); end > f.Size {
+			f.Size = end
+		}
+		f.dynamic = f.dynamic || c.dynamic || c.object
+	}
+	p.seen[f.Name] = append(p.seen[f.Name], f)
+	return nil
+}
+
+// lookup returns the item named by ref, a data name optionally
+// qualified as in "CNT OF HDR OF REC", among the items resolved so
+// far, and the number of items that match it. Each qualifier names a
+// group containing the item or the previous qualifier.
+func (p *parser) lookup(ref string) (*Field, int) {
+	names := strings.Split(ref, " OF ")
+	var obj *Field
+	n := 0
+	for _, f := range p.seen[names[0]] {
+		g, q := f.parent, names[1:]
+		for ; g != nil && len(q) > 0; g = g.parent {
+			if g.Name == q[0] {
+				q = q[1:]
+			}
+		}
+		if len(q) == 0 {
+			obj = f
+			n++
+		}
+	}
+	return obj, n
+}
+
+// elementary checks the picture and usage of an elementary item and
+// computes its size.
+func (p *parser) elementary(f *Field) error {
+	if f.Usage == Comp1 || f.Usage == Comp2 {
+		if f.Picture != "" {
+			return p.errorf(f.line, "%s item %s has a picture", f.Usage, f.Name)
+		}
+		f.Size = 4
+		if f.Usage == Comp2 {
+			f.Size = 8
+		}
+		return nil
+	}
+	if f.Picture == "" {
+		return p.errorf(f.line, "elementary item %s has no picture", f.Name)
+	}
+	if !f.parsePicture() {
+		return p.errorf(f.line, "invalid or unsupported picture %s for %s", f.Picture, f.Name)
+	}
+	if f.alpha {
+		if f.Usage != Display {
+			return p.errorf(f.line, "%s item %s is not numeric", f.Usage, f.Name)
+		}
+		return nil
+	}
+	switch f.Usage {
+	case Display:
+		f.Size = f.Digits
+	case Comp3:
+		f.Size = f.Digits/2 + 1
+	case Comp, Comp5:
+		switch {
+		case f.Digits <= 4:
+			f.Size = 2
+		case f.Digits <= 9:
+			f.Size = 4
+		case f.Digits <= 18:
+			f.Size = 8
+		default:
+			return p.errorf(f.line, "binary item %s has more than 18 digits", f.Name)
+		}
+	}
+	return nil
+}
+
+// parsePicture parses the PICTURE character string of an elementary
+// item and sets its digits and scale and, for a text item, its size.
+// It reports whether the picture is valid.
+func (f *Field) parsePicture() bool {
+	var text, edited, point bool
+	pic := f.Picture
+	for i := 0; i < len(pic); i++ {
+		c := pic[i]
+		n := 1
+		if i+1 < len(pic) && pic[i+1] == '(' {
+			j := strings.IndexByte(pic[i:], ')')
+			if j < 0 {
+				return false
+			}
+			var err error
+			if n, err = strconv.Atoi(pic[i+2 : i+j]); err != nil || n < 1 {
+				return false
+			}
+			i += j
+		}
+		switch c {
+		case '9':
+			f.Digits += n
+			if point {
+				f.Scale += n
+			}
+			f.Size += n
+		case 'S':
+			if i > 0 || n > 1 {
+				return false
+			}
+			f.Signed = true
+		case 'V':
+			if point || n > 1 {
+				return false
+			}
+			point = true
+		case 'X', 'A':
+			text = true
+			f.Size += n
+		case 'Z', '*', 'B', '0', '/', ',', '.', '+', '-', '$':
+			edited = true
+			f.Size += n
+		case 'C', 'D':
+			// CR and DB.
+			if i+1 == len(pic) || pic[i:i+2] != "CR" && pic[i:i+2] != "DB" {
+				return false
+			}
+			i++
+			edited = true
+			f.Size += 2
+		default:
+			return false
+		}
+	}
+	switch {
+	case text || edited:
+		if f.Signed || point && text {
+			return false
+		}
+		f.alpha = true
+		f.Digits, f.Scale = 0, 0
+	case f.Digits == 0 || f.Digits > maxDigits:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,279 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package copybook
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var parseTests = []struct {
+	Name  string
+	Input string
+
+	Record string // name of the record
+	Output string // items, as formatted by dump
+	Size   int
+	Error  string
+}{
+	{
+		Name:   "Customer",
+		Input:  customerCopybook,
+		Record: "CUSTOMER-REC",
+		Output: "CUST-ID(6) CUST-NAME(20) BALANCE(5) ORDER-COUNT(2) ORDERS(11)*0-5{ORDER-ID(4) AMOUNT(7)}",
+		Size:   88,
+	},
+	{
+		Name: "Redefines",
+		Input: `
+       01  REC.
+           05  KIND       PIC X.
+           05  BODY       PIC X(10).
+           05  NUM-BODY   REDEFINES BODY.
+               10  N      PIC S9(5) COMP-3 OCCURS 3.
+           05  TAIL       PIC X(2).
+`,
+		Record: "REC",
+		Output: "KIND(1) BODY(10) NUM-BODY(9){N(3)*3} TAIL(2)",
+		Size:   13,
+	},
+	{
+		Name: "RedefinesLonger",
+		Input: `
+       01  REC.
+           05  A          PIC X(2).
+           05  B          REDEFINES A PIC X(4).
+           05  C          REDEFINES A PIC 9(3).
+           05  D          PIC X.
+`,
+		Record: "REC",
+		Output: "A(2) B(4) C(3) D(1)",
+		Size:   5,
+	},
+	{
+		Name: "Usages",
+		Input: "000100 01  REC." + strings.Repeat(" ", 57) + "SEQ00001\n" + `
+000200*    Comment line.
+000300     05  FLAG      PIC X VALUE 'Y'.
+000400         88  FLAG-ON VALUE 'Y' 'y'.
+000500     05  NUMS      COMP.
+000600         10  B1    PIC S9(4).
+000700         10  B2    PIC 9(9) VALUE ZERO.
+000800         10  B3    PIC S9(18).
+000900     05  F1        COMP-1.
+001000     05  F2        USAGE IS COMPUTATIONAL-2.
+001100     05  ED        PIC ZZ,ZZ9.99-.
+001200     05  PIC X(3).
+001300     05  P         PICTURE IS S9(5)V9(2) PACKED-DECIMAL
+001400                   SIGN IS TRAILING.
+001500     05  Z         PIC 9(3) BLANK WHEN ZERO.
+`,
+		Record: "REC",
+		Output: "FLAG(1) NUMS(14){B1(2) B2(4) B3(8)} F1(4) F2(8) ED(10) FILLER(3) P(4) Z(3)",
+		Size:   47,
+	},
+	{
+		Name: "NoRecord",
+		Input: `
+       05  A PIC X(2).
+       05  B PIC S9(3).
+`,
+		Output: "A(2) B(3)",
+		Size:   5,
+	},
+	{
+		Name: "Continuation",
+		Input: `
+       01  REC.
+           05  T  PIC X(5) VALUE 'ABCDEFGHIJKLMNOPQRSTUVWXYZ.ABCDEFGH
+      -    'IJ. '.
+           05  U  PIC X.
+`,
+		Record: "REC",
+		Output: "T(5) U(1)",
+		Size:   6,
+	},
+	{
+		Name: "Nested",
+		Input: `
+       01  REC.
+           05  N         PIC 9(2).
+           05  ROWS      OCCURS 2.
+               10  COLS  OCCURS 3 TIMES INDEXED BY IX.
+                   15  C PIC X.
+`,
+		Record: "REC",
+		Output: "N(2) ROWS(3)*2{COLS(1)*3{C(1)}}",
+		Size:   8,
+	},
+	{
+		Name:  "TwoRecords",
+		Input: "       01  A PIC X(3).\n       01  B PIC X.\n",
+		Error: "copybook: line 2: more than one record",
+	},
+	{
+		Name:  "PictureP",
+		Input: "       05  A PIC 9(3)PP.\n",
+		Error: "copybook: line 1: invalid or unsupported picture 9(3)PP for A",
+	},
+	{
+		Name:  "Sync",
+		Input: "       05  A PIC S9(4) COMP SYNC.\n",
+		Error: "copybook: line 1: SYNCHRONIZED is not supported",
+	},
+	{
+		Name:  "SignSeparate",
+		Input: "       05  A PIC S9(4) SIGN TRAILING SEPARATE.\n",
+		Error: "copybook: line 1: only SIGN TRAILING is supported",
+	},
+	{
+		Name:  "UndefinedObject",
+		Input: "       05  T PIC X OCCURS 1 TO 3 DEPENDING ON N.\n",
+		Error: "copybook: line 1: OCCURS DEPENDING ON object N not defined before T",
+	},
+	{
+		Name:  "ObjectNotInteger",
+		Input: "       05  N PIC 9V9.\n       05  T PIC X OCCURS 1 TO 3 DEPENDING ON N.\n",
+		Error: "copybook: line 2: OCCURS DEPENDING ON object N is not an integer",
+	},
+	{
+		Name:  "ObjectInTable",
+		Input: "       05  G OCCURS 2.\n         10  N PIC 9.\n       05  T PIC X OCCURS 1 TO 3 DEPENDING ON N.\n",
+		Error: "copybook: line 3: OCCURS DEPENDING ON object N is in a table",
+	},
+	{
+		Name: "QualifiedObject",
+		Input: `
+       01  REC.
+           05  HDR.
+               10  CNT    PIC 9.
+           05  TRL.
+               10  CNT    PIC 9.
+           05  T          PIC X OCCURS 1 TO 3 DEPENDING ON CNT OF HDR.
+           05  U  PIC X OCCURS 1 TO 3 DEPENDING ON CNT IN TRL IN REC.
+`,
+		Record: "REC",
+		Output: "HDR(1){CNT(1)} TRL(1){CNT(1)} T(1)*1-3 U(1)*1-3",
+		Size:   8,
+	},
+	{
+		Name:  "AmbiguousObject",
+		Input: "       05  G.\n         10  N PIC 9.\n       05  H.\n         10  N PIC 9.\n       05  T PIC X OCCURS 1 TO 3 DEPENDING ON N.\n",
+		Error: "copybook: line 5: OCCURS DEPENDING ON object N is ambiguous",
+	},
+	{
+		Name:  "UndefinedQualifiedObject",
+		Input: "       05  G.\n         10  N PIC 9.\n       05  T PIC X OCCURS 1 TO 3 DEPENDING ON N OF H.\n",
+		Error: "copybook: line 3: OCCURS DEPENDING ON object N OF H not defined before T",
+	},
+	{
+		Name:  "MissingQualifier",
+		Input: "       05  N PIC 9.\n       05  T PIC X OCCURS 1 TO 3 DEPENDING ON N OF.\n",
+		Error: "copybook: line 2: missing qualifier",
+	},
+	{
+		Name:  "RedefinesNotPreceding",
+		Input: "       05 A PIC X.\n       05 B PIC X.\n       05 C REDEFINES A PIC X.\n",
+		Error: "copybook: line 3: C redefines A, which does not precede it",
+	},
+	{
+		Name:  "NoPeriod",
+		Input: "       05 A PIC X\n",
+		Error: "copybook: line 1: entry not terminated by a period",
+	},
+	{
+		Name:  "SubordinateToElementary",
+		Input: "       05 G PIC X.\n          10 H PIC X.\n",
+		Error: "copybook: line 2: H is subordinate to elementary item G",
+	},
+	{
+		Name:  "PackedText",
+		Input: "       05 A PIC X(2) COMP-3.\n",
+		Error: "copybook: line 1: COMP-3 item A is not numeric",
+	},
+	{
+		Name:  "LongBinary",
+		Input: "       05 A PIC 9(19) COMP.\n",
+		Error: "copybook: line 1: binary item A has more than 18 digits",
+	},
+	{
+		Name:  "Unexpected",
+		Input: "       05 A PIC X FOO.\n",
+		Error: `copybook: line 1: unexpected "FOO" in entry for A`,
+	},
+	{
+		Name:  "Renames",
+		Input: "       05 A PIC X.\n       66 B RENAMES A.\n",
+		Error: "copybook: line 2: RENAMES is not supported",
+	},
+	{
+		Name:  "UnterminatedLiteral",
+		Input: "       05 A PIC X VALUE 'A.\n       05 B PIC X.\n",
+		Error: "copybook: line 1: unterminated literal",
+	},
+	{
+		Name:  "Empty",
+		Input: "      * Nothing here.\n",
+		Error: "copybook: line 1: no data description entries",
+	},
+}
+
+// dump formats fields as name(size), followed by the range of
+// occurrences and the subordinate items.
+func dump(fields []*Field) string {
+	var s []string
+	for _, f := range fields {
+		d := fmt.Sprintf("%s(%d)", f.Name, f.Size)
+		switch {
+		case f.DependingOn != "":
+			d += fmt.Sprintf("*%d-%d", f.MinOccurs, f.Occurs)
+		case f.Occurs > 0:
+			d += fmt.Sprintf("*%d", f.Occurs)
+		}
+		if f.group() {
+			d += "{" + dump(f.Children) + "}"
+		}
+		s = append(s, d)
+	}
+	return strings.Join(s, " ")
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range parseTests {
+		l, err := Parse(strings.NewReader(tt.Input))
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("%s: error %v, want %s", tt.Name, err, tt.Error)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+		if out := dump(l.Fields); l.Name != tt.Record || out != tt.Output {
+			t.Errorf("%s: out=%s %q want %s %q", tt.Name, l.Name, out, tt.Record, tt.Output)
+		}
+		if l.Size() != tt.Size {
+			t.Errorf("%s: Size() = %d, want %d", tt.Name, l.Size(), tt.Size)
+		}
+	}
+}
+
+func TestParseField(t *testing.T) {
+	l, err := Parse(strings.NewReader(customerCopybook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := l.Fields[2]
+	if f.Name != "BALANCE" || f.Picture != "S9(7)V99" || f.Usage != Comp3 || f.Digits != 9 || f.Scale != 2 || !f.Signed {
+		t.Errorf("BALANCE = %+v", f)
+	}
+	f = l.Fields[4]
+	if f.Occurs != 5 || f.MinOccurs != 0 || f.DependingOn != "ORDER-COUNT" {
+		t.Errorf("ORDERS = %+v", f)
+	}
+}
@@ -61,12 +61,16 @@ import (
 // A ParseError is returned for parsing errors.
 // The first line is 1.  The first column is 0.
 type ParseError struct {
-	Line   int   // Line where the error occurred
-	Column int   // Column (rune index) where the error occurred
-	Err    error // The actual error
+	StartLine int   // Line where the field or record containing the error starts
+	Line      int   // Line where the error occurred
+	Column    int   // Column (rune index) where the error occurred
+	Err       error // The actual error
 }
 
 func (e *ParseError) Error() string {
+	if e.StartLine != 0 && e.StartLine != e.Line {
+		return fmt.Sprintf("line %d, column %d (in quoted field starting on line %d): %s", e.Line, e.Column, e.StartLine, e.Err)
+	}
 	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err)
 }
 
@@ -99,6 +103,11 @@ var (
 // non-doubled quote may appear in a quoted field.
 //
 // If TrimLeadingSpace is true, leading white space in a field is ignored.
+//
+// If ReuseRecord is true, calls to Read may return a slice sharing the
+// backing array of the slice returned by the previous call, to save
+// an allocation per record. By default, each call to Read returns newly
+// allocated memory owned by the caller.
 type Reader struct {
 	Comma            rune // field delimiter (set to ',' by NewReader)
 	Comment          rune // comment character for start of line
@@ -106,10 +115,26 @@ type Reader struct {
 	LazyQuotes       bool // allow lazy quotes
 	TrailingComma    bool // ignored; here for backwards compatibility
 	TrimLeadingSpace bool // trim leading space
+	ReuseRecord      bool // reuse the slice returned by Read
 	line             int
 	column           int
 	r                *bufio.Reader
-	field            bytes.Buffer
+
+	// record holds the unescaped fields of the current record,
+	// one after another. fieldIndexes holds the end of each field
+	// in record, and fieldPositions the position where it starts.
+	record         bytes.Buffer
+	fieldIndexes   []int
+	fieldPositions []position
+	recordLine     int      // line where the current record starts
+	pos            position // start of the field being parsed
+
+	lastRecord []string // record returned by the last call to Read
+}
+
+// position is a line and column in the input, numbered as in ParseError.
+type position struct {
+	line, column int
 }
 
 // NewReader returns a new Reader that reads from r.
@@ -123,17 +148,47 @@ func NewReader(r io.Reader) *Reader {
 // error creates a new ParseError based on err.
 func (r *Reader) error(err error) error {
 	return &ParseError{
-		Line:   r.line,
-		Column: r.column,
-		Err:    err,
+		StartLine: r.pos.line,
+		Line:      r.line,
+		Column:    r.column,
+		Err:       err,
 	}
 }
 
 // Read reads one record from r.  The record is a slice of strings with each
 // string representing one field.
+//
+// If ReuseRecord is true, the returned slice may be overwritten by the
+// next call to Read.
 func (r *Reader) Read() (record []string, err error) {
+	if r.ReuseRecord {
+		record, err = r.readRecord(r.lastRecord)
+		r.lastRecord = record
+	} else {
+		record, err = r.readRecord(nil)
+	}
+	return record, err
+}
+
+// FieldPos returns the line and column where the field with index field
+// starts in the record most recently returned by Read. Lines and columns
+// are numbered as in ParseError: the first line is 1, the first column
+// is 0 and columns count runes. For a quoted field, the position is that
+// of the opening quote.
+//
+// If FieldPos is called with an index out of range, it panics.
+func (r *Reader) FieldPos(field int) (line, column int) {
+	if field < 0 || field >= len(r.fieldPositions) {
+		panic("csv: field index out of range")
+	}
+	p := r.fieldPositions[field]
+	return p.line, p.column
+}
+
+// readRecord reads one record, storing its fields in dst if it has room.
+func (r *Reader) readRecord(dst []string) (record []string, err error) {
 	for {
-		record, err = r.parseRecord()
+		record, err = r.parseRecord(dst)
 		if record != nil {
 			break
 		}
@@ -144,7 +199,9 @@ func (r *Reader) Read() (record []string, err error) {
 
 	if r.FieldsPerRecord > 0 {
 		if len(record) != r.FieldsPerRecord {
-			r.column = 0 // report at start of record
+			// Report at the start of the record.
+			r.line, r.column = r.recordLine, 0
+			r.pos = position{r.recordLine, 0}
 			return record, r.error(ErrFieldCount)
 		}
 	} else if r.FieldsPerRecord == 0 {
@@ -160,7 +217,7 @@ func (r *Reader) Read() (record []string, err error) {
 // reported.
 func (r *Reader) ReadAll() (records [][]string, err error) {
 	for {
-		record, err := r.Read()
+		record, err := r.readRecord(nil)
 		if err == io.EOF {
 			return records, nil
 		}
@@ -207,12 +264,14 @@ func (r *Reader) skip(delim rune) error {
 }
 
 // parseRecord reads and parses a single csv record from r.
-func (r *Reader) parseRecord() (fields []string, err error) {
+// It returns a nil record for a blank or comment line.
+func (r *Reader) parseRecord(dst []string) (fields []string, err error) {
 	// Each record starts on a new line.  We increment our line
 	// number (lines start at 1, not 0) and set column to -1
 	// so as we increment in readRune it points to the character we read.
 	r.line++
 	r.column = -1
+	r.recordLine = r.line
 
 	// Peek at the first rune.  If it is an error we are done.
 	// If we support comments and it is the comment character
@@ -229,34 +288,52 @@ func (r *Reader) parseRecord() (fields []string, err error) {
 	r.r.UnreadRune()
 
 	// At this point we have at least one field.
+	r.record.Reset()
+	r.fieldIndexes = r.fieldIndexes[:0]
+	r.fieldPositions = r.fieldPositions[:0]
 	for {
 		haveField, delim, err := r.parseField()
 		if haveField {
-			// If FieldsPerRecord is greater then 0 we can assume the final
-			// length of fields to be equal to FieldsPerRecord.
-			if r.FieldsPerRecord > 0 && fields == nil {
-				fields = make([]string, 0, r.FieldsPerRecord)
-			}
-			fields = append(fields, r.field.String())
+			r.fieldIndexes = append(r.fieldIndexes, r.record.Len())
+			r.fieldPositions = append(r.fieldPositions, r.pos)
 		}
 		if delim == '\n' || err == io.EOF {
-			return fields, err
+			if len(r.fieldIndexes) == 0 {
+				return nil, err
+			}
+			return r.fields(dst), err
 		} else if err != nil {
 			return nil, err
 		}
 	}
 }
 
+// fields splits the current record into its fields, storing them in dst
+// if it has room. All fields share the memory of a single string.
+func (r *Reader) fields(dst []string) []string {
+	n := len(r.fieldIndexes)
+	if cap(dst) < n {
+		dst = make([]string, n)
+	}
+	dst = dst[:n]
+	str := r.record.String()
+	prev := 0
+	for i, end := range r.fieldIndexes {
+		dst[i] = str[prev:end]
+		prev = end
+	}
+	return dst
+}
+
 // parseField parses the next field in the record.  The read field is
-// located in r.field.  Delim is the first character not part of the field
-// (r.Comma or '\n').
+// appended to r.record and its start is stored in r.pos.  Delim is the
+// first character not part of the field (r.Comma or '\n').
 func (r *Reader) parseField() (haveField bool, delim rune, err error) {
-	r.field.Reset()
-
 	r1, err := r.readRune()
 	for err == nil && r.TrimLeadingSpace && r1 != '\n' && unicode.IsSpace(r1) {
 		r1, err = r.readRune()
 	}
+	r.pos = position{r.line, r.column}
 
 	if err == io.EOF && r.column != 0 {
 		return true, 0, err
@@ -305,19 +382,19 @@ func (r *Reader) parseField() (haveField bool, delim rune, err error) {
 						return false, 0, r.error(ErrQuote)
 					}
 					// accept the bare quote
-					r.field.WriteRune('"')
+					r.record.WriteRune('"')
 				}
 			case '\n':
 				r.line++
 				r.column = -1
 			}
-			r.field.WriteRune(r1)
+			r.record.WriteRune(r1)
 		}
 
 	default:
 		// unquoted field
 		for {
-			r.field.WriteRune(r1)
+			r.record.WriteRune(r1)
 			r1, err = r.readRune()
 			if err != nil || r1 == r.Comma {
 				break
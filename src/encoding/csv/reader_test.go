@@ -5,6 +5,7 @@
 package csv
 
 import (
+	"io"
 	"reflect"
 	"strings"
 	"testing"
@@ -161,6 +162,22 @@ field"`,
 		Input: `"a "word","b"`,
 		Error: `extraneous " in field`, Line: 1, Column: 3,
 	},
+	{
+		Name:  "MultiLineExtraneousQuote",
+		Input: "a,\"b\nc\"d\",e\n",
+		Error: `line 2, column 1 (in quoted field starting on line 1): extraneous " in field`, Line: 2, Column: 1,
+	},
+	{
+		Name:  "UnterminatedQuote",
+		Input: "a,\"b\nc,d\ne",
+		Error: `line 3, column 1 (in quoted field starting on line 1): extraneous " in field`, Line: 3, Column: 1,
+	},
+	{
+		Name:               "MultiLineFieldCount",
+		UseFieldsPerRecord: true,
+		Input:              "a,b\n\"c\nd\"\n",
+		Error:              "line 2, column 0: wrong number of fields", Line: 2,
+	},
 	{
 		Name:               "BadFieldCount",
 		UseFieldsPerRecord: true,
@@ -292,6 +309,60 @@ func TestRead(t *testing.T) {
 	}
 }
 
+func TestFieldPos(t *testing.T) {
+	const input = "a, \"b\nc\",d\n\n x,\"\",\n"
+	want := [][][2]int{
+		{{1, 0}, {1, 3}, {2, 3}},
+		{{4, 1}, {4, 3}, {4, 6}},
+	}
+	r := NewReader(strings.NewReader(input))
+	r.TrimLeadingSpace = true
+	for i, w := range want {
+		record, err := r.Read()
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if len(record) != len(w) {
+			t.Fatalf("record %d: got %d fields, want %d", i, len(record), len(w))
+		}
+		for j, pos := range w {
+			if line, col := r.FieldPos(j); line != pos[0] || col != pos[1] {
+				t.Errorf("record %d: FieldPos(%d) = %d:%d, want %d:%d", i, j, line, col, pos[0], pos[1])
+			}
+		}
+	}
+}
+
+func TestReuseRecord(t *testing.T) {
+	r := NewReader(strings.NewReader("a,b\nc,d\ne\n"))
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	out, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}; !reflect.DeepEqual(out, want) {
+		t.Errorf("ReadAll with ReuseRecord: out=%q want %q", out, want)
+	}
+
+	r = NewReader(strings.NewReader("a,b\nc,d\n"))
+	r.ReuseRecord = true
+	first, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &first[0] != &second[0] {
+		t.Error("Read with ReuseRecord did not reuse the record slice")
+	}
+	if want := []string{"c", "d"}; !reflect.DeepEqual(second, want) {
+		t.Errorf("second record: out=%q want %q", second, want)
+	}
+}
+
 func BenchmarkRead(b *testing.B) {
 	data := `x,y,z,w
 x,y,z,
@@ -313,3 +384,20 @@ x,,,
 		}
 	}
 }
+
+func BenchmarkReadReuseRecord(b *testing.B) {
+	data := strings.Repeat("x,y,z,w\n\"x\",\"y\",\"z\",\"\"\n", 5)
+	for i := 0; i < b.N; i++ {
+		r := NewReader(strings.NewReader(data))
+		r.ReuseRecord = true
+		for {
+			_, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatalf("could not read data: %s", err)
+			}
+		}
+	}
+}
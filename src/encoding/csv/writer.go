@@ -6,6 +6,7 @@ package csv
 
 import (
 	"bufio"
+	"errors"
 	"io"
 	"strings"
 	"unicode"
@@ -21,12 +22,37 @@ import (
 // Comma is the field delimiter.
 //
 // If UseCRLF is true, the Writer ends each record with \r\n instead of \n.
+//
+// Quote selects which fields are enclosed in quotes.
 type Writer struct {
-	Comma   rune // Field delimiter (set to ',' by NewWriter)
-	UseCRLF bool // True to use \r\n as the line terminator
+	Comma   rune       // Field delimiter (set to ',' by NewWriter)
+	UseCRLF bool       // True to use \r\n as the line terminator
+	Quote   QuoteStyle // Which fields to quote (QuoteMinimal by default)
 	w       *bufio.Writer
 }
 
+// A QuoteStyle selects which fields a Writer encloses in quotes.
+type QuoteStyle int
+
+const (
+	// QuoteMinimal quotes only the fields that need it: fields
+	// containing Comma, a quote, \r or \n, fields starting with
+	// white space, and the Postgres end-of-data marker `\.`.
+	QuoteMinimal QuoteStyle = iota
+
+	// QuoteAll quotes every field, including empty fields.
+	QuoteAll
+
+	// QuoteNone never quotes a field. Write returns ErrQuoteNone
+	// for a field containing Comma, a quote, \r or \n, since the
+	// record could not be read back.
+	QuoteNone
+)
+
+// ErrQuoteNone is returned by Write when the Writer's Quote is QuoteNone
+// and a field cannot be written without quotes.
+var ErrQuoteNone = errors.New("csv: field cannot be written without quotes")
+
 // NewWriter returns a new Writer that writes to w.
 func NewWriter(w io.Writer) *Writer {
 	return &Writer{
@@ -38,6 +64,13 @@ func NewWriter(w io.Writer) *Writer {
 // Writer writes a single CSV record to w along with any necessary quoting.
 // A record is a slice of strings with each string being one field.
 func (w *Writer) Write(record []string) (err error) {
+	if w.Quote == QuoteNone {
+		for _, field := range record {
+			if w.fieldHasSpecial(field) {
+				return ErrQuoteNone
+			}
+		}
+	}
 	for n, field := range record {
 		if n > 0 {
 			if _, err = w.w.WriteRune(w.Comma); err != nil {
@@ -114,8 +147,8 @@ func (w *Writer) WriteAll(records [][]string) (err error) {
 	return w.w.Flush()
 }
 
-// fieldNeedsQuotes reports whether our field must be enclosed in quotes.
-// Fields with a Comma, fields with a quote or newline, and
+// fieldNeedsQuotes reports whether our field must be enclosed in quotes,
+// according to w.Quote. With QuoteMinimal, fields with a Comma, fields with a quote or newline, and
 // fields which start with a space must be enclosed in quotes.
 // We used to quote empty strings, but we do not anymore (as of Go 1.4).
 // The two representations should be equivalent, but Postgres distinguishes
@@ -127,13 +160,25 @@ func (w *Writer) WriteAll(records [][]string) (err error) {
 // of Microsoft Excel and Google Drive.
 // For Postgres, quote the data terminating string `\.`.
 func (w *Writer) fieldNeedsQuotes(field string) bool {
+	switch w.Quote {
+	case QuoteAll:
+		return true
+	case QuoteNone:
+		return false
+	}
 	if field == "" {
 		return false
 	}
-	if field == `\.` || strings.IndexRune(field, w.Comma) >= 0 || strings.IndexAny(field, "\"\r\n") >= 0 {
+	if field == `\.` || w.fieldHasSpecial(field) {
 		return true
 	}
 
 	r1, _ := utf8.DecodeRuneInString(field)
 	return unicode.IsSpace(r1)
 }
+
+// fieldHasSpecial reports whether field contains Comma, a quote or
+// a line break, which can only be written inside a quoted field.
+func (w *Writer) fieldHasSpecial(field string) bool {
+	return strings.IndexRune(field, w.Comma) >= 0 || strings.IndexAny(field, "\"\r\n") >= 0
+}
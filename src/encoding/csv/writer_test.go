@@ -14,6 +14,7 @@ var writeTests = []struct {
 	Input   [][]string
 	Output  string
 	UseCRLF bool
+	Quote   QuoteStyle
 }{
 	{Input: [][]string{{"abc"}}, Output: "abc\n"},
 	{Input: [][]string{{"abc"}}, Output: "abc\r\n", UseCRLF: true},
@@ -39,6 +40,8 @@ var writeTests = []struct {
 	{Input: [][]string{{"a", "a", ""}}, Output: "a,a,\n"},
 	{Input: [][]string{{"a", "a", "a"}}, Output: "a,a,a\n"},
 	{Input: [][]string{{`\.`}}, Output: "\"\\.\"\n"},
+	{Input: [][]string{{"a", "", " b", `c"d`}}, Output: `"a",""," b","c""d"` + "\n", Quote: QuoteAll},
+	{Input: [][]string{{"a", "", " b", `\.`}}, Output: "a,, b,\\.\n", Quote: QuoteNone},
 }
 
 func TestWrite(t *testing.T) {
@@ -46,6 +49,7 @@ func TestWrite(t *testing.T) {
 		b := &bytes.Buffer{}
 		f := NewWriter(b)
 		f.UseCRLF = tt.UseCRLF
+		f.Quote = tt.Quote
 		err := f.WriteAll(tt.Input)
 		if err != nil {
 			t.Errorf("Unexpected error: %s\n", err)
@@ -83,3 +87,18 @@ func TestError(t *testing.T) {
 		t.Error("Error should not be nil")
 	}
 }
+
+func TestWriteQuoteNone(t *testing.T) {
+	for _, field := range []string{"a,b", `a"b`, "a\nb", "a\rb"} {
+		b := &bytes.Buffer{}
+		f := NewWriter(b)
+		f.Quote = QuoteNone
+		if err := f.Write([]string{"x", field}); err != ErrQuoteNone {
+			t.Errorf("Write(%q) = %v, want ErrQuoteNone", field, err)
+		}
+		f.Flush()
+		if b.Len() != 0 {
+			t.Errorf("Write(%q) wrote %q", field, b.String())
+		}
+	}
+}
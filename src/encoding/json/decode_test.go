@@ -1379,6 +1379,35 @@ func TestSkipArrayObjects(t *testing.T) {
 	}
 }
 
+func TestDisallowUnknownFields(t *testing.T) {
+	var dest struct {
+		A int
+		B string `json:"b"`
+		C int    `json:"-"`
+	}
+	for _, tt := range []struct {
+		in  string
+		err string
+	}{
+		{in: `{"A":1,"b":"x"}`},
+		{in: `{"A":1,"D":2}`, err: `json: unknown field "D"`},
+		{in: `{"C":3}`, err: `json: unknown field "C"`},
+	} {
+		dec := NewDecoder(strings.NewReader(tt.in))
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&dest)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("Decode(%s): error %v, want %q", tt.in, err, tt.err)
+		}
+	}
+	var m map[string]int
+	dec := NewDecoder(strings.NewReader(`{"D":2}`))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		t.Errorf("Decode into map: %v", err)
+	}
+}
+
 // Test semantics of pre-filled struct fields and pre-filled map fields.
 // Issue 4900.
 func TestPrefilled(t *testing.T) {
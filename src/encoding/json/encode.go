@@ -49,6 +49,8 @@ import (
 // The angle brackets "<" and ">" are escaped to "\u003c" and "\u003e"
 // to keep some browsers from misinterpreting JSON output as HTML.
 // Ampersand "&" is also escaped to "\u0026" for the same reason.
+// This escaping can be disabled using an Encoder that had SetEscapeHTML(false)
+// called on it.
 //
 // Array and slice values encode as JSON arrays, except that
 // []byte encodes as a base64-encoded string, and a nil slice
@@ -136,7 +138,7 @@ import (
 //
 func Marshal(v interface{}) ([]byte, error) {
 	e := &encodeState{}
-	err := e.marshal(v)
+	err := e.marshal(v, true)
 	if err != nil {
 		return nil, err
 	}
@@ -246,6 +248,7 @@ var hex = "0123456789abcdef"
 type encodeState struct {
 	bytes.Buffer // accumulated output
 	scratch      [64]byte
+	escapeHTML   bool // escape <, > and & in strings
 }
 
 var encodeStatePool sync.Pool
@@ -259,7 +262,7 @@ func newEncodeState() *encodeState {
 	return new(encodeState)
 }
 
-func (e *encodeState) marshal(v interface{}) (err error) {
+func (e *encodeState) marshal(v interface{}, escapeHTML bool) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if _, ok := r.(runtime.Error); ok {
@@ -271,6 +274,7 @@ func (e *encodeState) marshal(v interface{}) (err error) {
 			err = r.(error)
 		}
 	}()
+	e.escapeHTML = escapeHTML
 	e.reflectValue(reflect.ValueOf(v))
 	return nil
 }
@@ -352,11 +356,20 @@ func typeEncoder(t reflect.Type) encoderFunc {
 var (
 	marshalerType     = reflect.TypeOf(new(Marshaler)).Elem()
 	textMarshalerType = reflect.TypeOf(new(encoding.TextMarshaler)).Elem()
+	rawMessageType    = reflect.TypeOf(RawMessage(nil))
 )
 
 // newTypeEncoder constructs an encoderFunc for a type.
 // The returned encoder only checks CanAddr when allowAddr is true.
 func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
+	// RawMessage is copied to the output without calling MarshalJSON,
+	// which would validate and compact it again.
+	switch t {
+	case rawMessageType:
+		return rawMessageEncoder
+	case reflect.PtrTo(rawMessageType):
+		return newPtrEncoder(t)
+	}
 	if t.Implements(marshalerType) {
 		return marshalerEncoder
 	}
@@ -418,13 +431,26 @@ func marshalerEncoder(e *encodeState, v reflect.Value, quoted bool) {
 	b, err := m.MarshalJSON()
 	if err == nil {
 		// copy JSON into buffer, checking validity.
-		err = compact(&e.Buffer, b, true)
+		err = compact(&e.Buffer, b, e.escapeHTML)
 	}
 	if err != nil {
 		e.error(&MarshalerError{v.Type(), err})
 	}
 }
 
+func rawMessageEncoder(e *encodeState, v reflect.Value, quoted bool) {
+	b := v.Bytes()
+	if len(b) == 0 {
+		e.WriteString("null")
+		return
+	}
+	if e.escapeHTML {
+		HTMLEscape(&e.Buffer, b)
+	} else {
+		e.Write(b)
+	}
+}
+
 func addrMarshalerEncoder(e *encodeState, v reflect.Value, quoted bool) {
 	va := v.Addr()
 	if va.IsNil() {
@@ -435,7 +461,7 @@ func addrMarshalerEncoder(e *encodeState, v reflect.Value, quoted bool) {
 	b, err := m.MarshalJSON()
 	if err == nil {
 		// copy JSON into buffer, checking validity.
-		err = compact(&e.Buffer, b, true)
+		err = compact(&e.Buffer, b, e.escapeHTML)
 	}
 	if err != nil {
 		e.error(&MarshalerError{v.Type(), err})
@@ -791,7 +817,7 @@ func (e *encodeState) string(s string) int {
 	start := 0
 	for i := 0; i < len(s); {
 		if b := s[i]; b < utf8.RuneSelf {
-			if 0x20 <= b && b != '\\' && b != '"' && b != '<' && b != '>' && b != '&' {
+			if 0x20 <= b && b != '\\' && b != '"' && !(e.escapeHTML && (b == '<' || b == '>' || b == '&')) {
 				i++
 				continue
 			}
@@ -813,9 +839,10 @@ func (e *encodeState) string(s string) int {
 				e.WriteByte('t')
 			default:
 				// This encodes bytes < 0x20 except for \n and \r,
-				// as well as <, > and &. The latter are escaped because they
-				// can lead to security holes when user-controlled strings
-				// are rendered into JSON and served to some browsers.
+				// as well as <, > and & when escapeHTML is set. The latter
+				// are escaped because they can lead to security holes when
+				// user-controlled strings are rendered into JSON and served
+				// to some browsers.
 				e.WriteString(`\u00`)
 				e.WriteByte(hex[b>>4])
 				e.WriteByte(hex[b&0xF])
@@ -867,7 +894,7 @@ func (e *encodeState) stringBytes(s []byte) int {
 	start := 0
 	for i := 0; i < len(s); {
 		if b := s[i]; b < utf8.RuneSelf {
-			if 0x20 <= b && b != '\\' && b != '"' && b != '<' && b != '>' && b != '&' {
+			if 0x20 <= b && b != '\\' && b != '"' && !(e.escapeHTML && (b == '<' || b == '>' || b == '&')) {
 				i++
 				continue
 			}
@@ -889,9 +916,10 @@ func (e *encodeState) stringBytes(s []byte) int {
 				e.WriteByte('t')
 			default:
 				// This encodes bytes < 0x20 except for \n and \r,
-				// as well as <, >, and &. The latter are escaped because they
-				// can lead to security holes when user-controlled strings
-				// are rendered into JSON and served to some browsers.
+				// as well as <, >, and & when escapeHTML is set. The latter
+				// are escaped because they can lead to security holes when
+				// user-controlled strings are rendered into JSON and served
+				// to some browsers.
 				e.WriteString(`\u00`)
 				e.WriteByte(hex[b>>4])
 				e.WriteByte(hex[b&0xF])
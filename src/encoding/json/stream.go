@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"strings"
 )
 
 // A Decoder reads and decodes JSON objects from an input stream.
@@ -35,6 +36,11 @@ func NewDecoder(r io.Reader) *Decoder {
 // Number instead of as a float64.
 func (dec *Decoder) UseNumber() { dec.d.useNumber = true }
 
+// DisallowUnknownFields causes the Decoder to return an error when the destination
+// is a struct and the input contains object keys which do not match any
+// non-ignored, exported fields in the destination.
+func (dec *Decoder) DisallowUnknownFields() { dec.d.disallowUnknownFields = true }
+
 // Decode reads the next JSON-encoded value from its
 // input and stores it in the value pointed to by v.
 //
@@ -166,53 +172,240 @@ func nonSpace(b []byte) bool {
 
 // An Encoder writes JSON objects to an output stream.
 type Encoder struct {
-	w   io.Writer
-	err error
+	w          io.Writer
+	err        error
+	escapeHTML bool
+	buf        bytes.Buffer // output of the current call
+
+	indentPrefix string
+	indentValue  string
+
+	tokenState int
+	tokenStack []int
 }
 
 // NewEncoder returns a new encoder that writes to w.
 func NewEncoder(w io.Writer) *Encoder {
-	return &Encoder{w: w}
+	return &Encoder{w: w, escapeHTML: true}
 }
 
 // Encode writes the JSON encoding of v to the stream,
 // followed by a newline character.
 //
+// Inside an array or object opened by EncodeToken, Encode writes v
+// as the next element or object value and the newline is written
+// only after the outermost array or object is closed.
+//
 // See the documentation for Marshal for details about the
 // conversion of Go values to JSON.
 func (enc *Encoder) Encode(v interface{}) error {
 	if enc.err != nil {
 		return enc.err
 	}
+	if !enc.tokenValueAllowed() {
+		return enc.tokenError("value")
+	}
 	e := newEncodeState()
-	err := e.marshal(v)
+	err := e.marshal(v, enc.escapeHTML)
 	if err != nil {
 		return err
 	}
 
-	// Terminate each value with a newline.
-	// This makes the output look a little nicer
-	// when debugging, and some kind of space
-	// is required if the encoded value was a number,
-	// so that the reader knows there aren't more
-	// digits coming.
-	e.WriteByte('\n')
+	enc.buf.Reset()
+	enc.tokenPrepareForValue()
+	if enc.indentPrefix != "" || enc.indentValue != "" {
+		prefix := enc.indentPrefix + strings.Repeat(enc.indentValue, len(enc.tokenStack))
+		err = Indent(&enc.buf, e.Bytes(), prefix, enc.indentValue)
+	} else {
+		_, err = enc.buf.Write(e.Bytes())
+	}
+	encodeStatePool.Put(e)
+	if err != nil {
+		return err
+	}
+	enc.tokenValueEnd()
+	return enc.flush()
+}
 
-	if _, err = enc.w.Write(e.Bytes()); err != nil {
+// flush writes the output of the current call to the stream.
+func (enc *Encoder) flush() error {
+	if _, err := enc.w.Write(enc.buf.Bytes()); err != nil {
 		enc.err = err
+		return err
 	}
-	encodeStatePool.Put(e)
-	return err
+	return nil
+}
+
+// SetIndent instructs the encoder to format each subsequent encoded
+// value as if indented by the package-level function Indent(dst, src, prefix, indent).
+// Calling SetIndent("", "") disables indentation.
+func (enc *Encoder) SetIndent(prefix, indent string) {
+	enc.indentPrefix = prefix
+	enc.indentValue = indent
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters
+// should be escaped inside JSON quoted strings.
+// The default behavior is to escape &, <, and > to \u0026, \u003c, and \u003e
+// to avoid certain safety problems that can arise when embedding JSON in HTML.
+//
+// In non-HTML settings where the escaping interferes with the readability
+// of the output, SetEscapeHTML(false) disables this behavior.
+func (enc *Encoder) SetEscapeHTML(on bool) {
+	enc.escapeHTML = on
+}
+
+// EncodeToken writes the given JSON token to the stream.
+// It returns an error if the delimiters [ ] { } are not properly used
+// or if a value other than a string is given where an object key is
+// expected.
+//
+// A Delim opens or closes an array or object, a string is written as
+// an object key when one is expected, and any other value is written
+// as by Encode. Together with Encode, EncodeToken makes it possible to
+// write a large array or object one element at a time. The Encoder
+// writes the commas and colons between elements, and indents the
+// output as set by SetIndent.
+func (enc *Encoder) EncodeToken(t Token) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	enc.buf.Reset()
+	switch t := t.(type) {
+	case Delim:
+		switch t {
+		case '[', '{':
+			if !enc.tokenValueAllowed() {
+				return enc.tokenError(string(t))
+			}
+			enc.tokenPrepareForValue()
+			enc.buf.WriteByte(byte(t))
+			enc.tokenStack = append(enc.tokenStack, enc.tokenState)
+			if t == '[' {
+				enc.tokenState = tokenArrayStart
+			} else {
+				enc.tokenState = tokenObjectStart
+			}
+
+		case ']', '}':
+			var empty bool
+			switch {
+			case t == ']' && enc.tokenState == tokenArrayStart,
+				t == '}' && enc.tokenState == tokenObjectStart:
+				empty = true
+			case t == ']' && enc.tokenState == tokenArrayComma,
+				t == '}' && enc.tokenState == tokenObjectComma:
+			default:
+				return enc.tokenError(string(t))
+			}
+			enc.tokenState = enc.tokenStack[len(enc.tokenStack)-1]
+			enc.tokenStack = enc.tokenStack[:len(enc.tokenStack)-1]
+			if !empty {
+				enc.newline()
+			}
+			enc.buf.WriteByte(byte(t))
+			enc.tokenValueEnd()
+
+		default:
+			return enc.tokenError("delimiter " + string(t))
+		}
+
+	case string:
+		if enc.tokenState != tokenObjectStart && enc.tokenState != tokenObjectComma {
+			return enc.Encode(t)
+		}
+		if enc.tokenState == tokenObjectComma {
+			enc.buf.WriteByte(',')
+		}
+		enc.newline()
+		e := newEncodeState()
+		e.escapeHTML = enc.escapeHTML
+		e.string(t)
+		enc.buf.Write(e.Bytes())
+		encodeStatePool.Put(e)
+		enc.tokenState = tokenObjectColon
+
+	default:
+		return enc.Encode(t)
+	}
+	return enc.flush()
+}
+
+func (enc *Encoder) tokenValueAllowed() bool {
+	switch enc.tokenState {
+	case tokenTopValue, tokenArrayStart, tokenArrayComma, tokenObjectColon:
+		return true
+	}
+	return false
+}
+
+// tokenPrepareForValue writes the separator before a value.
+func (enc *Encoder) tokenPrepareForValue() {
+	switch enc.tokenState {
+	case tokenArrayComma:
+		enc.buf.WriteByte(',')
+		fallthrough
+	case tokenArrayStart:
+		enc.newline()
+	case tokenObjectColon:
+		enc.buf.WriteByte(':')
+		if enc.indentPrefix != "" || enc.indentValue != "" {
+			enc.buf.WriteByte(' ')
+		}
+	}
+}
+
+// tokenValueEnd updates the token state after a value and ends
+// each top-level value with a newline.
+func (enc *Encoder) tokenValueEnd() {
+	switch enc.tokenState {
+	case tokenTopValue:
+		enc.buf.WriteByte('\n')
+	case tokenArrayStart:
+		enc.tokenState = tokenArrayComma
+	case tokenObjectColon:
+		enc.tokenState = tokenObjectComma
+	}
+}
+
+// newline starts a new line indented for the current nesting, if
+// indentation is enabled.
+func (enc *Encoder) newline() {
+	if enc.indentPrefix != "" || enc.indentValue != "" {
+		newline(&enc.buf, enc.indentPrefix, enc.indentValue, len(enc.tokenStack))
+	}
+}
+
+func (enc *Encoder) tokenError(what string) error {
+	var context string
+	switch enc.tokenState {
+	case tokenTopValue:
+		context = " outside of array or object"
+	case tokenObjectStart, tokenObjectComma:
+		context = " looking for object key string"
+	case tokenObjectColon:
+		context = " looking for object value"
+	default:
+		context = " looking for array element"
+	}
+	return errors.New("json: unexpected " + what + context)
 }
 
 // RawMessage is a raw encoded JSON object.
 // It implements Marshaler and Unmarshaler and can
 // be used to delay JSON decoding or precompute a JSON encoding.
+//
+// Marshal and Encoder copy a RawMessage to the output as it is,
+// without validating or compacting it again, so it must hold valid
+// JSON. An empty RawMessage is encoded as null.
 type RawMessage []byte
 
-// MarshalJSON returns *m as the JSON encoding of m.
-func (m *RawMessage) MarshalJSON() ([]byte, error) {
-	return *m, nil
+// MarshalJSON returns m as the JSON encoding of m.
+func (m RawMessage) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+	return m, nil
 }
 
 // UnmarshalJSON sets *m to a copy of data.
@@ -461,20 +654,3 @@ func (dec *Decoder) peek() (byte, error) {
 		err = dec.refill()
 	}
 }
-
-/*
-TODO
-
-// EncodeToken writes the given JSON token to the stream.
-// It returns an error if the delimiters [ ] { } are not properly used.
-//
-// EncodeToken does not call Flush, because usually it is part of
-// a larger operation such as Encode, and those will call Flush when finished.
-// Callers that create an Encoder and then invoke EncodeToken directly,
-// without using Encode, need to call Flush when finished to ensure that
-// the JSON is written to the underlying writer.
-func (e *Encoder) EncodeToken(t Token) error  {
-	...
-}
-
-*/
@@ -57,6 +57,169 @@ func TestEncoder(t *testing.T) {
 	}
 }
 
+var streamEncodedIndent = `0.1
+"hello"
+null
+true
+false
+[
+>."a",
+>."b",
+>."c"
+>]
+{
+>."ß": "long s",
+>."K": "Kelvin"
+>}
+3.14
+`
+
+func TestEncoderIndent(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetIndent(">", ".")
+	for _, v := range streamTest {
+		enc.Encode(v)
+	}
+	if have, want := buf.String(), streamEncodedIndent; have != want {
+		t.Error("indented encoding mismatch")
+		diff(t, []byte(have), []byte(want))
+	}
+}
+
+func TestEncoderSetEscapeHTML(t *testing.T) {
+	var c C
+	var ct CText
+	var tagStruct struct {
+		Valid   int `json:"<>&#! "`
+		Invalid int `json:"\\"`
+	}
+	for _, tt := range []struct {
+		name       string
+		v          interface{}
+		wantEscape string
+		want       string
+	}{
+		{"c", c, `"\u003c\u0026\u003e"`, `"<&>"`},
+		{"ct", ct, `"\"\u003c\u0026\u003e\""`, `"\"<&>\""`},
+		{`"<&>"`, "<&>", `"\u003c\u0026\u003e"`, `"<&>"`},
+		{"rawMessage", RawMessage(`["<&>"]`), `["\u003c\u0026\u003e"]`, `["<&>"]`},
+		{
+			"tagStruct", tagStruct,
+			`{"\u003c\u003e\u0026#! ":0,"Invalid":0}`,
+			`{"<>&#! ":0,"Invalid":0}`,
+		},
+	} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		if err := enc.Encode(tt.v); err != nil {
+			t.Fatalf("Encode(%s): %s", tt.name, err)
+		}
+		if got := strings.TrimSpace(buf.String()); got != tt.wantEscape {
+			t.Errorf("Encode(%s) = %#q, want %#q", tt.name, got, tt.wantEscape)
+		}
+		buf.Reset()
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(tt.v); err != nil {
+			t.Fatalf("SetEscapeHTML(false) Encode(%s): %s", tt.name, err)
+		}
+		if got := strings.TrimSpace(buf.String()); got != tt.want {
+			t.Errorf("SetEscapeHTML(false) Encode(%s) = %#q, want %#q",
+				tt.name, got, tt.want)
+		}
+	}
+}
+
+var encodeTokenTests = []struct {
+	tokens []interface{} // Tokens, or values passed to Encode
+	indent bool
+	out    string
+	err    string // error from the last token
+}{
+	{tokens: []interface{}{Delim('['), Delim(']')}, out: "[]\n"},
+	{tokens: []interface{}{Delim('{'), Delim('}')}, out: "{}\n"},
+	{tokens: []interface{}{1.0, "a", nil, true}, out: "1\n\"a\"\nnull\ntrue\n"},
+	{
+		tokens: []interface{}{Delim('['), 1.0, Delim('{'), "a", "b", "c", []int{1, 2}, Delim('}'), Number("2"), Delim(']')},
+		out:    `[1,{"a":"b","c":[1,2]},2]` + "\n",
+	},
+	{
+		tokens: []interface{}{Delim('{'), "<", Delim('['), Delim(']'), "e", Delim('{'), Delim('}'), Delim('}')},
+		out:    `{"\u003c":[],"e":{}}` + "\n",
+	},
+	{
+		tokens: []interface{}{Delim('['), 1.0, Delim('{'), "a", map[string]int{"b": 2}, Delim('}'), Delim(']')},
+		indent: true,
+		out:    "[\n\t1,\n\t{\n\t\t\"a\": {\n\t\t\t\"b\": 2\n\t\t}\n\t}\n]\n",
+	},
+	{
+		tokens: []interface{}{Delim('{'), "a", Delim('['), Delim(']'), Delim('}')},
+		indent: true,
+		out:    "{\n\t\"a\": []\n}\n",
+	},
+	{tokens: []interface{}{Delim(']')}, err: "json: unexpected ] outside of array or object"},
+	{tokens: []interface{}{Delim('['), Delim('}')}, err: "json: unexpected } looking for array element"},
+	{tokens: []interface{}{Delim('{'), 1.0}, err: "json: unexpected value looking for object key string"},
+	{tokens: []interface{}{Delim('{'), "a", Delim('}')}, err: "json: unexpected } looking for object value"},
+	{tokens: []interface{}{Delim('{'), "a", 1.0, Delim(']')}, err: "json: unexpected ] looking for object key string"},
+	{tokens: []interface{}{Delim(':')}, err: "json: unexpected delimiter : outside of array or object"},
+}
+
+func TestEncodeToken(t *testing.T) {
+	for i, tt := range encodeTokenTests {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		if tt.indent {
+			enc.SetIndent("", "\t")
+		}
+		var err error
+		for _, tok := range tt.tokens {
+			if err != nil {
+				t.Fatalf("#%d: unexpected error %v", i, err)
+			}
+			switch tok := tok.(type) {
+			case Delim, string, float64, bool, Number, nil:
+				err = enc.EncodeToken(tok)
+			default:
+				err = enc.Encode(tok)
+			}
+		}
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("#%d: error %v, want %s", i, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error %v", i, err)
+			continue
+		}
+		if buf.String() != tt.out {
+			t.Errorf("#%d: out=%q want %q", i, buf.String(), tt.out)
+		}
+	}
+}
+
+// Test that EncodeToken writes each element of an array as it is
+// encoded rather than when the array is closed.
+func TestEncodeTokenStreaming(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.EncodeToken(Delim('['))
+	for i := 0; i < 3; i++ {
+		if err := enc.Encode(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if have, want := buf.String(), "[0,1,2"; have != want {
+		t.Errorf("before ]: have %q, want %q", have, want)
+	}
+	enc.EncodeToken(Delim(']'))
+	if have, want := buf.String(), "[0,1,2]\n"; have != want {
+		t.Errorf("after ]: have %q, want %q", have, want)
+	}
+}
+
 func TestDecoder(t *testing.T) {
 	for i := 0; i <= len(streamTest); i++ {
 		// Use stream without newlines as input,
@@ -150,6 +313,20 @@ func TestRawMessage(t *testing.T) {
 	}
 }
 
+func TestRawMessageValue(t *testing.T) {
+	data := struct {
+		X RawMessage
+		Y RawMessage
+	}{X: RawMessage(`[1, 2]`)}
+	b, err := Marshal(data)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if want := `{"X":[1, 2],"Y":null}`; string(b) != want {
+		t.Fatalf("Marshal: have %#q want %#q", b, want)
+	}
+}
+
 func TestNullRawMessage(t *testing.T) {
 	// TODO(rsc): Should not need the * in *RawMessage
 	var data struct {
@@ -0,0 +1,65 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package packed
+
+import "math/big"
+
+// A Decimal is a decimal number with a fixed number of digits after
+// the decimal point. Its value is Unscaled × 10**-Scale.
+// A nil Unscaled is zero.
+type Decimal struct {
+	Unscaled *big.Int
+	Scale    int
+}
+
+var bigTen = big.NewInt(10)
+
+// rescale returns the unscaled value of d for the given scale.
+func (d Decimal) rescale(scale int) (*big.Int, error) {
+	if d.Unscaled == nil || d.Scale == scale {
+		return d.Unscaled, nil
+	}
+	v := new(big.Int)
+	if d.Scale < scale {
+		v.Exp(bigTen, big.NewInt(int64(scale-d.Scale)), nil)
+		return v.Mul(v, d.Unscaled), nil
+	}
+	v.Exp(bigTen, big.NewInt(int64(d.Scale-scale)), nil)
+	var r big.Int
+	v.QuoRem(d.Unscaled, v, &r)
+	if r.Sign() != 0 {
+		return nil, ErrInexact
+	}
+	return v, nil
+}
+
+// String returns the decimal representation of d, such as "-123.45".
+func (d Decimal) String() string {
+	digits, neg := bigDigits(d.Unscaled)
+	var b []byte
+	if neg {
+		b = append(b, '-')
+	}
+	switch {
+	case d.Scale <= 0:
+		b = append(b, digits...)
+		if digits[0] != '0' {
+			for i := 0; i < -d.Scale; i++ {
+				b = append(b, '0')
+			}
+		}
+	case len(digits) > d.Scale:
+		b = append(b, digits[:len(digits)-d.Scale]...)
+		b = append(b, '.')
+		b = append(b, digits[len(digits)-d.Scale:]...)
+	default:
+		b = append(b, "0."...)
+		for i := len(digits); i < d.Scale; i++ {
+			b = append(b, '0')
+		}
+		b = append(b, digits...)
+	}
+	return string(b)
+}
@@ -0,0 +1,405 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package packed implements the packed decimal and zoned decimal
+// encodings of decimal numbers used by IBM mainframes.
+//
+// A packed decimal (COBOL COMP-3) number stores two decimal digits
+// per byte, one in each nibble, and ends with a sign nibble. A zoned
+// decimal (COBOL DISPLAY) number stores one digit per byte as an
+// EBCDIC character, with the sign in the zone of the last byte.
+//
+// The layout of a field is described by a Format, which gives the
+// number of digits and how many of them follow the implied decimal
+// point. Values are converted to and from int64, *big.Int and
+// Decimal. The int64 and *big.Int forms hold the unscaled value:
+// a field with Scale 2 containing 123.45 decodes as 12345.
+//
+// The sign nibbles C and F are positive and D is negative.
+// On input the alternative sign nibbles A and E are also accepted
+// as positive and B as negative.
+package packed
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+)
+
+// Sign nibbles.
+const (
+	signPositive = 0xC
+	signNegative = 0xD
+	signUnsigned = 0xF
+)
+
+var (
+	// ErrOverflow is returned when a value has more digits than the
+	// Format allows, when a negative value is encoded in an unsigned
+	// Format, or when a decoded value does not fit in an int64.
+	ErrOverflow = errors.New("packed: value out of range")
+
+	// ErrInexact is returned when a Decimal has more digits after the
+	// decimal point than the Format's Scale.
+	ErrInexact = errors.New("packed: value has more fractional digits than the scale")
+
+	// ErrLength is returned when the encoded data does not have the
+	// length of the Format.
+	ErrLength = errors.New("packed: wrong length for format")
+
+	errFormat = errors.New("packed: invalid precision or scale")
+)
+
+// A CorruptInputError reports an invalid digit or sign at the given
+// byte offset of the encoded data.
+type CorruptInputError int64
+
+func (e CorruptInputError) Error() string {
+	return "packed: illegal data at input byte " + strconv.FormatInt(int64(e), 10)
+}
+
+// A Format describes a packed or zoned decimal field.
+type Format struct {
+	// Precision is the total number of decimal digits in the field.
+	// It must be at least 1.
+	Precision int
+
+	// Scale is the number of digits after the implied decimal point.
+	// It must be between 0 and Precision.
+	Scale int
+
+	// Unsigned fields are encoded with the sign nibble F and cannot
+	// hold negative values. Decoding accepts any valid sign.
+	Unsigned bool
+}
+
+// PackedLen returns the length in bytes of the packed decimal
+// encoding of f.
+func (f Format) PackedLen() int { return f.Precision/2 + 1 }
+
+// ZonedLen returns the length in bytes of the zoned decimal
+// encoding of f.
+func (f Format) ZonedLen() int { return f.Precision }
+
+func (f Format) check() error {
+	if f.Precision < 1 || f.Scale < 0 || f.Scale > f.Precision {
+		return errFormat
+	}
+	return nil
+}
+
+func (f Format) sign(neg bool) byte {
+	switch {
+	case neg:
+		return signNegative
+	case f.Unsigned:
+		return signUnsigned
+	}
+	return signPositive
+}
+
+// AppendPacked appends the packed decimal encoding of the unscaled
+// value v to dst and returns the extended buffer.
+// On error dst is returned unchanged.
+func (f Format) AppendPacked(dst []byte, v int64) ([]byte, error) {
+	var buf [20]byte
+	digits, neg := int64Digits(&buf, v)
+	return f.appendPacked(dst, digits, neg)
+}
+
+// AppendPackedBig is like AppendPacked but takes a *big.Int.
+func (f Format) AppendPackedBig(dst []byte, v *big.Int) ([]byte, error) {
+	digits, neg := bigDigits(v)
+	return f.appendPacked(dst, digits, neg)
+}
+
+// AppendPackedDecimal is like AppendPacked but takes a Decimal,
+// which is rescaled to the Scale of f.
+func (f Format) AppendPackedDecimal(dst []byte, d Decimal) ([]byte, error) {
+	v, err := d.rescale(f.Scale)
+	if err != nil {
+		return dst, err
+	}
+	return f.AppendPackedBig(dst, v)
+}
+
+// AppendZoned appends the zoned decimal encoding of the unscaled
+// value v to dst and returns the extended buffer.
+// On error dst is returned unchanged.
+func (f Format) AppendZoned(dst []byte, v int64) ([]byte, error) {
+	var buf [20]byte
+	digits, neg := int64Digits(&buf, v)
+	return f.appendZoned(dst, digits, neg)
+}
+
+// AppendZonedBig is like AppendZoned but takes a *big.Int.
+func (f Format) AppendZonedBig(dst []byte, v *big.Int) ([]byte, error) {
+	digits, neg := bigDigits(v)
+	return f.appendZoned(dst, digits, neg)
+}
+
+// AppendZonedDecimal is like AppendZoned but takes a Decimal,
+// which is rescaled to the Scale of f.
+func (f Format) AppendZonedDecimal(dst []byte, d Decimal) ([]byte, error) {
+	v, err := d.rescale(f.Scale)
+	if err != nil {
+		return dst, err
+	}
+	return f.AppendZonedBig(dst, v)
+}
+
+// DecodePacked decodes the packed decimal src and returns its
+// unscaled value.
+func (f Format) DecodePacked(src []byte) (int64, error) {
+	var buf [32]byte
+	digits, neg, err := f.packedDigits(buf[:0], src)
+	if err != nil {
+		return 0, err
+	}
+	return digitsInt64(digits, neg)
+}
+
+// DecodePackedBig is like DecodePacked but returns a *big.Int.
+func (f Format) DecodePackedBig(src []byte) (*big.Int, error) {
+	digits, neg, err := f.packedDigits(nil, src)
+	if err != nil {
+		return nil, err
+	}
+	return digitsBig(digits, neg), nil
+}
+
+// DecodePackedDecimal is like DecodePacked but returns a Decimal
+// with the Scale of f.
+func (f Format) DecodePackedDecimal(src []byte) (Decimal, error) {
+	v, err := f.DecodePackedBig(src)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return Decimal{Unscaled: v, Scale: f.Scale}, nil
+}
+
+// DecodeZoned decodes the zoned decimal src and returns its
+// unscaled value.
+func (f Format) DecodeZoned(src []byte) (int64, error) {
+	var buf [32]byte
+	digits, neg, err := f.zonedDigits(buf[:0], src)
+	if err != nil {
+		return 0, err
+	}
+	return digitsInt64(digits, neg)
+}
+
+// DecodeZonedBig is like DecodeZoned but returns a *big.Int.
+func (f Format) DecodeZonedBig(src []byte) (*big.Int, error) {
+	digits, neg, err := f.zonedDigits(nil, src)
+	if err != nil {
+		return nil, err
+	}
+	return digitsBig(digits, neg), nil
+}
+
+// DecodeZonedDecimal is like DecodeZoned but returns a Decimal
+// with the Scale of f.
+func (f Format) DecodeZonedDecimal(src []byte) (Decimal, error) {
+	v, err := f.DecodeZonedBig(src)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return Decimal{Unscaled: v, Scale: f.Scale}, nil
+}
+
+// appendPacked appends the packed encoding of the number with the
+// decimal digits digits, most significant first and without leading
+// zeros, and sign neg.
+func (f Format) appendPacked(dst, digits []byte, neg bool) ([]byte, error) {
+	if err := f.checkValue(digits, neg); err != nil {
+		return dst, err
+	}
+	dst, b := grow(dst, f.PackedLen())
+	// Digit k, counting from the least significant, goes in the low
+	// nibble of byte n-1-(k+1)/2 if k is odd and in the high nibble
+	// of byte n-1-k/2 if k is even.
+	n := len(b)
+	b[n-1] = f.sign(neg)
+	for k := range digits {
+		d := digits[len(digits)-1-k] - '0'
+		if k%2 == 0 {
+			b[n-1-k/2] |= d << 4
+		} else {
+			b[n-1-(k+1)/2] |= d
+		}
+	}
+	return dst, nil
+}
+
+func (f Format) appendZoned(dst, digits []byte, neg bool) ([]byte, error) {
+	if err := f.checkValue(digits, neg); err != nil {
+		return dst, err
+	}
+	dst, b := grow(dst, f.ZonedLen())
+	for i := range b {
+		b[i] = 0xF0
+	}
+	for k := range digits {
+		b[len(b)-1-k] |= digits[len(digits)-1-k] - '0'
+	}
+	b[len(b)-1] = f.sign(neg)<<4 | b[len(b)-1]&0x0F
+	return dst, nil
+}
+
+func (f Format) checkValue(digits []byte, neg bool) error {
+	if err := f.check(); err != nil {
+		return err
+	}
+	if len(digits) > f.Precision || neg && f.Unsigned {
+		return ErrOverflow
+	}
+	return nil
+}
+
+// packedDigits appends the decimal digits of the packed decimal src
+// to dst and reports whether the number is negative.
+func (f Format) packedDigits(dst, src []byte) (digits []byte, neg bool, err error) {
+	if err := f.check(); err != nil {
+		return nil, false, err
+	}
+	if len(src) != f.PackedLen() {
+		return nil, false, ErrLength
+	}
+	last := len(src) - 1
+	neg, ok := decodeSign(src[last] & 0x0F)
+	if !ok {
+		return nil, false, CorruptInputError(last)
+	}
+	// An even precision leaves the first nibble unused.
+	if f.Precision%2 == 0 && src[0]>>4 != 0 {
+		if src[0]>>4 > 9 {
+			return nil, false, CorruptInputError(0)
+		}
+		return nil, false, ErrOverflow
+	}
+	for i, c := range src {
+		hi, lo := c>>4, c&0x0F
+		if hi > 9 || i < last && lo > 9 {
+			return nil, false, CorruptInputError(i)
+		}
+		if i > 0 || f.Precision%2 == 1 {
+			dst = append(dst, '0'+hi)
+		}
+		if i < last {
+			dst = append(dst, '0'+lo)
+		}
+	}
+	return dst, neg, nil
+}
+
+func (f Format) zonedDigits(dst, src []byte) (digits []byte, neg bool, err error) {
+	if err := f.check(); err != nil {
+		return nil, false, err
+	}
+	if len(src) != f.ZonedLen() {
+		return nil, false, ErrLength
+	}
+	last := len(src) - 1
+	for i, c := range src {
+		if c&0x0F > 9 {
+			return nil, false, CorruptInputError(i)
+		}
+		if i < last && c>>4 != 0xF {
+			return nil, false, CorruptInputError(i)
+		}
+		dst = append(dst, '0'+c&0x0F)
+	}
+	neg, ok := decodeSign(src[last] >> 4)
+	if !ok {
+		return nil, false, CorruptInputError(last)
+	}
+	return dst, neg, nil
+}
+
+// decodeSign reports whether the sign nibble s is negative
+// and whether it is a valid sign.
+func decodeSign(s byte) (neg, ok bool) {
+	switch s {
+	case 0xA, 0xC, 0xE, 0xF:
+		return false, true
+	case 0xB, 0xD:
+		return true, true
+	}
+	return false, false
+}
+
+// grow extends dst by n zero bytes and returns the extended buffer
+// and the new bytes.
+func grow(dst []byte, n int) ([]byte, []byte) {
+	l := len(dst)
+	if cap(dst)-l < n {
+		nb := make([]byte, l, 2*cap(dst)+n)
+		copy(nb, dst)
+		dst = nb
+	}
+	dst = dst[:l+n]
+	b := dst[l:]
+	for i := range b {
+		b[i] = 0
+	}
+	return dst, b
+}
+
+// int64Digits formats the absolute value of v into buf and returns
+// its digits and whether v is negative.
+func int64Digits(buf *[20]byte, v int64) ([]byte, bool) {
+	neg := v < 0
+	u := uint64(v)
+	if neg {
+		u = -u
+	}
+	i := len(buf)
+	for u >= 10 {
+		i--
+		buf[i] = byte('0' + u%10)
+		u /= 10
+	}
+	i--
+	buf[i] = byte('0' + u)
+	return buf[i:], neg
+}
+
+func bigDigits(v *big.Int) ([]byte, bool) {
+	if v == nil {
+		return []byte{'0'}, false
+	}
+	return new(big.Int).Abs(v).Append(nil, 10), v.Sign() < 0
+}
+
+func digitsInt64(digits []byte, neg bool) (int64, error) {
+	// Any u below cutoff can take another digit without
+	// overflowing a uint64; the range of int64 is checked below.
+	const cutoff = 1<<63/10 + 1
+	var u uint64
+	for _, c := range digits {
+		if u >= cutoff {
+			return 0, ErrOverflow
+		}
+		u = u*10 + uint64(c-'0')
+	}
+	if neg {
+		if u > 1<<63 {
+			return 0, ErrOverflow
+		}
+		return -int64(u), nil
+	}
+	if u > 1<<63-1 {
+		return 0, ErrOverflow
+	}
+	return int64(u), nil
+}
+
+func digitsBig(digits []byte, neg bool) *big.Int {
+	v, _ := new(big.Int).SetString(string(digits), 10)
+	if neg {
+		v.Neg(v)
+	}
+	return v
+}
@@ -0,0 +1,216 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package packed
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"testing"
+)
+
+type codecTest struct {
+	f      Format
+	v      int64
+	packed []byte
+	zoned  []byte
+}
+
+var codecTests = []codecTest{
+	{Format{Precision: 1}, 0, []byte{0x0C}, []byte{0xC0}},
+	{Format{Precision: 1}, 7, []byte{0x7C}, []byte{0xC7}},
+	{Format{Precision: 1}, -7, []byte{0x7D}, []byte{0xD7}},
+	{Format{Precision: 3}, 123, []byte{0x12, 0x3C}, []byte{0xF1, 0xF2, 0xC3}},
+	{Format{Precision: 3}, -123, []byte{0x12, 0x3D}, []byte{0xF1, 0xF2, 0xD3}},
+	{Format{Precision: 4}, 1234, []byte{0x01, 0x23, 0x4C}, []byte{0xF1, 0xF2, 0xF3, 0xC4}},
+	{Format{Precision: 5, Scale: 2}, -12345, []byte{0x12, 0x34, 0x5D}, []byte{0xF1, 0xF2, 0xF3, 0xF4, 0xD5}},
+	{Format{Precision: 5, Unsigned: true}, 42, []byte{0x00, 0x04, 0x2F}, []byte{0xF0, 0xF0, 0xF0, 0xF4, 0xF2}},
+	{Format{Precision: 19}, math.MaxInt64,
+		[]byte{0x92, 0x23, 0x37, 0x20, 0x36, 0x85, 0x47, 0x75, 0x80, 0x7C},
+		[]byte{0xF9, 0xF2, 0xF2, 0xF3, 0xF3, 0xF7, 0xF2, 0xF0, 0xF3, 0xF6, 0xF8, 0xF5, 0xF4, 0xF7, 0xF7, 0xF5, 0xF8, 0xF0, 0xC7}},
+	{Format{Precision: 19}, math.MinInt64,
+		[]byte{0x92, 0x23, 0x37, 0x20, 0x36, 0x85, 0x47, 0x75, 0x80, 0x8D},
+		[]byte{0xF9, 0xF2, 0xF2, 0xF3, 0xF3, 0xF7, 0xF2, 0xF0, 0xF3, 0xF6, 0xF8, 0xF5, 0xF4, 0xF7, 0xF7, 0xF5, 0xF8, 0xF0, 0xD8}},
+}
+
+func TestCodec(t *testing.T) {
+	for _, tt := range codecTests {
+		prefix := []byte("x")
+		b, err := tt.f.AppendPacked(prefix, tt.v)
+		if err != nil || !bytes.Equal(b, append([]byte("x"), tt.packed...)) {
+			t.Errorf("%+v.AppendPacked(%d) = % X, %v; want x% X", tt.f, tt.v, b, err, tt.packed)
+		}
+		if v, err := tt.f.DecodePacked(tt.packed); err != nil || v != tt.v {
+			t.Errorf("%+v.DecodePacked(% X) = %d, %v; want %d", tt.f, tt.packed, v, err, tt.v)
+		}
+		b, err = tt.f.AppendZoned(nil, tt.v)
+		if err != nil || !bytes.Equal(b, tt.zoned) {
+			t.Errorf("%+v.AppendZoned(%d) = % X, %v; want % X", tt.f, tt.v, b, err, tt.zoned)
+		}
+		if v, err := tt.f.DecodeZoned(tt.zoned); err != nil || v != tt.v {
+			t.Errorf("%+v.DecodeZoned(% X) = %d, %v; want %d", tt.f, tt.zoned, v, err, tt.v)
+		}
+
+		bv := big.NewInt(tt.v)
+		b, err = tt.f.AppendPackedBig(nil, bv)
+		if err != nil || !bytes.Equal(b, tt.packed) {
+			t.Errorf("%+v.AppendPackedBig(%d) = % X, %v; want % X", tt.f, tt.v, b, err, tt.packed)
+		}
+		if v, err := tt.f.DecodePackedBig(tt.packed); err != nil || v.Cmp(bv) != 0 {
+			t.Errorf("%+v.DecodePackedBig(% X) = %v, %v; want %d", tt.f, tt.packed, v, err, tt.v)
+		}
+		b, err = tt.f.AppendZonedBig(nil, bv)
+		if err != nil || !bytes.Equal(b, tt.zoned) {
+			t.Errorf("%+v.AppendZonedBig(%d) = % X, %v; want % X", tt.f, tt.v, b, err, tt.zoned)
+		}
+		if v, err := tt.f.DecodeZonedBig(tt.zoned); err != nil || v.Cmp(bv) != 0 {
+			t.Errorf("%+v.DecodeZonedBig(% X) = %v, %v; want %d", tt.f, tt.zoned, v, err, tt.v)
+		}
+	}
+}
+
+func TestBig(t *testing.T) {
+	f := Format{Precision: 31}
+	v, _ := new(big.Int).SetString("-1234567890123456789012345678901", 10)
+	want := []byte{0x12, 0x34, 0x56, 0x78, 0x90, 0x12, 0x34, 0x56, 0x78, 0x90, 0x12, 0x34, 0x56, 0x78, 0x90, 0x1D}
+	b, err := f.AppendPackedBig(nil, v)
+	if err != nil || !bytes.Equal(b, want) {
+		t.Fatalf("AppendPackedBig = % X, %v; want % X", b, err, want)
+	}
+	got, err := f.DecodePackedBig(b)
+	if err != nil || got.Cmp(v) != 0 {
+		t.Errorf("DecodePackedBig = %v, %v; want %v", got, err, v)
+	}
+	if _, err := f.DecodePacked(b); err != ErrOverflow {
+		t.Errorf("DecodePacked of 31 digits: err = %v; want %v", err, ErrOverflow)
+	}
+	if _, err := f.AppendPackedBig(nil, new(big.Int).Mul(v, big.NewInt(10))); err != ErrOverflow {
+		t.Errorf("AppendPackedBig of 32 digits: err = %v; want %v", err, ErrOverflow)
+	}
+}
+
+var decimalTests = []struct {
+	f   Format
+	d   Decimal
+	out string // decoded Decimal
+	err error
+}{
+	{Format{Precision: 5, Scale: 2}, Decimal{big.NewInt(-12345), 2}, "-123.45", nil},
+	{Format{Precision: 5, Scale: 2}, Decimal{big.NewInt(5), 1}, "0.50", nil},
+	{Format{Precision: 5, Scale: 2}, Decimal{big.NewInt(7), 0}, "7.00", nil},
+	{Format{Precision: 5, Scale: 2}, Decimal{big.NewInt(-1230), 3}, "-1.23", nil},
+	{Format{Precision: 5, Scale: 2}, Decimal{nil, 0}, "0.00", nil},
+	{Format{Precision: 5, Scale: 2}, Decimal{big.NewInt(1234), 3}, "", ErrInexact},
+	{Format{Precision: 5, Scale: 2}, Decimal{big.NewInt(1000), 0}, "", ErrOverflow},
+	{Format{Precision: 5, Scale: 2, Unsigned: true}, Decimal{big.NewInt(-1), 0}, "", ErrOverflow},
+}
+
+func TestDecimal(t *testing.T) {
+	for _, tt := range decimalTests {
+		b, err := tt.f.AppendPackedDecimal(nil, tt.d)
+		if err != tt.err {
+			t.Errorf("%+v.AppendPackedDecimal(%v) error = %v; want %v", tt.f, tt.d, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		d, err := tt.f.DecodePackedDecimal(b)
+		if err != nil || d.String() != tt.out {
+			t.Errorf("%+v packed round trip of %v = %v, %v; want %s", tt.f, tt.d, d, err, tt.out)
+		}
+		b, err = tt.f.AppendZonedDecimal(nil, tt.d)
+		if err != nil {
+			t.Errorf("%+v.AppendZonedDecimal(%v): %v", tt.f, tt.d, err)
+			continue
+		}
+		d, err = tt.f.DecodeZonedDecimal(b)
+		if err != nil || d.String() != tt.out {
+			t.Errorf("%+v zoned round trip of %v = %v, %v; want %s", tt.f, tt.d, d, err, tt.out)
+		}
+	}
+}
+
+func TestDecimalString(t *testing.T) {
+	for _, tt := range []struct {
+		d    Decimal
+		want string
+	}{
+		{Decimal{big.NewInt(12345), 2}, "123.45"},
+		{Decimal{big.NewInt(-5), 3}, "-0.005"},
+		{Decimal{big.NewInt(12), 0}, "12"},
+		{Decimal{big.NewInt(12), -2}, "1200"},
+		{Decimal{big.NewInt(0), -2}, "0"},
+		{Decimal{nil, 2}, "0.00"},
+	} {
+		if got := tt.d.String(); got != tt.want {
+			t.Errorf("Decimal{%v, %d}.String() = %q; want %q", tt.d.Unscaled, tt.d.Scale, got, tt.want)
+		}
+	}
+}
+
+var decodeErrorTests = []struct {
+	f      Format
+	packed bool
+	in     []byte
+	err    error
+}{
+	{Format{Precision: 3}, true, []byte{0x12}, ErrLength},
+	{Format{Precision: 3}, true, []byte{0x12, 0x34}, CorruptInputError(1)},
+	{Format{Precision: 3}, true, []byte{0x1A, 0x3C}, CorruptInputError(0)},
+	{Format{Precision: 3}, true, []byte{0x12, 0xAC}, CorruptInputError(1)},
+	{Format{Precision: 4}, true, []byte{0x11, 0x23, 0x4C}, ErrOverflow},
+	{Format{Precision: 3}, true, []byte{0x12, 0x3A}, nil},
+	{Format{Precision: 3}, true, []byte{0x12, 0x3B}, nil},
+	{Format{Precision: 3}, false, []byte{0xF1, 0xF2}, ErrLength},
+	{Format{Precision: 3}, false, []byte{0xF1, 0x42, 0xC3}, CorruptInputError(1)},
+	{Format{Precision: 3}, false, []byte{0xF1, 0xF2, 0x33}, CorruptInputError(2)},
+	{Format{Precision: 3}, false, []byte{0xF1, 0xFA, 0xC3}, CorruptInputError(1)},
+	{Format{Precision: 20}, false, []byte("\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xF9\xC9"), ErrOverflow},
+	{Format{Precision: 19}, false, []byte("\xF9\xF2\xF2\xF3\xF3\xF7\xF2\xF0\xF3\xF6\xF8\xF5\xF4\xF7\xF7\xF5\xF8\xF0\xC8"), ErrOverflow},
+	{Format{Precision: 0}, true, []byte{0x0C}, errFormat},
+	{Format{Precision: 2, Scale: 3}, false, []byte{0xF1, 0xC2}, errFormat},
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, tt := range decodeErrorTests {
+		var err error
+		if tt.packed {
+			_, err = tt.f.DecodePacked(tt.in)
+		} else {
+			_, err = tt.f.DecodeZoned(tt.in)
+		}
+		if err != tt.err {
+			t.Errorf("%+v decode(% X) (packed=%v) error = %v; want %v", tt.f, tt.in, tt.packed, err, tt.err)
+		}
+	}
+}
+
+func TestAppendError(t *testing.T) {
+	f := Format{Precision: 3}
+	dst := []byte("abc")
+	b, err := f.AppendPacked(dst, 1000)
+	if err != ErrOverflow || string(b) != "abc" {
+		t.Errorf("AppendPacked(1000) = %q, %v; want %q, %v", b, err, "abc", ErrOverflow)
+	}
+	b, err = f.AppendZoned(dst, -1000)
+	if err != ErrOverflow || string(b) != "abc" {
+		t.Errorf("AppendZoned(-1000) = %q, %v; want %q, %v", b, err, "abc", ErrOverflow)
+	}
+}
+
+func TestAllocs(t *testing.T) {
+	f := Format{Precision: 9, Scale: 2}
+	buf := make([]byte, 0, 64)
+	n := testing.AllocsPerRun(100, func() {
+		b, _ := f.AppendPacked(buf[:0], -123456789)
+		f.DecodePacked(b)
+		b, _ = f.AppendZoned(buf[:0], 123456789)
+		f.DecodeZoned(b)
+	})
+	if n > 0 {
+		t.Errorf("int64 encoding and decoding allocated %v times; want 0", n)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package record
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// A Reader reads the records of a dataset.
+//
+// The exported fields can be changed to customize the details before
+// the first call to Read.
+//
+// If NoBDW is true, variable-length records are read as a sequence of
+// RDW-prefixed records or segments without BDWs.
+//
+// If Text is true, records are converted from EBCDIC (IBM-1047) to UTF-8.
+type Reader struct {
+	NoBDW bool // variable-length input has no BDWs
+	Text  bool // convert records from EBCDIC to UTF-8
+
+	format    Format
+	lrecl     int
+	r         *bufio.Reader
+	off       int64 // offset of the next byte of input
+	blockLeft int   // bytes left in the current block
+}
+
+// NewReader returns a new Reader that reads records of format f from r.
+//
+// For fixed-length formats lrecl is the length of every record. For
+// variable-length formats it is the maximum length of a record,
+// including its RDW, or 0 for no limit beyond that of the format.
+func NewReader(r io.Reader, f Format, lrecl int) *Reader {
+	return &Reader{
+		format: f,
+		lrecl:  lrecl,
+		r:      bufio.NewReader(r),
+	}
+}
+
+var (
+	errLRECL  = errors.New("record: LRECL must be positive for fixed-length records")
+	errFormat = errors.New("record: unsupported record format")
+)
+
+// Read reads one record from r. At the end of the input Read returns
+// nil, io.EOF. If the input ends in the middle of a record Read returns
+// io.ErrUnexpectedEOF.
+func (r *Reader) Read() (record []byte, err error) {
+	switch {
+	case r.format == F || r.format == FB:
+		record, err = r.readFixed()
+	case r.format.variable():
+		record, err = r.readVariable()
+	default:
+		err = errFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.Text {
+		return decodeText(record)
+	}
+	return record, nil
+}
+
+// readFull reads len(buf) bytes, returning io.EOF only if no bytes
+// were read.
+func (r *Reader) readFull(buf []byte) error {
+	n, err := io.ReadFull(r.r, buf)
+	r.off += int64(n)
+	return err
+}
+
+func (r *Reader) readFixed() ([]byte, error) {
+	if r.lrecl <= 0 {
+		return nil, errLRECL
+	}
+	record := make([]byte, r.lrecl)
+	if err := r.readFull(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// readBDW reads a block descriptor word and starts a new block.
+func (r *Reader) readBDW() error {
+	off := r.off
+	var bdw [dwLen]byte
+	if err := r.readFull(bdw[:]); err != nil {
+		return err
+	}
+	var n int
+	if bdw[0]&0x80 != 0 {
+		// A large block BDW holds a 31-bit length.
+		n = int(binary.BigEndian.Uint32(bdw[:]) &^ bdwLarge)
+	} else {
+		if bdw[2] != 0 || bdw[3] != 0 {
+			return &FormatError{off, "invalid BDW"}
+		}
+		n = int(binary.BigEndian.Uint16(bdw[:]))
+	}
+	if n < 2*dwLen {
+		return &FormatError{off, "BDW length too short"}
+	}
+	r.blockLeft = n - dwLen
+	return nil
+}
+
+func (r *Reader) readVariable() ([]byte, error) {
+	record := []byte{}
+	spanning := false
+	for {
+		if !r.NoBDW && r.blockLeft == 0 {
+			if err := r.readBDW(); err != nil {
+				if err == io.EOF && spanning {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+		}
+
+		off := r.off
+		var rdw [dwLen]byte
+		if err := r.readFull(rdw[:]); err != nil {
+			if err == io.EOF && (spanning || !r.NoBDW) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		n := int(binary.BigEndian.Uint16(rdw[:]))
+		seg := rdw[2]
+		if n < dwLen || rdw[3] != 0 {
+			return nil, &FormatError{off, "invalid RDW"}
+		}
+		if !r.NoBDW {
+			if n > r.blockLeft {
+				return nil, &FormatError{off, "RDW length exceeds block"}
+			}
+			r.blockLeft -= n
+		}
+		if seg != segComplete && r.format != VBS {
+			return nil, &FormatError{off, "segment of a spanned record in " + r.format.String() + " dataset"}
+		}
+		switch {
+		case seg > segMiddle:
+			return nil, &FormatError{off, "invalid RDW segment code"}
+		case (seg == segComplete || seg == segFirst) && spanning:
+			return nil, &FormatError{off, "spanned record not completed"}
+		case (seg == segLast || seg == segMiddle) && !spanning:
+			return nil, &FormatError{off, "segment of a spanned record without first segment"}
+		}
+		if r.lrecl > 0 && len(record)+n > r.lrecl {
+			return nil, ErrTooLong
+		}
+
+		start := len(record)
+		record = append(record, make([]byte, n-dwLen)...)
+		if err := r.readFull(record[start:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		if r.format == V && !r.NoBDW && r.blockLeft != 0 {
+			return nil, &FormatError{off, "more than one record in V block"}
+		}
+		if seg == segComplete || seg == segLast {
+			return record, nil
+		}
+		spanning = true
+	}
+}
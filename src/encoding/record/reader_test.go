@@ -0,0 +1,219 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package record
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var readTests = []struct {
+	Name   string
+	Format Format
+	LRECL  int
+	NoBDW  bool
+	Text   bool
+	Input  string
+
+	Output []string
+	Error  string
+}{
+	{
+		Name:   "F",
+		Format: F,
+		LRECL:  3,
+		Input:  "abcdefghi",
+		Output: []string{"abc", "def", "ghi"},
+	},
+	{
+		Name:   "FBShort",
+		Format: FB,
+		LRECL:  4,
+		Input:  "abcdefg",
+		Output: []string{"abcd"},
+		Error:  io.ErrUnexpectedEOF.Error(),
+	},
+	{
+		Name:   "FText",
+		Format: F,
+		LRECL:  4,
+		Text:   true,
+		Input:  "\xc1\xc2\x40\x40\xf1\xf2\xf3\xf4",
+		Output: []string{"AB  ", "1234"},
+	},
+	{
+		Name:   "NoLRECL",
+		Format: F,
+		Input:  "abc",
+		Error:  errLRECL.Error(),
+	},
+	{
+		Name:   "V",
+		Format: V,
+		Input:  "\x00\x09\x00\x00\x00\x05\x00\x00a" + "\x00\x0a\x00\x00\x00\x06\x00\x00bc",
+		Output: []string{"a", "bc"},
+	},
+	{
+		Name:   "VTwoRecords",
+		Format: V,
+		Input:  "\x00\x0d\x00\x00\x00\x05\x00\x00a\x00\x04\x00\x00",
+		Output: []string{},
+		Error:  "record: more than one record in V block at offset 4",
+	},
+	{
+		Name:   "VB",
+		Format: VB,
+		Input:  "\x00\x13\x00\x00\x00\x05\x00\x00a\x00\x06\x00\x00bc\x00\x04\x00\x00" + "\x00\x0b\x00\x00\x00\x07\x00\x00def",
+		Output: []string{"a", "bc", "", "def"},
+	},
+	{
+		Name:   "VBLargeBlock",
+		Format: VB,
+		Input:  "\x80\x00\x00\x09\x00\x05\x00\x00a",
+		Output: []string{"a"},
+	},
+	{
+		Name:   "VBNoBDW",
+		Format: VB,
+		NoBDW:  true,
+		Input:  "\x00\x05\x00\x00a\x00\x06\x00\x00bc",
+		Output: []string{"a", "bc"},
+	},
+	{
+		Name:   "VBText",
+		Format: VB,
+		Text:   true,
+		Input:  "\x00\x0a\x00\x00\x00\x06\x00\x00\xc8\x89",
+		Output: []string{"Hi"},
+	},
+	{
+		Name:   "VBS",
+		Format: VBS,
+		Input: "\x00\x0e\x00\x00\x00\x05\x00\x00a\x00\x05\x01\x00b" +
+			"\x00\x0a\x00\x00\x00\x06\x03\x00cd" +
+			"\x00\x0e\x00\x00\x00\x05\x02\x00e\x00\x05\x00\x00f",
+		Output: []string{"a", "bcde", "f"},
+	},
+	{
+		Name:   "VBSNoBDW",
+		Format: VBS,
+		NoBDW:  true,
+		Input:  "\x00\x05\x01\x00a\x00\x05\x02\x00b",
+		Output: []string{"ab"},
+	},
+	{
+		Name:   "VBSTruncated",
+		Format: VBS,
+		Input:  "\x00\x09\x00\x00\x00\x05\x01\x00a",
+		Output: []string{},
+		Error:  io.ErrUnexpectedEOF.Error(),
+	},
+	{
+		Name:   "VBSMissingFirst",
+		Format: VBS,
+		Input:  "\x00\x09\x00\x00\x00\x05\x02\x00a",
+		Output: []string{},
+		Error:  "record: segment of a spanned record without first segment at offset 4",
+	},
+	{
+		Name:   "VBSNotCompleted",
+		Format: VBS,
+		Input:  "\x00\x0e\x00\x00\x00\x05\x01\x00a\x00\x05\x00\x00b",
+		Output: []string{},
+		Error:  "record: spanned record not completed at offset 9",
+	},
+	{
+		Name:   "VBSegment",
+		Format: VB,
+		Input:  "\x00\x09\x00\x00\x00\x05\x01\x00a",
+		Output: []string{},
+		Error:  "record: segment of a spanned record in VB dataset at offset 4",
+	},
+	{
+		Name:   "RDWExceedsBlock",
+		Format: VB,
+		Input:  "\x00\x09\x00\x00\x00\x06\x00\x00ab",
+		Output: []string{},
+		Error:  "record: RDW length exceeds block at offset 4",
+	},
+	{
+		Name:   "BadRDW",
+		Format: VB,
+		Input:  "\x00\x08\x00\x00\x00\x03\x00\x00",
+		Output: []string{},
+		Error:  "record: invalid RDW at offset 4",
+	},
+	{
+		Name:   "BadBDW",
+		Format: VB,
+		Input:  "\x00\x09\x00\x01\x00\x05\x00\x00a",
+		Output: []string{},
+		Error:  "record: invalid BDW at offset 0",
+	},
+	{
+		Name:   "ShortBDW",
+		Format: VB,
+		Input:  "\x00\x09\x00\x00\x00\x05\x00\x00a\x00\x04\x00\x00",
+		Output: []string{"a"},
+		Error:  "record: BDW length too short at offset 9",
+	},
+	{
+		Name:   "TooLong",
+		Format: VB,
+		LRECL:  6,
+		Input:  "\x00\x0b\x00\x00\x00\x07\x00\x00abc",
+		Output: []string{},
+		Error:  ErrTooLong.Error(),
+	},
+	{
+		Name:   "VBSTooLong",
+		Format: VBS,
+		LRECL:  6,
+		NoBDW:  true,
+		Input:  "\x00\x06\x01\x00ab\x00\x05\x02\x00c",
+		Output: []string{},
+		Error:  ErrTooLong.Error(),
+	},
+	{
+		Name:   "TruncatedBlock",
+		Format: VB,
+		Input:  "\x00\x0d\x00\x00\x00\x05\x00\x00a",
+		Output: []string{"a"},
+		Error:  io.ErrUnexpectedEOF.Error(),
+	},
+}
+
+func TestRead(t *testing.T) {
+	for _, tt := range readTests {
+		r := NewReader(strings.NewReader(tt.Input), tt.Format, tt.LRECL)
+		r.NoBDW = tt.NoBDW
+		r.Text = tt.Text
+		out := []string{}
+		var err error
+		for {
+			var rec []byte
+			rec, err = r.Read()
+			if err != nil {
+				break
+			}
+			out = append(out, string(rec))
+		}
+		if err == io.EOF {
+			err = nil
+		}
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("%s: error %v, want %s", tt.Name, err, tt.Error)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+		}
+		if tt.Output != nil && !reflect.DeepEqual(out, tt.Output) {
+			t.Errorf("%s: out=%q want %q", tt.Name, out, tt.Output)
+		}
+	}
+}
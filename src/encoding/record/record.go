@@ -0,0 +1,107 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package record reads and writes the records of z/OS datasets that
+// have been transferred as a byte stream, in binary mode.
+//
+// The record format (RECFM) of a dataset determines how records are
+// delimited. Fixed-length records (F and FB) are LRECL bytes long and
+// follow each other with no delimiters. Variable-length records (V, VB
+// and VBS) start with a 4-byte record descriptor word (RDW) holding the
+// length of the record, and are grouped into blocks that start with a
+// 4-byte block descriptor word (BDW) holding the length of the block.
+// A V block holds one record, a VB block holds as many records as fit
+// and in a VBS dataset a record may be split into segments spanning
+// several blocks.
+//
+// Undefined-length records (U) are delimited only by the block
+// boundaries of the dataset, which a byte stream does not preserve,
+// so they are not supported.
+//
+// Some transfer programs, such as z/OS FTP with the RDW option, keep
+// the RDWs of variable-length records but drop the BDWs. The NoBDW
+// fields of Reader and Writer select that layout.
+package record
+
+import (
+	"errors"
+	"fmt"
+	"internal/ebcdic"
+)
+
+// A Format is a record format.
+type Format int
+
+const (
+	F   Format = iota // fixed-length records, unblocked
+	FB                // fixed-length records, blocked
+	V                 // variable-length records, unblocked
+	VB                // variable-length records, blocked
+	VBS               // variable-length records, blocked and spanned
+)
+
+var formatNames = []string{
+	F:   "F",
+	FB:  "FB",
+	V:   "V",
+	VB:  "VB",
+	VBS: "VBS",
+}
+
+func (f Format) String() string {
+	if f >= 0 && int(f) < len(formatNames) {
+		return formatNames[f]
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+func (f Format) variable() bool { return f == V || f == VB || f == VBS }
+
+// ErrTooLong is returned for a record longer than the LRECL or one
+// that does not fit in a block.
+var ErrTooLong = errors.New("record: record too long")
+
+// A FormatError reports an invalid record or block descriptor word.
+type FormatError struct {
+	Offset int64  // byte offset of the descriptor word in the input
+	Msg    string // description of the error
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("record: %s at offset %d", e.Msg, e.Offset)
+}
+
+// Segment codes of the RDW of a spanned record.
+const (
+	segComplete = 0
+	segFirst    = 1
+	segLast     = 2
+	segMiddle   = 3
+)
+
+const (
+	dwLen     = 4         // length of a BDW or RDW
+	maxDWLen  = 1<<16 - 1 // maximum length in a BDW or RDW
+	maxBlkLen = 32760     // maximum BLKSIZE for variable-length records
+	bdwLarge  = 1 << 31   // flag for a large block BDW
+	ebcdicSP  = 0x40      // EBCDIC space
+)
+
+// decodeText converts rec from EBCDIC to UTF-8.
+func decodeText(rec []byte) ([]byte, error) {
+	runes, errStr := ebcdic.Decode(rec)
+	if errStr != "" {
+		return nil, errors.New("record: " + errStr)
+	}
+	return []byte(string(runes)), nil
+}
+
+// encodeText converts rec from UTF-8 to EBCDIC.
+func encodeText(rec []byte) ([]byte, error) {
+	b, errStr := ebcdic.Encode([]rune(string(rec)))
+	if errStr != "" {
+		return nil, errors.New("record: " + errStr)
+	}
+	return b, nil
+}
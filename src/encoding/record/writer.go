@@ -0,0 +1,212 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package record
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// A Writer writes the records of a dataset.
+//
+// The exported fields can be changed to customize the details before
+// the first call to Write.
+//
+// If NoBDW is true, variable-length records are written with an RDW
+// each but without BDWs, and are not spanned.
+//
+// If Text is true, records are converted from UTF-8 to EBCDIC (IBM-1047).
+//
+// Fixed-length records shorter than the LRECL are padded with zero
+// bytes, or with EBCDIC spaces if Text is true.
+type Writer struct {
+	NoBDW bool // write variable-length records without BDWs
+	Text  bool // convert records from UTF-8 to EBCDIC
+
+	format  Format
+	lrecl   int
+	blksize int
+	w       *bufio.Writer
+	block   []byte // contents of the current block, after its BDW
+}
+
+// NewWriter returns a new Writer that writes records of format f to w.
+//
+// For fixed-length formats lrecl is the length of every record and
+// blksize, if positive, must be a multiple of it. For variable-length
+// formats lrecl is the maximum length of a record, including its RDW,
+// or 0 for no limit beyond that of the format, and blksize is the
+// maximum length of a block, including its BDW, which must be at most
+// 32760.
+func NewWriter(w io.Writer, f Format, lrecl, blksize int) *Writer {
+	return &Writer{
+		format:  f,
+		lrecl:   lrecl,
+		blksize: blksize,
+		w:       bufio.NewWriter(w),
+	}
+}
+
+var errBLKSIZE = errors.New("record: invalid BLKSIZE")
+
+// check reports whether the Writer's format, LRECL and BLKSIZE are valid.
+func (w *Writer) check() error {
+	switch {
+	case w.format == F || w.format == FB:
+		if w.lrecl <= 0 {
+			return errLRECL
+		}
+		if w.blksize > 0 && w.blksize%w.lrecl != 0 {
+			return errBLKSIZE
+		}
+	case !w.format.variable():
+		return errFormat
+	case w.NoBDW:
+	case w.blksize > maxBlkLen || w.blksize < 2*dwLen, w.format == VBS && w.blksize == 2*dwLen:
+		// A block must hold a BDW and an RDW, and a spanned block
+		// room for at least one byte of a segment.
+		return errBLKSIZE
+	}
+	return nil
+}
+
+// Write writes a single record to w. Blocks of variable-length records
+// are written when they are full or by Flush.
+func (w *Writer) Write(record []byte) error {
+	if err := w.check(); err != nil {
+		return err
+	}
+	if w.Text {
+		var err error
+		if record, err = encodeText(record); err != nil {
+			return err
+		}
+	}
+	if w.format == F || w.format == FB {
+		return w.writeFixed(record)
+	}
+
+	n := dwLen + len(record)
+	if w.lrecl > 0 && n > w.lrecl {
+		return ErrTooLong
+	}
+	switch {
+	case w.NoBDW:
+		if n > maxDWLen {
+			return ErrTooLong
+		}
+		var rdw [dwLen]byte
+		putDW(rdw[:], n, segComplete)
+		if _, err := w.w.Write(rdw[:]); err != nil {
+			return err
+		}
+		_, err := w.w.Write(record)
+		return err
+	case w.format == VBS:
+		return w.writeSpanned(record)
+	}
+
+	if dwLen+n > w.blksize {
+		return ErrTooLong
+	}
+	if len(w.block) > 0 && dwLen+len(w.block)+n > w.blksize {
+		if err := w.flushBlock(); err != nil {
+			return err
+		}
+	}
+	w.block = appendSegment(w.block, record, segComplete)
+	if w.format == V {
+		return w.flushBlock()
+	}
+	return nil
+}
+
+func (w *Writer) writeFixed(record []byte) error {
+	if len(record) > w.lrecl {
+		return ErrTooLong
+	}
+	if _, err := w.w.Write(record); err != nil {
+		return err
+	}
+	pad := byte(0)
+	if w.Text {
+		pad = ebcdicSP
+	}
+	for i := len(record); i < w.lrecl; i++ {
+		if err := w.w.WriteByte(pad); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSpanned adds record to the current block, splitting it into
+// segments across as many blocks as needed.
+func (w *Writer) writeSpanned(record []byte) error {
+	seg := byte(segComplete)
+	for {
+		avail := w.blksize - dwLen - len(w.block) - dwLen
+		if len(record) <= avail {
+			if seg != segComplete {
+				seg = segLast
+			}
+			w.block = appendSegment(w.block, record, seg)
+			return nil
+		}
+		if avail > 0 {
+			if seg == segComplete {
+				seg = segFirst
+			} else {
+				seg = segMiddle
+			}
+			w.block = appendSegment(w.block, record[:avail], seg)
+			record = record[avail:]
+		}
+		if err := w.flushBlock(); err != nil {
+			return err
+		}
+	}
+}
+
+// Flush writes the current block and any buffered data to the
+// underlying io.Writer.
+func (w *Writer) Flush() error {
+	if err := w.flushBlock(); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+func (w *Writer) flushBlock() error {
+	if len(w.block) == 0 {
+		return nil
+	}
+	var bdw [dwLen]byte
+	putDW(bdw[:], dwLen+len(w.block), 0)
+	if _, err := w.w.Write(bdw[:]); err != nil {
+		return err
+	}
+	_, err := w.w.Write(w.block)
+	w.block = w.block[:0]
+	return err
+}
+
+// putDW stores a BDW or RDW for length n and segment code seg in b.
+func putDW(b []byte, n int, seg byte) {
+	b[0] = byte(n >> 8)
+	b[1] = byte(n)
+	b[2] = seg
+	b[3] = 0
+}
+
+// appendSegment appends an RDW for data with segment code seg and
+// data to b.
+func appendSegment(b, data []byte, seg byte) []byte {
+	var rdw [dwLen]byte
+	putDW(rdw[:], dwLen+len(data), seg)
+	b = append(b, rdw[:]...)
+	return append(b, data...)
+}
@@ -0,0 +1,202 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package record
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+var writeTests = []struct {
+	Name    string
+	Format  Format
+	LRECL   int
+	BLKSIZE int
+	NoBDW   bool
+	Text    bool
+	Input   []string
+
+	Output string
+	Error  error
+}{
+	{
+		Name:   "F",
+		Format: F,
+		LRECL:  3,
+		Input:  []string{"abc", "d"},
+		Output: "abcd\x00\x00",
+	},
+	{
+		Name:    "FBText",
+		Format:  FB,
+		LRECL:   4,
+		BLKSIZE: 8,
+		Text:    true,
+		Input:   []string{"AB", "1234"},
+		Output:  "\xc1\xc2\x40\x40\xf1\xf2\xf3\xf4",
+	},
+	{
+		Name:   "FTooLong",
+		Format: F,
+		LRECL:  3,
+		Input:  []string{"abcd"},
+		Error:  ErrTooLong,
+	},
+	{
+		Name:    "FBBadBLKSIZE",
+		Format:  FB,
+		LRECL:   3,
+		BLKSIZE: 10,
+		Input:   []string{"abc"},
+		Error:   errBLKSIZE,
+	},
+	{
+		Name:    "V",
+		Format:  V,
+		BLKSIZE: 100,
+		Input:   []string{"a", "bc"},
+		Output:  "\x00\x09\x00\x00\x00\x05\x00\x00a" + "\x00\x0a\x00\x00\x00\x06\x00\x00bc",
+	},
+	{
+		Name:    "VB",
+		Format:  VB,
+		BLKSIZE: 19,
+		Input:   []string{"a", "bc", "", "def"},
+		Output:  "\x00\x13\x00\x00\x00\x05\x00\x00a\x00\x06\x00\x00bc\x00\x04\x00\x00" + "\x00\x0b\x00\x00\x00\x07\x00\x00def",
+	},
+	{
+		Name:    "VBTooLongForBlock",
+		Format:  VB,
+		BLKSIZE: 10,
+		Input:   []string{"abc"},
+		Error:   ErrTooLong,
+	},
+	{
+		Name:    "VBTooLong",
+		Format:  VB,
+		LRECL:   6,
+		BLKSIZE: 100,
+		Input:   []string{"abc"},
+		Error:   ErrTooLong,
+	},
+	{
+		Name:   "VBNoBDW",
+		Format: VB,
+		NoBDW:  true,
+		Input:  []string{"a", "bc"},
+		Output: "\x00\x05\x00\x00a\x00\x06\x00\x00bc",
+	},
+	{
+		Name:    "VBS",
+		Format:  VBS,
+		BLKSIZE: 14,
+		Input:   []string{"a", "bcde", "f"},
+		Output: "\x00\x0e\x00\x00\x00\x05\x00\x00a\x00\x05\x01\x00b" +
+			"\x00\x0b\x00\x00\x00\x07\x02\x00cde" +
+			"\x00\x09\x00\x00\x00\x05\x00\x00f",
+	},
+	{
+		Name:    "VBSMiddle",
+		Format:  VBS,
+		BLKSIZE: 10,
+		Input:   []string{"abcde"},
+		Output: "\x00\x0a\x00\x00\x00\x06\x01\x00ab" +
+			"\x00\x0a\x00\x00\x00\x06\x03\x00cd" +
+			"\x00\x09\x00\x00\x00\x05\x02\x00e",
+	},
+	{
+		Name:    "VBSBadBLKSIZE",
+		Format:  VBS,
+		BLKSIZE: 8,
+		Input:   []string{"a"},
+		Error:   errBLKSIZE,
+	},
+	{
+		Name:    "VBText",
+		Format:  VB,
+		BLKSIZE: 100,
+		Text:    true,
+		Input:   []string{"Hi\n"},
+		Output:  "\x00\x0b\x00\x00\x00\x07\x00\x00\xc8\x89\x15",
+	},
+}
+
+func TestWrite(t *testing.T) {
+	for _, tt := range writeTests {
+		b := &bytes.Buffer{}
+		w := NewWriter(b, tt.Format, tt.LRECL, tt.BLKSIZE)
+		w.NoBDW = tt.NoBDW
+		w.Text = tt.Text
+		var err error
+		for _, rec := range tt.Input {
+			if err = w.Write([]byte(rec)); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != tt.Error {
+			t.Errorf("%s: error %v, want %v", tt.Name, err, tt.Error)
+			continue
+		}
+		if err == nil && b.String() != tt.Output {
+			t.Errorf("%s: out=%q want %q", tt.Name, b.String(), tt.Output)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var recs []string
+	for i := 0; i < 200; i++ {
+		recs = append(recs, string(bytes.Repeat([]byte{byte(i)}, i%70)))
+	}
+	for _, f := range []Format{V, VB, VBS} {
+		for _, noBDW := range []bool{false, true} {
+			b := &bytes.Buffer{}
+			w := NewWriter(b, f, 0, 40)
+			w.NoBDW = noBDW
+			if f == VBS {
+				w.blksize = 29
+			}
+			for _, rec := range recs {
+				if err := w.Write([]byte(rec)); err != nil {
+					if f != VBS && !noBDW && len(rec) > 40-8 && err == ErrTooLong {
+						continue
+					}
+					t.Fatalf("%v, NoBDW=%v: Write: %v", f, noBDW, err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("%v, NoBDW=%v: Flush: %v", f, noBDW, err)
+			}
+
+			r := NewReader(b, f, 0)
+			r.NoBDW = noBDW
+			var got []string
+			for {
+				rec, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("%v, NoBDW=%v: Read: %v", f, noBDW, err)
+				}
+				got = append(got, string(rec))
+			}
+			var want []string
+			for _, rec := range recs {
+				if f == VBS || noBDW || len(rec) <= 40-8 {
+					want = append(want, rec)
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%v, NoBDW=%v: read %d records, want %d", f, noBDW, len(got), len(want))
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"errors"
+	"fmt"
+	"internal/ebcdic"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// The package converts the EBCDIC code pages IBM-037, IBM-500 and
+// IBM-1047, used by z/OS and CICS, to and from UTF-8 without help
+// from a CharsetReader.
+
+// ebcdicSignature is "<?xm" in EBCDIC. An XML document in an EBCDIC
+// code page that starts with an XML declaration starts with these
+// bytes (see appendix F of the XML specification). They are the same
+// in all the EBCDIC code pages the package converts.
+var ebcdicSignature = [4]byte{0x4c, 0x6f, 0xa7, 0x94}
+
+// ebcdicCodePage returns the EBCDIC code page named by charset,
+// or nil if charset does not name one the package converts.
+func ebcdicCodePage(charset string) *ebcdic.CodePage {
+	name := strings.ToUpper(charset)
+	name = strings.Replace(name, "-", "", -1)
+	name = strings.Replace(name, "_", "", -1)
+	switch name {
+	case "IBM037", "IBM37", "CP037", "CP37", "EBCDIC037", "EBCDICCPUS", "EBCDICCPCA", "CSIBM037":
+		return ebcdic.CodePage037
+	case "IBM500", "CP500", "EBCDIC500", "EBCDICCPBE", "EBCDICCPCH", "CSIBM500":
+		return ebcdic.CodePage500
+	case "IBM1047", "CP1047", "EBCDIC1047":
+		return ebcdic.CodePage1047
+	}
+	return nil
+}
+
+// checkCharset returns an error if charset is neither UTF-8 nor
+// a code page the package converts itself.
+func checkCharset(charset string) error {
+	if strings.EqualFold(charset, "utf-8") || ebcdicCodePage(charset) != nil {
+		return nil
+	}
+	return fmt.Errorf("xml: unsupported charset %q", charset)
+}
+
+// An ebcdicReader converts EBCDIC input to UTF-8. It converts the
+// input a byte at a time, as it is read, so that a change of code page
+// applies to the rest of the input.
+type ebcdicReader struct {
+	r   io.Reader
+	cp  *ebcdic.CodePage
+	in  [512]byte
+	raw []byte // input not yet converted
+	enc [utf8.UTFMax]byte
+	out []byte // rest of the UTF-8 encoding in enc of the last rune
+	err error
+}
+
+func newEBCDICReader(r io.Reader, cp *ebcdic.CodePage) *ebcdicReader {
+	return &ebcdicReader{r: r, cp: cp}
+}
+
+// ReadByte implements io.ByteReader so that the Decoder does not
+// need to buffer the converted input again.
+func (r *ebcdicReader) ReadByte() (byte, error) {
+	if len(r.out) > 0 {
+		b := r.out[0]
+		r.out = r.out[1:]
+		return b, nil
+	}
+	for len(r.raw) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		var n int
+		n, r.err = r.r.Read(r.in[:])
+		r.raw = r.in[:n]
+	}
+	c := r.cp.Rune(r.raw[0])
+	r.raw = r.raw[1:]
+	if c < utf8.RuneSelf {
+		return byte(c), nil
+	}
+	n := utf8.EncodeRune(r.enc[:], c)
+	r.out = r.enc[1:n]
+	return r.enc[0], nil
+}
+
+func (r *ebcdicReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		// Return what has been read rather than wait for more input.
+		if n > 0 && len(r.out) == 0 && len(r.raw) == 0 {
+			break
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				break
+			}
+			return 0, err
+		}
+		p[n] = b
+		n++
+	}
+	return n, nil
+}
+
+// An ebcdicWriter converts UTF-8 output to EBCDIC.
+type ebcdicWriter struct {
+	w       io.Writer
+	cp      *ebcdic.CodePage
+	partial []byte // incomplete UTF-8 sequence at the end of the last Write
+}
+
+func (w *ebcdicWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(w.partial) > 0 {
+		p = append(append([]byte(nil), w.partial...), p...)
+	}
+	runes := make([]rune, 0, len(p))
+	var partial []byte
+	for len(p) > 0 {
+		if !utf8.FullRune(p) {
+			partial = append([]byte(nil), p...)
+			break
+		}
+		c, size := utf8.DecodeRune(p)
+		runes = append(runes, c)
+		p = p[size:]
+	}
+	b, errStr := w.cp.Encode(runes)
+	if errStr != "" {
+		return 0, errors.New("xml: " + errStr)
+	}
+	if _, err := w.w.Write(b); err != nil {
+		return 0, err
+	}
+	w.partial = partial
+	return n, nil
+}
+
+// flush reports an error if the output written so far ends with an
+// incomplete UTF-8 sequence, which cannot be converted. The sequence
+// is discarded.
+func (w *ebcdicWriter) flush() error {
+	if len(w.partial) == 0 {
+		return nil
+	}
+	w.partial = nil
+	return errors.New("xml: output ends with an incomplete UTF-8 sequence")
+}
+
+// An errReader returns err from every Read.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,324 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"internal/ebcdic"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// toEBCDIC converts s to IBM-1047.
+func toEBCDIC(t *testing.T, s string) []byte {
+	return toCodePage(t, ebcdic.CodePage1047, s)
+}
+
+// toCodePage converts s to the EBCDIC code page cp.
+func toCodePage(t *testing.T, cp *ebcdic.CodePage, s string) []byte {
+	b, errStr := cp.Encode([]rune(s))
+	if errStr != "" {
+		t.Fatalf("%v.Encode(%q): %s", cp, s, errStr)
+	}
+	return b
+}
+
+// readTokens returns copies of all the tokens in d.
+func readTokens(d *Decoder) ([]Token, error) {
+	var toks []Token
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return toks, nil
+		}
+		if err != nil {
+			return toks, err
+		}
+		toks = append(toks, CopyToken(tok))
+	}
+}
+
+const ebcdicInput = `<?xml version="1.0" encoding="IBM-1047"?>
+<req xmlns="urn:cics" id="[1]">Hello ^|~ &amp; {}</req>
+`
+
+func TestDecodeEBCDIC(t *testing.T) {
+	want, err := readTokens(NewDecoder(strings.NewReader(ebcdicInput)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The signature at the start selects IBM-1047.
+	d := NewDecoder(bytes.NewReader(toEBCDIC(t, ebcdicInput)))
+	toks, err := readTokens(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(toks, want) {
+		t.Errorf("tokens:\nhave %#v\nwant %#v", toks, want)
+	}
+
+	// SetCharset selects it for input without an XML declaration.
+	body := ebcdicInput[strings.Index(ebcdicInput, "\n")+1:]
+	d = NewDecoder(bytes.NewReader(toEBCDIC(t, body)))
+	if err := d.SetCharset("ibm1047"); err != nil {
+		t.Fatal(err)
+	}
+	toks, err = readTokens(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(toks, want[2:]) {
+		t.Errorf("tokens with SetCharset:\nhave %#v\nwant %#v", toks, want[2:])
+	}
+	if err := d.SetCharset("UTF-8"); err == nil {
+		t.Errorf("SetCharset after reading did not fail")
+	}
+}
+
+func TestDecodeEBCDICMismatch(t *testing.T) {
+	in := toEBCDIC(t, `<?xml version="1.0" encoding="ISO-8859-1"?><a/>`)
+	d := NewDecoder(bytes.NewReader(in))
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		t.Fatalf("unexpected CharsetReader call for %q", charset)
+		return nil, nil
+	}
+	_, err := readTokens(d)
+	if want := `xml: encoding "ISO-8859-1" declared but input is EBCDIC`; err == nil || err.Error() != want {
+		t.Errorf("error %v, want %s", err, want)
+	}
+}
+
+// Test that the code page named by the XML declaration applies to
+// the rest of the input, and that SetCharset selects IBM-037 and
+// IBM-500. The brackets and the exclamation mark differ in the
+// three code pages.
+func TestDecodeEBCDICCodePage(t *testing.T) {
+	const body = `<a b="[!]">{|}</a>`
+	want := []Token{
+		StartElement{Name{"", "a"}, []Attr{{Name{"", "b"}, "[!]"}}},
+		CharData("{|}"),
+		EndElement{Name{"", "a"}},
+	}
+	for _, tt := range []struct {
+		charset string
+		cp      *ebcdic.CodePage
+	}{
+		{"IBM-037", ebcdic.CodePage037},
+		{"IBM-500", ebcdic.CodePage500},
+		{"cp1047", ebcdic.CodePage1047},
+	} {
+		decl := `<?xml version="1.0" encoding="` + tt.charset + `"?>`
+		d := NewDecoder(bytes.NewReader(toCodePage(t, tt.cp, decl+body)))
+		toks, err := readTokens(d)
+		if err != nil {
+			t.Errorf("%s: %v", tt.charset, err)
+			continue
+		}
+		if len(toks) != 1+len(want) || !reflect.DeepEqual(toks[1:], want) {
+			t.Errorf("%s: tokens:\nhave %#v\nwant %#v", tt.charset, toks, want)
+		}
+
+		d = NewDecoder(bytes.NewReader(toCodePage(t, tt.cp, body)))
+		if err := d.SetCharset(tt.charset); err != nil {
+			t.Fatal(err)
+		}
+		toks, err = readTokens(d)
+		if err != nil || !reflect.DeepEqual(toks, want) {
+			t.Errorf("%s with SetCharset: tokens:\nhave %#v, %v\nwant %#v", tt.charset, toks, err, want)
+		}
+	}
+}
+
+// Test that input starting like the EBCDIC signature is read
+// unchanged when it is not the signature.
+func TestDecodeNotEBCDIC(t *testing.T) {
+	for _, in := range []string{"L", "Lo", "Lox", "Lorem<a/>", "<a>L</a>"} {
+		d := NewDecoder(strings.NewReader(in))
+		var text []byte
+		for {
+			tok, err := d.RawToken()
+			if err != nil {
+				break
+			}
+			switch tok := tok.(type) {
+			case CharData:
+				text = append(text, tok...)
+			case StartElement:
+				text = append(text, "<"+tok.Name.Local+">"...)
+			}
+		}
+		want := strings.Replace(strings.Replace(in, "<a/>", "<a>", 1), "</a>", "", 1)
+		if string(text) != want {
+			t.Errorf("%q: read %q, want %q", in, text, want)
+		}
+		if d.InputOffset() != int64(len(in)) {
+			t.Errorf("%q: InputOffset() = %d, want %d", in, d.InputOffset(), len(in))
+		}
+	}
+}
+
+func TestEncodeEBCDIC(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.SetCharset("IBM-1047"); err != nil {
+		t.Fatal(err)
+	}
+	toks := []Token{
+		ProcInst{"xml", []byte(`version="1.0" encoding="IBM-1047"`)},
+		StartElement{Name{"", "a"}, []Attr{{Name{"", "b"}, "[{}]"}}},
+		CharData("Hello ^|~ & <>"),
+		EndElement{Name{"", "a"}},
+	}
+	for _, tok := range toks {
+		if err := enc.EncodeToken(tok); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := toEBCDIC(t, `<?xml version="1.0" encoding="IBM-1047"?><a b="[{}]">Hello ^|~ &amp; &lt;&gt;</a>`)
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("output % x, want % x", buf.Bytes(), want)
+	}
+
+	// The Decoder reads the output back.
+	toks, err := readTokens(NewDecoder(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toks) != 4 || !reflect.DeepEqual(toks[2], CharData("Hello ^|~ & <>")) {
+		t.Errorf("tokens %#v", toks)
+	}
+
+	enc.EncodeToken(CharData("x"))
+	if err := enc.SetCharset("IBM-1047"); err == nil {
+		t.Errorf("SetCharset after writing did not fail")
+	}
+	if err := NewEncoder(&buf).SetCharset("EBCDIC-273"); err == nil {
+		t.Errorf("SetCharset of an unsupported charset did not fail")
+	}
+}
+
+func TestEncodeEBCDICCodePage(t *testing.T) {
+	const doc = `<a b="[!]">{|}</a>`
+	for _, tt := range []struct {
+		charset string
+		cp      *ebcdic.CodePage
+	}{
+		{"IBM-037", ebcdic.CodePage037},
+		{"ibm500", ebcdic.CodePage500},
+	} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		if err := enc.SetCharset(tt.charset); err != nil {
+			t.Fatal(err)
+		}
+		toks := []Token{
+			StartElement{Name{"", "a"}, []Attr{{Name{"", "b"}, "[!]"}}},
+			CharData("{|}"),
+			EndElement{Name{"", "a"}},
+		}
+		for _, tok := range toks {
+			if err := enc.EncodeToken(tok); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := enc.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if want := toCodePage(t, tt.cp, doc); !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%s: output % x, want % x", tt.charset, buf.Bytes(), want)
+		}
+	}
+}
+
+func TestMarshalEBCDIC(t *testing.T) {
+	type item struct {
+		Name  string `xml:"name,attr"`
+		Price string `xml:"price"`
+	}
+	in := item{"size [5]", "{}1"}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetCharset("IBM-1047")
+	if err := enc.Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	var out item
+	d := NewDecoder(&buf)
+	if err := d.SetCharset("IBM-1047"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("round trip: have %+v, want %+v", out, in)
+	}
+}
+
+// Test that the EBCDIC writer handles a UTF-8 sequence split
+// between two writes.
+func TestEBCDICWriterSplitRune(t *testing.T) {
+	var buf bytes.Buffer
+	w := &ebcdicWriter{w: &buf, cp: ebcdic.CodePage1047}
+	s := "a\u00a0"
+	if n, err := w.Write([]byte(s[:2])); n != 2 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if n, err := w.Write([]byte(s[2:])); n != 1 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if want := toEBCDIC(t, s); !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("output % x, want % x", buf.Bytes(), want)
+	}
+}
+
+// Test that flush reports output ending with an incomplete
+// UTF-8 sequence, which cannot be converted.
+func TestEBCDICWriterIncomplete(t *testing.T) {
+	var buf bytes.Buffer
+	w := &ebcdicWriter{w: &buf, cp: ebcdic.CodePage1047}
+	if n, err := w.Write([]byte("a\xc3")); n != 2 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	want := "xml: output ends with an incomplete UTF-8 sequence"
+	if err := w.flush(); err == nil || err.Error() != want {
+		t.Errorf("flush: error %v, want %s", err, want)
+	}
+	if err := w.flush(); err != nil {
+		t.Errorf("second flush: %v", err)
+	}
+	if want := toEBCDIC(t, "a"); !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("output % x, want % x", buf.Bytes(), want)
+	}
+}
+
+// Test that a Write that fails keeps the incomplete UTF-8 sequence
+// left by the Write before it.
+func TestEBCDICWriterError(t *testing.T) {
+	var buf bytes.Buffer
+	w := &ebcdicWriter{w: &buf, cp: ebcdic.CodePage1047}
+	s := "\u00a0"
+	if n, err := w.Write([]byte(s[:1])); n != 1 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if n, err := w.Write([]byte("\u4e16")); n != 0 || err == nil {
+		t.Fatalf("Write of a rune not in the code page = %d, %v", n, err)
+	}
+	if n, err := w.Write([]byte(s[1:])); n != 1 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if err := w.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := toEBCDIC(t, s); !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("output % x, want % x", buf.Bytes(), want)
+	}
+}
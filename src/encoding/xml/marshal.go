@@ -8,6 +8,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -132,7 +133,7 @@ type Encoder struct {
 
 // NewEncoder returns a new encoder that writes to w.
 func NewEncoder(w io.Writer) *Encoder {
-	e := &Encoder{printer{Writer: bufio.NewWriter(w)}}
+	e := &Encoder{printer{Writer: bufio.NewWriter(w), w: w}}
 	e.p.encoder = e
 	return e
 }
@@ -145,6 +146,77 @@ func (enc *Encoder) Indent(prefix, indent string) {
 	enc.p.indent = indent
 }
 
+// SetPrefix sets the encoder to use prefix for attributes in the
+// name space url. Without it, the encoder derives a prefix from the
+// last element of the url's path. If prefix is already in use for
+// another name space where it is needed, a variant of it is used.
+//
+// The prefix must be a valid XML name without colons that does not
+// begin with "xml". SetPrefix("", url) restores the default.
+func (enc *Encoder) SetPrefix(prefix, url string) error {
+	if url == "" || url == xmlURL {
+		return fmt.Errorf("xml: cannot set prefix for name space %q", url)
+	}
+	if prefix == "" {
+		delete(enc.p.preferredPrefix, url)
+		return nil
+	}
+	if !isName([]byte(prefix)) || strings.Contains(prefix, ":") || strings.HasPrefix(strings.ToLower(prefix), "xml") {
+		return fmt.Errorf("xml: invalid name space prefix %q", prefix)
+	}
+	if enc.p.preferredPrefix == nil {
+		enc.p.preferredPrefix = make(map[string]string)
+	}
+	enc.p.preferredPrefix[url] = prefix
+	return nil
+}
+
+// SetCharset sets the character encoding of the encoder's output to
+// charset, which must be "UTF-8" (the default) or one of the EBCDIC
+// code pages "IBM-037", "IBM-500" and "IBM-1047". Other character
+// sets must be converted by the caller.
+// It must be called before anything is written. SetCharset does not
+// write an XML declaration; to declare the encoding, pass a ProcInst
+// with the xml target to EncodeToken.
+func (enc *Encoder) SetCharset(charset string) error {
+	if err := checkCharset(charset); err != nil {
+		return err
+	}
+	if enc.p.Buffered() != 0 {
+		return errors.New("xml: SetCharset called after writing output")
+	}
+	w := enc.p.w
+	enc.p.ebcdic = nil
+	if cp := ebcdicCodePage(charset); cp != nil {
+		enc.p.ebcdic = &ebcdicWriter{w: w, cp: cp}
+		w = enc.p.ebcdic
+	}
+	enc.p.Writer = bufio.NewWriter(w)
+	return nil
+}
+
+// RegisterMarshaler arranges for the encoder to encode the values of
+// type typ, which need not be defined in the caller's package, by
+// calling f as if typ had f as its MarshalXML method. The value is
+// passed to f as v. If typ is a pointer type, f is used for values of
+// the pointed-to type that are addressable, as a MarshalXML method
+// with a pointer receiver would be.
+//
+// A registered function takes precedence over the Marshaler and
+// TextMarshaler methods of typ. It is used only for values encoded as
+// XML elements, not for attributes or character data.
+// RegisterMarshaler(typ, nil) removes the registration.
+func (enc *Encoder) RegisterMarshaler(typ reflect.Type, f func(e *Encoder, v interface{}, start StartElement) error) {
+	if f == nil {
+		delete(enc.p.marshalers, typ)
+		return
+	}
+	if enc.p.marshalers == nil {
+		enc.p.marshalers = make(map[reflect.Type]func(*Encoder, interface{}, StartElement) error)
+	}
+	enc.p.marshalers[typ] = f
+}
+
 // Encode writes the XML encoding of v to the stream.
 //
 // See the documentation for Marshal for details about the conversion
@@ -296,6 +368,7 @@ func (enc *Encoder) Flush() error {
 
 type printer struct {
 	*bufio.Writer
+	w          io.Writer // underlying writer
 	encoder    *Encoder
 	seq        int
 	indent     string
@@ -307,6 +380,26 @@ type printer struct {
 	attrPrefix map[string]string // map name space -> prefix
 	prefixes   []string
 	tags       []Name
+
+	preferredPrefix map[string]string // map name space -> prefix set by SetPrefix
+
+	// marshalers holds the functions set by RegisterMarshaler.
+	marshalers map[reflect.Type]func(*Encoder, interface{}, StartElement) error
+
+	ebcdic *ebcdicWriter // converting the output to EBCDIC, or nil
+}
+
+// Flush flushes the buffered output. It reports an error if the
+// output is converted to EBCDIC and ends with an incomplete UTF-8
+// sequence.
+func (p *printer) Flush() error {
+	if err := p.Writer.Flush(); err != nil {
+		return err
+	}
+	if p.ebcdic != nil {
+		return p.ebcdic.flush()
+	}
+	return nil
 }
 
 // createAttrPrefix finds the name space prefix attribute to use for the given name space,
@@ -330,18 +423,21 @@ func (p *printer) createAttrPrefix(url string) string {
 		p.attrNS = make(map[string]string)
 	}
 
-	// Pick a name. We try to use the final element of the path
-	// but fall back to _.
-	prefix := strings.TrimRight(url, "/")
-	if i := strings.LastIndex(prefix, "/"); i >= 0 {
-		prefix = prefix[i+1:]
-	}
-	if prefix == "" || !isName([]byte(prefix)) || strings.Contains(prefix, ":") {
-		prefix = "_"
-	}
-	if strings.HasPrefix(prefix, "xml") {
-		// xmlanything is reserved.
-		prefix = "_" + prefix
+	// Pick a name. We use the one given to SetPrefix, or try to
+	// use the final element of the path but fall back to _.
+	prefix := p.preferredPrefix[url]
+	if prefix == "" {
+		prefix = strings.TrimRight(url, "/")
+		if i := strings.LastIndex(prefix, "/"); i >= 0 {
+			prefix = prefix[i+1:]
+		}
+		if prefix == "" || !isName([]byte(prefix)) || strings.Contains(prefix, ":") {
+			prefix = "_"
+		}
+		if strings.HasPrefix(prefix, "xml") {
+			// xmlanything is reserved.
+			prefix = "_" + prefix
+		}
 	}
 	if p.attrNS[prefix] != "" {
 		// Name is taken. Find a better one.
@@ -421,6 +517,17 @@ func (p *printer) marshalValue(val reflect.Value, finfo *fieldInfo, startTemplat
 	kind := val.Kind()
 	typ := val.Type()
 
+	// Check for a registered marshaler.
+	if f := p.marshalers[typ]; f != nil && val.CanInterface() {
+		return p.marshalFunc(f, val.Interface(), defaultStart(typ, finfo, startTemplate))
+	}
+	if val.CanAddr() {
+		pv := val.Addr()
+		if f := p.marshalers[pv.Type()]; f != nil && pv.CanInterface() {
+			return p.marshalFunc(f, pv.Interface(), defaultStart(pv.Type(), finfo, startTemplate))
+		}
+	}
+
 	// Check for marshaler.
 	if val.CanInterface() && typ.Implements(marshalerType) {
 		return p.marshalInterface(val.Interface().(Marshaler), defaultStart(typ, finfo, startTemplate))
@@ -639,6 +746,24 @@ func (p *printer) marshalInterface(val Marshaler, start StartElement) error {
 	return nil
 }
 
+// marshalFunc marshals v with the function f registered for its type.
+func (p *printer) marshalFunc(f func(*Encoder, interface{}, StartElement) error, v interface{}, start StartElement) error {
+	// Push a marker onto the tag stack, as for MarshalXML.
+	p.tags = append(p.tags, Name{})
+	n := len(p.tags)
+
+	err := f(p.encoder, v, start)
+	if err != nil {
+		return err
+	}
+
+	if len(p.tags) > n {
+		return fmt.Errorf("xml: marshaler registered for %s wrote invalid XML: <%s> not closed", reflect.TypeOf(v), p.tags[len(p.tags)-1].Local)
+	}
+	p.tags = p.tags[:n-1]
+	return nil
+}
+
 // marshalTextInterface marshals a TextMarshaler interface value.
 func (p *printer) marshalTextInterface(val encoding.TextMarshaler, start StartElement) error {
 	if err := p.writeStart(&start); err != nil {
@@ -1823,3 +1823,104 @@ func TestSimpleUseOfEncodeToken(t *testing.T) {
 		t.Errorf("enc.EncodeToken: expected %q; got %q", want, buf.String())
 	}
 }
+
+func TestSetPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.SetPrefix("soap", "http://schemas.xmlsoap.org/soap/envelope/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.SetPrefix("a", "urn:a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.SetPrefix("a", "urn:b"); err != nil {
+		t.Fatal(err)
+	}
+	toks := []Token{
+		StartElement{Name{"", "x"}, []Attr{
+			{Name{"http://schemas.xmlsoap.org/soap/envelope/", "mustUnderstand"}, "1"},
+			{Name{"urn:a", "y"}, "2"},
+			{Name{"urn:b", "z"}, "3"},
+			{Name{"urn:c", "w"}, "4"},
+		}},
+		EndElement{Name{"", "x"}},
+	}
+	for _, tok := range toks {
+		if err := enc.EncodeToken(tok); err != nil {
+			t.Fatal(err)
+		}
+	}
+	enc.Flush()
+	want := `<x xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" soap:mustUnderstand="1" xmlns:a="urn:a" a:y="2" xmlns:a_1="urn:b" a_1:z="3" xmlns:_="urn:c" _:w="4"></x>`
+	if buf.String() != want {
+		t.Errorf("SetPrefix:\nhave %s\nwant %s", buf.String(), want)
+	}
+
+	for _, prefix := range []string{"a:b", "1a", "XMLfoo", "a b"} {
+		if err := enc.SetPrefix(prefix, "urn:a"); err == nil {
+			t.Errorf("SetPrefix(%q) did not fail", prefix)
+		}
+	}
+	if err := enc.SetPrefix("x", xmlURL); err == nil {
+		t.Errorf("SetPrefix for the xml name space did not fail")
+	}
+}
+
+type stamp struct {
+	At   time.Time  `xml:"at"`
+	Ptr  *time.Time `xml:"ptr"`
+	Attr time.Time  `xml:"attr,attr"`
+}
+
+type counter int
+
+type counted struct {
+	C counter
+}
+
+func TestRegisterMarshaler(t *testing.T) {
+	at := time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC)
+	day := func(e *Encoder, v interface{}, start StartElement) error {
+		return e.EncodeElement(v.(time.Time).Format("2006-01-02"), start)
+	}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.RegisterMarshaler(reflect.TypeOf(time.Time{}), day)
+	enc.RegisterMarshaler(reflect.TypeOf(new(counter)), func(e *Encoder, v interface{}, start StartElement) error {
+		return e.EncodeElement(int(*v.(*counter))+1, start)
+	})
+	if err := enc.Encode(&stamp{at, &at, at}); err != nil {
+		t.Fatal(err)
+	}
+	// The registration for *counter applies to addressable values only.
+	if err := enc.Encode(&counted{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(counted{1}); err != nil {
+		t.Fatal(err)
+	}
+	want := `<stamp attr="2016-01-02T15:04:05Z"><at>2016-01-02</at><ptr>2016-01-02</ptr></stamp>` +
+		`<counted><C>2</C></counted><counted><C>1</C></counted>`
+	if buf.String() != want {
+		t.Errorf("RegisterMarshaler:\nhave %s\nwant %s", buf.String(), want)
+	}
+
+	buf.Reset()
+	enc.RegisterMarshaler(reflect.TypeOf(time.Time{}), nil)
+	if err := enc.Encode(&stamp{At: at}); err != nil {
+		t.Fatal(err)
+	}
+	want = `<stamp attr="0001-01-01T00:00:00Z"><at>2016-01-02T15:04:05Z</at></stamp>`
+	if buf.String() != want {
+		t.Errorf("after removing the registration:\nhave %s\nwant %s", buf.String(), want)
+	}
+
+	enc.RegisterMarshaler(reflect.TypeOf(time.Time{}), func(e *Encoder, v interface{}, start StartElement) error {
+		return e.EncodeToken(start)
+	})
+	err := enc.Encode(&stamp{At: at})
+	want = "xml: marshaler registered for time.Time wrote invalid XML: <at> not closed"
+	if err == nil || err.Error() != want {
+		t.Errorf("unclosed element: error %v, want %s", err, want)
+	}
+}
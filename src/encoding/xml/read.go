@@ -133,6 +133,26 @@ func (d *Decoder) DecodeElement(v interface{}, start *StartElement) error {
 	return d.unmarshal(val.Elem(), start)
 }
 
+// RegisterUnmarshaler arranges for the decoder to decode XML elements
+// into values of type typ, which need not be defined in the caller's
+// package, by calling f as if *typ had f as its UnmarshalXML method.
+// A pointer to the value is passed to f as v.
+//
+// A registered function takes precedence over the Unmarshaler and
+// TextUnmarshaler methods of typ. It is used only for values decoded
+// from XML elements, not from attributes or character data.
+// RegisterUnmarshaler(typ, nil) removes the registration.
+func (d *Decoder) RegisterUnmarshaler(typ reflect.Type, f func(d *Decoder, v interface{}, start StartElement) error) {
+	if f == nil {
+		delete(d.unmarshalers, typ)
+		return
+	}
+	if d.unmarshalers == nil {
+		d.unmarshalers = make(map[reflect.Type]func(*Decoder, interface{}, StartElement) error)
+	}
+	d.unmarshalers[typ] = f
+}
+
 // An UnmarshalError represents an error in the unmarshalling process.
 type UnmarshalError string
 
@@ -199,6 +219,26 @@ func (p *Decoder) unmarshalInterface(val Unmarshaler, start *StartElement) error
 	return nil
 }
 
+// unmarshalFunc unmarshals a single XML element into the value v
+// points to with the function f registered for its type.
+func (p *Decoder) unmarshalFunc(f func(*Decoder, interface{}, StartElement) error, v interface{}, start *StartElement) error {
+	p.pushEOF()
+
+	p.unmarshalDepth++
+	err := f(p, v, *start)
+	p.unmarshalDepth--
+	if err != nil {
+		p.popEOF()
+		return err
+	}
+
+	if !p.popEOF() {
+		return fmt.Errorf("xml: unmarshaler registered for %s did not consume entire <%s> element", reflect.TypeOf(v).Elem(), start.Name.Local)
+	}
+
+	return nil
+}
+
 // unmarshalTextInterface unmarshals a single XML element into val.
 // The chardata contained in the element (but not its children)
 // is passed to the text unmarshaler.
@@ -300,6 +340,12 @@ func (p *Decoder) unmarshal(val reflect.Value, start *StartElement) error {
 		val = val.Elem()
 	}
 
+	if f := p.unmarshalers[val.Type()]; f != nil && val.CanAddr() {
+		if pv := val.Addr(); pv.CanInterface() {
+			return p.unmarshalFunc(f, pv.Interface(), start)
+		}
+	}
+
 	if val.CanInterface() && val.Type().Implements(unmarshalerType) {
 		// This is an unmarshaler with a non-pointer receiver,
 		// so it's likely to be incorrect, but we do what we're told.
@@ -733,3 +733,46 @@ func TestMalformedComment(t *testing.T) {
 		}
 	}
 }
+
+func TestRegisterUnmarshaler(t *testing.T) {
+	day := func(d *Decoder, v interface{}, start StartElement) error {
+		var s string
+		if err := d.DecodeElement(&s, &start); err != nil {
+			return err
+		}
+		at, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return err
+		}
+		*v.(*time.Time) = at
+		return nil
+	}
+	d := NewDecoder(strings.NewReader(`<stamp attr="2016-01-02T15:04:05Z"><at>2016-01-02</at><ptr>2016-01-03</ptr></stamp>`))
+	d.RegisterUnmarshaler(reflect.TypeOf(time.Time{}), day)
+	var s stamp
+	if err := d.Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC)
+	if !s.At.Equal(at) || s.Ptr == nil || !s.Ptr.Equal(at.AddDate(0, 0, 1)) || !s.Attr.Equal(at.Add(15*time.Hour+4*time.Minute+5*time.Second)) {
+		t.Errorf("RegisterUnmarshaler: have %+v", s)
+	}
+
+	d = NewDecoder(strings.NewReader(`<stamp><at>2016-01-02T15:04:05Z</at></stamp>`))
+	d.RegisterUnmarshaler(reflect.TypeOf(time.Time{}), day)
+	d.RegisterUnmarshaler(reflect.TypeOf(time.Time{}), nil)
+	s = stamp{}
+	if err := d.Decode(&s); err != nil || !s.At.Equal(at.Add(15*time.Hour+4*time.Minute+5*time.Second)) {
+		t.Errorf("after removing the registration: have %+v, %v", s, err)
+	}
+
+	d = NewDecoder(strings.NewReader(`<stamp><at>2016-01-02</at></stamp>`))
+	d.RegisterUnmarshaler(reflect.TypeOf(time.Time{}), func(d *Decoder, v interface{}, start StartElement) error {
+		return nil
+	})
+	err := d.Decode(&s)
+	want := "xml: unmarshaler registered for time.Time did not consume entire <at> element"
+	if err == nil || err.Error() != want {
+		t.Errorf("unconsumed element: error %v, want %s", err, want)
+	}
+}
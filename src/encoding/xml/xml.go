@@ -18,7 +18,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"internal/ebcdic"
 	"io"
+	"reflect"
 	"strconv"
 	"strings"
 	"unicode"
@@ -154,8 +156,17 @@ type Decoder struct {
 	// Strict mode does not enforce the requirements of the XML name spaces TR.
 	// In particular it does not reject name space tags using undefined prefixes.
 	// Such tags are recorded with the unknown prefix as the name space URL.
+	// Set StrictNamespaces to enforce them.
 	Strict bool
 
+	// StrictNamespaces, if true, makes Token enforce the requirements
+	// of the XML name spaces TR. Token returns a SyntaxError for
+	// a name using an undeclared prefix, for a declaration binding
+	// a prefix to an empty name space or declaring one of the reserved
+	// prefixes xml and xmlns, and for two attributes of an element
+	// with the same local name and name space.
+	StrictNamespaces bool
+
 	// When Strict == false, AutoClose indicates a set of elements to
 	// consider closed immediately after they are opened, regardless
 	// of whether an end element is present.
@@ -177,6 +188,15 @@ type Decoder struct {
 	// non-UTF-8 charset into UTF-8. If CharsetReader is nil or
 	// returns an error, parsing stops with an error. One of the
 	// the CharsetReader's result values must be non-nil.
+	//
+	// The Decoder converts the EBCDIC code pages IBM-037, IBM-500
+	// and IBM-1047 itself. It recognizes a document in EBCDIC that
+	// starts with an XML declaration, reading it as IBM-1047 until
+	// the declaration names the code page, and SetCharset selects
+	// the code page of one that does not. CharsetReader is not called
+	// for such a document. Other EBCDIC code pages are not recognized
+	// and need a CharsetReader, and SetCharset for a document without
+	// an XML declaration.
 	CharsetReader func(charset string, input io.Reader) (io.Reader, error)
 
 	// DefaultSpace sets the default name space used for unadorned tags,
@@ -198,6 +218,11 @@ type Decoder struct {
 	line           int
 	offset         int64
 	unmarshalDepth int
+	sniffed        bool          // checked the start of the input for EBCDIC
+	ebcdic         *ebcdicReader // converting the input from EBCDIC, or nil
+
+	// unmarshalers holds the functions set by RegisterUnmarshaler.
+	unmarshalers map[reflect.Type]func(*Decoder, interface{}, StartElement) error
 }
 
 // NewDecoder creates a new XML parser reading from r.
@@ -260,6 +285,13 @@ func (d *Decoder) Token() (t Token, err error) {
 	}
 	switch t1 := t.(type) {
 	case StartElement:
+		if d.StrictNamespaces {
+			if err := d.checkNamespaces(t1); err != nil {
+				d.err = err
+				return nil, d.err
+			}
+		}
+
 		// In XML name spaces, the translations listed in the
 		// attributes apply to the element name and
 		// to the other attribute names, so process
@@ -282,6 +314,16 @@ func (d *Decoder) Token() (t Token, err error) {
 		for i := range t1.Attr {
 			d.translate(&t1.Attr[i].Name, false)
 		}
+		if d.StrictNamespaces {
+			for i, a := range t1.Attr {
+				for _, b := range t1.Attr[:i] {
+					if a.Name == b.Name {
+						d.err = d.syntaxError("attribute " + a.Name.Local + " in name space " + a.Name.Space + " appears twice")
+						return nil, d.err
+					}
+				}
+			}
+		}
 		d.pushElement(t1.Name)
 		t = t1
 
@@ -297,6 +339,47 @@ func (d *Decoder) Token() (t Token, err error) {
 
 const xmlURL = "http://www.w3.org/XML/1998/namespace"
 
+// checkNamespaces checks the name space declarations in start and
+// the prefixes of its names, before they are translated.
+func (d *Decoder) checkNamespaces(start StartElement) error {
+	declared := func(prefix string) bool {
+		if prefix == "" || prefix == "xml" || prefix == "xmlns" {
+			return true
+		}
+		if _, ok := d.ns[prefix]; ok {
+			return true
+		}
+		for _, a := range start.Attr {
+			if a.Name.Space == "xmlns" && a.Name.Local == prefix {
+				return true
+			}
+		}
+		return false
+	}
+	for _, a := range start.Attr {
+		if a.Name.Space != "xmlns" {
+			continue
+		}
+		switch {
+		case a.Name.Local == "xmlns":
+			return d.syntaxError("reserved prefix xmlns declared")
+		case a.Name.Local == "xml" && a.Value != xmlURL:
+			return d.syntaxError("reserved prefix xml bound to " + a.Value)
+		case a.Value == "":
+			return d.syntaxError("prefix " + a.Name.Local + " bound to empty name space")
+		}
+	}
+	if !declared(start.Name.Space) {
+		return d.syntaxError("undeclared name space prefix " + start.Name.Space + " in element " + start.Name.Local)
+	}
+	for _, a := range start.Attr {
+		if !declared(a.Name.Space) {
+			return d.syntaxError("undeclared name space prefix " + a.Name.Space + " in attribute " + a.Name.Local)
+		}
+	}
+	return nil
+}
+
 // Apply name space translation to name n.
 // The default name space (for Space=="")
 // applies only to element names, not to attribute names.
@@ -318,6 +401,69 @@ func (d *Decoder) translate(n *Name, isElementName bool) {
 	}
 }
 
+// SetCharset sets the character encoding of the input to charset,
+// which must be "UTF-8" or one of the EBCDIC code pages "IBM-037",
+// "IBM-500" and "IBM-1047". It is needed only for a document in
+// EBCDIC that does not start with an XML declaration, and must be
+// called before the first call to Token or RawToken.
+func (d *Decoder) SetCharset(charset string) error {
+	if err := checkCharset(charset); err != nil {
+		return err
+	}
+	if d.offset != 0 || d.nextByte >= 0 {
+		return errors.New("xml: SetCharset called after reading input")
+	}
+	d.sniffed = true
+	cp := ebcdicCodePage(charset)
+	switch {
+	case cp == nil:
+	case d.ebcdic != nil:
+		d.ebcdic.cp = cp
+	default:
+		d.ebcdic = newEBCDICReader(d.r.(io.Reader), cp)
+		d.switchToReader(d.ebcdic)
+	}
+	return nil
+}
+
+// sniffEBCDIC checks whether the input starts with the EBCDIC
+// signature and if so converts the rest of the input from EBCDIC.
+// It is read as IBM-1047 until the XML declaration names the code page.
+func (d *Decoder) sniffEBCDIC() {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		d.err = err
+		return
+	}
+	if b != ebcdicSignature[0] {
+		d.nextByte = int(b)
+		return
+	}
+
+	// Unlikely but possible: a UTF-8 document starting with 'L'.
+	sig := []byte{b}
+	for len(sig) < len(ebcdicSignature) {
+		b, err = d.r.ReadByte()
+		if err != nil {
+			break
+		}
+		sig = append(sig, b)
+		if b != ebcdicSignature[len(sig)-1] {
+			break
+		}
+	}
+	rest := d.r.(io.Reader)
+	if err != nil {
+		rest = errReader{err}
+	}
+	r := io.MultiReader(bytes.NewReader(sig), rest)
+	if len(sig) == len(ebcdicSignature) && sig[len(sig)-1] == ebcdicSignature[len(sig)-1] {
+		d.ebcdic = newEBCDICReader(r, ebcdic.CodePage1047)
+		r = d.ebcdic
+	}
+	d.switchToReader(r)
+}
+
 func (d *Decoder) switchToReader(r io.Reader) {
 	// Get efficient byte at a time reader.
 	// Assume that if reader has its own
@@ -585,6 +731,19 @@ func (d *Decoder) rawToken() (Token, error) {
 			}
 			enc := procInst("encoding", content)
 			if enc != "" && enc != "utf-8" && enc != "UTF-8" && !strings.EqualFold(enc, "utf-8") {
+				// An EBCDIC declaration that could be read is already
+				// converted, either by the Decoder or by the caller.
+				// The code page it names applies to the rest of the input.
+				if cp := ebcdicCodePage(enc); cp != nil && (d.ebcdic != nil || d.CharsetReader == nil) {
+					if d.ebcdic != nil {
+						d.ebcdic.cp = cp
+					}
+					return ProcInst{target, data}, nil
+				}
+				if d.ebcdic != nil {
+					d.err = fmt.Errorf("xml: encoding %q declared but input is EBCDIC", enc)
+					return nil, d.err
+				}
 				if d.CharsetReader == nil {
 					d.err = fmt.Errorf("xml: encoding %q declared but Decoder.CharsetReader is nil", enc)
 					return nil, d.err
@@ -863,6 +1022,15 @@ func (d *Decoder) getc() (b byte, ok bool) {
 	if d.err != nil {
 		return 0, false
 	}
+	if !d.sniffed {
+		d.sniffed = true
+		if d.nextByte < 0 {
+			d.sniffEBCDIC()
+			if d.err != nil {
+				return 0, false
+			}
+		}
+	}
 	if d.nextByte >= 0 {
 		b = byte(d.nextByte)
 		d.nextByte = -1
@@ -781,6 +781,7 @@ func TestIssue12417(t *testing.T) {
 		{`<?xml encoding="UTF-8" version="1.0"?><root/>`, true},
 		{`<?xml encoding="utf-8" version="1.0"?><root/>`, true},
 		{`<?xml encoding="uuu-9" version="1.0"?><root/>`, false},
+		{`<?xml encoding="IBM-1047" version="1.0"?><root/>`, true},
 	}
 	for _, tc := range testCases {
 		d := NewDecoder(strings.NewReader(tc.s))
@@ -803,3 +804,46 @@ func TestIssue12417(t *testing.T) {
 		}
 	}
 }
+
+var strictNamespaceTests = []struct {
+	in  string
+	err string
+}{
+	{in: `<a xmlns:p="u"><p:b p:c="1" c="2"/></a>`},
+	{in: `<a xmlns="u" xml:lang="en"><b xmlns=""/></a>`},
+	{in: `<a xmlns:xml="http://www.w3.org/XML/1998/namespace"/>`},
+	{in: `<a><p:b/></a>`, err: "XML syntax error on line 1: undeclared name space prefix p in element b"},
+	{in: `<a p:c="1"/>`, err: "XML syntax error on line 1: undeclared name space prefix p in attribute c"},
+	{in: `<a xmlns:p="u"/><p:b/>`, err: "XML syntax error on line 1: undeclared name space prefix p in element b"},
+	{in: `<a xmlns:p=""/>`, err: "XML syntax error on line 1: prefix p bound to empty name space"},
+	{in: `<a xmlns:xmlns="u"/>`, err: "XML syntax error on line 1: reserved prefix xmlns declared"},
+	{in: `<a xmlns:xml="u"/>`, err: "XML syntax error on line 1: reserved prefix xml bound to u"},
+	{in: `<a xmlns:p="u" xmlns:q="u" p:c="1" q:c="2"/>`, err: "XML syntax error on line 1: attribute c in name space u appears twice"},
+}
+
+func TestStrictNamespaces(t *testing.T) {
+	for _, tt := range strictNamespaceTests {
+		d := NewDecoder(strings.NewReader(tt.in))
+		d.StrictNamespaces = true
+		var err error
+		for err == nil {
+			_, err = d.Token()
+		}
+		if err == io.EOF {
+			err = nil
+		}
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%s: error %v, want %q", tt.in, err, tt.err)
+		}
+
+		// Without StrictNamespaces, the input is accepted.
+		d = NewDecoder(strings.NewReader(tt.in))
+		err = nil
+		for err == nil {
+			_, err = d.Token()
+		}
+		if err != io.EOF && tt.err != "" {
+			t.Errorf("%s: error %v without StrictNamespaces", tt.in, err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package analysis defines the interface between a modular static
+// analysis and an analysis driver program.
+//
+// An analysis is described by an Analyzer: its name, documentation,
+// command-line flags, the analyses it depends on, and a Run function
+// that inspects a single package, presented to it as a Pass, and
+// reports problems as Diagnostics.
+//
+// A driver program, such as cmd/vet or a program built with the
+// go/analysis/multichecker package, loads each package, runs the
+// selected analyzers on it in dependency order, and prints the
+// diagnostics they report. See the go/analysis/checker package for
+// the machinery shared by drivers.
+//
+// Facts
+//
+// Analyzers that need to know something about the functions or types
+// of other packages, such as whether a function wraps a call to
+// fmt.Printf, compute facts. A fact is a value attached to a
+// package-level object or to a package, produced while analyzing the
+// package declaring it and made available when analyzing the packages
+// that import it. An analyzer lists the types of the facts it uses in
+// its FactTypes field, and the driver arranges to analyze the
+// dependencies of a package before the package itself.
+//
+// Suggested fixes
+//
+// A Diagnostic may carry SuggestedFixes, each a set of edits to the
+// source that would resolve the problem. Drivers may apply them on
+// request.
+package analysis
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+)
+
+// An Analyzer describes an analysis function and its options.
+type Analyzer struct {
+	// Name is the name of the analyzer. It must be a valid Go
+	// identifier, as it may appear in command-line flags.
+	Name string
+
+	// Doc is the documentation for the analyzer.
+	// The first sentence is used as a summary in flag usage.
+	Doc string
+
+	// Flags defines any flags accepted by the analyzer.
+	// Drivers make them available on the command line as
+	// -Name.flagname.
+	Flags flag.FlagSet
+
+	// Run applies the analyzer to a package.
+	// It returns an error if the analyzer failed.
+	//
+	// On success, the result is made available to the analyzers
+	// that require this one, and its type must be ResultType.
+	Run func(*Pass) (interface{}, error)
+
+	// RunDespiteErrors allows the driver to run the analyzer even
+	// on a package that contains type errors, in which case the
+	// type information of the Pass is incomplete.
+	RunDespiteErrors bool
+
+	// Requires is the set of analyzers that must run successfully
+	// on a package before this one. Their results are available
+	// in Pass.ResultOf.
+	Requires []*Analyzer
+
+	// ResultType is the type of the result of Run,
+	// or nil if the analyzer returns no result.
+	ResultType reflect.Type
+
+	// FactTypes lists the types of facts the analyzer imports and
+	// exports, each represented by a pointer to a zero value.
+	// An analyzer that uses facts is run on the dependencies of a
+	// package before the package itself.
+	FactTypes []Fact
+}
+
+func (a *Analyzer) String() string { return a.Name }
+
+// A Pass provides information to the Run function of an Analyzer
+// applied to a single package, and the operations it may perform.
+type Pass struct {
+	Analyzer *Analyzer // the analyzer being run
+
+	Fset       *token.FileSet // file position information
+	Files      []*ast.File    // the abstract syntax tree of each Go file
+	OtherFiles []string       // names of the non-Go files of the package, such as assembly
+	Pkg        *types.Package // type information about the package
+	TypesInfo  *types.Info    // type information about the syntax trees
+
+	// Report reports a Diagnostic, a finding about a specific
+	// location in the analyzed source code.
+	Report func(Diagnostic)
+
+	// ResultOf maps each analyzer in Analyzer.Requires to its result.
+	ResultOf map[*Analyzer]interface{}
+
+	// ImportObjectFact retrieves the fact of the type of fact
+	// associated with obj, which may belong to another package,
+	// copies it into fact, and reports whether it was found.
+	// fact must be a pointer of one of the FactTypes of the analyzer.
+	ImportObjectFact func(obj types.Object, fact Fact) bool
+
+	// ExportObjectFact associates fact with obj, a package-level
+	// object or method of the package being analyzed.
+	ExportObjectFact func(obj types.Object, fact Fact)
+
+	// ImportPackageFact retrieves the fact of the type of fact
+	// associated with pkg, copies it into fact, and reports
+	// whether it was found.
+	ImportPackageFact func(pkg *types.Package, fact Fact) bool
+
+	// ExportPackageFact associates fact with the package being analyzed.
+	ExportPackageFact func(fact Fact)
+}
+
+// Reportf is a helper function that reports a Diagnostic with the
+// given position and formatted message.
+func (pass *Pass) Reportf(pos token.Pos, format string, args ...interface{}) {
+	pass.Report(Diagnostic{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+func (pass *Pass) String() string {
+	return fmt.Sprintf("%s@%s", pass.Analyzer.Name, pass.Pkg.Path())
+}
+
+// A Fact is an intermediate fact produced during analysis.
+//
+// Facts must be pointers to types that are safe to copy, and each
+// fact type must be listed in the FactTypes of every analyzer that
+// uses it. The AFact method exists only to identify fact types.
+type Fact interface {
+	AFact()
+}
+
+// A Diagnostic is a message associated with a source location or range.
+//
+// An Analyzer may return a variety of diagnostics; the optional
+// Category, which should be a constant, may be used to classify them.
+type Diagnostic struct {
+	Pos      token.Pos
+	End      token.Pos // optional
+	Category string    // optional
+	Message  string
+
+	// SuggestedFixes are alternative edits that would fix
+	// the problem. Drivers may apply at most one of them.
+	SuggestedFixes []SuggestedFix
+}
+
+// A SuggestedFix is a set of edits that together resolve a diagnostic.
+// The edits must not overlap.
+type SuggestedFix struct {
+	Message   string
+	TextEdits []TextEdit
+}
+
+// A TextEdit replaces the source between Pos and End with NewText.
+// If End is not set, the edit inserts NewText at Pos.
+type TextEdit struct {
+	Pos     token.Pos
+	End     token.Pos
+	NewText []byte
+}
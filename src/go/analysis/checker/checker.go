@@ -0,0 +1,235 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package checker runs a set of analyzers, defined using the
+// go/analysis package, on Go packages. It is the engine shared by
+// analysis drivers such as cmd/vet and go/analysis/multichecker.
+//
+// A Checker loads a package from a list of files, type-checks it, and
+// runs its analyzers on it in dependency order. Dependencies of the
+// package that belong to the standard library are imported from their
+// export data; all others are loaded and type-checked from source so
+// that analyzers using facts can be run on them first. Facts are not
+// computed for standard library packages.
+package checker
+
+import (
+	"fmt"
+	"go/analysis"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+	"reflect"
+)
+
+// A Checker holds the analyzers to run and the state shared across
+// the packages it loads.
+type Checker struct {
+	// Analyzers is the set of analyzers whose diagnostics are
+	// reported. The analyzers they require are run too.
+	Analyzers []*analysis.Analyzer
+
+	// Context is the build context used to locate and select the
+	// source files of dependencies. If nil, build.Default is used.
+	Context *build.Context
+
+	// Importer imports standard library packages.
+	// If nil, the importer returned by importer.Default is used.
+	Importer types.Importer
+
+	fset     *token.FileSet
+	packages map[string]*importedPackage // packages loaded from source, by import path
+
+	objectFacts  map[objectFactKey]analysis.Fact
+	packageFacts map[packageFactKey]analysis.Fact
+}
+
+// A Package is a parsed and type-checked package.
+type Package struct {
+	Path       string
+	Fset       *token.FileSet
+	Files      []*ast.File
+	OtherFiles []string
+	Types      *types.Package
+	TypesInfo  *types.Info
+
+	// TypeErrors lists the errors found by the type checker.
+	// Only analyzers that set RunDespiteErrors are run on a
+	// package with type errors.
+	TypeErrors []error
+}
+
+// A Diagnostic is a diagnostic reported by one of the analyzers of a Checker.
+type Diagnostic struct {
+	analysis.Diagnostic
+	Analyzer *analysis.Analyzer
+}
+
+type objectFactKey struct {
+	obj types.Object
+	typ reflect.Type
+}
+
+type packageFactKey struct {
+	pkg *types.Package
+	typ reflect.Type
+}
+
+// Check runs the analyzers of c on pkg and returns the diagnostics
+// they report, in the order in which they were reported.
+// It returns an error if an analyzer fails.
+func (c *Checker) Check(pkg *Package) ([]Diagnostic, error) {
+	if err := analysis.Validate(c.Analyzers); err != nil {
+		return nil, err
+	}
+	var diags []Diagnostic
+	err := c.analyze(pkg, c.Analyzers, func(d Diagnostic) {
+		diags = append(diags, d)
+	})
+	return diags, err
+}
+
+// factAnalyzers returns the analyzers of c that use facts.
+func (c *Checker) factAnalyzers() []*analysis.Analyzer {
+	var list []*analysis.Analyzer
+	seen := make(map[*analysis.Analyzer]bool)
+	var visit func(a *analysis.Analyzer)
+	visit = func(a *analysis.Analyzer) {
+		if seen[a] {
+			return
+		}
+		seen[a] = true
+		if len(a.FactTypes) > 0 {
+			list = append(list, a)
+		}
+		for _, req := range a.Requires {
+			visit(req)
+		}
+	}
+	for _, a := range c.Analyzers {
+		visit(a)
+	}
+	return list
+}
+
+// analyze runs the analyzers in roots, and the analyzers they require,
+// on pkg. Diagnostics of the root analyzers are passed to report;
+// those of the other analyzers are discarded.
+func (c *Checker) analyze(pkg *Package, roots []*analysis.Analyzer, report func(Diagnostic)) error {
+	isRoot := make(map[*analysis.Analyzer]bool)
+	for _, a := range roots {
+		isRoot[a] = true
+	}
+
+	// ok records whether each analyzer that has run succeeded.
+	ok := make(map[*analysis.Analyzer]bool)
+	results := make(map[*analysis.Analyzer]interface{})
+	var run func(a *analysis.Analyzer) error
+	run = func(a *analysis.Analyzer) error {
+		if _, done := ok[a]; done {
+			return nil
+		}
+		ok[a] = false
+		for _, req := range a.Requires {
+			if err := run(req); err != nil {
+				return err
+			}
+			if !ok[req] {
+				return nil
+			}
+		}
+		if len(pkg.TypeErrors) > 0 && !a.RunDespiteErrors {
+			return nil
+		}
+
+		pass := &analysis.Pass{
+			Analyzer:   a,
+			Fset:       pkg.Fset,
+			Files:      pkg.Files,
+			OtherFiles: pkg.OtherFiles,
+			Pkg:        pkg.Types,
+			TypesInfo:  pkg.TypesInfo,
+			ResultOf:   make(map[*analysis.Analyzer]interface{}),
+			Report: func(d analysis.Diagnostic) {
+				if isRoot[a] {
+					report(Diagnostic{d, a})
+				}
+			},
+		}
+		for _, req := range a.Requires {
+			pass.ResultOf[req] = results[req]
+		}
+		c.installFacts(pass)
+
+		result, err := a.Run(pass)
+		if err != nil {
+			return fmt.Errorf("analyzer %s failed on package %s: %v", a.Name, pkg.Path, err)
+		}
+		if a.ResultType != nil && reflect.TypeOf(result) != a.ResultType {
+			return fmt.Errorf("analyzer %s returned a result of type %T, want %v", a.Name, result, a.ResultType)
+		}
+		results[a] = result
+		ok[a] = true
+		return nil
+	}
+	for _, a := range roots {
+		if err := run(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// installFacts sets the fact operations of pass.
+func (c *Checker) installFacts(pass *analysis.Pass) {
+	if c.objectFacts == nil {
+		c.objectFacts = make(map[objectFactKey]analysis.Fact)
+		c.packageFacts = make(map[packageFactKey]analysis.Fact)
+	}
+	a := pass.Analyzer
+	checkType := func(fact analysis.Fact) reflect.Type {
+		t := reflect.TypeOf(fact)
+		for _, f := range a.FactTypes {
+			if reflect.TypeOf(f) == t {
+				return t
+			}
+		}
+		panic(fmt.Sprintf("analyzer %s uses fact type %v not listed in its FactTypes", a.Name, t))
+	}
+	pass.ImportObjectFact = func(obj types.Object, fact analysis.Fact) bool {
+		if obj == nil {
+			panic("nil object")
+		}
+		stored, ok := c.objectFacts[objectFactKey{obj, checkType(fact)}]
+		if ok {
+			copyFact(fact, stored)
+		}
+		return ok
+	}
+	pass.ExportObjectFact = func(obj types.Object, fact analysis.Fact) {
+		if obj.Pkg() != pass.Pkg {
+			panic(fmt.Sprintf("analyzer %s: cannot export fact about %s, which is not declared in package %s", a.Name, obj, pass.Pkg.Path()))
+		}
+		c.objectFacts[objectFactKey{obj, checkType(fact)}] = fact
+	}
+	pass.ImportPackageFact = func(pkg *types.Package, fact analysis.Fact) bool {
+		if pkg == nil {
+			panic("nil package")
+		}
+		stored, ok := c.packageFacts[packageFactKey{pkg, checkType(fact)}]
+		if ok {
+			copyFact(fact, stored)
+		}
+		return ok
+	}
+	pass.ExportPackageFact = func(fact analysis.Fact) {
+		c.packageFacts[packageFactKey{pass.Pkg, checkType(fact)}] = fact
+	}
+}
+
+// copyFact copies the value of the fact src into dst.
+func copyFact(dst, src analysis.Fact) {
+	reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(src).Elem())
+}
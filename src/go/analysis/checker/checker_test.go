@@ -0,0 +1,236 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package checker
+
+import (
+	"fmt"
+	"go/analysis"
+	"go/ast"
+	"go/build"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// countAnalyzer counts the files of a package. Its diagnostics are
+// never reported as it is only required by markAnalyzer.
+var countAnalyzer = &analysis.Analyzer{
+	Name: "count",
+	Doc:  "count the files of a package",
+	Run: func(pass *analysis.Pass) (interface{}, error) {
+		pass.Reportf(pass.Files[0].Pos(), "counting")
+		return len(pass.Files), nil
+	},
+	ResultType: reflect.TypeOf(0),
+}
+
+// A markedFact records that a function is marked: its name begins
+// with "Mark" or it calls a marked function of its package.
+type markedFact struct {
+	Via string
+}
+
+func (*markedFact) AFact() {}
+
+// A markCountFact records the number of marked functions of a package.
+type markCountFact struct {
+	N int
+}
+
+func (*markCountFact) AFact() {}
+
+// markAnalyzer reports calls of marked functions of other packages,
+// suggesting a call of Unmarked instead, and the imports of packages
+// with marked functions.
+var markAnalyzer = &analysis.Analyzer{
+	Name:      "mark",
+	Doc:       "report calls of marked functions",
+	Requires:  []*analysis.Analyzer{countAnalyzer},
+	FactTypes: []analysis.Fact{new(markedFact), new(markCountFact)},
+	Run: func(pass *analysis.Pass) (interface{}, error) {
+		if n := pass.ResultOf[countAnalyzer].(int); n != len(pass.Files) {
+			return nil, fmt.Errorf("count = %d, want %d", n, len(pass.Files))
+		}
+		marked := 0
+		for _, f := range pass.Files {
+			for _, imp := range f.Imports {
+				path, _ := strconv.Unquote(imp.Path.Value)
+				for _, pkg := range pass.Pkg.Imports() {
+					var fact markCountFact
+					if pkg.Path() == path && pass.ImportPackageFact(pkg, &fact) {
+						pass.Reportf(imp.Pos(), "package %s has %d marked functions", path, fact.N)
+					}
+				}
+			}
+			for _, decl := range f.Decls {
+				decl, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				fn := pass.TypesInfo.Defs[decl.Name].(*types.Func)
+				via := ""
+				if strings.HasPrefix(fn.Name(), "Mark") {
+					via = fn.Name()
+				}
+				ast.Inspect(decl.Body, func(n ast.Node) bool {
+					sel, ok := n.(*ast.SelectorExpr)
+					if !ok {
+						id, ok := n.(*ast.Ident)
+						if ok && via == "" {
+							var fact markedFact
+							if callee, ok := pass.TypesInfo.Uses[id].(*types.Func); ok && pass.ImportObjectFact(callee, &fact) {
+								via = fact.Via
+							}
+						}
+						return true
+					}
+					callee, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func)
+					var fact markedFact
+					if ok && callee.Pkg() != pass.Pkg && pass.ImportObjectFact(callee, &fact) {
+						pass.Report(analysis.Diagnostic{
+							Pos:     sel.Pos(),
+							Message: fmt.Sprintf("call of %s, marked via %s", callee.Name(), fact.Via),
+							SuggestedFixes: []analysis.SuggestedFix{{
+								Message: "call Unmarked",
+								TextEdits: []analysis.TextEdit{
+									{Pos: sel.Sel.Pos(), End: sel.Sel.End(), NewText: []byte("Unmarked")},
+								},
+							}},
+						})
+					}
+					return false
+				})
+				if via != "" {
+					pass.ExportObjectFact(fn, &markedFact{via})
+					marked++
+				}
+			}
+		}
+		pass.ExportPackageFact(&markCountFact{marked})
+		return nil, nil
+	},
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, data := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckerFacts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"src/dep/dep.go": `package dep
+
+func MarkA() {}
+func B()     { MarkA() }
+func C()     {}
+
+func Unmarked() {}
+`,
+		"src/root/root.go": `package root
+
+import "dep"
+
+func F() {
+	dep.B()
+	dep.C()
+}
+`,
+	})
+
+	ctxt := build.Default
+	ctxt.GOPATH = dir
+	c := &Checker{Analyzers: []*analysis.Analyzer{markAnalyzer}, Context: &ctxt}
+	rootFile := filepath.Join(dir, "src", "root", "root.go")
+	pkg, err := c.Load("root", []string{rootFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkg.TypeErrors) > 0 {
+		t.Fatalf("type errors: %v", pkg.TypeErrors)
+	}
+	diags, err := c.Check(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range diags {
+		if d.Analyzer != markAnalyzer {
+			t.Errorf("diagnostic %q reported for analyzer %s", d.Message, d.Analyzer)
+		}
+		got = append(got, fmt.Sprintf("%d: %s", pkg.Fset.Position(d.Pos).Line, d.Message))
+	}
+	want := []string{
+		"3: package dep has 2 marked functions",
+		"6: call of B, marked via MarkA",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	fixed, err := ApplyFixes(pkg.Fset, append(diags, diags...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixed) != 1 || !strings.Contains(string(fixed[rootFile]), "\tdep.Unmarked()\n\tdep.C()\n") {
+		t.Errorf("ApplyFixes = %q", fixed)
+	}
+}
+
+func TestCheckerSkipsOnTypeErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"p.go": "package p\n\nvar x int = \"s\"\n",
+		"p.s":  "",
+	})
+
+	ran := false
+	a := &analysis.Analyzer{
+		Name: "ran",
+		Doc:  "record that the analyzer ran",
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			ran = true
+			if len(pass.OtherFiles) != 1 || filepath.Base(pass.OtherFiles[0]) != "p.s" {
+				return nil, fmt.Errorf("OtherFiles = %v", pass.OtherFiles)
+			}
+			return nil, nil
+		},
+	}
+	c := &Checker{Analyzers: []*analysis.Analyzer{a}}
+	pkg, err := c.Load("", []string{filepath.Join(dir, "p.go"), filepath.Join(dir, "p.s")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Path != "p" || len(pkg.TypeErrors) != 1 {
+		t.Fatalf("Load: path %q, type errors %v", pkg.Path, pkg.TypeErrors)
+	}
+	if _, err := c.Check(pkg); err != nil || ran {
+		t.Errorf("Check ran analyzer on package with type errors: ran=%v, err=%v", ran, err)
+	}
+	a.RunDespiteErrors = true
+	if _, err := c.Check(pkg); err != nil || !ran {
+		t.Errorf("Check with RunDespiteErrors: ran=%v, err=%v", ran, err)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package checker
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"sort"
+)
+
+// An edit is a TextEdit resolved to byte offsets within a file.
+type edit struct {
+	start, end int
+	text       []byte
+}
+
+type byStart []edit
+
+func (x byStart) Len() int           { return len(x) }
+func (x byStart) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x byStart) Less(i, j int) bool { return x[i].start < x[j].start }
+
+// ApplyFixes applies the first suggested fix of each diagnostic that
+// has one to the files it edits, and returns the new content of each
+// edited file, keyed by file name. A fix whose edits overlap those of
+// a fix applied earlier is skipped, as are duplicate fixes.
+func ApplyFixes(fset *token.FileSet, diags []Diagnostic) (map[string][]byte, error) {
+	edits := make(map[string][]edit)
+Diags:
+	for _, d := range diags {
+		if len(d.SuggestedFixes) == 0 {
+			continue
+		}
+		fix := make(map[string][]edit)
+		for _, te := range d.SuggestedFixes[0].TextEdits {
+			tf := fset.File(te.Pos)
+			if tf == nil {
+				return nil, fmt.Errorf("%s: suggested fix has invalid position", d.Analyzer.Name)
+			}
+			end := te.End
+			if !end.IsValid() {
+				end = te.Pos
+			}
+			e := edit{tf.Offset(te.Pos), tf.Offset(end), te.NewText}
+			if e.end < e.start {
+				return nil, fmt.Errorf("%s: %s: suggested fix ends before it starts", d.Analyzer.Name, fset.Position(te.Pos))
+			}
+			fix[tf.Name()] = append(fix[tf.Name()], e)
+		}
+		for name, list := range fix {
+			for _, e := range list {
+				for _, prev := range edits[name] {
+					if e.start == prev.start && e.end == prev.end && bytes.Equal(e.text, prev.text) {
+						continue Diags
+					}
+					if e.start < prev.end && prev.start < e.end || e.start == prev.start {
+						continue Diags
+					}
+				}
+			}
+		}
+		for name, list := range fix {
+			edits[name] = append(edits[name], list...)
+		}
+	}
+
+	out := make(map[string][]byte)
+	for name, list := range edits {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		sort.Sort(byStart(list))
+		var buf bytes.Buffer
+		last := 0
+		for _, e := range list {
+			if e.end > len(data) {
+				return nil, fmt.Errorf("%s: suggested fix beyond end of file", name)
+			}
+			buf.Write(data[last:e.start])
+			buf.Write(e.text)
+			last = e.end
+		}
+		buf.Write(data[last:])
+		out[name] = buf.Bytes()
+	}
+	return out, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package checker
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+)
+
+// An importedPackage records the outcome of loading a dependency from source.
+type importedPackage struct {
+	pkg *types.Package // nil while the package is being loaded
+	err error
+}
+
+func (c *Checker) init() {
+	if c.fset == nil {
+		c.fset = token.NewFileSet()
+		c.packages = make(map[string]*importedPackage)
+	}
+	if c.Importer == nil {
+		c.Importer = importer.Default()
+	}
+}
+
+func (c *Checker) context() *build.Context {
+	if c.Context != nil {
+		return c.Context
+	}
+	return &build.Default
+}
+
+// Load parses the named files, which must make up a single package,
+// and type-checks them as the package with the given import path.
+// If path is empty, the name of the package is used instead.
+// Files without a .go suffix are not parsed but recorded in the
+// OtherFiles of the result.
+//
+// Load returns an error only if a file cannot be read or parsed;
+// type errors are recorded in the TypeErrors of the result.
+func (c *Checker) Load(path string, filenames []string) (*Package, error) {
+	c.init()
+	var files []*ast.File
+	var other []string
+	for _, name := range filenames {
+		if !strings.HasSuffix(name, ".go") {
+			other = append(other, name)
+			continue
+		}
+		f, err := parser.ParseFile(c.fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files to check")
+	}
+	if path == "" {
+		path = files[0].Name.Name
+	}
+	pkg := c.check(path, files)
+	pkg.OtherFiles = other
+	return pkg, nil
+}
+
+// check type-checks files as the package with the given import path.
+func (c *Checker) check(path string, files []*ast.File) *Package {
+	pkg := &Package{
+		Path:  path,
+		Fset:  c.fset,
+		Files: files,
+		TypesInfo: &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Scopes:     make(map[ast.Node]*types.Scope),
+		},
+	}
+	config := types.Config{
+		Importer:    sourceImporter{c},
+		FakeImportC: true,
+		Error: func(err error) {
+			pkg.TypeErrors = append(pkg.TypeErrors, err)
+		},
+	}
+	pkg.Types, _ = config.Check(path, c.fset, files, pkg.TypesInfo)
+	return pkg
+}
+
+// A sourceImporter imports the packages of the standard library
+// using the Importer of a Checker, and all other packages by loading
+// them from source.
+type sourceImporter struct {
+	c *Checker
+}
+
+func (imp sourceImporter) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, "", 0)
+}
+
+func (imp sourceImporter) ImportFrom(path, srcDir string, mode types.ImportMode) (*types.Package, error) {
+	c := imp.c
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	if srcDir != "" {
+		if dir, err := filepath.Abs(srcDir); err == nil {
+			srcDir = dir
+		}
+	}
+	bp, err := c.context().Import(path, srcDir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if bp.Goroot {
+		if from, ok := c.Importer.(types.ImporterFrom); ok {
+			return from.ImportFrom(path, srcDir, 0)
+		}
+		return c.Importer.Import(bp.ImportPath)
+	}
+
+	if p := c.packages[bp.ImportPath]; p != nil {
+		if p.pkg == nil && p.err == nil {
+			return nil, fmt.Errorf("import cycle through package %s", bp.ImportPath)
+		}
+		return p.pkg, p.err
+	}
+	p := new(importedPackage)
+	c.packages[bp.ImportPath] = p
+
+	var files []*ast.File
+	for _, name := range append(bp.GoFiles, bp.CgoFiles...) {
+		f, err := parser.ParseFile(c.fset, filepath.Join(bp.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			p.err = err
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	// Type errors in dependencies are ignored: the package is
+	// complete enough for the importer as long as it was created.
+	pkg := c.check(bp.ImportPath, files)
+	if analyzers := c.factAnalyzers(); len(analyzers) > 0 {
+		if err := c.analyze(pkg, analyzers, func(Diagnostic) {}); err != nil {
+			p.err = err
+			return nil, err
+		}
+	}
+	p.pkg = pkg.Types
+	return p.pkg, nil
+}
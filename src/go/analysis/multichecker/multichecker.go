@@ -0,0 +1,272 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package multichecker defines the main function of an analysis
+// driver program that runs a set of analyzers, in the manner of
+// cmd/vet. A program that runs a project-specific set of checks is
+// written as
+//
+//	package main
+//
+//	import (
+//		"go/analysis/multichecker"
+//		"example.com/checks/nolocks"
+//		"example.com/checks/zossyscall"
+//	)
+//
+//	func main() { multichecker.Main(nolocks.Analyzer, zossyscall.Analyzer) }
+//
+// The resulting program accepts the same arguments as vet: either a
+// list of directories, which are searched recursively for packages,
+// or a list of files that make up a single package. It can be run by
+// the go command with 'go vet -vettool=prog'.
+//
+// Each analyzer is enabled by a boolean flag of the same name; if no
+// analyzer is enabled explicitly, all of them run. The flags defined
+// by an analyzer are available as -name.flag. The -fix flag applies
+// the suggested fixes of the reported diagnostics to the source files.
+package multichecker
+
+import (
+	"flag"
+	"fmt"
+	"go/analysis"
+	"go/analysis/checker"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	tags    = flag.String("tags", "", "comma-separated list of build tags to apply when loading packages")
+	fix     = flag.Bool("fix", false, "apply the suggested fixes of the diagnostics to the source files")
+	verbose = flag.Bool("v", false, "verbose")
+)
+
+var progname = filepath.Base(os.Args[0])
+
+// exitCode is the status with which Main exits.
+var exitCode = 0
+
+// Main is the main function of a checker command that runs the given
+// analyzers. It parses the command line, runs the selected analyzers
+// on the packages named by its arguments, prints the diagnostics they
+// report to standard error, and exits. The exit status is 1 if any
+// diagnostic was reported or any package could not be checked.
+func Main(analyzers ...*analysis.Analyzer) {
+	if err := analysis.Validate(analyzers); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", progname, err)
+		os.Exit(2)
+	}
+
+	enabled := make(map[*analysis.Analyzer]*bool)
+	for _, a := range analyzers {
+		enabled[a] = flag.Bool(a.Name, false, "enable "+a.Name+" analysis: "+summary(a.Doc))
+	}
+	registerAnalyzerFlags(analyzers)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", progname)
+		fmt.Fprintf(os.Stderr, "\t%s [flags] directory...\n", progname)
+		fmt.Fprintf(os.Stderr, "\t%s [flags] files... # Must be a single package\n", progname)
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+	}
+
+	var selected []*analysis.Analyzer
+	for _, a := range analyzers {
+		if *enabled[a] {
+			selected = append(selected, a)
+		}
+	}
+	if len(selected) == 0 {
+		selected = analyzers
+	}
+
+	ctxt := build.Default
+	if *tags != "" {
+		ctxt.BuildTags = append(strings.Split(*tags, ","), ctxt.BuildTags...)
+	}
+	r := &runner{
+		checker: &checker.Checker{Analyzers: selected, Context: &ctxt},
+		ctxt:    &ctxt,
+	}
+
+	var dirs, files bool
+	for _, name := range flag.Args() {
+		fi, err := os.Stat(name)
+		if err != nil {
+			warnf("%v", err)
+			continue
+		}
+		if fi.IsDir() {
+			dirs = true
+		} else {
+			files = true
+		}
+	}
+	if dirs && files {
+		flag.Usage()
+	}
+	if dirs {
+		for _, name := range flag.Args() {
+			filepath.Walk(name, r.visit)
+		}
+	} else {
+		r.checkFiles(flag.Args())
+	}
+
+	if *fix {
+		r.applyFixes()
+	}
+	os.Exit(exitCode)
+}
+
+// registerAnalyzerFlags defines the flags of each analyzer, and of the
+// analyzers it requires, as -name.flag.
+func registerAnalyzerFlags(analyzers []*analysis.Analyzer) {
+	seen := make(map[*analysis.Analyzer]bool)
+	var visit func(a *analysis.Analyzer)
+	visit = func(a *analysis.Analyzer) {
+		if seen[a] {
+			return
+		}
+		seen[a] = true
+		a.Flags.VisitAll(func(f *flag.Flag) {
+			flag.Var(f.Value, a.Name+"."+f.Name, f.Usage)
+		})
+		for _, req := range a.Requires {
+			visit(req)
+		}
+	}
+	for _, a := range analyzers {
+		visit(a)
+	}
+}
+
+// summary returns the first sentence of doc.
+func summary(doc string) string {
+	if i := strings.Index(doc, ". "); i >= 0 {
+		doc = doc[:i+1]
+	}
+	if i := strings.Index(doc, "\n\n"); i >= 0 {
+		doc = doc[:i]
+	}
+	return strings.Join(strings.Fields(doc), " ")
+}
+
+// A runner checks packages and accumulates their diagnostics.
+type runner struct {
+	checker *checker.Checker
+	ctxt    *build.Context
+	pkg     *checker.Package // most recently checked package
+	diags   []checker.Diagnostic
+}
+
+// visit checks the package in each directory of a tree.
+func (r *runner) visit(path string, fi os.FileInfo, err error) error {
+	if err != nil {
+		warnf("%v", err)
+		return nil
+	}
+	if !fi.IsDir() {
+		return nil
+	}
+	bp, err := r.ctxt.ImportDir(path, 0)
+	if err != nil {
+		if _, nogo := err.(*build.NoGoError); !nogo {
+			warnf("cannot process directory %s: %v", path, err)
+		}
+		return nil
+	}
+	names := stringList(bp.GoFiles, bp.CgoFiles, bp.TestGoFiles, bp.SFiles)
+	r.checkFiles(prefixDirectory(path, names))
+	if len(bp.XTestGoFiles) > 0 {
+		r.checkFiles(prefixDirectory(path, bp.XTestGoFiles))
+	}
+	return nil
+}
+
+// checkFiles checks the single package made up of the named files.
+func (r *runner) checkFiles(names []string) {
+	if *verbose {
+		for _, name := range names {
+			fmt.Fprintf(os.Stderr, "Checking file %s\n", name)
+		}
+	}
+	pkg, err := r.checker.Load("", names)
+	if err != nil {
+		warnf("%v", err)
+		return
+	}
+	if *verbose {
+		for _, err := range pkg.TypeErrors {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", progname, err)
+		}
+	}
+	diags, err := r.checker.Check(pkg)
+	for _, d := range diags {
+		if d.Pos.IsValid() {
+			posn := pkg.Fset.Position(d.Pos)
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", posn.Filename, posn.Line, d.Message)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s\n", d.Message)
+		}
+		exitCode = 1
+	}
+	if err != nil {
+		warnf("%v", err)
+	}
+	r.pkg = pkg
+	r.diags = append(r.diags, diags...)
+}
+
+// applyFixes writes the suggested fixes of the diagnostics to the source files.
+func (r *runner) applyFixes() {
+	if r.pkg == nil {
+		return
+	}
+	fixed, err := checker.ApplyFixes(r.pkg.Fset, r.diags)
+	if err != nil {
+		warnf("%v", err)
+		return
+	}
+	for name, data := range fixed {
+		mode := os.FileMode(0666)
+		if fi, err := os.Stat(name); err == nil {
+			mode = fi.Mode()
+		}
+		if err := ioutil.WriteFile(name, data, mode); err != nil {
+			warnf("%v", err)
+		}
+	}
+}
+
+func prefixDirectory(dir string, names []string) []string {
+	list := make([]string, len(names))
+	for i, name := range names {
+		list[i] = filepath.Join(dir, name)
+	}
+	return list
+}
+
+func stringList(lists ...[]string) []string {
+	var x []string
+	for _, list := range lists {
+		x = append(x, list...)
+	}
+	return x
+}
+
+// warnf prints a message to standard error and sets the exit status to 1.
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, progname+": "+format+"\n", args...)
+	exitCode = 1
+}
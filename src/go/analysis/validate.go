@@ -0,0 +1,86 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package analysis
+
+import (
+	"fmt"
+	"reflect"
+	"unicode"
+)
+
+// Validate reports an error if any of the analyzers, or the analyzers
+// they require, is misconfigured. It checks that each analyzer has a
+// valid and unique name, documentation and a Run function, that its
+// fact types are pointers, and that Requires has no cycles.
+func Validate(analyzers []*Analyzer) error {
+	names := make(map[string]*Analyzer)
+
+	// Traverse the Requires graph depth-first.
+	const (
+		white = iota
+		grey
+		black
+	)
+	color := make(map[*Analyzer]int)
+	var visit func(a *Analyzer) error
+	visit = func(a *Analyzer) error {
+		if a == nil {
+			return fmt.Errorf("nil *Analyzer")
+		}
+		switch color[a] {
+		case grey:
+			return fmt.Errorf("cycle detected involving analyzer %s", a.Name)
+		case black:
+			return nil
+		}
+		color[a] = grey
+
+		if !validIdent(a.Name) {
+			return fmt.Errorf("invalid analyzer name %q", a.Name)
+		}
+		if prev := names[a.Name]; prev != nil {
+			return fmt.Errorf("duplicate analyzer name %q", a.Name)
+		}
+		names[a.Name] = a
+		if a.Doc == "" {
+			return fmt.Errorf("analyzer %s is undocumented", a.Name)
+		}
+		if a.Run == nil {
+			return fmt.Errorf("analyzer %s has nil Run function", a.Name)
+		}
+		for _, f := range a.FactTypes {
+			if f == nil {
+				return fmt.Errorf("analyzer %s has nil FactType", a.Name)
+			}
+			t := reflect.TypeOf(f)
+			if t.Kind() != reflect.Ptr {
+				return fmt.Errorf("analyzer %s: fact type %s is not a pointer", a.Name, t)
+			}
+		}
+		for _, req := range a.Requires {
+			if err := visit(req); err != nil {
+				return err
+			}
+		}
+		color[a] = black
+		return nil
+	}
+	for _, a := range analyzers {
+		if err := visit(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validIdent reports whether name is a valid Go identifier.
+func validIdent(name string) bool {
+	for i, r := range name {
+		if !(r == '_' || unicode.IsLetter(r) || i > 0 && unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package analysis
+
+import (
+	"strings"
+	"testing"
+)
+
+type testFact struct{}
+
+func (*testFact) AFact() {}
+
+type badFact struct{}
+
+func (badFact) AFact() {}
+
+func run(*Pass) (interface{}, error) { return nil, nil }
+
+func TestValidate(t *testing.T) {
+	a := &Analyzer{Name: "a", Doc: "a", Run: run}
+	b := &Analyzer{Name: "b", Doc: "b", Run: run, Requires: []*Analyzer{a}, FactTypes: []Fact{new(testFact)}}
+	if err := Validate([]*Analyzer{a, b}); err != nil {
+		t.Errorf("Validate(a, b) = %v", err)
+	}
+
+	cyclic1 := &Analyzer{Name: "cyclic1", Doc: "c", Run: run}
+	cyclic2 := &Analyzer{Name: "cyclic2", Doc: "c", Run: run, Requires: []*Analyzer{cyclic1}}
+	cyclic1.Requires = []*Analyzer{cyclic2}
+
+	for _, tt := range []struct {
+		analyzers []*Analyzer
+		err       string
+	}{
+		{[]*Analyzer{nil}, "nil *Analyzer"},
+		{[]*Analyzer{{Name: "1a", Doc: "x", Run: run}}, "invalid analyzer name"},
+		{[]*Analyzer{{Name: "", Doc: "x", Run: run}}, "invalid analyzer name"},
+		{[]*Analyzer{a, {Name: "a", Doc: "x", Run: run}}, "duplicate analyzer name"},
+		{[]*Analyzer{{Name: "x", Run: run}}, "undocumented"},
+		{[]*Analyzer{{Name: "x", Doc: "x"}}, "nil Run function"},
+		{[]*Analyzer{{Name: "x", Doc: "x", Run: run, FactTypes: []Fact{badFact{}}}}, "is not a pointer"},
+		{[]*Analyzer{cyclic1}, "cycle detected"},
+	} {
+		err := Validate(tt.analyzers)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Validate(%v) = %v, want error containing %q", tt.analyzers, err, tt.err)
+		}
+	}
+}
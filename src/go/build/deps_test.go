@@ -99,7 +99,7 @@ var pkgDeps = map[string][]string{
 	"crypto/subtle":       {},
 	"encoding/base32":     {"L2"},
 	"encoding/base64":     {"L2"},
-	"encoding/binary":     {"L2", "reflect"},
+	"encoding/binary":     {"L2", "internal/ebcdic", "reflect"},
 	"hash":                {"L2"}, // interfaces
 	"hash/adler32":        {"L2", "hash"},
 	"hash/crc32":          {"L2", "hash"},
@@ -162,14 +162,16 @@ var pkgDeps = map[string][]string{
 	"regexp/syntax":  {"L2"},
 	"runtime/debug":  {"L2", "fmt", "io/ioutil", "os", "time"},
 	"runtime/pprof":  {"L2", "fmt", "text/tabwriter"},
-	"runtime/trace":  {"L0"},
+	"runtime/trace":  {"L0", "fmt"},
 	"text/tabwriter": {"L2"},
 
-	"testing":          {"L2", "flag", "fmt", "os", "runtime/debug", "runtime/pprof", "runtime/trace", "time"},
+	"testing":          {"L2", "crypto/sha256", "flag", "fmt", "io/ioutil", "os", "path/filepath", "reflect", "runtime/debug", "runtime/pprof", "runtime/trace", "time"},
 	"testing/iotest":   {"L2", "log"},
 	"testing/quick":    {"L2", "flag", "fmt", "reflect"},
 	"internal/testenv": {"L2", "os", "testing"},
 
+	"context": {"errors", "fmt", "reflect", "sync", "time"},
+
 	// L4 is defined as L3+fmt+log+time, because in general once
 	// you're using L3 packages, use of fmt, log, or time is not a big deal.
 	"L4": {
@@ -206,6 +208,11 @@ var pkgDeps = map[string][]string{
 	"go/internal/gccgoimporter": {"L4", "OS", "debug/elf", "go/constant", "go/token", "go/types", "text/scanner"},
 	"go/types":                  {"L4", "GOPARSER", "container/heap", "go/constant"},
 
+	// Static analysis.
+	"go/analysis":              {"L4", "flag", "go/ast", "go/token", "go/types"},
+	"go/analysis/checker":      {"L4", "OS", "GOPARSER", "go/analysis", "go/build", "go/importer", "go/types"},
+	"go/analysis/multichecker": {"L4", "OS", "flag", "go/analysis", "go/analysis/checker", "go/build"},
+
 	// One of a kind.
 	"archive/tar":              {"L4", "OS", "syscall"},
 	"archive/zip":              {"L4", "OS", "compress/flate"},
@@ -215,8 +222,8 @@ var pkgDeps = map[string][]string{
 	"compress/gzip":            {"L4", "compress/flate"},
 	"compress/lzw":             {"L4"},
 	"compress/zlib":            {"L4", "compress/flate"},
-	"database/sql":             {"L4", "container/list", "database/sql/driver"},
-	"database/sql/driver":      {"L4", "time"},
+	"database/sql":             {"L4", "container/list", "context", "database/sql/driver"},
+	"database/sql/driver":      {"L4", "context", "time"},
 	"debug/dwarf":              {"L4"},
 	"debug/elf":                {"L4", "OS", "debug/dwarf", "compress/zlib"},
 	"debug/gosym":              {"L4"},
@@ -226,12 +233,15 @@ var pkgDeps = map[string][]string{
 	"encoding":                 {"L4"},
 	"encoding/ascii85":         {"L4"},
 	"encoding/asn1":            {"L4", "math/big"},
+	"encoding/copybook":        {"L4", "encoding/packed", "internal/ebcdic", "math/big"},
 	"encoding/csv":             {"L4"},
 	"encoding/gob":             {"L4", "OS", "encoding"},
 	"encoding/hex":             {"L4"},
+	"encoding/packed":          {"L4", "math/big"},
+	"encoding/record":          {"L4", "internal/ebcdic"},
 	"encoding/json":            {"L4", "encoding"},
 	"encoding/pem":             {"L4"},
-	"encoding/xml":             {"L4", "encoding"},
+	"encoding/xml":             {"L4", "encoding", "internal/ebcdic"},
 	"flag":                     {"L4", "OS"},
 	"go/build":                 {"L4", "OS", "GOPARSER"},
 	"html":                     {"L4"},
@@ -241,6 +251,7 @@ var pkgDeps = map[string][]string{
 	"image/jpeg":               {"L4", "image/internal/imageutil"},
 	"image/png":                {"L4", "compress/zlib"},
 	"index/suffixarray":        {"L4", "regexp"},
+	"internal/ebcdic":          {},
 	"internal/singleflight":    {"sync"},
 	"internal/trace":           {"L4", "OS"},
 	"math/big":                 {"L4"},
@@ -356,7 +367,7 @@ var pkgDeps = map[string][]string{
 	// HTTP, kingpin of dependencies.
 	"net/http": {
 		"L4", "NET", "OS",
-		"compress/gzip", "crypto/tls", "mime/multipart", "runtime/debug",
+		"compress/gzip", "container/list", "crypto/tls", "mime/multipart", "runtime/debug",
 		"net/http/internal",
 		"internal/golang.org/x/net/http2/hpack",
 	},
@@ -0,0 +1,154 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ebcdic
+
+// A CodePage is a single-byte EBCDIC code page.
+type CodePage struct {
+	name   string
+	decode *[256]rune
+	encode map[rune]byte
+}
+
+// The code pages that can be converted. CodePage1047 is the code page
+// of the Decode and Encode functions. CodePage037 is the US and
+// Canada code page, and CodePage500 the international one.
+var (
+	CodePage037  = newCodePage("CodePage037", &codePage037)
+	CodePage500  = newCodePage("CodePage500", &codePage500)
+	CodePage1047 = new(CodePage) // set by initCodePage1047
+)
+
+// initCodePage1047 sets CodePage1047 from the codePage1047 table.
+func initCodePage1047() {
+	var decode [256]rune
+	for c, r := range codePage1047 {
+		decode[c] = r
+	}
+	*CodePage1047 = CodePage{"CodePage1047", &decode, toCodePage1047}
+}
+
+// newCodePage returns the code page with the given decoding table.
+// Where several codes decode to the same rune, the lowest is used to
+// encode it, as for CodePage1047.
+func newCodePage(name string, decode *[256]rune) *CodePage {
+	encode := make(map[rune]byte, len(decode))
+	for c := len(decode) - 1; c >= 0; c-- {
+		if r := decode[c]; r != RuneError {
+			encode[r] = byte(c)
+		}
+	}
+	return &CodePage{name, decode, encode}
+}
+
+// String returns the name of the code page.
+func (cp *CodePage) String() string { return cp.name }
+
+// Decode converts src from the code page to runes. As with Decode,
+// a code the code page does not define decodes as RuneError, and the
+// error message is always empty.
+func (cp *CodePage) Decode(src []byte) ([]rune, string) {
+	if len(src) == 0 {
+		return nil, ""
+	}
+	dst := make([]rune, len(src))
+	for i, c := range src {
+		dst[i] = cp.decode[c]
+	}
+	return dst, ""
+}
+
+// Rune returns the rune that c decodes as.
+func (cp *CodePage) Rune(c byte) rune { return cp.decode[c] }
+
+// Encode converts src to the code page. It returns an error message
+// if src holds a rune the code page does not have.
+func (cp *CodePage) Encode(src []rune) ([]byte, string) {
+	if len(src) == 0 {
+		return nil, ""
+	}
+	dst := make([]byte, len(src))
+	for i, r := range src {
+		c, ok := cp.encode[r]
+		if !ok {
+			return nil, "rune not in EBCDIC " + cp.name
+		}
+		dst[i] = c
+	}
+	return dst, ""
+}
+
+// The tables of CodePage037 and CodePage500 hold the IBM mappings to
+// Unicode, except that, as in CodePage1047, NL (0x15) decodes as '\n'
+// and is the code '\n' encodes as.
+
+var codePage037 = [256]rune{
+	'\x00', '\x01', '\x02', '\x03', '\x9c', '\x09', '\x86', '\x7f', // 0x00
+	'\x97', '\x8d', '\x8e', '\x0b', '\x0c', '\x0d', '\x0e', '\x0f', // 0x08
+	'\x10', '\x11', '\x12', '\x13', '\x9d', '\x0a', '\x08', '\x87', // 0x10
+	'\x18', '\x19', '\x92', '\x8f', '\x1c', '\x1d', '\x1e', '\x1f', // 0x18
+	'\x80', '\x81', '\x82', '\x83', '\x84', '\x0a', '\x17', '\x1b', // 0x20
+	'\x88', '\x89', '\x8a', '\x8b', '\x8c', '\x05', '\x06', '\x07', // 0x28
+	'\x90', '\x91', '\x16', '\x93', '\x94', '\x95', '\x96', '\x04', // 0x30
+	'\x98', '\x99', '\x9a', '\x9b', '\x14', '\x15', '\x9e', '\x1a', // 0x38
+	' ', '\xa0', '\xe2', '\xe4', '\xe0', '\xe1', '\xe3', '\xe5', // 0x40
+	'\xe7', '\xf1', '\xa2', '.', '<', '(', '+', '|', // 0x48
+	'&', '\xe9', '\xea', '\xeb', '\xe8', '\xed', '\xee', '\xef', // 0x50
+	'\xec', '\xdf', '!', '$', '*', ')', ';', '\xac', // 0x58
+	'-', '/', '\xc2', '\xc4', '\xc0', '\xc1', '\xc3', '\xc5', // 0x60
+	'\xc7', '\xd1', '\xa6', ',', '%', '_', '>', '?', // 0x68
+	'\xf8', '\xc9', '\xca', '\xcb', '\xc8', '\xcd', '\xce', '\xcf', // 0x70
+	'\xcc', '`', ':', '#', '@', '\'', '=', '"', // 0x78
+	'\xd8', 'a', 'b', 'c', 'd', 'e', 'f', 'g', // 0x80
+	'h', 'i', '\xab', '\xbb', '\xf0', '\xfd', '\xfe', '\xb1', // 0x88
+	'\xb0', 'j', 'k', 'l', 'm', 'n', 'o', 'p', // 0x90
+	'q', 'r', '\xaa', '\xba', '\xe6', '\xb8', '\xc6', '\xa4', // 0x98
+	'\xb5', '~', 's', 't', 'u', 'v', 'w', 'x', // 0xa0
+	'y', 'z', '\xa1', '\xbf', '\xd0', '\xdd', '\xde', '\xae', // 0xa8
+	'^', '\xa3', '\xa5', '\xb7', '\xa9', '\xa7', '\xb6', '\xbc', // 0xb0
+	'\xbd', '\xbe', '[', ']', '\xaf', '\xa8', '\xb4', '\xd7', // 0xb8
+	'{', 'A', 'B', 'C', 'D', 'E', 'F', 'G', // 0xc0
+	'H', 'I', '\xad', '\xf4', '\xf6', '\xf2', '\xf3', '\xf5', // 0xc8
+	'}', 'J', 'K', 'L', 'M', 'N', 'O', 'P', // 0xd0
+	'Q', 'R', '\xb9', '\xfb', '\xfc', '\xf9', '\xfa', '\xff', // 0xd8
+	'\\', '\xf7', 'S', 'T', 'U', 'V', 'W', 'X', // 0xe0
+	'Y', 'Z', '\xb2', '\xd4', '\xd6', '\xd2', '\xd3', '\xd5', // 0xe8
+	'0', '1', '2', '3', '4', '5', '6', '7', // 0xf0
+	'8', '9', '\xb3', '\xdb', '\xdc', '\xd9', '\xda', '\x9f', // 0xf8
+}
+
+var codePage500 = [256]rune{
+	'\x00', '\x01', '\x02', '\x03', '\x9c', '\x09', '\x86', '\x7f', // 0x00
+	'\x97', '\x8d', '\x8e', '\x0b', '\x0c', '\x0d', '\x0e', '\x0f', // 0x08
+	'\x10', '\x11', '\x12', '\x13', '\x9d', '\x0a', '\x08', '\x87', // 0x10
+	'\x18', '\x19', '\x92', '\x8f', '\x1c', '\x1d', '\x1e', '\x1f', // 0x18
+	'\x80', '\x81', '\x82', '\x83', '\x84', '\x0a', '\x17', '\x1b', // 0x20
+	'\x88', '\x89', '\x8a', '\x8b', '\x8c', '\x05', '\x06', '\x07', // 0x28
+	'\x90', '\x91', '\x16', '\x93', '\x94', '\x95', '\x96', '\x04', // 0x30
+	'\x98', '\x99', '\x9a', '\x9b', '\x14', '\x15', '\x9e', '\x1a', // 0x38
+	' ', '\xa0', '\xe2', '\xe4', '\xe0', '\xe1', '\xe3', '\xe5', // 0x40
+	'\xe7', '\xf1', '[', '.', '<', '(', '+', '!', // 0x48
+	'&', '\xe9', '\xea', '\xeb', '\xe8', '\xed', '\xee', '\xef', // 0x50
+	'\xec', '\xdf', ']', '$', '*', ')', ';', '^', // 0x58
+	'-', '/', '\xc2', '\xc4', '\xc0', '\xc1', '\xc3', '\xc5', // 0x60
+	'\xc7', '\xd1', '\xa6', ',', '%', '_', '>', '?', // 0x68
+	'\xf8', '\xc9', '\xca', '\xcb', '\xc8', '\xcd', '\xce', '\xcf', // 0x70
+	'\xcc', '`', ':', '#', '@', '\'', '=', '"', // 0x78
+	'\xd8', 'a', 'b', 'c', 'd', 'e', 'f', 'g', // 0x80
+	'h', 'i', '\xab', '\xbb', '\xf0', '\xfd', '\xfe', '\xb1', // 0x88
+	'\xb0', 'j', 'k', 'l', 'm', 'n', 'o', 'p', // 0x90
+	'q', 'r', '\xaa', '\xba', '\xe6', '\xb8', '\xc6', '\xa4', // 0x98
+	'\xb5', '~', 's', 't', 'u', 'v', 'w', 'x', // 0xa0
+	'y', 'z', '\xa1', '\xbf', '\xd0', '\xdd', '\xde', '\xae', // 0xa8
+	'\xa2', '\xa3', '\xa5', '\xb7', '\xa9', '\xa7', '\xb6', '\xbc', // 0xb0
+	'\xbd', '\xbe', '\xac', '|', '\xaf', '\xa8', '\xb4', '\xd7', // 0xb8
+	'{', 'A', 'B', 'C', 'D', 'E', 'F', 'G', // 0xc0
+	'H', 'I', '\xad', '\xf4', '\xf6', '\xf2', '\xf3', '\xf5', // 0xc8
+	'}', 'J', 'K', 'L', 'M', 'N', 'O', 'P', // 0xd0
+	'Q', 'R', '\xb9', '\xfb', '\xfc', '\xf9', '\xfa', '\xff', // 0xd8
+	'\\', '\xf7', 'S', 'T', 'U', 'V', 'W', 'X', // 0xe0
+	'Y', 'Z', '\xb2', '\xd4', '\xd6', '\xd2', '\xd3', '\xd5', // 0xe8
+	'0', '1', '2', '3', '4', '5', '6', '7', // 0xf0
+	'8', '9', '\xb3', '\xdb', '\xdc', '\xd9', '\xda', '\x9f', // 0xf8
+}
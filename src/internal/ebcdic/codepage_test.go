@@ -0,0 +1,54 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ebcdic
+
+import "testing"
+
+var codePageTests = []struct {
+	cp   *CodePage
+	text string
+	code []byte
+}{
+	{CodePage1047, "a[1]|\n", []byte{0x81, 0xba, 0xf1, 0xbb, 0x4f, 0x15}},
+	{CodePage037, "a[1]|\n", []byte{0x81, 0xba, 0xf1, 0xbb, 0x4f, 0x15}},
+	{CodePage500, "a[1]|\n", []byte{0x81, 0x4a, 0xf1, 0x5a, 0xbb, 0x15}},
+	{CodePage037, "café £!", []byte{0x83, 0x81, 0x86, 0x51, 0x40, 0xb1, 0x5a}},
+	{CodePage500, "café £!", []byte{0x83, 0x81, 0x86, 0x51, 0x40, 0xb1, 0x4f}},
+}
+
+func TestCodePage(t *testing.T) {
+	for _, tt := range codePageTests {
+		b, errStr := tt.cp.Encode([]rune(tt.text))
+		if errStr != "" || string(b) != string(tt.code) {
+			t.Errorf("%v.Encode(%q) = % x, %q, want % x", tt.cp, tt.text, b, errStr, tt.code)
+		}
+		r, errStr := tt.cp.Decode(tt.code)
+		if errStr != "" || string(r) != tt.text {
+			t.Errorf("%v.Decode(% x) = %q, %q, want %q", tt.cp, tt.code, string(r), errStr, tt.text)
+		}
+	}
+}
+
+// Test that every code of the complete code pages round trips.
+func TestCodePageRoundTrip(t *testing.T) {
+	for _, cp := range []*CodePage{CodePage037, CodePage500} {
+		for c := 0; c < 256; c++ {
+			if c == 0x25 {
+				continue // LF decodes as '\n', which encodes as NL
+			}
+			r, _ := cp.Decode([]byte{byte(c)})
+			b, errStr := cp.Encode(r)
+			if errStr != "" || len(b) != 1 || b[0] != byte(c) {
+				t.Errorf("%v: code %#x decodes as %U, which encodes as % x, %q", cp, c, r[0], b, errStr)
+			}
+		}
+	}
+}
+
+func TestCodePageEncodeError(t *testing.T) {
+	if _, errStr := CodePage037.Encode([]rune("€")); errStr != "rune not in EBCDIC CodePage037" {
+		t.Errorf("Encode of U+20AC: error %q", errStr)
+	}
+}
@@ -10,6 +10,11 @@ const (
 
 var codePage1047 map[byte]rune
 
+// toCodePage1047 is the inverse of codePage1047. Where several codes
+// decode to the same rune, it holds the lowest, so '\n' encodes as
+// NL (0x15), the newline of z/OS text files.
+var toCodePage1047 map[rune]byte
+
 func init() {
 
 	codePage1047 = map[byte]rune{
@@ -270,6 +275,14 @@ func init() {
 		0xfe: RuneError,
 		0xff: RuneError,
 	}
+
+	toCodePage1047 = make(map[rune]byte, len(codePage1047))
+	for c := 0xff; c >= 0; c-- {
+		if r := codePage1047[byte(c)]; r != RuneError {
+			toCodePage1047[r] = byte(c)
+		}
+	}
+	initCodePage1047()
 }
 
 // Decode from EBCDIC CodePage1047 to UTF-8
@@ -287,3 +300,19 @@ func Decode(src []byte) ([]rune, string) {
 	}
 	return dst, string("")
 }
+
+// Encode from UTF-8 to EBCDIC CodePage1047
+func Encode(src []rune) ([]byte, string) {
+	if len(src) == 0 {
+		return nil, string("")
+	}
+	dst := make([]byte, 0, len(src))
+	for _, r := range src {
+		c, ok := toCodePage1047[r]
+		if !ok {
+			return nil, string("rune not in EBCDIC CodePage1047")
+		}
+		dst = append(dst, c)
+	}
+	return dst, string("")
+}
@@ -0,0 +1,157 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+// TaskDesc describes a task created with runtime/trace.NewTask.
+type TaskDesc struct {
+	ID       uint64
+	Name     string      // empty if the task was created before tracing started
+	Parent   *TaskDesc   // nil for a task without parent
+	Children []*TaskDesc // subtasks created during tracing
+	Create   *Event      // UserTaskCreate event; nil if the task was created before tracing started
+	End      *Event      // UserTaskEnd event; nil if the task did not end before tracing stopped
+	Regions  []*RegionDesc
+	Logs     []*Event        // UserLog events
+	Gs       map[uint64]bool // goroutines that created, ended, or worked on the task
+}
+
+// Complete reports whether both the creation and the end of the task
+// are in the trace.
+func (t *TaskDesc) Complete() bool {
+	return t.Create != nil && t.End != nil
+}
+
+// Duration returns the time from the creation to the end of the task,
+// bounded by the first and last time of the trace, as given by
+// firstTs and lastTs.
+func (t *TaskDesc) Duration(firstTs, lastTs int64) int64 {
+	return t.LastTimestamp(lastTs) - t.FirstTimestamp(firstTs)
+}
+
+// FirstTimestamp returns the time the task was created, or firstTs
+// if it was created before tracing started.
+func (t *TaskDesc) FirstTimestamp(firstTs int64) int64 {
+	if t.Create != nil {
+		return t.Create.Ts
+	}
+	return firstTs
+}
+
+// LastTimestamp returns the time the task ended, or lastTs if it did
+// not end before tracing stopped.
+func (t *TaskDesc) LastTimestamp(lastTs int64) int64 {
+	if t.End != nil {
+		return t.End.Ts
+	}
+	return lastTs
+}
+
+// Descendants returns the task and all of its subtasks.
+func (t *TaskDesc) Descendants() []*TaskDesc {
+	res := []*TaskDesc{t}
+	for _, c := range t.Children {
+		res = append(res, c.Descendants()...)
+	}
+	return res
+}
+
+// RegionDesc describes a region of a goroutine marked with
+// runtime/trace.WithRegion or StartRegion.
+type RegionDesc struct {
+	Name   string
+	TaskID uint64 // 0 for a region of the background task
+	G      uint64
+	Start  *Event // UserRegion start event; nil if the region started before tracing started
+	End    *Event // UserRegion end event; nil if the region did not end before tracing stopped
+}
+
+// Duration returns the duration of the region, bounded by the first
+// and last time of the trace, as given by firstTs and lastTs.
+func (r *RegionDesc) Duration(firstTs, lastTs int64) int64 {
+	return r.LastTimestamp(lastTs) - r.FirstTimestamp(firstTs)
+}
+
+// FirstTimestamp returns the time the region started, or firstTs if it
+// started before tracing started.
+func (r *RegionDesc) FirstTimestamp(firstTs int64) int64 {
+	if r.Start != nil {
+		return r.Start.Ts
+	}
+	return firstTs
+}
+
+// LastTimestamp returns the time the region ended, or lastTs if it did
+// not end before tracing stopped.
+func (r *RegionDesc) LastTimestamp(lastTs int64) int64 {
+	if r.End != nil {
+		return r.End.Ts
+	}
+	return lastTs
+}
+
+// UserAnnotations collects the tasks and regions of the trace, which
+// must have been post-processed by Parse. The tasks are indexed by
+// their ids; the background task, which has id 0, is not included.
+// The regions, including those of the background task, are returned
+// in the order of their start; those that started before tracing
+// started come first.
+func UserAnnotations(events []*Event) (tasks map[uint64]*TaskDesc, regions []*RegionDesc) {
+	tasks = make(map[uint64]*TaskDesc)
+	task := func(id uint64) *TaskDesc {
+		if id == 0 {
+			return nil
+		}
+		t := tasks[id]
+		if t == nil {
+			t = &TaskDesc{ID: id, Gs: make(map[uint64]bool)}
+			tasks[id] = t
+		}
+		return t
+	}
+	var early []*RegionDesc         // regions that started before tracing started
+	linked := make(map[*Event]bool) // region end events linked to their start
+	for _, ev := range events {
+		switch ev.Type {
+		case EvUserTaskCreate:
+			t := task(ev.Args[0])
+			t.Name = ev.SArgs[0]
+			t.Create = ev
+			t.Gs[ev.G] = true
+			if p := task(ev.Args[1]); p != nil {
+				t.Parent = p
+				p.Children = append(p.Children, t)
+			}
+		case EvUserTaskEnd:
+			if t := task(ev.Args[0]); t != nil {
+				t.End = ev
+				t.Gs[ev.G] = true
+			}
+		case EvUserLog:
+			if t := task(ev.Args[0]); t != nil {
+				t.Logs = append(t.Logs, ev)
+				t.Gs[ev.G] = true
+			}
+		case EvUserRegion:
+			if t := task(ev.Args[0]); t != nil {
+				t.Gs[ev.G] = true
+			}
+			if ev.Args[1] == 0 {
+				regions = append(regions, &RegionDesc{Name: ev.SArgs[0], TaskID: ev.Args[0], G: ev.G, Start: ev, End: ev.Link})
+				if ev.Link != nil {
+					linked[ev.Link] = true
+				}
+			} else if !linked[ev] {
+				early = append(early, &RegionDesc{Name: ev.SArgs[0], TaskID: ev.Args[0], G: ev.G, End: ev})
+			}
+		}
+	}
+	regions = append(early, regions...)
+	for _, r := range regions {
+		if t := task(r.TaskID); t != nil {
+			t.Regions = append(t.Regions, r)
+		}
+	}
+	return tasks, regions
+}
@@ -27,6 +27,7 @@ type Event struct {
 	StkID uint64    // unique stack ID
 	Stk   []*Frame  // stack trace (can be empty)
 	Args  [3]uint64 // event-type-specific arguments
+	SArgs []string  // event-type-specific string arguments
 	// linked event (can be nil), depends on event type:
 	// for GCStart: the GCStop
 	// for GCScanStart: the GCScanDone
@@ -38,6 +39,8 @@ type Event struct {
 	// for GoUnblock: the associated GoStart
 	// for blocking GoSysCall: the associated GoSysExit
 	// for GoSysExit: the next GoStart
+	// for UserTaskCreate: the UserTaskEnd
+	// for UserRegion start: the corresponding UserRegion end
 	Link *Event
 }
 
@@ -59,11 +62,11 @@ const (
 
 // Parse parses, post-processes and verifies the trace.
 func Parse(r io.Reader) ([]*Event, error) {
-	rawEvents, err := readTrace(r)
+	ver, rawEvents, strings, err := readTrace(r)
 	if err != nil {
 		return nil, err
 	}
-	events, err := parseEvents(rawEvents)
+	events, err := parseEvents(ver, rawEvents, strings)
 	if err != nil {
 		return nil, err
 	}
@@ -80,46 +83,88 @@ func Parse(r io.Reader) ([]*Event, error) {
 
 // rawEvent is a helper type used during parsing.
 type rawEvent struct {
-	off  int
-	typ  byte
-	args []uint64
+	off   int
+	typ   byte
+	args  []uint64
+	sargs []string
 }
 
+// Trace format versions, as given by the trace header.
+const (
+	version1005 = 1005 // "go 1.5 trace": Go 1.5 and 1.6
+	version1007 = 1007 // "go 1.7 trace": adds the string dictionary and user annotations
+)
+
 // readTrace does wire-format parsing and verification.
 // It does not care about specific event types and argument meaning.
-func readTrace(r io.Reader) ([]rawEvent, error) {
+// It returns the version of the trace format, the events, and the
+// string dictionary of the trace.
+func readTrace(r io.Reader) (ver int, events []rawEvent, strings map[uint64]string, err error) {
 	// Read and validate trace header.
 	var buf [16]byte
 	off, err := r.Read(buf[:])
 	if off != 16 || err != nil {
-		return nil, fmt.Errorf("failed to read header: read %v, err %v", off, err)
+		err = fmt.Errorf("failed to read header: read %v, err %v", off, err)
+		return
 	}
-	if bytes.Compare(buf[:], []byte("go 1.5 trace\x00\x00\x00\x00")) != 0 {
-		return nil, fmt.Errorf("not a trace file")
+	switch {
+	case bytes.Equal(buf[:], []byte("go 1.5 trace\x00\x00\x00\x00")):
+		ver = version1005
+	case bytes.Equal(buf[:], []byte("go 1.7 trace\x00\x00\x00\x00")):
+		ver = version1007
+	default:
+		err = fmt.Errorf("not a trace file")
+		return
 	}
 
 	// Read events.
-	var events []rawEvent
+	strings = make(map[uint64]string)
 	for {
 		// Read event type and number of arguments (1 byte).
 		off0 := off
-		n, err := r.Read(buf[:1])
+		var n int
+		n, err = r.Read(buf[:1])
 		if err == io.EOF {
+			err = nil
 			break
 		}
 		if err != nil || n != 1 {
-			return nil, fmt.Errorf("failed to read trace at offset 0x%x: n=%v err=%v", off0, n, err)
+			err = fmt.Errorf("failed to read trace at offset 0x%x: n=%v err=%v", off0, n, err)
+			return
 		}
 		off += n
 		typ := buf[0] << 2 >> 2
 		narg := buf[0] >> 6
+		if typ == EvString && ver >= version1007 {
+			// String dictionary entry [ID, length, string].
+			var id uint64
+			var s string
+			id, off, err = readVal(r, off)
+			if err != nil {
+				return
+			}
+			if id == 0 {
+				err = fmt.Errorf("string at offset 0x%x has invalid id 0", off0)
+				return
+			}
+			if strings[id] != "" {
+				err = fmt.Errorf("string at offset 0x%x has duplicate id %v", off0, id)
+				return
+			}
+			s, off, err = readStr(r, off)
+			if err != nil {
+				return
+			}
+			strings[id] = s
+			continue
+		}
 		ev := rawEvent{typ: typ, off: off0}
 		if narg < 3 {
 			for i := 0; i < int(narg)+2; i++ { // sequence number and time stamp are present but not counted in narg
 				var v uint64
 				v, off, err = readVal(r, off)
 				if err != nil {
-					return nil, err
+					return
 				}
 				ev.args = append(ev.args, v)
 			}
@@ -128,36 +173,46 @@ func readTrace(r io.Reader) ([]rawEvent, error) {
 			var v uint64
 			v, off, err = readVal(r, off)
 			if err != nil {
-				return nil, err
+				return
 			}
 			evLen := v
 			off1 := off
 			for evLen > uint64(off-off1) {
 				v, off, err = readVal(r, off)
 				if err != nil {
-					return nil, err
+					return
 				}
 				ev.args = append(ev.args, v)
 			}
 			if evLen != uint64(off-off1) {
-				return nil, fmt.Errorf("event has wrong length at offset 0x%x: want %v, got %v", off0, evLen, off-off1)
+				err = fmt.Errorf("event has wrong length at offset 0x%x: want %v, got %v", off0, evLen, off-off1)
+				return
+			}
+		}
+		if typ == EvUserLog && ver >= version1007 {
+			// The message follows the event as its length and bytes.
+			var s string
+			s, off, err = readStr(r, off)
+			if err != nil {
+				return
 			}
+			ev.sargs = []string{s}
 		}
 		events = append(events, ev)
 	}
-	return events, nil
+	return
 }
 
 // Parse events transforms raw events into events.
 // It does analyze and verify per-event-type arguments.
-func parseEvents(rawEvents []rawEvent) (events []*Event, err error) {
+func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (events []*Event, err error) {
 	var ticksPerSec, lastSeq, lastTs int64
 	var lastG, timerGoid uint64
 	var lastP int
 	lastGs := make(map[int]uint64) // last goroutine running on P
 	stacks := make(map[uint64][]*Frame)
 	for _, raw := range rawEvents {
-		if raw.typ == EvNone || raw.typ >= EvCount {
+		if raw.typ == EvNone || raw.typ >= EvCount || ver < version1007 && raw.typ >= EvString {
 			err = fmt.Errorf("unknown event type %v at offset 0x%x", raw.typ, raw.off)
 			return
 		}
@@ -254,6 +309,12 @@ func parseEvents(rawEvents []rawEvent) (events []*Event, err error) {
 				if e.Args[2] != 0 {
 					e.Ts = int64(e.Args[2])
 				}
+			case EvUserTaskCreate, EvUserRegion:
+				// Task or region type.
+				e.SArgs = []string{strings[e.Args[2]]}
+			case EvUserLog:
+				// Category and message.
+				e.SArgs = []string{strings[e.Args[1]], raw.sargs[0]}
 			}
 			events = append(events, e)
 		}
@@ -377,6 +438,8 @@ func postProcessTrace(events []*Event) error {
 
 	gs := make(map[uint64]gdesc)
 	ps := make(map[int]pdesc)
+	tasks := make(map[uint64]*Event)           // task id to the task creation event
+	activeRegions := make(map[uint64][]*Event) // goroutine id to the stack of its active region start events
 	gs[0] = gdesc{state: gRunning}
 	var evGC *Event
 
@@ -556,6 +619,50 @@ func postProcessTrace(events []*Event) error {
 			g.evStart.Link = ev
 			g.evStart = nil
 			p.g = 0
+		case EvUserTaskCreate:
+			if err := checkRunning(p, g, ev, false); err != nil {
+				return err
+			}
+			taskid := ev.Args[0]
+			if _, ok := tasks[taskid]; ok {
+				return fmt.Errorf("task %v is created twice (offset %v, time %v)", taskid, ev.Off, ev.Ts)
+			}
+			tasks[taskid] = ev
+		case EvUserTaskEnd:
+			if err := checkRunning(p, g, ev, false); err != nil {
+				return err
+			}
+			// The task may have been created before tracing started.
+			if evCreate, ok := tasks[ev.Args[0]]; ok {
+				evCreate.Link = ev
+				delete(tasks, ev.Args[0])
+			}
+		case EvUserRegion:
+			if err := checkRunning(p, g, ev, false); err != nil {
+				return err
+			}
+			regions := activeRegions[ev.G]
+			switch mode := ev.Args[1]; mode {
+			case 0: // start
+				activeRegions[ev.G] = append(regions, ev)
+			case 1: // end
+				// The region may have started before tracing started.
+				if n := len(regions); n > 0 {
+					start := regions[n-1]
+					if start.Args[0] != ev.Args[0] || start.SArgs[0] != ev.SArgs[0] {
+						return fmt.Errorf("region %q of task %v of g %v ends within region %q of task %v (offset %v, time %v)",
+							ev.SArgs[0], ev.Args[0], ev.G, start.SArgs[0], start.Args[0], ev.Off, ev.Ts)
+					}
+					start.Link = ev
+					activeRegions[ev.G] = regions[:n-1]
+				}
+			default:
+				return fmt.Errorf("invalid region mode %v (offset %v, time %v)", mode, ev.Off, ev.Ts)
+			}
+		case EvUserLog:
+			if err := checkRunning(p, g, ev, false); err != nil {
+				return err
+			}
 		}
 
 		gs[ev.G] = g
@@ -672,6 +779,24 @@ func readVal(r io.Reader, off0 int) (v uint64, off int, err error) {
 	return 0, 0, fmt.Errorf("bad value at offset 0x%x", off0)
 }
 
+// readStr reads a string, given by its base-128 length followed by
+// its bytes, from r.
+func readStr(r io.Reader, off0 int) (s string, off int, err error) {
+	var n uint64
+	n, off, err = readVal(r, off0)
+	if err != nil {
+		return
+	}
+	if n > 1e6 {
+		return "", 0, fmt.Errorf("string at offset 0x%x has bad length %v", off0, n)
+	}
+	buf := make([]byte, n)
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return "", 0, fmt.Errorf("failed to read trace at offset 0x%x: %v", off0, err)
+	}
+	return string(buf), off + int(n), nil
+}
+
 type eventList []*Event
 
 func (l eventList) Len() int {
@@ -738,7 +863,12 @@ const (
 	EvNextGC         = 34 // memstats.next_gc change [timestamp, next_gc]
 	EvTimerGoroutine = 35 // denotes timer goroutine [timer goroutine id]
 	EvFutileWakeup   = 36 // denotes that the previous wakeup of this goroutine was futile [timestamp]
-	EvCount          = 37
+	EvString         = 37 // string dictionary entry [ID, length, string]
+	EvUserTaskCreate = 38 // trace.NewTask [timestamp, internal task id, internal parent task id, stack, name string]
+	EvUserTaskEnd    = 39 // end of a task [timestamp, internal task id, stack]
+	EvUserRegion     = 40 // trace.WithRegion [timestamp, internal task id, mode(0:start, 1:end), stack, name string]
+	EvUserLog        = 41 // trace.Log [timestamp, internal task id, key string id, stack, value string]
+	EvCount          = 42
 )
 
 var EventDescriptions = [EvCount]struct {
@@ -783,4 +913,9 @@ var EventDescriptions = [EvCount]struct {
 	EvNextGC:         {"NextGC", false, []string{"mem"}},
 	EvTimerGoroutine: {"TimerGoroutine", false, []string{"g", "unused"}},
 	EvFutileWakeup:   {"FutileWakeup", false, []string{}},
+	EvString:         {"String", false, []string{}},
+	EvUserTaskCreate: {"UserTaskCreate", true, []string{"taskid", "pid", "typeid"}},
+	EvUserTaskEnd:    {"UserTaskEnd", true, []string{"taskid"}},
+	EvUserRegion:     {"UserRegion", true, []string{"taskid", "mode", "typeid"}},
+	EvUserLog:        {"UserLog", true, []string{"id", "keyid"}},
 }
@@ -20,6 +20,9 @@ func TestCorruptedInputs(t *testing.T) {
 		"go 1.5 trace\x00\x00\x00\x00Q00\x020",
 		"go 1.5 trace\x00\x00\x00\x00T00\x020",
 		"go 1.5 trace\x00\x00\x00\x00\xc3\x0200",
+		"go 1.7 trace\x00\x00\x00\x00%\x00\x00",
+		"go 1.7 trace\x00\x00\x00\x00%\x01\xff\xff\xff\xff\x0f",
+		"go 1.7 trace\x00\x00\x00\x00%\x01\x01a%\x01\x01a",
 	}
 	for _, data := range tests {
 		events, err := Parse(strings.NewReader(data))
@@ -0,0 +1,234 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package math
+
+// IBM hexadecimal floating point (HFP) numbers have a sign bit, a
+// 7-bit characteristic holding a base 16 exponent biased by 64, and a
+// fraction of 6 (short), 14 (long) or 28 (extended) hexadecimal
+// digits. The value of a number is ±0.fraction × 16**(characteristic-64).
+// An extended number is stored as two long numbers: the first holds
+// the sign, the characteristic and the leading 14 digits, and the
+// second the trailing 14 digits.
+//
+// HFP has no infinities or NaNs, and its range, about 5.4e-79 to
+// 7.2e75, differs from that of both IEEE 754 formats, so conversions
+// may overflow or underflow. Conversions round to nearest, ties to even.
+
+// An HFPResult describes the outcome of a conversion between IEEE 754
+// and IBM hexadecimal floating point.
+type HFPResult int
+
+const (
+	// HFPExact means the value was converted exactly.
+	HFPExact HFPResult = iota
+
+	// HFPInexact means the value was rounded to the nearest
+	// representable value.
+	HFPInexact
+
+	// HFPOverflow means the magnitude of the value was too large.
+	// The result is the largest finite HFP number or an IEEE
+	// infinity of the same sign.
+	HFPOverflow
+
+	// HFPUnderflow means the magnitude of the value was too small to
+	// be represented without loss. The result is zero when converting
+	// to HFP, or a rounded subnormal or zero when converting to IEEE.
+	HFPUnderflow
+
+	// HFPInvalid means the value was a NaN, which has no HFP
+	// representation. The result is zero.
+	HFPInvalid
+)
+
+const (
+	hfpCharMask = 0x7F
+	hfpBias     = 64
+)
+
+// Float32ToHFP returns the HFP short representation of f.
+func Float32ToHFP(f float32) (uint32, HFPResult) {
+	neg, char, frac, r := hfpFromFloat64(float64(f), 6)
+	b := uint32(frac) | uint32(char)<<24
+	if neg {
+		b |= 1 << 31
+	}
+	return b, r
+}
+
+// Float64ToHFP returns the HFP long representation of f.
+func Float64ToHFP(f float64) (uint64, HFPResult) {
+	neg, char, frac, r := hfpFromFloat64(f, 14)
+	b := frac | uint64(char)<<56
+	if neg {
+		b |= 1 << 63
+	}
+	return b, r
+}
+
+// Float64ToHFPExtended returns the HFP extended representation of f
+// as its high and low halves. The low half of a nonzero result has
+// the characteristic of the high half less 14, modulo 128.
+func Float64ToHFPExtended(f float64) (hi, lo uint64, r HFPResult) {
+	// The 53-bit significand of f always fits in the leading 14 digits,
+	// so the trailing digits are zero.
+	neg, char, frac, r := hfpFromFloat64(f, 14)
+	hi = frac | uint64(char)<<56
+	if frac != 0 {
+		lo = uint64((char-14)&hfpCharMask) << 56
+	}
+	if neg {
+		hi |= 1 << 63
+		lo |= 1 << 63
+	}
+	return hi, lo, r
+}
+
+// HFPToFloat32 returns the float32 nearest to the HFP short number b.
+func HFPToFloat32(b uint32) (float32, HFPResult) {
+	x, r := hfpToIEEE(int(b>>24&hfpCharMask), uint64(b&(1<<24-1))<<40, 0, 24, 127)
+	return Float32frombits(uint32(x) | b&(1<<31)), r
+}
+
+// HFPToFloat64 returns the float64 nearest to the HFP long number b.
+func HFPToFloat64(b uint64) (float64, HFPResult) {
+	x, r := hfpToIEEE(int(b>>56&hfpCharMask), (b&(1<<56-1))<<8, 0, 53, bias)
+	return Float64frombits(x | b&(1<<63)), r
+}
+
+// HFPExtendedToFloat64 returns the float64 nearest to the HFP extended
+// number with high and low halves hi and lo. The sign and
+// characteristic of the low half are ignored.
+func HFPExtendedToFloat64(hi, lo uint64) (float64, HFPResult) {
+	lo &= 1<<56 - 1
+	x, r := hfpToIEEE(int(hi>>56&hfpCharMask), (hi&(1<<56-1))<<8|lo>>48, lo<<16, 53, bias)
+	return Float64frombits(x | hi&(1<<63)), r
+}
+
+// hfpFromFloat64 rounds f to an HFP number with the given number of
+// hexadecimal digits and returns its sign, characteristic and fraction.
+func hfpFromFloat64(f float64, digits uint) (neg bool, char int, frac uint64, r HFPResult) {
+	x := Float64bits(f)
+	neg = x>>63 != 0
+	exp := int(x>>shift) & mask
+	m := x & (1<<shift - 1)
+	switch {
+	case exp == mask && m != 0:
+		return false, 0, 0, HFPInvalid
+	case exp == mask:
+		return neg, hfpCharMask, 1<<(4*digits) - 1, HFPOverflow
+	case exp == 0 && m == 0:
+		return neg, 0, 0, HFPExact
+	case exp == 0:
+		exp = 1 // subnormal
+	default:
+		m |= 1 << shift
+	}
+
+	// f = m × 2**e = 0.m × 2**e2 with the top bit of m set.
+	s := nlz64(m)
+	m <<= s
+	e2 := exp - bias - shift + 64 - int(s)
+
+	// Choose the hexadecimal exponent e16 so that the leading
+	// hexadecimal digit of the fraction is not zero, and round.
+	e16 := (e2 + 3) >> 2
+	rs := uint(4*e16 - e2)
+	frac, inexact := shiftRound(m>>rs, m&(1<<rs-1) != 0, 64-4*digits)
+	if frac == 1<<(4*digits) {
+		frac >>= 4
+		e16++
+	}
+
+	char = e16 + hfpBias
+	switch {
+	case char > hfpCharMask:
+		return neg, hfpCharMask, 1<<(4*digits) - 1, HFPOverflow
+	case char < 0:
+		return false, 0, 0, HFPUnderflow
+	case inexact:
+		return neg, char, frac, HFPInexact
+	}
+	return neg, char, frac, HFPExact
+}
+
+// hfpToIEEE returns the IEEE 754 representation, without the sign
+// bit, of the magnitude of the HFP number with the given
+// characteristic and a fraction whose bits are those of hi followed
+// by those of lo. The IEEE format has a significand of prec bits
+// and an exponent bias of ebias.
+func hfpToIEEE(char int, hi, lo uint64, prec uint, ebias int) (uint64, HFPResult) {
+	e2 := 4 * (char - hfpBias)
+	if hi == 0 {
+		if lo == 0 {
+			return 0, HFPExact
+		}
+		hi, lo = lo, 0
+		e2 -= 64
+	}
+	if s := nlz64(hi); s > 0 {
+		hi = hi<<s | lo>>(64-s)
+		lo <<= s
+		e2 -= int(s)
+	}
+
+	// The value is 0.hi × 2**e2 = 1.hi × 2**(e2-1).
+	exp := e2 - 1 + ebias
+	emax := uint64(2*ebias + 1)
+	if exp > ebias*2 {
+		return emax << (prec - 1), HFPOverflow
+	}
+	sh := 64 - prec
+	if exp < 1 {
+		// Subnormal: fewer bits of the fraction are kept.
+		sh += uint(1 - exp)
+		exp = 1
+	}
+	m, inexact := shiftRound(hi, lo != 0, sh)
+	// m includes the implicit leading bit, which carries into the
+	// exponent field if rounding overflows the significand.
+	y := uint64(exp-1)<<(prec-1) + m
+	switch {
+	case y>>(prec-1) >= emax:
+		return emax << (prec - 1), HFPOverflow
+	case inexact && y < 1<<(prec-1):
+		return y, HFPUnderflow
+	case inexact:
+		return y, HFPInexact
+	}
+	return y, HFPExact
+}
+
+// shiftRound shifts m right by sh >= 1 bits, rounding to nearest even.
+// The sticky flag reports whether there are nonzero bits below m.
+func shiftRound(m uint64, sticky bool, sh uint) (q uint64, inexact bool) {
+	if sh > 64 {
+		return 0, m != 0 || sticky
+	}
+	var rem uint64
+	if sh < 64 {
+		q, rem = m>>sh, m&(1<<sh-1)
+	} else {
+		rem = m
+	}
+	half := uint64(1) << (sh - 1)
+	if rem > half || rem == half && (sticky || q&1 != 0) {
+		q++
+	}
+	return q, rem != 0 || sticky
+}
+
+// nlz64 returns the number of leading zero bits in x.
+func nlz64(x uint64) uint {
+	if x == 0 {
+		return 64
+	}
+	var n uint
+	for x&(1<<63) == 0 {
+		x <<= 1
+		n++
+	}
+	return n
+}
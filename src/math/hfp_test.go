@@ -0,0 +1,304 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package math_test
+
+import (
+	. "math"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+var hfpShortTests = []struct {
+	f   float32
+	hfp uint32
+	r   HFPResult
+}{
+	{0, 0x00000000, HFPExact},
+	{float32(Copysign(0, -1)), 0x80000000, HFPExact},
+	{1, 0x41100000, HFPExact},
+	{-1, 0xC1100000, HFPExact},
+	{0.5, 0x40800000, HFPExact},
+	{100, 0x42640000, HFPExact},
+	{-118.625, 0xC276A000, HFPExact},
+	{0.1, 0x4019999A, HFPInexact},
+	{MaxFloat32, 0x60FFFFFF, HFPExact},
+	{SmallestNonzeroFloat32, 0x1B800000, HFPExact},
+	{float32(Inf(1)), 0x7FFFFFFF, HFPOverflow},
+	{float32(Inf(-1)), 0xFFFFFFFF, HFPOverflow},
+	{float32(NaN()), 0, HFPInvalid},
+}
+
+func TestFloat32ToHFP(t *testing.T) {
+	for _, tt := range hfpShortTests {
+		if b, r := Float32ToHFP(tt.f); b != tt.hfp || r != tt.r {
+			t.Errorf("Float32ToHFP(%g) = %#08x, %d; want %#08x, %d", tt.f, b, r, tt.hfp, tt.r)
+		}
+	}
+}
+
+var hfpLongTests = []struct {
+	f   float64
+	hfp uint64
+	r   HFPResult
+}{
+	{0, 0, HFPExact},
+	{1, 0x4110000000000000, HFPExact},
+	{-118.625, 0xC276A00000000000, HFPExact},
+	{0.1, 0x401999999999999A, HFPExact},
+	{1 + Ldexp(1, -52), 0x4110000000000001, HFPExact},
+	{8 + Ldexp(1, -49), 0x4180000000000008, HFPExact},
+	{Ldexp(1, -260), 0x0010000000000000, HFPExact},
+	{Ldexp(1, -261), 0, HFPUnderflow},
+	{-Ldexp(1, -261), 0, HFPUnderflow},
+	{Ldexp(1, 252) * (1 - Ldexp(1, -53)), 0x7FFFFFFFFFFFFFF8, HFPExact},
+	{Ldexp(1, 252), 0x7FFFFFFFFFFFFFFF, HFPOverflow},
+	{-MaxFloat64, 0xFFFFFFFFFFFFFFFF, HFPOverflow},
+	{Inf(1), 0x7FFFFFFFFFFFFFFF, HFPOverflow},
+	{NaN(), 0, HFPInvalid},
+}
+
+func TestFloat64ToHFP(t *testing.T) {
+	for _, tt := range hfpLongTests {
+		if b, r := Float64ToHFP(tt.f); b != tt.hfp || r != tt.r {
+			t.Errorf("Float64ToHFP(%g) = %#016x, %d; want %#016x, %d", tt.f, b, r, tt.hfp, tt.r)
+		}
+	}
+}
+
+func TestHFPLongRounding(t *testing.T) {
+	// 14 hexadecimal digits hold 53 to 56 significant bits, so a float64
+	// is always exact. Long HFP numbers with a leading digit above 1 may
+	// need rounding to fit a float64.
+	for _, tt := range []struct {
+		hfp uint64
+		f   float64
+		r   HFPResult
+	}{
+		{0x4110000000000000, 1, HFPExact},
+		{0x4110000000000001, 1 + Ldexp(1, -52), HFPExact},
+		{0x4180000000000001, 8, HFPInexact},
+		{0x4180000000000004, 8, HFPInexact},                 // tie, to even
+		{0x418000000000000C, 8 + Ldexp(1, -48), HFPInexact}, // tie, to even
+		{0x4180000000000005, 8 + Ldexp(1, -49), HFPInexact},
+		{0x41FFFFFFFFFFFFFF, 16, HFPInexact},
+		{0x4010000000000000, 1.0 / 16, HFPExact},
+		{0x4000000000000001, Ldexp(1, -56), HFPExact}, // unnormalized
+		{0x7FFFFFFFFFFFFFFF, Ldexp(1, 252), HFPInexact},
+		{0x8000000000000000, Copysign(0, -1), HFPExact},
+	} {
+		if f, r := HFPToFloat64(tt.hfp); f != tt.f || Signbit(f) != Signbit(tt.f) || r != tt.r {
+			t.Errorf("HFPToFloat64(%#016x) = %g, %d; want %g, %d", tt.hfp, f, r, tt.f, tt.r)
+		}
+	}
+}
+
+// hfpShortValue returns the exact value of the HFP short number b.
+func hfpShortValue(b uint32) float64 {
+	v := Ldexp(float64(b&(1<<24-1)), 4*(int(b>>24&0x7F)-64)-24)
+	if b>>31 != 0 {
+		v = -v
+	}
+	return v
+}
+
+func testHFPToFloat32(t *testing.T, b uint32) bool {
+	f, r := HFPToFloat32(b)
+	// Every HFP short number is exact as a float64, and the conversion
+	// to float32 rounds to nearest even.
+	v := hfpShortValue(b)
+	want := float32(v)
+	wantR := HFPExact
+	switch {
+	case IsInf(float64(want), 0):
+		wantR = HFPOverflow
+	case float64(want) != v && Abs(float64(want)) < Ldexp(1, -126):
+		wantR = HFPUnderflow
+	case float64(want) != v:
+		wantR = HFPInexact
+	}
+	if Float32bits(f) != Float32bits(want) || r != wantR {
+		t.Errorf("HFPToFloat32(%#08x) = %g, %d; want %g, %d", b, f, r, want, wantR)
+		return false
+	}
+	return true
+}
+
+func TestHFPToFloat32(t *testing.T) {
+	// Every fraction for the characteristics of the smallest float32,
+	// the smallest normal float32 and the largest float32, and a
+	// sample of the fractions for some others.
+	for _, c := range []uint32{0x1B, 0x21, 0x60, 0, 0x1A, 0x20, 0x22, 0x40, 0x41, 0x5F, 0x61, 0x7F} {
+		step := uint32(997)
+		if !testing.Short() && (c == 0x1B || c == 0x21 || c == 0x60) {
+			step = 1
+		}
+		for frac := uint32(0); frac < 1<<24; frac += step {
+			if !testHFPToFloat32(t, c<<24|frac) || !testHFPToFloat32(t, 1<<31|c<<24|frac) {
+				return
+			}
+		}
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		if !testHFPToFloat32(t, rnd.Uint32()) {
+			return
+		}
+	}
+}
+
+// testFloat32ToHFP checks that Float32ToHFP(f) is the normalized
+// HFP short number nearest to f.
+func testFloat32ToHFP(t *testing.T, f float32) bool {
+	b, r := Float32ToHFP(f)
+	v := hfpShortValue(b)
+	if f == 0 {
+		if b != Float32bits(f) || r != HFPExact {
+			t.Errorf("Float32ToHFP(%g) = %#08x, %d; want %#08x, %d", f, b, r, Float32bits(f), HFPExact)
+			return false
+		}
+		return true
+	}
+	frac := b & (1<<24 - 1)
+	if frac < 1<<20 || Signbit(v) != Signbit(float64(f)) {
+		t.Errorf("Float32ToHFP(%g) = %#08x, not normalized or wrong sign", f, b)
+		return false
+	}
+	wantR := HFPExact
+	if v != float64(f) {
+		wantR = HFPInexact
+	}
+	// The neighboring values of the same sign. Differences between
+	// them and f are exact in float64.
+	up, down := b+1, b-1
+	if frac == 1<<24-1 {
+		up = b + 1<<24 - (1<<24 - 1<<20 - 1)
+	}
+	if frac == 1<<20 {
+		down = b - 1<<24 + (1<<24 - 1 - 1<<20)
+	}
+	d := Abs(v - float64(f))
+	du, dd := Abs(hfpShortValue(up)-float64(f)), Abs(hfpShortValue(down)-float64(f))
+	if r != wantR || d > du || d > dd || (d == du || d == dd) && d != 0 && frac&1 != 0 {
+		t.Errorf("Float32ToHFP(%g) = %#08x (%g), %d; not nearest even", f, b, v, r)
+		return false
+	}
+	return true
+}
+
+func TestFloat32ToHFPRounding(t *testing.T) {
+	// Every significand for the subnormals, the smallest normal
+	// exponent and the largest exponent, a sample of the significands
+	// for some others, and a sample of all float32 numbers.
+	for _, e := range []uint32{0, 1, 254, 2, 3, 4, 5, 126, 127, 128, 129, 253} {
+		step := uint32(101)
+		if !testing.Short() && (e == 0 || e == 1 || e == 254) {
+			step = 1
+		}
+		for m := uint32(0); m < 1<<23; m += step {
+			if !testFloat32ToHFP(t, Float32frombits(e<<23|m)) || !testFloat32ToHFP(t, Float32frombits(1<<31|e<<23|m)) {
+				return
+			}
+		}
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		f := Float32frombits(rnd.Uint32())
+		if IsNaN(float64(f)) || IsInf(float64(f), 0) {
+			continue
+		}
+		if !testFloat32ToHFP(t, f) {
+			return
+		}
+	}
+}
+
+// hfpBigValue returns the exact value of the HFP number with the given
+// sign, characteristic and fraction of n hexadecimal digits.
+func hfpBigValue(neg bool, char int, frac *big.Int, n int) *big.Float {
+	v := new(big.Float).SetPrec(256).SetInt(frac)
+	v.SetMantExp(v, 4*(char-64)-4*n)
+	if neg {
+		v.Neg(v)
+	}
+	return v
+}
+
+func TestHFPLongRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	n := 100000
+	if testing.Short() {
+		n = 10000
+	}
+	for i := 0; i < n; i++ {
+		b := uint64(rnd.Int63())<<1 | uint64(rnd.Int63n(2))
+		if i%2 == 0 {
+			b |= 1 << 52 // mostly normalized
+		}
+		f, r := HFPToFloat64(b)
+		v := hfpBigValue(b>>63 != 0, int(b>>56&0x7F), new(big.Int).SetUint64(b&(1<<56-1)), 14)
+		want, acc := v.Float64()
+		wantR := HFPExact
+		if acc != big.Exact {
+			wantR = HFPInexact
+		}
+		if f != want || r != wantR {
+			t.Fatalf("HFPToFloat64(%#016x) = %g, %d; want %g, %d", b, f, r, want, wantR)
+		}
+
+		// Normalized numbers convert back exactly.
+		if b&(1<<56-1) < 1<<52 {
+			continue
+		}
+		b2, r := Float64ToHFP(f)
+		if r != HFPExact {
+			t.Fatalf("Float64ToHFP(%g) = %#016x, %d; want exact", f, b2, r)
+		}
+		if g, _ := HFPToFloat64(b2); g != f {
+			t.Fatalf("HFPToFloat64(Float64ToHFP(%g)) = %g", f, g)
+		}
+	}
+}
+
+func TestHFPExtended(t *testing.T) {
+	for _, tt := range []struct {
+		hi, lo uint64
+		f      float64
+		r      HFPResult
+	}{
+		{0x4110000000000000, 0x3300000000000000, 1, HFPExact},
+		{0xC276A00000000000, 0xB400000000000000, -118.625, HFPExact},
+		{0x4180000000000004, 0x3300000000000000, 8, HFPInexact},
+		{0x4180000000000004, 0x3300000000000001, 8 + Ldexp(1, -49), HFPInexact}, // above the tie
+		{0x4100000000000000, 0x3310000000000000, Ldexp(1, -56), HFPExact},       // unnormalized high half
+		{0x0000000000000000, 0x7200000000000000, 0, HFPExact},
+	} {
+		if f, r := HFPExtendedToFloat64(tt.hi, tt.lo); f != tt.f || r != tt.r {
+			t.Errorf("HFPExtendedToFloat64(%#016x, %#016x) = %g, %d; want %g, %d", tt.hi, tt.lo, f, r, tt.f, tt.r)
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		f := Float64frombits(uint64(rnd.Int63())<<1 | uint64(rnd.Int63n(2)))
+		if IsNaN(f) {
+			continue
+		}
+		hi, lo, r := Float64ToHFPExtended(f)
+		long, lr := Float64ToHFP(f)
+		if hi != long || r != lr {
+			t.Fatalf("Float64ToHFPExtended(%g) = %#016x, %d; want %#016x, %d", f, hi, r, long, lr)
+		}
+		if r != HFPExact {
+			continue
+		}
+		if hi&(1<<56-1) != 0 && (lo>>56&0x7F != (hi>>56-14)&0x7F || lo&(1<<56-1) != 0 || lo>>63 != hi>>63) {
+			t.Fatalf("Float64ToHFPExtended(%g) low half = %#016x for high half %#016x", f, lo, hi)
+		}
+		if g, gr := HFPExtendedToFloat64(hi, lo); g != f || gr != HFPExact {
+			t.Fatalf("HFPExtendedToFloat64(Float64ToHFPExtended(%g)) = %g, %d", f, g, gr)
+		}
+	}
+}
@@ -657,6 +657,171 @@ func testTrailersServerToClient(t *testing.T, h2, flush bool) {
 	}
 }
 
+func TestTrailersServerToClientPrefix_h1(t *testing.T) {
+	testTrailersServerToClientPrefix(t, h1Mode, false)
+}
+func TestTrailersServerToClientPrefix_h2(t *testing.T) {
+	testTrailersServerToClientPrefix(t, h2Mode, false)
+}
+func TestTrailersServerToClientPrefix_Flush_h1(t *testing.T) {
+	testTrailersServerToClientPrefix(t, h1Mode, true)
+}
+func TestTrailersServerToClientPrefix_Flush_h2(t *testing.T) {
+	testTrailersServerToClientPrefix(t, h2Mode, true)
+}
+
+// Trailers set with TrailerPrefix need not be declared before the
+// header is written.
+func testTrailersServerToClientPrefix(t *testing.T, h2, flush bool) {
+	defer afterTest(t)
+	const body = "Some body"
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Trailer", "Server-Trailer-A")
+
+		io.WriteString(w, body)
+		if flush {
+			w.(Flusher).Flush()
+		}
+
+		w.Header().Set("Server-Trailer-A", "valuea")
+		w.Header().Set(TrailerPrefix+"Server-Trailer-B", "valueb")
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range res.Header {
+		if strings.HasPrefix(k, TrailerPrefix) {
+			t.Errorf("Header contains %q; TrailerPrefix keys must not be sent as headers", k)
+		}
+	}
+	if err := wantBody(res, nil, body); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.Trailer, (Header{
+		"Server-Trailer-A": {"valuea"},
+		"Server-Trailer-B": {"valueb"},
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Trailer after body read = %v; want %v", got, want)
+	}
+}
+
+// A handler that only ever uses TrailerPrefix must still get its
+// trailers sent, even though nothing was declared in the header.
+func TestTrailersServerToClientPrefixOnly_h1(t *testing.T) {
+	testTrailersServerToClientPrefixOnly(t, h1Mode)
+}
+func TestTrailersServerToClientPrefixOnly_h2(t *testing.T) {
+	testTrailersServerToClientPrefixOnly(t, h2Mode)
+}
+
+func testTrailersServerToClientPrefixOnly(t *testing.T, h2 bool) {
+	defer afterTest(t)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, "foo")
+		w.Header().Set(TrailerPrefix+"Grpc-Status", "0")
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wantBody(res, nil, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Errorf("Trailer Grpc-Status = %q; want %q (Trailer = %v)", got, "0", res.Trailer)
+	}
+}
+
+func TestServerPush_h1(t *testing.T) {
+	defer afterTest(t)
+	cst := newClientServerTest(t, h1Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if _, ok := w.(Pusher); ok {
+			t.Error("HTTP/1 ResponseWriter implements Pusher")
+		}
+	}))
+	defer cst.close()
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
+
+// The Go HTTP/2 client disables push, so the server must refuse to push.
+func TestServerPushDisabledByClient_h2(t *testing.T) {
+	defer afterTest(t)
+	cst := newClientServerTest(t, h2Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path != "/" {
+			t.Errorf("pushed request for %q ran although push is disabled", r.URL.Path)
+			return
+		}
+		p, ok := w.(Pusher)
+		if !ok {
+			t.Error("HTTP/2 ResponseWriter does not implement Pusher")
+			return
+		}
+		if err := p.Push("/pushed", nil); err != ErrNotSupported {
+			t.Errorf("Push = %v; want ErrNotSupported", err)
+		}
+	}))
+	defer cst.close()
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
+
+func TestTransportConnStats_h1(t *testing.T) { testTransportConnStats(t, h1Mode) }
+func TestTransportConnStats_h2(t *testing.T) { testTransportConnStats(t, h2Mode) }
+
+// Connections show up in Transport.ConnStats and are closed after
+// IdleConnTimeout, with either protocol.
+func testTransportConnStats(t *testing.T, h2 bool) {
+	defer afterTest(t)
+	const timeout = 50 * time.Millisecond
+	inHandler := make(chan bool)
+	unblock := make(chan bool)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		inHandler <- true
+		<-unblock
+	}), func(tr *Transport) {
+		tr.IdleConnTimeout = timeout
+	})
+	defer cst.close()
+
+	errc := make(chan error, 1)
+	go func() {
+		res, err := cst.c.Get(cst.ts.URL)
+		if err == nil {
+			res.Body.Close()
+		}
+		errc <- err
+	}()
+	<-inHandler
+
+	scheme := "http"
+	if h2 {
+		scheme = "https"
+	}
+	addr := cst.ts.Listener.Addr().String()
+	waitConnStats(t, cst.tr, []HostConnStats{{Scheme: scheme, Addr: addr, Active: 1}})
+	close(unblock)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	waitConnStats(t, cst.tr, []HostConnStats{{Scheme: scheme, Addr: addr, Idle: 1}})
+
+	// Nothing uses the connection any more, so IdleConnTimeout
+	// closes it.
+	waitConnStats(t, cst.tr, nil)
+}
+
 // Don't allow a Body.Read after Body.Close. Issue 13648.
 func TestResponseBodyReadAfterClose_h1(t *testing.T) { testResponseBodyReadAfterClose(t, h1Mode) }
 func TestResponseBodyReadAfterClose_h2(t *testing.T) { testResponseBodyReadAfterClose(t, h2Mode) }
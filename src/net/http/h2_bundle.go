@@ -28,6 +28,7 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"net"
 	"net/textproto"
 	"net/url"
@@ -131,7 +132,7 @@ func (c *http2dialCall) dial(addr string) {
 // This code decides which ones live or die.
 // The return value used is whether c was used.
 // c is never closed.
-func (p *http2clientConnPool) addConnIfNeeded(key string, t *http2Transport, c *tls.Conn) (used bool, err error) {
+func (p *http2clientConnPool) addConnIfNeeded(key string, t *http2Transport, c net.Conn) (used bool, err error) {
 	p.mu.Lock()
 	for _, cc := range p.conns[key] {
 		if cc.CanTakeNewRequest() {
@@ -166,7 +167,7 @@ type http2addConnCall struct {
 	err  error
 }
 
-func (c *http2addConnCall) run(t *http2Transport, key string, tc *tls.Conn) {
+func (c *http2addConnCall) run(t *http2Transport, key string, tc net.Conn) {
 	cc, err := t.NewClientConn(tc)
 
 	p := c.p
@@ -206,7 +207,18 @@ func (p *http2clientConnPool) addConnLocked(key string, cc *http2ClientConn) {
 
 func (p *http2clientConnPool) MarkDead(cc *http2ClientConn) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
+	keys := p.keys[cc]
+	p.markDeadLocked(cc)
+	p.mu.Unlock()
+	if p.t != nil && p.t.t1 != nil {
+		for _, key := range keys {
+			p.t.t1.h2ConnClosed(p.t, key)
+		}
+	}
+}
+
+// p.mu must be held
+func (p *http2clientConnPool) markDeadLocked(cc *http2ClientConn) {
 	for _, key := range p.keys[cc] {
 		vv, ok := p.conns[key]
 		if !ok {
@@ -222,6 +234,39 @@ func (p *http2clientConnPool) MarkDead(cc *http2ClientConn) {
 	delete(p.keys, cc)
 }
 
+// countConns returns the number of connections in the pool for addr,
+// or for all addresses if addr is empty, and how many of those have
+// no open streams.
+func (p *http2clientConnPool) countConns(addr string) (conns, idle int) {
+	p.foreachConn(func(key string, cc *http2ClientConn, isIdle bool) {
+		if addr != "" && key != addr {
+			return
+		}
+		conns++
+		if isIdle {
+			idle++
+		}
+	})
+	return
+}
+
+// foreachConn calls fn for each live connection in the pool, with the
+// address it's registered under and whether it has no open streams.
+func (p *http2clientConnPool) foreachConn(fn func(addr string, cc *http2ClientConn, idle bool)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr, vv := range p.conns {
+		for _, cc := range vv {
+			cc.mu.Lock()
+			closed, idle := cc.closed, len(cc.streams) == 0
+			cc.mu.Unlock()
+			if !closed {
+				fn(addr, cc, idle)
+			}
+		}
+	}
+}
+
 func (p *http2clientConnPool) closeIdleConnections() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -267,7 +312,7 @@ func http2configureTransport(t1 *Transport) (*http2Transport, error) {
 		t1.TLSClientConfig.NextProtos = append(t1.TLSClientConfig.NextProtos, "http/1.1")
 	}
 	upgradeFn := func(authority string, c *tls.Conn) RoundTripper {
-		addr := http2authorityAddr(authority)
+		addr := http2authorityAddr("https", authority)
 		if used, err := connPool.addConnIfNeeded(addr, t2, c); err != nil {
 			go c.Close()
 			return http2erringRoundTripper{err}
@@ -2484,6 +2529,11 @@ var (
 	http2errStreamClosed       = errors.New("http2: stream closed")
 )
 
+var (
+	http2ErrRecursivePush    = errors.New("http2: recursive push not allowed")
+	http2ErrPushLimitReached = errors.New("http2: push would exceed peer's SETTINGS_MAX_CONCURRENT_STREAMS")
+)
+
 var http2responseWriterStatePool = sync.Pool{
 	New: func() interface{} {
 		rws := &http2responseWriterState{}
@@ -2617,6 +2667,16 @@ type http2ServeConnOpts struct {
 	// requests. If nil, BaseConfig.Handler is used. If BaseConfig
 	// or BaseConfig.Handler is nil, http.DefaultServeMux is used.
 	Handler Handler
+
+	// UpgradeRequest is an initial request received on a connection
+	// undergoing an h2c upgrade. The request body must have been
+	// completely read from the connection before calling ServeConn,
+	// and the 101 Switching Protocols response written.
+	UpgradeRequest *Request
+
+	// Settings is the decoded contents of the HTTP2-Settings header
+	// in an h2c upgrade request.
+	Settings []byte
 }
 
 func (o *http2ServeConnOpts) baseConfig() *Server {
@@ -2649,7 +2709,9 @@ func (o *http2ServeConnOpts) handler() Handler {
 // the Request.TLS field in Handlers.
 //
 // ServeConn does not support h2c by itself. Any h2c support must be
-// implemented in terms of providing a suitably-behaving net.Conn.
+// implemented in terms of providing a suitably-behaving net.Conn,
+// and, for connections upgraded from HTTP/1.1, the UpgradeRequest
+// and Settings options.
 //
 // The opts parameter is optional. If nil, default values are used.
 func (s *http2Server) ServeConn(c net.Conn, opts *http2ServeConnOpts) {
@@ -2665,6 +2727,7 @@ func (s *http2Server) ServeConn(c net.Conn, opts *http2ServeConnOpts) {
 		wantWriteFrameCh: make(chan http2frameWriteMsg, 8),
 		wroteFrameCh:     make(chan http2frameWriteResult, 1),
 		bodyReadCh:       make(chan http2bodyReadMsg),
+		startPushCh:      make(chan *http2startPushRequest),
 		doneServing:      make(chan struct{}),
 		advMaxStreams:    s.maxConcurrentStreams(),
 		writeSched: http2writeScheduler{
@@ -2674,6 +2737,7 @@ func (s *http2Server) ServeConn(c net.Conn, opts *http2ServeConnOpts) {
 		headerTableSize:   http2initialHeaderTableSize,
 		serveG:            http2newGoroutineLock(),
 		pushEnabled:       true,
+		clientMaxStreams:  math.MaxUint32,
 	}
 	sc.flow.add(http2initialWindowSize)
 	sc.inflow.add(http2initialWindowSize)
@@ -2708,7 +2772,7 @@ func (s *http2Server) ServeConn(c net.Conn, opts *http2ServeConnOpts) {
 	if hook := http2testHookGetServerConn; hook != nil {
 		hook(sc)
 	}
-	sc.serve()
+	sc.serve(opts)
 }
 
 // isBadCipher reports whether the cipher is blacklisted by the HTTP/2 spec.
@@ -2749,15 +2813,16 @@ type http2serverConn struct {
 	handler          Handler
 	framer           *http2Framer
 	hpackDecoder     *hpack.Decoder
-	doneServing      chan struct{}              // closed when serverConn.serve ends
-	readFrameCh      chan http2readFrameResult  // written by serverConn.readFrames
-	wantWriteFrameCh chan http2frameWriteMsg    // from handlers -> serve
-	wroteFrameCh     chan http2frameWriteResult // from writeFrameAsync -> serve, tickles more frame writes
-	bodyReadCh       chan http2bodyReadMsg      // from handlers -> serve
-	testHookCh       chan func(int)             // code to run on the serve loop
-	flow             http2flow                  // conn-wide (not stream-specific) outbound flow control
-	inflow           http2flow                  // conn-wide inbound flow control
-	tlsState         *tls.ConnectionState       // shared by all handlers, like net/http
+	doneServing      chan struct{}               // closed when serverConn.serve ends
+	readFrameCh      chan http2readFrameResult   // written by serverConn.readFrames
+	wantWriteFrameCh chan http2frameWriteMsg     // from handlers -> serve
+	wroteFrameCh     chan http2frameWriteResult  // from writeFrameAsync -> serve, tickles more frame writes
+	bodyReadCh       chan http2bodyReadMsg       // from handlers -> serve
+	startPushCh      chan *http2startPushRequest // from handlers -> serve
+	testHookCh       chan func(int)              // code to run on the serve loop
+	flow             http2flow                   // conn-wide (not stream-specific) outbound flow control
+	inflow           http2flow                   // conn-wide inbound flow control
+	tlsState         *tls.ConnectionState        // shared by all handlers, like net/http
 	remoteAddrStr    string
 
 	// Everything following is owned by the serve loop; use serveG.check():
@@ -2769,7 +2834,9 @@ type http2serverConn struct {
 	clientMaxStreams      uint32 // SETTINGS_MAX_CONCURRENT_STREAMS from client (our PUSH_PROMISE limit)
 	advMaxStreams         uint32 // our SETTINGS_MAX_CONCURRENT_STREAMS advertised the client
 	curOpenStreams        uint32 // client's number of open streams
-	maxStreamID           uint32 // max ever seen
+	curPushedStreams      uint32 // number of open streams we pushed
+	maxStreamID           uint32 // max ever seen from the client
+	maxPushPromiseID      uint32 // ID of the last push promise, or zero
 	streams               map[uint32]*http2stream
 	initialWindowSize     int32
 	headerTableSize       uint32
@@ -2857,6 +2924,12 @@ type http2stream struct {
 	reqTrailer Header // handler's Request.Trailer
 }
 
+// isPushed reports whether the stream was initiated by the server
+// with a PUSH_PROMISE, rather than by the client.
+func (st *http2stream) isPushed() bool {
+	return st.id%2 == 0
+}
+
 func (sc *http2serverConn) Framer() *http2Framer { return sc.framer }
 
 func (sc *http2serverConn) CloseConn() error { return sc.conn.Close() }
@@ -2874,8 +2947,14 @@ func (sc *http2serverConn) state(streamID uint32) (http2streamState, *http2strea
 		return st.state, st
 	}
 
-	if streamID <= sc.maxStreamID {
-		return http2stateClosed, nil
+	if streamID%2 == 1 {
+		if streamID <= sc.maxStreamID {
+			return http2stateClosed, nil
+		}
+	} else {
+		if streamID <= sc.maxPushPromiseID {
+			return http2stateClosed, nil
+		}
 	}
 	return http2stateIdle, nil
 }
@@ -3125,7 +3204,7 @@ func (sc *http2serverConn) notePanic() {
 	}
 }
 
-func (sc *http2serverConn) serve() {
+func (sc *http2serverConn) serve(opts *http2ServeConnOpts) {
 	sc.serveG.check()
 	defer sc.notePanic()
 	defer sc.conn.Close()
@@ -3151,9 +3230,24 @@ func (sc *http2serverConn) serve() {
 		return
 	}
 
+	if opts != nil && opts.Settings != nil {
+		fr := &http2SettingsFrame{
+			http2FrameHeader: http2FrameHeader{valid: true},
+			p:                opts.Settings,
+		}
+		if err := fr.ForeachSetting(sc.processSetting); err != nil {
+			sc.rejectConn(http2ErrCodeProtocol, "invalid settings")
+			return
+		}
+	}
+
 	sc.setConnState(StateActive)
 	sc.setConnState(StateIdle)
 
+	if opts != nil && opts.UpgradeRequest != nil {
+		sc.upgradeRequest(opts.UpgradeRequest)
+	}
+
 	go sc.readFrames()
 
 	settingsTimer := time.NewTimer(http2firstSettingsTimeout)
@@ -3176,6 +3270,8 @@ func (sc *http2serverConn) serve() {
 			}
 		case m := <-sc.bodyReadCh:
 			sc.noteBodyRead(m.st, m.n)
+		case msg := <-sc.startPushCh:
+			sc.startPush(msg)
 		case <-settingsTimer.C:
 			sc.logf("timeout waiting for SETTINGS frames from %v", sc.conn.RemoteAddr())
 			return
@@ -3318,6 +3414,18 @@ func (sc *http2serverConn) startFrameWrite(wm http2frameWriteMsg) {
 		}
 	}
 
+	if wpp, ok := wm.write.(*http2writePushPromise); ok {
+		var err error
+		wpp.promisedID, err = wpp.allocatePromisedID()
+		if err != nil {
+			if ch := wm.done; ch != nil {
+				ch <- err
+			}
+			sc.scheduleFrameWrite()
+			return
+		}
+	}
+
 	sc.writingFrame = true
 	sc.needsFrameFlush = true
 	go sc.writeFrameAsync(wm)
@@ -3590,8 +3698,12 @@ func (sc *http2serverConn) closeStream(st *http2stream, err error) {
 		panic(fmt.Sprintf("invariant; can't close stream in state %v", st.state))
 	}
 	st.state = http2stateClosed
-	sc.curOpenStreams--
-	if sc.curOpenStreams == 0 {
+	if st.isPushed() {
+		sc.curPushedStreams--
+	} else {
+		sc.curOpenStreams--
+	}
+	if sc.curOpenStreams == 0 && sc.curPushedStreams == 0 {
 		sc.setConnState(StateIdle)
 	}
 	delete(sc.streams, st.id)
@@ -3756,29 +3868,14 @@ func (sc *http2serverConn) processHeaders(f *http2HeadersFrame) error {
 	if id > sc.maxStreamID {
 		sc.maxStreamID = id
 	}
-	st = &http2stream{
-		sc:    sc,
-		id:    id,
-		state: http2stateOpen,
-	}
+	state := http2stateOpen
 	if f.StreamEnded() {
-		st.state = http2stateHalfClosedRemote
+		state = http2stateHalfClosedRemote
 	}
-	st.cw.Init()
-
-	st.flow.conn = &sc.flow
-	st.flow.add(sc.initialWindowSize)
-	st.inflow.conn = &sc.inflow
-	st.inflow.add(http2initialWindowSize)
-
-	sc.streams[id] = st
+	st = sc.newStream(id, state)
 	if f.HasPriority() {
 		http2adjustStreamPriority(sc.streams, st.id, f.Priority)
 	}
-	sc.curOpenStreams++
-	if sc.curOpenStreams == 1 {
-		sc.setConnState(StateActive)
-	}
 	sc.req = http2requestParam{
 		stream: st,
 		header: make(Header),
@@ -4024,18 +4121,70 @@ func (sc *http2serverConn) newWriterAndRequest() (*http2responseWriter, *Request
 		}
 	}
 
+	rw := sc.newResponseWriter(rp.stream, req, body)
+	return rw, req, nil
+}
+
+func (sc *http2serverConn) newResponseWriter(st *http2stream, req *Request, body *http2requestBody) *http2responseWriter {
 	rws := http2responseWriterStatePool.Get().(*http2responseWriterState)
 	bwSave := rws.bw
 	*rws = http2responseWriterState{}
 	rws.conn = sc
 	rws.bw = bwSave
 	rws.bw.Reset(http2chunkWriter{rws})
-	rws.stream = rp.stream
+	rws.stream = st
 	rws.req = req
 	rws.body = body
+	return &http2responseWriter{rws: rws}
+}
 
-	rw := &http2responseWriter{rws: rws}
-	return rw, req, nil
+// newStream creates a stream in the given state, registers it with
+// the connection and counts it as open. Odd IDs are client-initiated
+// streams; even IDs are streams we promised with PUSH_PROMISE.
+func (sc *http2serverConn) newStream(id uint32, state http2streamState) *http2stream {
+	sc.serveG.check()
+	st := &http2stream{
+		sc:    sc,
+		id:    id,
+		state: state,
+	}
+	st.cw.Init()
+
+	st.flow.conn = &sc.flow
+	st.flow.add(sc.initialWindowSize)
+	st.inflow.conn = &sc.inflow
+	st.inflow.add(http2initialWindowSize)
+
+	sc.streams[id] = st
+	if st.isPushed() {
+		sc.curPushedStreams++
+	} else {
+		sc.curOpenStreams++
+	}
+	if sc.curOpenStreams+sc.curPushedStreams == 1 {
+		sc.setConnState(StateActive)
+	}
+	return st
+}
+
+// upgradeRequest runs the handler for the HTTP/1.1 request that
+// started an h2c upgrade. Its response is sent on stream 1, which
+// is half-closed (remote) from the start, as the request was
+// already read in full.
+func (sc *http2serverConn) upgradeRequest(req *Request) {
+	sc.serveG.check()
+	id := uint32(1)
+	sc.maxStreamID = id
+	st := sc.newStream(id, http2stateHalfClosedRemote)
+
+	if sc.hs.ReadTimeout != 0 {
+		sc.conn.SetReadDeadline(time.Time{})
+	}
+
+	body := &http2requestBody{conn: sc, stream: st}
+	req.Body = body
+	rw := sc.newResponseWriter(st, req, body)
+	go sc.runHandler(rw, req, sc.handler.ServeHTTP)
 }
 
 // Run on its own goroutine.
@@ -4225,6 +4374,7 @@ type http2responseWriter struct {
 var (
 	_ CloseNotifier     = (*http2responseWriter)(nil)
 	_ Flusher           = (*http2responseWriter)(nil)
+	_ Pusher            = (*http2responseWriter)(nil)
 	_ http2stringWriter = (*http2responseWriter)(nil)
 )
 
@@ -4534,6 +4684,198 @@ func (w *http2responseWriter) handlerDone() {
 	http2responseWriterStatePool.Put(rws)
 }
 
+// Push implements http.Pusher.
+func (w *http2responseWriter) Push(target string, opts *PushOptions) error {
+	st := w.rws.stream
+	sc := st.sc
+	sc.serveG.checkNotOn()
+
+	if st.isPushed() {
+		return http2ErrRecursivePush
+	}
+
+	if opts == nil {
+		opts = new(PushOptions)
+	}
+
+	if opts.Method == "" {
+		opts.Method = "GET"
+	}
+	if opts.Header == nil {
+		opts.Header = Header{}
+	}
+	wantScheme := "http"
+	if w.rws.req.TLS != nil {
+		wantScheme = "https"
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" {
+		if !strings.HasPrefix(target, "/") {
+			return fmt.Errorf("target must be an absolute URL or an absolute path: %q", target)
+		}
+		u.Scheme = wantScheme
+		u.Host = w.rws.req.Host
+	} else {
+		if u.Scheme != wantScheme {
+			return fmt.Errorf("cannot push URL with scheme %q from request with scheme %q", u.Scheme, wantScheme)
+		}
+		if u.Host == "" {
+			return errors.New("URL must have a host")
+		}
+	}
+	for k, vv := range opts.Header {
+		if strings.HasPrefix(k, ":") {
+			return fmt.Errorf("promised request headers cannot include pseudo header %q", k)
+		}
+
+		switch strings.ToLower(k) {
+		case "content-length", "content-encoding", "trailer", "te", "expect", "host",
+			"connection", "proxy-connection", "keep-alive", "transfer-encoding", "upgrade":
+			return fmt.Errorf("promised request headers cannot include %q", k)
+		}
+		if !http2validHeaderFieldName(http2lowerHeader(k)) {
+			return fmt.Errorf("invalid promised request header name %q", k)
+		}
+		for _, v := range vv {
+			if !http2validHeaderFieldValue(v) {
+				return fmt.Errorf("invalid value for promised request header %q", k)
+			}
+		}
+	}
+
+	if opts.Method != "GET" && opts.Method != "HEAD" {
+		return fmt.Errorf("method %q must be GET or HEAD", opts.Method)
+	}
+
+	msg := &http2startPushRequest{
+		parent: st,
+		method: opts.Method,
+		url:    u,
+		header: http2cloneHeader(opts.Header),
+		done:   http2errChanPool.Get().(chan error),
+	}
+
+	select {
+	case <-sc.doneServing:
+		return http2errClientDisconnected
+	case <-st.cw:
+		return http2errStreamClosed
+	case sc.startPushCh <- msg:
+	}
+
+	select {
+	case <-sc.doneServing:
+		return http2errClientDisconnected
+	case <-st.cw:
+		return http2errStreamClosed
+	case err := <-msg.done:
+		http2errChanPool.Put(msg.done)
+		return err
+	}
+}
+
+// startPushRequest is sent by a handler's Push call to the serve
+// goroutine, which writes the PUSH_PROMISE and starts the handler for
+// the promised request.
+type http2startPushRequest struct {
+	parent *http2stream
+	method string
+	url    *url.URL
+	header Header
+	done   chan error
+}
+
+func (sc *http2serverConn) startPush(msg *http2startPushRequest) {
+	sc.serveG.check()
+
+	if msg.parent.state != http2stateOpen && msg.parent.state != http2stateHalfClosedRemote {
+
+		msg.done <- http2errStreamClosed
+		return
+	}
+
+	if !sc.pushEnabled {
+		msg.done <- ErrNotSupported
+		return
+	}
+
+	allocatePromisedID := func() (uint32, error) {
+		sc.serveG.check()
+
+		if !sc.pushEnabled {
+			return 0, ErrNotSupported
+		}
+
+		if sc.curPushedStreams+1 > sc.clientMaxStreams {
+			return 0, http2ErrPushLimitReached
+		}
+
+		if sc.maxPushPromiseID+2 >= 1<<31 {
+			sc.goAway(http2ErrCodeNo)
+			return 0, http2ErrPushLimitReached
+		}
+		sc.maxPushPromiseID += 2
+		promisedID := sc.maxPushPromiseID
+
+		promised := sc.newStream(promisedID, http2stateHalfClosedRemote)
+		promised.parent = msg.parent
+		rw, req, err := sc.newPushedWriterAndRequest(promised, msg)
+		if err != nil {
+
+			panic(fmt.Sprintf("newPushedWriterAndRequest(%+v): %v", msg.url, err))
+		}
+
+		go sc.runHandler(rw, req, sc.handler.ServeHTTP)
+		return promisedID, nil
+	}
+
+	sc.writeFrame(http2frameWriteMsg{
+		write: &http2writePushPromise{
+			streamID:           msg.parent.id,
+			method:             msg.method,
+			url:                msg.url,
+			h:                  msg.header,
+			allocatePromisedID: allocatePromisedID,
+		},
+		stream: msg.parent,
+		done:   msg.done,
+	})
+}
+
+// newPushedWriterAndRequest builds the synthetic request for a
+// promised stream. Promised requests never have a body.
+func (sc *http2serverConn) newPushedWriterAndRequest(st *http2stream, msg *http2startPushRequest) (*http2responseWriter, *Request, error) {
+	sc.serveG.check()
+	requestURI := msg.url.RequestURI()
+	url_, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tlsState *tls.ConnectionState // nil if not scheme https
+	if msg.url.Scheme == "https" {
+		tlsState = sc.tlsState
+	}
+	body := &http2requestBody{conn: sc, stream: st}
+	req := &Request{
+		Method:     msg.method,
+		URL:        url_,
+		RemoteAddr: sc.remoteAddrStr,
+		Header:     http2cloneHeader(msg.header),
+		RequestURI: requestURI,
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+		ProtoMinor: 0,
+		TLS:        tlsState,
+		Host:       msg.url.Host,
+		Body:       body,
+	}
+	return sc.newResponseWriter(st, req, body), req, nil
+}
+
 // foreachHeaderElement splits v according to the "#rule" construction
 // in RFC 2616 section 2.1 and calls fn for each non-empty element.
 func http2foreachHeaderElement(v string, fn func(string)) {
@@ -4610,6 +4952,11 @@ type http2Transport struct {
 	// to mean no limit.
 	MaxHeaderListSize uint32
 
+	// AllowHTTP, if true, permits HTTP/2 requests using the insecure,
+	// plain-text "http" scheme. Note that this does not enable h2c
+	// support by itself; connections must be supplied by the caller.
+	AllowHTTP bool
+
 	// t1, if non-nil, is the standard library Transport using
 	// this transport. Its settings are used (but not its
 	// RoundTrip method, etc).
@@ -4688,6 +5035,12 @@ type http2ClientConn struct {
 
 	wmu  sync.Mutex // held while writing; acquire AFTER mu if holding both
 	werr error      // first write error that has occurred
+
+	// idleTimeout is the Transport's IdleConnTimeout. If non-zero,
+	// idleTimer closes the connection once it has had no open
+	// streams for that long.
+	idleTimeout time.Duration
+	idleTimer   *time.Timer
 }
 
 // clientStream is the state for a single HTTP/2 stream. One of these
@@ -4788,21 +5141,26 @@ func (t *http2Transport) RoundTrip(req *Request) (*Response, error) {
 }
 
 // authorityAddr returns a given authority (a host/IP, or host:port / ip:port)
-// and returns a host:port. The port 443 is added if needed.
-func http2authorityAddr(authority string) (addr string) {
+// and returns a host:port. The port 443 is added if needed, or port 80
+// for the "http" scheme.
+func http2authorityAddr(scheme string, authority string) (addr string) {
 	if _, _, err := net.SplitHostPort(authority); err == nil {
 		return authority
 	}
-	return net.JoinHostPort(authority, "443")
+	port := "443"
+	if scheme == "http" {
+		port = "80"
+	}
+	return net.JoinHostPort(authority, port)
 }
 
 // RoundTripOpt is like RoundTrip, but takes options.
 func (t *http2Transport) RoundTripOpt(req *Request, opt http2RoundTripOpt) (*Response, error) {
-	if req.URL.Scheme != "https" {
+	if !(req.URL.Scheme == "https" || (req.URL.Scheme == "http" && t.AllowHTTP)) {
 		return nil, errors.New("http2: unsupported scheme")
 	}
 
-	addr := http2authorityAddr(req.URL.Host)
+	addr := http2authorityAddr(req.URL.Scheme, req.URL.Host)
 	for {
 		cc, err := t.connPool().GetClientConn(req, addr)
 		if err != nil {
@@ -4898,6 +5256,13 @@ func (t *http2Transport) disableKeepAlives() bool {
 	return t.t1 != nil && t.t1.DisableKeepAlives
 }
 
+func (t *http2Transport) idleConnTimeout() time.Duration {
+	if t.t1 != nil {
+		return t.t1.IdleConnTimeout
+	}
+	return 0
+}
+
 func (t *http2Transport) NewClientConn(c net.Conn) (*http2ClientConn, error) {
 	if http2VerboseLogs {
 		t.vlogf("http2: Transport creating client conn to %v", c.RemoteAddr())
@@ -4917,6 +5282,10 @@ func (t *http2Transport) NewClientConn(c net.Conn) (*http2ClientConn, error) {
 		maxConcurrentStreams: 1000,
 		streams:              make(map[uint32]*http2clientStream),
 	}
+	if d := t.idleConnTimeout(); d != 0 {
+		cc.idleTimeout = d
+		cc.idleTimer = time.AfterFunc(d, cc.onIdleTimeout)
+	}
 	cc.cond = sync.NewCond(&cc.mu)
 	cc.flow.add(int32(http2initialWindowSize))
 
@@ -4994,6 +5363,16 @@ func (cc *http2ClientConn) canTakeNewRequestLocked() bool {
 		cc.nextStreamID < 2147483647
 }
 
+// onIdleTimeout is called from a time.AfterFunc goroutine. It will
+// only be called when we're idle, but because we're coming from a new
+// goroutine, there could be a new request coming in at the same time,
+// so this simply calls the synchronized closeIfIdle to shut down this
+// connection. The timer could just call closeIfIdle, but this is more
+// clear.
+func (cc *http2ClientConn) onIdleTimeout() {
+	cc.closeIfIdle()
+}
+
 func (cc *http2ClientConn) closeIfIdle() {
 	cc.mu.Lock()
 	if len(cc.streams) > 0 {
@@ -5394,7 +5773,7 @@ func (cc *http2ClientConn) encodeHeaders(req *Request, addGzipHeader bool, trail
 	cc.writeHeader(":method", req.Method)
 	if req.Method != "CONNECT" {
 		cc.writeHeader(":path", req.URL.RequestURI())
-		cc.writeHeader(":scheme", "https")
+		cc.writeHeader(":scheme", req.URL.Scheme)
 	}
 	if trailers != "" {
 		cc.writeHeader("trailer", trailers)
@@ -5498,6 +5877,9 @@ func (cc *http2ClientConn) newStream() *http2clientStream {
 	cs.inflow.setConnFlow(&cc.inflow)
 	cc.nextStreamID += 2
 	cc.streams[cs.ID] = cs
+	if cc.idleTimer != nil {
+		cc.idleTimer.Stop()
+	}
 	return cs
 }
 
@@ -5507,11 +5889,19 @@ func (cc *http2ClientConn) forgetStreamID(id uint32) {
 
 func (cc *http2ClientConn) streamByID(id uint32, andRemove bool) *http2clientStream {
 	cc.mu.Lock()
-	defer cc.mu.Unlock()
 	cs := cc.streams[id]
+	idle := false
 	if andRemove && cs != nil && !cc.closed {
 		delete(cc.streams, id)
 		close(cs.done)
+		idle = len(cc.streams) == 0
+		if idle && cc.idleTimer != nil {
+			cc.idleTimer.Reset(cc.idleTimeout)
+		}
+	}
+	cc.mu.Unlock()
+	if idle && cc.t.t1 != nil {
+		cc.t.t1.h2ConnIdle(cc)
 	}
 	return cs
 }
@@ -6354,6 +6744,60 @@ func (w *http2writeResHeaders) writeFrame(ctx http2writeContext) error {
 	return nil
 }
 
+// writePushPromise is a request to write a PUSH_PROMISE and 0+ CONTINUATION frames.
+type http2writePushPromise struct {
+	streamID uint32   // pusher stream
+	method   string   // for :method
+	url      *url.URL // for :scheme, :authority, :path
+	h        Header
+
+	// Creates an ID for a pushed stream. This runs on serveG just before
+	// the frame is written. The returned ID is copied to promisedID.
+	allocatePromisedID func() (uint32, error)
+	promisedID         uint32
+}
+
+func (w *http2writePushPromise) writeFrame(ctx http2writeContext) error {
+	enc, buf := ctx.HeaderEncoder()
+	buf.Reset()
+
+	http2encKV(enc, ":method", w.method)
+	http2encKV(enc, ":scheme", w.url.Scheme)
+	http2encKV(enc, ":authority", w.url.Host)
+	http2encKV(enc, ":path", w.url.RequestURI())
+	http2encodeHeaders(enc, w.h, nil)
+
+	headerBlock := buf.Bytes()
+
+	const maxFrameSize = 16384
+
+	first := true
+	for len(headerBlock) > 0 {
+		frag := headerBlock
+		if len(frag) > maxFrameSize {
+			frag = frag[:maxFrameSize]
+		}
+		headerBlock = headerBlock[len(frag):]
+		endHeaders := len(headerBlock) == 0
+		var err error
+		if first {
+			first = false
+			err = ctx.Framer().WritePushPromise(http2PushPromiseParam{
+				StreamID:      w.streamID,
+				PromiseID:     w.promisedID,
+				BlockFragment: frag,
+				EndHeaders:    endHeaders,
+			})
+		} else {
+			err = ctx.Framer().WriteContinuation(w.streamID, endHeaders, frag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type http2write100ContinueHeadersFrame struct {
 	streamID uint32
 }
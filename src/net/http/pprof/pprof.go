@@ -34,6 +34,11 @@
 //
 //	go tool pprof http://localhost:6060/debug/pprof/block
 //
+// Or to look at the holders of contended mutexes, after calling
+// runtime.SetMutexProfileFraction in your program:
+//
+//	go tool pprof http://localhost:6060/debug/pprof/mutex
+//
 // Or to collect a 5-second execution trace:
 //
 //	wget http://localhost:6060/debug/pprof/trace?seconds=5
@@ -55,6 +55,12 @@ type Response struct {
 	//
 	// The Body is automatically dechunked if the server replied
 	// with a "chunked" Transfer-Encoding.
+	//
+	// On a successful "101 Switching Protocols" response from the
+	// Transport, Body also implements io.Writer and provides direct
+	// access to the underlying connection. This is used by protocols
+	// such as WebSockets. The caller owns the connection and must
+	// close Body when done.
 	Body io.ReadCloser
 
 	// ContentLength records the length of the associated content.  The
@@ -203,6 +209,14 @@ func (r *Response) ProtoAtLeast(major, minor int) bool {
 		r.ProtoMajor == major && r.ProtoMinor >= minor
 }
 
+// isProtocolSwitch reports whether r is a response to a successful
+// protocol upgrade.
+func (r *Response) isProtocolSwitch() bool {
+	return r.StatusCode == StatusSwitchingProtocols &&
+		r.Header.Get("Upgrade") != "" &&
+		hasToken(r.Header.Get("Connection"), "upgrade")
+}
+
 // Write writes r to w in the HTTP/1.n server response format,
 // including the status line, headers, body, and optional trailer.
 //
@@ -12,6 +12,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"internal/golang.org/x/net/http2/hpack"
 	"internal/testenv"
 	"io"
 	"io/ioutil"
@@ -3936,6 +3937,318 @@ func TestServerValidatesHeaders(t *testing.T) {
 	}
 }
 
+func newH2CServer(h Handler) *httptest.Server {
+	ts := httptest.NewUnstartedServer(h)
+	ts.Config.UnencryptedHTTP2 = true
+	ts.Start()
+	return ts
+}
+
+func TestServerH2CPriorKnowledge(t *testing.T) {
+	defer afterTest(t)
+	ts := newH2CServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.ProtoMajor != 2 {
+			t.Errorf("handler got Proto %q; want HTTP/2.0", r.Proto)
+		}
+		if r.TLS != nil {
+			t.Error("handler got non-nil Request.TLS")
+		}
+		w.Header().Set("X-Addr", r.RemoteAddr)
+		io.WriteString(w, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	tr := &Transport{UnencryptedHTTP2: true}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+
+	var firstAddr string
+	for i := 0; i < 3; i++ {
+		res, err := c.Get(ts.URL + "/path")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.ProtoMajor != 2 {
+			t.Errorf("response Proto = %q; want HTTP/2.0", res.Proto)
+		}
+		if err := wantBody(res, nil, "/path"); err != nil {
+			t.Fatal(err)
+		}
+		addr := res.Header.Get("X-Addr")
+		if i == 0 {
+			firstAddr = addr
+		} else if addr != firstAddr {
+			t.Errorf("request %d used connection from %q; want reuse of %q", i, addr, firstAddr)
+		}
+	}
+}
+
+func TestServerH2CStillServesHTTP1(t *testing.T) {
+	defer afterTest(t)
+	ts := newH2CServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	defer ts.Close()
+
+	// A short request must not leave the server waiting for
+	// the rest of an HTTP/2 connection preface.
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET / HTTP/1.0\r\n\r\n")
+	res, err := ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wantBody(res, nil, "HTTP/1.0"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerH2CUpgrade(t *testing.T) {
+	defer afterTest(t)
+	ts := newH2CServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.ProtoMajor != 2 {
+			t.Errorf("handler got Proto %q; want HTTP/2.0", r.Proto)
+		}
+		for _, k := range []string{"Upgrade", "Connection", "Http2-Settings"} {
+			if v, ok := r.Header[k]; ok {
+				t.Errorf("handler got header %s = %q", k, v)
+			}
+		}
+		io.WriteString(w, "upgraded")
+	}))
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	// HTTP2-Settings carries SETTINGS_MAX_CONCURRENT_STREAMS = 100.
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: foo\r\nConnection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: AAMAAABk\r\n\r\n")
+	br := bufio.NewReader(conn)
+	res, err := ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != StatusSwitchingProtocols || res.Header.Get("Upgrade") != "h2c" {
+		t.Fatalf("got response %v, %v; want 101 switching to h2c", res.Status, res.Header)
+	}
+
+	// Client connection preface: the magic string and an empty SETTINGS frame.
+	io.WriteString(conn, "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+	conn.Write([]byte{0, 0, 0, 0x4, 0, 0, 0, 0, 0})
+
+	const (
+		frameData    = 0x0
+		frameHeaders = 0x1
+		flagEnd      = 0x1
+	)
+	var (
+		sawHeaders bool
+		body       []byte
+	)
+	for {
+		var hdr [9]byte
+		if _, err := io.ReadFull(br, hdr[:]); err != nil {
+			t.Fatalf("reading frame header: %v", err)
+		}
+		length := int(hdr[0])<<16 | int(hdr[1])<<8 | int(hdr[2])
+		typ, flags := hdr[3], hdr[4]
+		streamID := uint32(hdr[5]&0x7f)<<24 | uint32(hdr[6])<<16 | uint32(hdr[7])<<8 | uint32(hdr[8])
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			t.Fatalf("reading frame payload: %v", err)
+		}
+		if streamID != 1 {
+			continue
+		}
+		switch typ {
+		case frameHeaders:
+			sawHeaders = true
+		case frameData:
+			body = append(body, payload...)
+		}
+		if flags&flagEnd != 0 && (typ == frameData || typ == frameHeaders) {
+			break
+		}
+	}
+	if !sawHeaders {
+		t.Error("no HEADERS frame on stream 1")
+	}
+	if string(body) != "upgraded" {
+		t.Errorf("stream 1 body = %q; want %q", body, "upgraded")
+	}
+}
+
+func TestServerH2CIgnoresUpgradeWithBody(t *testing.T) {
+	defer afterTest(t)
+	ts := newH2CServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		slurp, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s", r.Proto, slurp)
+	}))
+	defer ts.Close()
+
+	req, _ := NewRequest("POST", ts.URL, strings.NewReader("body"))
+	req.Header.Set("Connection", "Upgrade, HTTP2-Settings")
+	req.Header.Set("Upgrade", "h2c")
+	req.Header.Set("HTTP2-Settings", "")
+	res, err := DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wantBody(res, nil, "HTTP/1.1 body"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerH2CPush(t *testing.T) {
+	defer afterTest(t)
+	pushErrc := make(chan error, 1)
+	ts := newH2CServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		switch r.URL.Path {
+		case "/":
+			p, ok := w.(Pusher)
+			if !ok {
+				pushErrc <- errors.New("ResponseWriter does not implement Pusher")
+				return
+			}
+			for _, bad := range []struct {
+				target string
+				opts   *PushOptions
+			}{
+				{"relative", nil},
+				{"https://foo/style.css", nil},
+				{"/style.css", &PushOptions{Method: "POST"}},
+				{"/style.css", &PushOptions{Header: Header{"Content-Length": {"1"}}}},
+				{"/style.css", &PushOptions{Header: Header{":path": {"/"}}}},
+			} {
+				if err := p.Push(bad.target, bad.opts); err == nil {
+					pushErrc <- fmt.Errorf("Push(%q, %+v) succeeded; want error", bad.target, bad.opts)
+					return
+				}
+			}
+			pushErrc <- p.Push("/style.css", &PushOptions{Header: Header{"User-Agent": {"pusher"}}})
+			io.WriteString(w, "index")
+		case "/style.css":
+			if p, ok := w.(Pusher); ok {
+				if err := p.Push("/other", nil); err == nil {
+					t.Error("recursive Push succeeded; want error")
+				}
+			}
+			if ua := r.Header.Get("User-Agent"); ua != "pusher" {
+				t.Errorf("pushed request User-Agent = %q; want %q", ua, "pusher")
+			}
+			io.WriteString(w, "css")
+		default:
+			t.Errorf("unexpected request for %q", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	const (
+		frameData        = 0x0
+		frameHeaders     = 0x1
+		framePushPromise = 0x5
+		flagEndStream    = 0x1
+		flagEndHeaders   = 0x4
+	)
+	writeFrame := func(typ, flags byte, streamID uint32, payload []byte) {
+		n := len(payload)
+		hdr := []byte{byte(n >> 16), byte(n >> 8), byte(n), typ, flags,
+			byte(streamID >> 24), byte(streamID >> 16), byte(streamID >> 8), byte(streamID)}
+		conn.Write(append(hdr, payload...))
+	}
+
+	var reqBlock bytes.Buffer
+	enc := hpack.NewEncoder(&reqBlock)
+	for _, f := range [][2]string{
+		{":method", "GET"},
+		{":scheme", "http"},
+		{":authority", ts.Listener.Addr().String()},
+		{":path", "/"},
+	} {
+		enc.WriteField(hpack.HeaderField{Name: f[0], Value: f[1]})
+	}
+	io.WriteString(conn, "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+	writeFrame(0x4, 0, 0, nil) // empty SETTINGS; push stays enabled
+	writeFrame(frameHeaders, flagEndStream|flagEndHeaders, 1, reqBlock.Bytes())
+
+	br := bufio.NewReader(conn)
+	var (
+		promised  []string // :path of each PUSH_PROMISE on stream 1
+		promiseID uint32
+		bodies    = map[uint32]string{}
+		ended     = map[uint32]bool{}
+	)
+	dec := hpack.NewDecoder(4096, nil)
+	for !ended[1] || (promiseID != 0 && !ended[promiseID]) {
+		var hdr [9]byte
+		if _, err := io.ReadFull(br, hdr[:]); err != nil {
+			t.Fatalf("reading frame header: %v", err)
+		}
+		length := int(hdr[0])<<16 | int(hdr[1])<<8 | int(hdr[2])
+		typ, flags := hdr[3], hdr[4]
+		streamID := uint32(hdr[5]&0x7f)<<24 | uint32(hdr[6])<<16 | uint32(hdr[7])<<8 | uint32(hdr[8])
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			t.Fatalf("reading frame payload: %v", err)
+		}
+		switch typ {
+		case framePushPromise:
+			if streamID != 1 {
+				t.Fatalf("PUSH_PROMISE on stream %d; want 1", streamID)
+			}
+			promiseID = uint32(payload[0]&0x7f)<<24 | uint32(payload[1])<<16 | uint32(payload[2])<<8 | uint32(payload[3])
+			fields, err := dec.DecodeFull(payload[4:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range fields {
+				if f.Name == ":path" {
+					promised = append(promised, f.Value)
+				}
+			}
+		case frameHeaders:
+			if _, err := dec.DecodeFull(payload); err != nil {
+				t.Fatal(err)
+			}
+		case frameData:
+			bodies[streamID] += string(payload)
+		}
+		if flags&flagEndStream != 0 && (typ == frameData || typ == frameHeaders) {
+			ended[streamID] = true
+		}
+	}
+	if err := <-pushErrc; err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if want := []string{"/style.css"}; !reflect.DeepEqual(promised, want) {
+		t.Errorf("promised paths = %q; want %q", promised, want)
+	}
+	if promiseID != 2 {
+		t.Errorf("promised stream ID = %d; want 2", promiseID)
+	}
+	if got := bodies[1]; got != "index" {
+		t.Errorf("stream 1 body = %q; want %q", got, "index")
+	}
+	if got := bodies[promiseID]; got != "css" {
+		t.Errorf("pushed stream body = %q; want %q", got, "css")
+	}
+}
+
 func BenchmarkClientServer(b *testing.B) {
 	b.ReportAllocs()
 	b.StopTimer()
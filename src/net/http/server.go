@@ -10,6 +10,7 @@ import (
 	"bufio"
 	"bytes"
 	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -69,6 +70,8 @@ type ResponseWriter interface {
 	// WriteHeader (or Write) has no effect unless the modified
 	// headers were declared as trailers by setting the
 	// "Trailer" header before the call to WriteHeader (see example).
+	// Trailers not known until after the header has been written
+	// may instead be set using keys prefixed with TrailerPrefix.
 	// To suppress implicit response headers, set their value to nil.
 	Header() Header
 
@@ -116,6 +119,46 @@ type Hijacker interface {
 	Hijack() (net.Conn, *bufio.ReadWriter, error)
 }
 
+// The Pusher interface is implemented by ResponseWriters that support
+// HTTP/2 server push. For more background, see RFC 7540, section 8.2.
+type Pusher interface {
+	// Push initiates an HTTP/2 server push. This constructs a synthetic
+	// request using the given target and options, serializes that request
+	// into a PUSH_PROMISE frame, then dispatches that request using the
+	// server's request handler. If opts is nil, default options are used.
+	//
+	// The target must either be an absolute path (like "/path") or an
+	// absolute URL that contains a valid host and the same scheme as the
+	// parent request. If the target is a path, it will inherit the scheme
+	// and host of the parent request.
+	//
+	// The HTTP/2 spec disallows recursive pushes and cross-authority
+	// pushes. Push may or may not detect these invalid pushes; however,
+	// invalid pushes will be detected and canceled by conforming clients.
+	//
+	// Handlers that wish to push URL X should call Push before sending
+	// any data that may trigger a request for URL X. This avoids a race
+	// where the client issues requests for X before receiving the
+	// PUSH_PROMISE for X.
+	//
+	// Push returns ErrNotSupported if the client has disabled push or
+	// if push is not supported on the underlying connection.
+	Push(target string, opts *PushOptions) error
+}
+
+// PushOptions describes options for Pusher.Push.
+type PushOptions struct {
+	// Method specifies the HTTP method for the promised request.
+	// If set, it must be "GET" or "HEAD". Empty means "GET".
+	Method string
+
+	// Header specifies additional promised request headers. This cannot
+	// include HTTP/2 pseudo header fields like ":path" and ":scheme",
+	// which will be added automatically. Headers that only make sense
+	// for requests with a body, such as Content-Length, are rejected.
+	Header Header
+}
+
 // The CloseNotifier interface is implemented by ResponseWriters which
 // allow detecting when the underlying connection has gone away.
 //
@@ -289,13 +332,7 @@ func (cw *chunkWriter) close() {
 		bw := cw.res.conn.bufw // conn's bufio writer
 		// zero chunk to mark EOF
 		bw.WriteString("0\r\n")
-		if len(cw.res.trailers) > 0 {
-			trailers := make(Header)
-			for _, h := range cw.res.trailers {
-				if vv := cw.res.handlerHeader[h]; len(vv) > 0 {
-					trailers[h] = vv
-				}
-			}
+		if trailers := cw.res.finalTrailers(); trailers != nil {
 			trailers.Write(bw) // the writer handles noting errors
 		}
 		// final blank line after the trailers (whether
@@ -363,6 +400,23 @@ type atomicBool int32
 func (b *atomicBool) isSet() bool { return atomic.LoadInt32((*int32)(b)) != 0 }
 func (b *atomicBool) setTrue()    { atomic.StoreInt32((*int32)(b), 1) }
 
+// TrailerPrefix is a magic prefix for ResponseWriter.Header map keys
+// that, if present, signals that the map entry is actually for
+// the response trailers, and not the response headers. The prefix
+// is stripped after the ServeHTTP call finishes and the values are
+// sent in the trailers.
+//
+// This mechanism is intended only for trailers that are not known
+// prior to the headers being written. If the set of trailers is fixed
+// or known before the header is written, the normal Go trailers mechanism
+// of declaring them in the "Trailer" header is preferred.
+//
+// TrailerPrefix works the same way for HTTP/1.1 and HTTP/2 responses.
+// HTTP/1.1 trailers can only be sent on chunked responses, so setting
+// a TrailerPrefix key keeps the server from adding an automatic
+// Content-Length.
+const TrailerPrefix = "Trailer:"
+
 // declareTrailer is called for each Trailer header when the
 // response header is written. It notes that a header will need to be
 // written in the trailers at the end of the response.
@@ -376,6 +430,32 @@ func (w *response) declareTrailer(k string) {
 	w.trailers = append(w.trailers, k)
 }
 
+// finalTrailers is called after the Handler exits and returns a non-nil
+// value if the Handler set any trailers, either by declaring them in
+// the Trailer header or by using the TrailerPrefix.
+func (w *response) finalTrailers() Header {
+	var t Header
+	for k, vv := range w.handlerHeader {
+		if strings.HasPrefix(k, TrailerPrefix) {
+			if t == nil {
+				t = make(Header)
+			}
+			t[CanonicalHeaderKey(strings.TrimPrefix(k, TrailerPrefix))] = vv
+		}
+	}
+	for _, k := range w.trailers {
+		vv := w.handlerHeader[k]
+		if len(vv) == 0 {
+			continue
+		}
+		if t == nil {
+			t = make(Header)
+		}
+		t[k] = vv
+	}
+	return t
+}
+
 // requestTooLarge is called by maxBytesReader when too much input has
 // been read from the client.
 func (w *response) requestTooLarge() {
@@ -896,6 +976,24 @@ func (cw *chunkWriter) writeHeader(p []byte) {
 	var setHeader extraHeader
 
 	trailers := false
+	for k := range header {
+		if strings.HasPrefix(k, TrailerPrefix) {
+			// Undeclared trailers are sent after the body
+			// and never as part of the header.
+			delHeader(k)
+			trailers = true
+		}
+	}
+	if w.handlerDone.isSet() && !trailers {
+		// The handler may have set undeclared trailers after its
+		// final Write, when the header had already been copied.
+		for k := range w.handlerHeader {
+			if strings.HasPrefix(k, TrailerPrefix) {
+				trailers = true
+				break
+			}
+		}
+	}
 	for _, v := range cw.header["Trailer"] {
 		trailers = true
 		foreachHeaderElement(v, cw.res.declareTrailer)
@@ -1421,6 +1519,11 @@ func (c *conn) serve() {
 	c.bufr = newBufioReader(c.r)
 	c.bufw = newBufioWriterSize(checkConnErrorWriter{c}, 4<<10)
 
+	if c.server.UnencryptedHTTP2 && c.tlsState == nil && c.sawH2CPreface() {
+		c.serveH2C(nil)
+		return
+	}
+
 	for {
 		w, err := c.readRequest()
 		if c.r.remain != c.server.initialReadLimitSize() {
@@ -1464,6 +1567,13 @@ func (c *conn) serve() {
 			return
 		}
 
+		if c.server.UnencryptedHTTP2 && c.tlsState == nil {
+			if settings, ok := h2cUpgradeSettings(req); ok {
+				c.upgradeH2C(req, settings)
+				return
+			}
+		}
+
 		// HTTP cannot have multiple simultaneous active requests.[*]
 		// Until the server replies to this request, it can't read another,
 		// so we might as well run the handler in this goroutine.
@@ -1484,6 +1594,96 @@ func (c *conn) serve() {
 	}
 }
 
+// sawH2CPreface reports whether the client opened the connection
+// with the HTTP/2 connection preface. It only waits for as many
+// bytes as it takes to rule the preface out, so an HTTP/1 client
+// is never kept waiting for input it will not send.
+func (c *conn) sawH2CPreface() bool {
+	if d := c.server.ReadTimeout; d != 0 {
+		c.rwc.SetReadDeadline(time.Now().Add(d))
+	}
+	c.r.setReadLimit(c.server.initialReadLimitSize())
+	for n := 1; n <= len(http2ClientPreface); n++ {
+		buf, err := c.bufr.Peek(n)
+		if err != nil || buf[n-1] != http2ClientPreface[n-1] {
+			return false
+		}
+	}
+	return true
+}
+
+// h2cUpgradeSettings reports whether req asks to switch to HTTP/2
+// over cleartext and can be upgraded, and returns the decoded
+// contents of its HTTP2-Settings header.
+func h2cUpgradeSettings(req *Request) (settings []byte, ok bool) {
+	if !hasToken(req.Header.get("Upgrade"), "h2c") {
+		return nil, false
+	}
+	conn := req.Header.get("Connection")
+	if !hasToken(conn, "upgrade") || !hasToken(conn, "http2-settings") {
+		return nil, false
+	}
+	vv := req.Header["Http2-Settings"]
+	if len(vv) != 1 {
+		return nil, false
+	}
+	// The request body would have to be read in full before
+	// switching protocols. Rather than buffer it, keep serving
+	// such requests over HTTP/1.1, which RFC 7540 permits.
+	if req.ContentLength != 0 || req.Method == "CONNECT" {
+		return nil, false
+	}
+	settings, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(vv[0], "="))
+	if err != nil || len(settings)%6 != 0 {
+		return nil, false
+	}
+	return settings, true
+}
+
+// upgradeH2C switches the connection to HTTP/2 in response to req,
+// an HTTP/1.1 request carrying "Upgrade: h2c". The handler's
+// response to req is sent on HTTP/2 stream 1.
+func (c *conn) upgradeH2C(req *Request, settings []byte) {
+	req.Header.Del("Upgrade")
+	req.Header.Del("Connection")
+	req.Header.Del("Http2-Settings")
+	req.Proto = "HTTP/2.0"
+	req.ProtoMajor = 2
+	req.ProtoMinor = 0
+	req.Close = false
+
+	io.WriteString(c.bufw, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n")
+	if err := c.bufw.Flush(); err != nil {
+		return
+	}
+	c.serveH2C(&http2ServeConnOpts{
+		UpgradeRequest: req,
+		Settings:       settings,
+	})
+}
+
+// serveH2C serves HTTP/2 over the unencrypted connection c until
+// the client goes away. The opts, if non-nil, carry the state of
+// an HTTP/1.1 upgrade.
+func (c *conn) serveH2C(opts *http2ServeConnOpts) {
+	if opts == nil {
+		opts = new(http2ServeConnOpts)
+	}
+	opts.BaseConfig = c.server
+	opts.Handler = serverHandler{c.server}
+	c.r.setInfiniteReadLimit()
+	c.server.h2cServer().ServeConn(&bufferedConn{Conn: c.rwc, r: c.bufr}, opts)
+}
+
+// bufferedConn is a net.Conn whose reads are served by a
+// bufio.Reader that may already hold data read from the Conn.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
 func (w *response) sendExpectationFailed() {
 	// TODO(bradfitz): let ServeHTTP handlers handle
 	// requests with non-standard expectation[s]? Seems
@@ -1996,6 +2196,15 @@ type Server struct {
 	// If TLSNextProto is nil, HTTP/2 support is enabled automatically.
 	TLSNextProto map[string]func(*Server, *tls.Conn, Handler)
 
+	// UnencryptedHTTP2, if true, permits clients to speak HTTP/2
+	// over connections that do not use TLS ("h2c"). A client may
+	// either send the HTTP/2 connection preface immediately
+	// ("prior knowledge") or ask to switch an HTTP/1.1 request
+	// with "Upgrade: h2c", as described in RFC 7540, section 3.2.
+	// Upgrade requests with a body are served over HTTP/1.1.
+	// UnencryptedHTTP2 has no effect on TLS connections.
+	UnencryptedHTTP2 bool
+
 	// ConnState specifies an optional callback function that is
 	// called when a client connection changes state. See the
 	// ConnState type and associated constants for details.
@@ -2010,6 +2219,8 @@ type Server struct {
 	disableKeepAlives int32     // accessed atomically.
 	nextProtoOnce     sync.Once // guards initialization of TLSNextProto in Serve
 	nextProtoErr      error
+	h2cOnce           sync.Once // guards initialization of h2c
+	h2c               *http2Server
 }
 
 // A ConnState represents the state of a client connection to a server.
@@ -2278,6 +2489,13 @@ func (srv *Server) ListenAndServeTLS(certFile, keyFile string) error {
 	return srv.Serve(tlsListener)
 }
 
+// h2cServer returns the HTTP/2 server used for unencrypted
+// connections.
+func (srv *Server) h2cServer() *http2Server {
+	srv.h2cOnce.Do(func() { srv.h2c = new(http2Server) })
+	return srv.h2c
+}
+
 func (srv *Server) setupHTTP2() error {
 	srv.nextProtoOnce.Do(srv.onceSetNextProtoDefaults)
 	return srv.nextProtoErr
@@ -12,6 +12,7 @@ package http
 import (
 	"bufio"
 	"compress/gzip"
+	"container/list"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -20,6 +21,7 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -50,7 +52,9 @@ const DefaultMaxIdleConnsPerHost = 2
 // By default, Transport caches connections for future re-use.
 // This may leave many open connections when accessing many hosts.
 // This behavior can be managed using Transport's CloseIdleConnections method
-// and the MaxIdleConnsPerHost and DisableKeepAlives fields.
+// and the MaxIdleConns, MaxIdleConnsPerHost, MaxConnsPerHost,
+// IdleConnTimeout and DisableKeepAlives fields. The ConnStats method
+// reports the current state of the connection pool.
 //
 // Transports should be reused instead of created as needed.
 // Transports are safe for concurrent use by multiple goroutines.
@@ -66,6 +70,12 @@ type Transport struct {
 	wantIdle   bool // user has requested to close all idle conns
 	idleConn   map[connectMethodKey][]*persistConn
 	idleConnCh map[connectMethodKey]chan *persistConn
+	idleLRU    connLRU
+
+	connsPerHostMu    sync.Mutex
+	connsPerHost      map[connectMethodKey]int           // HTTP/1 conns dialing, active or idle
+	connsPerHostWait  map[connectMethodKey]int           // getConn calls waiting for a free slot
+	connsPerHostAvail map[connectMethodKey]chan struct{} // closed when a conn to the host goes away
 
 	reqMu       sync.Mutex
 	reqCanceler map[*Request]func()
@@ -117,11 +127,35 @@ type Transport struct {
 	// uncompressed.
 	DisableCompression bool
 
+	// MaxIdleConns controls the maximum number of idle (keep-alive)
+	// connections across all hosts. An HTTP/2 connection is idle
+	// while it has no open streams. When the limit is exceeded,
+	// the least recently used idle HTTP/1 connection is closed,
+	// and HTTP/2 connections are closed as soon as they become
+	// idle. Zero means no limit.
+	MaxIdleConns int
+
 	// MaxIdleConnsPerHost, if non-zero, controls the maximum idle
 	// (keep-alive) to keep per-host.  If zero,
 	// DefaultMaxIdleConnsPerHost is used.
 	MaxIdleConnsPerHost int
 
+	// MaxConnsPerHost optionally limits the total number of
+	// connections per host, including connections in the dialing,
+	// active, and idle states, for both HTTP/1 and HTTP/2. When
+	// the limit is reached, requests that need a new connection
+	// wait until a connection becomes idle or is closed, or until
+	// the request is canceled.
+	//
+	// Zero means no limit.
+	MaxConnsPerHost int
+
+	// IdleConnTimeout is the maximum amount of time an idle
+	// (keep-alive) connection, HTTP/1 or HTTP/2, will remain idle
+	// before closing itself.
+	// Zero means no limit.
+	IdleConnTimeout time.Duration
+
 	// ResponseHeaderTimeout, if non-zero, specifies the amount of
 	// time to wait for a server's response headers after fully
 	// writing the request (including its body, if any). This
@@ -146,19 +180,26 @@ type Transport struct {
 	// If TLSNextProto is nil, HTTP/2 support is enabled automatically.
 	TLSNextProto map[string]func(authority string, c *tls.Conn) RoundTripper
 
+	// UnencryptedHTTP2, if true, makes the Transport use HTTP/2
+	// over unencrypted TCP connections ("h2c") for "http" URLs.
+	// The server must accept HTTP/2 with prior knowledge; there
+	// is no fallback to HTTP/1.1. Requests sent through a proxy
+	// continue to use HTTP/1.1.
+	UnencryptedHTTP2 bool
+
 	// nextProtoOnce guards initialization of TLSNextProto and
 	// h2transport (via onceSetNextProtoDefaults)
 	nextProtoOnce sync.Once
 	h2transport   *http2Transport // non-nil if http2 wired up
-
-	// TODO: tunable on global max cached connections
-	// TODO: tunable on timeout on cached connections
-	// TODO: tunable on max per-host TCP dials in flight (Issue 13957)
+	h2cTransport  *http2Transport // non-nil if UnencryptedHTTP2 is set
 }
 
 // onceSetNextProtoDefaults initializes TLSNextProto.
 // It must be called via t.nextProtoOnce.Do.
 func (t *Transport) onceSetNextProtoDefaults() {
+	if t.UnencryptedHTTP2 {
+		t.h2cTransport = newH2CTransport(t)
+	}
 	if strings.Contains(os.Getenv("GODEBUG"), "http2client=0") {
 		return
 	}
@@ -193,6 +234,21 @@ func (t *Transport) onceSetNextProtoDefaults() {
 	}
 }
 
+// newH2CTransport returns the HTTP/2 transport used for "http"
+// URLs when t.UnencryptedHTTP2 is set. Like the TLS one, it never
+// dials by itself: t dials the connections and hands them over in
+// dialConn.
+func newH2CTransport(t *Transport) *http2Transport {
+	connPool := new(http2clientConnPool)
+	t2 := &http2Transport{
+		ConnPool:  http2noDialClientConnPool{connPool},
+		t1:        t,
+		AllowHTTP: true,
+	}
+	connPool.t = t2
+	return t2
+}
+
 // ProxyFromEnvironment returns the URL of the proxy to use for a
 // given request, as indicated by the environment variables
 // HTTP_PROXY, HTTPS_PROXY and NO_PROXY (or the lowercase versions
@@ -286,6 +342,12 @@ func (t *Transport) RoundTrip(req *Request) (*Response, error) {
 			return resp, err
 		}
 	}
+	if t2 := t.h2cTransport; t2 != nil && req.URL.Scheme == "http" {
+		// Use an already established h2c connection, if any.
+		if resp, err := (http2noDialH2RoundTripper{t2}).RoundTrip(req); err != ErrSkipAltProtocol {
+			return resp, err
+		}
+	}
 	if s := req.URL.Scheme; s != "http" && s != "https" {
 		req.closeBody()
 		return nil, &badStringError{"unsupported protocol scheme", s}
@@ -407,6 +469,7 @@ func (t *Transport) CloseIdleConnections() {
 	t.idleConn = nil
 	t.idleConnCh = nil
 	t.wantIdle = true
+	t.idleLRU = connLRU{}
 	t.idleMu.Unlock()
 	for _, conns := range m {
 		for _, pconn := range conns {
@@ -416,6 +479,9 @@ func (t *Transport) CloseIdleConnections() {
 	if t2 := t.h2transport; t2 != nil {
 		t2.CloseIdleConnections()
 	}
+	if t2 := t.h2cTransport; t2 != nil {
+		t2.CloseIdleConnections()
+	}
 }
 
 // CancelRequest cancels an in-flight request by closing its connection.
@@ -510,6 +576,8 @@ var (
 	errCloseIdleConns     = errors.New("http: CloseIdleConnections called")
 	errReadLoopExiting    = errors.New("http: persistConn.readLoop exiting")
 	errServerClosedIdle   = errors.New("http: server closed idle conn")
+	errCallerOwnsConn     = errors.New("http: caller owns the connection after a protocol switch")
+	errIdleConnTimeout    = errors.New("http: idle connection timeout")
 )
 
 func (t *Transport) putOrCloseIdleConn(pconn *persistConn) {
@@ -572,6 +640,23 @@ func (t *Transport) tryPutIdleConn(pconn *persistConn) error {
 		}
 	}
 	t.idleConn[key] = append(t.idleConn[key], pconn)
+	t.idleLRU.add(pconn)
+	if t.MaxIdleConns != 0 && t.idleLRU.len()+t.h2IdleConnCount() > t.MaxIdleConns {
+		oldest := t.idleLRU.removeOldest()
+		oldest.close(errTooManyIdle)
+		t.removeIdleConnLocked(oldest)
+		if oldest == pconn {
+			t.idleMu.Unlock()
+			return errTooManyIdle
+		}
+	}
+	if t.IdleConnTimeout > 0 {
+		if pconn.idleTimer != nil {
+			pconn.idleTimer.Reset(t.IdleConnTimeout)
+		} else {
+			pconn.idleTimer = time.AfterFunc(t.IdleConnTimeout, pconn.closeConnIfStillIdle)
+		}
+	}
 	t.idleMu.Unlock()
 	return nil
 }
@@ -619,12 +704,311 @@ func (t *Transport) getIdleConn(cm connectMethod) (pconn *persistConn) {
 			pconn = pconns[len(pconns)-1]
 			t.idleConn[key] = pconns[:len(pconns)-1]
 		}
-		if !pconn.isBroken() {
-			return
+		t.idleLRU.remove(pconn)
+		if pconn.isBroken() {
+			continue
 		}
+		if pconn.idleTimer != nil && !pconn.idleTimer.Stop() {
+			// We picked this conn at the same time it
+			// was expiring and it's trying to close
+			// itself in another goroutine. Don't use it.
+			continue
+		}
+		return
 	}
 }
 
+// removeIdleConn marks pconn as dead.
+func (t *Transport) removeIdleConn(pconn *persistConn) {
+	t.idleMu.Lock()
+	defer t.idleMu.Unlock()
+	t.removeIdleConnLocked(pconn)
+}
+
+// t.idleMu must be held.
+func (t *Transport) removeIdleConnLocked(pconn *persistConn) {
+	if pconn.idleTimer != nil {
+		pconn.idleTimer.Stop()
+	}
+	t.idleLRU.remove(pconn)
+	key := pconn.cacheKey
+	pconns := t.idleConn[key]
+	switch len(pconns) {
+	case 0:
+		// Nothing
+	case 1:
+		if pconns[0] == pconn {
+			delete(t.idleConn, key)
+		}
+	default:
+		for i, v := range pconns {
+			if v != pconn {
+				continue
+			}
+			// Slide down, keeping most recently-used
+			// conns at the end.
+			copy(pconns[i:], pconns[i+1:])
+			t.idleConn[key] = pconns[:len(pconns)-1]
+			break
+		}
+	}
+}
+
+// h2Transports returns the HTTP/2 transports that own t's "https"
+// and h2c connections, either of which may be nil. The "https" one is
+// found through the registered protocol, so it's also found when
+// HTTP/2 was configured explicitly rather than by default.
+func (t *Transport) h2Transports() (h2, h2c *http2Transport) {
+	t.altMu.RLock()
+	rt := t.altProto["https"]
+	t.altMu.RUnlock()
+	if rt, ok := rt.(http2noDialH2RoundTripper); ok {
+		h2 = rt.t
+	}
+	return h2, t.h2cTransport
+}
+
+// h2Pool returns the HTTP/2 connection pool holding the connections
+// for key, or nil if HTTP/2 isn't used for key.
+func (t *Transport) h2Pool(key connectMethodKey) *http2clientConnPool {
+	if key.proxy != "" {
+		return nil
+	}
+	h2, h2c := t.h2Transports()
+	var t2 *http2Transport
+	switch key.scheme {
+	case "https":
+		t2 = h2
+	case "http":
+		t2 = h2c
+	}
+	if t2 == nil {
+		return nil
+	}
+	if p, ok := t2.ConnPool.(http2noDialClientConnPool); ok {
+		return p.http2clientConnPool
+	}
+	return nil
+}
+
+// h2IdleConnCount returns the number of HTTP/2 connections without
+// open streams, for the MaxIdleConns limit.
+func (t *Transport) h2IdleConnCount() int {
+	n := 0
+	h2, h2c := t.h2Transports()
+	for _, t2 := range []*http2Transport{h2, h2c} {
+		if t2 == nil {
+			continue
+		}
+		if p, ok := t2.ConnPool.(http2noDialClientConnPool); ok {
+			_, idle := p.countConns("")
+			n += idle
+		}
+	}
+	return n
+}
+
+// h2ConnIdle is called by an HTTP/2 connection when its last open
+// stream finishes. It closes cc if that puts the Transport over its
+// MaxIdleConns limit.
+func (t *Transport) h2ConnIdle(cc *http2ClientConn) {
+	if t.MaxIdleConns == 0 {
+		return
+	}
+	t.idleMu.Lock()
+	n := t.idleLRU.len() + t.h2IdleConnCount()
+	t.idleMu.Unlock()
+	if n > t.MaxIdleConns {
+		cc.closeIfIdle()
+	}
+}
+
+// h2ConnClosed is called by an HTTP/2 connection pool of t when
+// its connection to addr goes away, freeing a MaxConnsPerHost slot.
+func (t *Transport) h2ConnClosed(t2 *http2Transport, addr string) {
+	key := connectMethodKey{scheme: "https", addr: addr}
+	if t2 == t.h2cTransport {
+		key.scheme = "http"
+	}
+	t.connsPerHostMu.Lock()
+	t.wakeHostConnWaitersLocked(key)
+	t.connsPerHostMu.Unlock()
+}
+
+// incHostConns reserves a connection slot for key. If MaxConnsPerHost
+// connections to the host already exist, nothing is reserved and
+// incHostConns returns a channel that is closed once one of them
+// goes away.
+func (t *Transport) incHostConns(key connectMethodKey) <-chan struct{} {
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	if max := t.MaxConnsPerHost; max > 0 {
+		n := t.connsPerHost[key]
+		if p := t.h2Pool(key); p != nil {
+			conns, _ := p.countConns(key.addr)
+			n += conns
+		}
+		if n >= max {
+			ch, ok := t.connsPerHostAvail[key]
+			if !ok {
+				if t.connsPerHostAvail == nil {
+					t.connsPerHostAvail = make(map[connectMethodKey]chan struct{})
+				}
+				ch = make(chan struct{})
+				t.connsPerHostAvail[key] = ch
+			}
+			return ch
+		}
+	}
+	if t.connsPerHost == nil {
+		t.connsPerHost = make(map[connectMethodKey]int)
+	}
+	t.connsPerHost[key]++
+	return nil
+}
+
+// decHostConns releases a connection slot reserved by incHostConns.
+func (t *Transport) decHostConns(key connectMethodKey) {
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	if n := t.connsPerHost[key] - 1; n > 0 {
+		t.connsPerHost[key] = n
+	} else {
+		delete(t.connsPerHost, key)
+	}
+	t.wakeHostConnWaitersLocked(key)
+}
+
+// t.connsPerHostMu must be held.
+func (t *Transport) wakeHostConnWaitersLocked(key connectMethodKey) {
+	if ch, ok := t.connsPerHostAvail[key]; ok {
+		close(ch)
+		delete(t.connsPerHostAvail, key)
+	}
+}
+
+// addHostConnWaiter adjusts the number of requests waiting for a
+// connection slot to key, as reported by ConnStats.
+func (t *Transport) addHostConnWaiter(key connectMethodKey, delta int) {
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	if t.connsPerHostWait == nil {
+		t.connsPerHostWait = make(map[connectMethodKey]int)
+	}
+	if n := t.connsPerHostWait[key] + delta; n > 0 {
+		t.connsPerHostWait[key] = n
+	} else {
+		delete(t.connsPerHostWait, key)
+	}
+}
+
+// HostConnStats describes a Transport's connections to one host, as
+// reported by Transport.ConnStats.
+type HostConnStats struct {
+	// Scheme is the scheme of the requests, "http" or "https".
+	Scheme string
+
+	// Addr is the "host:port" of the target server. It is empty
+	// for "http" requests sent through a proxy, which share their
+	// connections to the proxy across all target hosts.
+	Addr string
+
+	// Proxy is the URL of the proxy the connections go through,
+	// or empty if they go directly to the server.
+	Proxy string
+
+	// Active is the number of connections being dialed or
+	// carrying requests. An HTTP/2 connection is active while it
+	// has at least one open stream.
+	Active int
+
+	// Idle is the number of idle connections available for reuse.
+	Idle int
+
+	// Waiting is the number of requests waiting for a connection
+	// because MaxConnsPerHost has been reached.
+	Waiting int
+}
+
+// ConnStats returns a snapshot of the Transport's connection pool,
+// with one entry for each host that has connections or waiting
+// requests. Entries are sorted by Scheme, Addr and Proxy.
+// Connections that negotiated HTTP/2 are included.
+func (t *Transport) ConnStats() []HostConnStats {
+	t.nextProtoOnce.Do(t.onceSetNextProtoDefaults)
+	m := make(map[connectMethodKey]*HostConnStats)
+	get := func(key connectMethodKey) *HostConnStats {
+		hs, ok := m[key]
+		if !ok {
+			hs = &HostConnStats{Scheme: key.scheme, Addr: key.addr, Proxy: key.proxy}
+			m[key] = hs
+		}
+		return hs
+	}
+
+	t.idleMu.Lock()
+	for key, pconns := range t.idleConn {
+		get(key).Idle += len(pconns)
+	}
+	t.idleMu.Unlock()
+
+	t.connsPerHostMu.Lock()
+	for key, n := range t.connsPerHost {
+		hs := get(key)
+		if n > hs.Idle {
+			hs.Active += n - hs.Idle
+		}
+	}
+	for key, n := range t.connsPerHostWait {
+		get(key).Waiting += n
+	}
+	t.connsPerHostMu.Unlock()
+
+	h2, h2c := t.h2Transports()
+	for _, t2 := range []*http2Transport{h2, h2c} {
+		if t2 == nil {
+			continue
+		}
+		p, ok := t2.ConnPool.(http2noDialClientConnPool)
+		if !ok {
+			continue
+		}
+		scheme := "https"
+		if t2 == h2c {
+			scheme = "http"
+		}
+		p.foreachConn(func(addr string, cc *http2ClientConn, idle bool) {
+			hs := get(connectMethodKey{scheme: scheme, addr: addr})
+			if idle {
+				hs.Idle++
+			} else {
+				hs.Active++
+			}
+		})
+	}
+
+	stats := make([]HostConnStats, 0, len(m))
+	for _, hs := range m {
+		stats = append(stats, *hs)
+	}
+	sort.Sort(byHostConnStats(stats))
+	return stats
+}
+
+type byHostConnStats []HostConnStats
+
+func (s byHostConnStats) Len() int      { return len(s) }
+func (s byHostConnStats) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byHostConnStats) Less(i, j int) bool {
+	if s[i].Scheme != s[j].Scheme {
+		return s[i].Scheme < s[j].Scheme
+	}
+	if s[i].Addr != s[j].Addr {
+		return s[i].Addr < s[j].Addr
+	}
+	return s[i].Proxy < s[j].Proxy
+}
+
 func (t *Transport) setReqCanceler(r *Request, fn func()) {
 	t.reqMu.Lock()
 	defer t.reqMu.Unlock()
@@ -705,12 +1089,42 @@ func (t *Transport) getConn(req *Request, cm connectMethod) (*persistConn, error
 	cancelc := make(chan struct{})
 	t.setReqCanceler(req, func() { close(cancelc) })
 
+	idleConnCh := t.getIdleConnCh(cm)
+
+	// Wait for a connection slot if MaxConnsPerHost connections
+	// to the host already exist. A connection that becomes idle
+	// in the meantime is used directly.
+	key := cm.key()
+	if avail := t.incHostConns(key); avail != nil {
+		t.addHostConnWaiter(key, 1)
+		for avail != nil {
+			select {
+			case <-avail:
+				avail = t.incHostConns(key)
+			case pc := <-idleConnCh:
+				t.addHostConnWaiter(key, -1)
+				return pc, nil
+			case <-req.Cancel:
+				t.addHostConnWaiter(key, -1)
+				return nil, errRequestCanceledConn
+			case <-cancelc:
+				t.addHostConnWaiter(key, -1)
+				return nil, errRequestCanceledConn
+			}
+		}
+		t.addHostConnWaiter(key, -1)
+	}
+
 	go func() {
 		pc, err := t.dialConn(cm)
+		if err != nil || pc.alt != nil {
+			// Failed dials hold no slot, and HTTP/2
+			// connections are counted by their pool.
+			t.decHostConns(key)
+		}
 		dialc <- dialRes{pc, err}
 	}()
 
-	idleConnCh := t.getIdleConnCh(cm)
 	select {
 	case v := <-dialc:
 		// Our dial finished.
@@ -855,6 +1269,19 @@ func (t *Transport) dialConn(cm connectMethod) (*persistConn, error) {
 		}
 	}
 
+	if t2 := t.h2cTransport; t2 != nil && cm.targetScheme == "http" && cm.proxyURL == nil {
+		pool := t2.ConnPool.(http2noDialClientConnPool)
+		used, err := pool.addConnIfNeeded(cm.targetAddr, t2, pconn.conn)
+		if err != nil {
+			pconn.conn.Close()
+			return nil, err
+		}
+		if !used {
+			go pconn.conn.Close()
+		}
+		return &persistConn{alt: t2}, nil
+	}
+
 	pconn.br = bufio.NewReader(noteEOFReader{pconn.conn, &pconn.sawEOF})
 	pconn.bw = bufio.NewWriter(pconn.conn)
 	go pconn.readLoop()
@@ -1006,6 +1433,10 @@ type persistConn struct {
 	// whether or not a connection can be reused. Issue 7569.
 	writeErrCh chan error
 
+	// idleTimer is non-nil once the conn was put idle with an
+	// IdleConnTimeout. Guarded by t.idleMu.
+	idleTimer *time.Timer
+
 	lk                   sync.Mutex // guards following fields
 	numExpectedResponses int
 	closed               error // set non-nil when conn is closed, before closech is closed
@@ -1041,6 +1472,21 @@ func (pc *persistConn) isReused() bool {
 	return r
 }
 
+// closeConnIfStillIdle closes the connection if it's still sitting idle.
+// This is what's called by the persistConn's idleTimer, and is run in its
+// own goroutine.
+func (pc *persistConn) closeConnIfStillIdle() {
+	t := pc.t
+	t.idleMu.Lock()
+	defer t.idleMu.Unlock()
+	if !t.idleLRU.contains(pc) {
+		// Not idle.
+		return
+	}
+	t.removeIdleConnLocked(pc)
+	pc.close(errIdleConnTimeout)
+}
+
 func (pc *persistConn) cancelRequest() {
 	pc.lk.Lock()
 	defer pc.lk.Unlock()
@@ -1050,7 +1496,10 @@ func (pc *persistConn) cancelRequest() {
 
 func (pc *persistConn) readLoop() {
 	closeErr := errReadLoopExiting // default value, if not changed below
-	defer func() { pc.close(closeErr) }()
+	defer func() {
+		pc.close(closeErr)
+		pc.t.removeIdleConn(pc)
+	}()
 
 	tryPutIdleConn := func() bool {
 		if err := pc.t.tryPutIdleConn(pc); err != nil {
@@ -1117,6 +1566,7 @@ func (pc *persistConn) readLoop() {
 		pc.lk.Unlock()
 
 		hasBody := rc.req.Method != "HEAD" && resp.ContentLength != 0
+		bodyWritable := resp.isProtocolSwitch()
 
 		if resp.Close || rc.req.Close || resp.StatusCode <= 199 {
 			// Don't do keep-alive on error if either party requested a close
@@ -1125,6 +1575,21 @@ func (pc *persistConn) readLoop() {
 			alive = false
 		}
 
+		if bodyWritable {
+			// The connection now speaks whatever protocol the
+			// server switched to. Hand it over to the caller as
+			// the response body and stop reading from it here.
+			resp.Body = &readWriteCloserBody{br: pc.br, rwc: pc.conn}
+			closeErr = errCallerOwnsConn
+			pc.t.setReqCanceler(rc.req, nil)
+			select {
+			case rc.ch <- responseAndError{res: resp}:
+			case <-rc.callerGone:
+				pc.conn.Close()
+			}
+			return
+		}
+
 		if !hasBody {
 			pc.t.setReqCanceler(rc.req, nil)
 
@@ -1204,6 +1669,33 @@ func (pc *persistConn) readLoop() {
 	}
 }
 
+// readWriteCloserBody is the Response.Body type used when we want to
+// give users write access to the Body through the underlying
+// connection (TCP, unless using custom dialers). This is then
+// the concrete type for a Response.Body on the 101 Switching
+// Protocols response, as used by WebSockets, h2c, etc.
+type readWriteCloserBody struct {
+	br  *bufio.Reader // used until empty
+	rwc io.ReadWriteCloser
+}
+
+func (b *readWriteCloserBody) Read(p []byte) (n int, err error) {
+	if b.br != nil {
+		if n := b.br.Buffered(); len(p) > n {
+			p = p[:n]
+		}
+		n, err = b.br.Read(p)
+		if b.br.Buffered() == 0 {
+			b.br = nil
+		}
+		return n, err
+	}
+	return b.rwc.Read(p)
+}
+
+func (b *readWriteCloserBody) Write(p []byte) (int, error) { return b.rwc.Write(p) }
+func (b *readWriteCloserBody) Close() error                { return b.rwc.Close() }
+
 func maybeUngzipResponse(resp *Response) {
 	if resp.Header.Get("Content-Encoding") == "gzip" {
 		resp.Header.Del("Content-Encoding")
@@ -1565,8 +2057,11 @@ func (pc *persistConn) closeLocked(err error) {
 			// freelist for http2. That's done by the
 			// alternate protocol's RoundTripper.
 		} else {
-			pc.conn.Close()
+			if err != errCallerOwnsConn {
+				pc.conn.Close()
+			}
 			close(pc.closech)
+			pc.t.decHostConns(pc.cacheKey)
 		}
 	}
 	pc.mutateHeaderFunc = nil
@@ -1779,3 +2274,50 @@ func cloneTLSClientConfig(cfg *tls.Config) *tls.Config {
 		CurvePreferences:         cfg.CurvePreferences,
 	}
 }
+
+// connLRU tracks idle connections from least to most recently used.
+type connLRU struct {
+	ll *list.List // list.Element.Value type is of *persistConn
+	m  map[*persistConn]*list.Element
+}
+
+// add adds pc to the head of the linked list.
+func (cl *connLRU) add(pc *persistConn) {
+	if cl.ll == nil {
+		cl.ll = list.New()
+		cl.m = make(map[*persistConn]*list.Element)
+	}
+	ele := cl.ll.PushFront(pc)
+	if _, ok := cl.m[pc]; ok {
+		panic("persistConn was already in LRU")
+	}
+	cl.m[pc] = ele
+}
+
+// removeOldest removes and returns the least recently used connection.
+func (cl *connLRU) removeOldest() *persistConn {
+	ele := cl.ll.Back()
+	pc := ele.Value.(*persistConn)
+	cl.ll.Remove(ele)
+	delete(cl.m, pc)
+	return pc
+}
+
+// remove removes pc from cl.
+func (cl *connLRU) remove(pc *persistConn) {
+	if ele, ok := cl.m[pc]; ok {
+		cl.ll.Remove(ele)
+		delete(cl.m, pc)
+	}
+}
+
+// contains reports whether pc is in cl.
+func (cl *connLRU) contains(pc *persistConn) bool {
+	_, ok := cl.m[pc]
+	return ok
+}
+
+// len returns the number of items in the cache.
+func (cl *connLRU) len() int {
+	return len(cl.m)
+}
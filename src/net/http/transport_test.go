@@ -29,6 +29,7 @@ import (
 	"os"
 	"reflect"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -436,6 +437,178 @@ func TestTransportMaxPerHostIdleConns(t *testing.T) {
 	}
 }
 
+func TestTransportMaxIdleConns(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		// No body for convenience.
+	}))
+	defer ts.Close()
+	tr := &Transport{
+		MaxIdleConns: 4,
+		Dial: func(network, _ string) (net.Conn, error) {
+			return net.Dial(network, ts.Listener.Addr().String())
+		},
+	}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+
+	_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostAddr := func(n int) string {
+		return fmt.Sprintf("host-%d.dns-is-faked.golang:%s", n, port)
+	}
+	hitHost := func(n int) {
+		res, err := c.Get("http://" + hostAddr(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	idleKeys := func() []string {
+		keys := tr.IdleConnKeysForTesting()
+		sort.Strings(keys)
+		return keys
+	}
+	for i := 0; i < 4; i++ {
+		hitHost(i)
+	}
+	want := []string{
+		"|http|" + hostAddr(0),
+		"|http|" + hostAddr(1),
+		"|http|" + hostAddr(2),
+		"|http|" + hostAddr(3),
+	}
+	if got := idleKeys(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("idle conn keys mismatch.\n got: %q\nwant: %q\n", got, want)
+	}
+
+	// Now hitting the 5th host should kick out the first host:
+	hitHost(4)
+	want = []string{
+		"|http|" + hostAddr(1),
+		"|http|" + hostAddr(2),
+		"|http|" + hostAddr(3),
+		"|http|" + hostAddr(4),
+	}
+	if got := idleKeys(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("idle conn keys mismatch after 5th host.\n got: %q\nwant: %q\n", got, want)
+	}
+}
+
+// waitConnStats polls tr.ConnStats until it equals want.
+func waitConnStats(t *testing.T, tr *Transport, want []HostConnStats) {
+	var got []HostConnStats
+	for i := 0; i < 200; i++ {
+		got = tr.ConnStats()
+		if len(got) == 0 && len(want) == 0 || reflect.DeepEqual(got, want) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("ConnStats = %+v; want %+v", got, want)
+}
+
+func TestTransportIdleConnTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		// No body for convenience.
+	}))
+	defer ts.Close()
+	const timeout = 100 * time.Millisecond
+	tr := &Transport{IdleConnTimeout: timeout}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+
+	res, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	addr := ts.Listener.Addr().String()
+	if got, want := tr.ConnStats(), []HostConnStats{{Scheme: "http", Addr: addr, Idle: 1}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after request, ConnStats = %+v; want %+v", got, want)
+	}
+	waitConnStats(t, tr, nil)
+	if n := len(tr.IdleConnKeysForTesting()); n != 0 {
+		t.Errorf("after IdleConnTimeout, %d idle conn keys remain", n)
+	}
+}
+
+func TestTransportMaxConnsPerHost(t *testing.T) {
+	defer afterTest(t)
+	var (
+		mu       sync.Mutex
+		numConns int
+	)
+	gotReq := make(chan bool, 3)
+	unblock := make(chan bool)
+	ts := httptest.NewUnstartedServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		gotReq <- true
+		<-unblock
+	}))
+	ts.Config.ConnState = func(c net.Conn, state ConnState) {
+		if state == StateNew {
+			mu.Lock()
+			numConns++
+			mu.Unlock()
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	tr := &Transport{MaxConnsPerHost: 1}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+
+	errc := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			res, err := c.Get(ts.URL)
+			if err == nil {
+				_, err = ioutil.ReadAll(res.Body)
+				res.Body.Close()
+			}
+			errc <- err
+		}()
+	}
+	<-gotReq
+	addr := ts.Listener.Addr().String()
+	waitConnStats(t, tr, []HostConnStats{{Scheme: "http", Addr: addr, Active: 1, Waiting: 2}})
+
+	// A waiting request can be canceled.
+	cancel := make(chan struct{})
+	req, _ := NewRequest("GET", ts.URL, nil)
+	req.Cancel = cancel
+	reqErrc := make(chan error, 1)
+	go func() {
+		_, err := c.Do(req)
+		reqErrc <- err
+	}()
+	waitConnStats(t, tr, []HostConnStats{{Scheme: "http", Addr: addr, Active: 1, Waiting: 3}})
+	close(cancel)
+	if err := <-reqErrc; err == nil {
+		t.Error("canceled request succeeded")
+	}
+
+	close(unblock)
+	for i := 0; i < 3; i++ {
+		if err := <-errc; err != nil {
+			t.Error(err)
+		}
+	}
+	waitConnStats(t, tr, []HostConnStats{{Scheme: "http", Addr: addr, Idle: 1}})
+	mu.Lock()
+	defer mu.Unlock()
+	if numConns != 1 {
+		t.Errorf("server saw %d connections; want 1", numConns)
+	}
+}
+
 func TestTransportServerClosingUnexpectedly(t *testing.T) {
 	setParallel(t)
 	defer afterTest(t)
@@ -3050,6 +3223,56 @@ func TestNoCrashReturningTransportAltConn(t *testing.T) {
 	<-handledPendingDial
 }
 
+func TestTransportResponseBodyWritableOnProtocolSwitch(t *testing.T) {
+	defer afterTest(t)
+	done := make(chan struct{})
+	defer close(done)
+	cst := newClientServerTest(t, h1Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		conn, _, err := w.(Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols Hi\r\nConnection: upgRADe\r\nUpgrade: foo\r\n\r\nSome buffered data\n")
+		bs := bufio.NewScanner(conn)
+		bs.Scan()
+		fmt.Fprintf(conn, "%s\n", strings.ToUpper(bs.Text()))
+		<-done
+	}))
+	defer cst.close()
+
+	req, _ := NewRequest("GET", cst.ts.URL, nil)
+	req.Header.Set("Upgrade", "foo")
+	req.Header.Set("Connection", "upgrade")
+	res, err := cst.c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 101 {
+		t.Fatalf("expected 101 switching protocols; got %v, %v", res.Status, res.Header)
+	}
+	rwc, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatalf("expected a ReadWriteCloser; got a %T", res.Body)
+	}
+	defer rwc.Close()
+	bs := bufio.NewScanner(rwc)
+	if !bs.Scan() {
+		t.Fatalf("expected readable input")
+	}
+	if got, want := bs.Text(), "Some buffered data"; got != want {
+		t.Errorf("read %q; want %q", got, want)
+	}
+	io.WriteString(rwc, "echo\n")
+	if !bs.Scan() {
+		t.Fatalf("expected another line")
+	}
+	if got, want := bs.Text(), "ECHO"; got != want {
+		t.Errorf("read %q; want %q", got, want)
+	}
+}
+
 var errFakeRoundTrip = errors.New("fake roundtrip")
 
 type funcRoundTripper func()
@@ -0,0 +1,275 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// BuildInfo represents the build information read from a Go binary.
+type BuildInfo struct {
+	// GoVersion is the version of the Go toolchain that built the binary,
+	// for example "go1.7".
+	GoVersion string
+
+	// Path is the import path of the main package of the binary,
+	// for example "golang.org/x/tools/cmd/stringer".
+	// For a test binary, it is the import path of the package
+	// under test with a ".test" suffix.
+	Path string
+
+	// Main describes the module containing the main package,
+	// or, outside of a module, the version control repository
+	// containing it. Main is empty for a program in GOROOT.
+	Main Module
+
+	// Deps describes the modules or repositories, other than Main and
+	// GOROOT, that provided packages linked into the binary.
+	Deps []*Module
+
+	// Settings describes the build settings used to build the binary,
+	// such as the target operating system and the version control
+	// revision of the main package.
+	Settings []BuildSetting
+}
+
+// A Module describes a single module, or repository, included in a build.
+type Module struct {
+	Path    string  // import path of the module or repository root
+	Version string  // version, or "(devel)" if not known
+	Sum     string  // checksum
+	Replace *Module // replaced by this module
+}
+
+// A BuildSetting is a key-value pair describing one setting that
+// influenced a build.
+//
+// Defined keys include:
+//
+//	- -compiler: the compiler toolchain flag used
+//	- -gcflags, -ldflags, -asmflags: the corresponding flags, if set
+//	- -race, -msan, -trimpath: set to "true" if the corresponding flag was used
+//	- -tags: the build tags used, if any
+//	- CGO_ENABLED: the effective CGO_ENABLED environment variable
+//	- GOARCH: the architecture target
+//	- GOOS: the operating system target
+//	- vcs: the version control system for the source tree, such as "git"
+//	- vcs.revision: the revision identifier for the current commit or checkout
+//	- vcs.time: the modification time associated with vcs.revision, in RFC3339 format
+//	- vcs.modified: "true" or "false" indicating whether the source tree had local modifications
+type BuildSetting struct {
+	// Key and Value describe the build setting.
+	// Key must not contain an equals sign, space, tab, or newline.
+	// Value must not contain newlines ('\n').
+	Key, Value string
+}
+
+// ReadBuildInfo returns the build information embedded in the running
+// binary. The information is available only in binaries built by the
+// go command.
+func ReadBuildInfo() (info *BuildInfo, ok bool) {
+	data := modinfo()
+	if data == "" {
+		return nil, false
+	}
+	bi, err := ParseBuildInfo(data)
+	if err != nil {
+		return nil, false
+	}
+	bi.GoVersion = runtime.Version()
+	return bi, true
+}
+
+// quoteKey reports whether key is required to be quoted.
+func quoteKey(key string) bool {
+	return len(key) == 0 || strings.ContainsAny(key, "= \t\r\n\"`")
+}
+
+// quoteValue reports whether value is required to be quoted.
+func quoteValue(value string) bool {
+	return strings.ContainsAny(value, " \t\r\n\"`")
+}
+
+// String returns the build information in the line-oriented text
+// format parsed by ParseBuildInfo.
+func (bi *BuildInfo) String() string {
+	var buf []byte
+	if bi.GoVersion != "" {
+		buf = append(buf, "go\t"...)
+		buf = append(buf, bi.GoVersion...)
+		buf = append(buf, '\n')
+	}
+	if bi.Path != "" {
+		buf = append(buf, "path\t"...)
+		buf = append(buf, bi.Path...)
+		buf = append(buf, '\n')
+	}
+	var formatMod func(string, Module)
+	formatMod = func(word string, m Module) {
+		buf = append(buf, word...)
+		buf = append(buf, '\t')
+		buf = append(buf, m.Path...)
+		buf = append(buf, '\t')
+		buf = append(buf, m.Version...)
+		if m.Replace == nil {
+			buf = append(buf, '\t')
+			buf = append(buf, m.Sum...)
+		} else {
+			buf = append(buf, '\n')
+			formatMod("=>", *m.Replace)
+		}
+		buf = append(buf, '\n')
+	}
+	if bi.Main != (Module{}) {
+		formatMod("mod", bi.Main)
+	}
+	for _, dep := range bi.Deps {
+		formatMod("dep", *dep)
+	}
+	for _, s := range bi.Settings {
+		key := s.Key
+		if quoteKey(key) {
+			key = strconv.Quote(key)
+		}
+		value := s.Value
+		if quoteValue(value) {
+			value = strconv.Quote(value)
+		}
+		buf = append(buf, "build\t"...)
+		buf = append(buf, key...)
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+		buf = append(buf, '\n')
+	}
+	return string(buf)
+}
+
+// ParseBuildInfo parses the build information in the format
+// written by BuildInfo.String.
+func ParseBuildInfo(data string) (bi *BuildInfo, err error) {
+	lineNum := 1
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("could not parse Go build info: line %d: %v", lineNum, err)
+		}
+	}()
+
+	readModuleLine := func(elem []string) (Module, error) {
+		if len(elem) != 2 && len(elem) != 3 {
+			return Module{}, fmt.Errorf("expected 2 or 3 columns; got %d", len(elem))
+		}
+		m := Module{Path: elem[0], Version: elem[1]}
+		if len(elem) == 3 {
+			m.Sum = elem[2]
+		}
+		return m, nil
+	}
+
+	bi = new(BuildInfo)
+	var last *Module
+	for len(data) > 0 {
+		i := strings.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		line := data[:i]
+		data = data[i+1:]
+		word, rest := line, ""
+		if j := strings.IndexByte(line, '\t'); j >= 0 {
+			word, rest = line[:j], line[j+1:]
+		}
+		switch word {
+		case "go":
+			bi.GoVersion = rest
+		case "path":
+			bi.Path = rest
+		case "mod":
+			last = &bi.Main
+			if *last, err = readModuleLine(strings.Split(rest, "\t")); err != nil {
+				return nil, err
+			}
+		case "dep":
+			last = new(Module)
+			bi.Deps = append(bi.Deps, last)
+			if *last, err = readModuleLine(strings.Split(rest, "\t")); err != nil {
+				return nil, err
+			}
+		case "=>":
+			elem := strings.Split(rest, "\t")
+			if len(elem) != 3 {
+				return nil, fmt.Errorf("expected 3 columns for replacement; got %d", len(elem))
+			}
+			if last == nil {
+				return nil, fmt.Errorf("replacement with no module on previous line")
+			}
+			last.Replace = &Module{Path: elem[0], Version: elem[1], Sum: elem[2]}
+			last = nil
+		case "build":
+			var key, value string
+			if key, rest, err = unquotePrefix(rest, "="); err != nil {
+				return nil, fmt.Errorf("invalid build key: %v", err)
+			}
+			if key == "" {
+				return nil, fmt.Errorf("empty key")
+			}
+			if len(rest) == 0 || rest[0] != '=' {
+				return nil, fmt.Errorf("missing = after key")
+			}
+			if value, rest, err = unquotePrefix(rest[1:], ""); err != nil {
+				return nil, fmt.Errorf("invalid build value: %v", err)
+			}
+			if rest != "" {
+				return nil, fmt.Errorf("unexpected text after value: %q", rest)
+			}
+			bi.Settings = append(bi.Settings, BuildSetting{Key: key, Value: value})
+		}
+		lineNum++
+	}
+	return bi, nil
+}
+
+// unquotePrefix returns the prefix of s up to the first of the
+// characters in stop, or all of s if there is none, together with the
+// rest of s. If s starts with a quotation mark, the prefix is instead
+// the quoted string at the start of s, unquoted.
+func unquotePrefix(s, stop string) (prefix, rest string, err error) {
+	if s == "" || (s[0] != '"' && s[0] != '`') {
+		i := len(s)
+		if stop != "" {
+			if j := strings.IndexAny(s, stop); j >= 0 {
+				i = j
+			}
+		}
+		return s[:i], s[i:], nil
+	}
+	end := -1
+	if s[0] == '`' {
+		if j := strings.IndexByte(s[1:], '`'); j >= 0 {
+			end = j + 2
+		}
+	} else {
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '"' {
+				end = i + 1
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return "", "", fmt.Errorf("unterminated quoted string")
+	}
+	prefix, err = strconv.Unquote(s[:end])
+	if err != nil {
+		return "", "", err
+	}
+	return prefix, s[end:], nil
+}
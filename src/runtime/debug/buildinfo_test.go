@@ -0,0 +1,92 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"reflect"
+	"runtime"
+	. "runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestReadBuildInfo(t *testing.T) {
+	bi, ok := ReadBuildInfo()
+	if !ok {
+		t.Fatal("ReadBuildInfo returned no information for a binary built by go test")
+	}
+	if bi.GoVersion != runtime.Version() {
+		t.Errorf("GoVersion = %q, want %q", bi.GoVersion, runtime.Version())
+	}
+	if bi.Path != "runtime/debug.test" {
+		t.Errorf("Path = %q, want %q", bi.Path, "runtime/debug.test")
+	}
+	if bi.Main != (Module{}) || len(bi.Deps) != 0 {
+		t.Errorf("got main %v and deps %v for a test of the standard library", bi.Main, bi.Deps)
+	}
+	var goos string
+	for _, s := range bi.Settings {
+		if s.Key == "GOOS" {
+			goos = s.Value
+		}
+	}
+	if goos != runtime.GOOS {
+		t.Errorf("GOOS setting = %q, want %q", goos, runtime.GOOS)
+	}
+}
+
+func TestParseBuildInfoRoundTrip(t *testing.T) {
+	bi := &BuildInfo{
+		GoVersion: "go1.7",
+		Path:      "example.com/app/cmd/app",
+		Main:      Module{Path: "example.com/app", Version: "(devel)"},
+		Deps: []*Module{
+			{Path: "example.com/dep", Version: "v1.0.0", Sum: "h1:xyz"},
+			{Path: "example.com/old", Version: "v1.2.0", Replace: &Module{Path: "example.com/new", Version: "v1.3.0", Sum: "h1:abc"}},
+		},
+		Settings: []BuildSetting{
+			{Key: "-compiler", Value: "gc"},
+			{Key: "-ldflags", Value: `-X "main.v=a b"`},
+			{Key: "-tags", Value: "a,b"},
+			{Key: "GOOS", Value: "zos"},
+			{Key: "odd key=", Value: ""},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	text := bi.String()
+	for _, line := range []string{
+		"go\tgo1.7\n",
+		"mod\texample.com/app\t(devel)\t\n",
+		"dep\texample.com/old\tv1.2.0\n=>\texample.com/new\tv1.3.0\th1:abc\n",
+		"build\t-ldflags=\"-X \\\"main.v=a b\\\"\"\n",
+		"build\t\"odd key=\"=\n",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("String() does not contain %q:\n%s", line, text)
+		}
+	}
+	got, err := ParseBuildInfo(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, bi) {
+		t.Errorf("ParseBuildInfo(%q) = %+v, want %+v", text, got, bi)
+	}
+}
+
+func TestParseBuildInfoErrors(t *testing.T) {
+	for _, text := range []string{
+		"mod\texample.com/app\n",
+		"=>\texample.com/new\tv1.0.0\t\n",
+		"build\t=gc\n",
+		"build\tGOOS\n",
+		"build\t\"GOOS=linux\n",
+		"build\tGOOS=\"linux\" extra\n",
+	} {
+		if bi, err := ParseBuildInfo(text); err == nil {
+			t.Errorf("ParseBuildInfo(%q) = %+v, want error", text, bi)
+		}
+	}
+}
@@ -37,6 +37,7 @@ import (
 //	heap         - a sampling of all heap allocations
 //	threadcreate - stack traces that led to the creation of new OS threads
 //	block        - stack traces that led to blocking on synchronization primitives
+//	mutex        - stack traces of holders of contended mutexes
 //
 // These predefined profiles maintain themselves and panic on an explicit
 // Add or Remove method call.
@@ -48,6 +49,12 @@ import (
 // all known allocations. This exception helps mainly in programs running
 // without garbage collection enabled, usually for debugging purposes.
 //
+// The mutex profile reports the unlocks of sync.Mutex and sync.RWMutex
+// values that released goroutines waiting for them, with the time the
+// goroutines waited, sampled at the rate set by
+// runtime.SetMutexProfileFraction. Its stacks show where contended
+// locks are held rather than where goroutines block on them.
+//
 // The CPU profile is not available as a Profile.  It has a special API,
 // the StartCPUProfile and StopCPUProfile functions, because it streams
 // output to a writer during profiling.
@@ -90,6 +97,12 @@ var blockProfile = &Profile{
 	write: writeBlock,
 }
 
+var mutexProfile = &Profile{
+	name:  "mutex",
+	count: countMutex,
+	write: writeMutex,
+}
+
 func lockProfiles() {
 	profiles.mu.Lock()
 	if profiles.m == nil {
@@ -99,6 +112,7 @@ func lockProfiles() {
 			"threadcreate": threadcreateProfile,
 			"heap":         heapProfile,
 			"block":        blockProfile,
+			"mutex":        mutexProfile,
 		}
 	}
 }
@@ -642,11 +656,31 @@ func countBlock() int {
 
 // writeBlock writes the current blocking profile to w.
 func writeBlock(w io.Writer, debug int) error {
+	return writeContention(w, debug, runtime.BlockProfile, "contention", "")
+}
+
+// countMutex returns the number of records in the mutex profile.
+func countMutex() int {
+	n, _ := runtime.MutexProfile(nil)
+	return n
+}
+
+// writeMutex writes the current mutex profile to w.
+func writeMutex(w io.Writer, debug int) error {
+	period := fmt.Sprintf("sampling period=%d\n", runtime.SetMutexProfileFraction(-1))
+	return writeContention(w, debug, runtime.MutexProfile, "mutex", period)
+}
+
+// writeContention writes the contention profile returned by fetch,
+// either the blocking or the mutex profile, to w. The profile is
+// written in the legacy contention format with the given name and
+// the extra header lines in header.
+func writeContention(w io.Writer, debug int, fetch func([]runtime.BlockProfileRecord) (int, bool), name, header string) error {
 	var p []runtime.BlockProfileRecord
-	n, ok := runtime.BlockProfile(nil)
+	n, ok := fetch(nil)
 	for {
 		p = make([]runtime.BlockProfileRecord, n+50)
-		n, ok = runtime.BlockProfile(p)
+		n, ok = fetch(p)
 		if ok {
 			p = p[:n]
 			break
@@ -663,8 +697,9 @@ func writeBlock(w io.Writer, debug int) error {
 		w = tw
 	}
 
-	fmt.Fprintf(w, "--- contention:\n")
+	fmt.Fprintf(w, "--- %s:\n", name)
 	fmt.Fprintf(w, "cycles/second=%v\n", runtime_cyclesPerSecond())
+	fmt.Fprint(w, header)
 	for i := range p {
 		r := &p[i]
 		fmt.Fprintf(w, "%v %v @", r.Cycles, r.Count)
@@ -21,3 +21,12 @@ func setPanicOnFault(new bool) (old bool) {
 	releasem(mp)
 	return old
 }
+
+// modinfo is the build information recorded by cmd/go,
+// set by the linker.
+var modinfo string
+
+//go:linkname runtime_debug_modinfo runtime/debug.modinfo
+func runtime_debug_modinfo() string {
+	return modinfo
+}
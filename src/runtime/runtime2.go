@@ -168,6 +168,7 @@ type sudog struct {
 	next        *sudog
 	prev        *sudog
 	elem        unsafe.Pointer // data element
+	acquiretime int64
 	releasetime int64
 	nrelease    int32  // -1 for acquire
 	waitlink    *sudog // g.waiting list
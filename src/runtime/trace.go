@@ -57,7 +57,12 @@ const (
 	traceEvNextGC         = 34 // memstats.next_gc change [timestamp, next_gc]
 	traceEvTimerGoroutine = 35 // denotes timer goroutine [timer goroutine id]
 	traceEvFutileWakeup   = 36 // denotes that the previous wakeup of this goroutine was futile [timestamp]
-	traceEvCount          = 37
+	traceEvString         = 37 // string dictionary entry [ID, length, string]
+	traceEvUserTaskCreate = 38 // trace.NewTask [timestamp, internal task id, internal parent task id, stack, name string]
+	traceEvUserTaskEnd    = 39 // end of a task [timestamp, internal task id, stack]
+	traceEvUserRegion     = 40 // trace.WithRegion [timestamp, internal task id, mode(0:start, 1:end), stack, name string]
+	traceEvUserLog        = 41 // trace.Log [timestamp, internal task id, key string id, stack, value string]
+	traceEvCount          = 42
 )
 
 const (
@@ -88,6 +93,12 @@ const (
 	// Such wakeups happen on buffered channels and sync.Mutex,
 	// but are generally not interesting for end user.
 	traceFutileWakeup byte = 128
+	// Maximum length of a string in the string dictionary of the trace,
+	// such as the name of a task or region. Longer strings are truncated.
+	traceMaxStringLen = 1 << 10
+	// Maximum length of a message of trace.Log. Longer messages are
+	// truncated, so that a message always fits into an empty buffer.
+	traceMaxLogLen = 32 << 10
 )
 
 // trace is global tracing context.
@@ -111,6 +122,10 @@ var trace struct {
 	reader        *g              // goroutine that called ReadTrace, or nil
 	stackTab      traceStackTable // maps stack traces to unique ids
 
+	stringsLock mutex
+	strings     map[string]uint64 // maps user annotation strings to unique ids
+	stringSeq   uint64            // last assigned string id
+
 	bufLock mutex       // protects buf
 	buf     traceBufPtr // global trace buffer, used when running without a p
 }
@@ -191,6 +206,8 @@ func StartTrace() error {
 	trace.timeStart = nanotime()
 	trace.headerWritten = false
 	trace.footerWritten = false
+	trace.strings = make(map[string]uint64)
+	trace.stringSeq = 0
 
 	// Can't set trace.enabled yet. While the world is stopped, exitsyscall could
 	// already emit a delayed event (see exitTicks in exitsyscall) if we set trace.enabled here.
@@ -280,7 +297,7 @@ func StopTrace() {
 
 	// The world is started but we've set trace.shutdown, so new tracing can't start.
 	// Wait for the trace reader to flush pending buffers and stop.
-	semacquire(&trace.shutdownSema, false)
+	semacquire(&trace.shutdownSema, 0)
 	if raceenabled {
 		raceacquire(unsafe.Pointer(&trace.shutdownSema))
 	}
@@ -309,6 +326,7 @@ func StopTrace() {
 		trace.empty = buf.ptr().link
 		sysFree(unsafe.Pointer(buf), unsafe.Sizeof(*buf.ptr()), &memstats.other_sys)
 	}
+	trace.strings = nil
 	trace.shutdown = false
 	unlock(&trace.lock)
 }
@@ -348,7 +366,7 @@ func ReadTrace() []byte {
 		trace.headerWritten = true
 		trace.lockOwner = nil
 		unlock(&trace.lock)
-		return []byte("go 1.5 trace\x00\x00\x00\x00")
+		return []byte("go 1.7 trace\x00\x00\x00\x00")
 	}
 	// Wait for new data.
 	if trace.fullHead == 0 && !trace.shutdown {
@@ -476,9 +494,18 @@ func traceEvent(ev byte, skip int, args ...uint64) {
 		traceReleaseBuffer(pid)
 		return
 	}
+	traceEventLocked(0, mp, pid, bufp, ev, skip, args...)
+	traceReleaseBuffer(pid)
+}
+
+// traceEventLocked writes an event to the buffer bufp acquired with
+// traceAcquireBuffer. skip is interpreted as in traceEvent, relative to
+// the caller of traceEventLocked. extraBytes is the number of bytes the
+// caller appends to the event after traceEventLocked returns.
+func traceEventLocked(extraBytes int, mp *m, pid int32, bufp *traceBufPtr, ev byte, skip int, args ...uint64) {
 	buf := (*bufp).ptr()
-	const maxSize = 2 + 5*traceBytesPerNumber // event type, length, sequence, timestamp, stack id and two add params
-	if buf == nil || len(buf.arr)-buf.pos < maxSize {
+	const maxSize = 2 + 6*traceBytesPerNumber // event type, length, sequence, timestamp, stack id and three add params
+	if buf == nil || len(buf.arr)-buf.pos < maxSize+extraBytes {
 		buf = traceFlush(traceBufPtrOf(buf)).ptr()
 		(*bufp).set(buf)
 	}
@@ -526,7 +553,7 @@ func traceEvent(ev byte, skip int, args ...uint64) {
 		gp := mp.curg
 		var nstk int
 		if gp == _g_ {
-			nstk = callers(skip, buf.stk[:])
+			nstk = callers(skip+1, buf.stk[:])
 		} else if gp != nil {
 			gp = mp.curg
 			// This may happen when tracing a system call,
@@ -553,7 +580,6 @@ func traceEvent(ev byte, skip int, args ...uint64) {
 		// Fill in actual length.
 		*lenp = byte(evSize - 2)
 	}
-	traceReleaseBuffer(pid)
 }
 
 // traceAcquireBuffer returns trace buffer to use and, if necessary, locks it.
@@ -904,3 +930,117 @@ func traceHeapAlloc() {
 func traceNextGC() {
 	traceEvent(traceEvNextGC, -1, memstats.next_gc)
 }
+
+// traceString returns the id of the string s in the string dictionary
+// of the trace, writing an EvString event to the buffer bufp if s is
+// not yet in the dictionary. The empty string always has id 0.
+// The caller must hold the buffer, as acquired by traceAcquireBuffer.
+func traceString(pid int32, bufp *traceBufPtr, s string) uint64 {
+	if s == "" {
+		return 0
+	}
+	if len(s) > traceMaxStringLen {
+		s = s[:traceMaxStringLen]
+	}
+	lock(&trace.stringsLock)
+	if id, ok := trace.strings[s]; ok {
+		unlock(&trace.stringsLock)
+		return id
+	}
+	trace.stringSeq++
+	id := trace.stringSeq
+	trace.strings[s] = id
+	unlock(&trace.stringsLock)
+
+	// The allocation above may have emitted events and replaced *bufp,
+	// so use *bufp only from now on.
+	buf := (*bufp).ptr()
+	size := 2 + 5*traceBytesPerNumber + len(s) // batch header, event type, id, length and string
+	if buf == nil || len(buf.arr)-buf.pos < size {
+		buf = traceFlush(traceBufPtrOf(buf)).ptr()
+		(*bufp).set(buf)
+	}
+	if buf.pos == 0 {
+		// Start the batch here, as traceEventLocked only does so
+		// for an empty buffer.
+		seq, ticksraw := tracestamp()
+		ticks := uint64(ticksraw) / traceTickDiv
+		buf.byte(traceEvBatch | 1<<traceArgCountShift)
+		buf.varint(uint64(pid))
+		buf.varint(seq)
+		buf.varint(ticks)
+		buf.lastSeq = seq
+		buf.lastTicks = ticks
+	}
+	buf.byte(traceEvString)
+	buf.varint(id)
+	buf.varint(uint64(len(s)))
+	buf.pos += copy(buf.arr[buf.pos:], s)
+	return id
+}
+
+// The following functions implement the user annotations of package
+// runtime/trace. Their stacks start at the caller of the runtime/trace
+// function that calls them.
+
+//go:linkname trace_userTaskCreate runtime/trace.userTaskCreate
+func trace_userTaskCreate(id, parentID uint64, taskType string) {
+	if !trace.enabled {
+		return
+	}
+	// Same as in traceEvent.
+	mp, pid, bufp := traceAcquireBuffer()
+	if !trace.enabled && !mp.startingtrace {
+		traceReleaseBuffer(pid)
+		return
+	}
+	typeStringID := traceString(pid, bufp, taskType)
+	traceEventLocked(0, mp, pid, bufp, traceEvUserTaskCreate, 2, id, parentID, typeStringID)
+	traceReleaseBuffer(pid)
+}
+
+//go:linkname trace_userTaskEnd runtime/trace.userTaskEnd
+func trace_userTaskEnd(id uint64) {
+	if !trace.enabled {
+		return
+	}
+	traceEvent(traceEvUserTaskEnd, 3, id)
+}
+
+//go:linkname trace_userRegion runtime/trace.userRegion
+func trace_userRegion(id, mode uint64, regionType string) {
+	if !trace.enabled {
+		return
+	}
+	mp, pid, bufp := traceAcquireBuffer()
+	if !trace.enabled && !mp.startingtrace {
+		traceReleaseBuffer(pid)
+		return
+	}
+	typeStringID := traceString(pid, bufp, regionType)
+	traceEventLocked(0, mp, pid, bufp, traceEvUserRegion, 2, id, mode, typeStringID)
+	traceReleaseBuffer(pid)
+}
+
+//go:linkname trace_userLog runtime/trace.userLog
+func trace_userLog(id uint64, category, message string) {
+	if !trace.enabled {
+		return
+	}
+	mp, pid, bufp := traceAcquireBuffer()
+	if !trace.enabled && !mp.startingtrace {
+		traceReleaseBuffer(pid)
+		return
+	}
+	categoryID := traceString(pid, bufp, category)
+	if len(message) > traceMaxLogLen {
+		message = message[:traceMaxLogLen]
+	}
+	// The message follows the event as its length and bytes.
+	extraBytes := traceBytesPerNumber + len(message)
+	traceEventLocked(extraBytes, mp, pid, bufp, traceEvUserLog, 2, id, categoryID)
+	buf := (*bufp).ptr()
+	buf.varint(uint64(len(message)))
+	buf.pos += copy(buf.arr[buf.pos:], message)
+	traceReleaseBuffer(pid)
+}
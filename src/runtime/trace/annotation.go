@@ -0,0 +1,139 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// A Task is a logical operation of a program, such as the handling of
+// a request, that may span several goroutines. The execution tracer
+// records the creation and the end of each task, and the regions and
+// log messages associated with it, and 'go tool trace' measures the
+// latency of the tasks and analyzes the goroutines involved in them.
+//
+// A nil *Task is the background task: regions and log messages
+// associated with it belong to no particular task.
+type Task struct {
+	id uint64
+}
+
+// lastTaskID is the last assigned task id. The background task has id 0.
+var lastTaskID uint64
+
+// NewTask creates a task of the type taskType as a subtask of parent,
+// which may be nil, and returns it. The task must be ended with End,
+// usually in the goroutine that created it or in the last goroutine
+// that works on it.
+//
+// The task type is used by 'go tool trace' to group tasks; it should
+// identify a kind of operation, such as "handle request", rather than
+// a particular instance of it.
+func NewTask(parent *Task, taskType string) *Task {
+	id := atomic.AddUint64(&lastTaskID, 1)
+	userTaskCreate(id, parent.taskID(), taskType)
+	return &Task{id: id}
+}
+
+// End marks the end of the operation represented by the task.
+func (t *Task) End() {
+	userTaskEnd(t.taskID())
+}
+
+// taskID returns the id of t, which is 0 for the background task.
+func (t *Task) taskID() uint64 {
+	if t == nil {
+		return 0
+	}
+	return t.id
+}
+
+// Region modes recorded by the execution tracer.
+const (
+	regionStartMode = 0
+	regionEndMode   = 1
+)
+
+// A Region is a time interval of the execution of a goroutine that is
+// part of a task. Regions must be properly nested within a goroutine:
+// a region ends in the goroutine that started it, and it ends after
+// the regions started within it.
+type Region struct {
+	id         uint64
+	regionType string
+}
+
+// WithRegion calls fn as a region of the type regionType associated
+// with the task t, which may be nil, and records the start and the end
+// of the region in the execution trace.
+func WithRegion(t *Task, regionType string, fn func()) {
+	id := t.taskID()
+	userRegion(id, regionStartMode, regionType)
+	defer userRegion(id, regionEndMode, regionType)
+	fn()
+}
+
+// StartRegion starts a region of the type regionType associated with
+// the task t, which may be nil, and returns it. The region must be
+// ended with End in the same goroutine:
+//
+//	defer trace.StartRegion(t, "copy").End()
+//
+func StartRegion(t *Task, regionType string) *Region {
+	id := t.taskID()
+	userRegion(id, regionStartMode, regionType)
+	return &Region{id: id, regionType: regionType}
+}
+
+// End marks the end of the region.
+func (r *Region) End() {
+	userRegion(r.id, regionEndMode, r.regionType)
+}
+
+// Log records in the execution trace a message of the given category
+// associated with the task t, which may be nil. The category may be
+// empty; 'go tool trace' uses it to group and filter the messages.
+func Log(t *Task, category, message string) {
+	userLog(t.taskID(), category, message)
+}
+
+// Logf is like Log, but the message is formatted using the format
+// and args, in the manner of fmt.Printf. The message is formatted
+// only if tracing is enabled.
+func Logf(t *Task, category, format string, args ...interface{}) {
+	if !IsEnabled() {
+		return
+	}
+	// Call userLog directly, so that the stack of the
+	// event has the same depth as that of Log.
+	userLog(t.taskID(), category, fmt.Sprintf(format, args...))
+}
+
+// tracing is 1 while tracing is enabled by Start.
+var tracing int32
+
+// IsEnabled reports whether tracing is enabled. The result is only
+// advisory: tracing may be started or stopped at any time.
+func IsEnabled() bool {
+	return atomic.LoadInt32(&tracing) != 0
+}
+
+// The following functions are implemented in the runtime.
+
+// userTaskCreate records the creation of the task id of the type
+// taskType as a subtask of parentID.
+func userTaskCreate(id, parentID uint64, taskType string)
+
+// userTaskEnd records the end of the task id.
+func userTaskEnd(id uint64)
+
+// userRegion records the start (mode 0) or the end (mode 1) of a
+// region of the type regionType associated with the task id.
+func userRegion(id, mode uint64, regionType string)
+
+// userLog records a message of the given category associated with
+// the task id.
+func userLog(id uint64, category, message string)
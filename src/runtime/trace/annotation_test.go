@@ -0,0 +1,109 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace_test
+
+import (
+	"bytes"
+	"fmt"
+	"internal/trace"
+	"reflect"
+	"runtime"
+	. "runtime/trace"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestUserAnnotations(t *testing.T) {
+	if IsEnabled() {
+		t.Fatalf("tracing is enabled before Start")
+	}
+	// Annotations made while tracing is disabled are not recorded.
+	early := NewTask(nil, "early")
+	WithRegion(early, "untraced", func() {})
+
+	buf := new(bytes.Buffer)
+	if err := Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	if !IsEnabled() {
+		t.Fatalf("tracing is not enabled after Start")
+	}
+
+	task := NewTask(nil, "task0")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sub := NewTask(task, "task1")
+		defer sub.End()
+		WithRegion(sub, "region0", func() {
+			defer StartRegion(sub, "region1").End()
+			Log(sub, "key0", "0123456789abcdef")
+		})
+	}()
+	wg.Wait()
+	Logf(task, "key1", "%d", 42)
+	Log(nil, "", "background")
+	task.End()
+	early.End()
+	Stop()
+	if IsEnabled() {
+		t.Fatalf("tracing is enabled after Stop")
+	}
+
+	events, _, err := parseTrace(t, buf)
+	if err != nil {
+		t.Fatalf("failed to parse trace: %v", err)
+	}
+	tasks, regions := trace.UserAnnotations(events)
+
+	var got []string
+	for _, task := range tasks {
+		parent := "-"
+		if task.Parent != nil {
+			parent = task.Parent.Name
+		}
+		got = append(got, fmt.Sprintf("task %s parent %s complete %v", task.Name, parent, task.Complete()))
+		for _, log := range task.Logs {
+			got = append(got, fmt.Sprintf("log %s %s", task.Name, strings.Join(log.SArgs, "=")))
+		}
+		for _, r := range task.Regions {
+			got = append(got, fmt.Sprintf("region %s %s complete %v", task.Name, r.Name, r.Start != nil && r.End != nil))
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		"log task0 key1=42",
+		"log task1 key0=0123456789abcdef",
+		"region task1 region0 complete true",
+		"region task1 region1 complete true",
+		"task  parent - complete false", // early, created before tracing started
+		"task task0 parent - complete true",
+		"task task1 parent task0 complete true",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("annotations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(regions) != 2 {
+		t.Errorf("got %d regions, want 2", len(regions))
+	}
+
+	// The stacks of the annotations start in this test.
+	for _, ev := range events {
+		switch ev.Type {
+		case trace.EvUserTaskCreate, trace.EvUserTaskEnd, trace.EvUserRegion, trace.EvUserLog:
+			if len(ev.Stk) == 0 {
+				t.Errorf("%s event has no stack", trace.EventDescriptions[ev.Type].Name)
+				continue
+			}
+			fn := runtime.FuncForPC(uintptr(ev.Stk[0].PC) - 1)
+			if fn == nil || !strings.HasPrefix(fn.Name(), "runtime/trace_test.TestUserAnnotations") {
+				t.Errorf("%s event has stack starting with %v", trace.EventDescriptions[ev.Type].Name, fn)
+			}
+		}
+	}
+}
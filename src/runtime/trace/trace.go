@@ -9,11 +9,20 @@
 // in a compact form. A precise nanosecond-precision timestamp and a stack
 // trace is captured for most events. A trace can be analyzed later with
 // 'go tool trace' command.
+//
+// The execution trace can be annotated with the operations a program
+// performs: a Task, created with NewTask, represents an operation that
+// may involve several goroutines; the regions of the execution of a
+// goroutine that work on a task are marked with WithRegion or
+// StartRegion; and messages associated with a task are recorded with
+// Log. 'go tool trace' shows the latencies of the tasks and regions of
+// a trace and analyzes the goroutines that worked on each task.
 package trace
 
 import (
 	"io"
 	"runtime"
+	"sync/atomic"
 )
 
 // Start enables tracing for the current program.
@@ -23,6 +32,7 @@ func Start(w io.Writer) error {
 	if err := runtime.StartTrace(); err != nil {
 		return err
 	}
+	atomic.StoreInt32(&tracing, 1)
 	go func() {
 		for {
 			data := runtime.ReadTrace()
@@ -38,5 +48,6 @@ func Start(w io.Writer) error {
 // Stop stops the current tracing, if any.
 // Stop only returns after all the writes for the trace have completed.
 func Stop() {
+	atomic.StoreInt32(&tracing, 0)
 	runtime.StopTrace()
 }
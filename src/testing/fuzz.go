@@ -0,0 +1,694 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var matchFuzz = flag.String("test.fuzz", "", "run the fuzz test matching `regexp` with randomized inputs")
+var fuzzDuration = flag.Duration("test.fuzztime", 0, "time to spend fuzzing; default is to run until a failure is found")
+var fuzzCacheDir = flag.String("test.fuzzcachedir", "", "directory in which to store inputs that expand coverage")
+
+// corpusDir is the directory, relative to the package directory,
+// holding the seed corpus files of each fuzz test.
+const corpusDir = "testdata/fuzz"
+
+// corpusHeader is the first line of every corpus file.
+const corpusHeader = "go test fuzz v1"
+
+// fuzzStatusInterval is how often progress is printed while fuzzing.
+const fuzzStatusInterval = 3 * time.Second
+
+// An internal type but exported because it is cross-package; part of the implementation
+// of the "go test" command.
+type InternalFuzzTarget struct {
+	Name string
+	Fn   func(f *F)
+}
+
+var (
+	fuzzTargets  []InternalFuzzTarget
+	fuzzCounters map[string][]uint32
+)
+
+// RegisterFuzz records the fuzz tests of a test binary and, if the binary
+// was built for fuzzing, the coverage counters used to guide the mutation
+// of inputs.
+// NOTE: This function is internal to the testing infrastructure and may change.
+// It is not covered (yet) by the Go 1 compatibility guidelines.
+func RegisterFuzz(targets []InternalFuzzTarget, counters map[string][]uint32) {
+	fuzzTargets = targets
+	fuzzCounters = counters
+}
+
+// F is a type passed to fuzz tests.
+//
+// A fuzz test is a function of the form
+//	func FuzzXxx(*testing.F)
+// It adds seed inputs with Add and then calls Fuzz with the fuzz target,
+// a function receiving a *T followed by one argument for each value of
+// the inputs.
+//
+// By default a fuzz test runs the fuzz target once for each seed input
+// and for each file in the testdata/fuzz/FuzzXxx directory, as subtests
+// named after the input. With the -test.fuzz flag, which 'go test -fuzz'
+// sets, the fuzz target is instead run with inputs generated by mutating
+// the corpus, keeping those that reach new code according to the coverage
+// counters of the test binary. An input that makes the fuzz target fail
+// is written to testdata/fuzz/FuzzXxx so that it becomes part of the seed
+// corpus.
+//
+// The reporting methods of F, such as Fatal and Skip, apply to the fuzz test
+// itself and must not be called from the fuzz target, which should use
+// the methods of its *T argument instead.
+type F struct {
+	common
+	context    *testContext
+	fuzzing    bool // generate new inputs rather than running the seed corpus
+	corpus     []corpusEntry
+	fuzzCalled bool
+}
+
+// corpusEntry is a single input to a fuzz target.
+type corpusEntry struct {
+	name   string
+	values []interface{}
+}
+
+// fuzzTypes lists the types that may be used for the arguments of
+// fuzz targets and seed inputs.
+var fuzzTypes = map[reflect.Type]bool{
+	reflect.TypeOf([]byte(nil)): true,
+	reflect.TypeOf(""):          true,
+	reflect.TypeOf(false):       true,
+	reflect.TypeOf(int(0)):      true,
+	reflect.TypeOf(int8(0)):     true,
+	reflect.TypeOf(int16(0)):    true,
+	reflect.TypeOf(int32(0)):    true,
+	reflect.TypeOf(int64(0)):    true,
+	reflect.TypeOf(uint(0)):     true,
+	reflect.TypeOf(uint8(0)):    true,
+	reflect.TypeOf(uint16(0)):   true,
+	reflect.TypeOf(uint32(0)):   true,
+	reflect.TypeOf(uint64(0)):   true,
+	reflect.TypeOf(float32(0)):  true,
+	reflect.TypeOf(float64(0)):  true,
+}
+
+// Add adds the arguments to the seed corpus of the fuzz test. The
+// arguments must match, in number and type, the arguments of the fuzz
+// target following its *T. Add may not be called after Fuzz.
+func (f *F) Add(args ...interface{}) {
+	if f.fuzzCalled {
+		panic("testing: F.Add called after F.Fuzz")
+	}
+	for _, arg := range args {
+		if !fuzzTypes[reflect.TypeOf(arg)] {
+			panic(fmt.Sprintf("testing: unsupported type to F.Add: %T", arg))
+		}
+	}
+	name := fmt.Sprintf("seed#%d", len(f.corpus))
+	f.corpus = append(f.corpus, corpusEntry{name: name, values: args})
+}
+
+// Fuzz runs the fuzz target ff, a function of the form
+//	func(t *testing.T, arg1 Type1, arg2 Type2, ...)
+// whose argument types are []byte, string, bool, or one of the
+// integer or floating-point types. Fuzz may be called only once.
+//
+// Unless fuzzing, ff is run as a subtest of the fuzz test once for each
+// entry of the seed corpus.
+func (f *F) Fuzz(ff interface{}) {
+	if f.fuzzCalled {
+		panic("testing: F.Fuzz called more than once")
+	}
+	f.fuzzCalled = true
+
+	fn := reflect.ValueOf(ff)
+	fnType := fn.Type()
+	if fnType.Kind() != reflect.Func {
+		panic("testing: F.Fuzz must receive a function")
+	}
+	if fnType.NumIn() < 2 || fnType.In(0) != reflect.TypeOf((*T)(nil)) {
+		panic("testing: fuzz target must receive a *T followed by at least one argument")
+	}
+	if fnType.NumOut() != 0 {
+		panic("testing: fuzz target must not return a value")
+	}
+	var types []reflect.Type
+	for i := 1; i < fnType.NumIn(); i++ {
+		typ := fnType.In(i)
+		if !fuzzTypes[typ] {
+			panic(fmt.Sprintf("testing: unsupported type for fuzz target argument: %v", typ))
+		}
+		types = append(types, typ)
+	}
+
+	for _, e := range f.corpus {
+		if err := checkCorpusEntry(e, types); err != nil {
+			f.Fatalf("seed corpus entry %s: %v", e.name, err)
+		}
+	}
+	files, err := readCorpus(filepath.Join(corpusDir, f.name), types)
+	if err != nil {
+		f.Fatal(err)
+	}
+	corpus := append(f.corpus, files...)
+
+	if f.fuzzing {
+		f.fuzz(fn, types, corpus)
+		return
+	}
+	for _, e := range corpus {
+		args := e.values
+		f.runSubtest(f.context, e.name, func(t *T) {
+			callFuzzTarget(fn, t, args)
+		})
+	}
+}
+
+// callFuzzTarget calls the fuzz target fn with t and args.
+func callFuzzTarget(fn reflect.Value, t *T, args []interface{}) {
+	in := make([]reflect.Value, 1+len(args))
+	in[0] = reflect.ValueOf(t)
+	for i, arg := range args {
+		in[i+1] = reflect.ValueOf(arg)
+	}
+	fn.Call(in)
+}
+
+// fuzz runs the fuzz target with inputs derived from corpus until it fails
+// or the -test.fuzztime duration has elapsed.
+func (f *F) fuzz(fn reflect.Value, types []reflect.Type, corpus []corpusEntry) {
+	var cacheDir string
+	if *fuzzCacheDir != "" {
+		cacheDir = filepath.Join(*fuzzCacheDir, f.name)
+		cached, err := readCorpus(cacheDir, types)
+		if err != nil {
+			f.Fatal(err)
+		}
+		corpus = append(corpus, cached...)
+	}
+	if len(corpus) == 0 {
+		zero := make([]interface{}, len(types))
+		for i, typ := range types {
+			zero[i] = reflect.Zero(typ).Interface()
+		}
+		corpus = append(corpus, corpusEntry{name: "zero", values: zero})
+	}
+
+	cov := newCoverage(fuzzCounters)
+	m := newMutator()
+
+	// Run the existing corpus first. It establishes the coverage
+	// that new inputs must expand, and a failure here is not news.
+	for _, e := range corpus {
+		cov.snapshot()
+		if t := f.runInput(fn, e.values); t.Failed() {
+			f.reportFailure(t, e.name, nil)
+			return
+		}
+		cov.update()
+	}
+	seeds := len(corpus)
+
+	start := time.Now()
+	lastStatus := start
+	var execs int64
+	status := func() {
+		elapsed := time.Since(start).Seconds()
+		rate := 0.0
+		if elapsed > 0 {
+			rate = float64(execs) / elapsed
+		}
+		f.printRoot("fuzz: elapsed: %.0fs, execs: %d (%.0f/sec), new interesting: %d (total: %d)\n",
+			elapsed, execs, rate, len(corpus)-seeds, len(corpus))
+	}
+	for *fuzzDuration <= 0 || time.Since(start) < *fuzzDuration {
+		parent := corpus[m.rand(len(corpus))]
+		values := m.mutate(parent.values)
+		cov.snapshot()
+		t := f.runInput(fn, values)
+		execs++
+		if t.Failed() {
+			status()
+			f.reportFailure(t, "", values)
+			return
+		}
+		if cov.update() {
+			data := marshalCorpusFile(values)
+			e := corpusEntry{name: corpusFileName(data), values: values}
+			corpus = append(corpus, e)
+			if cacheDir != "" {
+				// The cache only speeds up later runs, so errors are ignored.
+				writeCorpusFile(filepath.Join(cacheDir, e.name), data)
+			}
+		}
+		if time.Since(lastStatus) >= fuzzStatusInterval {
+			status()
+			lastStatus = time.Now()
+		}
+	}
+	status()
+}
+
+// runInput runs the fuzz target with a single input and returns the
+// *T it was given. Unlike a subtest, the *T is not attached to the fuzz
+// test, so its result and output are reported only if it failed.
+func (f *F) runInput(fn reflect.Value, values []interface{}) *T {
+	t := &T{
+		common: common{
+			signal:  make(chan bool),
+			barrier: make(chan bool),
+			name:    f.name,
+			level:   f.level + 1,
+		},
+		context: f.context,
+	}
+	t.w = indenter{&t.common}
+	go func() {
+		defer func() {
+			t.duration = time.Since(t.start)
+			err := recover()
+			if !t.finished && err == nil {
+				err = "fuzz target executed panic(nil) or runtime.Goexit"
+			}
+			if err != nil {
+				t.mu.Lock()
+				t.failed = true
+				t.output = append(t.output, fmt.Sprintf("\tpanic: %v\n%s", err, debug.Stack())...)
+				t.mu.Unlock()
+			}
+			t.signal <- true
+		}()
+		t.start = time.Now()
+		callFuzzTarget(fn, t, values)
+		t.finished = true
+	}()
+	<-t.signal
+	return t
+}
+
+// reportFailure reports that the input of t made the fuzz target fail.
+// If values is not nil, the input is new and is written to the seed
+// corpus so that it can be reproduced. Otherwise it is the corpus
+// entry called name.
+func (f *F) reportFailure(t *T, name string, values []interface{}) {
+	var msg string
+	if values != nil {
+		data := marshalCorpusFile(values)
+		name = corpusFileName(data)
+		file := filepath.Join(corpusDir, f.name, name)
+		if err := writeCorpusFile(file, data); err != nil {
+			msg = fmt.Sprintf("\tcould not write failing input: %v\n", err)
+		} else {
+			msg = fmt.Sprintf("\tFailing input written to %s\n\tTo re-run:\n\tgo test -run=%s/%s\n", file, f.name, name)
+		}
+	} else {
+		msg = fmt.Sprintf("\tFailure while testing corpus entry %s/%s\n", f.name, name)
+	}
+
+	t.name = f.name + "/" + name
+	t.parent = &f.common
+	t.report()
+	f.Fail()
+	f.mu.Lock()
+	f.output = append(f.output, msg...)
+	f.mu.Unlock()
+}
+
+// fRunner runs the fuzz test f the way tRunner runs a test.
+func fRunner(f *F, fn func(f *F)) {
+	defer func() {
+		f.duration += time.Now().Sub(f.start)
+		err := recover()
+		if !f.finished && err == nil {
+			err = fmt.Errorf("fuzz test executed panic(nil) or runtime.Goexit")
+		}
+		if err != nil {
+			f.Fail()
+			f.report()
+			panic(err)
+		}
+
+		if len(f.sub) > 0 {
+			// Run the parallel subtests of the seed corpus.
+			f.context.release()
+			close(f.barrier)
+			for _, sub := range f.sub {
+				<-sub.signal
+			}
+			f.context.waitParallel()
+		}
+		f.report()
+
+		f.mu.Lock()
+		f.done = true
+		f.mu.Unlock()
+		f.signal <- true
+	}()
+
+	f.start = time.Now()
+	fn(f)
+	f.finished = true
+}
+
+// runFuzzTest runs target as a subtest of t. If fuzzing is set, the
+// fuzz target is run with generated inputs. It reports whether the fuzz
+// test succeeded.
+func (t *T) runFuzzTest(target InternalFuzzTarget, fuzzing bool) bool {
+	atomic.StoreInt32(&t.hasSub, 1)
+	name, ok := t.context.match.fullName(&t.common, target.Name)
+	if !ok {
+		return true
+	}
+	f := &F{
+		common: common{
+			barrier: make(chan bool),
+			signal:  make(chan bool),
+			name:    name,
+			parent:  &t.common,
+			level:   t.level + 1,
+			chatty:  t.chatty,
+		},
+		context: t.context,
+		fuzzing: fuzzing,
+	}
+	f.w = indenter{&f.common}
+
+	if f.chatty {
+		f.printRoot("=== RUN   %s\n", f.name)
+	}
+	go fRunner(f, target.Fn)
+	<-f.signal
+	return !f.Failed()
+}
+
+// runFuzzing runs the fuzz test selected by the -test.fuzz flag with
+// generated inputs. It reports whether no failure was found.
+func runFuzzing(matchString func(pat, str string) (bool, error), targets []InternalFuzzTarget) bool {
+	if *matchFuzz == "" {
+		return true
+	}
+	m := newMatcher(matchString, *matchFuzz, "-test.fuzz")
+	var selected []InternalFuzzTarget
+	for _, target := range targets {
+		if _, ok := m.fullName(nil, target.Name); ok {
+			selected = append(selected, target)
+		}
+	}
+	switch len(selected) {
+	case 0:
+		fmt.Fprintln(os.Stderr, "testing: warning: no fuzz tests to fuzz")
+		return true
+	case 1:
+	default:
+		var names []string
+		for _, target := range selected {
+			names = append(names, target.Name)
+		}
+		fmt.Fprintf(os.Stderr, "testing: will not fuzz, -test.fuzz matches more than one fuzz test: %s\n", strings.Join(names, ", "))
+		return false
+	}
+
+	ctx := newTestContext(1, m)
+	t := &T{
+		common: common{
+			signal:  make(chan bool),
+			barrier: make(chan bool),
+			w:       os.Stdout,
+			chatty:  *chatty,
+		},
+		context: ctx,
+	}
+	tRunner(t, func(t *T) {
+		t.runFuzzTest(selected[0], true)
+		// See the comment in RunTests.
+		go func() { <-t.signal }()
+	})
+	return !t.Failed()
+}
+
+// coverage tracks the coverage counters of the test binary to decide
+// whether an input reached new code. Besides the counters becoming
+// non-zero, a counter moving into a new range of hit counts
+// (1, 2, 3, 4-7, 8-15, 16-31, 32-127, 128+) counts as new coverage,
+// which rewards inputs that run loops a different number of times.
+type coverage struct {
+	counters [][]uint32 // counters of each file, in file name order
+	before   []uint32   // counter values before running the input
+	seen     []uint8    // bit mask of the hit count ranges of each counter
+}
+
+func newCoverage(counters map[string][]uint32) *coverage {
+	var files []string
+	n := 0
+	for file, c := range counters {
+		files = append(files, file)
+		n += len(c)
+	}
+	sort.Strings(files)
+	c := &coverage{
+		before: make([]uint32, n),
+		seen:   make([]uint8, n),
+	}
+	for _, file := range files {
+		c.counters = append(c.counters, counters[file])
+	}
+	return c
+}
+
+// snapshot records the current counter values.
+func (c *coverage) snapshot() {
+	i := 0
+	for _, counters := range c.counters {
+		for j := range counters {
+			c.before[i] = atomic.LoadUint32(&counters[j])
+			i++
+		}
+	}
+}
+
+// update reports whether the counters incremented since the last
+// snapshot show coverage that no earlier input had, and records it.
+// The counters themselves are left alone so that the coverage of the
+// test binary can still be reported.
+func (c *coverage) update() bool {
+	grew := false
+	i := 0
+	for _, counters := range c.counters {
+		for j := range counters {
+			if bit := countBucket(atomic.LoadUint32(&counters[j]) - c.before[i]); c.seen[i]&bit != bit {
+				c.seen[i] |= bit
+				grew = true
+			}
+			i++
+		}
+	}
+	return grew
+}
+
+// countBucket returns the bit representing the range of hit counts n is in.
+func countBucket(n uint32) uint8 {
+	switch {
+	case n == 0:
+		return 0
+	case n <= 3:
+		return 1 << (n - 1)
+	case n <= 7:
+		return 1 << 3
+	case n <= 15:
+		return 1 << 4
+	case n <= 31:
+		return 1 << 5
+	case n <= 127:
+		return 1 << 6
+	}
+	return 1 << 7
+}
+
+// checkCorpusEntry reports whether the values of e match the argument types
+// of the fuzz target.
+func checkCorpusEntry(e corpusEntry, types []reflect.Type) error {
+	if len(e.values) != len(types) {
+		return fmt.Errorf("wrong number of values: got %d, want %d", len(e.values), len(types))
+	}
+	for i, v := range e.values {
+		if typ := reflect.TypeOf(v); typ != types[i] {
+			return fmt.Errorf("value %d has type %v, want %v", i, typ, types[i])
+		}
+	}
+	return nil
+}
+
+// readCorpus reads the corpus files in dir, which need not exist.
+func readCorpus(dir string, types []reflect.Type) ([]corpusEntry, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var corpus []corpusEntry
+	for _, file := range files {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		name := filepath.Join(dir, file.Name())
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		values, err := unmarshalCorpusFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		e := corpusEntry{name: file.Name(), values: values}
+		if err := checkCorpusEntry(e, types); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		corpus = append(corpus, e)
+	}
+	return corpus, nil
+}
+
+// corpusFileName returns the name of the corpus file holding data.
+func corpusFileName(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+}
+
+// writeCorpusFile writes data to file, creating its directory if needed.
+func writeCorpusFile(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0666)
+}
+
+// marshalCorpusFile encodes values in the corpus file format: the
+// corpusHeader line followed by one line per value, each a Go
+// conversion expression of a literal such as
+//	[]byte("\x00abc")
+//	int64(-5)
+//	float64(+Inf)
+func marshalCorpusFile(values []interface{}) []byte {
+	var b bytes.Buffer
+	b.WriteString(corpusHeader + "\n")
+	for _, v := range values {
+		switch v := v.(type) {
+		case []byte:
+			fmt.Fprintf(&b, "[]byte(%s)\n", strconv.Quote(string(v)))
+		case string:
+			fmt.Fprintf(&b, "string(%s)\n", strconv.Quote(v))
+		case float32:
+			fmt.Fprintf(&b, "float32(%s)\n", strconv.FormatFloat(float64(v), 'g', -1, 32))
+		case float64:
+			fmt.Fprintf(&b, "float64(%s)\n", strconv.FormatFloat(v, 'g', -1, 64))
+		default:
+			fmt.Fprintf(&b, "%T(%v)\n", v, v)
+		}
+	}
+	return b.Bytes()
+}
+
+// unmarshalCorpusFile decodes a corpus file written by marshalCorpusFile.
+// It also accepts the byte and rune aliases of uint8 and int32.
+func unmarshalCorpusFile(data []byte) ([]interface{}, error) {
+	lines := strings.Split(string(data), "\n")
+	if strings.TrimSpace(lines[0]) != corpusHeader {
+		return nil, errors.New("missing corpus file header")
+	}
+	var values []interface{}
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.Index(line, "(")
+		if i < 0 || !strings.HasSuffix(line, ")") {
+			return nil, fmt.Errorf("malformed line %q", line)
+		}
+		v, err := parseCorpusValue(line[:i], line[i+1:len(line)-1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed line %q: %v", line, err)
+		}
+		values = append(values, v)
+	}
+	if values == nil {
+		return nil, errors.New("no values in corpus file")
+	}
+	return values, nil
+}
+
+// parseCorpusValue returns the value of the literal lit converted to typ.
+func parseCorpusValue(typ, lit string) (interface{}, error) {
+	if strings.HasPrefix(lit, "'") && typ != "string" && typ != "[]byte" {
+		// A character literal, as in byte('a') or rune('☺').
+		r, _, tail, err := strconv.UnquoteChar(lit[1:], '\'')
+		if err != nil || tail != "'" {
+			return nil, fmt.Errorf("malformed character literal %s", lit)
+		}
+		lit = strconv.Itoa(int(r))
+	}
+	switch typ {
+	case "[]byte":
+		s, err := strconv.Unquote(lit)
+		return []byte(s), err
+	case "string":
+		return strconv.Unquote(lit)
+	case "bool":
+		return strconv.ParseBool(lit)
+	case "int":
+		n, err := strconv.ParseInt(lit, 0, strconv.IntSize)
+		return int(n), err
+	case "int8":
+		n, err := strconv.ParseInt(lit, 0, 8)
+		return int8(n), err
+	case "int16":
+		n, err := strconv.ParseInt(lit, 0, 16)
+		return int16(n), err
+	case "int32", "rune":
+		n, err := strconv.ParseInt(lit, 0, 32)
+		return int32(n), err
+	case "int64":
+		return strconv.ParseInt(lit, 0, 64)
+	case "uint":
+		n, err := strconv.ParseUint(lit, 0, strconv.IntSize)
+		return uint(n), err
+	case "uint8", "byte":
+		n, err := strconv.ParseUint(lit, 0, 8)
+		return uint8(n), err
+	case "uint16":
+		n, err := strconv.ParseUint(lit, 0, 16)
+		return uint16(n), err
+	case "uint32":
+		n, err := strconv.ParseUint(lit, 0, 32)
+		return uint32(n), err
+	case "uint64":
+		return strconv.ParseUint(lit, 0, 64)
+	case "float32":
+		n, err := strconv.ParseFloat(lit, 32)
+		return float32(n), err
+	case "float64":
+		return strconv.ParseFloat(lit, 64)
+	}
+	return nil, fmt.Errorf("unsupported type %s", typ)
+}
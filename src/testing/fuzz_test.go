@@ -0,0 +1,218 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func TestCorpusFileRoundTrip(t *T) {
+	values := []interface{}{
+		[]byte("\x00\xc1\xff\n"), "héllo\t", true,
+		int(-1), int8(math.MinInt8), int16(300), int32('x'), int64(math.MaxInt64),
+		uint(7), uint8(0x40), uint16(math.MaxUint16), uint32(1 << 31), uint64(math.MaxUint64),
+		float32(1.5), float64(-0.1), math.Inf(1),
+	}
+	data := marshalCorpusFile(values)
+	got, err := unmarshalCorpusFile(data)
+	if err != nil {
+		t.Fatalf("unmarshalCorpusFile(%q): %v", data, err)
+	}
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("round trip of %q:\ngot  %#v\nwant %#v", data, got, values)
+	}
+
+	got, err = unmarshalCorpusFile([]byte(corpusHeader + "\nfloat64(NaN)\nbyte('a')\nrune('\\u263a')\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := got[0].(float64); !ok || !math.IsNaN(f) {
+		t.Errorf("float64(NaN) decoded as %#v", got[0])
+	}
+	if got[1] != uint8('a') || got[2] != int32(0x263a) {
+		t.Errorf("byte and rune decoded as %#v, %#v", got[1], got[2])
+	}
+}
+
+func TestUnmarshalCorpusFileErrors(t *T) {
+	for _, data := range []string{
+		"",
+		"int(1)\n",
+		corpusHeader + "\n",
+		corpusHeader + "\nint(1\n",
+		corpusHeader + "\nint8(300)\n",
+		corpusHeader + "\nstring(abc)\n",
+		corpusHeader + "\ncomplex128(1)\n",
+	} {
+		if v, err := unmarshalCorpusFile([]byte(data)); err == nil {
+			t.Errorf("unmarshalCorpusFile(%q) = %v, want error", data, v)
+		}
+	}
+}
+
+func TestCoverageUpdate(t *T) {
+	counters := []uint32{0, 0}
+	c := newCoverage(map[string][]uint32{"f": counters})
+	run := func(hits ...uint32) bool {
+		c.snapshot()
+		for i, n := range hits {
+			counters[i] += n
+		}
+		return c.update()
+	}
+	for _, tt := range []struct {
+		hits []uint32
+		want bool
+	}{
+		{[]uint32{0, 0}, false},
+		{[]uint32{1, 0}, true},
+		{[]uint32{1, 0}, false},
+		{[]uint32{5, 0}, true},
+		{[]uint32{6, 0}, false},
+		{[]uint32{1, 1}, true},
+		{[]uint32{200, 1}, true},
+		{[]uint32{300, 1}, false},
+	} {
+		if got := run(tt.hits...); got != tt.want {
+			t.Errorf("hits %v: new coverage = %v, want %v", tt.hits, got, tt.want)
+		}
+	}
+}
+
+func TestMutatorKeepsTypes(t *T) {
+	m := newMutator()
+	values := []interface{}{[]byte("abc"), "abc", true, int16(5), uint64(5), float32(5)}
+	orig := marshalCorpusFile(values)
+	for i := 0; i < 1000; i++ {
+		mutated := m.mutate(values)
+		for j, v := range mutated {
+			if reflect.TypeOf(v) != reflect.TypeOf(values[j]) {
+				t.Fatalf("mutated value %d has type %T, want %T", j, v, values[j])
+			}
+		}
+		values = mutated
+		if data := marshalCorpusFile(values); len(data) > 2*maxFuzzLen {
+			t.Fatalf("mutated input grew to %d bytes", len(data))
+		}
+	}
+	if bytes.Equal(marshalCorpusFile(values), orig) {
+		t.Errorf("1000 mutations did not change the input")
+	}
+}
+
+// runFuzzRoot runs target as a fuzz test below a root test writing to buf.
+func runFuzzRoot(target InternalFuzzTarget, fuzzing bool, buf *bytes.Buffer) bool {
+	ctx := newTestContext(1, newMatcher(regexp.MatchString, "", ""))
+	root := &T{
+		common: common{
+			signal: make(chan bool),
+			w:      buf,
+			chatty: true,
+		},
+		context: ctx,
+	}
+	ok := root.runFuzzTest(target, fuzzing)
+	ctx.release()
+	return ok
+}
+
+func TestFuzzSeedCorpus(t *T) {
+	var got []string
+	target := InternalFuzzTarget{"FuzzSeed", func(f *F) {
+		f.Add("a", 1)
+		f.Add("b", 2)
+		f.Fuzz(func(t *T, s string, n int) {
+			got = append(got, strings.Repeat(s, n))
+			if s == "b" {
+				t.Error("bad input")
+			}
+		})
+	}}
+	buf := &bytes.Buffer{}
+	if runFuzzRoot(target, false, buf) {
+		t.Errorf("fuzz test with failing seed succeeded")
+	}
+	if want := []string{"a", "bb"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fuzz target called with %q, want %q", got, want)
+	}
+	want := "--- FAIL: FuzzSeed (N.NNs)\n" +
+		"    --- PASS: FuzzSeed/seed#0 (N.NNs)\n" +
+		"    --- FAIL: FuzzSeed/seed#1 (N.NNs)\n"
+	if out := regexp.MustCompile(`\d\.\d\ds`).ReplaceAllString(buf.String(), "N.NNs"); !strings.Contains(out, want) {
+		t.Errorf("output:\n%s\nwant it to contain:\n%s", out, want)
+	}
+}
+
+func TestFuzzFindsFailure(t *T) {
+	dir, err := ioutil.TempDir("", "fuzz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// The fuzz target maintains its own coverage counters, one per
+	// matched byte of "FUZ", in place of compiler instrumentation.
+	counters := []uint32{0, 0, 0}
+	defer func(old map[string][]uint32, d time.Duration) {
+		fuzzCounters, *fuzzDuration = old, d
+	}(fuzzCounters, *fuzzDuration)
+	fuzzCounters = map[string][]uint32{"fuzz.go": counters}
+	*fuzzDuration = time.Minute
+
+	target := InternalFuzzTarget{"FuzzFind", func(f *F) {
+		f.Add([]byte("xxx"))
+		f.Fuzz(func(t *T, b []byte) {
+			for i, c := range []byte("FUZ") {
+				if len(b) <= i || b[i] != c {
+					return
+				}
+				counters[i]++
+			}
+			t.Fatal("found FUZ")
+		})
+	}}
+	buf := &bytes.Buffer{}
+	if runFuzzRoot(target, true, buf) {
+		t.Fatalf("fuzzing did not find the failure:\n%s", buf)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "found FUZ") || !strings.Contains(out, "go test -run=FuzzFind/") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	// The failing input was written to the seed corpus and now makes
+	// the fuzz test fail without fuzzing.
+	files, err := filepath.Glob(filepath.Join(corpusDir, "FuzzFind", "*"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("corpus files = %v, %v; want one file", files, err)
+	}
+	data, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values, err := unmarshalCorpusFile(data); err != nil || !bytes.HasPrefix(values[0].([]byte), []byte("FUZ")) {
+		t.Errorf("corpus file %q decodes to %v, %v", data, values, err)
+	}
+	buf.Reset()
+	if runFuzzRoot(target, false, buf) {
+		t.Errorf("seed corpus run passed with failing input in testdata:\n%s", buf)
+	}
+}
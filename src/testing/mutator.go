@@ -0,0 +1,206 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// maxFuzzLen is the length beyond which byte slices and strings
+// are not grown by mutation.
+const maxFuzzLen = 1 << 16
+
+// interestingBytes are byte values that are likely to be special cases.
+// 0x40 and 0xF0 are the space and zero characters of EBCDIC.
+var interestingBytes = []byte{0x00, 0x01, 0x0a, 0x0d, ' ', '0', '9', 0x40, 0x7f, 0x80, 0xc1, 0xf0, 0xf9, 0xff}
+
+// interestingInts are integer values that are likely to be special cases.
+var interestingInts = []int64{
+	0, 1, -1, 2, 7, 8, 16, 64, 100,
+	math.MaxInt8, math.MinInt8, math.MaxUint8,
+	math.MaxInt16, math.MinInt16, math.MaxUint16,
+	math.MaxInt32, math.MinInt32, math.MaxUint32,
+	math.MaxInt64, math.MinInt64,
+}
+
+// interestingFloats are floating-point values that are likely to be special cases.
+var interestingFloats = []float64{
+	0, 1, -1, 0.5, 1e-7, 1e7,
+	math.MaxFloat32, math.SmallestNonzeroFloat32,
+	math.MaxFloat64, math.SmallestNonzeroFloat64,
+	math.Inf(1), math.Inf(-1), math.NaN(),
+}
+
+// mutator generates new fuzz inputs by randomly changing existing ones.
+type mutator struct {
+	r *rand.Rand
+}
+
+func newMutator() *mutator {
+	return &mutator{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// rand returns a random number in [0, n).
+func (m *mutator) rand(n int) int {
+	return m.r.Intn(n)
+}
+
+// mutate returns a copy of values with a few random changes.
+func (m *mutator) mutate(values []interface{}) []interface{} {
+	values = append([]interface{}(nil), values...)
+	for i := range values {
+		if b, ok := values[i].([]byte); ok {
+			values[i] = append([]byte(nil), b...)
+		}
+	}
+	for n := 1 + m.rand(4); n > 0; n-- {
+		m.mutateValue(values, m.rand(len(values)))
+	}
+	return values
+}
+
+// mutateValue changes values[i].
+func (m *mutator) mutateValue(values []interface{}, i int) {
+	switch v := values[i].(type) {
+	case []byte:
+		values[i] = m.mutateBytes(v)
+	case string:
+		values[i] = string(m.mutateBytes([]byte(v)))
+	case bool:
+		values[i] = !v
+	case int:
+		values[i] = int(m.mutateInt(int64(v)))
+	case int8:
+		values[i] = int8(m.mutateInt(int64(v)))
+	case int16:
+		values[i] = int16(m.mutateInt(int64(v)))
+	case int32:
+		values[i] = int32(m.mutateInt(int64(v)))
+	case int64:
+		values[i] = m.mutateInt(v)
+	case uint:
+		values[i] = uint(m.mutateInt(int64(v)))
+	case uint8:
+		values[i] = uint8(m.mutateInt(int64(v)))
+	case uint16:
+		values[i] = uint16(m.mutateInt(int64(v)))
+	case uint32:
+		values[i] = uint32(m.mutateInt(int64(v)))
+	case uint64:
+		values[i] = uint64(m.mutateInt(int64(v)))
+	case float32:
+		values[i] = float32(m.mutateFloat(float64(v)))
+	case float64:
+		values[i] = m.mutateFloat(v)
+	default:
+		panic("testing: unsupported fuzz value type")
+	}
+}
+
+// mutateInt returns a variation of v. Unsigned and narrower integers
+// are mutated as int64 and truncated afterwards.
+func (m *mutator) mutateInt(v int64) int64 {
+	switch m.rand(5) {
+	case 0:
+		return v + int64(1+m.rand(16))
+	case 1:
+		return v - int64(1+m.rand(16))
+	case 2:
+		return v ^ 1<<uint(m.rand(64))
+	case 3:
+		return interestingInts[m.rand(len(interestingInts))]
+	}
+	return m.r.Int63() - m.r.Int63()
+}
+
+// mutateFloat returns a variation of v.
+func (m *mutator) mutateFloat(v float64) float64 {
+	switch m.rand(6) {
+	case 0:
+		return v + float64(m.rand(32)-16)
+	case 1:
+		return v * (m.r.Float64()*4 - 2)
+	case 2:
+		return -v
+	case 3:
+		return math.Float64frombits(math.Float64bits(v) ^ 1<<uint(m.rand(64)))
+	case 4:
+		return interestingFloats[m.rand(len(interestingFloats))]
+	}
+	return m.r.NormFloat64() * math.Pow(10, float64(m.rand(20)))
+}
+
+// mutateBytes changes b in place, or returns a changed copy of it
+// if its length changes.
+func (m *mutator) mutateBytes(b []byte) []byte {
+	for {
+		switch m.rand(8) {
+		case 0:
+			// Insert random bytes.
+			if len(b) >= maxFuzzLen {
+				continue
+			}
+			n := 1 + m.rand(8)
+			pos := m.rand(len(b) + 1)
+			ins := make([]byte, n)
+			for i := range ins {
+				ins[i] = byte(m.rand(256))
+			}
+			return append(b[:pos:pos], append(ins, b[pos:]...)...)
+		case 1:
+			// Remove a range of bytes.
+			if len(b) == 0 {
+				continue
+			}
+			pos := m.rand(len(b))
+			n := 1 + m.rand(len(b)-pos)
+			return append(b[:pos], b[pos+n:]...)
+		case 2:
+			// Duplicate a range of bytes.
+			if len(b) == 0 || len(b) >= maxFuzzLen {
+				continue
+			}
+			src := m.rand(len(b))
+			n := 1 + m.rand(len(b)-src)
+			dst := m.rand(len(b) + 1)
+			dup := append([]byte(nil), b[src:src+n]...)
+			return append(b[:dst:dst], append(dup, b[dst:]...)...)
+		case 3:
+			// Flip a bit.
+			if len(b) == 0 {
+				continue
+			}
+			b[m.rand(len(b))] ^= 1 << uint(m.rand(8))
+		case 4:
+			// Set a random byte.
+			if len(b) == 0 {
+				continue
+			}
+			b[m.rand(len(b))] = byte(m.rand(256))
+		case 5:
+			// Set an interesting byte.
+			if len(b) == 0 {
+				continue
+			}
+			b[m.rand(len(b))] = interestingBytes[m.rand(len(interestingBytes))]
+		case 6:
+			// Swap two bytes.
+			if len(b) < 2 {
+				continue
+			}
+			i, j := m.rand(len(b)), m.rand(len(b))
+			b[i], b[j] = b[j], b[i]
+		case 7:
+			// Add a small number to a byte.
+			if len(b) == 0 {
+				continue
+			}
+			b[m.rand(len(b))] += byte(m.rand(35) - 17)
+		}
+		return b
+	}
+}